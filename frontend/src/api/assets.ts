@@ -64,10 +64,11 @@ export interface MoveAssetRequest {
 // ============================================================================
 
 /**
- * List all assets
+ * List all assets, or with parentId those anywhere under that folder
  */
-export async function listAssets(): Promise<Asset[]> {
-  const resp = await fetch(getApiUrl("/api/assets"));
+export async function listAssets(parentId?: string): Promise<Asset[]> {
+  const query = parentId ? `?parent_id=${encodeURIComponent(parentId)}` : "";
+  const resp = await fetch(getApiUrl(`/api/assets${query}`));
   if (!resp.ok) {
     throw new Error(`List assets failed: HTTP ${resp.status}`);
   }
@@ -164,6 +165,57 @@ export async function moveAsset(id: string, req: MoveAssetRequest): Promise<Asse
   return json.data!;
 }
 
+// ============================================================================
+// Asset Revision APIs
+// ============================================================================
+
+export interface AssetRevision {
+  id: number;
+  asset_id: string;
+  action: "import" | "create" | "update" | "move" | "delete" | "restore";
+  asset: Asset; // the asset after the change, or just before its deletion
+  created_at: string;
+}
+
+/**
+ * List an asset's revisions, newest first
+ */
+export async function listAssetRevisions(id: string): Promise<AssetRevision[]> {
+  const resp = await fetch(getApiUrl(`/api/assets/${encodeURIComponent(id)}/revisions`));
+  if (!resp.ok) {
+    throw new Error(`List asset revisions failed: HTTP ${resp.status}`);
+  }
+  const json = (await resp.json()) as APIResponse<AssetRevision[]>;
+  return json.data || [];
+}
+
+/**
+ * List the last revision of each deleted asset, newest first
+ */
+export async function listDeletedAssets(): Promise<AssetRevision[]> {
+  const resp = await fetch(getApiUrl("/api/assets/deleted"));
+  if (!resp.ok) {
+    throw new Error(`List deleted assets failed: HTTP ${resp.status}`);
+  }
+  const json = (await resp.json()) as APIResponse<AssetRevision[]>;
+  return json.data || [];
+}
+
+/**
+ * Roll an asset back to a revision, recreating it if it was deleted
+ */
+export async function restoreAssetRevision(id: string, revisionId: number): Promise<Asset> {
+  const resp = await fetch(
+    getApiUrl(`/api/assets/${encodeURIComponent(id)}/revisions/${revisionId}/restore`),
+    { method: "POST" },
+  );
+  const json = (await resp.json()) as APIResponse<Asset>;
+  if (!resp.ok || (json.code !== 200 && json.code !== 0)) {
+    throw new Error(json.message || `Restore asset failed: HTTP ${resp.status}`);
+  }
+  return json.data!;
+}
+
 // ============================================================================
 // Docker Container APIs
 // ============================================================================
@@ -0,0 +1,93 @@
+// Broadcast API - HTTP API functions for terminal broadcast groups
+
+import { getApiUrl } from "./base";
+
+// ============================================================================
+// Types
+// ============================================================================
+
+export interface BroadcastMember {
+  session_id: string;
+  enabled: boolean;
+}
+
+export interface BroadcastGroup {
+  id: string;
+  name: string;
+  members: BroadcastMember[];
+  created_at: string;
+}
+
+// Sent to a member's terminal as {"type": "broadcast", "data": {"groups": [...]}}
+export interface BroadcastMarker {
+  group_id: string;
+  name: string;
+  enabled: boolean;
+  members: number;
+}
+
+interface APIResponse<T> {
+  code: number;
+  message: string;
+  data?: T;
+}
+
+// ============================================================================
+// API Functions
+// ============================================================================
+
+async function request<T>(path: string, method: string, action: string, body?: unknown): Promise<T | undefined> {
+  const resp = await fetch(getApiUrl(path), {
+    method,
+    headers: body === undefined ? undefined : { "Content-Type": "application/json" },
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  const json = (await resp.json().catch(() => null)) as APIResponse<T> | null;
+  if (!resp.ok || !json || json.code !== 200) {
+    throw new Error(json?.message || `${action} failed: HTTP ${resp.status}`);
+  }
+  return json.data;
+}
+
+/**
+ * Create a broadcast group. Session IDs are terminal tab keys; the tabs need
+ * not be connected yet.
+ */
+export async function createBroadcastGroup(name: string, sessionIds: string[]): Promise<BroadcastGroup> {
+  const group = await request<BroadcastGroup>("/terminal/broadcast", "POST", "Create broadcast group", {
+    name,
+    session_ids: sessionIds,
+  });
+  if (!group) throw new Error("Create broadcast group: empty response");
+  return group;
+}
+
+/**
+ * Delete a broadcast group; its terminals stay open
+ */
+export async function deleteBroadcastGroup(groupId: string): Promise<void> {
+  await request(`/terminal/broadcast/${encodeURIComponent(groupId)}`, "DELETE", "Delete broadcast group");
+}
+
+/**
+ * Enable or disable a member of a broadcast group
+ */
+export async function setBroadcastMember(groupId: string, sessionId: string, enabled: boolean): Promise<void> {
+  await request(
+    `/terminal/broadcast/${encodeURIComponent(groupId)}/members/${encodeURIComponent(sessionId)}`,
+    "PUT",
+    "Update broadcast member",
+    { enabled },
+  );
+}
+
+/**
+ * Remove a terminal from a broadcast group
+ */
+export async function removeBroadcastMember(groupId: string, sessionId: string): Promise<void> {
+  await request(
+    `/terminal/broadcast/${encodeURIComponent(groupId)}/members/${encodeURIComponent(sessionId)}`,
+    "DELETE",
+    "Remove broadcast member",
+  );
+}
@@ -14,6 +14,7 @@ export type MessagePartType =
   | "reasoning"
   | "tool_call"
   | "tool_result"
+  | "approval"
   | "image_url"
   | "audio_url"
   | "video_url"
@@ -31,6 +32,17 @@ export interface ToolResultPart {
   content: string;
 }
 
+export type ApprovalStatus = "pending" | "approved" | "rejected";
+
+export interface ApprovalPart {
+  tool_call_id: string;
+  name: string;
+  arguments?: string;
+  status: ApprovalStatus;
+  decided_by?: string; // Who approved or rejected the call
+  reason?: string;
+}
+
 export interface ImageURL {
   url: string;
   mime_type?: string;
@@ -64,6 +76,7 @@ export interface MessagePart {
   text?: string;
   tool_call?: ToolCallPart;
   tool_result?: ToolResultPart;
+  approval?: ApprovalPart;
   image_url?: ImageURL;
   audio_url?: AudioURL;
   video_url?: VideoURL;
@@ -186,6 +199,7 @@ export interface ChatCompletionChunk {
       reasoning_content?: string;
       agent_name?: string;
       run_path?: string[]; // Agent call path for multi-agent scenarios
+      approval?: ApprovalPart; // Tool call approval request or decision
     };
     finish_reason?: string;
   }>;
@@ -443,6 +457,28 @@ export async function cancelStream(conversationId: string): Promise<void> {
   }
 }
 
+/**
+ * Approve or reject a tool call waiting for approval
+ */
+export async function resolveToolApproval(
+  conversationId: string,
+  toolCallId: string,
+  approved: boolean,
+  reason?: string
+): Promise<void> {
+  const res = await fetch(
+    `${baseUrl}/api/v1/conversations/${encodeURIComponent(conversationId)}/approvals/${encodeURIComponent(toolCallId)}`,
+    {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ approved, reason }),
+    }
+  );
+  if (!res.ok) {
+    throw new Error(`Failed to resolve tool approval: ${res.statusText}`);
+  }
+}
+
 /**
  * Get the streaming status of a conversation
  */
@@ -22,8 +22,13 @@ export const Events = {
   ASSET_DELETED: "asset.deleted",
   // Tunnels
   TUNNEL_CREATED: "tunnel.created",
+  TUNNEL_UPDATED: "tunnel.updated",
   TUNNEL_STATUS_CHANGED: "tunnel.statusChanged",
   TUNNEL_DELETED: "tunnel.deleted",
+  TUNNEL_METRICS: "tunnel.metrics",
+  // Terminals
+  TERMINAL_BROADCAST_CHANGED: "terminal.broadcastChanged",
+  TERMINAL_TRIGGER: "terminal.trigger",
   // Containers
   CONTAINER_STATUS_CHANGED: "container.statusChanged",
   CONTAINER_LIST_CHANGED: "container.listChanged",
@@ -60,6 +65,29 @@ export interface TunnelEventData extends EventData {
   TunnelID: string;
   AssetID?: string;
   Status?: string;
+  Message?: string;
+}
+
+export interface TunnelMetricsEventData extends EventData {
+  TunnelID: string;
+  BytesSent: number;
+  BytesReceived: number;
+  Connections: number;
+  SendRate: number;
+  ReceiveRate: number;
+}
+
+export interface TerminalBroadcastEventData extends EventData {
+  GroupID: string;
+  SessionIDs?: string[];
+}
+
+export interface TerminalTriggerEventData extends EventData {
+  SessionID: string;
+  AssetID: string;
+  TriggerID: string;
+  Name: string;
+  Message: string;
 }
 
 export interface ContainerEventData extends EventData {
@@ -91,9 +119,9 @@ export const EventGroups = {
   FS: [Events.FS_CHANGED, Events.FS_CREATED, Events.FS_DELETED, Events.FS_RENAMED] as EventName[],
   ASSET: [Events.ASSET_CREATED, Events.ASSET_UPDATED, Events.ASSET_DELETED] as EventName[],
   // All tunnel events
-  TUNNEL: [Events.TUNNEL_CREATED, Events.TUNNEL_STATUS_CHANGED, Events.TUNNEL_DELETED] as EventName[],
-  // Only list-changing tunnel events (add/remove)
-  TUNNEL_LIST: [Events.TUNNEL_CREATED, Events.TUNNEL_DELETED] as EventName[],
+  TUNNEL: [Events.TUNNEL_CREATED, Events.TUNNEL_UPDATED, Events.TUNNEL_STATUS_CHANGED, Events.TUNNEL_DELETED] as EventName[],
+  // Only list-changing tunnel events (add/edit/remove)
+  TUNNEL_LIST: [Events.TUNNEL_CREATED, Events.TUNNEL_UPDATED, Events.TUNNEL_DELETED] as EventName[],
   // Only status change events (start/stop/error)
   TUNNEL_STATUS: [Events.TUNNEL_STATUS_CHANGED] as EventName[],
   CONTAINER: [Events.CONTAINER_STATUS_CHANGED, Events.CONTAINER_LIST_CHANGED] as EventName[],
@@ -0,0 +1,46 @@
+// Secrets API - status and master key of the encrypted secret vault
+
+import { getApiUrl } from "./base";
+
+export interface VaultStatus {
+  source: "" | "keyring" | "passphrase" | "file";
+  locked: boolean;
+  count: number;
+}
+
+interface APIResponse<T> {
+  code: number;
+  message: string;
+  data?: T;
+}
+
+async function request(path: string, init?: RequestInit): Promise<VaultStatus> {
+  const resp = await fetch(getApiUrl(path), {
+    ...init,
+    headers: init?.body ? { "Content-Type": "application/json" } : undefined,
+  });
+  const json = (await resp.json()) as APIResponse<VaultStatus>;
+  if (!resp.ok || json.code !== 200 || !json.data) {
+    throw new Error(json.message || `HTTP ${resp.status}`);
+  }
+  return json.data;
+}
+
+export function getVaultStatus(): Promise<VaultStatus> {
+  return request("/api/secrets/status");
+}
+
+export function unlockVault(passphrase: string): Promise<VaultStatus> {
+  return request("/api/secrets/unlock", {
+    method: "POST",
+    body: JSON.stringify({ passphrase }),
+  });
+}
+
+// An empty passphrase moves the master key back to the OS keyring
+export function setVaultPassphrase(current: string, passphrase: string): Promise<VaultStatus> {
+  return request("/api/secrets/passphrase", {
+    method: "PUT",
+    body: JSON.stringify({ current, passphrase }),
+  });
+}
@@ -0,0 +1,89 @@
+// Triggers API - HTTP API functions for rules run over terminal output
+
+import { getApiUrl } from "./base";
+
+// ============================================================================
+// Types
+// ============================================================================
+
+export type TriggerAction = "highlight" | "notify" | "respond" | "capture";
+
+export interface Trigger {
+  id: string;
+  asset_id: string; // empty applies to every asset
+  name: string;
+  pattern: string; // Go regular expression
+  action: TriggerAction;
+  enabled: boolean;
+  color?: string;
+  message?: string;
+  response?: string;
+  variable?: string;
+  cooldown?: number; // seconds
+  updated_at: string;
+}
+
+export type TriggerInput = Omit<Trigger, "id" | "updated_at">;
+
+interface APIResponse<T> {
+  code: number;
+  message: string;
+  data?: T;
+}
+
+// ============================================================================
+// API Functions
+// ============================================================================
+
+async function request<T>(path: string, method: string, action: string, body?: unknown): Promise<T | undefined> {
+  const resp = await fetch(getApiUrl(path), {
+    method,
+    headers: body === undefined ? undefined : { "Content-Type": "application/json" },
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  const json = (await resp.json().catch(() => null)) as APIResponse<T> | null;
+  if (!resp.ok || !json || json.code !== 200) {
+    throw new Error(json?.message || `${action} failed: HTTP ${resp.status}`);
+  }
+  return json.data;
+}
+
+/**
+ * List triggers, only those of the asset when given
+ */
+export async function listTriggers(assetId?: string): Promise<Trigger[]> {
+  const query = assetId ? `?asset_id=${encodeURIComponent(assetId)}` : "";
+  return (await request<Trigger[]>(`/api/triggers${query}`, "GET", "List triggers")) ?? [];
+}
+
+export async function createTrigger(input: TriggerInput): Promise<Trigger> {
+  const trigger = await request<Trigger>("/api/triggers", "POST", "Create trigger", input);
+  if (!trigger) throw new Error("Create trigger: empty response");
+  return trigger;
+}
+
+/**
+ * Replace a trigger
+ */
+export async function updateTrigger(id: string, input: TriggerInput): Promise<Trigger> {
+  const trigger = await request<Trigger>(`/api/triggers/${encodeURIComponent(id)}`, "PUT", "Update trigger", input);
+  if (!trigger) throw new Error("Update trigger: empty response");
+  return trigger;
+}
+
+export async function deleteTrigger(id: string): Promise<void> {
+  await request(`/api/triggers/${encodeURIComponent(id)}`, "DELETE", "Delete trigger");
+}
+
+/**
+ * Variables captured in a terminal session by capture triggers
+ */
+export async function getSessionVariables(sessionId: string): Promise<Record<string, string>> {
+  return (
+    (await request<Record<string, string>>(
+      `/terminal/sessions/${encodeURIComponent(sessionId)}/variables`,
+      "GET",
+      "Get session variables",
+    )) ?? {}
+  );
+}
@@ -15,12 +15,58 @@ export interface TunnelInfo {
   local_port: number;
   remote_host?: string;
   remote_port?: number;
-  status: "running" | "stopped" | "error";
+  status: "running" | "stopped" | "error" | "reconnecting";
   error_message?: string;
   bytes_sent?: number;
   bytes_received?: number;
   connections?: number;
   started_at?: string;
+  auto_start?: boolean;
+  restart_policy?: "never" | "on-failure" | "always";
+  restarts?: number;
+  // Set for standalone port forwards
+  target?: PortForwardTarget;
+  // From tunnel.metrics events, bytes per second
+  send_rate?: number;
+  receive_rate?: number;
+}
+
+// What a standalone port forward connects to: a host through an SSH asset,
+// a container on a Docker host, or a workspace's runtime
+export interface PortForwardTarget {
+  type: "ssh" | "docker" | "workspace";
+  asset_id?: string;
+  container?: string;
+  workspace_id?: string;
+}
+
+// Tunnel settings, as in an SSH asset's tunnels
+export interface PortForwardConfig {
+  id?: string;
+  type: "local" | "remote" | "dynamic";
+  local_host?: string;
+  local_port: number;
+  remote_host?: string;
+  remote_port?: number;
+  auto_start?: boolean;
+  restart_policy?: "never" | "on-failure" | "always";
+  health_check?: { interval?: number; timeout?: number };
+  proxy?: Record<string, unknown>;
+}
+
+export interface PortForward {
+  id: string;
+  name: string;
+  target: PortForwardTarget;
+  config: PortForwardConfig;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface PortForwardRequest {
+  name: string;
+  target: PortForwardTarget;
+  config: PortForwardConfig;
 }
 
 export interface TunnelStats {
@@ -28,10 +74,34 @@ export interface TunnelStats {
   running: number;
   stopped: number;
   error: number;
+  reconnecting: number;
   total_bytes_sent: number;
   total_bytes_received: number;
 }
 
+export interface TunnelConnection {
+  id: string;
+  client: string;
+  target: string;
+  bytes_sent: number;
+  bytes_received: number;
+  opened_at: string;
+  closed_at?: string;
+  error?: string;
+}
+
+export interface TunnelConnectionsResponse {
+  active: TunnelConnection[];
+  recent: TunnelConnection[];
+}
+
+export interface TunnelTrafficPoint {
+  time: string;
+  bytes_sent: number;
+  bytes_received: number;
+  connections: number;
+}
+
 export interface TunnelListResponse {
   tunnels: TunnelInfo[];
   stats: TunnelStats;
@@ -59,7 +129,7 @@ export async function listTunnels(): Promise<TunnelListResponse> {
   if (json.code !== 200) {
     throw new Error(json.message || "List tunnels failed");
   }
-  return json.data ?? { tunnels: [], stats: { total: 0, running: 0, stopped: 0, error: 0, total_bytes_sent: 0, total_bytes_received: 0 } };
+  return json.data ?? { tunnels: [], stats: { total: 0, running: 0, stopped: 0, error: 0, reconnecting: 0, total_bytes_sent: 0, total_bytes_received: 0 } };
 }
 
 /**
@@ -74,7 +144,7 @@ export async function getTunnelStats(): Promise<TunnelStats> {
   if (json.code !== 200) {
     throw new Error(json.message || "Get tunnel stats failed");
   }
-  return json.data ?? { total: 0, running: 0, stopped: 0, error: 0, total_bytes_sent: 0, total_bytes_received: 0 };
+  return json.data ?? { total: 0, running: 0, stopped: 0, error: 0, reconnecting: 0, total_bytes_sent: 0, total_bytes_received: 0 };
 }
 
 /**
@@ -109,3 +179,96 @@ export async function stopTunnel(tunnelId: string): Promise<void> {
   }
 }
 
+/**
+ * List a tunnel's open and recently closed connections
+ */
+export async function listTunnelConnections(tunnelId: string): Promise<TunnelConnectionsResponse> {
+  const resp = await fetch(getApiUrl(`/api/tunnels/${tunnelId}/connections`));
+  if (!resp.ok) {
+    throw new Error(`List tunnel connections failed: HTTP ${resp.status}`);
+  }
+  const json = (await resp.json()) as APIResponse<TunnelConnectionsResponse>;
+  if (json.code !== 200) {
+    throw new Error(json.message || "List tunnel connections failed");
+  }
+  return json.data ?? { active: [], recent: [] };
+}
+
+/**
+ * Force-close an open tunnel connection
+ */
+export async function closeTunnelConnection(tunnelId: string, connId: string): Promise<void> {
+  const resp = await fetch(getApiUrl(`/api/tunnels/${tunnelId}/connections/${connId}`), {
+    method: "DELETE",
+  });
+  if (!resp.ok) {
+    throw new Error(`Close tunnel connection failed: HTTP ${resp.status}`);
+  }
+  const json = (await resp.json()) as APIResponse<unknown>;
+  if (json.code !== 200) {
+    throw new Error(json.message || "Close tunnel connection failed");
+  }
+}
+
+/**
+ * Get a tunnel's traffic per minute over the last minutes, oldest first
+ */
+export async function getTunnelHistory(tunnelId: string, minutes = 60): Promise<TunnelTrafficPoint[]> {
+  const resp = await fetch(getApiUrl(`/api/tunnels/${tunnelId}/history?minutes=${minutes}`));
+  if (!resp.ok) {
+    throw new Error(`Get tunnel history failed: HTTP ${resp.status}`);
+  }
+  const json = (await resp.json()) as APIResponse<TunnelTrafficPoint[]>;
+  if (json.code !== 200) {
+    throw new Error(json.message || "Get tunnel history failed");
+  }
+  return json.data ?? [];
+}
+
+/**
+ * Get a standalone port forward
+ */
+export async function getPortForward(tunnelId: string): Promise<PortForward> {
+  const resp = await fetch(getApiUrl(`/api/tunnels/${tunnelId}`));
+  if (!resp.ok) {
+    throw new Error(`Get port forward failed: HTTP ${resp.status}`);
+  }
+  const json = (await resp.json()) as APIResponse<PortForward>;
+  if (json.code !== 200 || !json.data) {
+    throw new Error(json.message || "Get port forward failed");
+  }
+  return json.data;
+}
+
+/**
+ * Create a standalone port forward, or replace one's settings when tunnelId
+ * is given. Validation errors are thrown with the server's message.
+ */
+export async function savePortForward(req: PortForwardRequest, tunnelId?: string): Promise<PortForward> {
+  const resp = await fetch(getApiUrl(tunnelId ? `/api/tunnels/${tunnelId}` : "/api/tunnels"), {
+    method: tunnelId ? "PUT" : "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(req),
+  });
+  const json = (await resp.json().catch(() => null)) as APIResponse<PortForward> | null;
+  if (!resp.ok || !json || json.code !== 200 || !json.data) {
+    throw new Error(json?.message || `Save port forward failed: HTTP ${resp.status}`);
+  }
+  return json.data;
+}
+
+/**
+ * Stop and delete a standalone port forward
+ */
+export async function deletePortForward(tunnelId: string): Promise<void> {
+  const resp = await fetch(getApiUrl(`/api/tunnels/${tunnelId}`), {
+    method: "DELETE",
+  });
+  if (!resp.ok) {
+    throw new Error(`Delete port forward failed: HTTP ${resp.status}`);
+  }
+  const json = (await resp.json()) as APIResponse<unknown>;
+  if (json.code !== 200) {
+    throw new Error(json.message || "Delete port forward failed");
+  }
+}
@@ -66,6 +66,9 @@ export interface Workspace {
   embedding_model?: string;
   embedding_dimension?: number;
   extraction_model?: string;
+  // Tool approval configuration
+  tool_approval_mode?: "always" | "dangerous" | "never" | "allowlist";
+  tool_approval_allowlist?: string[];
   // Relations
   runtime?: WorkspaceRuntime;
   assets?: WorkspaceAssetRef[];
@@ -99,6 +102,9 @@ export interface CreateWorkspaceRequest {
   embedding_model?: string;
   embedding_dimension?: number;
   extraction_model?: string;
+  // Tool approval configuration
+  tool_approval_mode?: "always" | "dangerous" | "never" | "allowlist";
+  tool_approval_allowlist?: string[];
   // Runtime and relations
   runtime?: {
     type: "local" | "docker-local" | "docker-remote";
@@ -150,6 +156,9 @@ export interface UpdateWorkspaceRequest {
   embedding_model?: string;
   embedding_dimension?: number;
   extraction_model?: string;
+  // Tool approval configuration
+  tool_approval_mode?: "always" | "dangerous" | "never" | "allowlist";
+  tool_approval_allowlist?: string[];
   // Relations
   runtime?: CreateWorkspaceRequest["runtime"];
   assets?: CreateWorkspaceRequest["assets"];
@@ -0,0 +1,254 @@
+// Port Forward Dialog - Create or edit a standalone port forward
+// Targets a host through an SSH asset, a container on a Docker host, or a
+// workspace runtime; containers only take local forwards to their IP
+
+import React, { useEffect, useState } from "react";
+import {
+  Dialog,
+  DialogTitle,
+  DialogContent,
+  DialogActions,
+  Button,
+  TextField,
+  MenuItem,
+  Box,
+  FormControlLabel,
+  Checkbox,
+  Alert,
+} from "@mui/material";
+import { useQuery } from "@tanstack/react-query";
+
+import { useAssetList, useSavePortForward } from "../stores";
+import { getPortForward } from "../api/tunnels";
+import type { PortForwardTarget, PortForwardConfig } from "../api/tunnels";
+import { listWorkspaces } from "../api/workspaces";
+
+interface PortForwardDialogProps {
+  open: boolean;
+  tunnelId?: string; // edit this port forward, else create one
+  onClose: () => void;
+}
+
+const emptyConfig: PortForwardConfig = {
+  type: "local",
+  local_host: "127.0.0.1",
+  local_port: 0,
+  remote_host: "",
+  remote_port: 0,
+  auto_start: false,
+  restart_policy: "never",
+};
+
+const PortForwardDialog: React.FC<PortForwardDialogProps> = ({ open, tunnelId, onClose }) => {
+  const [name, setName] = useState("");
+  const [target, setTarget] = useState<PortForwardTarget>({ type: "ssh" });
+  const [config, setConfig] = useState<PortForwardConfig>(emptyConfig);
+  const [error, setError] = useState<string | null>(null);
+
+  const { data: assets = [] } = useAssetList();
+  const { data: workspaces = [] } = useQuery({
+    queryKey: ["workspaces", "list"],
+    queryFn: () => listWorkspaces(),
+    enabled: open && target.type === "workspace",
+  });
+  const { data: existing } = useQuery({
+    queryKey: ["tunnels", "portForward", tunnelId],
+    queryFn: () => getPortForward(tunnelId!),
+    enabled: open && !!tunnelId,
+  });
+  const saveMutation = useSavePortForward();
+
+  useEffect(() => {
+    if (!open) return;
+    setError(null);
+    if (tunnelId && existing) {
+      setName(existing.name);
+      setTarget(existing.target);
+      setConfig({ ...emptyConfig, ...existing.config });
+    } else if (!tunnelId) {
+      setName("");
+      setTarget({ type: "ssh" });
+      setConfig(emptyConfig);
+    }
+  }, [open, tunnelId, existing]);
+
+  const container = target.type !== "ssh";
+  const assetType = target.type === "docker" ? "docker_host" : "ssh";
+  const targetAssets = assets.filter((a) => a.type === assetType);
+
+  const setTargetType = (type: PortForwardTarget["type"]) => {
+    setTarget({ type });
+    if (type !== "ssh") {
+      setConfig((c) => ({ ...c, type: "local" }));
+    }
+  };
+
+  const handleSave = () => {
+    setError(null);
+    saveMutation.mutate(
+      {
+        req: {
+          name: name.trim(),
+          target,
+          config: container ? { ...config, remote_host: "" } : config,
+        },
+        tunnelId,
+      },
+      {
+        onSuccess: () => onClose(),
+        onError: (e) => setError((e as Error).message),
+      },
+    );
+  };
+
+  const portField = (label: string, key: "local_port" | "remote_port") => (
+    <TextField
+      size="small"
+      label={label}
+      type="number"
+      value={config[key] || ""}
+      onChange={(e) => setConfig({ ...config, [key]: parseInt(e.target.value, 10) || 0 })}
+      sx={{ width: 120 }}
+    />
+  );
+
+  return (
+    <Dialog open={open} onClose={onClose} maxWidth="sm" fullWidth>
+      <DialogTitle sx={{ py: 1.5 }}>{tunnelId ? "Edit Port Forward" : "New Port Forward"}</DialogTitle>
+      <DialogContent dividers sx={{ display: "flex", flexDirection: "column", gap: 2 }}>
+        {error && <Alert severity="error">{error}</Alert>}
+        <TextField size="small" label="Name" value={name} onChange={(e) => setName(e.target.value)} autoFocus />
+
+        <Box sx={{ display: "flex", gap: 1 }}>
+          <TextField
+            select
+            size="small"
+            label="Target"
+            value={target.type}
+            onChange={(e) => setTargetType(e.target.value as PortForwardTarget["type"])}
+            sx={{ width: 140 }}
+          >
+            <MenuItem value="ssh">SSH host</MenuItem>
+            <MenuItem value="docker">Container</MenuItem>
+            <MenuItem value="workspace">Workspace</MenuItem>
+          </TextField>
+          {target.type === "workspace" ? (
+            <TextField
+              select
+              size="small"
+              label="Workspace"
+              value={target.workspace_id ?? ""}
+              onChange={(e) => setTarget({ ...target, workspace_id: e.target.value })}
+              sx={{ flex: 1 }}
+            >
+              {workspaces.map((w) => (
+                <MenuItem key={w.id} value={w.id}>
+                  {w.name} ({w.runtime_type})
+                </MenuItem>
+              ))}
+            </TextField>
+          ) : (
+            <TextField
+              select
+              size="small"
+              label={target.type === "docker" ? "Docker host" : "SSH asset"}
+              value={target.asset_id ?? ""}
+              onChange={(e) => setTarget({ ...target, asset_id: e.target.value })}
+              sx={{ flex: 1 }}
+            >
+              {targetAssets.map((a) => (
+                <MenuItem key={a.id} value={a.id}>
+                  {a.name}
+                </MenuItem>
+              ))}
+            </TextField>
+          )}
+          {target.type === "docker" && (
+            <TextField
+              size="small"
+              label="Container"
+              placeholder="name or ID"
+              value={target.container ?? ""}
+              onChange={(e) => setTarget({ ...target, container: e.target.value })}
+              sx={{ flex: 1 }}
+            />
+          )}
+        </Box>
+
+        <Box sx={{ display: "flex", gap: 1 }}>
+          <TextField
+            select
+            size="small"
+            label="Type"
+            value={config.type}
+            disabled={container}
+            onChange={(e) => setConfig({ ...config, type: e.target.value as PortForwardConfig["type"] })}
+            sx={{ width: 120 }}
+          >
+            <MenuItem value="local">Local</MenuItem>
+            <MenuItem value="remote">Remote</MenuItem>
+            <MenuItem value="dynamic">Dynamic</MenuItem>
+          </TextField>
+          <TextField
+            size="small"
+            label="Local host"
+            value={config.local_host ?? ""}
+            onChange={(e) => setConfig({ ...config, local_host: e.target.value })}
+            sx={{ flex: 1 }}
+          />
+          {portField("Local port", "local_port")}
+        </Box>
+
+        {config.type !== "dynamic" && (
+          <Box sx={{ display: "flex", gap: 1 }}>
+            <TextField
+              size="small"
+              label="Remote host"
+              value={container ? "" : config.remote_host ?? ""}
+              placeholder={container ? "container IP, looked up on start" : ""}
+              disabled={container}
+              onChange={(e) => setConfig({ ...config, remote_host: e.target.value })}
+              sx={{ flex: 1 }}
+            />
+            {portField(container ? "Container port" : "Remote port", "remote_port")}
+          </Box>
+        )}
+
+        <Box sx={{ display: "flex", gap: 1, alignItems: "center" }}>
+          <TextField
+            select
+            size="small"
+            label="Restart"
+            value={config.restart_policy ?? "never"}
+            onChange={(e) =>
+              setConfig({ ...config, restart_policy: e.target.value as PortForwardConfig["restart_policy"] })
+            }
+            sx={{ width: 160 }}
+          >
+            <MenuItem value="never">Never</MenuItem>
+            <MenuItem value="on-failure">On failure</MenuItem>
+            <MenuItem value="always">Always</MenuItem>
+          </TextField>
+          <FormControlLabel
+            control={
+              <Checkbox
+                size="small"
+                checked={!!config.auto_start}
+                onChange={(e) => setConfig({ ...config, auto_start: e.target.checked })}
+              />
+            }
+            label="Start with the app"
+          />
+        </Box>
+      </DialogContent>
+      <DialogActions>
+        <Button onClick={onClose}>Cancel</Button>
+        <Button variant="contained" onClick={handleSave} disabled={!name.trim() || saveMutation.isPending}>
+          {tunnelId ? "Save" : "Create"}
+        </Button>
+      </DialogActions>
+    </Dialog>
+  );
+};
+
+export default PortForwardDialog;
@@ -0,0 +1,169 @@
+// Tunnel Connections - Dialog showing a tunnel's connections and traffic history
+// Connections are refreshed by tunnel.metrics events, history every minute
+
+import React from "react";
+import {
+  Dialog,
+  DialogTitle,
+  DialogContent,
+  Box,
+  Typography,
+  IconButton,
+  Table,
+  TableBody,
+  TableCell,
+  TableContainer,
+  TableHead,
+  TableRow,
+  Tooltip,
+  CircularProgress,
+} from "@mui/material";
+import CloseIcon from "@mui/icons-material/Close";
+import LinkOffIcon from "@mui/icons-material/LinkOff";
+
+import {
+  useTunnelConnections,
+  useTunnelHistory,
+  useCloseTunnelConnection,
+} from "../stores";
+import type { TunnelInfo, TunnelConnection, TunnelTrafficPoint } from "../api/tunnels";
+
+interface TunnelConnectionsProps {
+  tunnel: TunnelInfo | null;
+  onClose: () => void;
+  formatBytes: (bytes: number) => string;
+}
+
+// Bar chart of the traffic per minute, sent above the axis and received below
+const TrafficHistory: React.FC<{
+  points: TunnelTrafficPoint[];
+  formatBytes: (bytes: number) => string;
+}> = ({ points, formatBytes }) => {
+  const max = Math.max(1, ...points.map((p) => Math.max(p.bytes_sent, p.bytes_received)));
+  return (
+    <Box sx={{ display: "flex", alignItems: "stretch", height: 64, gap: "1px" }}>
+      {points.map((p) => (
+        <Tooltip
+          key={p.time}
+          title={`${new Date(p.time).toLocaleTimeString()} ↑${formatBytes(p.bytes_sent)} ↓${formatBytes(p.bytes_received)} · ${p.connections} conn`}
+        >
+          <Box sx={{ flex: 1, display: "flex", flexDirection: "column" }}>
+            <Box sx={{ flex: 1, display: "flex", alignItems: "flex-end" }}>
+              <Box sx={{ width: "100%", height: `${(p.bytes_sent / max) * 100}%`, bgcolor: "primary.main" }} />
+            </Box>
+            <Box sx={{ flex: 1 }}>
+              <Box sx={{ width: "100%", height: `${(p.bytes_received / max) * 100}%`, bgcolor: "success.main" }} />
+            </Box>
+          </Box>
+        </Tooltip>
+      ))}
+    </Box>
+  );
+};
+
+const TunnelConnections: React.FC<TunnelConnectionsProps> = ({ tunnel, onClose, formatBytes }) => {
+  const tunnelId = tunnel?.id ?? null;
+  const { data: connections, isLoading } = useTunnelConnections(tunnelId);
+  const { data: history } = useTunnelHistory(tunnelId);
+  const closeMutation = useCloseTunnelConnection();
+
+  const renderRow = (conn: TunnelConnection, active: boolean) => (
+    <TableRow key={`${active ? "a" : "r"}-${conn.id}`} hover>
+      <TableCell sx={{ fontFamily: "monospace", fontSize: 12 }}>{conn.client}</TableCell>
+      <TableCell sx={{ fontFamily: "monospace", fontSize: 12 }}>{conn.target || "-"}</TableCell>
+      <TableCell>
+        <Typography variant="caption" sx={{ fontSize: 11, whiteSpace: "nowrap" }}>
+          ↑{formatBytes(conn.bytes_sent)} ↓{formatBytes(conn.bytes_received)}
+        </Typography>
+      </TableCell>
+      <TableCell>
+        <Typography variant="caption" sx={{ fontSize: 11 }}>
+          {new Date(conn.opened_at).toLocaleTimeString()}
+          {conn.closed_at && ` – ${new Date(conn.closed_at).toLocaleTimeString()}`}
+        </Typography>
+      </TableCell>
+      <TableCell align="right">
+        {active ? (
+          <Tooltip title="Close connection">
+            <IconButton
+              size="small"
+              onClick={() => tunnelId && closeMutation.mutate({ tunnelId, connId: conn.id })}
+            >
+              <LinkOffIcon fontSize="small" />
+            </IconButton>
+          </Tooltip>
+        ) : (
+          conn.error && (
+            <Typography variant="caption" color="error.main" sx={{ fontSize: 11 }}>
+              {conn.error}
+            </Typography>
+          )
+        )}
+      </TableCell>
+    </TableRow>
+  );
+
+  const active = connections?.active ?? [];
+  const recent = [...(connections?.recent ?? [])].reverse();
+
+  return (
+    <Dialog open={!!tunnel} onClose={onClose} maxWidth="md" fullWidth>
+      <DialogTitle sx={{ display: "flex", alignItems: "center", py: 1.5 }}>
+        <Typography variant="h6" component="span" sx={{ flex: 1 }}>
+          {tunnel?.asset_name} · {tunnel ? `${tunnel.local_host}:${tunnel.local_port}` : ""}
+        </Typography>
+        {tunnel?.send_rate !== undefined && (
+          <Typography variant="caption" color="text.secondary" sx={{ mr: 1 }}>
+            ↑{formatBytes(tunnel.send_rate)}/s ↓{formatBytes(tunnel.receive_rate ?? 0)}/s
+          </Typography>
+        )}
+        <IconButton size="small" onClick={onClose}>
+          <CloseIcon fontSize="small" />
+        </IconButton>
+      </DialogTitle>
+      <DialogContent dividers sx={{ p: 0 }}>
+        <Box sx={{ px: 2, py: 1, borderBottom: "1px solid", borderColor: "divider" }}>
+          <Typography variant="caption" color="text.secondary">
+            Traffic, last hour
+          </Typography>
+          <TrafficHistory points={history ?? []} formatBytes={formatBytes} />
+        </Box>
+        <TableContainer>
+          <Table size="small">
+            <TableHead>
+              <TableRow>
+                <TableCell>Client</TableCell>
+                <TableCell>Target</TableCell>
+                <TableCell sx={{ width: 120 }}>Traffic</TableCell>
+                <TableCell sx={{ width: 160 }}>Time</TableCell>
+                <TableCell sx={{ width: 120 }} align="right" />
+              </TableRow>
+            </TableHead>
+            <TableBody>
+              {isLoading ? (
+                <TableRow>
+                  <TableCell colSpan={5} align="center" sx={{ py: 4 }}>
+                    <CircularProgress size={24} />
+                  </TableCell>
+                </TableRow>
+              ) : active.length === 0 && recent.length === 0 ? (
+                <TableRow>
+                  <TableCell colSpan={5} align="center" sx={{ py: 4 }}>
+                    <Typography color="text.secondary">No connections</Typography>
+                  </TableCell>
+                </TableRow>
+              ) : (
+                <>
+                  {active.map((conn) => renderRow(conn, true))}
+                  {recent.map((conn) => renderRow(conn, false))}
+                </>
+              )}
+            </TableBody>
+          </Table>
+        </TableContainer>
+      </DialogContent>
+    </Dialog>
+  );
+};
+
+export default TunnelConnections;
@@ -1,7 +1,7 @@
-// Tunnel Manager - Dialog for managing SSH tunnels
+// Tunnel Manager - Dialog for managing SSH tunnels and standalone port forwards
 // Uses TanStack Query for data fetching with event-driven updates
 
-import React from "react";
+import React, { useState } from "react";
 import {
   Dialog,
   DialogTitle,
@@ -25,14 +25,21 @@ import PlayArrowIcon from "@mui/icons-material/PlayArrow";
 import StopIcon from "@mui/icons-material/Stop";
 import RefreshIcon from "@mui/icons-material/Refresh";
 import SwapHorizIcon from "@mui/icons-material/SwapHoriz";
+import LanIcon from "@mui/icons-material/Lan";
+import AddIcon from "@mui/icons-material/Add";
+import EditIcon from "@mui/icons-material/Edit";
+import DeleteIcon from "@mui/icons-material/Delete";
 
 import {
   useTunnels,
   useStartTunnel,
   useStopTunnel,
   useInvalidateTunnels,
+  useDeletePortForward,
 } from "../stores";
 import type { TunnelInfo } from "../api/tunnels";
+import TunnelConnections from "./TunnelConnections";
+import PortForwardDialog from "./PortForwardDialog";
 
 // Re-export types for backward compatibility
 export type { TunnelInfo, TunnelStats } from "../api/tunnels";
@@ -69,6 +76,11 @@ const TunnelManager: React.FC<TunnelManagerProps> = ({ open, onClose }) => {
   // Use TanStack Query for data
   const { tunnels, stats, loading } = useTunnels();
   const invalidate = useInvalidateTunnels();
+  const [inspectedId, setInspectedId] = useState<string | null>(null);
+  const inspected = tunnels.find((t) => t.id === inspectedId) ?? null;
+  // Port forward being edited; "" for a new one
+  const [editingId, setEditingId] = useState<string | null>(null);
+  const deleteMutation = useDeletePortForward();
 
   // Mutations for start/stop - invalidate on error to show error in status tooltip
   const startMutation = useStartTunnel();
@@ -104,6 +116,8 @@ const TunnelManager: React.FC<TunnelManagerProps> = ({ open, onClose }) => {
         return "default";
       case "error":
         return "error";
+      case "reconnecting":
+        return "warning";
       default:
         return "default";
     }
@@ -114,12 +128,26 @@ const TunnelManager: React.FC<TunnelManagerProps> = ({ open, onClose }) => {
       return `${tunnel.local_host}:${tunnel.local_port}`;
     }
     if (tunnel.type === "local") {
-      return `${tunnel.local_host}:${tunnel.local_port} → ${tunnel.remote_host}:${tunnel.remote_port}`;
+      // Containers are reached on their IP, looked up on start
+      const remote =
+        tunnel.target?.type === "docker"
+          ? tunnel.target.container
+          : tunnel.target?.type === "workspace"
+            ? "workspace"
+            : tunnel.remote_host;
+      return `${tunnel.local_host}:${tunnel.local_port} → ${remote}:${tunnel.remote_port}`;
     }
     // remote
     return `${tunnel.remote_host}:${tunnel.remote_port} → ${tunnel.local_host}:${tunnel.local_port}`;
   };
 
+  const handleDelete = (tunnel: TunnelInfo) => {
+    if (!window.confirm(`Delete port forward "${tunnel.asset_name}"?`)) return;
+    deleteMutation.mutate(tunnel.id, {
+      onError: () => invalidate(),
+    });
+  };
+
   const getTypeLabel = (type: string) => {
     switch (type) {
       case "local":
@@ -140,6 +168,11 @@ const TunnelManager: React.FC<TunnelManagerProps> = ({ open, onClose }) => {
         <Typography variant="h6" component="span" sx={{ flex: 1 }}>
           Tunnel Manager
         </Typography>
+        <Tooltip title="New port forward">
+          <IconButton size="small" onClick={() => setEditingId("")} sx={{ mr: 1 }}>
+            <AddIcon fontSize="small" />
+          </IconButton>
+        </Tooltip>
         <IconButton size="small" onClick={() => invalidate()} disabled={loading}>
           <RefreshIcon fontSize="small" />
         </IconButton>
@@ -192,6 +225,16 @@ const TunnelManager: React.FC<TunnelManagerProps> = ({ open, onClose }) => {
               Error
             </Typography>
           </Box>
+          {stats.reconnecting > 0 && (
+            <Box sx={{ display: "flex", alignItems: "baseline", gap: 0.5 }}>
+              <Typography variant="body2" fontWeight={600} color="warning.main">
+                {stats.reconnecting}
+              </Typography>
+              <Typography variant="caption" color="text.secondary">
+                Reconnecting
+              </Typography>
+            </Box>
+          )}
           <Box sx={{ ml: "auto", display: "flex", alignItems: "baseline", gap: 0.5 }}>
             <Typography variant="caption" color="text.secondary">
               Traffic
@@ -213,7 +256,7 @@ const TunnelManager: React.FC<TunnelManagerProps> = ({ open, onClose }) => {
                 <TableCell sx={{ width: 80 }}>Status</TableCell>
                 <TableCell sx={{ width: 100 }}>Traffic</TableCell>
                 <TableCell sx={{ width: 80 }}>Uptime</TableCell>
-                <TableCell sx={{ width: 80 }} align="right">
+                <TableCell sx={{ width: 160 }} align="right">
                   Actions
                 </TableCell>
               </TableRow>
@@ -278,16 +321,40 @@ const TunnelManager: React.FC<TunnelManagerProps> = ({ open, onClose }) => {
                       <Typography variant="caption" sx={{ fontSize: 11, whiteSpace: "nowrap" }}>
                         ↑{formatBytes(tunnel.bytes_sent || 0)} ↓{formatBytes(tunnel.bytes_received || 0)}
                       </Typography>
+                      {!!tunnel.connections && (
+                        <Typography variant="caption" color="text.secondary" sx={{ fontSize: 11, display: "block" }}>
+                          {tunnel.connections} conn
+                        </Typography>
+                      )}
                     </TableCell>
                     <TableCell>
                       <Typography variant="body2" sx={{ fontSize: 11 }}>
                         {formatDuration(tunnel.started_at)}
                       </Typography>
                     </TableCell>
-                    <TableCell align="right">
+                    <TableCell align="right" sx={{ whiteSpace: "nowrap" }}>
+                      {tunnel.target && (
+                        <>
+                          <Tooltip title="Edit">
+                            <IconButton size="small" onClick={() => setEditingId(tunnel.id)}>
+                              <EditIcon fontSize="small" />
+                            </IconButton>
+                          </Tooltip>
+                          <Tooltip title="Delete">
+                            <IconButton size="small" onClick={() => handleDelete(tunnel)}>
+                              <DeleteIcon fontSize="small" />
+                            </IconButton>
+                          </Tooltip>
+                        </>
+                      )}
+                      <Tooltip title="Connections">
+                        <IconButton size="small" onClick={() => setInspectedId(tunnel.id)}>
+                          <LanIcon fontSize="small" />
+                        </IconButton>
+                      </Tooltip>
                       {actionLoading === tunnel.id ? (
                         <CircularProgress size={16} />
-                      ) : tunnel.status === "running" ? (
+                      ) : tunnel.status === "running" || tunnel.status === "reconnecting" ? (
                         <Tooltip title="Stop">
                           <IconButton
                             size="small"
@@ -315,6 +382,16 @@ const TunnelManager: React.FC<TunnelManagerProps> = ({ open, onClose }) => {
           </Table>
         </TableContainer>
       </DialogContent>
+      <TunnelConnections
+        tunnel={inspected}
+        onClose={() => setInspectedId(null)}
+        formatBytes={formatBytes}
+      />
+      <PortForwardDialog
+        open={editingId !== null}
+        tunnelId={editingId || undefined}
+        onClose={() => setEditingId(null)}
+      />
     </Dialog>
   );
 };
@@ -239,10 +239,12 @@ const AssetPage = React.forwardRef<AssetPageHandle, AssetPageProps>(
     // Listen to asset connect event
     useEffect(() => {
       const handler = (e: Event) => {
-        const { asset, node } = (e as CustomEvent).detail;
+        // key is set when the caller needs the session ID up front, as for
+        // a broadcast group
+        const { asset, node, key: presetKey } = (e as CustomEvent).detail;
         const timestamp = Date.now();
         const rand = Math.floor(Math.random() * 10000);
-        const key = `host-${asset.id}-${timestamp}-${rand}`;
+        const key = presetKey || `host-${asset.id}-${timestamp}-${rand}`;
         setTabs((prev) => {
           const same = prev.filter(
             (t) => t.assetId === asset.id && t.key !== "welcome",
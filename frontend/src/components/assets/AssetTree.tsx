@@ -38,6 +38,7 @@ import OpenInNewIcon from "@mui/icons-material/OpenInNew";
 import DeleteIcon from "@mui/icons-material/Delete";
 import SearchIcon from "@mui/icons-material/Search";
 import ClearIcon from "@mui/icons-material/Clear";
+import PodcastsIcon from "@mui/icons-material/Podcasts";
 import Popover from "@mui/material/Popover";
 import AddHostDialog from "./AddHostDialog.tsx";
 import { useAssets } from "../../stores";
@@ -46,12 +47,14 @@ import {
   deleteAsset,
   updateAsset,
   moveAsset,
+  listAssets,
   listDockerContainers,
   containerAction,
   type Asset,
   type ContainerInfo,
   type MoveAssetRequest,
 } from "../../api/assets";
+import { createBroadcastGroup } from "../../api/broadcast";
 
 
 // HostNode for tree view
@@ -773,6 +776,38 @@ const AssetTree: React.FC<AssetsTreeProps> = () => {
       return false;
     };
 
+    // Connect to every host under a folder, typing into all of them at once
+    const handleConnectFolder = async () => {
+      const folder = contextNode?.asset;
+      closeMenu();
+      if (!folder) return;
+      try {
+        const hosts = (await listAssets(folder.id)).filter(
+          (a) => a.type === "ssh" || a.type === "local",
+        );
+        if (hosts.length === 0) return;
+        const keys = hosts.map(
+          (a) => `host-${a.id}-${Date.now()}-${Math.floor(Math.random() * 10000)}`,
+        );
+        // Group the tabs first so each terminal shows its marker on connect
+        await createBroadcastGroup(folder.name, keys);
+        hosts.forEach((asset, i) => {
+          const info = extractHostInfo(asset);
+          window.dispatchEvent(
+            new CustomEvent("asset-connect", {
+              detail: {
+                asset,
+                node: { ip: info?.host, port: info?.port },
+                key: keys[i],
+              },
+            }),
+          );
+        });
+      } catch (e) {
+        console.error("Failed to connect folder:", e);
+      }
+    };
+
     // Context menu capability checks
     const canConnect =
       !!contextNode?.asset && contextNode.asset.type !== "folder" && contextNode.asset.type !== "docker_host";
@@ -781,6 +816,7 @@ const AssetTree: React.FC<AssetsTreeProps> = () => {
     const canDelete = !!contextNode?.asset || !!contextNode?.containerInfo;
     const canAddHostHere = contextNode?.asset?.type === "folder";
     const canAddFolderHere = contextNode?.asset?.type === "folder";
+    const canConnectFolder = contextNode?.asset?.type === "folder";
     const isDockerHost = contextNode?.asset?.type === "docker_host";
     const isContainer = !!contextNode?.containerInfo;
     const containerRunning = contextNode?.containerInfo?.state === "running";
@@ -1154,6 +1190,13 @@ const AssetTree: React.FC<AssetsTreeProps> = () => {
               Connect
             </MenuItem>
           )}
+          {/* Folder: connect all hosts as a broadcast group */}
+          {canConnectFolder && (
+            <MenuItem onClick={handleConnectFolder}>
+              <PodcastsIcon fontSize="small" style={{ marginRight: 8 }} />
+              Connect All (Broadcast)
+            </MenuItem>
+          )}
           {/* Container connect */}
           {canConnectContainer && (
             <MenuItem
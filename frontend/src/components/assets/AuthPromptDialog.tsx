@@ -0,0 +1,80 @@
+import React, { useEffect, useState } from "react";
+import {
+  Button,
+  Dialog,
+  DialogActions,
+  DialogContent,
+  DialogTitle,
+  TextField,
+  Typography,
+} from "@mui/material";
+
+// Keyboard-interactive challenge from an SSH server, e.g. an OTP prompt
+export interface AuthPrompt {
+  user: string;
+  host: string;
+  name: string;
+  instruction: string;
+  questions: { prompt: string; echo: boolean }[];
+}
+
+interface AuthPromptDialogProps {
+  prompt: AuthPrompt | null;
+  // answers is null when the user cancels
+  onAnswer: (answers: string[] | null) => void;
+}
+
+const AuthPromptDialog: React.FC<AuthPromptDialogProps> = ({ prompt, onAnswer }) => {
+  const [answers, setAnswers] = useState<string[]>([]);
+
+  useEffect(() => {
+    setAnswers(prompt ? prompt.questions.map(() => "") : []);
+  }, [prompt]);
+
+  const submit = (e: React.FormEvent) => {
+    e.preventDefault();
+    onAnswer(answers);
+  };
+
+  return (
+    <Dialog open={!!prompt} onClose={() => onAnswer(null)} maxWidth="xs" fullWidth>
+      <form onSubmit={submit}>
+        <DialogTitle>{prompt?.name || "Authentication required"}</DialogTitle>
+        <DialogContent>
+          <Typography variant="body2" color="text.secondary" gutterBottom>
+            {prompt?.user}@{prompt?.host}
+          </Typography>
+          {prompt?.instruction && (
+            <Typography variant="body2" gutterBottom sx={{ whiteSpace: "pre-wrap" }}>
+              {prompt.instruction}
+            </Typography>
+          )}
+          {prompt?.questions.map((q, i) => (
+            <TextField
+              key={i}
+              autoFocus={i === 0}
+              fullWidth
+              size="small"
+              margin="dense"
+              label={q.prompt.trim()}
+              type={q.echo ? "text" : "password"}
+              autoComplete={q.echo ? "off" : "one-time-code"}
+              value={answers[i] ?? ""}
+              onChange={(e) =>
+                setAnswers((prev) => prev.map((a, j) => (j === i ? e.target.value : a)))
+              }
+            />
+          ))}
+        </DialogContent>
+        <DialogActions>
+          <Button onClick={() => onAnswer(null)}>Cancel</Button>
+          <Button type="submit" variant="contained">
+            Continue
+          </Button>
+        </DialogActions>
+      </form>
+    </Dialog>
+  );
+};
+
+export default AuthPromptDialog;
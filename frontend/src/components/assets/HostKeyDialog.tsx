@@ -0,0 +1,52 @@
+import React from "react";
+import {
+  Button,
+  Dialog,
+  DialogActions,
+  DialogContent,
+  DialogTitle,
+  Typography,
+} from "@mui/material";
+
+// Host key presented by a server the backend doesn't know yet
+export interface HostKeyPrompt {
+  host: string;
+  key_type: string;
+  fingerprint: string;
+  public_key: string;
+  status: string;
+}
+
+interface HostKeyDialogProps {
+  prompt: HostKeyPrompt | null;
+  onAnswer: (accept: boolean) => void;
+}
+
+const HostKeyDialog: React.FC<HostKeyDialogProps> = ({ prompt, onAnswer }) => (
+  <Dialog open={!!prompt} onClose={() => onAnswer(false)} maxWidth="sm" fullWidth>
+    <DialogTitle>Unknown host key</DialogTitle>
+    <DialogContent>
+      <Typography variant="body2" gutterBottom>
+        The authenticity of host <b>{prompt?.host}</b> can't be established.
+        Verify the fingerprint with the server's administrator before trusting it.
+      </Typography>
+      <Typography variant="body2" color="text.secondary">
+        {prompt?.key_type} key fingerprint:
+      </Typography>
+      <Typography
+        variant="body2"
+        sx={{ fontFamily: "monospace", wordBreak: "break-all", mt: 0.5 }}
+      >
+        {prompt?.fingerprint}
+      </Typography>
+    </DialogContent>
+    <DialogActions>
+      <Button onClick={() => onAnswer(false)}>Cancel</Button>
+      <Button variant="contained" onClick={() => onAnswer(true)}>
+        Trust and connect
+      </Button>
+    </DialogActions>
+  </Dialog>
+);
+
+export default HostKeyDialog;
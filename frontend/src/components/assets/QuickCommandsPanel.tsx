@@ -23,7 +23,7 @@ import DeleteIcon from "@mui/icons-material/Delete";
 import EditIcon from "@mui/icons-material/Edit";
 import SearchIcon from "@mui/icons-material/Search";
 import BoltIcon from "@mui/icons-material/Bolt";
-import { sendToTerminal } from "./Terminal";
+import { getTerminalVariables, sendToTerminal } from "./Terminal";
 import {
   fetchQuickCommands,
   createQuickCommand,
@@ -45,14 +45,16 @@ interface QuickCommandsPanelProps {
   activeTabKey: string;
 }
 
-// simple variable substitution {{DATE}} {{TIME}}
-function renderTemplate(text: string): string {
+// simple variable substitution {{DATE}} {{TIME}}, and the variables
+// captured from the terminal's output by triggers, like {{host}}
+function renderTemplate(text: string, vars: Record<string, string>): string {
   const now = new Date();
   const ctx: Record<string, string> = {
+    ...vars,
     DATE: now.toISOString().slice(0, 10),
     TIME: now.toISOString().slice(11, 19),
   };
-  return text.replace(/{{([A-Z_]+)}}/g, (_, k) => ctx[k] || `{{${k}}}`);
+  return text.replace(/{{([A-Za-z_][A-Za-z0-9_]*)}}/g, (_, k) => ctx[k] ?? `{{${k}}}`);
 }
 
 const QuickCommandsPanel: React.FC<QuickCommandsPanelProps> = ({
@@ -189,7 +191,7 @@ const QuickCommandsPanel: React.FC<QuickCommandsPanelProps> = ({
   const insertCommand = useCallback(
     (cmd: QuickCommand, execute: boolean) => {
       if (!activeTabKey || activeTabKey === "welcome") return;
-      const text = renderTemplate(cmd.content);
+      const text = renderTemplate(cmd.content, getTerminalVariables(activeTabKey));
       sendToTerminal(activeTabKey, text, execute);
     },
     [activeTabKey],
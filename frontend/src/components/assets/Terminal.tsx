@@ -9,6 +9,15 @@ import "@xterm/xterm/css/xterm.css";
 import { getWsUrl } from "../../api/base";
 import TerminalContextMenu from "./TerminalContextMenu";
 import TerminalSearchBar from "./TerminalSearchBar";
+import HostKeyDialog, { HostKeyPrompt } from "./HostKeyDialog";
+import AuthPromptDialog, { AuthPrompt } from "./AuthPromptDialog";
+import TerminalBroadcastMarker from "./TerminalBroadcastMarker";
+import TerminalTransferDialog, {
+  base64ToBytes,
+  bytesToBase64,
+  type TerminalTransfer,
+} from "./TerminalTransferDialog";
+import { type BroadcastMarker } from "../../api/broadcast";
 
 interface TerminalProps {
   hostInfo: {
@@ -35,9 +44,40 @@ const terminalInstances = new Map<
     searchAddon: SearchAddon;
     isInitialized: boolean;
     domElement: HTMLDivElement | null;
+    // Reattaches to the backend session, or starts a new one if it ended
+    reconnect: (() => void) | null;
   }
 >();
 
+// Values captured by the backend's capture triggers, per tab
+const terminalVariables = new Map<string, Record<string, string>>();
+
+// Variables captured from a terminal's output, for quick command templates
+export function getTerminalVariables(tabKey: string): Record<string, string> {
+  return terminalVariables.get(tabKey) ?? {};
+}
+
+// Marks text matched by a highlight trigger. The match is on one of the
+// lines just written, so look for it near the cursor.
+function highlightTriggerMatch(terminal: Terminal, text: string, color: string) {
+  const buffer = terminal.buffer.active;
+  const cursor = buffer.baseY + buffer.cursorY;
+  for (let row = cursor; row >= Math.max(0, cursor - 10); row--) {
+    const x = buffer.getLine(row)?.translateToString(true).indexOf(text) ?? -1;
+    if (x < 0) continue;
+    const marker = terminal.registerMarker(row - cursor);
+    if (!marker) return;
+    terminal.registerDecoration({
+      marker,
+      x,
+      width: Math.min(text.length, terminal.cols - x),
+      backgroundColor: color,
+      layer: "bottom",
+    });
+    return;
+  }
+}
+
 export function sendToTerminal(
   tabKey: string,
   text: string,
@@ -59,90 +99,6 @@ export function sendToTerminal(
   return true;
 }
 
-// Function to handle terminal output request
-const handleTerminalOutputRequest = (
-  terminal: Terminal,
-  socket: WebSocket,
-  request: any,
-) => {
-  try {
-    const requestedLines: number =
-      request.lines && request.lines > 0 ? request.lines : 200;
-
-    // xterm v5+ active is current buffer (normal or alt), get its length
-    const buffer: any = (terminal as any).buffer.active; // compatibility type
-    const totalLines: number = buffer.length;
-    const end = totalLines; // logical end
-    const start = Math.max(0, end - requestedLines);
-    const lines: string[] = [];
-
-    for (let i = start; i < end; i++) {
-      try {
-        const line = buffer.getLine(i);
-        if (line) {
-          // translateToString(true) removes right-side blanks
-          const text = line.translateToString(true);
-          lines.push(text);
-        } else {
-          lines.push("");
-        }
-      } catch (e) {
-        console.warn("read line error", i, e);
-        lines.push("");
-      }
-    }
-
-    // Trim leading and trailing empty lines (preserve middle structure)
-    let firstIdx = 0;
-    while (firstIdx < lines.length && lines[firstIdx].trim() === "") firstIdx++;
-    let lastIdx = lines.length - 1;
-    while (lastIdx >= firstIdx && lines[lastIdx].trim() === "") lastIdx--;
-    let sliced = firstIdx <= lastIdx ? lines.slice(firstIdx, lastIdx + 1) : [];
-
-    // Fallback: if still empty, use selection approach
-    if (sliced.length === 0) {
-      try {
-        terminal.selectAll();
-        const selection = terminal.getSelection();
-        terminal.clearSelection();
-        if (selection) {
-          const selLines = selection.split(/\r?\n/);
-          sliced = selLines
-            .slice(-requestedLines)
-            .filter((l) => l.trim() !== "");
-        }
-      } catch (e) {
-        console.warn("fallback selection failed", e);
-      }
-    }
-
-    const response = {
-      type: "TermOutputResponse",
-      request_id: request.request_id,
-      output: sliced,
-      success: true,
-      error: "",
-      debug_info: {
-        totalLines,
-        requestedLines,
-        start,
-        end,
-        returned: sliced.length,
-      },
-    };
-    socket.send(JSON.stringify(response));
-  } catch (error) {
-    const errorResponse = {
-      type: "TermOutputResponse",
-      request_id: request.request_id,
-      output: [],
-      success: false,
-      error: String(error),
-    };
-    socket.send(JSON.stringify(errorResponse));
-  }
-};
-
 function TerminalComponent({
   hostInfo,
   tabKey,
@@ -163,6 +119,155 @@ function TerminalComponent({
   // Search bar state
   const [searchOpen, setSearchOpen] = useState(false);
 
+  // Unknown host key waiting for the user's decision
+  const [hostKeyPrompt, setHostKeyPrompt] = useState<HostKeyPrompt | null>(null);
+
+  const handleHostKeyAnswer = useCallback((accept: boolean) => {
+    const terminalData = terminalInstances.get(tabKey);
+    if (terminalData?.socket?.readyState === WebSocket.OPEN) {
+      terminalData.socket.send(
+        JSON.stringify({ type: "TermHostKeyDecision", accept }),
+      );
+    }
+    setHostKeyPrompt(null);
+  }, [tabKey]);
+
+  // Keyboard-interactive challenge (e.g. OTP) waiting for the user's answers
+  const [authPrompt, setAuthPrompt] = useState<AuthPrompt | null>(null);
+
+  // Broadcast groups this terminal types into along with others
+  const [broadcastGroups, setBroadcastGroups] = useState<BroadcastMarker[]>([]);
+
+  const handleAuthAnswer = useCallback((answers: string[] | null) => {
+    const terminalData = terminalInstances.get(tabKey);
+    if (terminalData?.socket?.readyState === WebSocket.OPEN) {
+      terminalData.socket.send(
+        JSON.stringify(
+          answers
+            ? { type: "TermAuthResponse", answers }
+            : { type: "TermAuthResponse", cancel: true },
+        ),
+      );
+    }
+    setAuthPrompt(null);
+  }, [tabKey]);
+
+  // File transfer started by rz/sz or trz/tsz in the session, with the files
+  // picked to upload and the parts of the file being downloaded
+  const [transfer, setTransfer] = useState<TerminalTransfer | null>(null);
+  const uploadFilesRef = useRef<File[]>([]);
+  const downloadRef = useRef<{ name: string; parts: Uint8Array[] } | null>(null);
+
+  const sendTransferMessage = useCallback((msg: object) => {
+    const socket = terminalInstances.get(tabKey)?.socket;
+    if (socket?.readyState === WebSocket.OPEN) {
+      socket.send(JSON.stringify(msg));
+    }
+  }, [tabKey]);
+
+  const handleTransferPick = useCallback((files: File[] | null) => {
+    if (!transfer) return;
+    uploadFilesRef.current = files ?? [];
+    sendTransferMessage(
+      files
+        ? {
+            type: "TermTransferFiles",
+            id: transfer.id,
+            files: files.map((f) => ({ name: f.name, size: f.size })),
+          }
+        : { type: "TermTransferFiles", id: transfer.id, cancel: true },
+    );
+    setTransfer({
+      ...transfer,
+      picking: false,
+      total: (files ?? []).reduce((n, f) => n + f.size, 0),
+    });
+  }, [transfer, sendTransferMessage]);
+
+  const handleTransferCancel = useCallback(() => {
+    if (transfer) {
+      sendTransferMessage({ type: "TermTransferCancel", id: transfer.id });
+    }
+  }, [transfer, sendTransferMessage]);
+
+  // The backend reads uploads a piece at a time and streams downloads
+  // file by file
+  const handleTransferMessage = useCallback((msg: { type: string; data: any }) => {
+    const d = msg.data;
+    switch (msg.type) {
+      case "transfer_start":
+        uploadFilesRef.current = [];
+        downloadRef.current = null;
+        setTransfer({
+          id: d.id,
+          protocol: d.protocol,
+          direction: d.direction,
+          picking: d.direction === "upload",
+          file: "",
+          done: 0,
+          total: 0,
+        });
+        break;
+      case "transfer_read": {
+        const file = uploadFilesRef.current[d.file];
+        const reply = { type: "TermTransferData", id: d.id, file: d.file, offset: d.offset };
+        if (!file) {
+          sendTransferMessage({ ...reply, error: "no such file" });
+          break;
+        }
+        file
+          .slice(d.offset, d.offset + d.size)
+          .arrayBuffer()
+          .then((buf) => {
+            sendTransferMessage({ ...reply, data: bytesToBase64(new Uint8Array(buf)) });
+            const before = uploadFilesRef.current
+              .slice(0, d.file)
+              .reduce((n, f) => n + f.size, 0);
+            setTransfer((prev) =>
+              prev && { ...prev, file: file.name, done: before + d.offset + buf.byteLength },
+            );
+          })
+          .catch((e) => sendTransferMessage({ ...reply, error: String(e) }));
+        break;
+      }
+      case "transfer_file":
+        downloadRef.current = { name: d.name, parts: [] };
+        setTransfer((prev) => prev && { ...prev, file: d.name, done: 0, total: d.size });
+        break;
+      case "transfer_data": {
+        const bytes = base64ToBytes(d.data ?? "");
+        downloadRef.current?.parts.push(bytes);
+        setTransfer((prev) => prev && { ...prev, done: prev.done + bytes.length });
+        break;
+      }
+      case "transfer_file_end": {
+        const download = downloadRef.current;
+        downloadRef.current = null;
+        if (download && d.complete) {
+          const url = URL.createObjectURL(new Blob(download.parts as BlobPart[]));
+          const a = document.createElement("a");
+          a.href = url;
+          a.download = download.name;
+          document.body.appendChild(a);
+          a.click();
+          document.body.removeChild(a);
+          URL.revokeObjectURL(url);
+        }
+        break;
+      }
+      case "transfer_end":
+        uploadFilesRef.current = [];
+        downloadRef.current = null;
+        setTransfer(null);
+        if (d.error) {
+          terminalInstances
+            .get(tabKey)
+            ?.terminal.writeln(`\r\n\x1b[31mFile transfer failed: ${d.error}\x1b[m`);
+        }
+        break;
+    }
+  }, [tabKey, sendTransferMessage]);
+
   // Wrap onConnectionStateChange to track connection state locally
   const handleConnectionStateChange = useCallback((connected: boolean) => {
     setIsConnected(connected);
@@ -255,14 +360,7 @@ function TerminalComponent({
   }, [tabKey]);
 
   const handleReconnect = useCallback(() => {
-    // Trigger reconnection by closing and reopening socket
-    const terminalData = terminalInstances.get(tabKey);
-    if (terminalData && terminalData.socket) {
-      terminalData.socket.close();
-      // The socket onclose handler should trigger reconnection logic
-      // Or we can dispatch a custom event
-      window.dispatchEvent(new CustomEvent("terminal-reconnect", { detail: { tabKey } }));
-    }
+    terminalInstances.get(tabKey)?.reconnect?.();
   }, [tabKey]);
 
   const handleExportOutput = useCallback(() => {
@@ -442,6 +540,7 @@ function TerminalComponent({
         searchAddon,
         isInitialized: false,
         domElement: null,
+        reconnect: null,
       };
 
       terminalInstances.set(tabKey, terminalData);
@@ -464,8 +563,20 @@ function TerminalComponent({
       const rawPath = containerId
         ? `/terminal/docker/${assetId}/${containerId}`
         : `/terminal/connect/${assetId}`;
-      const connectSocket = async () => {
-        const socketUrl = getWsUrl(rawPath);
+
+      // The backend keeps the session when the socket drops. Reattaching
+      // replays the output written after the bytes already received.
+      const MAX_REATTACH_ATTEMPTS = 5;
+      let received = 0;
+      let sessionEnded = false;
+      let reattachAttempts = 0;
+
+      const connectSocket = (reattach: boolean) => {
+        const socketUrl = getWsUrl(
+          reattach
+            ? `/terminal/attach/${encodeURIComponent(tabKey)}?offset=${received}`
+            : rawPath,
+        );
 
         if (!socketUrl) {
           terminalData?.terminal.writeln(
@@ -475,6 +586,13 @@ function TerminalComponent({
           return;
         }
 
+        if (!reattach) {
+          received = 0;
+        }
+        sessionEnded = false;
+        watermark = 0;
+        isPaused = false;
+
         const socket = new WebSocket(socketUrl);
         terminalData!.socket = socket;
         console.log("Connecting to asset:", assetId, "via URL:", socketUrl);
@@ -488,6 +606,7 @@ function TerminalComponent({
             "tab:",
             tabKey,
           );
+          reattachAttempts = 0;
           handleConnectionStateChange(true);
           const currentTerminalData = terminalInstances.get(tabKey);
           if (currentTerminalData) {
@@ -509,9 +628,11 @@ function TerminalComponent({
                 cols: cols,
               }),
             );
-            currentTerminalData.terminal.writeln(
-              `\r\n\x1b[32mConnecting to ${hostInfo.name}...\x1b[m`,
-            );
+            if (!reattach) {
+              currentTerminalData.terminal.writeln(
+                `\r\n\x1b[32mConnecting to ${hostInfo.name}...\x1b[m`,
+              );
+            }
           }
         };
 
@@ -526,36 +647,63 @@ function TerminalComponent({
                 // Handle connection status messages
                 if (msg.data.status === "disconnected") {
                   console.log("SSH connection disconnected:", msg.data.message);
+                  sessionEnded = true;
                   currentTerminalData.terminal.writeln(
                     `\r\n\x1b[33m${msg.data.message}\x1b[m`,
                   );
                   handleConnectionStateChange(false);
+                } else if (msg.data.status === "detached" || msg.data.status === "expired") {
+                  // Taken over by another client, or gone while detached
+                  sessionEnded = true;
+                  currentTerminalData.terminal.writeln(
+                    `\r\n\x1b[33m${msg.data.message}\x1b[m`,
+                  );
+                  handleConnectionStateChange(false);
+                } else if (msg.data.status === "reconnecting") {
+                  // The SSH connection dropped; the backend redials it
+                  currentTerminalData.terminal.writeln(
+                    `\r\n\x1b[33m${msg.data.message}\x1b[m`,
+                  );
+                  handleConnectionStateChange(false);
+                } else if (msg.data.status === "reconnected") {
+                  currentTerminalData.terminal.writeln(
+                    `\r\n\x1b[32m${msg.data.message}\x1b[m`,
+                  );
+                  handleConnectionStateChange(true);
                 } else if (msg.data.status === "error") {
                   console.error("SSH connection error:", msg.data.message);
+                  sessionEnded = true;
                   currentTerminalData.terminal.writeln(
                     `\r\n\x1b[31mConnection Error: ${msg.data.message}\x1b[m`,
                   );
-                  currentTerminalData.terminal.writeln(
-                    `\x1b[31mTrying to reconnect...\x1b[m`,
-                  );
                   handleConnectionStateChange(false);
-                  // Could add reconnect logic here
                 }
               } else if (msg.type === "error") {
                 console.error("Terminal error:", msg.message);
                 currentTerminalData.terminal.writeln(
                   `\r\n\x1b[31mError: ${msg.message}\x1b[m`,
                 );
+              } else if (msg.type === "host_key") {
+                currentTerminalData.terminal.writeln(
+                  `\r\n\x1b[33mUnknown host key for ${msg.data.host}: ${msg.data.key_type} ${msg.data.fingerprint}\x1b[m`,
+                );
+                setHostKeyPrompt(msg.data);
+              } else if (msg.type === "auth_prompt") {
+                setAuthPrompt(msg.data);
+              } else if (msg.type === "broadcast") {
+                setBroadcastGroups(msg.data?.groups || []);
+              } else if (msg.type.startsWith("transfer_")) {
+                handleTransferMessage(msg);
+              } else if (msg.type === "trigger_highlight") {
+                // Wait for the output before it to be on screen
+                const { terminal } = currentTerminalData;
+                terminal.write("", () =>
+                  highlightTriggerMatch(terminal, msg.data.text, msg.data.color),
+                );
+              } else if (msg.type === "variables") {
+                terminalVariables.set(tabKey, msg.data || {});
               } else if (msg.type === "change-theme") {
                 currentTerminalData.terminal.options.theme = msg.themeOptions;
-              } else if (msg.type === "TermOutputRequest") {
-                // Handle terminal output retrieval request
-                console.log("Received terminal output request:", msg);
-                handleTerminalOutputRequest(
-                  currentTerminalData.terminal,
-                  socket,
-                  msg,
-                );
               }
             } catch (e) {
               // If not JSON
@@ -568,6 +716,7 @@ function TerminalComponent({
             }
           } else {
             let dataArray = new Uint8Array(event.data);
+            received += dataArray.length;
             watermark += dataArray.length;
             currentTerminalData.terminal.write(dataArray, () => {
               //watermark = Math.max(watermark - dataArray.length, 0);
@@ -600,13 +749,26 @@ function TerminalComponent({
 
         socket.onclose = () => {
           console.log("WebSocket closed for asset:", assetId, "tab:", tabKey);
-          handleConnectionStateChange(false);
           const currentTerminalData = terminalInstances.get(tabKey);
-          if (currentTerminalData) {
+          // Closed by cleanupTerminal, or replaced by a newer socket
+          if (!currentTerminalData || currentTerminalData.socket !== socket) return;
+          handleConnectionStateChange(false);
+          if (!sessionEnded && reattachAttempts < MAX_REATTACH_ATTEMPTS) {
+            const delay = 1000 * 2 ** reattachAttempts;
+            reattachAttempts++;
             currentTerminalData.terminal.writeln(
-              "\r\n\x1b[31mConnection closed\x1b[m",
+              `\r\n\x1b[33mConnection lost, reattaching in ${delay / 1000}s...\x1b[m`,
             );
+            setTimeout(() => {
+              if (terminalInstances.get(tabKey)?.socket === socket) {
+                connectSocket(true);
+              }
+            }, delay);
+            return;
           }
+          currentTerminalData.terminal.writeln(
+            "\r\n\x1b[31mConnection closed\x1b[m",
+          );
         };
 
         socket.onerror = (error) => {
@@ -619,72 +781,68 @@ function TerminalComponent({
             );
           }
         };
+      };
 
-        // Terminal event handling
-        terminalData!.terminal.onResize(({ rows, cols }) => {
-          console.log("resize tty", rows, cols);
-          if (socket.readyState === WebSocket.OPEN) {
-            // Use new message format
-            socket.send(
-              JSON.stringify({
-                type: "TermResize",
-                rows: rows,
-                cols: cols,
-              }),
-            );
-          }
-        });
+      // Reattach to the session if it is still running, else start a new one
+      terminalData.reconnect = () => {
+        const previous = terminalData?.socket;
+        reattachAttempts = 0;
+        connectSocket(!sessionEnded);
+        previous?.close();
+      };
 
-        terminalData!.terminal.onData((data) => {
-          if (socket.readyState === WebSocket.OPEN) {
-            // Use new message format
-            socket.send(
-              JSON.stringify({
-                type: "TermInput",
-                data: data,
-              }),
-            );
-          }
-        });
+      // Terminal event handling, sent over whichever socket is current
+      const sendToSocket = (payload: object) => {
+        const socket = terminalInstances.get(tabKey)?.socket;
+        if (socket?.readyState === WebSocket.OPEN) {
+          socket.send(JSON.stringify(payload));
+        }
+      };
 
-        terminalData!.terminal.onRender(() => {
-          const currentTerminalData = terminalInstances.get(tabKey);
-          if (currentTerminalData) {
-            currentTerminalData.terminal.refresh(
-              currentTerminalData.terminal.rows,
-              currentTerminalData.terminal.rows,
-            );
-          }
-        });
+      terminal.onResize(({ rows, cols }) => {
+        console.log("resize tty", rows, cols);
+        // Use new message format
+        sendToSocket({ type: "TermResize", rows: rows, cols: cols });
+      });
 
-        // Theme change handling
-        const handleThemeChange = (e: MediaQueryListEvent) => {
-          const currentTerminalData = terminalInstances.get(tabKey);
-          if (currentTerminalData) {
-            currentTerminalData.terminal.options.theme = e.matches
-              ? {
-                  background: "#1e1e1e",
-                  foreground: "#d4d4d4",
-                  cursor: "#ffffff",
-                }
-              : {
-                  background: "#ffffff",
-                  foreground: "#000000",
-                  cursor: "#000000",
-                };
-          }
-        };
+      terminal.onData((data) => {
+        // Use new message format
+        sendToSocket({ type: "TermInput", data: data });
+      });
 
-        const mediaQuery = window.matchMedia("(prefers-color-scheme: dark)");
-        mediaQuery.addEventListener("change", handleThemeChange);
+      terminal.onRender(() => {
+        const currentTerminalData = terminalInstances.get(tabKey);
+        if (currentTerminalData) {
+          currentTerminalData.terminal.refresh(
+            currentTerminalData.terminal.rows,
+            currentTerminalData.terminal.rows,
+          );
+        }
+      });
 
-        // Cleanup
-        return () => {
-          mediaQuery.removeEventListener("change", handleThemeChange);
-        };
+      // Theme change handling
+      const handleThemeChange = (e: MediaQueryListEvent) => {
+        const currentTerminalData = terminalInstances.get(tabKey);
+        if (currentTerminalData) {
+          currentTerminalData.terminal.options.theme = e.matches
+            ? {
+                background: "#1e1e1e",
+                foreground: "#d4d4d4",
+                cursor: "#ffffff",
+              }
+            : {
+                background: "#ffffff",
+                foreground: "#000000",
+                cursor: "#000000",
+              };
+        }
       };
 
-      void connectSocket();
+      window
+        .matchMedia("(prefers-color-scheme: dark)")
+        .addEventListener("change", handleThemeChange);
+
+      connectSocket(false);
 
       // Note: We don't close the socket here on unmount because the terminal
       // instance persists in terminalInstances map and may be reattached
@@ -837,6 +995,7 @@ function TerminalComponent({
           onFindNext={handleFindNext}
           onFindPrevious={handleFindPrevious}
         />
+        <TerminalBroadcastMarker tabKey={tabKey} groups={broadcastGroups} />
       </div>
       <TerminalContextMenu
         anchorPosition={contextMenu}
@@ -854,6 +1013,13 @@ function TerminalComponent({
         hasSelection={hasSelection}
         isConnected={isConnected}
       />
+      <HostKeyDialog prompt={hostKeyPrompt} onAnswer={handleHostKeyAnswer} />
+      <AuthPromptDialog prompt={authPrompt} onAnswer={handleAuthAnswer} />
+      <TerminalTransferDialog
+        transfer={transfer}
+        onPick={handleTransferPick}
+        onCancel={handleTransferCancel}
+      />
     </>
   );
 }
@@ -863,8 +1029,11 @@ export const cleanupTerminal = (tabKey: string) => {
   const terminalData = terminalInstances.get(tabKey);
   if (terminalData) {
     try {
-      // Close WebSocket connection
+      // End the backend session, then close the WebSocket connection
       if (terminalData.socket) {
+        if (terminalData.socket.readyState === WebSocket.OPEN) {
+          terminalData.socket.send(JSON.stringify({ type: "TermClose" }));
+        }
         terminalData.socket.close();
       }
 
@@ -873,6 +1042,7 @@ export const cleanupTerminal = (tabKey: string) => {
 
       // Delete from Map
       terminalInstances.delete(tabKey);
+      terminalVariables.delete(tabKey);
 
       console.log("Terminal cleaned up:", tabKey);
     } catch (error) {
@@ -0,0 +1,51 @@
+import { Box, Chip } from "@mui/material";
+import PodcastsIcon from "@mui/icons-material/Podcasts";
+import { setBroadcastMember, type BroadcastMarker } from "../../api/broadcast";
+
+interface TerminalBroadcastMarkerProps {
+  tabKey: string;
+  groups: BroadcastMarker[];
+}
+
+// Shows the broadcast groups a terminal is in; clicking a group turns the
+// terminal's share of its input on or off
+export default function TerminalBroadcastMarker({ tabKey, groups }: TerminalBroadcastMarkerProps) {
+  if (groups.length === 0) return null;
+
+  const toggle = (group: BroadcastMarker) => {
+    setBroadcastMember(group.group_id, tabKey, !group.enabled).catch((e) =>
+      console.error("Failed to update broadcast member:", e),
+    );
+  };
+
+  return (
+    <Box
+      sx={{
+        position: "absolute",
+        bottom: 8,
+        right: 8,
+        zIndex: 999,
+        display: "flex",
+        gap: 0.5,
+      }}
+    >
+      {groups.map((group) => (
+        <Chip
+          key={group.group_id}
+          size="small"
+          icon={<PodcastsIcon sx={{ fontSize: 14 }} />}
+          label={`${group.name} (${group.members})`}
+          color={group.enabled ? "warning" : "default"}
+          variant={group.enabled ? "filled" : "outlined"}
+          onClick={() => toggle(group)}
+          title={
+            group.enabled
+              ? "Input is broadcast to this group. Click to stop"
+              : "Not receiving broadcast input. Click to rejoin"
+          }
+          sx={{ fontSize: 11, opacity: group.enabled ? 0.9 : 0.6 }}
+        />
+      ))}
+    </Box>
+  );
+}
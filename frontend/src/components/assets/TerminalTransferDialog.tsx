@@ -0,0 +1,115 @@
+import React, { useRef } from "react";
+import {
+  Button,
+  Dialog,
+  DialogActions,
+  DialogContent,
+  DialogTitle,
+  LinearProgress,
+  Typography,
+} from "@mui/material";
+
+// An rz/sz or trz/tsz transfer running in the terminal
+export interface TerminalTransfer {
+  id: string;
+  protocol: "zmodem" | "trzsz";
+  direction: "upload" | "download";
+  // Waiting for the user to pick the files to upload
+  picking: boolean;
+  file: string;
+  done: number;
+  total: number;
+}
+
+interface TerminalTransferDialogProps {
+  transfer: TerminalTransfer | null;
+  // files is null when the user declines to upload
+  onPick: (files: File[] | null) => void;
+  onCancel: () => void;
+}
+
+export function bytesToBase64(data: Uint8Array): string {
+  let binary = "";
+  for (let i = 0; i < data.length; i += 0x8000) {
+    binary += String.fromCharCode(...data.subarray(i, i + 0x8000));
+  }
+  return btoa(binary);
+}
+
+export function base64ToBytes(data: string): Uint8Array {
+  const binary = atob(data);
+  const bytes = new Uint8Array(binary.length);
+  for (let i = 0; i < binary.length; i++) {
+    bytes[i] = binary.charCodeAt(i);
+  }
+  return bytes;
+}
+
+function formatBytes(n: number): string {
+  if (n < 1024) return `${n} B`;
+  if (n < 1024 * 1024) return `${(n / 1024).toFixed(1)} KB`;
+  return `${(n / 1024 / 1024).toFixed(1)} MB`;
+}
+
+const TerminalTransferDialog: React.FC<TerminalTransferDialogProps> = ({
+  transfer,
+  onPick,
+  onCancel,
+}) => {
+  const inputRef = useRef<HTMLInputElement>(null);
+  const command = transfer?.protocol === "trzsz" ? "trz" : "rz";
+
+  return (
+    <Dialog open={!!transfer} maxWidth="xs" fullWidth>
+      <DialogTitle>
+        {transfer?.direction === "upload" ? "Upload files" : "Download files"}
+      </DialogTitle>
+      <DialogContent>
+        {transfer?.picking ? (
+          <Typography variant="body2">
+            The remote host is waiting for files ({command}).
+          </Typography>
+        ) : (
+          <>
+            <Typography variant="body2" noWrap gutterBottom>
+              {transfer?.file || "Waiting for the remote host..."}
+            </Typography>
+            <LinearProgress
+              variant={transfer?.total ? "determinate" : "indeterminate"}
+              value={transfer?.total ? (100 * transfer.done) / transfer.total : 0}
+            />
+            <Typography variant="caption" color="text.secondary">
+              {formatBytes(transfer?.done ?? 0)}
+              {transfer?.total ? ` of ${formatBytes(transfer.total)}` : ""}
+            </Typography>
+          </>
+        )}
+        <input
+          ref={inputRef}
+          type="file"
+          multiple
+          hidden
+          onChange={(e) => {
+            const files = Array.from(e.target.files ?? []);
+            e.target.value = "";
+            onPick(files.length > 0 ? files : null);
+          }}
+        />
+      </DialogContent>
+      <DialogActions>
+        {transfer?.picking ? (
+          <>
+            <Button onClick={() => onPick(null)}>Cancel</Button>
+            <Button variant="contained" onClick={() => inputRef.current?.click()}>
+              Choose files
+            </Button>
+          </>
+        ) : (
+          <Button onClick={onCancel}>Cancel</Button>
+        )}
+      </DialogActions>
+    </Dialog>
+  );
+};
+
+export default TerminalTransferDialog;
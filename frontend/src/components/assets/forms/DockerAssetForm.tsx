@@ -41,6 +41,7 @@ export interface DockerHostConfig {
   font_size?: number;
   copy_on_select?: boolean;
   bell?: boolean;
+  record?: boolean;
 }
 
 export interface DockerAssetFormHandle {
@@ -134,6 +135,7 @@ const DockerAssetForm = React.forwardRef<DockerAssetFormHandle, Props>(
         font_size: cfg.font_size || 14,
         copy_on_select: cfg.copy_on_select || false,
         bell: cfg.bell !== false,
+        record: cfg.record === true,
       };
     });
 
@@ -175,6 +177,7 @@ const DockerAssetForm = React.forwardRef<DockerAssetFormHandle, Props>(
         font_size: cfg.font_size || 14,
         copy_on_select: cfg.copy_on_select || false,
         bell: cfg.bell !== false,
+        record: cfg.record === true,
       });
       setTestResult(null);
     }, [asset, defaultParentId]);
@@ -243,6 +246,7 @@ const DockerAssetForm = React.forwardRef<DockerAssetFormHandle, Props>(
           font_size: config.font_size,
           copy_on_select: config.copy_on_select,
           bell: config.bell,
+          record: config.record,
         },
         tags: [] as string[],
         parent_id: parentFolder,
@@ -567,6 +571,18 @@ const DockerAssetForm = React.forwardRef<DockerAssetFormHandle, Props>(
                   Terminal bell
                 </Typography>
               </Box>
+              <Box display="flex" alignItems="center" gap={1}>
+                <Switch
+                  size="small"
+                  checked={config.record === true}
+                  onChange={(e) =>
+                    setConfig({ ...config, record: e.target.checked })
+                  }
+                />
+                <Typography variant="body2" color="text.secondary">
+                  Record sessions
+                </Typography>
+              </Box>
             </Box>
           </Box>
         </FormSection>
@@ -31,6 +31,8 @@ export interface LocalConfig {
   font_size?: number;
   copy_on_select?: boolean;
   bell?: boolean;
+  record?: boolean;
+  shell_integration?: boolean;
 }
 
 export interface LocalAssetFormHandle {
@@ -125,6 +127,8 @@ const LocalAssetForm = React.forwardRef<LocalAssetFormHandle, Props>(
         font_size: cfg.font_size || 14,
         copy_on_select: cfg.copy_on_select || false,
         bell: cfg.bell !== false, // default true
+        record: cfg.record === true,
+        shell_integration: cfg.shell_integration === true,
       };
     });
 
@@ -149,6 +153,8 @@ const LocalAssetForm = React.forwardRef<LocalAssetFormHandle, Props>(
         font_size: cfg.font_size || 14,
         copy_on_select: cfg.copy_on_select || false,
         bell: cfg.bell !== false,
+        record: cfg.record === true,
+        shell_integration: cfg.shell_integration === true,
       });
     }, [asset, defaultParentId]);
 
@@ -522,6 +528,33 @@ const LocalAssetForm = React.forwardRef<LocalAssetFormHandle, Props>(
                   Terminal bell
                 </Typography>
               </Box>
+              <Box display="flex" alignItems="center" gap={1}>
+                <Switch
+                  size="small"
+                  checked={config.record === true}
+                  onChange={(e) =>
+                    setConfig((c) => ({ ...c, record: e.target.checked }))
+                  }
+                />
+                <Typography variant="body2" color="text.secondary">
+                  Record sessions
+                </Typography>
+              </Box>
+              <Box display="flex" alignItems="center" gap={1}>
+                <Switch
+                  size="small"
+                  checked={config.shell_integration === true}
+                  onChange={(e) =>
+                    setConfig((c) => ({
+                      ...c,
+                      shell_integration: e.target.checked,
+                    }))
+                  }
+                />
+                <Typography variant="body2" color="text.secondary">
+                  Shell integration (track commands and exit codes)
+                </Typography>
+              </Box>
             </Box>
           </Box>
         </FormSection>
@@ -36,6 +36,8 @@ export interface SshConfig {
   password?: string;
   private_key_path?: string;
   private_key_passphrase?: string;
+  certificate_path?: string;
+  use_agent?: boolean;
   private_key?: string;
   timeout?: number;
   keepalive_interval?: number;
@@ -62,6 +64,10 @@ export interface SshConfig {
     remote_host?: string;
     remote_port?: number;
     description?: string;
+    auto_start?: boolean;
+    restart_policy?: "never" | "on-failure" | "always";
+    health_check?: { interval?: number; timeout?: number };
+    proxy?: TunnelProxyConfig;
   }>;
   // Terminal settings
   shell?: string;
@@ -74,6 +80,28 @@ export interface SshConfig {
   font_size?: number;
   copy_on_select?: boolean;
   bell?: boolean;
+  record?: boolean;
+  shell_integration?: boolean;
+  // Redial dropped connections, optionally into a tmux/screen session
+  reconnect?: {
+    max_attempts: number;
+    initial_backoff?: number;
+    max_backoff?: number;
+    multiplexer?: "" | "tmux" | "screen" | "auto";
+    session_name?: string;
+  };
+}
+
+// Proxy settings of a dynamic tunnel
+export interface TunnelProxyConfig {
+  mode?: "socks" | "http" | "mixed";
+  username?: string;
+  password?: string;
+  pac?: boolean;
+  allowed_hosts?: string[];
+  blocked_hosts?: string[];
+  allowed_forward_ports?: number[];
+  blocked_forward_ports?: number[];
 }
 
 export interface SshAssetFormHandle {
@@ -92,6 +120,52 @@ interface Props {
 }
 
 // Form field label component
+type AuthMethod = "password" | "keyFile" | "agent" | "interactive";
+
+// authMethodOf infers the auth method of a saved config. Saved assets with
+// no credentials authenticate interactively.
+function authMethodOf(cfg: any, saved: boolean): AuthMethod {
+  if (cfg?.use_agent === true) return "agent";
+  if (cfg?.private_key_path) return "keyFile";
+  if (cfg?.password || !saved) return "password";
+  return "interactive";
+}
+
+// Splits a comma separated list, dropping empty entries
+function splitList(value: string): string[] {
+  return value.split(",").map((v) => v.trim()).filter(Boolean);
+}
+
+// Builds the proxy settings of a new dynamic tunnel, or undefined for a
+// plain SOCKS proxy without auth or rules
+function buildTunnelProxy(t: {
+  proxy_mode: "socks" | "http" | "mixed";
+  proxy_username: string;
+  proxy_password: string;
+  proxy_pac: boolean;
+  allowed_hosts: string;
+  blocked_hosts: string;
+  allowed_ports: string;
+  blocked_ports: string;
+}): TunnelProxyConfig | undefined {
+  const ports = (v: string) => splitList(v).map((p) => parseInt(p, 10)).filter((p) => p > 0);
+  const proxy: TunnelProxyConfig = {
+    mode: t.proxy_mode !== "socks" ? t.proxy_mode : undefined,
+    username: t.proxy_username || undefined,
+    password: t.proxy_username && t.proxy_password ? t.proxy_password : undefined,
+    pac: t.proxy_pac || undefined,
+    allowed_hosts: splitList(t.allowed_hosts),
+    blocked_hosts: splitList(t.blocked_hosts),
+    allowed_forward_ports: ports(t.allowed_ports),
+    blocked_forward_ports: ports(t.blocked_ports),
+  };
+  (Object.keys(proxy) as (keyof TunnelProxyConfig)[]).forEach((k) => {
+    const v = proxy[k];
+    if (v === undefined || (Array.isArray(v) && v.length === 0)) delete proxy[k];
+  });
+  return Object.keys(proxy).length > 0 ? proxy : undefined;
+}
+
 function FieldLabel({ label, required }: { label: string; required?: boolean }) {
   return (
     <Typography
@@ -164,6 +238,8 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
         private_key_path: cfg.private_key_path || "",
         private_key_passphrase: cfg.private_key_passphrase || "",
         private_key: cfg.private_key || "",
+        certificate_path: cfg.certificate_path || "",
+        use_agent: cfg.use_agent === true,
         timeout: typeof cfg.timeout === "number" ? cfg.timeout : 30,
         keepalive_interval: typeof cfg.keepalive_interval === "number" ? cfg.keepalive_interval : 60,
         connection_mode: cfg.connection_mode || "direct",
@@ -175,7 +251,7 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
         jump_asset_id: cfg.jump_asset_id || "",
         compression: cfg.compression || false,
         agent_forwarding: cfg.agent_forwarding || false,
-        strict_host_key: cfg.strict_host_key !== false,
+        strict_host_key: cfg.strict_host_key === true,
         tunnels: cfg.tunnels || [],
         shell: cfg.shell || "",
         term_type: cfg.term_type || "xterm-256color",
@@ -185,13 +261,14 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
         font_size: cfg.font_size || 14,
         copy_on_select: cfg.copy_on_select || false,
         bell: cfg.bell !== false,
+        record: cfg.record === true,
+        shell_integration: cfg.shell_integration === true,
+        reconnect: cfg.reconnect,
       };
     });
-    const [authMethod, setAuthMethod] = useState<"password" | "keyFile">(() => {
-      const cfg = (asset?.config || {}) as any;
-      if (cfg.private_key_path && cfg.private_key_path !== "") return "keyFile";
-      return "password";
-    });
+    const [authMethod, setAuthMethod] = useState<AuthMethod>(() =>
+      authMethodOf(asset?.config, !!asset),
+    );
     const [keyEncrypted, setKeyEncrypted] = useState<boolean>(false);
 
     // Environment variable editing state
@@ -205,12 +282,34 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
       local_port: string;
       remote_host: string;
       remote_port: string;
+      auto_start: boolean;
+      restart_policy: "never" | "on-failure" | "always";
+      health_check: boolean;
+      proxy_mode: "socks" | "http" | "mixed";
+      proxy_username: string;
+      proxy_password: string;
+      proxy_pac: boolean;
+      allowed_hosts: string;
+      blocked_hosts: string;
+      allowed_ports: string;
+      blocked_ports: string;
     }>({
       type: "local",
       local_host: "127.0.0.1",
       local_port: "",
       remote_host: "",
       remote_port: "",
+      auto_start: false,
+      restart_policy: "never",
+      health_check: false,
+      proxy_mode: "socks",
+      proxy_username: "",
+      proxy_password: "",
+      proxy_pac: false,
+      allowed_hosts: "",
+      blocked_hosts: "",
+      allowed_ports: "",
+      blocked_ports: "",
     });
 
     // Track the asset ID to detect actual asset changes vs just refetches
@@ -235,6 +334,8 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
         private_key_path: cfg.private_key_path || "",
         private_key_passphrase: cfg.private_key_passphrase || "",
         private_key: cfg.private_key || "",
+        certificate_path: cfg.certificate_path || "",
+        use_agent: cfg.use_agent === true,
         timeout: typeof cfg.timeout === "number" ? cfg.timeout : 30,
         keepalive_interval: typeof cfg.keepalive_interval === "number" ? cfg.keepalive_interval : 60,
         connection_mode: cfg.connection_mode || "direct",
@@ -246,7 +347,7 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
         jump_asset_id: cfg.jump_asset_id || "",
         compression: cfg.compression || false,
         agent_forwarding: cfg.agent_forwarding || false,
-        strict_host_key: cfg.strict_host_key !== false,
+        strict_host_key: cfg.strict_host_key === true,
         tunnels: cfg.tunnels || [],
         shell: cfg.shell || "",
         term_type: cfg.term_type || "xterm-256color",
@@ -256,18 +357,18 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
         font_size: cfg.font_size || 14,
         copy_on_select: cfg.copy_on_select || false,
         bell: cfg.bell !== false,
+        record: cfg.record === true,
+        shell_integration: cfg.shell_integration === true,
+        reconnect: cfg.reconnect,
       });
-      if (cfg.private_key_path && cfg.private_key_path !== "")
-        setAuthMethod("keyFile");
-      else if (cfg.password && cfg.password !== "") setAuthMethod("password");
-      else setAuthMethod("password");
+      setAuthMethod(authMethodOf(cfg, !!asset));
     }, [asset?.id, defaultParentId]);
 
     const isValid = React.useMemo(() => {
       if (!name.trim() || !config.host || !config.username) return false;
       if (authMethod === "password") return !!config.password;
       if (authMethod === "keyFile") return !!config.private_key_path;
-      return false;
+      return authMethod === "agent" || authMethod === "interactive";
     }, [name, config, authMethod]);
 
     useEffect(() => {
@@ -278,7 +379,11 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
       if (!isValid) return false;
       const authConfig = { ...config } as any;
       if (authMethod !== "password") authConfig.password = "";
-      if (authMethod !== "keyFile") authConfig.private_key_path = "";
+      if (authMethod !== "keyFile") {
+        authConfig.private_key_path = "";
+        authConfig.certificate_path = "";
+      }
+      authConfig.use_agent = authMethod === "agent";
       authConfig.private_key = "";
       const body = {
         name: name.trim(),
@@ -643,9 +748,30 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
                   control={<Radio size="small" />}
                   label={<Typography variant="body2">SSH Key</Typography>}
                 />
+                <FormControlLabel
+                  value="agent"
+                  control={<Radio size="small" />}
+                  label={<Typography variant="body2">SSH Agent</Typography>}
+                />
+                <FormControlLabel
+                  value="interactive"
+                  control={<Radio size="small" />}
+                  label={<Typography variant="body2">Interactive</Typography>}
+                />
               </RadioGroup>
             </Box>
 
+            {authMethod === "agent" && (
+              <Typography variant="caption" color="text.secondary">
+                Keys are offered from the ssh-agent running at SSH_AUTH_SOCK.
+              </Typography>
+            )}
+            {authMethod === "interactive" && (
+              <Typography variant="caption" color="text.secondary">
+                The server's prompts, such as one-time codes, are asked in the terminal when connecting.
+              </Typography>
+            )}
+
             {/* Password auth */}
             {authMethod === "password" && (
               <Box>
@@ -684,6 +810,18 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
                     )}
                   />
                 </Box>
+                <Box flex={1}>
+                  <FieldLabel label="Certificate Path" />
+                  <TextField
+                    size="small"
+                    fullWidth
+                    placeholder="Defaults to <key file>-cert.pub"
+                    value={config.certificate_path || ""}
+                    onChange={(e) =>
+                      setConfig((c) => ({ ...c, certificate_path: e.target.value }))
+                    }
+                  />
+                </Box>
                 {keyEncrypted && (
                   <Box sx={{ minWidth: 200 }}>
                     <FieldLabel label="Key Passphrase" />
@@ -738,7 +876,7 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
             <Box display="flex" alignItems="center" gap={1}>
               <Switch
                 size="small"
-                checked={config.strict_host_key !== false}
+                checked={config.strict_host_key === true}
                 onChange={(e) =>
                   setConfig((c) => ({ ...c, strict_host_key: e.target.checked }))
                 }
@@ -746,6 +884,11 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
               <Typography variant="body2" color="text.secondary">
                 Strict host key checking
               </Typography>
+              <Typography variant="caption" color="text.disabled">
+                {config.strict_host_key === true
+                  ? "Unknown hosts are refused"
+                  : "New host keys are confirmed on first connect"}
+              </Typography>
             </Box>
           </Box>
         </FormSection>
@@ -761,11 +904,17 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
                     key={idx}
                     size="small"
                     label={
-                      tunnel.type === "dynamic"
+                      (tunnel.type === "dynamic"
                         ? `[D] ${tunnel.local_host || "127.0.0.1"}:${tunnel.local_port}`
                         : tunnel.type === "local"
                         ? `[L] ${tunnel.local_host || "127.0.0.1"}:${tunnel.local_port} → ${tunnel.remote_host}:${tunnel.remote_port}`
-                        : `[R] ${tunnel.remote_host}:${tunnel.remote_port} → ${tunnel.local_host || "127.0.0.1"}:${tunnel.local_port}`
+                        : `[R] ${tunnel.remote_host}:${tunnel.remote_port} → ${tunnel.local_host || "127.0.0.1"}:${tunnel.local_port}`) +
+                      (tunnel.auto_start ? " [auto]" : "") +
+                      (tunnel.restart_policy && tunnel.restart_policy !== "never" ? ` [restart ${tunnel.restart_policy}]` : "") +
+                      (tunnel.health_check ? " [health]" : "") +
+                      (tunnel.proxy?.mode && tunnel.proxy.mode !== "socks" ? ` [${tunnel.proxy.mode}]` : "") +
+                      (tunnel.proxy?.username ? " [auth]" : "") +
+                      (tunnel.proxy?.pac ? " [pac]" : "")
                     }
                     onDelete={() => {
                       setConfig((c) => ({
@@ -865,6 +1014,10 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
                       local_port: parseInt(newTunnel.local_port, 10),
                       remote_host: newTunnel.type !== "dynamic" ? newTunnel.remote_host : undefined,
                       remote_port: newTunnel.type !== "dynamic" ? parseInt(newTunnel.remote_port, 10) : undefined,
+                      auto_start: newTunnel.auto_start || undefined,
+                      restart_policy: newTunnel.restart_policy !== "never" ? newTunnel.restart_policy : undefined,
+                      health_check: newTunnel.health_check ? {} : undefined,
+                      proxy: newTunnel.type === "dynamic" ? buildTunnelProxy(newTunnel) : undefined,
                     };
                     setConfig((c) => ({
                       ...c,
@@ -876,6 +1029,17 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
                       local_port: "",
                       remote_host: "",
                       remote_port: "",
+                      auto_start: false,
+                      restart_policy: "never",
+                      health_check: false,
+                      proxy_mode: "socks",
+                      proxy_username: "",
+                      proxy_password: "",
+                      proxy_pac: false,
+                      allowed_hosts: "",
+                      blocked_hosts: "",
+                      allowed_ports: "",
+                      blocked_ports: "",
                     });
                   }}
                   sx={{ mb: 0.5 }}
@@ -883,8 +1047,149 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
                   <AddIcon />
                 </IconButton>
               </Box>
+              <Box display="flex" gap={2} alignItems="center">
+                <Box display="flex" alignItems="center" gap={1}>
+                  <Switch
+                    size="small"
+                    checked={newTunnel.auto_start}
+                    onChange={(e) =>
+                      setNewTunnel((t) => ({ ...t, auto_start: e.target.checked }))
+                    }
+                  />
+                  <Typography variant="body2">Start with the app</Typography>
+                </Box>
+                <Box display="flex" alignItems="center" gap={1}>
+                  <Switch
+                    size="small"
+                    checked={newTunnel.health_check}
+                    onChange={(e) =>
+                      setNewTunnel((t) => ({ ...t, health_check: e.target.checked }))
+                    }
+                  />
+                  <Typography variant="body2">Health check</Typography>
+                </Box>
+                <Box sx={{ width: 160 }}>
+                  <FormControl size="small" fullWidth>
+                    <Select
+                      value={newTunnel.restart_policy}
+                      onChange={(e) =>
+                        setNewTunnel((t) => ({ ...t, restart_policy: e.target.value as any }))
+                      }
+                    >
+                      <MenuItem value="never">Don't restart</MenuItem>
+                      <MenuItem value="on-failure">Restart on failure</MenuItem>
+                      <MenuItem value="always">Always restart</MenuItem>
+                    </Select>
+                  </FormControl>
+                </Box>
+              </Box>
+              {newTunnel.type === "dynamic" && (
+                <>
+                  <Box display="flex" gap={2} alignItems="flex-end">
+                    <Box sx={{ width: 160 }}>
+                      <FieldLabel label="Proxy Protocol" />
+                      <FormControl size="small" fullWidth>
+                        <Select
+                          value={newTunnel.proxy_mode}
+                          onChange={(e) =>
+                            setNewTunnel((t) => ({ ...t, proxy_mode: e.target.value as any }))
+                          }
+                        >
+                          <MenuItem value="socks">SOCKS4/5</MenuItem>
+                          <MenuItem value="http">HTTP</MenuItem>
+                          <MenuItem value="mixed">SOCKS and HTTP</MenuItem>
+                        </Select>
+                      </FormControl>
+                    </Box>
+                    <Box flex={1}>
+                      <FieldLabel label="Proxy Username" />
+                      <TextField
+                        size="small"
+                        fullWidth
+                        placeholder="No authentication"
+                        value={newTunnel.proxy_username}
+                        onChange={(e) =>
+                          setNewTunnel((t) => ({ ...t, proxy_username: e.target.value }))
+                        }
+                      />
+                    </Box>
+                    <Box flex={1}>
+                      <FieldLabel label="Proxy Password" />
+                      <TextField
+                        size="small"
+                        fullWidth
+                        type="password"
+                        disabled={!newTunnel.proxy_username}
+                        value={newTunnel.proxy_password}
+                        onChange={(e) =>
+                          setNewTunnel((t) => ({ ...t, proxy_password: e.target.value }))
+                        }
+                      />
+                    </Box>
+                    <Box display="flex" alignItems="center" gap={1} sx={{ mb: 0.5 }}>
+                      <Switch
+                        size="small"
+                        checked={newTunnel.proxy_pac}
+                        onChange={(e) =>
+                          setNewTunnel((t) => ({ ...t, proxy_pac: e.target.checked }))
+                        }
+                      />
+                      <Typography variant="body2">PAC file</Typography>
+                    </Box>
+                  </Box>
+                  <Box display="flex" gap={2}>
+                    <Box flex={1}>
+                      <FieldLabel label="Allowed Hosts" />
+                      <TextField
+                        size="small"
+                        fullWidth
+                        placeholder="*.internal, 10.0.0.0/8"
+                        value={newTunnel.allowed_hosts}
+                        onChange={(e) =>
+                          setNewTunnel((t) => ({ ...t, allowed_hosts: e.target.value }))
+                        }
+                      />
+                    </Box>
+                    <Box flex={1}>
+                      <FieldLabel label="Blocked Hosts" />
+                      <TextField
+                        size="small"
+                        fullWidth
+                        value={newTunnel.blocked_hosts}
+                        onChange={(e) =>
+                          setNewTunnel((t) => ({ ...t, blocked_hosts: e.target.value }))
+                        }
+                        helperText="Blocking an IP or CIDR refuses all host names"
+                      />
+                    </Box>
+                    <Box sx={{ width: 120 }}>
+                      <FieldLabel label="Allowed Ports" />
+                      <TextField
+                        size="small"
+                        fullWidth
+                        placeholder="80, 443"
+                        value={newTunnel.allowed_ports}
+                        onChange={(e) =>
+                          setNewTunnel((t) => ({ ...t, allowed_ports: e.target.value }))
+                        }
+                      />
+                    </Box>
+                    <Box sx={{ width: 120 }}>
+                      <FieldLabel label="Blocked Ports" />
+                      <TextField
+                        size="small"
+                        fullWidth
+                        value={newTunnel.blocked_ports}
+                        onChange={(e) =>
+                          setNewTunnel((t) => ({ ...t, blocked_ports: e.target.value }))
+                        }
+                      />
+                    </Box>
+                  </Box>
+                </>
+              )}
               <Typography variant="caption" color="text.secondary">
-                Local (-L): Forward local port to remote. Remote (-R): Forward remote port to local. Dynamic (-D): SOCKS proxy.
+                Local (-L): Forward local port to remote. Remote (-R): Forward remote port to local. Dynamic (-D): SOCKS or HTTP proxy.
               </Typography>
             </Box>
           </Box>
@@ -1099,9 +1404,169 @@ const SshAssetForm = React.forwardRef<SshAssetFormHandle, Props>(
                   Terminal bell
                 </Typography>
               </Box>
+              <Box display="flex" alignItems="center" gap={1}>
+                <Switch
+                  size="small"
+                  checked={config.record === true}
+                  onChange={(e) =>
+                    setConfig((c) => ({ ...c, record: e.target.checked }))
+                  }
+                />
+                <Typography variant="body2" color="text.secondary">
+                  Record sessions
+                </Typography>
+              </Box>
+              <Box display="flex" alignItems="center" gap={1}>
+                <Switch
+                  size="small"
+                  checked={config.shell_integration === true}
+                  onChange={(e) =>
+                    setConfig((c) => ({
+                      ...c,
+                      shell_integration: e.target.checked,
+                    }))
+                  }
+                />
+                <Typography variant="body2" color="text.secondary">
+                  Shell integration (track commands and exit codes)
+                </Typography>
+              </Box>
             </Box>
           </Box>
         </FormSection>
+
+        {/* Reconnect Section */}
+        <FormSection title="Reconnect">
+          <Box display="flex" flexDirection="column" gap={1.5}>
+            <Box display="flex" alignItems="center" gap={1}>
+              <Switch
+                size="small"
+                checked={(config.reconnect?.max_attempts ?? 0) > 0}
+                onChange={(e) =>
+                  setConfig((c) => ({
+                    ...c,
+                    reconnect: e.target.checked
+                      ? { ...c.reconnect, max_attempts: 5 }
+                      : undefined,
+                  }))
+                }
+              />
+              <Typography variant="body2" color="text.secondary">
+                Reconnect when the connection drops
+              </Typography>
+            </Box>
+            {(config.reconnect?.max_attempts ?? 0) > 0 && (
+              <>
+                <Box display="flex" gap={2}>
+                  <Box sx={{ width: 120 }}>
+                    <FieldLabel label="Max Attempts" />
+                    <TextField
+                      size="small"
+                      fullWidth
+                      type="number"
+                      value={config.reconnect?.max_attempts || 5}
+                      onChange={(e) =>
+                        setConfig((c) => ({
+                          ...c,
+                          reconnect: {
+                            ...c.reconnect,
+                            max_attempts: Math.max(1, Number(e.target.value)),
+                          },
+                        }))
+                      }
+                      inputProps={{ min: 1, max: 100 }}
+                    />
+                  </Box>
+                  <Box sx={{ width: 150 }}>
+                    <FieldLabel label="First Wait (s)" />
+                    <TextField
+                      size="small"
+                      fullWidth
+                      type="number"
+                      placeholder="1"
+                      value={config.reconnect?.initial_backoff || ""}
+                      onChange={(e) =>
+                        setConfig((c) => ({
+                          ...c,
+                          reconnect: {
+                            ...c.reconnect!,
+                            initial_backoff: Number(e.target.value) || undefined,
+                          },
+                        }))
+                      }
+                      inputProps={{ min: 1, max: 300 }}
+                    />
+                  </Box>
+                  <Box sx={{ width: 150 }}>
+                    <FieldLabel label="Longest Wait (s)" />
+                    <TextField
+                      size="small"
+                      fullWidth
+                      type="number"
+                      placeholder="30"
+                      value={config.reconnect?.max_backoff || ""}
+                      onChange={(e) =>
+                        setConfig((c) => ({
+                          ...c,
+                          reconnect: {
+                            ...c.reconnect!,
+                            max_backoff: Number(e.target.value) || undefined,
+                          },
+                        }))
+                      }
+                      inputProps={{ min: 1, max: 600 }}
+                    />
+                  </Box>
+                </Box>
+                <Box display="flex" gap={2}>
+                  <Box sx={{ width: 150 }}>
+                    <FieldLabel label="Keep Shell In" />
+                    <FormControl size="small" fullWidth>
+                      <Select
+                        value={config.reconnect?.multiplexer || ""}
+                        displayEmpty
+                        onChange={(e) =>
+                          setConfig((c) => ({
+                            ...c,
+                            reconnect: {
+                              ...c.reconnect!,
+                              multiplexer: e.target.value as any,
+                            },
+                          }))
+                        }
+                      >
+                        <MenuItem value="">Nothing</MenuItem>
+                        <MenuItem value="tmux">tmux</MenuItem>
+                        <MenuItem value="screen">screen</MenuItem>
+                        <MenuItem value="auto">tmux or screen</MenuItem>
+                      </Select>
+                    </FormControl>
+                  </Box>
+                  {config.reconnect?.multiplexer && (
+                    <Box flex={1}>
+                      <FieldLabel label="Session Name" />
+                      <TextField
+                        size="small"
+                        fullWidth
+                        placeholder="choraleia"
+                        value={config.reconnect?.session_name || ""}
+                        onChange={(e) =>
+                          setConfig((c) => ({
+                            ...c,
+                            reconnect: {
+                              ...c.reconnect!,
+                              session_name: e.target.value,
+                            },
+                          }))
+                        }
+                      />
+                    </Box>
+                  )}
+                </Box>
+              </>
+            )}
+          </Box>
+        </FormSection>
       </Box>
     );
   },
@@ -3,9 +3,11 @@ import { Box, List, ListItemButton, ListItemIcon, ListItemText, Typography } fro
 import SmartToyIcon from "@mui/icons-material/SmartToy";
 import TuneIcon from "@mui/icons-material/Tune";
 import InfoIcon from "@mui/icons-material/Info";
+import LockIcon from "@mui/icons-material/Lock";
 import Models from "./models";
+import Security from "./security";
 
-type SettingsMenu = "models" | "general" | "about";
+type SettingsMenu = "models" | "security" | "general" | "about";
 
 interface MenuItem {
   id: SettingsMenu;
@@ -15,6 +17,7 @@ interface MenuItem {
 
 const MENU_ITEMS: MenuItem[] = [
   { id: "models", label: "Models", icon: <SmartToyIcon fontSize="small" /> },
+  { id: "security", label: "Security", icon: <LockIcon fontSize="small" /> },
   { id: "general", label: "General", icon: <TuneIcon fontSize="small" /> },
   { id: "about", label: "About", icon: <InfoIcon fontSize="small" /> },
 ];
@@ -26,6 +29,8 @@ export const SettingsPage: React.FC = () => {
     switch (selectedMenu) {
       case "models":
         return <Models />;
+      case "security":
+        return <Security />;
       case "general":
         return (
           <Box p={3}>
@@ -0,0 +1,137 @@
+import React, { useEffect, useState } from "react";
+import { Alert, Box, Button, Stack, TextField, Typography } from "@mui/material";
+import {
+  getVaultStatus,
+  setVaultPassphrase,
+  unlockVault,
+  VaultStatus,
+} from "../../api/secrets";
+
+const SOURCE_LABELS: Record<VaultStatus["source"], string> = {
+  "": "Not set up yet, a key is created with the first saved secret",
+  keyring: "Master key in the OS keyring",
+  passphrase: "Master key derived from a passphrase",
+  file: "Master key in ~/.choraleia/master.key",
+};
+
+// Security settings: unlock the secret vault and manage its passphrase
+const Security: React.FC = () => {
+  const [status, setStatus] = useState<VaultStatus | null>(null);
+  const [error, setError] = useState<string | null>(null);
+  const [passphrase, setPassphrase] = useState("");
+  const [current, setCurrent] = useState("");
+  const [next, setNext] = useState("");
+  const [confirm, setConfirm] = useState("");
+
+  useEffect(() => {
+    getVaultStatus()
+      .then(setStatus)
+      .catch((e) => setError(String(e.message || e)));
+  }, []);
+
+  const run = async (action: () => Promise<VaultStatus>) => {
+    setError(null);
+    try {
+      setStatus(await action());
+      setPassphrase("");
+      setCurrent("");
+      setNext("");
+      setConfirm("");
+    } catch (e) {
+      setError(e instanceof Error ? e.message : String(e));
+    }
+  };
+
+  const hasPassphrase = status?.source === "passphrase";
+
+  return (
+    <Box p={3} maxWidth={520}>
+      <Typography variant="h6" mb={1}>
+        Security
+      </Typography>
+      <Typography variant="body2" color="text.secondary" mb={2}>
+        Asset passwords, private keys, model API keys and tool tokens are encrypted in
+        ~/.choraleia/secrets.json.
+      </Typography>
+      {error && (
+        <Alert severity="error" sx={{ mb: 2 }}>
+          {error}
+        </Alert>
+      )}
+      {status && (
+        <Typography variant="body2" mb={2}>
+          {SOURCE_LABELS[status.source]} · {status.count} secret{status.count === 1 ? "" : "s"}
+          {status.locked ? " · locked" : ""}
+        </Typography>
+      )}
+
+      {status?.locked && hasPassphrase && (
+        <Stack direction="row" spacing={1} mb={3}>
+          <TextField
+            size="small"
+            type="password"
+            label="Passphrase"
+            value={passphrase}
+            onChange={(e) => setPassphrase(e.target.value)}
+            fullWidth
+          />
+          <Button
+            variant="contained"
+            disabled={!passphrase}
+            onClick={() => run(() => unlockVault(passphrase))}
+          >
+            Unlock
+          </Button>
+        </Stack>
+      )}
+
+      {status && !status.locked && (
+        <Stack spacing={1.5}>
+          <Typography fontSize={14} fontWeight={600}>
+            {hasPassphrase ? "Change passphrase" : "Protect with a passphrase"}
+          </Typography>
+          <Typography variant="body2" color="text.secondary">
+            {hasPassphrase
+              ? "Leave the new passphrase empty to keep the key in the OS keyring instead."
+              : "The vault stays locked after each start until the passphrase is entered, or set CHORALEIA_MASTER_PASSPHRASE."}
+          </Typography>
+          {hasPassphrase && (
+            <TextField
+              size="small"
+              type="password"
+              label="Current passphrase"
+              value={current}
+              onChange={(e) => setCurrent(e.target.value)}
+            />
+          )}
+          <TextField
+            size="small"
+            type="password"
+            label="New passphrase"
+            value={next}
+            onChange={(e) => setNext(e.target.value)}
+          />
+          <TextField
+            size="small"
+            type="password"
+            label="Confirm new passphrase"
+            value={confirm}
+            error={confirm !== "" && confirm !== next}
+            onChange={(e) => setConfirm(e.target.value)}
+          />
+          <Box>
+            <Button
+              variant="contained"
+              disabled={next !== confirm || (!hasPassphrase && !next)}
+              onClick={() => run(() => setVaultPassphrase(current, next))}
+            >
+              {hasPassphrase && !next ? "Remove passphrase" : "Save"}
+            </Button>
+          </Box>
+        </Stack>
+      )}
+    </Box>
+  );
+};
+
+export default Security;
@@ -204,6 +204,58 @@ function ChipListInput({
   );
 }
 
+// JSON Schema Editor component (edits as text, commits only valid JSON objects)
+function JsonSchemaEditor({
+  label,
+  value,
+  onChange,
+}: {
+  label: string;
+  value?: Record<string, unknown>;
+  onChange: (value: Record<string, unknown> | undefined) => void;
+}) {
+  const [text, setText] = React.useState(() => (value ? JSON.stringify(value, null, 2) : ""));
+  const [error, setError] = React.useState<string | null>(null);
+
+  const handleChange = (next: string) => {
+    setText(next);
+    if (!next.trim()) {
+      setError(null);
+      onChange(undefined);
+      return;
+    }
+    try {
+      const parsed = JSON.parse(next);
+      if (typeof parsed !== "object" || parsed === null || Array.isArray(parsed)) {
+        setError("Schema must be a JSON object");
+        return;
+      }
+      setError(null);
+      onChange(parsed);
+    } catch {
+      setError("Invalid JSON");
+    }
+  };
+
+  return (
+    <Box>
+      <FieldLabel label={label} />
+      <TextField
+        size="small"
+        fullWidth
+        multiline
+        minRows={3}
+        placeholder={'{"type": "object", "properties": {"query": {"type": "string"}}}'}
+        value={text}
+        onChange={(e) => handleChange(e.target.value)}
+        error={!!error}
+        helperText={error || "Arguments are passed to the script as JSON on stdin and in CHORALEIA_TOOL_ARGS"}
+        sx={{ "& textarea": { fontFamily: "monospace", fontSize: 12 } }}
+      />
+    </Box>
+  );
+}
+
 // Environment Variables Editor component
 function EnvVarsEditor({
   env,
@@ -1151,6 +1203,15 @@ function ToolConfigItem({
                   })
                 }
               />
+              <JsonSchemaEditor
+                label="Input Schema (JSON Schema)"
+                value={tool.script?.inputSchema}
+                onChange={(inputSchema) =>
+                  onUpdate({
+                    script: { ...tool.script, runtime: tool.script?.runtime || "python", inputSchema },
+                  })
+                }
+              />
               <EnvVarsEditor
                 env={tool.script?.env}
                 onChange={(env) =>
@@ -2552,6 +2613,47 @@ const SpaceConfigDialog: React.FC<SpaceConfigDialogProps> = ({
               </Box>
             </FormSection>
 
+            <FormSection title="Tool Approval">
+              <Box display="flex" flexDirection="column" gap={2}>
+                <Box>
+                  <FieldLabel label="Ask Before Running" />
+                  <FormControl size="small" fullWidth>
+                    <Select
+                      value={state.tool_approval_mode || "dangerous"}
+                      onChange={(e) =>
+                        handleChange({ tool_approval_mode: e.target.value as SpaceConfigInput["tool_approval_mode"] })
+                      }
+                    >
+                      <MenuItem value="dangerous">Tools that can modify data</MenuItem>
+                      <MenuItem value="always">Every tool call</MenuItem>
+                      <MenuItem value="allowlist">Every tool not in the allowlist</MenuItem>
+                      <MenuItem value="never">Never</MenuItem>
+                    </Select>
+                    <FormHelperText>
+                      The agent pauses until you approve or reject the call in the chat
+                    </FormHelperText>
+                  </FormControl>
+                </Box>
+
+                <Collapse in={state.tool_approval_mode === "allowlist"}>
+                  <ChipListInput
+                    label="Allowed Tools"
+                    items={state.tool_approval_allowlist || []}
+                    onAdd={(item) =>
+                      handleChange({ tool_approval_allowlist: [...(state.tool_approval_allowlist || []), item] })
+                    }
+                    onRemove={(item) =>
+                      handleChange({
+                        tool_approval_allowlist: (state.tool_approval_allowlist || []).filter((t) => t !== item),
+                      })
+                    }
+                    placeholder="Tool name, press Enter to add"
+                    chipColor="success"
+                  />
+                </Collapse>
+              </Box>
+            </FormSection>
+
             <FormSection title="Compression & Memory">
               <Box display="flex" flexDirection="column" gap={2}>
                 {/* Compression Configuration */}
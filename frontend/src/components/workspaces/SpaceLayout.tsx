@@ -231,6 +231,9 @@ const SpaceLayout: React.FC<SpaceLayoutProps> = ({ onBackToOverview }) => {
       embedding_model: activeWorkspace.embedding_model,
       embedding_dimension: activeWorkspace.embedding_dimension,
       extraction_model: activeWorkspace.extraction_model,
+      // Tool approval configuration
+      tool_approval_mode: activeWorkspace.tool_approval_mode,
+      tool_approval_allowlist: activeWorkspace.tool_approval_allowlist,
     };
   }, [activeWorkspace]);
 
@@ -313,6 +313,9 @@ const SpacesView: React.FC = () => {
         embedding_model: editingWorkspace.embedding_model,
         embedding_dimension: editingWorkspace.embedding_dimension,
         extraction_model: editingWorkspace.extraction_model,
+        // Tool approval configuration
+        tool_approval_mode: editingWorkspace.tool_approval_mode,
+        tool_approval_allowlist: editingWorkspace.tool_approval_allowlist,
       };
     }
     return createRoomConfigTemplate(`workspace-${workspaces.length + 1}`);
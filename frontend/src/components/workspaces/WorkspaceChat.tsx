@@ -60,7 +60,7 @@ type UIMessage = {
 };
 
 // Convert MessagePart to UI content format
-function partToUIContent(part: MessagePart, toolResultsMap: Map<string, string>): any | null {
+function partToUIContent(part: MessagePart, toolResultsMap: Map<string, string>, approvalsMap: Map<string, any>): any | null {
   switch (part.type) {
     case "text":
       return part.text ? { type: "text", text: part.text } : null;
@@ -74,11 +74,13 @@ function partToUIContent(part: MessagePart, toolResultsMap: Map<string, string>)
           toolName: part.tool_call.name,
           argsText: part.tool_call.arguments,
           result: toolResultsMap.get(part.tool_call.id),
+          artifact: approvalsMap.get(part.tool_call.id),
         };
       }
       return null;
     case "tool_result":
-      // Tool results are merged into tool-call parts, not displayed separately
+    case "approval":
+      // Tool results and approvals are merged into tool-call parts, not displayed separately
       return null;
     case "image_url":
       return part.image_url ? { type: "image", url: part.image_url.url, detail: part.image_url.detail } : null;
@@ -94,13 +96,13 @@ function partToUIContent(part: MessagePart, toolResultsMap: Map<string, string>)
 }
 
 // Convert Message to UIMessage
-function storedToUIMessage(msg: Message, toolResultsMap: Map<string, string>): UIMessage | null {
+function storedToUIMessage(msg: Message, toolResultsMap: Map<string, string>, approvalsMap: Map<string, any>): UIMessage | null {
   const content: any[] = [];
 
   // Convert parts to UI content
   if (msg.parts && msg.parts.length > 0) {
     for (const part of msg.parts) {
-      const uiContent = partToUIContent(part, toolResultsMap);
+      const uiContent = partToUIContent(part, toolResultsMap, approvalsMap);
       if (uiContent) {
         content.push(uiContent);
       }
@@ -124,21 +126,25 @@ function storedToUIMessage(msg: Message, toolResultsMap: Map<string, string>): U
 
 // Convert Message array to UIMessage array
 function storedMessagesToUIMessages(apiMessages: Message[]): UIMessage[] {
-  // Build tool results map from tool_result parts
+  // Build tool results and approvals maps from tool_result and approval parts
   const toolResultsMap = new Map<string, string>();
+  const approvalsMap = new Map<string, any>();
   for (const msg of apiMessages) {
     if (msg.parts) {
       for (const part of msg.parts) {
         if (part.type === "tool_result" && part.tool_result) {
           toolResultsMap.set(part.tool_result.tool_call_id, part.tool_result.content);
         }
+        if (part.type === "approval" && part.approval) {
+          approvalsMap.set(part.approval.tool_call_id, { approval: part.approval, conversationId: msg.conversation_id });
+        }
       }
     }
   }
 
   const result: UIMessage[] = [];
   for (const msg of apiMessages) {
-    const converted = storedToUIMessage(msg, toolResultsMap);
+    const converted = storedToUIMessage(msg, toolResultsMap, approvalsMap);
     if (converted) result.push(converted);
   }
 
@@ -394,6 +400,18 @@ export default function WorkspaceChat({ workspaceId, onConversationChange }: Wor
             continue;
           }
 
+          // Approval requests and decisions - attach to the tool-call part
+          if (choice.delta.approval) {
+            const approval = choice.delta.approval;
+            const toolCallPart = contentParts.find(
+                p => p.type === "tool-call" && p.toolCallId === approval.tool_call_id
+            );
+            if (toolCallPart) {
+              toolCallPart.artifact = { approval, conversationId: chunk.conversation_id };
+            }
+            continue;
+          }
+
           // Tool results - find tool-call part by tool_call_id and update result
           if (choice.delta.role === "tool" && choice.delta.tool_call_id) {
             const toolCallId = choice.delta.tool_call_id;
@@ -616,6 +634,18 @@ export default function WorkspaceChat({ workspaceId, onConversationChange }: Wor
           continue;
         }
 
+        // Approval requests and decisions - attach to the tool-call part
+        if (choice.delta.approval) {
+          const approval = choice.delta.approval;
+          const toolCallPart = contentParts.find(
+              p => p.type === "tool-call" && p.toolCallId === approval.tool_call_id
+          );
+          if (toolCallPart) {
+            toolCallPart.artifact = { approval, conversationId: chunk.conversation_id };
+          }
+          continue;
+        }
+
         // Tool results - find tool-call part by tool_call_id and update result
         if (choice.delta.role === "tool" && choice.delta.tool_call_id) {
           const toolCallId = choice.delta.tool_call_id;
@@ -17,17 +17,31 @@ import CheckIcon from "@mui/icons-material/Check";
 import CheckCircleOutlineIcon from "@mui/icons-material/CheckCircleOutline";
 import ErrorOutlineIcon from "@mui/icons-material/ErrorOutline";
 import BuildIcon from "@mui/icons-material/Build";
+import HourglassEmptyIcon from "@mui/icons-material/HourglassEmpty";
+import Button from "@mui/material/Button";
 import { useTheme, alpha } from "@mui/material/styles";
+import { ApprovalPart, resolveToolApproval } from "../../../api/chat";
 
 // Tool status type
-type ToolStatus = "running" | "success" | "error";
+type ToolStatus = "approval" | "running" | "success" | "error";
+
+// Approval state attached to a tool-call part
+interface ApprovalArtifact {
+    approval: ApprovalPart;
+    conversationId?: string;
+}
 
 export const ToolFallback: ToolCallMessagePartComponent = ({
+                                                               toolCallId,
                                                                toolName,
                                                                argsText,
                                                                result,
+                                                               artifact,
                                                            }) => {
     const [isExpanded, setIsExpanded] = useState(false);
+    const [answering, setAnswering] = useState(false);
+    const approval = (artifact as ApprovalArtifact | undefined)?.approval;
+    const conversationId = (artifact as ApprovalArtifact | undefined)?.conversationId;
     const [argsCopied, setArgsCopied] = useState(false);
     const [resultCopied, setResultCopied] = useState(false);
     const theme = useTheme();
@@ -35,10 +49,23 @@ export const ToolFallback: ToolCallMessagePartComponent = ({
 
     // Determine tool status
     const status: ToolStatus = useMemo(() => {
+        if (result === undefined && approval?.status === "pending") return "approval";
         if (result === undefined) return "running";
         if (typeof result === "string" && result.startsWith("Error:")) return "error";
         return "success";
-    }, [result]);
+    }, [result, approval?.status]);
+
+    const answerApproval = async (approved: boolean) => {
+        if (!conversationId) return;
+        setAnswering(true);
+        try {
+            await resolveToolApproval(conversationId, approval?.tool_call_id || toolCallId, approved);
+        } catch (err) {
+            console.error("Failed to answer tool approval:", err);
+        } finally {
+            setAnswering(false);
+        }
+    };
 
     const toggleExpanded = () => setIsExpanded((p) => !p);
 
@@ -111,6 +138,14 @@ export const ToolFallback: ToolCallMessagePartComponent = ({
             : "rgba(255,255,255,0.08)";
 
         switch (status) {
+            case "approval":
+                return {
+                    icon: <HourglassEmptyIcon sx={{ fontSize: 16, color: theme.palette.warning.main }} />,
+                    iconColor: theme.palette.warning.main,
+                    bgColor,
+                    headerBgColor,
+                    label: "Waiting for approval",
+                };
             case "running":
                 return {
                     icon: <CircularProgress size={14} thickness={4} sx={{ color: theme.palette.info.main }} />,
@@ -221,6 +256,28 @@ export const ToolFallback: ToolCallMessagePartComponent = ({
                 </Box>
             </Box>
 
+            {/* Approval request */}
+            {status === "approval" && (
+                <Box sx={{ display: "flex", alignItems: "center", gap: 1, px: 1.5, py: 1 }}>
+                    <Typography variant="caption" sx={{ color: theme.palette.text.secondary, flex: 1 }}>
+                        This tool call needs your approval before it runs.
+                    </Typography>
+                    <Button size="small" color="error" disabled={answering} onClick={() => answerApproval(false)}>
+                        Reject
+                    </Button>
+                    <Button size="small" variant="contained" disabled={answering} onClick={() => answerApproval(true)}>
+                        Approve
+                    </Button>
+                </Box>
+            )}
+            {approval && approval.status !== "pending" && (
+                <Typography variant="caption" sx={{ display: "block", px: 1.5, pt: 0.5, color: theme.palette.text.secondary }}>
+                    {approval.status === "approved" ? "Approved" : "Rejected"}
+                    {approval.decided_by ? ` by ${approval.decided_by}` : ""}
+                    {approval.reason ? `: ${approval.reason}` : ""}
+                </Typography>
+            )}
+
             {/* Content */}
             <Collapse in={isExpanded} unmountOnExit timeout={150}>
                 <Divider sx={{ mb: 0 }} />
@@ -252,6 +252,7 @@ export type MCPSSEConfig = {
   timeout?: number;          // Connection timeout in ms
   reconnect?: boolean;       // Auto-reconnect on disconnect (default: true)
   reconnectInterval?: number; // Reconnect interval in ms (default: 1000)
+  reconnectAttempts?: number; // Attempts before giving up (default: until stopped)
 };
 
 // MCP HTTP configuration (Streamable HTTP)
@@ -289,6 +290,7 @@ export type ScriptConfig = {
   cwd?: string;              // Working directory
   timeout?: number;          // Execution timeout in ms
   runtimeEnv?: RuntimeEnv;   // Where to run: "local" (host machine) or "workspace" (container/pod)
+  inputSchema?: Record<string, unknown>; // JSON schema for tool arguments (passed as JSON on stdin)
 };
 
 // Built-in tool options
@@ -474,6 +476,9 @@ export type SpaceConfigInput = {
   embedding_model?: string;
   embedding_dimension?: number;
   extraction_model?: string;
+  // Tool approval configuration
+  tool_approval_mode?: "always" | "dangerous" | "never" | "allowlist";
+  tool_approval_allowlist?: string[];
 };
 
 // Validate workspace name for K8s/DNS compatibility
@@ -887,6 +892,9 @@ export type Workspace = {
   embedding_model?: string;
   embedding_dimension?: number;
   extraction_model?: string;
+  // Tool approval configuration
+  tool_approval_mode?: "always" | "dangerous" | "never" | "allowlist";
+  tool_approval_allowlist?: string[];
   // File tree loaded from runtime environment (not persisted)
   fileTree: FileNode[];
   fileTreeLoading?: boolean;
@@ -1018,6 +1026,8 @@ export const createRoomConfigTemplate = (name = "new-space"): SpaceConfigInput =
   embedding_model: undefined,
   embedding_dimension: undefined,
   extraction_model: undefined,
+  tool_approval_mode: "dangerous",
+  tool_approval_allowlist: [],
 });
 
 const findFileNode = (nodes: FileNode[], path: string): FileNode | undefined => {
@@ -1224,6 +1234,9 @@ const convertBackendWorkspace = (ws: workspacesApi.Workspace): Workspace => {
     embedding_model: ws.embedding_model,
     embedding_dimension: ws.embedding_dimension,
     extraction_model: ws.extraction_model,
+    // Tool approval configuration
+    tool_approval_mode: ws.tool_approval_mode,
+    tool_approval_allowlist: ws.tool_approval_allowlist,
     workMode: "chat",  // Default to chat mode
     fileTree: [],  // Will be loaded from runtime environment
     fileTreeLoading: false,
@@ -1244,6 +1257,9 @@ const convertToBackendRequest = (ws: Workspace): workspacesApi.CreateWorkspaceRe
     embedding_model: ws.embedding_model,
     embedding_dimension: ws.embedding_dimension,
     extraction_model: ws.extraction_model,
+    // Tool approval configuration
+    tool_approval_mode: ws.tool_approval_mode,
+    tool_approval_allowlist: ws.tool_approval_allowlist,
     runtime: ws.runtime ? {
       type: ws.runtime.type,
       docker_asset_id: ws.runtime.dockerAssetId,
@@ -1769,6 +1785,9 @@ export const WorkspaceProvider: React.FC<React.PropsWithChildren> = ({
         embedding_model: config.embedding_model,
         embedding_dimension: config.embedding_dimension,
         extraction_model: config.extraction_model,
+        // Tool approval configuration
+        tool_approval_mode: config.tool_approval_mode,
+        tool_approval_allowlist: config.tool_approval_allowlist,
         rooms: [newRoom],
         activeRoomId: newRoom.id,
         fileTree: [],
@@ -1825,6 +1844,9 @@ export const WorkspaceProvider: React.FC<React.PropsWithChildren> = ({
                 embedding_model: config.embedding_model,
                 embedding_dimension: config.embedding_dimension,
                 extraction_model: config.extraction_model,
+                // Tool approval configuration
+                tool_approval_mode: config.tool_approval_mode,
+                tool_approval_allowlist: config.tool_approval_allowlist,
               }
             : workspace,
         ),
@@ -1843,6 +1865,9 @@ export const WorkspaceProvider: React.FC<React.PropsWithChildren> = ({
           embedding_model: config.embedding_model,
           embedding_dimension: config.embedding_dimension,
           extraction_model: config.extraction_model,
+          // Tool approval configuration
+          tool_approval_mode: config.tool_approval_mode,
+          tool_approval_allowlist: config.tool_approval_allowlist,
           runtime: config.runtime ? {
             type: config.runtime.type,
             docker_asset_id: config.runtime.dockerAssetId,
@@ -82,7 +82,7 @@ export function useAssetList() {
 
   return useQuery({
     queryKey: assetKeys.lists(),
-    queryFn: listAssets,
+    queryFn: () => listAssets(),
   });
 }
 
@@ -21,6 +21,11 @@ export {
   useStartTunnel,
   useStopTunnel,
   useInvalidateTunnels,
+  useTunnelConnections,
+  useTunnelHistory,
+  useCloseTunnelConnection,
+  useSavePortForward,
+  useDeletePortForward,
   initTunnelEvents,
 } from "./tunnelStore";
 
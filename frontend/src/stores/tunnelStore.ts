@@ -4,6 +4,7 @@
 // - Global event subscription (not per-hook) to avoid duplicate listeners
 // - Tunnel created/deleted → refresh the list
 // - Tunnel status changed → update single tunnel in cache
+// - Tunnel metrics → update its traffic in cache, refresh its connections
 
 import { useQuery, useMutation, useQueryClient, QueryClient } from "@tanstack/react-query";
 import {
@@ -11,10 +12,15 @@ import {
   getTunnelStats,
   startTunnel,
   stopTunnel,
+  listTunnelConnections,
+  closeTunnelConnection,
+  getTunnelHistory,
+  savePortForward,
+  deletePortForward,
 } from "../api/tunnels";
-import type { TunnelInfo, TunnelStats, TunnelListResponse } from "../api/tunnels";
+import type { TunnelInfo, TunnelStats, TunnelListResponse, PortForwardRequest } from "../api/tunnels";
 import { eventClient, RECONNECT_EVENT } from "../api/event_hooks";
-import { Events, type TunnelEventData } from "../api/events";
+import { Events, type TunnelEventData, type TunnelMetricsEventData } from "../api/events";
 
 // ============================================================================
 // Query Keys
@@ -24,6 +30,8 @@ export const tunnelKeys = {
   all: ["tunnels"] as const,
   lists: () => [...tunnelKeys.all, "list"] as const,
   stats: () => [...tunnelKeys.all, "stats"] as const,
+  connections: (id: string) => [...tunnelKeys.all, "connections", id] as const,
+  history: (id: string, minutes: number) => [...tunnelKeys.all, "history", id, minutes] as const,
 };
 
 // ============================================================================
@@ -40,11 +48,15 @@ export function initTunnelEvents(queryClient: QueryClient) {
   if (tunnelEventsInitialized) return;
   tunnelEventsInitialized = true;
 
-  // On tunnel created/deleted, refresh the list
+  // On tunnel created/updated/deleted, refresh the list
   eventClient.on(Events.TUNNEL_CREATED, () => {
     queryClient.invalidateQueries({ queryKey: tunnelKeys.all });
   });
 
+  eventClient.on(Events.TUNNEL_UPDATED, () => {
+    queryClient.invalidateQueries({ queryKey: tunnelKeys.all });
+  });
+
   eventClient.on(Events.TUNNEL_DELETED, () => {
     queryClient.invalidateQueries({ queryKey: tunnelKeys.all });
   });
@@ -60,7 +72,7 @@ export function initTunnelEvents(queryClient: QueryClient) {
 
         const tunnels = old.tunnels.map((t) =>
           t.id === data.TunnelID && data.Status
-            ? { ...t, status: data.Status as TunnelInfo["status"] }
+            ? { ...t, status: data.Status as TunnelInfo["status"], error_message: data.Message || undefined }
             : t
         );
 
@@ -72,6 +84,35 @@ export function initTunnelEvents(queryClient: QueryClient) {
     );
   });
 
+  // On metrics, update the tunnel's traffic in cache and refresh its
+  // connections if they are being watched
+  eventClient.on<TunnelMetricsEventData>(Events.TUNNEL_METRICS, (data) => {
+    if (!data.TunnelID) return;
+
+    queryClient.setQueryData<TunnelListResponse>(
+      tunnelKeys.lists(),
+      (old) => {
+        if (!old) return old;
+
+        const tunnels = old.tunnels.map((t) =>
+          t.id === data.TunnelID
+            ? {
+                ...t,
+                bytes_sent: data.BytesSent,
+                bytes_received: data.BytesReceived,
+                connections: data.Connections,
+                send_rate: data.SendRate,
+                receive_rate: data.ReceiveRate,
+              }
+            : t
+        );
+
+        return { tunnels, stats: calculateStats(tunnels) };
+      }
+    );
+    queryClient.invalidateQueries({ queryKey: tunnelKeys.connections(data.TunnelID) });
+  });
+
   // On reconnect, refresh all tunnel queries
   eventClient.on(RECONNECT_EVENT, () => {
     queryClient.invalidateQueries({ queryKey: tunnelKeys.all });
@@ -106,6 +147,7 @@ export function useTunnels() {
       running: 0,
       stopped: 0,
       error: 0,
+      reconnecting: 0,
       total_bytes_sent: 0,
       total_bytes_received: 0,
     },
@@ -147,6 +189,69 @@ export function useStopTunnel() {
   });
 }
 
+/**
+ * Hook to fetch a tunnel's open and recently closed connections.
+ * Refreshed by tunnel.metrics events.
+ */
+export function useTunnelConnections(tunnelId: string | null) {
+  return useQuery({
+    queryKey: tunnelKeys.connections(tunnelId ?? ""),
+    queryFn: () => listTunnelConnections(tunnelId!),
+    enabled: !!tunnelId,
+  });
+}
+
+/**
+ * Hook to fetch a tunnel's traffic per minute.
+ */
+export function useTunnelHistory(tunnelId: string | null, minutes = 60) {
+  return useQuery({
+    queryKey: tunnelKeys.history(tunnelId ?? "", minutes),
+    queryFn: () => getTunnelHistory(tunnelId!, minutes),
+    enabled: !!tunnelId,
+    refetchInterval: 60_000,
+  });
+}
+
+/**
+ * Hook to force-close a tunnel connection.
+ */
+export function useCloseTunnelConnection() {
+  const queryClient = useQueryClient();
+  return useMutation({
+    mutationFn: ({ tunnelId, connId }: { tunnelId: string; connId: string }) =>
+      closeTunnelConnection(tunnelId, connId),
+    onSuccess: (_, { tunnelId }) => {
+      queryClient.invalidateQueries({ queryKey: tunnelKeys.connections(tunnelId) });
+    },
+  });
+}
+
+/**
+ * Hook to create a standalone port forward, or update one given its ID.
+ * Updates emit no event, so the list is refreshed here.
+ */
+export function useSavePortForward() {
+  const queryClient = useQueryClient();
+  return useMutation({
+    mutationFn: ({ req, tunnelId }: { req: PortForwardRequest; tunnelId?: string }) =>
+      savePortForward(req, tunnelId),
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: tunnelKeys.lists() });
+    },
+  });
+}
+
+/**
+ * Hook to delete a standalone port forward.
+ * Note: The list is refreshed by the tunnel.deleted event.
+ */
+export function useDeletePortForward() {
+  return useMutation({
+    mutationFn: deletePortForward,
+  });
+}
+
 /**
  * Hook to manually invalidate tunnel queries.
  */
@@ -166,6 +271,7 @@ function calculateStats(tunnels: TunnelInfo[]): TunnelStats {
   let running = 0;
   let stopped = 0;
   let error = 0;
+  let reconnecting = 0;
   let total_bytes_sent = 0;
   let total_bytes_received = 0;
 
@@ -173,6 +279,7 @@ function calculateStats(tunnels: TunnelInfo[]): TunnelStats {
     if (t.status === "running") running++;
     else if (t.status === "stopped") stopped++;
     else if (t.status === "error") error++;
+    else if (t.status === "reconnecting") reconnecting++;
     total_bytes_sent += t.bytes_sent ?? 0;
     total_bytes_received += t.bytes_received ?? 0;
   }
@@ -182,6 +289,7 @@ function calculateStats(tunnels: TunnelInfo[]): TunnelStats {
     running,
     stopped,
     error,
+    reconnecting,
     total_bytes_sent,
     total_bytes_received,
   };
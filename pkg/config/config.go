@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -19,16 +20,26 @@ import (
 // server:
 //   host: 127.0.0.1
 //   port: 8088
+// terminal:
+//   idle_timeout: 30m        # how long a detached session is kept
+//   scrollback_bytes: 1048576
+//   recording:
+//     enabled: false         # record every session; assets can opt in on their own
+//     retention: 720h        # remove recordings older than this
+//     max_bytes: 1073741824  # remove the oldest recordings past this total
 //
 // Notes:
 // - If the config file does not exist, Load returns defaults without error.
 // - If the config file exists but cannot be parsed, Load returns an error.
 // - Port must be between 1 and 65535.
+// - terminal.idle_timeout 0 ends a session as soon as its client disconnects.
+// - terminal.recording.retention and max_bytes 0 disable that limit.
 //
 // All code and comments must be in English.
 
 type AppConfig struct {
-	Server ServerConfig `yaml:"server"`
+	Server   ServerConfig   `yaml:"server"`
+	Terminal TerminalConfig `yaml:"terminal,omitempty"`
 }
 
 type ServerConfig struct {
@@ -36,9 +47,29 @@ type ServerConfig struct {
 	Port *int    `yaml:"port"`
 }
 
+// TerminalConfig controls terminal sessions that outlive their WebSocket
+type TerminalConfig struct {
+	IdleTimeout     *time.Duration  `yaml:"idle_timeout,omitempty"`
+	ScrollbackBytes *int            `yaml:"scrollback_bytes,omitempty"`
+	Recording       RecordingConfig `yaml:"recording,omitempty"`
+}
+
+// RecordingConfig controls asciicast recordings of terminal sessions
+type RecordingConfig struct {
+	Enabled   *bool          `yaml:"enabled,omitempty"`
+	Retention *time.Duration `yaml:"retention,omitempty"`
+	MaxBytes  *int64         `yaml:"max_bytes,omitempty"`
+}
+
 const (
 	DefaultHost = "127.0.0.1"
 	DefaultPort = 8088
+
+	DefaultTerminalIdleTimeout = 30 * time.Minute
+	DefaultScrollbackBytes     = 1 << 20
+
+	DefaultRecordingRetention = 30 * 24 * time.Hour
+	DefaultRecordingMaxBytes  = 1 << 30
 )
 
 // DefaultPaths returns the config dir and config file path.
@@ -86,6 +117,22 @@ func Load() (*AppConfig, string, error) {
 		return nil, "", fmt.Errorf("invalid server.port %d in %s", port, configFile)
 	}
 
+	if idle := cfg.TerminalIdleTimeout(); idle < 0 {
+		return nil, "", fmt.Errorf("invalid terminal.idle_timeout %s in %s", idle, configFile)
+	}
+
+	if size := cfg.ScrollbackBytes(); size < 1 {
+		return nil, "", fmt.Errorf("invalid terminal.scrollback_bytes %d in %s", size, configFile)
+	}
+
+	if retention := cfg.RecordingRetention(); retention < 0 {
+		return nil, "", fmt.Errorf("invalid terminal.recording.retention %s in %s", retention, configFile)
+	}
+
+	if size := cfg.RecordingMaxBytes(); size < 0 {
+		return nil, "", fmt.Errorf("invalid terminal.recording.max_bytes %d in %s", size, configFile)
+	}
+
 	return cfg, configFile, nil
 }
 
@@ -143,4 +190,47 @@ func (c *AppConfig) Port() int {
 	return *c.Server.Port
 }
 
+// TerminalIdleTimeout is how long a terminal session without a client is
+// kept for reattaching
+func (c *AppConfig) TerminalIdleTimeout() time.Duration {
+	if c == nil || c.Terminal.IdleTimeout == nil {
+		return DefaultTerminalIdleTimeout
+	}
+	return *c.Terminal.IdleTimeout
+}
+
+// ScrollbackBytes is how much output each terminal session keeps for
+// replaying to a reattaching client
+func (c *AppConfig) ScrollbackBytes() int {
+	if c == nil || c.Terminal.ScrollbackBytes == nil {
+		return DefaultScrollbackBytes
+	}
+	return *c.Terminal.ScrollbackBytes
+}
+
+// RecordAllSessions reports whether every terminal session is recorded,
+// not only those of assets that ask for it
+func (c *AppConfig) RecordAllSessions() bool {
+	if c == nil || c.Terminal.Recording.Enabled == nil {
+		return false
+	}
+	return *c.Terminal.Recording.Enabled
+}
+
+// RecordingRetention is how long terminal recordings are kept
+func (c *AppConfig) RecordingRetention() time.Duration {
+	if c == nil || c.Terminal.Recording.Retention == nil {
+		return DefaultRecordingRetention
+	}
+	return *c.Terminal.Recording.Retention
+}
+
+// RecordingMaxBytes is how much space terminal recordings may take in total
+func (c *AppConfig) RecordingMaxBytes() int64 {
+	if c == nil || c.Terminal.Recording.MaxBytes == nil {
+		return DefaultRecordingMaxBytes
+	}
+	return *c.Terminal.Recording.MaxBytes
+}
+
 func ptr[T any](v T) *T { return &v }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoad_MissingFile_ReturnsDefault(t *testing.T) {
@@ -99,3 +100,64 @@ func TestLoad_ParsesPort(t *testing.T) {
 		t.Fatalf("cfg.Port() = %d, want %d", got, 9090)
 	}
 }
+
+func TestLoad_ParsesTerminal(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	configDir := filepath.Join(home, ".choraleia")
+	if err := os.MkdirAll(configDir, 0o700); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+	configPath := filepath.Join(configDir, "config.yaml")
+
+	if err := os.WriteFile(configPath, []byte("terminal:\n  idle_timeout: 2h\n  scrollback_bytes: 4096\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, _, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.TerminalIdleTimeout(); got != 2*time.Hour {
+		t.Fatalf("cfg.TerminalIdleTimeout() = %s, want %s", got, 2*time.Hour)
+	}
+	if got := cfg.ScrollbackBytes(); got != 4096 {
+		t.Fatalf("cfg.ScrollbackBytes() = %d, want %d", got, 4096)
+	}
+	if cfg.RecordAllSessions() {
+		t.Fatalf("cfg.RecordAllSessions() = true, want false")
+	}
+	if got := cfg.RecordingRetention(); got != DefaultRecordingRetention {
+		t.Fatalf("cfg.RecordingRetention() = %s, want %s", got, DefaultRecordingRetention)
+	}
+}
+
+func TestLoad_ParsesRecording(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	configDir := filepath.Join(home, ".choraleia")
+	if err := os.MkdirAll(configDir, 0o700); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+	configPath := filepath.Join(configDir, "config.yaml")
+
+	if err := os.WriteFile(configPath, []byte("terminal:\n  recording:\n    enabled: true\n    retention: 168h\n    max_bytes: 0\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, _, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.RecordAllSessions() {
+		t.Fatalf("cfg.RecordAllSessions() = false, want true")
+	}
+	if got := cfg.RecordingRetention(); got != 168*time.Hour {
+		t.Fatalf("cfg.RecordingRetention() = %s, want %s", got, 168*time.Hour)
+	}
+	if got := cfg.RecordingMaxBytes(); got != 0 {
+		t.Fatalf("cfg.RecordingMaxBytes() = %d, want 0", got)
+	}
+}
@@ -61,6 +61,14 @@ const (
 	ChunkTypeAudioURL   = "audio_url"   // Audio
 	ChunkTypeVideoURL   = "video_url"   // Video
 	ChunkTypeFileURL    = "file_url"    // File
+	ChunkTypeApproval   = "approval"    // Tool call approval request or decision
+)
+
+// Tool call approval status constants
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
 )
 
 // MessageChunk represents a single chunk of a message stored in database
@@ -87,6 +95,10 @@ type MessageChunk struct {
 	// Tool result fields (for tool_result type)
 	ToolResultContent string `json:"tool_result_content,omitempty" gorm:"type:text"`
 
+	// Approval fields (for approval type, with the tool call fields above)
+	ApprovalStatus string `json:"approval_status,omitempty" gorm:"size:20"` // pending, approved, rejected
+	ApprovalBy     string `json:"approval_by,omitempty" gorm:"size:100"`    // Who answered the request
+
 	// Media fields (for image_url, audio_url, video_url, file_url types)
 	MediaURL      string `json:"media_url,omitempty" gorm:"type:text"`
 	MediaMimeType string `json:"media_mime_type,omitempty" gorm:"size:100"`
@@ -113,6 +125,7 @@ type MessagePart struct {
 	Text       string          `json:"text,omitempty"`
 	ToolCall   *ToolCallPart   `json:"tool_call,omitempty"`
 	ToolResult *ToolResultPart `json:"tool_result,omitempty"`
+	Approval   *ApprovalPart   `json:"approval,omitempty"`
 	ImageURL   *ImageURLPart   `json:"image_url,omitempty"`
 	AudioURL   *AudioURLPart   `json:"audio_url,omitempty"`
 	VideoURL   *VideoURLPart   `json:"video_url,omitempty"`
@@ -131,6 +144,15 @@ type ToolResultPart struct {
 	Content    string `json:"content"`
 }
 
+type ApprovalPart struct {
+	ToolCallID string `json:"tool_call_id"`
+	Name       string `json:"name"`
+	Arguments  string `json:"arguments,omitempty"`
+	Status     string `json:"status"`               // pending, approved, rejected
+	DecidedBy  string `json:"decided_by,omitempty"` // Who approved or rejected the call
+	Reason     string `json:"reason,omitempty"`
+}
+
 type ImageURLPart struct {
 	URL    string `json:"url"`
 	Detail string `json:"detail,omitempty"`
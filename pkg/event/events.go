@@ -13,8 +13,12 @@ const (
 	AssetUpdated        = "asset.updated"
 	AssetDeleted        = "asset.deleted"
 	TunnelCreated       = "tunnel.created"
+	TunnelUpdated       = "tunnel.updated"
 	TunnelStatusChanged = "tunnel.statusChanged"
 	TunnelDeleted       = "tunnel.deleted"
+	TunnelMetrics       = "tunnel.metrics"
+	TerminalBroadcast   = "terminal.broadcastChanged"
+	TerminalTrigger     = "terminal.trigger"
 	ContainerStatus     = "container.statusChanged"
 	ContainerList       = "container.listChanged"
 	TaskCreated         = "task.created"
@@ -24,6 +28,8 @@ const (
 	AgentMetrics        = "agent.metrics"
 	AgentDisconnected   = "agent.disconnected"
 	ConfigChanged       = "system.configChanged"
+	ApprovalRequested   = "chat.approvalRequested"
+	ApprovalResolved    = "chat.approvalResolved"
 )
 
 // ============================================================================
@@ -101,10 +107,19 @@ type TunnelCreatedEvent struct {
 
 func (e TunnelCreatedEvent) EventName() string { return TunnelCreated }
 
+// TunnelUpdatedEvent is emitted when a tunnel's settings change.
+type TunnelUpdatedEvent struct {
+	TunnelID string
+	AssetID  string
+}
+
+func (e TunnelUpdatedEvent) EventName() string { return TunnelUpdated }
+
 // TunnelStatusChangedEvent is emitted when tunnel status changes.
 type TunnelStatusChangedEvent struct {
 	TunnelID string
-	Status   string // "running", "stopped", "error"
+	Status   string // "running", "stopped", "error", "reconnecting"
+	Message  string // why it failed or is restarting
 }
 
 func (e TunnelStatusChangedEvent) EventName() string { return TunnelStatusChanged }
@@ -116,6 +131,44 @@ type TunnelDeletedEvent struct {
 
 func (e TunnelDeletedEvent) EventName() string { return TunnelDeleted }
 
+// TunnelMetricsEvent is emitted at most every few seconds while a tunnel's
+// traffic or connections change.
+type TunnelMetricsEvent struct {
+	TunnelID      string
+	BytesSent     int64 // totals since the tunnel was loaded
+	BytesReceived int64
+	Connections   int32 // open now
+	SendRate      int64 // bytes per second since the last event
+	ReceiveRate   int64
+}
+
+func (e TunnelMetricsEvent) EventName() string { return TunnelMetrics }
+
+// ============================================================================
+// Terminal Events
+// ============================================================================
+
+// TerminalBroadcastChangedEvent is emitted when a broadcast group is created,
+// deleted or changes its members.
+type TerminalBroadcastChangedEvent struct {
+	GroupID    string
+	SessionIDs []string // Members affected
+}
+
+func (e TerminalBroadcastChangedEvent) EventName() string { return TerminalBroadcast }
+
+// TerminalTriggerEvent is emitted when a notify trigger matches a session's
+// output.
+type TerminalTriggerEvent struct {
+	SessionID string
+	AssetID   string
+	TriggerID string
+	Name      string
+	Message   string
+}
+
+func (e TerminalTriggerEvent) EventName() string { return TerminalTrigger }
+
 // ============================================================================
 // Docker/Container Events
 // ============================================================================
@@ -192,6 +245,29 @@ type AgentDisconnectedEvent struct {
 
 func (e AgentDisconnectedEvent) EventName() string { return AgentDisconnected }
 
+// ============================================================================
+// Chat Events
+// ============================================================================
+
+// ApprovalRequestedEvent is emitted when an agent tool call waits for approval.
+type ApprovalRequestedEvent struct {
+	ConversationID string
+	MessageID      string
+	ToolCallID     string
+	ToolName       string
+}
+
+func (e ApprovalRequestedEvent) EventName() string { return ApprovalRequested }
+
+// ApprovalResolvedEvent is emitted when a tool call is approved or rejected.
+type ApprovalResolvedEvent struct {
+	ConversationID string
+	ToolCallID     string
+	Status         string // "approved", "rejected"
+}
+
+func (e ApprovalResolvedEvent) EventName() string { return ApprovalResolved }
+
 // ============================================================================
 // System Events
 // ============================================================================
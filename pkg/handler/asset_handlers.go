@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/choraleia/choraleia/pkg/models"
 	"github.com/choraleia/choraleia/pkg/service"
+	"github.com/choraleia/choraleia/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"log/slog"
 )
@@ -34,7 +37,7 @@ func (h *AssetHandler) Create(c *gin.Context) {
 		return
 	}
 	h.Logger.Info("Asset created via API", "assetId", asset.ID, "name", asset.Name, "type", asset.Type, "clientIP", c.ClientIP())
-	c.JSON(http.StatusCreated, models.Response{Code: 200, Message: "Created successfully", Data: asset})
+	c.JSON(http.StatusCreated, models.Response{Code: 200, Message: "Created successfully", Data: redactAsset(asset)})
 }
 
 func (h *AssetHandler) List(c *gin.Context) {
@@ -45,9 +48,10 @@ func (h *AssetHandler) List(c *gin.Context) {
 	if tagsStr != "" {
 		tags = strings.Split(tagsStr, ",")
 	}
-	assets, err := h.Svc.ListAssets(assetType, tags, search)
+	parentID := c.Query("parent_id")
+	assets, err := h.Svc.ListAssets(assetType, tags, search, parentID)
 	if err != nil {
-		h.Logger.Error("Failed to list assets", "assetType", assetType, "search", search, "tags", tags, "error", err)
+		h.Logger.Error("Failed to list assets", "assetType", assetType, "search", search, "tags", tags, "parentID", parentID, "error", err)
 		c.JSON(http.StatusInternalServerError, models.Response{Code: 500, Message: err.Error()})
 		return
 	}
@@ -64,7 +68,7 @@ func (h *AssetHandler) Get(c *gin.Context) {
 		return
 	}
 	h.Logger.Debug("Asset retrieved via API", "assetId", id, "name", asset.Name, "clientIP", c.ClientIP())
-	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Retrieved successfully", Data: asset})
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Retrieved successfully", Data: redactAsset(asset)})
 }
 
 func (h *AssetHandler) Update(c *gin.Context) {
@@ -82,7 +86,7 @@ func (h *AssetHandler) Update(c *gin.Context) {
 		return
 	}
 	h.Logger.Info("Asset updated via API", "assetId", id, "name", asset.Name, "clientIP", c.ClientIP())
-	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Updated successfully", Data: asset})
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Updated successfully", Data: redactAsset(asset)})
 }
 
 func (h *AssetHandler) Delete(c *gin.Context) {
@@ -137,7 +141,7 @@ func (h *AssetHandler) Move(c *gin.Context) {
 		newParent = *req.NewParentID
 	}
 	h.Logger.Info("Asset moved via API", "assetId", id, "newParent", newParent, "position", strings.ToLower(req.Position), "clientIP", c.ClientIP())
-	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Moved successfully", Data: asset})
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Moved successfully", Data: redactAsset(asset)})
 }
 
 func (h *AssetHandler) ListSSHKeys(c *gin.Context) {
@@ -166,10 +170,68 @@ func (h *AssetHandler) InspectSSHKey(c *gin.Context) {
 	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: info})
 }
 
+func (h *AssetHandler) Revisions(c *gin.Context) {
+	id := c.Param("id")
+	revs, err := h.Svc.ListRevisions(id)
+	if err != nil {
+		h.Logger.Error("Failed to list asset revisions", "assetId", id, "error", err)
+		c.JSON(http.StatusInternalServerError, models.Response{Code: 500, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Retrieved successfully", Data: redactRevisions(revs)})
+}
+
+func (h *AssetHandler) Deleted(c *gin.Context) {
+	revs, err := h.Svc.ListDeletedAssets()
+	if err != nil {
+		h.Logger.Error("Failed to list deleted assets", "error", err)
+		c.JSON(http.StatusInternalServerError, models.Response{Code: 500, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Retrieved successfully", Data: redactRevisions(revs)})
+}
+
+func (h *AssetHandler) RestoreRevision(c *gin.Context) {
+	id := c.Param("id")
+	revID, err := strconv.ParseUint(c.Param("revId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: "invalid revision id"})
+		return
+	}
+	asset, err := h.Svc.RestoreRevision(id, uint(revID))
+	if err != nil {
+		h.Logger.Error("Failed to restore asset revision", "assetId", id, "revisionId", revID, "error", err, "clientIP", c.ClientIP())
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrAssetRevisionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, models.Response{Code: status, Message: err.Error()})
+		return
+	}
+	h.Logger.Info("Asset revision restored via API", "assetId", id, "revisionId", revID, "clientIP", c.ClientIP())
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Restored successfully", Data: redactAsset(asset)})
+}
+
 func convertToAssetSlice(assets []*models.Asset) []models.Asset {
 	res := make([]models.Asset, len(assets))
 	for i, a := range assets {
-		res[i] = *a
+		res[i] = redactAsset(a)
 	}
 	return res
 }
+
+// redactAsset copies an asset for a response. Stored secrets are vault
+// references; any plaintext left while the vault is locked is redacted.
+func redactAsset(a *models.Asset) models.Asset {
+	res := *a
+	res.Config = utils.RedactMap(a.Config)
+	return res
+}
+
+// redactRevisions redacts the asset snapshots of revisions like assets
+func redactRevisions(revs []models.AssetRevision) []models.AssetRevision {
+	for i := range revs {
+		revs[i].Asset.Config = utils.RedactMap(revs[i].Asset.Config)
+	}
+	return revs
+}
@@ -4,6 +4,7 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -41,6 +42,9 @@ func (h *ChatHandler) RegisterRoutes(r *gin.RouterGroup) {
 
 		// Messages
 		conversations.GET("/:id/messages", h.GetMessages)
+
+		// Tool call approvals
+		conversations.POST("/:id/approvals/:tool_call_id", h.ResolveToolApproval)
 	}
 
 	// Stream management
@@ -303,6 +307,29 @@ func (h *ChatHandler) CancelStream(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"cancelled": true})
 }
 
+// ResolveToolApproval approves or rejects a tool call waiting for approval
+// POST /api/v1/conversations/:id/approvals/:tool_call_id
+func (h *ChatHandler) ResolveToolApproval(c *gin.Context) {
+	var req service.ToolApprovalDecision
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversationID := c.Param("id")
+	toolCallID := c.Param("tool_call_id")
+	if err := h.chatService.ResolveToolApproval(conversationID, toolCallID, req); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrApprovalNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tool_call_id": toolCallID, "approved": req.Approved})
+}
+
 // GetStreamStatus checks if a conversation has an active stream
 // GET /api/v1/chat/status/:conversation_id
 func (h *ChatHandler) GetStreamStatus(c *gin.Context) {
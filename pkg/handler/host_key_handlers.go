@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service/hostkey"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+)
+
+// hostKeyScanTimeout bounds fetching a key from a server
+const hostKeyScanTimeout = 10 * time.Second
+
+// HostKeyHandler manages trusted SSH host keys
+type HostKeyHandler struct {
+	store  *hostkey.Store
+	logger *slog.Logger
+}
+
+// NewHostKeyHandler creates a new host key handler
+func NewHostKeyHandler(store *hostkey.Store, logger *slog.Logger) *HostKeyHandler {
+	return &HostKeyHandler{store: store, logger: logger}
+}
+
+// AcceptHostKeyRequest trusts a key for a host. Without a public key the
+// server is asked for its key, which must match the fingerprint if given.
+type AcceptHostKeyRequest struct {
+	Host        string `json:"host" binding:"required"`
+	Port        int    `json:"port"`
+	PublicKey   string `json:"public_key,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// List returns the known host keys
+// GET /api/host-keys
+func (h *HostKeyHandler) List(c *gin.Context) {
+	entries, err := h.store.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{Code: 500, Message: err.Error()})
+		return
+	}
+	if entries == nil {
+		entries = []hostkey.Entry{}
+	}
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: entries})
+}
+
+// Scan fetches a server's host key and compares it with the keys on record
+// GET /api/host-keys/scan?host=&port=
+func (h *HostKeyHandler) Scan(c *gin.Context) {
+	host := c.Query("host")
+	if host == "" {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: "host is required"})
+		return
+	}
+	port, _ := strconv.Atoi(c.DefaultQuery("port", "22"))
+	addr := hostAddr(host, port)
+
+	key, err := hostkey.Scan(c.Request.Context(), addr, hostKeyScanTimeout)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, models.Response{Code: 502, Message: err.Error()})
+		return
+	}
+	k, err := h.store.Inspect(addr, nil, key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{Code: 500, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: k})
+}
+
+// Accept trusts a host key
+// POST /api/host-keys
+func (h *HostKeyHandler) Accept(c *gin.Context) {
+	var req AcceptHostKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: "Invalid request: " + err.Error()})
+		return
+	}
+	addr := hostAddr(req.Host, req.Port)
+
+	var (
+		key ssh.PublicKey
+		err error
+	)
+	if req.PublicKey != "" {
+		key, err = hostkey.ParseKey(req.PublicKey)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: err.Error()})
+			return
+		}
+	} else {
+		key, err = hostkey.Scan(c.Request.Context(), addr, hostKeyScanTimeout)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, models.Response{Code: 502, Message: err.Error()})
+			return
+		}
+	}
+	if fp := ssh.FingerprintSHA256(key); req.Fingerprint != "" && req.Fingerprint != fp {
+		c.JSON(http.StatusConflict, models.Response{
+			Code:    409,
+			Message: fmt.Sprintf("host presented %s, not the expected %s", fp, req.Fingerprint),
+		})
+		return
+	}
+
+	if err := h.store.Add(addr, key); err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{Code: 500, Message: err.Error()})
+		return
+	}
+	k, err := h.store.Inspect(addr, nil, key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{Code: 500, Message: err.Error()})
+		return
+	}
+	h.logger.Info("Accepted SSH host key", "host", addr, "fingerprint", k.Fingerprint)
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: k})
+}
+
+// Revoke removes a trusted key, or marks an OpenSSH key as revoked
+// DELETE /api/host-keys/:id
+func (h *HostKeyHandler) Revoke(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.store.Revoke(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, hostkey.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, models.Response{Code: status, Message: err.Error()})
+		return
+	}
+	h.logger.Info("Revoked SSH host key", "id", id)
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK"})
+}
+
+func hostAddr(host string, port int) string {
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
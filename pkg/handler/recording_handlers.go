@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service/recording"
+	"github.com/gin-gonic/gin"
+)
+
+// asciicastType is the media type of asciicast v2 files
+const asciicastType = "application/x-asciicast"
+
+// RecordingHandler serves terminal session recordings
+type RecordingHandler struct {
+	store  *recording.Store
+	logger *slog.Logger
+}
+
+// NewRecordingHandler creates a new recording handler
+func NewRecordingHandler(store *recording.Store, logger *slog.Logger) *RecordingHandler {
+	return &RecordingHandler{store: store, logger: logger}
+}
+
+// List returns the recordings, newest first, optionally of one asset
+// GET /api/recordings?asset_id=
+func (h *RecordingHandler) List(c *gin.Context) {
+	infos, err := h.store.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{Code: 500, Message: err.Error()})
+		return
+	}
+	assetID := c.Query("asset_id")
+	result := []recording.Info{}
+	for _, info := range infos {
+		if assetID == "" || info.AssetID == assetID {
+			result = append(result, info)
+		}
+	}
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: result})
+}
+
+// Stream serves a recording for in-app playback. Range requests are
+// supported, and a recording in progress is served as written so far.
+// GET /api/recordings/:id
+func (h *RecordingHandler) Stream(c *gin.Context) {
+	h.serve(c, false)
+}
+
+// Download serves a recording as a .cast file attachment
+// GET /api/recordings/:id/download
+func (h *RecordingHandler) Download(c *gin.Context) {
+	h.serve(c, true)
+}
+
+func (h *RecordingHandler) serve(c *gin.Context, attachment bool) {
+	id := c.Param("id")
+	f, info, err := h.store.Open(id)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	defer f.Close()
+
+	c.Header("Content-Type", asciicastType)
+	if attachment {
+		c.Header("Content-Disposition", "attachment; filename=\""+info.ID+recording.Ext+"\"")
+	}
+	// No modification time: a recording in progress keeps changing
+	http.ServeContent(c.Writer, c.Request, info.ID+recording.Ext, time.Time{}, f)
+}
+
+// Delete removes a recording
+// DELETE /api/recordings/:id
+func (h *RecordingHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.store.Delete(id); err != nil {
+		h.writeError(c, err)
+		return
+	}
+	h.logger.Info("Deleted terminal recording", "id", id)
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK"})
+}
+
+func (h *RecordingHandler) writeError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, recording.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, recording.ErrActive):
+		status = http.StatusConflict
+	}
+	c.JSON(status, models.Response{Code: status, Message: err.Error()})
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/secrets"
+	"github.com/gin-gonic/gin"
+)
+
+// SecretsHandler manages the secret vault's master key
+type SecretsHandler struct {
+	vault  *secrets.Vault
+	logger *slog.Logger
+}
+
+// NewSecretsHandler creates a new secrets handler
+func NewSecretsHandler(vault *secrets.Vault, logger *slog.Logger) *SecretsHandler {
+	return &SecretsHandler{vault: vault, logger: logger}
+}
+
+// UnlockRequest carries the vault passphrase
+type UnlockRequest struct {
+	Passphrase string `json:"passphrase" binding:"required"`
+}
+
+// SetPassphraseRequest sets, changes or removes the vault passphrase. An
+// empty passphrase moves the master key back to the OS keyring.
+type SetPassphraseRequest struct {
+	Current    string `json:"current,omitempty"`
+	Passphrase string `json:"passphrase"`
+}
+
+// Status reports the key source and whether the vault is locked
+// GET /api/secrets/status
+func (h *SecretsHandler) Status(c *gin.Context) {
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: h.vault.Status()})
+}
+
+// Unlock opens a passphrase-protected vault
+// POST /api/secrets/unlock
+func (h *SecretsHandler) Unlock(c *gin.Context) {
+	var req UnlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: "Invalid request: " + err.Error()})
+		return
+	}
+	if err := h.vault.Unlock(req.Passphrase); err != nil {
+		c.JSON(secretsErrorStatus(err), models.Response{Code: secretsErrorStatus(err), Message: err.Error()})
+		return
+	}
+	h.logger.Info("Secret vault unlocked")
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Unlocked", Data: h.vault.Status()})
+}
+
+// SetPassphrase protects the vault with a passphrase
+// PUT /api/secrets/passphrase
+func (h *SecretsHandler) SetPassphrase(c *gin.Context) {
+	var req SetPassphraseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: "Invalid request: " + err.Error()})
+		return
+	}
+	if err := h.vault.SetPassphrase(req.Current, req.Passphrase); err != nil {
+		c.JSON(secretsErrorStatus(err), models.Response{Code: secretsErrorStatus(err), Message: err.Error()})
+		return
+	}
+	h.logger.Info("Secret vault master key changed", "source", h.vault.Status().Source)
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Updated", Data: h.vault.Status()})
+}
+
+func secretsErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, secrets.ErrBadPassphrase):
+		return http.StatusForbidden
+	case errors.Is(err, secrets.ErrLocked), errors.Is(err, secrets.ErrNoPassphrase):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
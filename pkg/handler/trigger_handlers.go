@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TriggerHandler provides HTTP handlers for terminal trigger rules
+type TriggerHandler struct {
+	Svc    *service.TriggerService
+	Logger *slog.Logger
+}
+
+func NewTriggerHandler(svc *service.TriggerService, logger *slog.Logger) *TriggerHandler {
+	return &TriggerHandler{Svc: svc, Logger: logger}
+}
+
+// List handles listing triggers, only those of ?asset_id= when given
+func (h *TriggerHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: h.Svc.List(c.Query("asset_id"))})
+}
+
+// Get handles retrieving a single trigger
+func (h *TriggerHandler) Get(c *gin.Context) {
+	tr, err := h.Svc.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Response{Code: 404, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: tr})
+}
+
+// Create handles adding a new trigger
+func (h *TriggerHandler) Create(c *gin.Context) {
+	var req models.TriggerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: "Invalid request: " + err.Error()})
+		return
+	}
+	tr, err := h.Svc.Create(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, models.Response{Code: 200, Message: "Created", Data: tr})
+}
+
+// Update handles replacing an existing trigger
+func (h *TriggerHandler) Update(c *gin.Context) {
+	var req models.TriggerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: "Invalid request: " + err.Error()})
+		return
+	}
+	tr, err := h.Svc.Update(c.Param("id"), &req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrTriggerNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, models.Response{Code: status, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Updated", Data: tr})
+}
+
+// Delete handles removing a trigger
+func (h *TriggerHandler) Delete(c *gin.Context) {
+	if err := h.Svc.Delete(c.Param("id")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrTriggerNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, models.Response{Code: status, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "Deleted"})
+}
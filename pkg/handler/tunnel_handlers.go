@@ -2,10 +2,15 @@
 package handler
 
 import (
+	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
 
+	"github.com/choraleia/choraleia/pkg/models"
 	"github.com/choraleia/choraleia/pkg/service"
+	"github.com/choraleia/choraleia/pkg/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -58,6 +63,118 @@ func (h *TunnelHandler) GetStats(c *gin.Context) {
 	})
 }
 
+// Get returns a standalone port forward
+// GET /api/tunnels/:id
+func (h *TunnelHandler) Get(c *gin.Context) {
+	pf, err := h.tunnelService.GetPortForward(c.Param("id"))
+	if err != nil {
+		h.tunnelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": redactPortForward(pf),
+	})
+}
+
+// Create adds a standalone port forward to an SSH host, a container or a
+// workspace
+// POST /api/tunnels
+func (h *TunnelHandler) Create(c *gin.Context) {
+	var req models.PortForwardRequest
+	if !bindPortForward(c, &req) {
+		return
+	}
+
+	pf, err := h.tunnelService.CreatePortForward(&req)
+	if err != nil {
+		h.logger.Error("Failed to create port forward", "error", err)
+		h.tunnelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": redactPortForward(pf),
+	})
+}
+
+// Update replaces a standalone port forward's settings; a redacted proxy
+// password keeps the stored one
+// PUT /api/tunnels/:id
+func (h *TunnelHandler) Update(c *gin.Context) {
+	var req models.PortForwardRequest
+	if !bindPortForward(c, &req) {
+		return
+	}
+	if p := req.Config.Proxy; p != nil && p.Password == utils.RedactedValue {
+		prev, err := h.tunnelService.GetPortForward(c.Param("id"))
+		if err != nil {
+			h.tunnelError(c, err)
+			return
+		}
+		p.Password = ""
+		if prev.Config.Proxy != nil {
+			p.Password = prev.Config.Proxy.Password
+		}
+	}
+
+	pf, err := h.tunnelService.UpdatePortForward(c.Param("id"), &req)
+	if err != nil {
+		h.logger.Error("Failed to update port forward", "id", c.Param("id"), "error", err)
+		h.tunnelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": redactPortForward(pf),
+	})
+}
+
+// Delete stops and removes a standalone port forward
+// DELETE /api/tunnels/:id
+func (h *TunnelHandler) Delete(c *gin.Context) {
+	if err := h.tunnelService.DeletePortForward(c.Param("id")); err != nil {
+		h.logger.Error("Failed to delete port forward", "id", c.Param("id"), "error", err)
+		h.tunnelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "port forward deleted",
+	})
+}
+
+// bindPortForward reads and validates a port forward request, responding
+// 400 if it is invalid
+func bindPortForward(c *gin.Context, req *models.PortForwardRequest) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		err = req.Validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "invalid request: " + err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// redactPortForward hides the proxy password of a port forward
+func redactPortForward(pf *models.PortForward) *models.PortForward {
+	if p := pf.Config.Proxy; p != nil && p.Password != "" {
+		proxy := *p
+		proxy.Password = utils.RedactedValue
+		pf.Config.Proxy = &proxy
+	}
+	return pf
+}
+
 // Start starts a specific tunnel
 // POST /api/tunnels/:id/start
 func (h *TunnelHandler) Start(c *gin.Context) {
@@ -121,3 +238,90 @@ func (h *TunnelHandler) Stop(c *gin.Context) {
 		"message": "tunnel stopped",
 	})
 }
+
+// Connections returns a tunnel's open and recently closed connections
+// GET /api/tunnels/:id/connections
+func (h *TunnelHandler) Connections(c *gin.Context) {
+	active, recent, err := h.tunnelService.ListConnections(c.Param("id"))
+	if err != nil {
+		h.tunnelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"active": active,
+			"recent": recent,
+		},
+	})
+}
+
+// CloseConnection force-closes an open tunnel connection
+// DELETE /api/tunnels/:id/connections/:connId
+func (h *TunnelHandler) CloseConnection(c *gin.Context) {
+	if err := h.tunnelService.CloseConnection(c.Param("id"), c.Param("connId")); err != nil {
+		h.tunnelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "connection closed",
+	})
+}
+
+// History returns a tunnel's traffic per minute, oldest first
+// GET /api/tunnels/:id/history?minutes=60
+func (h *TunnelHandler) History(c *gin.Context) {
+	minutes, err := strconv.Atoi(c.DefaultQuery("minutes", "60"))
+	if err != nil || minutes <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "minutes must be a positive number",
+		})
+		return
+	}
+
+	points, err := h.tunnelService.TrafficHistory(c.Param("id"), minutes)
+	if err != nil {
+		h.tunnelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": points,
+	})
+}
+
+// ProxyPAC serves the PAC file of a dynamic forward with pac enabled
+// GET /api/tunnels/:id/proxy.pac
+func (h *TunnelHandler) ProxyPAC(c *gin.Context) {
+	host, _, err := net.SplitHostPort(c.Request.Host)
+	if err != nil {
+		host = c.Request.Host
+	}
+	pac, err := h.tunnelService.ProxyPAC(c.Param("id"), host)
+	if err != nil {
+		h.tunnelError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/x-ns-proxy-autoconfig", []byte(pac))
+}
+
+// tunnelError responds 404 for an unknown tunnel, connection or PAC file,
+// 400 for a port forward target that does not exist, else 500
+func (h *TunnelHandler) tunnelError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, service.ErrTunnelNotFound) || errors.Is(err, service.ErrTunnelConnectionNotFound) ||
+		errors.Is(err, service.ErrTunnelNoPAC) {
+		status = http.StatusNotFound
+	} else if errors.Is(err, service.ErrPortForwardTarget) {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": err.Error(),
+	})
+}
@@ -112,7 +112,7 @@ func (h *WorkspaceHandler) Create(c *gin.Context) {
 	workspace, err := h.workspaceService.Create(c.Request.Context(), &req)
 	if err != nil {
 		status := http.StatusInternalServerError
-		if err == service.ErrWorkspaceNameExists || err == service.ErrWorkspaceNameInvalid {
+		if err == service.ErrWorkspaceNameExists || err == service.ErrWorkspaceNameInvalid || err == service.ErrToolApprovalInvalid {
 			status = http.StatusBadRequest
 		}
 		c.JSON(status, gin.H{"error": err.Error()})
@@ -168,7 +168,7 @@ func (h *WorkspaceHandler) Update(c *gin.Context) {
 		status := http.StatusInternalServerError
 		if err == service.ErrWorkspaceNotFound {
 			status = http.StatusNotFound
-		} else if err == service.ErrWorkspaceNameExists || err == service.ErrWorkspaceNameInvalid {
+		} else if err == service.ErrWorkspaceNameExists || err == service.ErrWorkspaceNameInvalid || err == service.ErrToolApprovalInvalid {
 			status = http.StatusBadRequest
 		}
 		c.JSON(status, gin.H{"error": err.Error()})
@@ -228,7 +228,7 @@ func (h *WorkspaceHandler) Clone(c *gin.Context) {
 		status := http.StatusInternalServerError
 		if err == service.ErrWorkspaceNotFound {
 			status = http.StatusNotFound
-		} else if err == service.ErrWorkspaceNameExists || err == service.ErrWorkspaceNameInvalid {
+		} else if err == service.ErrWorkspaceNameExists || err == service.ErrWorkspaceNameInvalid || err == service.ErrToolApprovalInvalid {
 			status = http.StatusBadRequest
 		}
 		c.JSON(status, gin.H{"error": err.Error()})
@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/secrets"
 	"github.com/choraleia/choraleia/pkg/service"
+	"github.com/choraleia/choraleia/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -295,7 +297,11 @@ func (h *WorkspaceHandler) ListTools(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"tools": workspace.Tools})
+	tools := make([]models.WorkspaceTool, len(workspace.Tools))
+	for i, tool := range workspace.Tools {
+		tools[i] = redactTool(tool)
+	}
+	c.JSON(http.StatusOK, gin.H{"tools": tools})
 }
 
 // AddTool adds a tool to a workspace
@@ -336,12 +342,16 @@ func (h *WorkspaceHandler) AddTool(c *gin.Context) {
 		UpdatedAt:   time.Now(),
 	}
 
+	if _, err := secrets.SealMap(tool.Config); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	if err := h.workspaceService.DB().Create(tool).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, tool)
+	c.JSON(http.StatusCreated, redactTool(*tool))
 }
 
 // UpdateTool updates a tool
@@ -380,6 +390,12 @@ func (h *WorkspaceHandler) UpdateTool(c *gin.Context) {
 		updates["enabled"] = *req.Enabled
 	}
 	if req.Config != nil {
+		// Secrets the API returned redacted keep their values
+		utils.RestoreRedacted(*req.Config, tool.Config)
+		if _, err := secrets.SealMap(*req.Config); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 		updates["config"] = *req.Config
 	}
 	if req.AIHint != nil {
@@ -393,7 +409,7 @@ func (h *WorkspaceHandler) UpdateTool(c *gin.Context) {
 
 	// Reload
 	h.workspaceService.DB().First(&tool, "id = ?", toolID)
-	c.JSON(http.StatusOK, tool)
+	c.JSON(http.StatusOK, redactTool(tool))
 }
 
 // RemoveTool removes a workspace tool
@@ -442,7 +458,14 @@ func (h *WorkspaceHandler) ToggleTool(c *gin.Context) {
 	}
 
 	tool.Enabled = req.Enabled
-	c.JSON(http.StatusOK, tool)
+	c.JSON(http.StatusOK, redactTool(tool))
+}
+
+// redactTool copies a tool for a response. Stored secrets are vault
+// references; any plaintext left while the vault is locked is redacted.
+func redactTool(tool models.WorkspaceTool) models.WorkspaceTool {
+	tool.Config = utils.RedactMap(tool.Config)
+	return tool
 }
 
 // TestTool tests tool connection
@@ -450,16 +473,28 @@ func (h *WorkspaceHandler) TestTool(c *gin.Context) {
 	workspaceID := c.Param("id")
 	toolID := c.Param("toolId")
 
-	var tool models.WorkspaceTool
-	if err := h.workspaceService.DB().First(&tool, "id = ? AND workspace_id = ?", toolID, workspaceID).Error; err != nil {
+	// Tools running in the workspace runtime need the workspace to start
+	workspace, err := h.workspaceService.Get(c.Request.Context(), workspaceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	var tool *models.WorkspaceTool
+	for i := range workspace.Tools {
+		if workspace.Tools[i].ID == toolID {
+			tool = &workspace.Tools[i]
+			break
+		}
+	}
+	if tool == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "tool not found"})
 		return
 	}
 
-	// TODO: Implement actual tool testing
-	result := &service.ToolTestResult{
-		Success: true,
-		Message: "Tool connection successful",
+	result, err := h.workspaceService.GetToolManager().TestConnection(c.Request.Context(), workspace, tool)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, result)
@@ -26,9 +26,13 @@ func init() {
 	RegisterMsgType(&TermResize{})
 	RegisterMsgType(&TermInput{})
 	RegisterMsgType(&TermPause{})
-	RegisterMsgType(&TermOutputRequest{})
-	RegisterMsgType(&TermOutputResponse{})
 	RegisterMsgType(&TermSetSessionId{})
+	RegisterMsgType(&TermHostKeyDecision{})
+	RegisterMsgType(&TermAuthResponse{})
+	RegisterMsgType(&TermClose{})
+	RegisterMsgType(&TermTransferFiles{})
+	RegisterMsgType(&TermTransferData{})
+	RegisterMsgType(&TermTransferCancel{})
 }
 
 type TermResize struct {
@@ -47,26 +51,62 @@ type TermPause struct {
 	Pause bool `json:"pause"`
 }
 
-// TermOutputRequest request to fetch terminal output
-type TermOutputRequest struct {
+// TermSetSessionId set terminal session ID
+type TermSetSessionId struct {
 	Base
-	RequestID string `json:"request_id"` // request ID used to match response
-	Lines     int    `json:"lines"`      // number of lines requested, 0 means all
+	SessionId string `json:"session_id"` // tab key from frontend used as session ID
 }
 
-// TermOutputResponse terminal output response
-type TermOutputResponse struct {
+// TermHostKeyDecision answers a prompt to trust an unknown SSH host key
+type TermHostKeyDecision struct {
 	Base
-	RequestID string   `json:"request_id"` // corresponding request ID
-	Output    []string `json:"output"`     // terminal output lines
-	Success   bool     `json:"success"`    // whether fetching succeeded
-	Error     string   `json:"error"`      // error message (if any)
+	Accept bool `json:"accept"`
 }
 
-// TermSetSessionId set terminal session ID
-type TermSetSessionId struct {
+// TermAuthResponse answers a keyboard-interactive challenge, one answer per
+// question, or cancels the login
+type TermAuthResponse struct {
 	Base
-	SessionId string `json:"session_id"` // tab key from frontend used as session ID
+	Answers []string `json:"answers"`
+	Cancel  bool     `json:"cancel"`
+}
+
+// TermClose ends the session instead of leaving it detached when the
+// WebSocket closes
+type TermClose struct {
+	Base
+}
+
+// TermTransferFile is a file picked for an upload
+type TermTransferFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// TermTransferFiles answers the start of an upload with the files picked,
+// none or Cancel if the user declined
+type TermTransferFiles struct {
+	Base
+	ID     string             `json:"id"`
+	Files  []TermTransferFile `json:"files"`
+	Cancel bool               `json:"cancel"`
+}
+
+// TermTransferData answers a read of an uploaded file. Data holds the bytes
+// from Offset; Error is set if the file couldn't be read.
+type TermTransferData struct {
+	Base
+	ID     string `json:"id"`
+	File   int    `json:"file"`
+	Offset int64  `json:"offset"`
+	Data   []byte `json:"data"`
+	Error  string `json:"error,omitempty"`
+}
+
+// TermTransferCancel stops a file transfer
+type TermTransferCancel struct {
+	Base
+	ID string `json:"id"`
 }
 
 func ParseMessage(data []byte) (interface{}, error) {
@@ -1,9 +1,16 @@
 package models
 
 import (
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/choraleia/choraleia/pkg/secrets"
 )
 
 // AssetType asset type enum
@@ -18,17 +25,67 @@ const (
 
 // Asset generic asset structure (linked list for sibling ordering)
 type Asset struct {
-	ID          string                 `json:"id" gorm:"primaryKey"`
-	Name        string                 `json:"name" gorm:"not null"`
-	Type        AssetType              `json:"type" gorm:"not null"`
-	Description string                 `json:"description"`
-	Config      map[string]interface{} `json:"config" gorm:"type:json"`
-	Tags        []string               `json:"tags" gorm:"type:json"`
-	ParentID    *string                `json:"parent_id" gorm:"index"` // parent node ID (nil=root)
-	PrevID      *string                `json:"prev_id"`                // previous sibling id
-	NextID      *string                `json:"next_id"`                // next sibling id
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
+	ID          string     `json:"id" gorm:"primaryKey;size:36"`
+	Name        string     `json:"name" gorm:"not null"`
+	Type        AssetType  `json:"type" gorm:"size:20;not null"`
+	Description string     `json:"description"`
+	Config      JSONMap    `json:"config" gorm:"type:json"`
+	Tags        StringList `json:"tags" gorm:"type:json"`
+	ParentID    *string    `json:"parent_id" gorm:"index;size:36"` // parent node ID (nil=root)
+	PrevID      *string    `json:"prev_id" gorm:"size:36"`         // previous sibling id
+	NextID      *string    `json:"next_id" gorm:"size:36"`         // next sibling id
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for Asset
+func (Asset) TableName() string {
+	return "assets"
+}
+
+// Asset revision actions
+const (
+	AssetRevisionImport  = "import" // imported from assets.json
+	AssetRevisionCreate  = "create"
+	AssetRevisionUpdate  = "update"
+	AssetRevisionMove    = "move"
+	AssetRevisionDelete  = "delete"
+	AssetRevisionRestore = "restore"
+)
+
+// AssetRevision records an asset as it was after a change, or just before
+// it was deleted. Secrets in the snapshot are vault references, as stored.
+type AssetRevision struct {
+	ID        uint          `json:"id" gorm:"primaryKey;autoIncrement"`
+	AssetID   string        `json:"asset_id" gorm:"index;size:36;not null"`
+	Action    string        `json:"action" gorm:"size:20;not null"`
+	Asset     AssetSnapshot `json:"asset" gorm:"type:json"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// TableName returns the table name for AssetRevision
+func (AssetRevision) TableName() string {
+	return "asset_revisions"
+}
+
+// AssetSnapshot is an asset stored whole in a revision
+type AssetSnapshot Asset
+
+// Value implements driver.Valuer for AssetSnapshot
+func (a AssetSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+// Scan implements sql.Scanner for AssetSnapshot
+func (a *AssetSnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
 }
 
 // SSHConfig SSH connection config
@@ -41,6 +98,9 @@ type SSHConfig struct {
 	PrivateKeyPath       string `json:"private_key_path,omitempty"`
 	PrivateKeyPassphrase string `json:"private_key_passphrase,omitempty"`
 	PrivateKey           string `json:"private_key,omitempty"`
+	CertificatePath      string `json:"certificate_path,omitempty"` // OpenSSH user certificate; defaults to <key path>-cert.pub
+	Certificate          string `json:"certificate,omitempty"`      // Inline OpenSSH user certificate
+	UseAgent             bool   `json:"use_agent,omitempty"`        // Offer the keys of the ssh-agent at SSH_AUTH_SOCK
 	Timeout              int    `json:"timeout"`
 	KeepaliveInterval    int    `json:"keepalive_interval,omitempty"`
 
@@ -63,7 +123,7 @@ type SSHConfig struct {
 	// Advanced connection
 	Compression     bool `json:"compression,omitempty"`
 	AgentForwarding bool `json:"agent_forwarding,omitempty"`
-	StrictHostKey   bool `json:"strict_host_key,omitempty"`
+	StrictHostKey   bool `json:"strict_host_key,omitempty"` // Refuse unknown host keys instead of trusting them on first use
 
 	// Tunnels / Port forwarding
 	Tunnels []SSHTunnel `json:"tunnels,omitempty"`
@@ -79,6 +139,28 @@ type SSHConfig struct {
 	FontSize     int  `json:"font_size,omitempty"`
 	CopyOnSelect bool `json:"copy_on_select,omitempty"`
 	Bell         bool `json:"bell,omitempty"`
+	Record       bool `json:"record,omitempty"` // Record terminal sessions as asciicast
+
+	// Install prompt hooks that report commands, exit codes and the cwd
+	ShellIntegration bool `json:"shell_integration,omitempty"`
+
+	// Redial when the connection drops, and optionally keep the shell in a
+	// tmux or screen session to come back to
+	Reconnect *SSHReconnect `json:"reconnect,omitempty"`
+}
+
+// SSHReconnect is how a terminal redials a dropped SSH connection. The
+// wait between attempts starts at InitialBackoff and doubles up to
+// MaxBackoff.
+type SSHReconnect struct {
+	MaxAttempts    int `json:"max_attempts"`              // 0 turns reconnecting off
+	InitialBackoff int `json:"initial_backoff,omitempty"` // seconds, default 1
+	MaxBackoff     int `json:"max_backoff,omitempty"`     // seconds, default 30
+
+	// Multiplexer runs the shell in a named session that survives the
+	// connection: "tmux", "screen", or "auto" for whichever is installed
+	Multiplexer string `json:"multiplexer,omitempty"`
+	SessionName string `json:"session_name,omitempty"` // default "choraleia"
 }
 
 // SSHTunnel represents a port forwarding tunnel configuration
@@ -89,6 +171,58 @@ type SSHTunnel struct {
 	LocalPort  int    `json:"local_port"`
 	RemoteHost string `json:"remote_host,omitempty"` // not used for dynamic
 	RemotePort int    `json:"remote_port,omitempty"` // not used for dynamic
+
+	AutoStart     bool               `json:"auto_start,omitempty"`     // start when the app starts
+	RestartPolicy string             `json:"restart_policy,omitempty"` // "never" (default), "on-failure", "always"
+	HealthCheck   *TunnelHealthCheck `json:"health_check,omitempty"`   // probe the forwarded port
+
+	Proxy *TunnelProxy `json:"proxy,omitempty"` // dynamic forwards only
+}
+
+// Tunnel restart policies. on-failure gives up after a few attempts in a
+// row; always keeps trying.
+const (
+	TunnelRestartNever     = "never"
+	TunnelRestartOnFailure = "on-failure"
+	TunnelRestartAlways    = "always"
+)
+
+// TunnelHealthCheck periodically dials the port a tunnel forwards to; a
+// tunnel failing several probes in a row is restarted as its policy allows
+type TunnelHealthCheck struct {
+	Interval int `json:"interval,omitempty"` // seconds between probes, default 30
+	Timeout  int `json:"timeout,omitempty"`  // seconds per probe, default 5
+}
+
+// Proxy protocols a dynamic forward serves
+const (
+	TunnelProxySOCKS = "socks" // SOCKS5 and SOCKS4/4a, the default
+	TunnelProxyHTTP  = "http"  // HTTP CONNECT and plain HTTP proxying
+	TunnelProxyMixed = "mixed" // both, told apart by the first byte
+)
+
+// TunnelProxy configures the proxy a dynamic forward serves. With a
+// username, SOCKS5 clients authenticate with username/password and HTTP
+// clients with Basic Proxy-Authorization; SOCKS4 clients, which cannot
+// send a password, are refused.
+type TunnelProxy struct {
+	Mode     string `json:"mode,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	PAC      bool   `json:"pac,omitempty"` // serve a PAC file for browsers
+
+	ForwardRules
+}
+
+// ForwardRules limits the destinations a forward may connect to, the way
+// SSHRestrictions.AllowedForwardPorts does for workspace assets. Hosts are
+// names with * wildcards, IPs or CIDRs. Blocked entries win; empty allowed
+// lists allow everything.
+type ForwardRules struct {
+	AllowedHosts        []string `json:"allowed_hosts,omitempty"`
+	BlockedHosts        []string `json:"blocked_hosts,omitempty"`
+	AllowedForwardPorts []int    `json:"allowed_forward_ports,omitempty"`
+	BlockedForwardPorts []int    `json:"blocked_forward_ports,omitempty"`
 }
 
 // LocalConfig local terminal config
@@ -104,6 +238,10 @@ type LocalConfig struct {
 	FontSize       int               `json:"font_size,omitempty"`
 	CopyOnSelect   bool              `json:"copy_on_select,omitempty"`
 	Bell           bool              `json:"bell,omitempty"`
+	Record         bool              `json:"record,omitempty"` // Record terminal sessions as asciicast
+
+	// Install prompt hooks that report commands, exit codes and the cwd
+	ShellIntegration bool `json:"shell_integration,omitempty"`
 }
 
 // VNCConfig VNC connection config
@@ -153,6 +291,7 @@ type DockerHostConfig struct {
 	FontSize          int    `json:"font_size,omitempty"`
 	CopyOnSelect      bool   `json:"copy_on_select,omitempty"`
 	Bell              bool   `json:"bell,omitempty"`
+	Record            bool   `json:"record,omitempty"` // Record terminal sessions as asciicast
 }
 
 // ContainerInfo represents a Docker container's basic info
@@ -314,32 +453,8 @@ func (a *Asset) validateSSHConfig() error {
 
 	// Tunnel validation
 	for i, tunnel := range cfg.Tunnels {
-		if tunnel.Type == "" {
-			return fmt.Errorf("tunnel[%d]: type is required", i)
-		}
-		validTypes := []string{"local", "remote", "dynamic"}
-		valid := false
-		for _, t := range validTypes {
-			if tunnel.Type == t {
-				valid = true
-				break
-			}
-		}
-		if !valid {
-			return fmt.Errorf("tunnel[%d]: type must be one of: local, remote, dynamic", i)
-		}
-
-		if tunnel.LocalPort <= 0 || tunnel.LocalPort > 65535 {
-			return fmt.Errorf("tunnel[%d]: local_port must be between 1 and 65535", i)
-		}
-
-		if tunnel.Type != "dynamic" {
-			if tunnel.RemoteHost == "" {
-				return fmt.Errorf("tunnel[%d]: remote_host is required for %s tunnel", i, tunnel.Type)
-			}
-			if tunnel.RemotePort <= 0 || tunnel.RemotePort > 65535 {
-				return fmt.Errorf("tunnel[%d]: remote_port must be between 1 and 65535", i)
-			}
+		if err := tunnel.validate(); err != nil {
+			return fmt.Errorf("tunnel[%d]: %w", i, err)
 		}
 	}
 
@@ -354,6 +469,71 @@ func (a *Asset) validateSSHConfig() error {
 	return nil
 }
 
+// validate checks a tunnel's settings
+func (t *SSHTunnel) validate() error {
+	switch t.Type {
+	case "":
+		return fmt.Errorf("type is required")
+	case "local", "remote", "dynamic":
+	default:
+		return fmt.Errorf("type must be one of: local, remote, dynamic")
+	}
+
+	if t.LocalPort <= 0 || t.LocalPort > 65535 {
+		return fmt.Errorf("local_port must be between 1 and 65535")
+	}
+
+	if t.Type != "dynamic" {
+		if t.RemoteHost == "" {
+			return fmt.Errorf("remote_host is required for %s tunnel", t.Type)
+		}
+		if t.RemotePort <= 0 || t.RemotePort > 65535 {
+			return fmt.Errorf("remote_port must be between 1 and 65535")
+		}
+	}
+
+	switch t.RestartPolicy {
+	case "", TunnelRestartNever, TunnelRestartOnFailure, TunnelRestartAlways:
+	default:
+		return fmt.Errorf("restart_policy must be one of: never, on-failure, always")
+	}
+	if hc := t.HealthCheck; hc != nil && (hc.Interval < 0 || hc.Timeout < 0) {
+		return fmt.Errorf("health_check interval and timeout must be non-negative")
+	}
+	return t.Proxy.validate(t.Type)
+}
+
+// validate checks the proxy settings of a tunnel of the type; nil is valid
+func (p *TunnelProxy) validate(tunnelType string) error {
+	if p == nil {
+		return nil
+	}
+	if tunnelType != "dynamic" {
+		return fmt.Errorf("proxy is only used by dynamic tunnels")
+	}
+	switch p.Mode {
+	case "", TunnelProxySOCKS, TunnelProxyHTTP, TunnelProxyMixed:
+	default:
+		return fmt.Errorf("proxy mode must be one of: socks, http, mixed")
+	}
+	if p.Password != "" && p.Username == "" {
+		return fmt.Errorf("proxy password requires a username")
+	}
+	for _, host := range slices.Concat(p.AllowedHosts, p.BlockedHosts) {
+		if strings.Contains(host, "/") {
+			if _, _, err := net.ParseCIDR(host); err != nil {
+				return fmt.Errorf("proxy host rule %q is not a valid CIDR", host)
+			}
+		}
+	}
+	for _, port := range slices.Concat(p.AllowedForwardPorts, p.BlockedForwardPorts) {
+		if port <= 0 || port > 65535 {
+			return fmt.Errorf("proxy port rule %d must be between 1 and 65535", port)
+		}
+	}
+	return nil
+}
+
 func (a *Asset) validateLocalConfig() error {
 	var cfg LocalConfig
 	if err := a.GetTypedConfig(&cfg); err != nil {
@@ -437,9 +617,14 @@ func isValidShellPath(shell string) bool {
 	return true // Allow other paths, let system handle invalid ones
 }
 
-// GetTypedConfig decode generic map config into target struct
+// GetTypedConfig decode generic map config into target struct, resolving
+// secret references to their values
 func (a *Asset) GetTypedConfig(target interface{}) error {
-	configBytes, err := json.Marshal(a.Config)
+	config, err := secrets.ResolveMap(a.Config)
+	if err != nil {
+		return err
+	}
+	configBytes, err := json.Marshal(config)
 	if err != nil {
 		return err
 	}
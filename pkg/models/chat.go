@@ -27,6 +27,7 @@ const (
 	ChunkTypeAudioURL   = db.ChunkTypeAudioURL
 	ChunkTypeVideoURL   = db.ChunkTypeVideoURL
 	ChunkTypeFileURL    = db.ChunkTypeFileURL
+	ChunkTypeApproval   = db.ChunkTypeApproval
 )
 
 // Message status constants
@@ -252,14 +253,15 @@ type ChatCompletionChunkChoice struct {
 
 // ChatCompletionChunkDelta represents the delta content in a streaming chunk
 type ChatCompletionChunkDelta struct {
-	Role             string     `json:"role,omitempty"`
-	Content          string     `json:"content,omitempty"`
-	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
-	ToolCallID       string     `json:"tool_call_id,omitempty"`
-	Refusal          string     `json:"refusal,omitempty"`
-	ReasoningContent string     `json:"reasoning_content,omitempty"` // Extended
-	AgentName        string     `json:"agent_name,omitempty"`        // Extended: current agent name
-	RunPath          []string   `json:"run_path,omitempty"`          // Extended: agent call path for multi-agent scenarios
+	Role             string           `json:"role,omitempty"`
+	Content          string           `json:"content,omitempty"`
+	ToolCalls        []ToolCall       `json:"tool_calls,omitempty"`
+	ToolCallID       string           `json:"tool_call_id,omitempty"`
+	Refusal          string           `json:"refusal,omitempty"`
+	ReasoningContent string           `json:"reasoning_content,omitempty"` // Extended
+	AgentName        string           `json:"agent_name,omitempty"`        // Extended: current agent name
+	RunPath          []string         `json:"run_path,omitempty"`          // Extended: agent call path for multi-agent scenarios
+	Approval         *db.ApprovalPart `json:"approval,omitempty"`          // Extended: tool call approval request or decision
 }
 
 // ========== Constants ==========
@@ -2,8 +2,14 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+
+	"github.com/choraleia/choraleia/pkg/secrets"
+	"github.com/choraleia/choraleia/pkg/utils"
 )
 
 const modelFileName = ".choraleia/models.json"
@@ -152,6 +158,21 @@ func getModelFilePath() string {
 
 // Load model list
 func LoadModels() ([]*ModelConfig, error) {
+	models, err := LoadSealedModels()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range models {
+		if m != nil {
+			m.resolveSecrets()
+		}
+	}
+	return models, nil
+}
+
+// LoadSealedModels loads the model list as stored, with secret references
+// in place of the values
+func LoadSealedModels() ([]*ModelConfig, error) {
 	path := getModelFilePath()
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return []*ModelConfig{}, nil
@@ -172,24 +193,97 @@ func LoadModels() ([]*ModelConfig, error) {
 	return models, nil
 }
 
-// Save model list
+// Save model list. API keys and secret extra fields are stored in the
+// secret vault and written as references.
 func SaveModels(models []*ModelConfig) error {
 	path := getModelFilePath()
 	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
 		return err
 	}
-	for _, m := range models {
-		if m != nil {
-			m.Normalize()
+	sealed := make([]*ModelConfig, len(models))
+	for i, m := range models {
+		if m == nil {
+			continue
+		}
+		m.Normalize()
+		c := *m
+		apiKey, err := secrets.Seal(c.ApiKey)
+		if err != nil {
+			return fmt.Errorf("model %s: %w", c.Name, err)
 		}
+		c.ApiKey = apiKey
+		c.Extra = maps.Clone(c.Extra)
+		if _, err := secrets.SealMap(c.Extra); err != nil {
+			return fmt.Errorf("model %s: %w", c.Name, err)
+		}
+		sealed[i] = &c
 	}
-	data, err := json.MarshalIndent(models, "", "  ")
+	data, err := json.MarshalIndent(sealed, "", "  ")
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(path, data, 0600)
 }
 
+// resolveSecrets replaces secret references with their values. While the
+// vault is locked the references stay, so models can still be listed;
+// clients built from them fail with ErrLocked.
+func (m *ModelConfig) resolveSecrets() {
+	if apiKey, err := secrets.Resolve(m.ApiKey); err == nil {
+		m.ApiKey = apiKey
+	}
+	if extra, err := secrets.ResolveMap(m.Extra); err == nil {
+		m.Extra = extra
+	}
+}
+
+// CheckSecrets fails when the API key is still a reference, which happens
+// while the secret vault is locked
+func (m *ModelConfig) CheckSecrets() error {
+	if secrets.IsRef(m.ApiKey) {
+		return fmt.Errorf("api key of model %s: %w", m.Name, secrets.ErrLocked)
+	}
+	return nil
+}
+
+// MigrateModelSecrets moves plaintext API keys in models.json into the
+// secret vault
+func MigrateModelSecrets() error {
+	data, err := os.ReadFile(getModelFilePath())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var stored []*ModelConfig
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(stored, (*ModelConfig).hasPlaintextSecret) {
+		return nil
+	}
+	list, err := LoadModels()
+	if err != nil {
+		return err
+	}
+	return SaveModels(list)
+}
+
+func (m *ModelConfig) hasPlaintextSecret() bool {
+	if m == nil {
+		return false
+	}
+	if m.ApiKey != "" && !secrets.IsRef(m.ApiKey) {
+		return true
+	}
+	for k, v := range m.Extra {
+		if s, ok := v.(string); ok && s != "" && utils.IsSecretKey(k) && !secrets.IsRef(s) {
+			return true
+		}
+	}
+	return false
+}
+
 // SupportedModelProviders supported model providers
 var SupportedModelProviders = map[string]struct{}{
 	"openai":    {},
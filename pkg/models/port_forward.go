@@ -0,0 +1,104 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Port forward target types
+const (
+	PortForwardTargetSSH       = "ssh"       // a host reached through an SSH asset
+	PortForwardTargetDocker    = "docker"    // a container on a Docker host asset
+	PortForwardTargetWorkspace = "workspace" // a workspace's runtime
+)
+
+// PortForward is a tunnel kept on its own rather than in an SSH asset's
+// config. Besides SSH hosts it can forward to a container, reached through
+// the SSH asset of a remote Docker host or directly on this machine; the
+// container's IP is looked up each time the forward starts.
+type PortForward struct {
+	ID        string            `json:"id" gorm:"primaryKey;size:36"`
+	Name      string            `json:"name" gorm:"size:100;not null"`
+	Target    PortForwardTarget `json:"target" gorm:"embedded;embeddedPrefix:target_"`
+	Config    SSHTunnel         `json:"config" gorm:"type:json"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// TableName returns the table name for PortForward
+func (PortForward) TableName() string {
+	return "port_forwards"
+}
+
+// PortForwardTarget is what a port forward connects to
+type PortForwardTarget struct {
+	Type        string `json:"type" gorm:"size:20;not null"`          // "ssh", "docker", "workspace"
+	AssetID     string `json:"asset_id,omitempty" gorm:"size:36"`     // SSH asset, or Docker host asset
+	Container   string `json:"container,omitempty" gorm:"size:100"`   // docker: container ID or name
+	WorkspaceID string `json:"workspace_id,omitempty" gorm:"size:36"` // workspace
+}
+
+// PortForwardRequest creates or replaces a port forward. For container
+// targets Config must be a local forward; its remote host is ignored, as
+// the forward connects to the container's IP.
+type PortForwardRequest struct {
+	Name   string            `json:"name" binding:"required"`
+	Target PortForwardTarget `json:"target"`
+	Config SSHTunnel         `json:"config"`
+}
+
+// Validate checks the request's target and tunnel settings
+func (r *PortForwardRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	cfg := r.Config
+	switch r.Target.Type {
+	case PortForwardTargetSSH:
+		if r.Target.AssetID == "" {
+			return fmt.Errorf("target asset_id is required")
+		}
+	case PortForwardTargetDocker, PortForwardTargetWorkspace:
+		if r.Target.Type == PortForwardTargetDocker && (r.Target.AssetID == "" || r.Target.Container == "") {
+			return fmt.Errorf("target asset_id and container are required for docker targets")
+		}
+		if r.Target.Type == PortForwardTargetWorkspace && r.Target.WorkspaceID == "" {
+			return fmt.Errorf("target workspace_id is required for workspace targets")
+		}
+		if cfg.Type != "local" {
+			return fmt.Errorf("%s targets only support local forwards", r.Target.Type)
+		}
+		// Stands in for the container's IP, looked up on start
+		cfg.RemoteHost = r.Target.Type
+	default:
+		return fmt.Errorf("target type must be one of: ssh, docker, workspace")
+	}
+	return cfg.validate()
+}
+
+// Value implements driver.Valuer for SSHTunnel
+func (t SSHTunnel) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+// Scan implements sql.Scanner for SSHTunnel
+func (t *SSHTunnel) Scan(value interface{}) error {
+	if value == nil {
+		*t = SSHTunnel{}
+		return nil
+	}
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(data, t)
+}
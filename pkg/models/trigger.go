@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// TriggerAction is what a trigger does when its pattern matches
+type TriggerAction string
+
+const (
+	TriggerHighlight TriggerAction = "highlight" // mark the match in the terminal
+	TriggerNotify    TriggerAction = "notify"    // emit a terminal.trigger event
+	TriggerRespond   TriggerAction = "respond"   // type Response into the terminal
+	TriggerCapture   TriggerAction = "capture"   // keep the match in Variable
+)
+
+// Trigger is a rule run against the output of an asset's terminal sessions,
+// line by line. Message, Response and the captured value may refer to the
+// match's groups as $0, $1 and so on.
+type Trigger struct {
+	ID      string        `json:"id"`
+	AssetID string        `json:"asset_id"` // empty applies to every asset
+	Name    string        `json:"name"`
+	Pattern string        `json:"pattern"` // Go regular expression
+	Action  TriggerAction `json:"action"`
+	Enabled bool          `json:"enabled"`
+
+	Color    string `json:"color,omitempty"`    // highlight: CSS color, default yellow
+	Message  string `json:"message,omitempty"`  // notify: default the match
+	Response string `json:"response,omitempty"` // respond: typed as is, so end it with \r to press Enter
+	Variable string `json:"variable,omitempty"` // capture: the variable set to group 1, or the match
+
+	// Cooldown is the least time in seconds between firings in a session;
+	// 0 means the default of the action
+	Cooldown int `json:"cooldown,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TriggerRequest creates or replaces a trigger
+type TriggerRequest struct {
+	AssetID  string        `json:"asset_id"`
+	Name     string        `json:"name" binding:"required"`
+	Pattern  string        `json:"pattern" binding:"required"`
+	Action   TriggerAction `json:"action" binding:"required"`
+	Enabled  *bool         `json:"enabled"` // default true
+	Color    string        `json:"color"`
+	Message  string        `json:"message"`
+	Response string        `json:"response"`
+	Variable string        `json:"variable"`
+	Cooldown int           `json:"cooldown"`
+}
@@ -59,6 +59,59 @@ const (
 	ContainerModeNew      ContainerMode = "new"
 )
 
+// ToolApprovalMode decides which agent tool calls wait for user approval
+type ToolApprovalMode string
+
+const (
+	ToolApprovalAlways    ToolApprovalMode = "always"    // Every tool call
+	ToolApprovalDangerous ToolApprovalMode = "dangerous" // Tools that can modify data
+	ToolApprovalNever     ToolApprovalMode = "never"     // No tool call
+	ToolApprovalAllowlist ToolApprovalMode = "allowlist" // Tools outside ToolApprovalAllowlist
+)
+
+// RequiresApproval reports whether a call to the named tool must be approved
+func (w *Workspace) RequiresApproval(toolName string, dangerous bool) bool {
+	switch w.ToolApprovalMode {
+	case ToolApprovalAlways:
+		return true
+	case ToolApprovalNever:
+		return false
+	case ToolApprovalAllowlist:
+		for _, name := range w.ToolApprovalAllowlist {
+			if name == toolName {
+				return false
+			}
+		}
+		return true
+	default:
+		return dangerous
+	}
+}
+
+// StringList is a slice of strings for GORM JSON storage
+type StringList []string
+
+// Value implements driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return json.Marshal(l)
+}
+
+// Scan implements sql.Scanner
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		*l = StringList{}
+		return nil
+	}
+}
+
 // Workspace represents a workspace configuration
 type Workspace struct {
 	ID           string          `json:"id" gorm:"primaryKey;size:36"`
@@ -80,6 +133,10 @@ type Workspace struct {
 	EmbeddingDimension *int    `json:"embedding_dimension,omitempty" gorm:"default:null"` // Embedding vector dimension (immutable once set)
 	ExtractionModel    *string `json:"extraction_model,omitempty" gorm:"size:100"`        // Model ID for memory extraction
 
+	// Tool approval configuration
+	ToolApprovalMode      ToolApprovalMode `json:"tool_approval_mode" gorm:"size:20;default:'dangerous'"`
+	ToolApprovalAllowlist StringList       `json:"tool_approval_allowlist" gorm:"type:text"` // Tool names that run without approval in allowlist mode
+
 	// Relations
 	Runtime *WorkspaceRuntime   `json:"runtime,omitempty" gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE"`
 	Assets  []WorkspaceAssetRef `json:"assets,omitempty" gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE"`
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -101,3 +102,17 @@ type RedisRestrictions struct {
 	ReadOnly           *bool    `json:"read_only,omitempty"`
 	MaxKeysReturn      *int     `json:"max_keys_return,omitempty"`
 }
+
+// DecodeRestrictions decodes stored restrictions into one of the typed
+// restriction structs. The frontend saves camelCase keys, so both forms work.
+func DecodeRestrictions(restrictions JSONMap, target interface{}) error {
+	normalized := make(map[string]interface{}, len(restrictions))
+	for k, v := range restrictions {
+		normalized[camelToSnake(k)] = v
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
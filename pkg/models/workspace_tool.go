@@ -1,7 +1,12 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
+	"unicode"
+
+	"github.com/choraleia/choraleia/pkg/secrets"
 )
 
 // ToolType represents the type of tool
@@ -78,9 +83,10 @@ type MCPSSEConfig struct {
 	URL               string            `json:"url"`
 	Headers           map[string]string `json:"headers,omitempty"`
 	Auth              *MCPAuthConfig    `json:"auth,omitempty"`
-	Timeout           int               `json:"timeout,omitempty"`
+	Timeout           int               `json:"timeout,omitempty"`            // Request timeout in ms
 	Reconnect         *bool             `json:"reconnect,omitempty"`          // Auto-reconnect (default: true)
 	ReconnectInterval int               `json:"reconnect_interval,omitempty"` // Reconnect interval in ms
+	ReconnectAttempts int               `json:"reconnect_attempts,omitempty"` // Attempts before giving up (0: until stopped)
 }
 
 // MCPHTTPConfig represents MCP HTTP configuration
@@ -88,7 +94,7 @@ type MCPHTTPConfig struct {
 	URL     string            `json:"url"`
 	Headers map[string]string `json:"headers,omitempty"`
 	Auth    *MCPAuthConfig    `json:"auth,omitempty"`
-	Timeout int               `json:"timeout,omitempty"`
+	Timeout int               `json:"timeout,omitempty"` // Request timeout in ms
 	Retries int               `json:"retries,omitempty"` // Number of retries on failure
 }
 
@@ -119,8 +125,11 @@ type ScriptConfig struct {
 	Args       []string          `json:"args,omitempty"`
 	Env        map[string]string `json:"env,omitempty"`
 	Cwd        string            `json:"cwd,omitempty"`
-	Timeout    int               `json:"timeout,omitempty"`
+	Timeout    int               `json:"timeout,omitempty"`     // Execution timeout in ms
 	RuntimeEnv RuntimeEnv        `json:"runtime_env,omitempty"` // local or workspace
+	// InputSchema is the JSON schema of the tool arguments; arguments are passed
+	// to the script as JSON on stdin and in CHORALEIA_TOOL_ARGS
+	InputSchema map[string]interface{} `json:"input_schema,omitempty"`
 }
 
 // BrowserServiceConfig represents browser service configuration
@@ -160,3 +169,56 @@ type ToolWithStatus struct {
 	Status ToolStatus `json:"status"`
 	Error  string     `json:"error,omitempty"`
 }
+
+// DecodeToolConfig decodes a tool's config map into target.
+// The frontend nests type-specific config under a section key (e.g. "mcp_stdio")
+// and uses camelCase field names; both layouts are accepted. Keys inside nested
+// maps such as headers and env are user data and are left untouched. Secret
+// references are resolved to their values.
+func DecodeToolConfig(config JSONMap, section string, target interface{}) error {
+	configToUse, err := secrets.ResolveMap(config)
+	if err != nil {
+		return err
+	}
+	if nested, ok := configToUse[section].(map[string]interface{}); ok {
+		configToUse = nested
+	}
+
+	normalized := make(map[string]interface{}, len(configToUse))
+	for k, v := range configToUse {
+		key := camelToSnake(k)
+		// Auth objects carry struct fields, normalize their keys too
+		if key == "auth" {
+			if authMap, ok := v.(map[string]interface{}); ok {
+				normalizedAuth := make(map[string]interface{}, len(authMap))
+				for ak, av := range authMap {
+					normalizedAuth[camelToSnake(ak)] = av
+				}
+				v = normalizedAuth
+			}
+		}
+		normalized[key] = v
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// camelToSnake converts camelCase keys (runtimeEnv, apiKeyHeader, specUrl) to snake_case
+func camelToSnake(s string) string {
+	var sb strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
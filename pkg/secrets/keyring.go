@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	keyringService = "choraleia"
+	keyringAccount = "master-key"
+	keyringTimeout = 10 * time.Second
+)
+
+// systemKeyring keeps the master key in the macOS login keychain or the
+// freedesktop Secret Service through their command line tools
+type systemKeyring struct{}
+
+func (systemKeyring) Get() ([]byte, error) {
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		args = []string{"security", "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w"}
+	case "linux", "freebsd", "openbsd":
+		args = []string{"secret-tool", "lookup", "service", keyringService, "account", keyringAccount}
+	default:
+		return nil, errKeyringMissing
+	}
+	out, err := run(args, "")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, fmt.Errorf("no vault key in the OS keyring")
+	}
+	return hex.DecodeString(out)
+}
+
+func (systemKeyring) Set(key []byte) error {
+	var args []string
+	var stdin string
+	switch runtime.GOOS {
+	case "darwin":
+		// The command goes through stdin, as security -i reads it, so the
+		// key never shows in the process list
+		args = []string{"security", "-i"}
+		stdin = fmt.Sprintf("add-generic-password -U -s %s -a %s -l \"Choraleia vault key\" -w %s\n",
+			keyringService, keyringAccount, hex.EncodeToString(key))
+	case "linux", "freebsd", "openbsd":
+		args = []string{"secret-tool", "store", "--label=Choraleia vault key",
+			"service", keyringService, "account", keyringAccount}
+		stdin = hex.EncodeToString(key)
+	default:
+		return errKeyringMissing
+	}
+	_, err := run(args, stdin)
+	return err
+}
+
+// run runs a keyring tool. The timeout covers a Secret Service that never
+// answers, as on a headless machine without a session bus.
+func run(args []string, stdin string) (string, error) {
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return "", errKeyringMissing
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), keyringTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", cmd.Args[0], msg)
+		}
+		return "", fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,778 @@
+// Package secrets keeps passwords, private keys and API tokens encrypted at
+// rest. Configs store a reference such as "secret:3f2a..." in place of the
+// value, and the vault resolves it when the value is needed.
+//
+// Values are sealed with AES-256-GCM under a master key that comes from the
+// OS keyring, or from a passphrase through Argon2id. A passphrase-protected
+// vault stays locked until it is unlocked through the API or the
+// CHORALEIA_MASTER_PASSPHRASE environment variable.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/choraleia/choraleia/pkg/utils"
+	"golang.org/x/crypto/argon2"
+)
+
+// Sources of the master key
+const (
+	SourceKeyring    = "keyring"
+	SourcePassphrase = "passphrase"
+	SourceFile       = "file"
+)
+
+// PassphraseEnv unlocks a passphrase-protected vault at startup
+const PassphraseEnv = "CHORALEIA_MASTER_PASSPHRASE"
+
+var (
+	ErrLocked         = errors.New("secret vault is locked")
+	ErrNotFound       = errors.New("secret not found")
+	ErrBadPassphrase  = errors.New("wrong passphrase")
+	ErrNoPassphrase   = errors.New("secret vault is not protected by a passphrase")
+	errKeyringMissing = errors.New("os keyring is not available")
+)
+
+const (
+	fileVersion = 1
+	idBytes     = 16
+	checkValue  = "choraleia"
+)
+
+// Status describes the vault for the settings UI
+type Status struct {
+	Source string `json:"source"`
+	Locked bool   `json:"locked"`
+	Count  int    `json:"count"`
+}
+
+// Keyring stores the master key in the operating system's credential store
+type Keyring interface {
+	Get() ([]byte, error)
+	Set(key []byte) error
+}
+
+type vaultFile struct {
+	Version int               `json:"version"`
+	Source  string            `json:"source"`
+	Salt    []byte            `json:"salt,omitempty"` // Argon2id salt of a passphrase source
+	Check   []byte            `json:"check"`          // Known value sealed under the master key
+	Secrets map[string][]byte `json:"secrets"`        // ID to nonce and ciphertext
+}
+
+// Vault holds sealed secrets in a JSON file next to the other settings
+type Vault struct {
+	mu       sync.Mutex
+	path     string
+	keyFile  string
+	keyring  Keyring
+	file     vaultFile
+	aead     cipher.AEAD       // nil while locked
+	plain    map[string]string // Decrypted secrets of an unlocked vault
+	unlocked []func()
+	handed   map[string]bool // IDs Seal returned while Prune gathers references
+	err      error           // Why the vault file could not be opened
+	logger   *slog.Logger
+}
+
+var (
+	defaultVault *Vault
+	defaultOnce  sync.Once
+)
+
+// Open loads the vault at path and unlocks it when the master key is at
+// hand. A new vault creates its key with the first secret, from the
+// passphrase environment variable, the OS keyring, or else keyFile.
+// keyring may be nil.
+func Open(path, keyFile string, keyring Keyring) (*Vault, error) {
+	v := &Vault{
+		path:    path,
+		keyFile: keyFile,
+		keyring: keyring,
+		file:    vaultFile{Version: fileVersion, Secrets: map[string][]byte{}},
+		logger:  utils.GetLogger(),
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return v, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &v.file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if v.file.Secrets == nil {
+		v.file.Secrets = map[string][]byte{}
+	}
+
+	switch v.file.Source {
+	case SourcePassphrase:
+		if pass := os.Getenv(PassphraseEnv); pass != "" {
+			if err := v.Unlock(pass); err != nil {
+				v.logger.Error("Failed to unlock secret vault", "env", PassphraseEnv, "error", err)
+			}
+		} else {
+			v.logger.Warn("Secret vault is locked until its passphrase is entered")
+		}
+	case SourceKeyring:
+		key, err := v.keyringGet()
+		if err != nil {
+			v.logger.Error("Failed to read the vault key from the OS keyring", "error", err)
+			break
+		}
+		if err := v.unlockWith(key); err != nil {
+			v.logger.Error("Failed to unlock secret vault", "source", SourceKeyring, "error", err)
+		}
+	case SourceFile:
+		key, err := readKeyFile(keyFile)
+		if err != nil {
+			v.logger.Error("Failed to read the vault key file", "path", keyFile, "error", err)
+			break
+		}
+		if err := v.unlockWith(key); err != nil {
+			v.logger.Error("Failed to unlock secret vault", "source", SourceFile, "error", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown key source %q in %s", v.file.Source, path)
+	}
+	return v, nil
+}
+
+// Default returns the vault at ~/.choraleia/secrets.json. A vault that
+// cannot be read stays locked, so nothing is written over it.
+func Default() *Vault {
+	defaultOnce.Do(func() {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		dir := filepath.Join(home, ".choraleia")
+		v, err := Open(filepath.Join(dir, "secrets.json"), filepath.Join(dir, "master.key"), systemKeyring{})
+		if err != nil {
+			utils.GetLogger().Error("Failed to open secret vault", "error", err)
+			v = &Vault{path: filepath.Join(dir, "secrets.json"), err: err, logger: utils.GetLogger()}
+		}
+		defaultVault = v
+	})
+	return defaultVault
+}
+
+// ready makes sure secrets can be sealed, setting up the master key of a
+// new vault. The caller holds the lock.
+func (v *Vault) ready() error {
+	switch {
+	case v.err != nil:
+		return v.err
+	case v.aead != nil:
+		return nil
+	case v.file.Source == "":
+		return v.create()
+	}
+	return ErrLocked
+}
+
+// isLocked reports whether the vault waits for its key. The caller holds
+// the lock.
+func (v *Vault) isLocked() bool {
+	return v.err != nil || v.aead == nil && v.file.Source != ""
+}
+
+// create sets up the master key of a new vault. The file is written with
+// the first secret.
+func (v *Vault) create() error {
+	if pass := os.Getenv(PassphraseEnv); pass != "" {
+		return v.usePassphrase(pass)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	err := errKeyringMissing
+	if v.keyring != nil {
+		err = v.keyring.Set(key)
+	}
+	if err == nil {
+		v.file.Source = SourceKeyring
+	} else {
+		if err := writeKeyFile(v.keyFile, key); err != nil {
+			return fmt.Errorf("store master key: %w", err)
+		}
+		v.logger.Warn("OS keyring unavailable, the vault key is kept in a file; set a passphrase to protect it",
+			"path", v.keyFile, "error", err)
+		v.file.Source = SourceFile
+	}
+	return v.setKey(key)
+}
+
+// Status reports the key source, whether the vault is locked and how many
+// secrets it holds
+func (v *Vault) Status() Status {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return Status{Source: v.file.Source, Locked: v.isLocked(), Count: len(v.file.Secrets)}
+}
+
+// Unlock opens a passphrase-protected vault
+func (v *Vault) Unlock(passphrase string) error {
+	v.mu.Lock()
+	if v.file.Source != SourcePassphrase {
+		v.mu.Unlock()
+		return ErrNoPassphrase
+	}
+	key := deriveKey(passphrase, v.file.Salt)
+	v.mu.Unlock()
+	return v.unlockWith(key)
+}
+
+// SetPassphrase protects the vault with a passphrase, re-encrypting every
+// secret under the derived key. An empty passphrase moves the key back to
+// the OS keyring, or the key file when there is no keyring. The vault must
+// be unlocked, and current must match when it already has a passphrase.
+func (v *Vault) SetPassphrase(current, passphrase string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.ready(); err != nil {
+		return err
+	}
+	if v.file.Source == SourcePassphrase {
+		aead, err := newAEAD(deriveKey(current, v.file.Salt))
+		if err != nil {
+			return err
+		}
+		if check, err := open(aead, "check", v.file.Check); err != nil || check != checkValue {
+			return ErrBadPassphrase
+		}
+	}
+
+	// Go back to the old key if the new one can't be saved, so the vault in
+	// memory keeps matching the file
+	previous, aead := v.file, v.aead
+	rollback := func(err error) error {
+		v.file, v.aead = previous, aead
+		return err
+	}
+
+	if passphrase != "" {
+		if err := v.usePassphrase(passphrase); err != nil {
+			return rollback(err)
+		}
+		if err := v.save(); err != nil {
+			return rollback(err)
+		}
+		// The key file would still decrypt copies of the old vault
+		if previous.Source == SourceFile {
+			_ = os.Remove(v.keyFile)
+		}
+		return nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	// The new key replaces the stored one before the file is saved, so the
+	// old key is put back if the save fails
+	var oldKey []byte
+	switch previous.Source {
+	case SourceKeyring:
+		oldKey, _ = v.keyringGet()
+	case SourceFile:
+		oldKey, _ = readKeyFile(v.keyFile)
+	}
+	source := SourceKeyring
+	if err := v.keyringSet(key); err != nil {
+		if err := writeKeyFile(v.keyFile, key); err != nil {
+			return fmt.Errorf("store master key: %w", err)
+		}
+		source = SourceFile
+	}
+	restore := func(err error) error {
+		switch {
+		case oldKey == nil || source != previous.Source:
+		case source == SourceKeyring:
+			if kerr := v.keyringSet(oldKey); kerr != nil {
+				v.logger.Error("Failed to put the old vault key back in the OS keyring", "error", kerr)
+			}
+		case source == SourceFile:
+			if kerr := writeKeyFile(v.keyFile, oldKey); kerr != nil {
+				v.logger.Error("Failed to put the old vault key back", "path", v.keyFile, "error", kerr)
+			}
+		}
+		return rollback(err)
+	}
+	v.file.Source = source
+	v.file.Salt = nil
+	if err := v.setKey(key); err != nil {
+		return restore(err)
+	}
+	if err := v.save(); err != nil {
+		return restore(err)
+	}
+	return nil
+}
+
+// WhenUnlocked runs fn now if the vault is unlocked, otherwise once it is.
+// Stores use it to migrate plaintext secrets.
+func (v *Vault) WhenUnlocked(fn func()) {
+	v.mu.Lock()
+	if v.isLocked() {
+		v.unlocked = append(v.unlocked, fn)
+		v.mu.Unlock()
+		return
+	}
+	v.mu.Unlock()
+	fn()
+}
+
+// Seal stores a value and returns its reference. References and empty
+// strings are returned as they are, and a value already in the vault keeps
+// its reference, so sealing a resolved config again changes nothing.
+func (v *Vault) Seal(value string) (string, error) {
+	if value == "" || IsRef(value) {
+		return value, nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.ready(); err != nil {
+		return "", err
+	}
+	for id, plain := range v.plain {
+		if subtle.ConstantTimeCompare([]byte(plain), []byte(value)) == 1 {
+			v.hand(id)
+			return utils.SecretRefPrefix + id, nil
+		}
+	}
+
+	buf := make([]byte, idBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+	sealed, err := seal(v.aead, id, value)
+	if err != nil {
+		return "", err
+	}
+	v.file.Secrets[id] = sealed
+	v.plain[id] = value
+	if err := v.save(); err != nil {
+		delete(v.file.Secrets, id)
+		delete(v.plain, id)
+		return "", err
+	}
+	v.hand(id)
+	return utils.SecretRefPrefix + id, nil
+}
+
+// hand notes a reference given out while Prune runs, which may not be in
+// the references it gathered. The caller holds the lock.
+func (v *Vault) hand(id string) {
+	if v.handed != nil {
+		v.handed[id] = true
+	}
+}
+
+// Prune deletes the secrets nothing refers to any more. inUse gathers the
+// references the stores hold; secrets sealed meanwhile are kept. It
+// returns how many secrets were deleted.
+func (v *Vault) Prune(inUse func() (map[string]bool, error)) (int, error) {
+	v.mu.Lock()
+	if v.err != nil {
+		v.mu.Unlock()
+		return 0, v.err
+	}
+	ids := make([]string, 0, len(v.file.Secrets))
+	for id := range v.file.Secrets {
+		ids = append(ids, id)
+	}
+	v.handed = make(map[string]bool)
+	v.mu.Unlock()
+
+	used, err := inUse()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	handed := v.handed
+	v.handed = nil
+	if err != nil {
+		return 0, err
+	}
+	removed := make(map[string][]byte)
+	for _, id := range ids {
+		if !used[utils.SecretRefPrefix+id] && !handed[id] {
+			removed[id] = v.file.Secrets[id]
+			delete(v.file.Secrets, id)
+		}
+	}
+	if len(removed) == 0 {
+		return 0, nil
+	}
+	if err := v.save(); err != nil {
+		maps.Copy(v.file.Secrets, removed)
+		return 0, err
+	}
+	for id := range removed {
+		delete(v.plain, id)
+	}
+	return len(removed), nil
+}
+
+// Resolve returns the value a reference points to. Anything that is not a
+// reference is returned unchanged.
+func (v *Vault) Resolve(s string) (string, error) {
+	if !IsRef(s) {
+		return s, nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.isLocked() {
+		if v.err != nil {
+			return "", v.err
+		}
+		return "", ErrLocked
+	}
+	plain, ok := v.plain[strings.TrimPrefix(s, utils.SecretRefPrefix)]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return plain, nil
+}
+
+// SealMap seals the values of secret keys in m and in the maps nested in
+// it, directly or in lists, in place. It reports whether anything changed.
+func (v *Vault) SealMap(m map[string]interface{}) (bool, error) {
+	changed := false
+	for k, val := range m {
+		switch val := val.(type) {
+		case string:
+			if !utils.IsSecretKey(k) || val == "" || IsRef(val) {
+				continue
+			}
+			ref, err := v.Seal(val)
+			if err != nil {
+				return changed, err
+			}
+			m[k] = ref
+			changed = true
+		case map[string]interface{}:
+			c, err := v.SealMap(val)
+			changed = changed || c
+			if err != nil {
+				return changed, err
+			}
+		case []interface{}:
+			for _, item := range val {
+				nested, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				c, err := v.SealMap(nested)
+				changed = changed || c
+				if err != nil {
+					return changed, err
+				}
+			}
+		}
+	}
+	return changed, nil
+}
+
+// ResolveMap returns a copy of m with every reference, at any depth,
+// replaced by its value
+func (v *Vault) ResolveMap(m map[string]interface{}) (map[string]interface{}, error) {
+	if m == nil {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, val := range m {
+		switch val := val.(type) {
+		case string:
+			plain, err := v.Resolve(val)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = plain
+		case map[string]interface{}:
+			nested, err := v.ResolveMap(val)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = nested
+		case []interface{}:
+			items := make([]interface{}, len(val))
+			for i, item := range val {
+				items[i] = item
+				if nested, ok := item.(map[string]interface{}); ok {
+					resolved, err := v.ResolveMap(nested)
+					if err != nil {
+						return nil, err
+					}
+					items[i] = resolved
+				}
+			}
+			out[k] = items
+		default:
+			out[k] = val
+		}
+	}
+	return out, nil
+}
+
+// refPattern finds references in serialized configs
+var refPattern = regexp.MustCompile(regexp.QuoteMeta(utils.SecretRefPrefix) + fmt.Sprintf("[0-9a-f]{%d}", 2*idBytes))
+
+// CollectRefs adds the references found anywhere in v, serialized as JSON,
+// to refs
+func CollectRefs(v interface{}, refs map[string]bool) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refPattern.FindAll(data, -1) {
+		refs[string(ref)] = true
+	}
+	return nil
+}
+
+// IsRef reports whether s is a secret reference
+func IsRef(s string) bool {
+	id, ok := strings.CutPrefix(s, utils.SecretRefPrefix)
+	if !ok || len(id) != 2*idBytes {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// The helpers below use the default vault, which is only opened once a
+// value needs it
+
+// Seal stores a value in the default vault
+func Seal(value string) (string, error) {
+	if value == "" || IsRef(value) {
+		return value, nil
+	}
+	return Default().Seal(value)
+}
+
+// Resolve resolves a reference against the default vault
+func Resolve(s string) (string, error) {
+	if !IsRef(s) {
+		return s, nil
+	}
+	return Default().Resolve(s)
+}
+
+// SealMap seals the secret values of a config map in the default vault
+func SealMap(m map[string]interface{}) (bool, error) {
+	if !hasSecret(m, func(k, v string) bool { return utils.IsSecretKey(k) && !IsRef(v) }) {
+		return false, nil
+	}
+	return Default().SealMap(m)
+}
+
+// ResolveMap resolves the references of a config map against the default
+// vault. A map without references is returned as it is.
+func ResolveMap(m map[string]interface{}) (map[string]interface{}, error) {
+	if !hasSecret(m, func(_, v string) bool { return IsRef(v) }) {
+		return m, nil
+	}
+	return Default().ResolveMap(m)
+}
+
+// hasSecret reports whether any non-empty string in m, at any depth,
+// matches
+func hasSecret(m map[string]interface{}, match func(k, v string) bool) bool {
+	for k, val := range m {
+		switch val := val.(type) {
+		case string:
+			if val != "" && match(k, val) {
+				return true
+			}
+		case map[string]interface{}:
+			if hasSecret(val, match) {
+				return true
+			}
+		case []interface{}:
+			for _, item := range val {
+				if nested, ok := item.(map[string]interface{}); ok && hasSecret(nested, match) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// usePassphrase derives a new master key from a passphrase and re-encrypts
+// the secrets under it. The caller saves the file.
+func (v *Vault) usePassphrase(passphrase string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	v.file.Source = SourcePassphrase
+	v.file.Salt = salt
+	return v.setKey(deriveKey(passphrase, salt))
+}
+
+// unlockWith checks key against the vault and decrypts every secret
+func (v *Vault) unlockWith(key []byte) error {
+	v.mu.Lock()
+	aead, err := newAEAD(key)
+	if err != nil {
+		v.mu.Unlock()
+		return err
+	}
+	if check, err := open(aead, "check", v.file.Check); err != nil || check != checkValue {
+		v.mu.Unlock()
+		if v.file.Source == SourcePassphrase {
+			return ErrBadPassphrase
+		}
+		return fmt.Errorf("master key does not match the vault")
+	}
+
+	plain := make(map[string]string, len(v.file.Secrets))
+	for id, sealed := range v.file.Secrets {
+		value, err := open(aead, id, sealed)
+		if err != nil {
+			v.mu.Unlock()
+			return fmt.Errorf("decrypt secret %s: %w", id, err)
+		}
+		plain[id] = value
+	}
+	v.aead = aead
+	v.plain = plain
+	pending := v.unlocked
+	v.unlocked = nil
+	v.mu.Unlock()
+
+	for _, fn := range pending {
+		fn()
+	}
+	return nil
+}
+
+// setKey switches to a new master key, re-encrypting the secrets held in
+// plaintext. The caller holds the lock or owns the vault.
+func (v *Vault) setKey(key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	check, err := seal(aead, "check", checkValue)
+	if err != nil {
+		return err
+	}
+	secrets := make(map[string][]byte, len(v.plain))
+	for id, value := range v.plain {
+		if secrets[id], err = seal(aead, id, value); err != nil {
+			return err
+		}
+	}
+	if v.plain == nil {
+		v.plain = map[string]string{}
+	}
+	v.file.Check = check
+	v.file.Secrets = secrets
+	v.aead = aead
+	return nil
+}
+
+// save writes the vault atomically, readable only by the user
+func (v *Vault) save() error {
+	data, err := json.MarshalIndent(v.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return err
+	}
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, v.path)
+}
+
+func (v *Vault) keyringGet() ([]byte, error) {
+	if v.keyring == nil {
+		return nil, errKeyringMissing
+	}
+	return v.keyring.Get()
+}
+
+func (v *Vault) keyringSet(key []byte) error {
+	if v.keyring == nil {
+		return errKeyringMissing
+	}
+	return v.keyring.Set(key)
+}
+
+// deriveKey stretches a passphrase with Argon2id using the RFC 9106
+// second recommended parameters
+func deriveKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, 3, 64*1024, 4, 32)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts value with a random nonce, binding it to id so sealed
+// values cannot be swapped between entries
+func seal(aead cipher.AEAD, id, value string) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, []byte(value), []byte(id)), nil
+}
+
+func open(aead cipher.AEAD, id string, sealed []byte) (string, error) {
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("sealed value is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimSpace(string(data)))
+}
+
+func writeKeyFile(path string, key []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600)
+}
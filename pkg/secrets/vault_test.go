@@ -0,0 +1,177 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTestVault(t *testing.T, dir string) *Vault {
+	t.Helper()
+	v, err := Open(filepath.Join(dir, "secrets.json"), filepath.Join(dir, "master.key"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestSealResolve(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	dir := t.TempDir()
+	v := openTestVault(t, dir)
+
+	config := map[string]interface{}{
+		"host":     "db.internal",
+		"password": "hunter2",
+		"auth":     map[string]interface{}{"type": "bearer", "token": "tok"},
+		"tunnels":  []interface{}{map[string]interface{}{"id": "t1", "proxy": map[string]interface{}{"password": "pw"}}},
+	}
+	changed, err := v.SealMap(config)
+	if err != nil || !changed {
+		t.Fatalf("SealMap = %v, %v", changed, err)
+	}
+	ref, _ := config["password"].(string)
+	proxy := config["tunnels"].([]interface{})[0].(map[string]interface{})["proxy"].(map[string]interface{})
+	if !IsRef(ref) || !IsRef(proxy["password"].(string)) || config["host"] != "db.internal" {
+		t.Fatalf("sealed config = %v", config)
+	}
+	// The same value keeps its reference
+	if again, err := v.Seal("hunter2"); err != nil || again != ref {
+		t.Fatalf("Seal = %q, %v, want %q", again, err, ref)
+	}
+
+	// A reopened vault reads its key from the key file
+	v = openTestVault(t, dir)
+	resolved, err := v.ResolveMap(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolvedProxy := resolved["tunnels"].([]interface{})[0].(map[string]interface{})["proxy"].(map[string]interface{})
+	if resolved["password"] != "hunter2" || resolved["auth"].(map[string]interface{})["token"] != "tok" || resolvedProxy["password"] != "pw" {
+		t.Fatalf("resolved = %v", resolved)
+	}
+	if config["password"] != ref || !IsRef(proxy["password"].(string)) {
+		t.Fatal("ResolveMap changed its input")
+	}
+	if info, err := os.Stat(filepath.Join(dir, "secrets.json")); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("vault file = %v, %v", info, err)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	dir := t.TempDir()
+	v := openTestVault(t, dir)
+	ref, err := v.Seal("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SetPassphrase("", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "master.key")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("key file left behind: %v", err)
+	}
+
+	v = openTestVault(t, dir)
+	if _, err := v.Resolve(ref); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Resolve while locked = %v", err)
+	}
+	migrated := false
+	v.WhenUnlocked(func() { migrated = true })
+	if err := v.Unlock("wrong"); !errors.Is(err, ErrBadPassphrase) || migrated {
+		t.Fatalf("Unlock with a wrong passphrase = %v", err)
+	}
+	if err := v.Unlock("correct horse"); err != nil || !migrated {
+		t.Fatalf("Unlock = %v, migrated %v", err, migrated)
+	}
+	if plain, err := v.Resolve(ref); err != nil || plain != "hunter2" {
+		t.Fatalf("Resolve = %q, %v", plain, err)
+	}
+	if err := v.SetPassphrase("wrong", "other"); !errors.Is(err, ErrBadPassphrase) {
+		t.Fatalf("SetPassphrase with a wrong current passphrase = %v", err)
+	}
+}
+
+func TestSetPassphraseSaveFailure(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	dir := t.TempDir()
+	v := openTestVault(t, dir)
+	ref, err := v.Seal("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory in the way of the temporary file makes the save fail
+	if err := os.Mkdir(filepath.Join(dir, "secrets.json.tmp"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SetPassphrase("", "correct horse"); err == nil {
+		t.Fatal("SetPassphrase succeeded without saving")
+	}
+	if status := v.Status(); status.Source != SourceFile || status.Locked {
+		t.Fatalf("status after a failed save = %+v", status)
+	}
+	// Moving to a new key file puts the old key back
+	if err := v.SetPassphrase("", ""); err == nil {
+		t.Fatal("SetPassphrase succeeded without saving")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "master.key")); err != nil {
+		t.Fatalf("key file removed: %v", err)
+	}
+
+	// The vault still seals under the key on disk
+	if err := os.Remove(filepath.Join(dir, "secrets.json.tmp")); err != nil {
+		t.Fatal(err)
+	}
+	other, err := v.Seal("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v = openTestVault(t, dir)
+	for ref, want := range map[string]string{ref: "hunter2", other: "other"} {
+		if plain, err := v.Resolve(ref); err != nil || plain != want {
+			t.Fatalf("Resolve = %q, %v, want %q", plain, err, want)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	v := openTestVault(t, t.TempDir())
+	kept, err := v.Seal("kept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphan, err := v.Seal("orphan")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sealed string
+	removed, err := v.Prune(func() (map[string]bool, error) {
+		refs := map[string]bool{}
+		config := map[string]interface{}{"auth": map[string]interface{}{"password": kept}}
+		if err := CollectRefs(config, refs); err != nil {
+			return nil, err
+		}
+		// Sealed while the references are gathered
+		sealed, err = v.Seal("new")
+		return refs, err
+	})
+	if err != nil || removed != 1 {
+		t.Fatalf("Prune = %d, %v", removed, err)
+	}
+	if _, err := v.Resolve(orphan); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("orphan = %v", err)
+	}
+	for ref, want := range map[string]string{kept: "kept", sealed: "new"} {
+		if plain, err := v.Resolve(ref); err != nil || plain != want {
+			t.Fatalf("Resolve = %q, %v, want %q", plain, err, want)
+		}
+	}
+	if status := v.Status(); status.Count != 2 {
+		t.Fatalf("count = %d", status.Count)
+	}
+}
@@ -8,13 +8,16 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/choraleia/choraleia/pkg/api"
-	"github.com/choraleia/choraleia/pkg/message"
+	"github.com/choraleia/choraleia/pkg/config"
 	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service/shellintegration"
+	"github.com/choraleia/choraleia/pkg/service/vt"
 	utils2 "github.com/choraleia/choraleia/pkg/utils"
 	"github.com/cloudwego/eino-ext/components/model/ark"
 	"github.com/cloudwego/eino-ext/components/model/claude"
@@ -37,43 +40,52 @@ import (
 
 // TerminalManager manages all active terminal sessions
 type TerminalManager struct {
-	terminals map[string]*TerminalSession
-	mutex     sync.RWMutex
-	logger    *slog.Logger
+	terminals      map[string]*TerminalSession
+	mutex          sync.RWMutex
+	logger         *slog.Logger
+	scrollbackSize int
+	shares         *ShareStore
+	broadcasts     *BroadcastStore
 }
 
-// TerminalSession stores terminal session info
+// TerminalSession stores terminal session info. A session outlives its
+// WebSocket: conn is nil while no client is attached.
 type TerminalSession struct {
 	ID      string
 	AssetID string
-	Output  []string
+	Output  *Scrollback
+	Screen  *vt.Screen                // rendered view of Output, for reading what the terminal shows
+	Shell   *shellintegration.Tracker // commands, exit codes and cwd from shell integration markers
 	LastCmd string
+	Vars    map[string]string // values captured by triggers
 	mutex   sync.RWMutex
-	// websocket connection reference for sending requests
+	// websocket of the attached client
 	conn *websocket.Conn
 	term *Terminal // new: backend Terminal instance pointer
 }
 
-// OutputRequest represents a pending websocket output request
-type OutputRequest struct {
-	requestID string
-	response  chan *message.TermOutputResponse
-	timeout   time.Time
+// TerminalSessionInfo describes a session for listing
+type TerminalSessionInfo struct {
+	ID         string     `json:"id"`
+	AssetID    string     `json:"asset_id"`
+	Cwd        string     `json:"cwd,omitempty"` // reported by shell integration
+	Attached   bool       `json:"attached"`
+	Viewers    int        `json:"viewers"` // clients joined through share tokens
+	DetachedAt *time.Time `json:"detached_at,omitempty"`
 }
 
-type TerminalOutputManager struct {
-	pendingRequests map[string]*OutputRequest
-	mutex           sync.RWMutex
-}
+// screenHistoryLines matches the scrollback of the frontend terminal
+const screenHistoryLines = 10000
 
-var GlobalTerminalManager = &TerminalManager{
-	terminals: make(map[string]*TerminalSession),
-	logger:    utils2.GetLogger(),
-}
+// commandHistorySize bounds the commands kept per session
+const commandHistorySize = 500
 
-// Global output request manager
-var GlobalOutputManager = &TerminalOutputManager{
-	pendingRequests: make(map[string]*OutputRequest),
+var GlobalTerminalManager = &TerminalManager{
+	terminals:      make(map[string]*TerminalSession),
+	logger:         utils2.GetLogger(),
+	scrollbackSize: config.DefaultScrollbackBytes,
+	shares:         NewShareStore(),
+	broadcasts:     NewBroadcastStore(),
 }
 
 // RegisterTerminal registers a new terminal session
@@ -84,12 +96,143 @@ func (tm *TerminalManager) RegisterTerminal(sessionID, assetID string) {
 	tm.terminals[sessionID] = &TerminalSession{
 		ID:      sessionID,
 		AssetID: assetID,
-		Output:  make([]string, 0),
+		Output:  NewScrollback(tm.scrollbackSize),
+		Screen:  vt.New(vt.DefaultRows, vt.DefaultCols, screenHistoryLines),
+		Shell:   shellintegration.NewTracker(commandHistorySize),
 		LastCmd: "",
 		mutex:   sync.RWMutex{},
 	}
 }
 
+// SetScrollbackSize sets how many bytes of output new sessions keep
+func (tm *TerminalManager) SetScrollbackSize(size int) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.scrollbackSize = size
+}
+
+// GetTerminal returns the backend terminal of a session, or nil
+func (tm *TerminalManager) GetTerminal(sessionID string) *Terminal {
+	tm.mutex.RLock()
+	session, exists := tm.terminals[sessionID]
+	tm.mutex.RUnlock()
+	if !exists {
+		return nil
+	}
+	session.mutex.RLock()
+	defer session.mutex.RUnlock()
+	return session.term
+}
+
+// GetSessionAsset returns the asset a session was opened on
+func (tm *TerminalManager) GetSessionAsset(sessionID string) (string, bool) {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	session, exists := tm.terminals[sessionID]
+	if !exists {
+		return "", false
+	}
+	return session.AssetID, true
+}
+
+// GetSessionContainer returns the container a Docker host session runs in,
+// empty for other sessions
+func (tm *TerminalManager) GetSessionContainer(sessionID string) string {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	session, exists := tm.terminals[sessionID]
+	if !exists || session.term == nil {
+		return ""
+	}
+	return session.term.containerID
+}
+
+// SendInput types data into a session's terminal as its client would
+func (tm *TerminalManager) SendInput(sessionID string, data []byte) error {
+	term := tm.GetTerminal(sessionID)
+	if term == nil {
+		return fmt.Errorf("terminal not ready: %s", sessionID)
+	}
+	term.writeToTerminal(data)
+	return nil
+}
+
+// ReadOutput returns a session's buffered output written after offset and
+// the offset that follows it
+func (tm *TerminalManager) ReadOutput(sessionID string, offset int64) ([]byte, int64, error) {
+	tm.mutex.RLock()
+	session, exists := tm.terminals[sessionID]
+	tm.mutex.RUnlock()
+	if !exists {
+		return nil, 0, fmt.Errorf("terminal session not found: %s", sessionID)
+	}
+	out, end := session.Output.Read(offset)
+	return out, end, nil
+}
+
+// RemoveTerminal drops a session if term still owns it
+func (tm *TerminalManager) RemoveTerminal(sessionID string, term *Terminal) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	if session, exists := tm.terminals[sessionID]; exists && session.term == term {
+		delete(tm.terminals, sessionID)
+		tm.shares.RemoveSession(sessionID)
+		tm.broadcasts.RemoveSession(sessionID)
+	}
+}
+
+// Shares returns the share tokens of the sessions
+func (tm *TerminalManager) Shares() *ShareStore {
+	return tm.shares
+}
+
+// Broadcasts returns the broadcast groups of the sessions
+func (tm *TerminalManager) Broadcasts() *BroadcastStore {
+	return tm.broadcasts
+}
+
+// ListSessions describes the sessions that have a running terminal
+func (tm *TerminalManager) ListSessions() []TerminalSessionInfo {
+	tm.mutex.RLock()
+	infos := make([]TerminalSessionInfo, 0, len(tm.terminals))
+	terms := make([]*Terminal, 0, len(tm.terminals))
+	for _, session := range tm.terminals {
+		session.mutex.RLock()
+		term := session.term
+		session.mutex.RUnlock()
+		if term == nil {
+			continue
+		}
+		infos = append(infos, TerminalSessionInfo{ID: session.ID, AssetID: session.AssetID, Cwd: session.Shell.Cwd()})
+		terms = append(terms, term)
+	}
+	tm.mutex.RUnlock()
+
+	// A terminal takes the manager's lock while holding its own, so ask for
+	// its state only after releasing ours
+	for i, term := range terms {
+		if detachedAt, attached := term.attachState(); attached {
+			infos[i].Attached = true
+		} else {
+			infos[i].DetachedAt = &detachedAt
+		}
+		infos[i].Viewers = term.viewerCount()
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// GetOutputSince returns a session's buffered output written after offset
+func (tm *TerminalManager) GetOutputSince(sessionID string, offset int64) []byte {
+	tm.mutex.RLock()
+	session, exists := tm.terminals[sessionID]
+	tm.mutex.RUnlock()
+	if !exists {
+		return nil
+	}
+	return session.Output.Since(offset)
+}
+
 // SetTerminalConnection sets websocket connection for a session
 func (tm *TerminalManager) SetTerminalConnection(sessionID string, conn *websocket.Conn) {
 	tm.mutex.RLock()
@@ -126,106 +269,46 @@ func (tm *TerminalManager) AttachTerminal(sessionID string, term *Terminal) {
 	session.mutex.Unlock()
 }
 
-// RequestTerminalOutput requests terminal output via websocket
-func (tm *TerminalManager) RequestTerminalOutput(terminalId string, lines int) ([]string, error) {
-	tm.mutex.RLock()
-	session, exists := tm.terminals[terminalId]
-	tm.mutex.RUnlock()
-
-	if !exists {
+// ReadTerminalOutput renders the last lines a terminal shows from the
+// backend screen model: its history and screen, or only the screen while a
+// full-screen application such as vim or top is running
+func (tm *TerminalManager) ReadTerminalOutput(terminalId string, lines int) ([]string, error) {
+	screen := tm.GetTerminalScreen(terminalId)
+	if screen == nil {
 		return nil, fmt.Errorf("terminal session not found: %s", terminalId)
 	}
+	return screen.Lines(lines), nil
+}
 
-	session.mutex.RLock()
-	conn := session.conn
-	session.mutex.RUnlock()
-
-	if conn == nil {
-		tm.logger.Warn("No websocket connection for terminal", "terminalId", terminalId)
-		return nil, fmt.Errorf("no websocket connection for terminal: %s", terminalId)
-	}
-
-	// Generate request ID
-	requestID := uuid.New().String()
-
-	// Create response channel
-	responseChan := make(chan *message.TermOutputResponse, 1)
-
-	// Register request
-	GlobalOutputManager.mutex.Lock()
-	GlobalOutputManager.pendingRequests[requestID] = &OutputRequest{
-		requestID: requestID,
-		response:  responseChan,
-		timeout:   time.Now().Add(5 * time.Second), // 5s timeout
-	}
-	GlobalOutputManager.mutex.Unlock()
-
-	// Send request to frontend
-	request := &message.TermOutputRequest{
-		Base:      message.Base{Type: "TermOutputRequest"},
-		RequestID: requestID,
-		Lines:     lines,
-	}
-
-	requestBytes, err := json.Marshal(request)
-	if err != nil {
-		// Cleanup request
-		GlobalOutputManager.mutex.Lock()
-		delete(GlobalOutputManager.pendingRequests, requestID)
-		GlobalOutputManager.mutex.Unlock()
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	session.mutex.Lock()
-	err = conn.WriteMessage(websocket.TextMessage, requestBytes)
-	session.mutex.Unlock()
+// GetTerminalOutput fetches the last 200 lines of output
+func (tm *TerminalManager) GetTerminalOutput(terminalId string) ([]string, error) {
+	return tm.ReadTerminalOutput(terminalId, 200)
+}
 
-	if err != nil {
-		// Cleanup request
-		GlobalOutputManager.mutex.Lock()
-		delete(GlobalOutputManager.pendingRequests, requestID)
-		GlobalOutputManager.mutex.Unlock()
-		tm.logger.Error("Failed to send output request", "error", err, "terminalId", terminalId)
-		return nil, fmt.Errorf("failed to send output request: %w", err)
-	}
-
-	// Wait response or timeout
-	select {
-	case response := <-responseChan:
-		GlobalOutputManager.mutex.Lock()
-		delete(GlobalOutputManager.pendingRequests, requestID)
-		GlobalOutputManager.mutex.Unlock()
-		if response.Success {
-			return response.Output, nil
-		} else {
-			tm.logger.Error("Frontend failed to get output", "error", response.Error, "terminalId", terminalId)
-			return nil, fmt.Errorf("frontend failed to get output: %s", response.Error)
-		}
-	case <-time.After(5 * time.Second):
-		GlobalOutputManager.mutex.Lock()
-		delete(GlobalOutputManager.pendingRequests, requestID)
-		GlobalOutputManager.mutex.Unlock()
-		tm.logger.Warn("Request timeout for terminal output", "terminalId", terminalId, "requestID", requestID)
-		return nil, fmt.Errorf("request timeout for terminal output")
+// GetTerminalScreen returns the screen model of a session, or nil
+func (tm *TerminalManager) GetTerminalScreen(sessionID string) *vt.Screen {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	if session, exists := tm.terminals[sessionID]; exists {
+		return session.Screen
 	}
+	return nil
 }
 
-// GetTerminalOutput fetches output (200 lines) via websocket
-func (tm *TerminalManager) GetTerminalOutput(terminalId string) ([]string, error) {
-	return tm.RequestTerminalOutput(terminalId, 200)
+// GetShellTracker returns the shell integration tracker of a session, or nil
+func (tm *TerminalManager) GetShellTracker(sessionID string) *shellintegration.Tracker {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	if session, exists := tm.terminals[sessionID]; exists {
+		return session.Shell
+	}
+	return nil
 }
 
-// HandleOutputResponse processes frontend output response
-func (tom *TerminalOutputManager) HandleOutputResponse(response *message.TermOutputResponse) {
-	tom.mutex.RLock()
-	request, exists := tom.pendingRequests[response.RequestID]
-	tom.mutex.RUnlock()
-	if !exists {
-		return
-	}
-	select {
-	case request.response <- response:
-	default:
+// ResizeScreen keeps a session's screen model the size of its terminal
+func (tm *TerminalManager) ResizeScreen(sessionID string, rows, cols int) {
+	if screen := tm.GetTerminalScreen(sessionID); screen != nil {
+		screen.Resize(rows, cols)
 	}
 }
 
@@ -270,6 +353,9 @@ func (s *AIAgentService) getChatModel(c *gin.Context) (einoModel.ToolCallingChat
 	if !hasChat {
 		return nil, fmt.Errorf("model %s does not support chat task type", selectedModel)
 	}
+	if err := modelConfig.CheckSecrets(); err != nil {
+		return nil, err
+	}
 
 	ctx := context.Background()
 
@@ -874,8 +960,14 @@ func (s *AIAgentService) HandleAgentChat(c *gin.Context) {
 func (s *AIAgentService) buildTerminalContextForMessage(c *gin.Context) string {
 	var contextParts []string
 
-	if c.Query("currentTerminal") != "" && c.Query("currentTerminal") != "welcome" {
-		contextParts = append(contextParts, fmt.Sprintf("Current Terminal ID: %s", c.Query("currentTerminal")))
+	if current := c.Query("currentTerminal"); current != "" && current != "welcome" {
+		contextParts = append(contextParts, fmt.Sprintf("Current Terminal ID: %s", current))
+		if screen := GlobalTerminalManager.GetTerminalScreen(current); screen != nil {
+			contextParts = append(contextParts, describeScreen(screen, terminalContextLines))
+		}
+		if tracker := GlobalTerminalManager.GetShellTracker(current); tracker != nil && tracker.Active() {
+			contextParts = append(contextParts, describeCommands(tracker, terminalContextCommands))
+		}
 	}
 
 	if c.Query("selectedTerminals") != "" {
@@ -899,21 +991,57 @@ func (s *AIAgentService) buildTerminalContextForMessage(c *gin.Context) string {
 	return ""
 }
 
-// AppendOutput appends terminal output
-func (tm *TerminalManager) AppendOutput(sessionID, output string) {
+// terminalContextLines bounds the current terminal's output in a message
+const terminalContextLines = 40
+
+// terminalContextCommands bounds the current terminal's recent commands in a
+// message
+const terminalContextCommands = 10
+
+// describeCommands lists a shell's working directory and recent commands
+// with their exit codes for the model
+func describeCommands(tracker *shellintegration.Tracker, n int) string {
+	var b strings.Builder
+	if cwd := tracker.Cwd(); cwd != "" {
+		fmt.Fprintf(&b, "Current Working Directory: %s\n", cwd)
+	}
+	b.WriteString("Recent Commands:")
+	for _, cmd := range tracker.History(n) {
+		status := "exit code unknown"
+		if cmd.ExitCode != nil {
+			status = fmt.Sprintf("exit code %d", *cmd.ExitCode)
+		}
+		fmt.Fprintf(&b, "\n- %s (%s, %s)", cmd.Command, status, time.Duration(cmd.DurationMs)*time.Millisecond)
+	}
+	if running := tracker.Running(); running != nil {
+		fmt.Fprintf(&b, "\n- %s (running for %s)", running.Command, time.Since(running.StartedAt).Round(time.Second))
+	}
+	return b.String()
+}
+
+// describeScreen renders what a terminal shows for the model, saying so when
+// a full-screen application owns the screen
+func describeScreen(screen *vt.Screen, lines int) string {
+	output := strings.Join(screen.Lines(lines), "\n")
+	if screen.AltScreen() {
+		row, col := screen.Cursor()
+		return fmt.Sprintf("Current Terminal Screen (full-screen application, cursor at row %d, column %d):\n%s", row+1, col+1, output)
+	}
+	return fmt.Sprintf("Current Terminal Output (last %d lines):\n%s", lines, output)
+}
+
+// AppendOutput appends terminal output to the session's scrollback and
+// screen model
+func (tm *TerminalManager) AppendOutput(sessionID string, output []byte) {
 	tm.mutex.RLock()
 	session, exists := tm.terminals[sessionID]
 	tm.mutex.RUnlock()
 	if !exists {
 		return
 	}
-	session.mutex.Lock()
-	defer session.mutex.Unlock()
-	session.Output = append(session.Output, output)
-	// Keep recent output (capped large buffer)
-	if len(session.Output) > 1000000 {
-		session.Output = session.Output[len(session.Output)-1000000:]
-	}
+	_, _ = session.Output.Write(output)
+	_, _ = session.Screen.Write(output)
+	_, _ = session.Shell.Write(output)
 }
 
 // SetLastCommand sets last executed command
@@ -929,7 +1057,41 @@ func (tm *TerminalManager) SetLastCommand(sessionID, cmd string) {
 	session.LastCmd = cmd
 }
 
-// GetLastCommand gets last executed command
+// SetVariable sets a variable captured from the session's output
+func (tm *TerminalManager) SetVariable(sessionID, name, value string) {
+	tm.mutex.RLock()
+	session, exists := tm.terminals[sessionID]
+	tm.mutex.RUnlock()
+	if !exists {
+		return
+	}
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if session.Vars == nil {
+		session.Vars = make(map[string]string)
+	}
+	session.Vars[name] = value
+}
+
+// Variables returns a copy of the variables captured in the session
+func (tm *TerminalManager) Variables(sessionID string) map[string]string {
+	tm.mutex.RLock()
+	session, exists := tm.terminals[sessionID]
+	tm.mutex.RUnlock()
+	vars := make(map[string]string)
+	if !exists {
+		return vars
+	}
+	session.mutex.RLock()
+	defer session.mutex.RUnlock()
+	for k, v := range session.Vars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// GetLastCommand gets last executed command, as reported by the shell when
+// it has shell integration
 func (tm *TerminalManager) GetLastCommand(sessionID string) string {
 	tm.mutex.RLock()
 	session, exists := tm.terminals[sessionID]
@@ -937,6 +1099,15 @@ func (tm *TerminalManager) GetLastCommand(sessionID string) string {
 	if !exists {
 		return ""
 	}
+	if session.Shell.Active() {
+		if running := session.Shell.Running(); running != nil {
+			return running.Command
+		}
+		if history := session.Shell.History(1); len(history) > 0 {
+			return history[0].Command
+		}
+		return ""
+	}
 	session.mutex.RLock()
 	defer session.mutex.RUnlock()
 	return session.LastCmd
@@ -948,11 +1119,13 @@ func (tm *TerminalManager) MigrateSession(oldSessionID, newSessionID, assetID st
 	defer tm.mutex.Unlock()
 	oldSession, exists := tm.terminals[oldSessionID]
 	if exists {
-		tm.terminals[newSessionID] = &TerminalSession{ID: newSessionID, AssetID: assetID, Output: oldSession.Output, LastCmd: oldSession.LastCmd, mutex: sync.RWMutex{}, conn: conn, term: oldSession.term}
+		tm.terminals[newSessionID] = &TerminalSession{ID: newSessionID, AssetID: assetID, Output: oldSession.Output, Screen: oldSession.Screen, Shell: oldSession.Shell, LastCmd: oldSession.LastCmd, Vars: oldSession.Vars, mutex: sync.RWMutex{}, conn: conn, term: oldSession.term}
 		delete(tm.terminals, oldSessionID)
+		tm.shares.RenameSession(oldSessionID, newSessionID)
+		tm.broadcasts.RenameSession(oldSessionID, newSessionID)
 		tm.logger.Info("Migrated session data", "oldSessionID", oldSessionID, "newSessionID", newSessionID)
 	} else {
-		tm.terminals[newSessionID] = &TerminalSession{ID: newSessionID, AssetID: assetID, Output: make([]string, 0), LastCmd: "", mutex: sync.RWMutex{}, conn: conn}
+		tm.terminals[newSessionID] = &TerminalSession{ID: newSessionID, AssetID: assetID, Output: NewScrollback(tm.scrollbackSize), Screen: vt.New(vt.DefaultRows, vt.DefaultCols, screenHistoryLines), Shell: shellintegration.NewTracker(commandHistorySize), LastCmd: "", mutex: sync.RWMutex{}, conn: conn}
 		tm.logger.Info("Created new session", "sessionID", newSessionID, "assetID", assetID)
 	}
 }
@@ -42,11 +42,18 @@ type WriteFileInput struct {
 // Tool implementations
 
 func GetTerminalOutput(_ context.Context, params *TerminalOutputInput) (string, error) {
-	output, err := GlobalTerminalManager.RequestTerminalOutput(params.TerminalId, params.Lines)
-	if err != nil {
-		GlobalTerminalManager.logger.Error("Failed to request terminal output via websocket", "error", err, "terminalId", params.TerminalId)
-		return "", fmt.Errorf("failed to get terminal output: %w", err)
+	if params.Lines <= 0 {
+		params.Lines = 100
 	}
+	screen := GlobalTerminalManager.GetTerminalScreen(params.TerminalId)
+	if screen == nil {
+		return "", fmt.Errorf("failed to get terminal output: terminal session not found: %s", params.TerminalId)
+	}
+	if screen.AltScreen() {
+		// A full-screen application: the screen is the whole picture
+		return describeScreen(screen, params.Lines), nil
+	}
+	output := screen.Lines(params.Lines)
 	if len(output) == 0 {
 		return "Terminal output is empty", nil
 	}
@@ -67,9 +74,7 @@ func ExecTerminalCommand(_ context.Context, params *ExecCommandInput) (string, e
 	if !exists || session.term == nil {
 		return "", fmt.Errorf("terminal not ready: %s", params.TerminalId)
 	}
-	session.mutex.RLock()
-	startLen := len(session.Output)
-	session.mutex.RUnlock()
+	start := session.Output.Offset()
 
 	marker := "__CHORALEIA_EXIT_CODE__"
 	augmentedCmd := fmt.Sprintf("%s; echo %s$?", params.Command, marker)
@@ -81,17 +86,7 @@ func ExecTerminalCommand(_ context.Context, params *ExecCommandInput) (string, e
 	exitCodeRegex := regexp.MustCompile(marker + `([0-9]+)`)
 
 	for time.Now().Before(deadline) {
-		session.mutex.RLock()
-		currentLen := len(session.Output)
-		var newData string
-		if currentLen > startLen {
-			var b strings.Builder
-			for _, chunk := range session.Output[startLen:] {
-				b.WriteString(chunk)
-			}
-			newData = b.String()
-		}
-		session.mutex.RUnlock()
+		newData := string(session.Output.Since(start))
 		if newData != "" {
 			if m := exitCodeRegex.FindStringSubmatch(newData); len(m) == 2 {
 				_, _ = fmt.Sscanf(m[1], "%d", &exitCode)
@@ -101,13 +96,7 @@ func ExecTerminalCommand(_ context.Context, params *ExecCommandInput) (string, e
 		time.Sleep(200 * time.Millisecond)
 	}
 
-	session.mutex.RLock()
-	var b strings.Builder
-	for _, chunk := range session.Output[startLen:] {
-		b.WriteString(chunk)
-	}
-	allOutput := b.String()
-	session.mutex.RUnlock()
+	allOutput := string(session.Output.Since(start))
 
 	if exitCode == -1 {
 		if m := exitCodeRegex.FindStringSubmatch(allOutput); len(m) == 2 {
@@ -120,14 +109,8 @@ func ExecTerminalCommand(_ context.Context, params *ExecCommandInput) (string, e
 		if session.term != nil {
 			session.term.writeToTerminal([]byte("\x03"))
 			time.Sleep(3 * time.Millisecond)
-			session.mutex.RLock()
-			var b2 strings.Builder
-			for _, chunk := range session.Output[startLen:] {
-				b2.WriteString(chunk)
-			}
-			allOutput = b2.String()
+			allOutput = string(session.Output.Since(start))
 			allOutput = strings.ReplaceAll(allOutput, marker, "")
-			session.mutex.RUnlock()
 			return fmt.Sprintf("Command timed out, attempted interrupt (Ctrl+C).\nCommand: %s\nOutput:\n%s", params.Command, allOutput), nil
 		}
 		return fmt.Sprintf("Command executed but exit code not detected (possibly timeout).\nCommand: %s\nOutput:\n%s", params.Command, allOutput), nil
@@ -148,24 +131,14 @@ func ReadFile(_ context.Context, params *ReadFileInput) (string, error) {
 	}
 	marker := "__CHORALEIA_EXIT_CODE__"
 	cmd := fmt.Sprintf("cat -- %s; echo %s$?", params.Path, marker)
-	session.mutex.RLock()
-	startLen := len(session.Output)
-	session.mutex.RUnlock()
+	start := session.Output.Offset()
 	session.term.writeToTerminal([]byte(cmd + "\n"))
 	deadline := time.Now().Add(15 * time.Second)
 	exitCode := -1
 	exitCodeRegex := regexp.MustCompile(marker + `([0-9]+)`)
 	var collected string
 	for time.Now().Before(deadline) {
-		session.mutex.RLock()
-		if len(session.Output) > startLen {
-			var b strings.Builder
-			for _, chunk := range session.Output[startLen:] {
-				b.WriteString(chunk)
-			}
-			collected = b.String()
-		}
-		session.mutex.RUnlock()
+		collected = string(session.Output.Since(start))
 		if collected != "" {
 			if m := exitCodeRegex.FindStringSubmatch(collected); len(m) == 2 {
 				_, _ = fmt.Sscanf(m[1], "%d", &exitCode)
@@ -209,24 +182,14 @@ func WriteFile(_ context.Context, params *WriteFileInput) (string, error) {
 	}
 	marker := "__CHORALEIA_EXIT_CODE__"
 	cmd := fmt.Sprintf("cat <<'%s' %s %s\n%s\n%s\necho %s$?", delimiter, redir, params.Path, params.Content, delimiter, marker)
-	session.mutex.RLock()
-	startLen := len(session.Output)
-	session.mutex.RUnlock()
+	start := session.Output.Offset()
 	session.term.writeToTerminal([]byte(cmd + "\n"))
 	deadline := time.Now().Add(20 * time.Second)
 	exitCode := -1
 	exitCodeRegex := regexp.MustCompile(marker + `([0-9]+)`)
 	var collected string
 	for time.Now().Before(deadline) {
-		session.mutex.RLock()
-		if len(session.Output) > startLen {
-			var b strings.Builder
-			for _, chunk := range session.Output[startLen:] {
-				b.WriteString(chunk)
-			}
-			collected = b.String()
-		}
-		session.mutex.RUnlock()
+		collected = string(session.Output.Since(start))
 		if collected != "" {
 			if m := exitCodeRegex.FindStringSubmatch(collected); len(m) == 2 {
 				_, _ = fmt.Sscanf(m[1], "%d", &exitCode)
@@ -256,7 +219,7 @@ func WriteFile(_ context.Context, params *WriteFileInput) (string, error) {
 func NewTerminalOutputTool() tool.InvokableTool {
 	terminalTool := utils.NewTool(&schema.ToolInfo{
 		Name:  "terminal_get_output",
-		Desc:  "Fetch recent output of a specified terminal; choose a terminal_id from IDs mentioned in user messages.",
+		Desc:  "Fetch recent output of a specified terminal as rendered text; choose a terminal_id from IDs mentioned in user messages. While a full-screen application such as vim or top runs, returns its current screen and cursor position.",
 		Extra: map[string]any{},
 		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
 			"terminal_id": {Type: schema.String, Required: true, Desc: "Terminal ID to inspect. Use explicit IDs mentioned by user (e.g. from 'Current Terminal ID' or 'Available Terminal List'), avoid literal placeholders like 'currentTerminal'."},
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/event"
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/secrets"
+	"gorm.io/gorm"
+)
+
+// maxAssetRevisions is how many revisions are kept per asset
+const maxAssetRevisions = 50
+
+// ErrAssetRevisionNotFound is returned for a revision that does not exist
+// or is not of the asset
+var ErrAssetRevisionNotFound = errors.New("asset revision not found")
+
+// recordRevision stores a snapshot of the asset and drops its revisions
+// beyond maxAssetRevisions
+func recordRevision(tx *gorm.DB, asset *models.Asset, action string) error {
+	rev := &models.AssetRevision{
+		AssetID: asset.ID,
+		Action:  action,
+		Asset:   models.AssetSnapshot(*asset),
+	}
+	if err := tx.Create(rev).Error; err != nil {
+		return err
+	}
+	newest := tx.Model(&models.AssetRevision{}).Select("id").
+		Where("asset_id = ?", asset.ID).Order("id DESC").Limit(maxAssetRevisions)
+	return tx.Where("asset_id = ? AND id NOT IN (?)", asset.ID, newest).
+		Delete(&models.AssetRevision{}).Error
+}
+
+// ListRevisions returns the revisions of an asset, deleted or not, newest
+// first
+func (s *AssetService) ListRevisions(assetID string) ([]models.AssetRevision, error) {
+	var revs []models.AssetRevision
+	if err := s.db.Where("asset_id = ?", assetID).Order("id DESC").Find(&revs).Error; err != nil {
+		return nil, err
+	}
+	return revs, nil
+}
+
+// ListDeletedAssets returns the delete revision of each deleted asset,
+// newest first
+func (s *AssetService) ListDeletedAssets() ([]models.AssetRevision, error) {
+	var revs []models.AssetRevision
+	err := s.db.Where("action = ? AND asset_id NOT IN (?)", models.AssetRevisionDelete,
+		s.db.Model(&models.Asset{}).Select("id")).
+		Order("id DESC").Find(&revs).Error
+	if err != nil {
+		return nil, err
+	}
+	// An asset restored and deleted again has several
+	seen := make(map[string]bool)
+	result := revs[:0]
+	for _, rev := range revs {
+		if !seen[rev.AssetID] {
+			seen[rev.AssetID] = true
+			result = append(result, rev)
+		}
+	}
+	return result, nil
+}
+
+// RestoreRevision rolls an asset back to a revision. A deleted asset is
+// recreated without its children, which are restored on their own; it goes
+// back into its folder, or the root if the folder is gone too. An existing
+// asset keeps its place unless the revision has it in another folder.
+func (s *AssetService) RestoreRevision(assetID string, revisionID uint) (*models.Asset, error) {
+	var rev models.AssetRevision
+	if err := s.db.First(&rev, "id = ? AND asset_id = ?", revisionID, assetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAssetRevisionNotFound
+		}
+		return nil, err
+	}
+
+	var asset *models.Asset
+	var created bool
+	var oldTunnelIDs []string
+	err := s.change(func(tx *gorm.DB, t *assetTree) error {
+		snap := models.Asset(rev.Asset)
+		parentID := snap.ParentID
+		if parentID != nil && t.assets[*parentID] == nil {
+			parentID = nil
+		}
+
+		asset = t.assets[assetID]
+		created = asset == nil
+		if created {
+			asset = &snap
+			asset.ParentID = parentID
+			asset.PrevID, asset.NextID = nil, nil
+			t.assets[asset.ID] = asset
+			t.insertAppend(asset)
+		} else {
+			if asset.Type == models.AssetTypeSSH {
+				oldTunnelIDs = getTunnelIDs(asset.Config)
+			}
+			if !sameParent(asset.ParentID, parentID) {
+				if err := t.move(asset, &models.MoveAssetRequest{NewParentID: parentID, Position: "append"}); err != nil {
+					return err
+				}
+			}
+			asset.Name = snap.Name
+			asset.Description = snap.Description
+			asset.Config = snap.Config
+			asset.Tags = snap.Tags
+		}
+		asset.UpdatedAt = time.Now()
+		if err := asset.ValidateConfig(); err != nil {
+			return fmt.Errorf("config validation failed: %v", err)
+		}
+		// Revisions recorded before secrets were sealed may hold plaintext
+		if _, err := secrets.SealMap(asset.Config); err != nil {
+			return fmt.Errorf("failed to store secrets: %v", err)
+		}
+		t.edit(asset)
+		return recordRevision(tx, asset, models.AssetRevisionRestore)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		event.Emit(event.AssetCreatedEvent{AssetID: asset.ID})
+		if asset.Type == models.AssetTypeSSH {
+			emitTunnelCreatedEvents(asset.ID, asset.Config)
+		}
+	} else {
+		event.Emit(event.AssetUpdatedEvent{AssetID: asset.ID})
+		if asset.Type == models.AssetTypeSSH {
+			emitTunnelDiffEvents(asset.ID, oldTunnelIDs, getTunnelIDs(asset.Config))
+		}
+	}
+	return asset, nil
+}
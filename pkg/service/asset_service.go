@@ -2,45 +2,77 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/choraleia/choraleia/pkg/event"
 	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/secrets"
+	"github.com/choraleia/choraleia/pkg/utils"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
 )
 
-// AssetService asset management service (in-memory + file persistence)
+// ErrAssetNotFound is returned for an asset that does not exist
+var ErrAssetNotFound = errors.New("asset not found")
+
+// AssetService asset management service (SQLite persistence with revision
+// history)
 type AssetService struct {
-	dataFile string
-	assets   map[string]*models.Asset
+	db *gorm.DB
+	// mu serializes changes: each rewrites sibling links read from the
+	// database in the same transaction
+	mu sync.Mutex
+	// assets.json of earlier versions, imported once
+	legacyFile string
 }
 
-// NewAssetService creates a new asset service instance
-func NewAssetService() *AssetService {
+// NewAssetService creates a new asset service instance; AutoMigrate must
+// run before it is used
+func NewAssetService(db *gorm.DB) *AssetService {
 	homeDir, _ := os.UserHomeDir()
-	dataDir := filepath.Join(homeDir, ".choraleia")
-	_ = os.MkdirAll(dataDir, 0755)
+	return &AssetService{
+		db:         db,
+		legacyFile: filepath.Join(homeDir, ".choraleia", "assets.json"),
+	}
+}
 
-	service := &AssetService{
-		dataFile: filepath.Join(dataDir, "assets.json"),
-		assets:   make(map[string]*models.Asset),
+// AutoMigrate creates the asset tables and imports the assets.json of
+// earlier versions
+func (s *AssetService) AutoMigrate() error {
+	if err := s.db.AutoMigrate(&models.Asset{}, &models.AssetRevision{}); err != nil {
+		return err
+	}
+	// The import seals secrets, so a locked vault holds it back
+	if secrets.Default().Status().Locked {
+		secrets.Default().WhenUnlocked(func() {
+			if err := s.importLegacyFile(); err != nil {
+				utils.GetLogger().Error("Failed to import assets", "file", s.legacyFile, "error", err)
+			}
+		})
+	} else if err := s.importLegacyFile(); err != nil {
+		return fmt.Errorf("failed to import %s: %w", s.legacyFile, err)
 	}
-	_ = service.loadAssets()
-	return service
+	// Move plaintext secrets of earlier versions into the vault
+	secrets.Default().WhenUnlocked(s.migrateSecrets)
+	return nil
 }
 
-// loadAssets loads assets data from file
-func (s *AssetService) loadAssets() error {
-	if _, err := os.Stat(s.dataFile); os.IsNotExist(err) {
+// importLegacyFile merges the assets of assets.json into the database,
+// skipping IDs it already has, with their secrets sealed, then deletes the
+// file so no plaintext copy is left
+func (s *AssetService) importLegacyFile() error {
+	data, err := os.ReadFile(s.legacyFile)
+	if os.IsNotExist(err) {
 		return nil
 	}
-	data, err := os.ReadFile(s.dataFile)
 	if err != nil {
 		return err
 	}
@@ -49,37 +81,234 @@ func (s *AssetService) loadAssets() error {
 		return err
 	}
 
-	needsSave := false
+	var imported []*models.Asset
+	err = s.change(func(tx *gorm.DB, t *assetTree) error {
+		for _, asset := range legacyOrder(list) {
+			if t.assets[asset.ID] != nil {
+				continue
+			}
+			// Assets under a folder that is gone go to the root, appended
+			// after the assets already there
+			if asset.ParentID != nil && t.assets[*asset.ParentID] == nil {
+				asset.ParentID = nil
+			}
+			asset.PrevID, asset.NextID = nil, nil
+			// Migrate: ensure SSH assets have tunnel IDs
+			if asset.Type == models.AssetTypeSSH && asset.Config != nil {
+				ensureTunnelIDs(asset.Config)
+			}
+			if _, err := secrets.SealMap(asset.Config); err != nil {
+				return fmt.Errorf("failed to store secrets: %v", err)
+			}
+			t.assets[asset.ID] = asset
+			t.insertAppend(asset)
+			t.edit(asset)
+			if err := recordRevision(tx, asset, models.AssetRevisionImport); err != nil {
+				return err
+			}
+			imported = append(imported, asset)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(s.legacyFile); err != nil {
+		return err
+	}
+	utils.GetLogger().Info("Imported assets into the database", "count", len(imported), "skipped", len(list)-len(imported), "file", s.legacyFile)
+	for _, asset := range imported {
+		event.Emit(event.AssetCreatedEvent{AssetID: asset.ID})
+		if asset.Type == models.AssetTypeSSH {
+			emitTunnelCreatedEvents(asset.ID, asset.Config)
+		}
+	}
+	return nil
+}
+
+// legacyOrder lists the assets of assets.json parents first and siblings
+// in the order of their links, so appending them one by one rebuilds the
+// tree. Assets under a missing folder follow, and those still left, in a
+// parent cycle, come last in file order.
+func legacyOrder(list []models.Asset) []*models.Asset {
+	byID := make(map[string]*models.Asset, len(list))
 	for i := range list {
-		asset := list[i]
-		// Migrate: ensure SSH assets have tunnel IDs
-		if asset.Type == models.AssetTypeSSH && asset.Config != nil {
-			if ensureTunnelIDs(asset.Config) {
-				needsSave = true
+		byID[list[i].ID] = &list[i]
+	}
+	placed := make(map[string]bool, len(list))
+	var ordered []*models.Asset
+	var visit func(parentID *string)
+	visit = func(parentID *string) {
+		var siblings []*models.Asset
+		for i := range list {
+			if a := &list[i]; !placed[a.ID] && sameParent(a.ParentID, parentID) {
+				siblings = append(siblings, a)
+			}
+		}
+		start := len(ordered)
+		// Follow the links from the head, then take what they missed
+		for _, head := range siblings {
+			if head.PrevID != nil && byID[*head.PrevID] != nil {
+				continue
+			}
+			for a := head; a != nil && !placed[a.ID] && sameParent(a.ParentID, parentID); {
+				placed[a.ID] = true
+				ordered = append(ordered, a)
+				if a.NextID == nil {
+					break
+				}
+				a = byID[*a.NextID]
+			}
+			break
+		}
+		for _, a := range siblings {
+			if !placed[a.ID] {
+				placed[a.ID] = true
+				ordered = append(ordered, a)
 			}
 		}
-		s.assets[asset.ID] = &asset
+		for _, a := range ordered[start:] {
+			visit(&a.ID)
+		}
+	}
+	visit(nil)
+	// Assets under a missing folder start trees of their own
+	for i := range list {
+		if a := &list[i]; !placed[a.ID] && a.ParentID != nil && byID[*a.ParentID] == nil {
+			placed[a.ID] = true
+			ordered = append(ordered, a)
+			visit(&a.ID)
+		}
 	}
+	for i := range list {
+		if !placed[list[i].ID] {
+			ordered = append(ordered, &list[i])
+		}
+	}
+	return ordered
+}
 
-	// Save if migration occurred
-	if needsSave {
-		_ = s.saveAssets()
+// migrateSecrets seals plaintext passwords and keys left in asset configs
+// and in the snapshots of their revisions
+func (s *AssetService) migrateSecrets() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var assets []models.Asset
+	if err := s.db.Find(&assets).Error; err != nil {
+		utils.GetLogger().Error("Failed to load assets", "error", err)
+		return
+	}
+	for i := range assets {
+		changed, err := secrets.SealMap(assets[i].Config)
+		if err != nil {
+			utils.GetLogger().Error("Failed to move asset secrets into the vault", "asset", assets[i].ID, "error", err)
+			return
+		}
+		if !changed {
+			continue
+		}
+		if err := s.db.Model(&assets[i]).UpdateColumn("config", assets[i].Config).Error; err != nil {
+			utils.GetLogger().Error("Failed to save asset", "asset", assets[i].ID, "error", err)
+		}
 	}
 
-	return nil
+	var revs []models.AssetRevision
+	if err := s.db.Find(&revs).Error; err != nil {
+		utils.GetLogger().Error("Failed to load asset revisions", "error", err)
+		return
+	}
+	for i := range revs {
+		changed, err := secrets.SealMap(revs[i].Asset.Config)
+		if err != nil {
+			utils.GetLogger().Error("Failed to move revision secrets into the vault", "revision", revs[i].ID, "error", err)
+			return
+		}
+		if !changed {
+			continue
+		}
+		if err := s.db.Model(&revs[i]).UpdateColumn("asset", revs[i].Asset).Error; err != nil {
+			utils.GetLogger().Error("Failed to save asset revision", "revision", revs[i].ID, "error", err)
+		}
+	}
 }
 
-// saveAssets persists assets to file
-func (s *AssetService) saveAssets() error {
-	list := make([]models.Asset, 0, len(s.assets))
-	for _, a := range s.assets {
-		list = append(list, *a)
-	}
-	data, err := json.MarshalIndent(list, "", "  ")
-	if err != nil {
-		return err
+// assetTree is every asset, loaded to change sibling links in a transaction.
+// Assets touched or removed are written back when the change commits.
+type assetTree struct {
+	assets  map[string]*models.Asset
+	touched map[string]bool // true: saved whole, else only its links
+	removed []*models.Asset
+}
+
+// change runs fn on the asset tree in a transaction and saves what it
+// touched or removed
+func (s *AssetService) change(fn func(tx *gorm.DB, t *assetTree) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var list []*models.Asset
+		if err := tx.Find(&list).Error; err != nil {
+			return err
+		}
+		t := &assetTree{
+			assets:  make(map[string]*models.Asset, len(list)),
+			touched: make(map[string]bool),
+		}
+		for _, a := range list {
+			t.assets[a.ID] = a
+		}
+
+		if err := fn(tx, t); err != nil {
+			return err
+		}
+		for _, a := range t.removed {
+			if err := tx.Delete(a).Error; err != nil {
+				return err
+			}
+		}
+		for id, whole := range t.touched {
+			a := t.assets[id]
+			if whole {
+				if err := tx.Save(a).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			// Siblings keep their UpdatedAt
+			links := map[string]interface{}{"parent_id": a.ParentID, "prev_id": a.PrevID, "next_id": a.NextID}
+			if err := tx.Model(a).UpdateColumns(links).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// touch marks the sibling links of an asset to save
+func (t *assetTree) touch(a *models.Asset) {
+	if !t.touched[a.ID] {
+		t.touched[a.ID] = false
 	}
-	return os.WriteFile(s.dataFile, data, 0644)
+}
+
+// edit marks an asset to save whole
+func (t *assetTree) edit(a *models.Asset) {
+	t.touched[a.ID] = true
+}
+
+// remove deletes an asset from the tree
+func (t *assetTree) remove(a *models.Asset) {
+	delete(t.assets, a.ID)
+	delete(t.touched, a.ID)
+	t.removed = append(t.removed, a)
+}
+
+// sameParent reports whether two parent IDs name the same folder, or both
+// the root
+func sameParent(a, b *string) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
 }
 
 // CreateAsset creates a new asset, appending at tail of sibling list
@@ -103,13 +332,17 @@ func (s *AssetService) CreateAsset(req *models.CreateAssetRequest) (*models.Asse
 	if err := asset.ValidateConfig(); err != nil {
 		return nil, fmt.Errorf("config validation failed: %v", err)
 	}
-	// append at tail
-	if tail := s.findTail(req.ParentID); tail != nil {
-		asset.PrevID = &tail.ID
-		tail.NextID = &asset.ID
+	if _, err := secrets.SealMap(asset.Config); err != nil {
+		return nil, fmt.Errorf("failed to store secrets: %v", err)
 	}
-	s.assets[asset.ID] = asset
-	if err := s.saveAssets(); err != nil {
+	err := s.change(func(tx *gorm.DB, t *assetTree) error {
+		// append at tail
+		t.assets[asset.ID] = asset
+		t.insertAppend(asset)
+		t.edit(asset)
+		return recordRevision(tx, asset, models.AssetRevisionCreate)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to save asset: %v", err)
 	}
 	// Emit asset created event
@@ -123,11 +356,12 @@ func (s *AssetService) CreateAsset(req *models.CreateAssetRequest) (*models.Asse
 	return asset, nil
 }
 
-// findTail finds last sibling with given parent (NextID == nil)
-func (s *AssetService) findTail(parentID *string) *models.Asset {
+// findTail finds last sibling with given parent (NextID == nil), other
+// than the asset itself
+func (t *assetTree) findTail(parentID *string, except string) *models.Asset {
 	var tail *models.Asset
-	for _, a := range s.assets {
-		if (a.ParentID == nil && parentID == nil) || (a.ParentID != nil && parentID != nil && *a.ParentID == *parentID) {
+	for _, a := range t.assets {
+		if a.ID != except && sameParent(a.ParentID, parentID) {
 			if a.NextID == nil { // possible multiple tails if broken; latest wins
 				tail = a
 			}
@@ -136,10 +370,10 @@ func (s *AssetService) findTail(parentID *string) *models.Asset {
 	return tail
 }
 
-// findHead finds a head sibling (PrevID == nil)
-func (s *AssetService) findHead(parentID *string) *models.Asset {
-	for _, a := range s.assets {
-		if (a.ParentID == nil && parentID == nil) || (a.ParentID != nil && parentID != nil && *a.ParentID == *parentID) {
+// findHead finds a head sibling (PrevID == nil), other than the asset itself
+func (t *assetTree) findHead(parentID *string, except string) *models.Asset {
+	for _, a := range t.assets {
+		if a.ID != except && sameParent(a.ParentID, parentID) {
 			if a.PrevID == nil {
 				return a
 			}
@@ -149,163 +383,215 @@ func (s *AssetService) findHead(parentID *string) *models.Asset {
 }
 
 // detach removes a node from its current sibling linked list
-func (s *AssetService) detach(a *models.Asset) {
+func (t *assetTree) detach(a *models.Asset) {
 	if a.PrevID != nil {
-		if prev, ok := s.assets[*a.PrevID]; ok {
+		if prev, ok := t.assets[*a.PrevID]; ok {
 			prev.NextID = a.NextID
+			t.touch(prev)
 		}
 	}
 	if a.NextID != nil {
-		if next, ok := s.assets[*a.NextID]; ok {
+		if next, ok := t.assets[*a.NextID]; ok {
 			next.PrevID = a.PrevID
+			t.touch(next)
 		}
 	}
 	a.PrevID = nil
 	a.NextID = nil
+	t.touch(a)
 }
 
 // insertAppend appends node at end for its parent
-func (s *AssetService) insertAppend(a *models.Asset) {
-	if tail := s.findTail(a.ParentID); tail != nil {
+func (t *assetTree) insertAppend(a *models.Asset) {
+	if tail := t.findTail(a.ParentID, a.ID); tail != nil {
 		tail.NextID = &a.ID
 		a.PrevID = &tail.ID
+		t.touch(tail)
 	}
+	t.touch(a)
 }
 
-// MoveAsset moves an asset relative to target sibling or append
+// isDescendant reports whether the folder folderID is id or lies under it
+func (t *assetTree) isDescendant(folderID *string, id string) bool {
+	visited := make(map[string]struct{})
+	for curID := folderID; curID != nil; {
+		if _, seen := visited[*curID]; seen { // Guard against potential unexpected cycles
+			return false
+		}
+		visited[*curID] = struct{}{}
+		if *curID == id { // Detected that the descendant chain contains the asset itself
+			return true
+		}
+		curAsset, exists := t.assets[*curID]
+		if !exists {
+			return false
+		}
+		curID = curAsset.ParentID
+	}
+	return false
+}
+
+// MoveAsset moves an asset relative to target sibling or append. The
+// sibling links change in one transaction.
 func (s *AssetService) MoveAsset(id string, req *models.MoveAssetRequest) (*models.Asset, error) {
-	a, ok := s.assets[id]
-	if !ok {
-		return nil, fmt.Errorf("asset not found")
+	var moved *models.Asset
+	err := s.change(func(tx *gorm.DB, t *assetTree) error {
+		a, ok := t.assets[id]
+		if !ok {
+			return ErrAssetNotFound
+		}
+		if err := t.move(a, req); err != nil {
+			return err
+		}
+		a.UpdatedAt = time.Now()
+		t.edit(a)
+		moved = a
+		return recordRevision(tx, a, models.AssetRevisionMove)
+	})
+	if err != nil {
+		return nil, err
 	}
+	// Emit asset updated event (move is a form of update)
+	event.Emit(event.AssetUpdatedEvent{AssetID: moved.ID})
+	return moved, nil
+}
+
+// move relinks a into the place req asks for
+func (t *assetTree) move(a *models.Asset, req *models.MoveAssetRequest) error {
 	// Validate target parent chain to avoid moving into itself or its descendant
 	if req.NewParentID != nil {
-		if *req.NewParentID == id {
-			return nil, fmt.Errorf("cannot move asset into itself")
+		if *req.NewParentID == a.ID {
+			return fmt.Errorf("cannot move asset into itself")
 		}
-		visited := make(map[string]struct{})
-		curID := req.NewParentID
-		for curID != nil {
-			if _, seen := visited[*curID]; seen { // Guard against potential unexpected cycles
-				break
-			}
-			visited[*curID] = struct{}{}
-			if *curID == id { // Detected that the descendant chain contains the asset itself
-				return nil, fmt.Errorf("cannot move asset into its descendant")
-			}
-			curAsset, exists := s.assets[*curID]
-			if !exists || curAsset.ParentID == nil {
-				break
-			}
-			curID = curAsset.ParentID
+		if t.isDescendant(req.NewParentID, a.ID) {
+			return fmt.Errorf("cannot move asset into its descendant")
 		}
 	}
-	// detach from old list
-	s.detach(a)
-	// update parent
-	a.ParentID = req.NewParentID
 	var ref *models.Asset
 	if req.TargetSiblingID != nil {
-		ref = s.assets[*req.TargetSiblingID]
+		ref = t.assets[*req.TargetSiblingID]
 		if ref == nil {
-			return nil, fmt.Errorf("target sibling not found")
+			return fmt.Errorf("target sibling not found")
 		}
 		// parent mismatch check
-		if (ref.ParentID == nil && a.ParentID != nil) || (ref.ParentID != nil && a.ParentID == nil) || (ref.ParentID != nil && a.ParentID != nil && *ref.ParentID != *a.ParentID) {
-			return nil, fmt.Errorf("target sibling not in same parent")
+		if !sameParent(ref.ParentID, req.NewParentID) {
+			return fmt.Errorf("target sibling not in same parent")
+		}
+		if ref.ID == a.ID {
+			return fmt.Errorf("cannot move asset next to itself")
 		}
 	}
 	pos := strings.ToLower(req.Position)
 	switch pos {
+	case "before", "after", "append", "":
+	default:
+		return fmt.Errorf("invalid position")
+	}
+
+	// detach from old list
+	t.detach(a)
+	// update parent
+	a.ParentID = req.NewParentID
+	switch pos {
 	case "before":
 		if ref == nil { // insert at head
-			if head := s.findHead(a.ParentID); head != nil {
+			if head := t.findHead(a.ParentID, a.ID); head != nil {
 				head.PrevID = &a.ID
 				a.NextID = &head.ID
+				t.touch(head)
 			}
 		} else {
 			prevID := ref.PrevID
 			if prevID != nil {
-				if prev, ok := s.assets[*prevID]; ok {
+				if prev, ok := t.assets[*prevID]; ok {
 					prev.NextID = &a.ID
 					a.PrevID = &prev.ID
+					t.touch(prev)
 				}
 			}
 			a.NextID = &ref.ID
 			ref.PrevID = &a.ID
+			t.touch(ref)
 		}
 	case "after":
 		if ref == nil { // treat as append
-			s.insertAppend(a)
+			t.insertAppend(a)
 		} else {
 			nextID := ref.NextID
 			if nextID != nil {
-				if next, ok := s.assets[*nextID]; ok {
+				if next, ok := t.assets[*nextID]; ok {
 					next.PrevID = &a.ID
 					a.NextID = &next.ID
+					t.touch(next)
 				}
 			}
 			a.PrevID = &ref.ID
 			ref.NextID = &a.ID
+			t.touch(ref)
 		}
-	case "append", "":
-		s.insertAppend(a)
 	default:
-		return nil, fmt.Errorf("invalid position")
+		t.insertAppend(a)
 	}
-	a.UpdatedAt = time.Now()
-	if err := s.saveAssets(); err != nil {
-		return nil, err
-	}
-	// Emit asset updated event (move is a form of update)
-	event.Emit(event.AssetUpdatedEvent{AssetID: a.ID})
-	return a, nil
+	t.touch(a)
+	return nil
 }
 
 // GetAsset gets asset by ID
 func (s *AssetService) GetAsset(id string) (*models.Asset, error) {
-	asset, exists := s.assets[id]
-	if !exists {
-		return nil, fmt.Errorf("asset not found")
+	var asset models.Asset
+	if err := s.db.First(&asset, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAssetNotFound
+		}
+		return nil, err
 	}
-	return asset, nil
+	return &asset, nil
 }
 
 // UpdateAsset updates an existing asset
 func (s *AssetService) UpdateAsset(id string, req *models.UpdateAssetRequest) (*models.Asset, error) {
-	asset, exists := s.assets[id]
-	if !exists {
-		return nil, fmt.Errorf("asset not found")
-	}
-
+	var asset *models.Asset
 	// Track old tunnel IDs for SSH assets to detect additions/deletions
 	var oldTunnelIDs []string
-	if asset.Type == models.AssetTypeSSH && req.Config != nil {
-		oldTunnelIDs = getTunnelIDs(asset.Config)
-	}
+	err := s.change(func(tx *gorm.DB, t *assetTree) error {
+		var exists bool
+		if asset, exists = t.assets[id]; !exists {
+			return ErrAssetNotFound
+		}
+		if asset.Type == models.AssetTypeSSH && req.Config != nil {
+			oldTunnelIDs = getTunnelIDs(asset.Config)
+		}
 
-	if req.Name != nil {
-		asset.Name = *req.Name
-	}
-	if req.Description != nil {
-		asset.Description = *req.Description
-	}
-	if req.Config != nil {
-		// Ensure tunnel IDs exist for SSH assets
-		if asset.Type == models.AssetTypeSSH {
-			ensureTunnelIDs(req.Config)
+		if req.Name != nil {
+			asset.Name = *req.Name
 		}
-		asset.Config = req.Config
-	}
-	if req.Tags != nil {
-		asset.Tags = req.Tags
-	}
-	asset.UpdatedAt = time.Now()
-	if err := asset.ValidateConfig(); err != nil {
-		return nil, fmt.Errorf("config validation failed: %v", err)
-	}
-	if err := s.saveAssets(); err != nil {
-		return nil, fmt.Errorf("failed to save asset: %v", err)
+		if req.Description != nil {
+			asset.Description = *req.Description
+		}
+		if req.Config != nil {
+			// Ensure tunnel IDs exist for SSH assets
+			if asset.Type == models.AssetTypeSSH {
+				ensureTunnelIDs(req.Config)
+			}
+			// Secrets the API returned redacted keep their values
+			utils.RestoreRedacted(req.Config, asset.Config)
+			asset.Config = req.Config
+		}
+		if req.Tags != nil {
+			asset.Tags = req.Tags
+		}
+		asset.UpdatedAt = time.Now()
+		if err := asset.ValidateConfig(); err != nil {
+			return fmt.Errorf("config validation failed: %v", err)
+		}
+		if _, err := secrets.SealMap(asset.Config); err != nil {
+			return fmt.Errorf("failed to store secrets: %v", err)
+		}
+		t.edit(asset)
+		return recordRevision(tx, asset, models.AssetRevisionUpdate)
+	})
+	if err != nil {
+		return nil, err
 	}
 	// Emit asset updated event
 	event.Emit(event.AssetUpdatedEvent{AssetID: asset.ID})
@@ -399,7 +685,8 @@ func emitTunnelDeletedEvents(config map[string]interface{}) {
 	}
 }
 
-// emitTunnelDiffEvents compares old and new tunnel IDs and emits created/deleted events
+// emitTunnelDiffEvents compares old and new tunnel IDs and emits created,
+// updated and deleted events
 func emitTunnelDiffEvents(assetID string, oldIDs, newIDs []string) {
 	oldSet := make(map[string]bool)
 	for _, id := range oldIDs {
@@ -410,13 +697,18 @@ func emitTunnelDiffEvents(assetID string, oldIDs, newIDs []string) {
 		newSet[id] = true
 	}
 
-	// Emit created events for new tunnels
+	// Emit created events for new tunnels, updated events for kept ones
 	for _, id := range newIDs {
 		if !oldSet[id] {
 			event.Emit(event.TunnelCreatedEvent{
 				TunnelID: id,
 				AssetID:  assetID,
 			})
+		} else {
+			event.Emit(event.TunnelUpdatedEvent{
+				TunnelID: id,
+				AssetID:  assetID,
+			})
 		}
 	}
 
@@ -430,42 +722,75 @@ func emitTunnelDiffEvents(assetID string, oldIDs, newIDs []string) {
 	}
 }
 
-// DeleteAsset deletes an asset and its children recursively
+// DeleteAsset deletes an asset and its children recursively. Each keeps a
+// delete revision it can be restored from.
 func (s *AssetService) DeleteAsset(id string) error {
-	asset, exists := s.assets[id]
-	if !exists {
-		return fmt.Errorf("asset not found")
+	var deleted []*models.Asset
+	err := s.change(func(tx *gorm.DB, t *assetTree) error {
+		asset, exists := t.assets[id]
+		if !exists {
+			return ErrAssetNotFound
+		}
+		// detach self from siblings
+		t.detach(asset)
+		deleted = t.subtree(asset)
+		for _, a := range deleted {
+			if err := recordRevision(tx, a, models.AssetRevisionDelete); err != nil {
+				return err
+			}
+			t.remove(a)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-
-	// Emit tunnel deleted events for SSH assets before deletion
-	if asset.Type == models.AssetTypeSSH {
-		emitTunnelDeletedEvents(asset.Config)
+	for _, a := range deleted {
+		// Emit tunnel deleted events for SSH assets
+		if a.Type == models.AssetTypeSSH {
+			emitTunnelDeletedEvents(a.Config)
+		}
+		// Emit asset deleted event
+		event.Emit(event.AssetDeletedEvent{AssetID: a.ID})
 	}
+	return nil
+}
 
-	// detach self from siblings
-	s.detach(asset)
-	// recursive delete children
-	for cid, child := range s.assets {
-		if child.ParentID != nil && *child.ParentID == id {
-			_ = s.DeleteAsset(cid)
+// subtree returns an asset and all assets under it, parents first
+func (t *assetTree) subtree(root *models.Asset) []*models.Asset {
+	result := []*models.Asset{root}
+	seen := map[string]bool{root.ID: true}
+	for i := 0; i < len(result); i++ {
+		for _, a := range t.assets {
+			if a.ParentID != nil && *a.ParentID == result[i].ID && !seen[a.ID] {
+				seen[a.ID] = true
+				result = append(result, a)
+			}
 		}
 	}
-	delete(s.assets, id)
-	if err := s.saveAssets(); err != nil {
-		return err
-	}
-	// Emit asset deleted event
-	event.Emit(event.AssetDeletedEvent{AssetID: id})
-	return nil
+	return result
 }
 
-// ListAssets lists assets with filters (ordering handled by client via linked list)
-func (s *AssetService) ListAssets(assetType string, tags []string, search string) ([]*models.Asset, error) {
+// ListAssets lists assets with filters (ordering handled by client via linked list).
+// A non-empty parentID keeps the assets anywhere under that folder.
+func (s *AssetService) ListAssets(assetType string, tags []string, search string, parentID string) ([]*models.Asset, error) {
+	var list []*models.Asset
+	if err := s.db.Find(&list).Error; err != nil {
+		return nil, err
+	}
+	assets := make(map[string]*models.Asset, len(list))
+	for _, a := range list {
+		assets[a.ID] = a
+	}
+
 	var result []*models.Asset
-	for _, asset := range s.assets {
+	for _, asset := range list {
 		if assetType != "" && string(asset.Type) != assetType {
 			continue
 		}
+		if parentID != "" && !isUnder(assets, asset, parentID) {
+			continue
+		}
 		if len(tags) > 0 {
 			has := false
 			for _, t := range tags {
@@ -494,6 +819,22 @@ func (s *AssetService) ListAssets(assetType string, tags []string, search string
 	return result, nil
 }
 
+// isUnder reports whether folderID is an ancestor of asset
+func isUnder(assets map[string]*models.Asset, asset *models.Asset, folderID string) bool {
+	// Bounded by the asset count, in case the tree has a cycle
+	for i := 0; i <= len(assets) && asset.ParentID != nil; i++ {
+		if *asset.ParentID == folderID {
+			return true
+		}
+		parent, ok := assets[*asset.ParentID]
+		if !ok {
+			return false
+		}
+		asset = parent
+	}
+	return false
+}
+
 // ParseSSHConfig parses SSH config file
 func (s *AssetService) ParseSSHConfig() ([]*models.ParsedSSHHost, error) {
 	homeDir, _ := os.UserHomeDir()
@@ -586,11 +927,16 @@ func (s *AssetService) ImportFromSSHConfig() (int, error) {
 		return 0, err
 	}
 
+	sshAssets, err := s.ListAssets(string(models.AssetTypeSSH), nil, "", "")
+	if err != nil {
+		return 0, err
+	}
+
 	imported := 0
 	for _, host := range hosts {
 		// skip if already exists
 		exists := false
-		for _, asset := range s.assets {
+		for _, asset := range sshAssets {
 			if asset.Type == models.AssetTypeSSH {
 				var cfg models.SSHConfig
 				if err := asset.GetTypedConfig(&cfg); err == nil {
@@ -625,7 +971,8 @@ func (s *AssetService) ImportFromSSHConfig() (int, error) {
 			Config:      cfgMap,
 			Tags:        []string{"ssh", "imported"},
 		}
-		if _, err := s.CreateAsset(req); err == nil {
+		if asset, err := s.CreateAsset(req); err == nil {
+			sshAssets = append(sshAssets, asset)
 			imported++
 		}
 	}
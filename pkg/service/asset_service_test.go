@@ -0,0 +1,114 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/choraleia/choraleia/pkg/models"
+)
+
+// newAssetTree links the named assets in order under the root, and the
+// assets of folder "f" under it
+func newAssetTree(root, inF []string) *assetTree {
+	t := &assetTree{assets: make(map[string]*models.Asset), touched: make(map[string]bool)}
+	link := func(ids []string, parent *string) {
+		for _, id := range ids {
+			a := &models.Asset{ID: id, ParentID: parent}
+			t.assets[id] = a
+			t.insertAppend(a)
+		}
+	}
+	link(root, nil)
+	f := "f"
+	link(inF, &f)
+	t.touched = make(map[string]bool)
+	return t
+}
+
+// siblings lists the assets under parent from head to tail
+func (t *assetTree) siblings(parent *string) string {
+	var ids []string
+	for a := t.findHead(parent, ""); a != nil && len(ids) <= len(t.assets); {
+		ids = append(ids, a.ID)
+		if a.NextID == nil {
+			break
+		}
+		a = t.assets[*a.NextID]
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestAssetTreeMove(t *testing.T) {
+	f := "f"
+	ref := func(id string) *string { return &id }
+	tests := []struct {
+		name     string
+		id       string
+		req      models.MoveAssetRequest
+		root, in string
+		err      string
+	}{
+		{"before", "c", models.MoveAssetRequest{TargetSiblingID: ref("a"), Position: "before"}, "c,a,b,f", "x,y", ""},
+		{"after", "a", models.MoveAssetRequest{TargetSiblingID: ref("b"), Position: "after"}, "b,a,c,f", "x,y", ""},
+		{"head", "f", models.MoveAssetRequest{Position: "before"}, "f,a,b,c", "x,y", ""},
+		{"into folder", "b", models.MoveAssetRequest{NewParentID: &f, TargetSiblingID: ref("y"), Position: "before"}, "a,c,f", "x,b,y", ""},
+		{"out of folder", "x", models.MoveAssetRequest{Position: "append"}, "a,b,c,f,x", "y", ""},
+		{"into itself", "f", models.MoveAssetRequest{NewParentID: &f}, "a,b,c,f", "x,y", "into itself"},
+		{"into descendant", "f", models.MoveAssetRequest{NewParentID: ref("x")}, "a,b,c,f", "x,y", "descendant"},
+		{"other parent", "a", models.MoveAssetRequest{TargetSiblingID: ref("x"), Position: "after"}, "a,b,c,f", "x,y", "same parent"},
+		{"bad position", "a", models.MoveAssetRequest{Position: "sideways"}, "a,b,c,f", "x,y", "invalid position"},
+	}
+	for _, tt := range tests {
+		tree := newAssetTree([]string{"a", "b", "c", "f"}, []string{"x", "y"})
+		err := tree.move(tree.assets[tt.id], &tt.req)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+			if len(tree.touched) != 0 {
+				t.Errorf("%s: changed %v on error", tt.name, tree.touched)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if got := tree.siblings(nil); got != tt.root {
+			t.Errorf("%s: root %s, want %s", tt.name, got, tt.root)
+		}
+		if got := tree.siblings(&f); got != tt.in {
+			t.Errorf("%s: folder %s, want %s", tt.name, got, tt.in)
+		}
+	}
+}
+
+func TestAssetTreeSubtree(t *testing.T) {
+	tree := newAssetTree([]string{"a", "f"}, []string{"x", "g"})
+	g := "g"
+	tree.assets["z"] = &models.Asset{ID: "z", ParentID: &g}
+
+	var ids []string
+	for _, a := range tree.subtree(tree.assets["f"]) {
+		ids = append(ids, a.ID)
+	}
+	if ids[0] != "f" || ids[len(ids)-1] != "z" || len(ids) != 4 {
+		t.Errorf("subtree = %v, want f first, z last of 4", ids)
+	}
+}
+
+func TestLegacyOrder(t *testing.T) {
+	f, b, c, missing, a := "f", "b", "c", "missing", "a"
+	list := []models.Asset{
+		{ID: "c", ParentID: &f, PrevID: &b},
+		{ID: "o", ParentID: &missing},
+		{ID: "a", NextID: &f},
+		{ID: "b", ParentID: &f, NextID: &c},
+		{ID: "f", PrevID: &a},
+	}
+
+	var ids []string
+	for _, asset := range legacyOrder(list) {
+		ids = append(ids, asset.ID)
+	}
+	if got := strings.Join(ids, ","); got != "a,f,b,c,o" {
+		t.Errorf("legacyOrder = %s, want a,f,b,c,o", got)
+	}
+}
@@ -39,6 +39,8 @@ var (
 // ToolLoader interface for loading tools (implemented by tools package)
 type ToolLoader interface {
 	LoadWorkspaceTools(ctx context.Context, workspaceID string, conversationID string, toolConfigs []models.WorkspaceTool) ([]tool.InvokableTool, error)
+	// IsDangerousTool reports whether a loaded tool can modify data
+	IsDangerousTool(t tool.InvokableTool, name string) bool
 }
 
 // ChatService handles workspace chat operations
@@ -58,6 +60,13 @@ type ChatService struct {
 
 	// Active streams management for graceful handling
 	activeStreams sync.Map // conversationID -> *StreamSession
+
+	// Tool calls waiting for user approval
+	pendingApprovals sync.Map // conversationID/toolCallID -> *pendingApproval
+
+	// chunksMu guards Message.Chunks, which tool calls append to while
+	// the agent loop is streaming
+	chunksMu sync.Mutex
 }
 
 // StreamSession tracks an active streaming session
@@ -243,6 +252,9 @@ func (s *ChatService) LoadMessageWithChunks(messageID string) (*models.Message,
 
 // AddAndSaveTextChunk adds a text chunk to message and saves to database in real-time
 func (s *ChatService) AddAndSaveTextChunk(msg *models.Message, text string, roundIndex int, agentName string, runPath []string) error {
+	s.chunksMu.Lock()
+	defer s.chunksMu.Unlock()
+
 	// Get sequence index (count of existing chunks in this round)
 	seqIndex := s.getNextSeqIndex(msg, roundIndex)
 
@@ -275,6 +287,9 @@ func (s *ChatService) AddAndSaveTextChunk(msg *models.Message, text string, roun
 
 // AddAndSaveReasoningChunk adds a reasoning chunk to message and saves to database in real-time
 func (s *ChatService) AddAndSaveReasoningChunk(msg *models.Message, text string, roundIndex int, agentName string, runPath []string) error {
+	s.chunksMu.Lock()
+	defer s.chunksMu.Unlock()
+
 	seqIndex := s.getNextSeqIndex(msg, roundIndex)
 
 	// Serialize runPath to JSON string
@@ -303,6 +318,9 @@ func (s *ChatService) AddAndSaveReasoningChunk(msg *models.Message, text string,
 
 // AddAndSaveToolCallChunk adds a tool call chunk to message and saves to database in real-time
 func (s *ChatService) AddAndSaveToolCallChunk(msg *models.Message, toolCallID, toolName, args string, roundIndex int, agentName string, runPath []string) error {
+	s.chunksMu.Lock()
+	defer s.chunksMu.Unlock()
+
 	seqIndex := s.getNextSeqIndex(msg, roundIndex)
 
 	// Serialize runPath to JSON string
@@ -333,6 +351,9 @@ func (s *ChatService) AddAndSaveToolCallChunk(msg *models.Message, toolCallID, t
 
 // AddAndSaveToolResultChunk adds a tool result chunk to message and saves to database in real-time
 func (s *ChatService) AddAndSaveToolResultChunk(msg *models.Message, toolCallID, toolName, content string, roundIndex int, agentName string, runPath []string) error {
+	s.chunksMu.Lock()
+	defer s.chunksMu.Unlock()
+
 	seqIndex := s.getNextSeqIndex(msg, roundIndex)
 
 	// Serialize runPath to JSON string
@@ -361,6 +382,46 @@ func (s *ChatService) AddAndSaveToolResultChunk(msg *models.Message, toolCallID,
 	return s.db.Create(&chunk).Error
 }
 
+// AddAndSaveApprovalChunk records a tool call approval request or decision.
+// The chunk joins the round, agent and run path of the tool call it answers.
+func (s *ChatService) AddAndSaveApprovalChunk(msg *models.Message, approval *db.ApprovalPart) (db.MessageChunk, error) {
+	s.chunksMu.Lock()
+	defer s.chunksMu.Unlock()
+
+	chunk := db.MessageChunk{
+		ID:             uuid.New().String(),
+		MessageID:      msg.ID,
+		Type:           db.ChunkTypeApproval,
+		RoundIndex:     msg.GetMaxRoundIndex(),
+		ToolCallID:     approval.ToolCallID,
+		ToolName:       approval.Name,
+		ToolArgs:       approval.Arguments,
+		ApprovalStatus: approval.Status,
+		ApprovalBy:     approval.DecidedBy,
+		Text:           approval.Reason,
+		CreatedAt:      time.Now(),
+	}
+	for _, c := range msg.Chunks {
+		if c.Type == db.ChunkTypeToolCall && c.ToolCallID == approval.ToolCallID {
+			chunk.RoundIndex = c.RoundIndex
+			chunk.AgentName = c.AgentName
+			chunk.RunPath = c.RunPath
+			break
+		}
+	}
+	chunk.SeqIndex = s.getNextSeqIndex(msg, chunk.RoundIndex)
+
+	msg.Chunks = append(msg.Chunks, chunk)
+	return chunk, s.db.Create(&chunk).Error
+}
+
+// currentRoundIndex returns the message's latest round index
+func (s *ChatService) currentRoundIndex(msg *models.Message) int {
+	s.chunksMu.Lock()
+	defer s.chunksMu.Unlock()
+	return msg.GetMaxRoundIndex()
+}
+
 // getNextSeqIndex returns the next sequence index for a given round
 func (s *ChatService) getNextSeqIndex(msg *models.Message, roundIndex int) int {
 	count := 0
@@ -586,6 +647,37 @@ func (s *ChatService) chunksToMessageParts(chunks []db.MessageChunk) []db.Messag
 				},
 			})
 
+		case db.ChunkTypeApproval:
+			approval := &db.ApprovalPart{
+				ToolCallID: chunk.ToolCallID,
+				Name:       chunk.ToolName,
+				Arguments:  chunk.ToolArgs,
+				Status:     chunk.ApprovalStatus,
+				DecidedBy:  chunk.ApprovalBy,
+				Reason:     chunk.Text,
+			}
+			// A decision updates the part of the request it answers
+			if approval.Status != db.ApprovalPending {
+				updated := false
+				for i := len(parts) - 1; i >= 0; i-- {
+					if parts[i].Approval != nil && parts[i].Approval.ToolCallID == chunk.ToolCallID {
+						parts[i].Approval = approval
+						updated = true
+						break
+					}
+				}
+				if updated {
+					continue
+				}
+			}
+			parts = append(parts, db.MessagePart{
+				Type:      "approval",
+				Index:     chunk.RoundIndex,
+				AgentName: chunk.AgentName,
+				RunPath:   parseRunPath(chunk.RunPath),
+				Approval:  approval,
+			})
+
 		case db.ChunkTypeImageURL:
 			parts = append(parts, db.MessagePart{
 				Type:      "image_url",
@@ -2318,6 +2410,28 @@ func (s *ChatService) runStreamingAgent(ctx context.Context, req *models.ChatCom
 		return assistantMsg, ErrModelNotConfigured
 	}
 
+	// Helper to send chunk and update buffer. Tool calls waiting for approval
+	// send from the agent's goroutines, which can outlive this function.
+	var sendMu sync.RWMutex
+	sendClosed := false
+	defer func() {
+		sendMu.Lock()
+		sendClosed = true
+		sendMu.Unlock()
+	}()
+	sendChunk := func(chunk *models.ChatCompletionChunk) {
+		sendMu.RLock()
+		defer sendMu.RUnlock()
+		if sendClosed {
+			return
+		}
+		s.updateStreamBuffer(conv.ID, chunk)
+		chunks <- chunk
+	}
+
+	// Tool calls the workspace wants approved wait for the user
+	workspaceTools = s.gateToolApprovals(ctx, req.WorkspaceID, conv.ID, assistantMsg, modelID, workspaceTools, sendChunk)
+
 	// Convert []tool.InvokableTool to []tool.BaseTool
 	baseTools := make([]tool.BaseTool, len(workspaceTools))
 	for i, t := range workspaceTools {
@@ -2378,12 +2492,6 @@ func (s *ChatService) runStreamingAgent(ctx context.Context, req *models.ChatCom
 		}
 	}
 
-	// Helper to send chunk and update buffer
-	sendChunk := func(chunk *models.ChatCompletionChunk) {
-		s.updateStreamBuffer(conv.ID, chunk)
-		chunks <- chunk
-	}
-
 	// Send initial chunk with role
 	sendChunk(&models.ChatCompletionChunk{
 		ID:             assistantMsg.ID,
@@ -2467,7 +2575,7 @@ func (s *ChatService) runStreamingAgent(ctx context.Context, req *models.ChatCom
 			errorMsg := s.formatAgentError(chunk.Err)
 
 			// Get current round index
-			roundIndex := currentAssistantMsg.GetMaxRoundIndex()
+			roundIndex := s.currentRoundIndex(currentAssistantMsg)
 			if roundIndex < 0 {
 				roundIndex = 0
 			}
@@ -2562,7 +2670,7 @@ func (s *ChatService) runStreamingAgent(ctx context.Context, req *models.ChatCom
 			}
 
 			// Get current round index from the assistant message
-			roundIndex := currentAssistantMsg.GetMaxRoundIndex()
+			roundIndex := s.currentRoundIndex(currentAssistantMsg)
 
 			// Add tool result to current assistant message's chunks and save to database
 			if err := s.AddAndSaveToolResultChunk(currentAssistantMsg, fullMsg.ToolCallID, fullMsg.ToolName, fullMsg.Content, roundIndex, currentAgentName, currentRunPath); err != nil {
@@ -2597,7 +2705,7 @@ func (s *ChatService) runStreamingAgent(ctx context.Context, req *models.ChatCom
 			var err error
 
 			// Get current round index for this response
-			roundIndex := currentAssistantMsg.GetMaxRoundIndex()
+			roundIndex := s.currentRoundIndex(currentAssistantMsg)
 
 			// Check if this is a streaming message
 			if chunk.Output.MessageOutput.IsStreaming && chunk.Output.MessageOutput.MessageStream != nil {
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/db"
+	"github.com/choraleia/choraleia/pkg/event"
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/google/uuid"
+)
+
+// toolApprovalTimeout rejects a tool call nobody answers
+const toolApprovalTimeout = 30 * time.Minute
+
+// ErrApprovalNotFound is returned when no tool call waits for the given answer
+var ErrApprovalNotFound = errors.New("no pending approval for this tool call")
+
+// ToolApprovalDecision is the user's answer to a pending tool call
+type ToolApprovalDecision struct {
+	Approved bool   `json:"approved"`
+	User     string `json:"user,omitempty"`   // Who answered, recorded in the history
+	Reason   string `json:"reason,omitempty"` // Passed to the model on rejection
+}
+
+// pendingApproval is a tool call blocked on a decision
+type pendingApproval struct {
+	decision chan ToolApprovalDecision
+}
+
+// approvalGate pauses tool calls of one streaming run until they're answered
+type approvalGate struct {
+	s              *ChatService
+	conversationID string
+	msg            *models.Message
+	modelID        string
+	send           func(*models.ChatCompletionChunk)
+}
+
+// approvalTool asks the gate before running the tool it wraps
+type approvalTool struct {
+	tool.InvokableTool
+	gate *approvalGate
+	name string
+}
+
+// gateToolApprovals wraps the tools whose calls the workspace's approval
+// policy requires the user to approve
+func (s *ChatService) gateToolApprovals(ctx context.Context, workspaceID, conversationID string, msg *models.Message, modelID string, tools []tool.InvokableTool, send func(*models.ChatCompletionChunk)) []tool.InvokableTool {
+	if len(tools) == 0 || s.toolLoader == nil {
+		return tools
+	}
+	workspace, err := s.workspaceService.Get(ctx, workspaceID)
+	if err != nil {
+		// Without the policy every call waits for the user
+		s.logger.Warn("Failed to load workspace approval policy, approving every tool call", "workspaceID", workspaceID, "error", err)
+		workspace = &models.Workspace{ToolApprovalMode: models.ToolApprovalAlways}
+	}
+	if workspace.ToolApprovalMode == models.ToolApprovalNever {
+		return tools
+	}
+
+	gate := &approvalGate{
+		s:              s,
+		conversationID: conversationID,
+		msg:            msg,
+		modelID:        modelID,
+		send:           send,
+	}
+	gated := make([]tool.InvokableTool, len(tools))
+	for i, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			gated[i] = &approvalTool{InvokableTool: t, gate: gate}
+			continue
+		}
+		if !workspace.RequiresApproval(info.Name, s.toolLoader.IsDangerousTool(t, info.Name)) {
+			gated[i] = t
+			continue
+		}
+		gated[i] = &approvalTool{InvokableTool: t, gate: gate, name: info.Name}
+	}
+	return gated
+}
+
+func (t *approvalTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	decision, err := t.gate.wait(ctx, compose.GetToolCallID(ctx), t.name, argumentsInJSON)
+	if err != nil {
+		return "", err
+	}
+	if !decision.Approved {
+		result := "Error: the user rejected this tool call"
+		if decision.Reason != "" {
+			result += ": " + decision.Reason
+		}
+		return result + ". Do not retry it; ask the user how to proceed.", nil
+	}
+	return t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+}
+
+// wait announces a tool call and blocks until it's answered, times out or
+// the run is cancelled. Cancellation is recorded as a rejection.
+func (g *approvalGate) wait(ctx context.Context, toolCallID, toolName, args string) (ToolApprovalDecision, error) {
+	if toolCallID == "" {
+		toolCallID = uuid.New().String()
+	}
+	key := approvalKey(g.conversationID, toolCallID)
+	pending := &pendingApproval{decision: make(chan ToolApprovalDecision, 1)}
+	g.s.pendingApprovals.Store(key, pending)
+	defer g.s.pendingApprovals.Delete(key)
+
+	g.record(&db.ApprovalPart{ToolCallID: toolCallID, Name: toolName, Arguments: args, Status: db.ApprovalPending})
+	event.Emit(event.ApprovalRequestedEvent{
+		ConversationID: g.conversationID,
+		MessageID:      g.msg.ID,
+		ToolCallID:     toolCallID,
+		ToolName:       toolName,
+	})
+	g.s.logger.Info("Tool call waiting for approval",
+		"conversationID", g.conversationID,
+		"toolCallID", toolCallID,
+		"tool", toolName)
+
+	timer := time.NewTimer(toolApprovalTimeout)
+	defer timer.Stop()
+
+	var (
+		decision ToolApprovalDecision
+		err      error
+	)
+	select {
+	case decision = <-pending.decision:
+	case <-timer.C:
+		decision = ToolApprovalDecision{User: "system", Reason: fmt.Sprintf("no answer within %s", toolApprovalTimeout)}
+	case <-ctx.Done():
+		decision = ToolApprovalDecision{User: "system", Reason: "the run was cancelled"}
+		err = ctx.Err()
+	}
+
+	status := db.ApprovalRejected
+	if decision.Approved {
+		status = db.ApprovalApproved
+	}
+	g.record(&db.ApprovalPart{
+		ToolCallID: toolCallID,
+		Name:       toolName,
+		Arguments:  args,
+		Status:     status,
+		DecidedBy:  decision.User,
+		Reason:     decision.Reason,
+	})
+	event.Emit(event.ApprovalResolvedEvent{
+		ConversationID: g.conversationID,
+		ToolCallID:     toolCallID,
+		Status:         status,
+	})
+	g.s.logger.Info("Tool call approval resolved",
+		"conversationID", g.conversationID,
+		"toolCallID", toolCallID,
+		"tool", toolName,
+		"status", status,
+		"by", decision.User)
+	return decision, err
+}
+
+// record persists an approval chunk and streams it to the client
+func (g *approvalGate) record(approval *db.ApprovalPart) {
+	chunk, err := g.s.AddAndSaveApprovalChunk(g.msg, approval)
+	if err != nil {
+		g.s.logger.Warn("Failed to save approval chunk", "error", err)
+	}
+	var runPath []string
+	if chunk.RunPath != "" {
+		_ = json.Unmarshal([]byte(chunk.RunPath), &runPath)
+	}
+	g.send(&models.ChatCompletionChunk{
+		ID:             g.msg.ID,
+		Object:         "chat.completion.chunk",
+		Created:        time.Now().Unix(),
+		Model:          g.modelID,
+		ConversationID: g.conversationID,
+		Choices: []models.ChatCompletionChunkChoice{
+			{
+				Index: 0,
+				Delta: models.ChatCompletionChunkDelta{
+					ToolCallID: approval.ToolCallID,
+					Approval:   approval,
+					AgentName:  chunk.AgentName,
+					RunPath:    runPath,
+				},
+			},
+		},
+	})
+}
+
+// ResolveToolApproval answers a tool call waiting for approval
+func (s *ChatService) ResolveToolApproval(conversationID, toolCallID string, decision ToolApprovalDecision) error {
+	value, ok := s.pendingApprovals.LoadAndDelete(approvalKey(conversationID, toolCallID))
+	if !ok {
+		return ErrApprovalNotFound
+	}
+	if decision.User == "" {
+		decision.User = "user"
+	}
+	value.(*pendingApproval).decision <- decision
+	return nil
+}
+
+func approvalKey(conversationID, toolCallID string) string {
+	return conversationID + "/" + toolCallID
+}
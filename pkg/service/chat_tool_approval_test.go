@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/db"
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// countingTool counts its runs
+type countingTool struct{ runs int }
+
+func (t *countingTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: "write_file"}, nil
+}
+
+func (t *countingTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	t.runs++
+	return "written", nil
+}
+
+func TestApprovalTool(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gdb.AutoMigrate(&db.MessageChunk{}); err != nil {
+		t.Fatal(err)
+	}
+	s := &ChatService{db: gdb, logger: slog.Default()}
+
+	// call runs the gated tool and answers its approval with decision
+	call := func(decision ToolApprovalDecision) (string, *countingTool, *models.Message) {
+		inner := &countingTool{}
+		msg := &models.Message{ID: "m"}
+		gated := &approvalTool{
+			InvokableTool: inner,
+			name:          "write_file",
+			gate: &approvalGate{s: s, conversationID: "c", msg: msg, modelID: "model",
+				send: func(*models.ChatCompletionChunk) {}},
+		}
+		done := make(chan string, 1)
+		go func() {
+			out, err := gated.InvokableRun(context.Background(), `{"path":"/tmp/x"}`)
+			if err != nil {
+				out = "run error: " + err.Error()
+			}
+			done <- out
+		}()
+
+		var toolCallID string
+		for deadline := time.Now().Add(5 * time.Second); toolCallID == "" && time.Now().Before(deadline); {
+			s.pendingApprovals.Range(func(key, _ interface{}) bool {
+				toolCallID = strings.TrimPrefix(key.(string), "c/")
+				return false
+			})
+			time.Sleep(time.Millisecond)
+		}
+		if toolCallID == "" {
+			t.Fatal("tool call did not wait for approval")
+		}
+		if inner.runs != 0 {
+			t.Fatal("tool ran before it was approved")
+		}
+		if err := s.ResolveToolApproval("c", toolCallID, decision); err != nil {
+			t.Fatal(err)
+		}
+		return <-done, inner, msg
+	}
+
+	out, inner, msg := call(ToolApprovalDecision{Approved: true, User: "alice"})
+	if out != "written" || inner.runs != 1 {
+		t.Errorf("approved: %q after %d runs", out, inner.runs)
+	}
+	if last := msg.Chunks[len(msg.Chunks)-1]; last.ApprovalStatus != db.ApprovalApproved || last.ApprovalBy != "alice" {
+		t.Errorf("approved chunk = %+v", last)
+	}
+
+	out, inner, msg = call(ToolApprovalDecision{Reason: "wrong file"})
+	if inner.runs != 0 || !strings.Contains(out, "rejected") || !strings.Contains(out, "wrong file") {
+		t.Errorf("rejected: %q after %d runs", out, inner.runs)
+	}
+	if last := msg.Chunks[len(msg.Chunks)-1]; last.ApprovalStatus != db.ApprovalRejected || last.ApprovalBy != "user" {
+		t.Errorf("rejected chunk = %+v", last)
+	}
+
+	if err := s.ResolveToolApproval("c", "unknown", ToolApprovalDecision{Approved: true}); err != ErrApprovalNotFound {
+		t.Errorf("answer without a pending call: %v", err)
+	}
+}
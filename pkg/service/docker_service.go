@@ -12,13 +12,14 @@ import (
 	"log/slog"
 
 	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service/fs"
 	"github.com/choraleia/choraleia/pkg/utils"
-	"golang.org/x/crypto/ssh"
 )
 
 // DockerService handles Docker host operations
 type DockerService struct {
 	assetService *AssetService
+	sshPool      *fs.SSHPool
 	logger       *slog.Logger
 }
 
@@ -35,6 +36,11 @@ func NewDockerService(assetService *AssetService) *DockerService {
 	}
 }
 
+// SetSSHPool sets the SSH pool used to reach remote Docker hosts
+func (s *DockerService) SetSSHPool(pool *fs.SSHPool) {
+	s.sshPool = pool
+}
+
 // ListContainers returns containers from a Docker host
 func (s *DockerService) ListContainers(ctx context.Context, asset *models.Asset, showAll bool) ([]models.ContainerInfo, error) {
 	var cfg models.DockerHostConfig
@@ -138,6 +144,36 @@ func (s *DockerService) containerAction(ctx context.Context, asset *models.Asset
 	return err
 }
 
+// ContainerIP returns the IP address of a container on the first network it
+// is attached to. A nil asset is the Docker daemon on this machine.
+func (s *DockerService) ContainerIP(ctx context.Context, asset *models.Asset, containerID string) (string, error) {
+	var cfg models.DockerHostConfig
+	if asset != nil {
+		if err := asset.GetTypedConfig(&cfg); err != nil {
+			return "", fmt.Errorf("invalid docker host config: %w", err)
+		}
+	}
+
+	args := []string{"inspect", "-f", "{{range .NetworkSettings.Networks}}{{.IPAddress}} {{end}}", containerID}
+
+	var output string
+	var err error
+	if cfg.ConnectionType == "ssh" && cfg.SSHAssetID != "" {
+		output, err = s.execViaSSH(ctx, cfg.SSHAssetID, "docker", args)
+	} else {
+		output, err = s.execLocal(ctx, "docker", args)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	ips := strings.Fields(output)
+	if len(ips) == 0 {
+		return "", fmt.Errorf("container %s has no IP address", containerID)
+	}
+	return ips[0], nil
+}
+
 // TestConnection tests the Docker daemon connection
 func (s *DockerService) TestConnection(ctx context.Context, asset *models.Asset) (*DockerInfo, error) {
 	var cfg models.DockerHostConfig
@@ -206,22 +242,15 @@ func (s *DockerService) execLocal(ctx context.Context, bin string, args []string
 
 // execViaSSH executes a command via SSH on a remote host
 func (s *DockerService) execViaSSH(ctx context.Context, sshAssetID string, bin string, args []string) (string, error) {
-	// Get SSH asset
-	sshAsset, err := s.assetService.GetAsset(sshAssetID)
-	if err != nil {
-		return "", fmt.Errorf("SSH asset not found: %w", err)
-	}
-
-	if sshAsset.Type != models.AssetTypeSSH {
-		return "", fmt.Errorf("referenced asset is not an SSH connection")
+	if s.sshPool == nil {
+		return "", fmt.Errorf("ssh pool not available")
 	}
 
-	// Build SSH connection
-	client, err := s.createSSHClient(sshAsset)
+	// The pooled client is shared, so only the session is closed here
+	client, err := s.sshPool.GetSSHClient(sshAssetID)
 	if err != nil {
 		return "", fmt.Errorf("SSH connection failed: %w", err)
 	}
-	defer client.Close()
 
 	// Create session
 	session, err := client.NewSession()
@@ -251,56 +280,6 @@ func (s *DockerService) execViaSSH(ctx context.Context, sshAssetID string, bin s
 	return stdout.String(), nil
 }
 
-// createSSHClient creates an SSH client from asset config
-func (s *DockerService) createSSHClient(asset *models.Asset) (*ssh.Client, error) {
-	config := asset.Config
-
-	host, _ := config["host"].(string)
-	if host == "" {
-		return nil, fmt.Errorf("SSH host not specified")
-	}
-
-	port := 22
-	if p, ok := config["port"].(float64); ok {
-		port = int(p)
-	}
-
-	username, _ := config["username"].(string)
-	if username == "" {
-		return nil, fmt.Errorf("SSH username not specified")
-	}
-
-	sshConfig := &ssh.ClientConfig{
-		User:            username,
-		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
-	}
-
-	// Add password auth
-	if password, ok := config["password"].(string); ok && password != "" {
-		sshConfig.Auth = append(sshConfig.Auth, ssh.Password(password))
-	}
-
-	// Add key auth
-	if privateKey, ok := config["private_key"].(string); ok && privateKey != "" {
-		passphrase, _ := config["private_key_passphrase"].(string)
-		if signer, err := parsePrivateKeyWithPassphrase(privateKey, passphrase); err == nil {
-			sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(signer))
-		}
-	}
-
-	if privateKeyPath, ok := config["private_key_path"].(string); ok && privateKeyPath != "" {
-		passphrase, _ := config["private_key_passphrase"].(string)
-		if signer, err := loadPrivateKeyFromPath(privateKeyPath, passphrase); err == nil {
-			sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(signer))
-		}
-	}
-
-	addr := fmt.Sprintf("%s:%d", host, port)
-	return ssh.Dial("tcp", addr, sshConfig)
-}
-
 // shellQuote quotes a string for shell safety
 func shellQuote(s string) string {
 	if s == "" {
@@ -309,20 +288,3 @@ func shellQuote(s string) string {
 	// Simple quoting: wrap in single quotes and escape existing single quotes
 	return "'" + strings.ReplaceAll(s, "'", "'\"'\"'") + "'"
 }
-
-// parsePrivateKeyWithPassphrase parses a private key with optional passphrase
-func parsePrivateKeyWithPassphrase(key, passphrase string) (ssh.Signer, error) {
-	if passphrase != "" {
-		return ssh.ParsePrivateKeyWithPassphrase([]byte(key), []byte(passphrase))
-	}
-	return ssh.ParsePrivateKey([]byte(key))
-}
-
-// loadPrivateKeyFromPath loads a private key from file path
-func loadPrivateKeyFromPath(path, passphrase string) (ssh.Signer, error) {
-	key, err := exec.Command("cat", path).Output()
-	if err != nil {
-		return nil, err
-	}
-	return parsePrivateKeyWithPassphrase(string(key), passphrase)
-}
@@ -5,24 +5,25 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"net"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service/sshconn"
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
 // SSHPool manages SSH connections for SSH assets.
 // It provides both raw SSH clients (for remote command execution)
-// and SFTP clients (for file operations).
+// and SFTP clients (for file operations), shared by every feature that
+// doesn't need a dedicated connection. Clients handed out are owned by the
+// pool and must not be closed by callers.
 // Connections are automatically checked for health and cleaned up if dead.
 type SSHPool struct {
-	assets AssetResolver
+	dialer *sshconn.Dialer
 
 	mu      sync.Mutex
 	clients map[string]*sshClientEntry
@@ -35,6 +36,7 @@ type SSHPool struct {
 type sshClientEntry struct {
 	ssh       *ssh.Client
 	sftp      *sftp.Client // lazily created
+	refs      int          // long-lived holders, see Acquire
 	lastUsed  time.Time
 	createdAt time.Time
 }
@@ -46,7 +48,7 @@ type AssetResolver interface {
 
 func NewSSHPool(assets AssetResolver) *SSHPool {
 	pool := &SSHPool{
-		assets:      assets,
+		dialer:      sshconn.NewDialer(assets),
 		clients:     make(map[string]*sshClientEntry),
 		stopCleanup: make(chan struct{}),
 	}
@@ -72,22 +74,33 @@ func (p *SSHPool) cleanupLoop() {
 
 // cleanupDeadConnections removes connections that are dead or idle too long
 func (p *SSHPool) cleanupDeadConnections() {
+	p.mu.Lock()
+	entries := make(map[string]*sshClientEntry, len(p.clients))
+	for key, entry := range p.clients {
+		entries[key] = entry
+	}
+	p.mu.Unlock()
+
+	// Probe without the lock, a dead peer can take a while to answer
+	dead := make(map[string]bool)
+	for key, entry := range entries {
+		if !p.isConnectionAlive(entry.ssh) {
+			dead[key] = true
+		}
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	now := time.Now()
 	maxIdleTime := 10 * time.Minute
 
-	for key, entry := range p.clients {
-		// Check if connection is dead
-		if !p.isConnectionAlive(entry.ssh) {
-			p.closeEntry(entry)
-			delete(p.clients, key)
+	for key, entry := range entries {
+		if p.clients[key] != entry {
 			continue
 		}
-
-		// Check if connection has been idle too long
-		if now.Sub(entry.lastUsed) > maxIdleTime {
+		// Check if connection is dead or has been idle too long
+		if dead[key] || (entry.refs == 0 && now.Sub(entry.lastUsed) > maxIdleTime) {
 			p.closeEntry(entry)
 			delete(p.clients, key)
 		}
@@ -128,206 +141,140 @@ func (p *SSHPool) CloseAll() {
 	}
 }
 
-// GetSSHClient returns an SSH client for the given asset.
-func (p *SSHPool) GetSSHClient(assetID string) (*ssh.Client, error) {
-	key := cacheKey(assetID)
-
+// cached returns the live pooled entry for key, or nil after dropping a dead one
+func (p *SSHPool) cached(key string) *sshClientEntry {
 	p.mu.Lock()
-	if cached, ok := p.clients[key]; ok {
-		// Check if connection is still alive
-		if p.isConnectionAlive(cached.ssh) {
-			cached.lastUsed = time.Now()
-			cli := cached.ssh
-			p.mu.Unlock()
-			return cli, nil
-		}
-		// Connection is dead, remove it
-		p.closeEntry(cached)
-		delete(p.clients, key)
-	}
+	entry := p.clients[key]
 	p.mu.Unlock()
-
-	asset, err := p.assets.GetAsset(assetID)
-	if err != nil {
-		return nil, err
-	}
-	if asset.Type != models.AssetTypeSSH {
-		return nil, fmt.Errorf("asset is not ssh")
+	if entry == nil {
+		return nil
 	}
 
-	ctx := context.Background()
-	sshClient, err := dialSSHFromAsset(ctx, asset)
-	if err != nil {
-		return nil, err
+	// Check if connection is still alive, without holding the lock
+	if p.isConnectionAlive(entry.ssh) {
+		p.mu.Lock()
+		entry.lastUsed = time.Now()
+		p.mu.Unlock()
+		return entry
 	}
+	// Connection is dead, remove it unless it was replaced meanwhile
+	p.mu.Lock()
+	if p.clients[key] == entry {
+		p.closeEntry(entry)
+		delete(p.clients, key)
+	}
+	p.mu.Unlock()
+	return nil
+}
 
+// add pools a freshly dialed client. If another caller pooled one for the
+// same key while this one was dialing, the new client is closed and the
+// pooled entry is returned instead.
+func (p *SSHPool) add(key string, client *ssh.Client) *sshClientEntry {
 	now := time.Now()
 	p.mu.Lock()
-	p.clients[key] = &sshClientEntry{
-		ssh:       sshClient,
+	defer p.mu.Unlock()
+
+	if entry, ok := p.clients[key]; ok {
+		_ = client.Close()
+		entry.lastUsed = now
+		return entry
+	}
+	entry := &sshClientEntry{
+		ssh:       client,
 		lastUsed:  now,
 		createdAt: now,
 	}
-	p.mu.Unlock()
-
-	return sshClient, nil
+	p.clients[key] = entry
+	return entry
 }
 
-// GetSFTPClient returns an SFTP client for the given asset.
-func (p *SSHPool) GetSFTPClient(ctx context.Context, assetID string) (*sftp.Client, error) {
+// entry returns the pooled entry for the asset, dialing it if needed
+func (p *SSHPool) entry(ctx context.Context, assetID string) (*sshClientEntry, error) {
 	key := cacheKey(assetID)
-
-	p.mu.Lock()
-	if cached, ok := p.clients[key]; ok {
-		// Check if connection is still alive
-		if !p.isConnectionAlive(cached.ssh) {
-			// Connection is dead, remove it
-			p.closeEntry(cached)
-			delete(p.clients, key)
-			p.mu.Unlock()
-			// Fall through to create new connection
-		} else {
-			cached.lastUsed = time.Now()
-			if cached.sftp != nil {
-				cli := cached.sftp
-				p.mu.Unlock()
-				return cli, nil
-			}
-			// SSH client exists but no SFTP client yet
-			sftpCli, err := sftp.NewClient(cached.ssh)
-			if err != nil {
-				p.mu.Unlock()
-				return nil, fmt.Errorf("create sftp client: %w", err)
-			}
-			cached.sftp = sftpCli
-			p.mu.Unlock()
-			return sftpCli, nil
-		}
-	} else {
-		p.mu.Unlock()
+	if entry := p.cached(key); entry != nil {
+		return entry, nil
 	}
 
-	asset, err := p.assets.GetAsset(assetID)
+	sshClient, err := p.dialer.Dial(ctx, assetID)
 	if err != nil {
 		return nil, err
 	}
-	if asset.Type != models.AssetTypeSSH {
-		return nil, fmt.Errorf("asset is not ssh")
-	}
+	return p.add(key, sshClient), nil
+}
 
-	sshClient, err := dialSSHFromAsset(ctx, asset)
+// GetSSHClient returns an SSH client for the given asset.
+func (p *SSHPool) GetSSHClient(assetID string) (*ssh.Client, error) {
+	entry, err := p.entry(context.Background(), assetID)
 	if err != nil {
 		return nil, err
 	}
+	return entry.ssh, nil
+}
 
-	sftpCli, err := sftp.NewClient(sshClient)
+// Acquire returns a pooled SSH client for a long-lived holder such as a
+// tunnel. The client isn't closed for being idle until release is called.
+func (p *SSHPool) Acquire(assetID string) (*ssh.Client, func(), error) {
+	client, err := p.GetSSHClient(assetID)
 	if err != nil {
-		_ = sshClient.Close()
-		return nil, fmt.Errorf("create sftp client: %w", err)
+		return nil, nil, err
 	}
+	key := cacheKey(assetID)
 
-	now := time.Now()
 	p.mu.Lock()
-	p.clients[key] = &sshClientEntry{
-		ssh:       sshClient,
-		sftp:      sftpCli,
-		lastUsed:  now,
-		createdAt: now,
+	if entry, ok := p.clients[key]; ok && entry.ssh == client {
+		entry.refs++
 	}
 	p.mu.Unlock()
 
-	return sftpCli, nil
-}
-
-func cacheKey(assetID string) string {
-	sum := sha256.Sum256([]byte(assetID))
-	return hex.EncodeToString(sum[:])
-}
-
-func dialSSHFromAsset(ctx context.Context, asset *models.Asset) (*ssh.Client, error) {
-	cfg := asset.Config
-
-	host, _ := cfg["host"].(string)
-	if host == "" {
-		return nil, fmt.Errorf("ssh host not specified")
-	}
-
-	port := 22
-	if portConfig, ok := cfg["port"].(float64); ok {
-		port = int(portConfig)
-	}
-
-	username, _ := cfg["username"].(string)
-	if username == "" {
-		return nil, fmt.Errorf("ssh username not specified")
-	}
-
-	sshConfig := &ssh.ClientConfig{
-		User:            username,
-		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
-	}
-
-	if timeoutConfig, ok := cfg["timeout"].(float64); ok {
-		sshConfig.Timeout = time.Duration(timeoutConfig) * time.Second
-	}
-
-	if password, ok := cfg["password"].(string); ok && password != "" {
-		sshConfig.Auth = append(sshConfig.Auth, ssh.Password(password))
-	}
-
-	passphrase, _ := cfg["private_key_passphrase"].(string)
-
-	if privateKeyPath, ok := cfg["private_key_path"].(string); ok && privateKeyPath != "" {
-		key, err := loadPrivateKeyFromFile(privateKeyPath, passphrase)
-		if err == nil {
-			sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(key))
-		}
-	}
-	if privateKey, ok := cfg["private_key"].(string); ok && privateKey != "" {
-		key, err := parsePrivateKeyString(privateKey, passphrase)
-		if err == nil {
-			sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(key))
-		}
-	}
-
-	if len(sshConfig.Auth) == 0 {
-		sshConfig.Auth = append(sshConfig.Auth, ssh.Password(""))
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			// Only the entry still pooled for this client holds the ref
+			if entry, ok := p.clients[key]; ok && entry.ssh == client && entry.refs > 0 {
+				entry.refs--
+				entry.lastUsed = time.Now()
+			}
+		})
 	}
+	return client, release, nil
+}
 
-	addr := net.JoinHostPort(host, fmt.Sprintf("%d", port))
-	dialer := &net.Dialer{Timeout: sshConfig.Timeout}
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
+// GetSFTPClient returns an SFTP client for the given asset.
+func (p *SSHPool) GetSFTPClient(ctx context.Context, assetID string) (*sftp.Client, error) {
+	entry, err := p.entry(ctx, assetID)
 	if err != nil {
-		return nil, fmt.Errorf("dial ssh tcp: %w", err)
+		return nil, err
 	}
 
-	c, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
-	if err != nil {
-		_ = conn.Close()
-		return nil, fmt.Errorf("ssh handshake: %w", err)
+	p.mu.Lock()
+	cli := entry.sftp
+	p.mu.Unlock()
+	if cli != nil {
+		return cli, nil
 	}
-	return ssh.NewClient(c, chans, reqs), nil
-}
 
-func loadPrivateKeyFromFile(path string, passphrase string) (ssh.Signer, error) {
-	key, err := os.ReadFile(path)
+	// SSH client exists but no SFTP client yet
+	sftpCli, err := sftp.NewClient(entry.ssh)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("create sftp client: %w", err)
 	}
-	return parsePrivateKeyString(string(key), passphrase)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if entry.sftp != nil {
+		// Another caller got there first
+		_ = sftpCli.Close()
+		return entry.sftp, nil
+	}
+	entry.sftp = sftpCli
+	return sftpCli, nil
 }
 
-func parsePrivateKeyString(keyData string, passphrase string) (ssh.Signer, error) {
-	signer, err := ssh.ParsePrivateKey([]byte(keyData))
-	if err == nil {
-		return signer, nil
-	}
-	if passphrase == "" {
-		return nil, err
-	}
-	return ssh.ParsePrivateKeyWithPassphrase([]byte(keyData), []byte(passphrase))
+func cacheKey(assetID string) string {
+	sum := sha256.Sum256([]byte(assetID))
+	return hex.EncodeToString(sum[:])
 }
 
 // normalizeRemotePath is shared between pool-backed SFTP filesystem calls.
@@ -15,6 +15,9 @@ type FSService struct {
 
 func NewFSService(reg *FSRegistry) *FSService { return &FSService{reg: reg} }
 
+// SSHPool returns the SSH connection pool shared with the registry
+func (s *FSService) SSHPool() *fsimpl.SSHPool { return s.reg.SSHPool() }
+
 // openFS creates a FileSystem instance from EndpointSpec.
 // Type can be omitted if AssetID is provided - it will be auto-detected.
 func (s *FSService) openFS(ctx context.Context, spec EndpointSpec) (fsimpl.FileSystem, error) {
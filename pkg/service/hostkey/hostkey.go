@@ -0,0 +1,170 @@
+// Package hostkey verifies SSH host keys against the user's OpenSSH
+// known_hosts file and a known_hosts file managed by Choraleia.
+package hostkey
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var (
+	ErrUnknown  = errors.New("host key is not known")
+	ErrChanged  = errors.New("host key has changed")
+	ErrRevoked  = errors.New("host key is revoked")
+	ErrRejected = errors.New("host key was rejected")
+)
+
+// Status is how a presented key compares to the keys on record
+type Status string
+
+const (
+	StatusKnown   Status = "known"
+	StatusUnknown Status = "unknown"
+	StatusChanged Status = "changed"
+	StatusRevoked Status = "revoked"
+)
+
+// Key is a host key presented by a server
+type Key struct {
+	Host        string   `json:"host"` // Address as dialed, host:port
+	KeyType     string   `json:"key_type"`
+	Fingerprint string   `json:"fingerprint"` // SHA256 fingerprint
+	PublicKey   string   `json:"public_key"`  // authorized_keys format
+	Status      Status   `json:"status"`
+	Known       []string `json:"known,omitempty"` // Fingerprints on record when the key changed
+
+	key ssh.PublicKey
+}
+
+func newKey(hostname string, key ssh.PublicKey) *Key {
+	return &Key{
+		Host:        hostname,
+		KeyType:     key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+		key:         key,
+	}
+}
+
+func (k *Key) String() string {
+	return fmt.Sprintf("%s %s %s", k.Host, k.KeyType, k.Fingerprint)
+}
+
+// Confirm asks the user whether to trust an unknown key
+type Confirm func(k *Key) bool
+
+// Inspect compares a presented key with the keys on record for the host
+func (s *Store) Inspect(hostname string, remote net.Addr, key ssh.PublicKey) (*Key, error) {
+	k := newKey(hostname, key)
+	err := s.check(hostname, remote, key)
+
+	var (
+		keyErr  *knownhosts.KeyError
+		revoked *knownhosts.RevokedError
+	)
+	switch {
+	case err == nil:
+		k.Status = StatusKnown
+	case errors.As(err, &revoked):
+		k.Status = StatusRevoked
+	case errors.As(err, &keyErr) && len(keyErr.Want) > 0:
+		k.Status = StatusChanged
+		for _, want := range keyErr.Want {
+			k.Known = append(k.Known, ssh.FingerprintSHA256(want.Key))
+		}
+	case errors.As(err, &keyErr):
+		k.Status = StatusUnknown
+	default:
+		return nil, err
+	}
+	return k, nil
+}
+
+// Callback returns a host key callback. Changed and revoked keys are always
+// refused. Unknown keys are refused in strict mode; otherwise they are put
+// to confirm, or trusted on first use when confirm is nil, and recorded.
+func (s *Store) Callback(strict bool, confirm Confirm) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		k, err := s.Inspect(hostname, remote, key)
+		if err != nil {
+			return err
+		}
+		switch k.Status {
+		case StatusKnown:
+			return nil
+		case StatusRevoked:
+			s.logger.Warn("Refused revoked SSH host key", "host", hostname, "fingerprint", k.Fingerprint)
+			return fmt.Errorf("%w: %s", ErrRevoked, k)
+		case StatusChanged:
+			s.logger.Warn("SSH host key changed", "host", hostname, "fingerprint", k.Fingerprint, "known", k.Known)
+			return fmt.Errorf("%w: %s, expected %s; revoke the old key if the change is expected",
+				ErrChanged, k, strings.Join(k.Known, ", "))
+		}
+
+		if strict {
+			return fmt.Errorf("%w: %s; accept it before connecting with strict host key checking", ErrUnknown, k)
+		}
+		if confirm != nil && !confirm(k) {
+			return fmt.Errorf("%w: %s", ErrRejected, k)
+		}
+		if err := s.Add(hostname, key); err != nil {
+			return err
+		}
+		s.logger.Info("Trusted new SSH host key", "host", hostname, "fingerprint", k.Fingerprint, "confirmed", confirm != nil)
+		return nil
+	}
+}
+
+// Algorithms returns the host key algorithms of the keys on record for the
+// host, for ssh.ClientConfig.HostKeyAlgorithms. Like OpenSSH, a client that
+// knows a host's key asks for that key type, so a server offering several
+// isn't reported as changed. It returns nil when no key is on record.
+func (s *Store) Algorithms(hostname string) []string {
+	var keyErr *knownhosts.KeyError
+	if !errors.As(s.check(hostname, nil, probeKey{}), &keyErr) {
+		return nil
+	}
+	var algos []string
+	seen := make(map[string]bool)
+	for _, want := range keyErr.Want {
+		for _, algo := range keyAlgorithms(want.Key.Type()) {
+			if !seen[algo] {
+				seen[algo] = true
+				algos = append(algos, algo)
+			}
+		}
+	}
+	return algos
+}
+
+// keyAlgorithms lists the signature algorithms a key type can be offered with
+func keyAlgorithms(keyType string) []string {
+	switch keyType {
+	case ssh.KeyAlgoRSA:
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	case ssh.CertAlgoRSAv01:
+		return []string{ssh.CertAlgoRSASHA512v01, ssh.CertAlgoRSASHA256v01, ssh.CertAlgoRSAv01}
+	}
+	return []string{keyType}
+}
+
+// probeKey matches no recorded key, so checking it lists the keys on record
+type probeKey struct{}
+
+func (probeKey) Type() string                                 { return "probe" }
+func (probeKey) Marshal() []byte                              { return []byte("probe") }
+func (probeKey) Verify(data []byte, sig *ssh.Signature) error { return errors.New("probe key") }
+
+// ParseKey parses a key in authorized_keys format
+func ParseKey(publicKey string) (ssh.PublicKey, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	return key, nil
+}
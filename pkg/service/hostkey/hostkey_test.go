@@ -0,0 +1,145 @@
+package hostkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newTestKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestCallback(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(filepath.Join(dir, "managed"), filepath.Join(dir, "openssh"))
+	key, other := newTestKey(t), newTestKey(t)
+
+	if err := store.Callback(true, nil)("web:22", nil, key); !errors.Is(err, ErrUnknown) {
+		t.Fatalf("strict unknown = %v", err)
+	}
+	if err := store.Callback(false, func(*Key) bool { return false })("web:22", nil, key); !errors.Is(err, ErrRejected) {
+		t.Fatalf("rejected = %v", err)
+	}
+
+	// Trust on first use, then known
+	var prompted *Key
+	confirm := func(k *Key) bool { prompted = k; return true }
+	if err := store.Callback(false, confirm)("web:22", nil, key); err != nil {
+		t.Fatalf("first use = %v", err)
+	}
+	if prompted == nil || prompted.Fingerprint != ssh.FingerprintSHA256(key) {
+		t.Fatalf("prompt = %+v", prompted)
+	}
+	if err := store.Callback(true, nil)("web:22", nil, key); err != nil {
+		t.Fatalf("known key = %v", err)
+	}
+	if err := store.Callback(false, nil)("web:22", nil, other); !errors.Is(err, ErrChanged) {
+		t.Fatalf("changed key = %v", err)
+	}
+	if err := store.Callback(true, nil)("web:2222", nil, key); !errors.Is(err, ErrUnknown) {
+		t.Fatalf("other port = %v", err)
+	}
+
+	// Accepting the new key replaces the old one
+	if err := store.Add("web:22", other); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := store.List()
+	if err != nil || len(entries) != 1 || entries[0].Fingerprint != ssh.FingerprintSHA256(other) {
+		t.Fatalf("entries = %+v, %v", entries, err)
+	}
+	if err := store.Revoke(entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if k, _ := store.Inspect("web:22", nil, other); k.Status != StatusUnknown {
+		t.Fatalf("after revoke = %s", k.Status)
+	}
+}
+
+func TestRevokeOpenSSHEntry(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(filepath.Join(dir, "managed"), filepath.Join(dir, "openssh"))
+	key := newTestKey(t)
+	line := "# comment\n[db]:2222 " + string(ssh.MarshalAuthorizedKey(key))
+	if err := os.WriteFile(store.openssh, []byte(line), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := store.List()
+	if err != nil || len(entries) != 1 || entries[0].Source != SourceOpenSSH || entries[0].Line != 2 {
+		t.Fatalf("entries = %+v, %v", entries, err)
+	}
+	if err := store.Callback(true, nil)("db:2222", nil, key); err != nil {
+		t.Fatalf("openssh key = %v", err)
+	}
+	if err := store.Revoke(entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Callback(false, nil)("db:2222", nil, key); !errors.Is(err, ErrRevoked) {
+		t.Fatalf("revoked key = %v", err)
+	}
+	if err := store.Revoke("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing = %v", err)
+	}
+}
+
+func TestAlgorithms(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(filepath.Join(dir, "managed"), filepath.Join(dir, "openssh"))
+	if algos := store.Algorithms("web:22"); algos != nil {
+		t.Fatalf("no files = %v", algos)
+	}
+
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ssh.NewPublicKey(&ec.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := ssh.NewPublicKey(&rs.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The OpenSSH entry is hashed, as with HashKnownHosts
+	line := knownhosts.Line([]string{knownhosts.HashHostname("web")}, ecKey) + "\n"
+	if err := os.WriteFile(store.openssh, []byte(line), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add("web:22", rsaKey); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA, ssh.KeyAlgoECDSA256}
+	if algos := store.Algorithms("web:22"); !reflect.DeepEqual(algos, want) {
+		t.Fatalf("algorithms = %v, want %v", algos, want)
+	}
+	if algos := store.Algorithms("web:2222"); algos != nil {
+		t.Fatalf("other port = %v", algos)
+	}
+}
@@ -0,0 +1,283 @@
+package hostkey
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/utils"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Sources of known_hosts entries
+const (
+	SourceChoraleia = "choraleia"
+	SourceOpenSSH   = "openssh"
+)
+
+// ErrNotFound is returned when revoking an entry that doesn't exist
+var ErrNotFound = errors.New("host key entry not found")
+
+// Entry is one key line of a known_hosts file
+type Entry struct {
+	ID          string   `json:"id"`
+	Hosts       []string `json:"hosts"`
+	KeyType     string   `json:"key_type"`
+	Fingerprint string   `json:"fingerprint"`
+	Marker      string   `json:"marker,omitempty"` // "revoked" or "cert-authority"
+	Comment     string   `json:"comment,omitempty"`
+	Source      string   `json:"source"`
+	Line        int      `json:"line"`
+}
+
+// Store reads host keys from the OpenSSH known_hosts file, which it never
+// writes, and keeps the keys trusted through Choraleia in its own file.
+// Managed keys are consulted first, so an accepted key replaces an older one.
+type Store struct {
+	mu      sync.Mutex
+	managed string
+	openssh string
+	logger  *slog.Logger
+}
+
+var (
+	defaultStore *Store
+	defaultOnce  sync.Once
+)
+
+// NewStore creates a store over a managed and an OpenSSH known_hosts file.
+// Either path may be empty.
+func NewStore(managed, openssh string) *Store {
+	return &Store{
+		managed: managed,
+		openssh: openssh,
+		logger:  utils.GetLogger(),
+	}
+}
+
+// Default returns the store over ~/.choraleia/known_hosts and ~/.ssh/known_hosts
+func Default() *Store {
+	defaultOnce.Do(func() {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		defaultStore = NewStore(
+			filepath.Join(home, ".choraleia", "known_hosts"),
+			filepath.Join(home, ".ssh", "known_hosts"),
+		)
+	})
+	return defaultStore
+}
+
+// check runs the known_hosts callback over both files
+func (s *Store) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var files []string
+	for _, path := range []string{s.managed, s.openssh} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		return &knownhosts.KeyError{}
+	}
+	callback, err := knownhosts.New(files...)
+	if err != nil {
+		return fmt.Errorf("load known hosts: %w", err)
+	}
+	// knownhosts only accepts TCP remotes; proxied connections may not be
+	if _, ok := remote.(*net.TCPAddr); !ok {
+		remote = &net.TCPAddr{IP: net.IPv4zero}
+	}
+	return callback(hostname, remote, key)
+}
+
+// List returns the entries of both files, managed ones first
+func (s *Store) List() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []Entry
+	for _, f := range []struct{ path, source string }{{s.managed, SourceChoraleia}, {s.openssh, SourceOpenSSH}} {
+		lines, err := readLines(f.path)
+		if err != nil {
+			return nil, err
+		}
+		for i, line := range lines {
+			if e, ok := parseEntry(line, f.source, i+1); ok {
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Add trusts a key for a host, replacing managed keys of the same type
+// recorded for that host before
+func (s *Store) Add(hostname string, key ssh.PublicKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.managed == "" {
+		return fmt.Errorf("no managed known_hosts file")
+	}
+	host := knownhosts.Normalize(hostname)
+	lines, err := readLines(s.managed)
+	if err != nil {
+		return err
+	}
+	kept := lines[:0]
+	for _, line := range lines {
+		marker, hosts, pub, _, _, err := ssh.ParseKnownHosts([]byte(line))
+		if err == nil && marker == "" && pub.Type() == key.Type() && containsHost(hosts, host) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	kept = append(kept, knownhosts.Line([]string{host}, key))
+	return s.writeManaged(kept)
+}
+
+// Revoke removes a managed entry. OpenSSH entries can't be edited, so their
+// key is marked @revoked in the managed file instead, refusing it for every host.
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lines, err := readLines(s.managed)
+	if err != nil {
+		return err
+	}
+	for i, line := range lines {
+		if e, ok := parseEntry(line, SourceChoraleia, i+1); ok && e.ID == id {
+			return s.writeManaged(append(lines[:i:i], lines[i+1:]...))
+		}
+	}
+
+	openssh, err := readLines(s.openssh)
+	if err != nil {
+		return err
+	}
+	for i, line := range openssh {
+		e, ok := parseEntry(line, SourceOpenSSH, i+1)
+		if !ok || e.ID != id {
+			continue
+		}
+		_, _, pub, _, _, _ := ssh.ParseKnownHosts([]byte(line))
+		revoked := "@revoked * " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+		return s.writeManaged(append(lines, revoked))
+	}
+	return ErrNotFound
+}
+
+// writeManaged replaces the managed file
+func (s *Store) writeManaged(lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(s.managed), 0o700); err != nil {
+		return fmt.Errorf("create known_hosts dir: %w", err)
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	tmp := s.managed + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("write known_hosts: %w", err)
+	}
+	if err := os.Rename(tmp, s.managed); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// Scan fetches the host key a server presents, like ssh-keyscan
+func Scan(ctx context.Context, addr string, timeout time.Duration) (ssh.PublicKey, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", addr, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	errScanned := errors.New("scanned")
+	var key ssh.PublicKey
+	config := &ssh.ClientConfig{
+		HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errScanned
+		},
+		Timeout: timeout,
+	}
+	_, _, _, err = ssh.NewClientConn(conn, addr, config)
+	if key == nil {
+		return nil, fmt.Errorf("ssh handshake with %s: %w", addr, err)
+	}
+	return key, nil
+}
+
+// parseEntry parses a known_hosts line, skipping blanks and comments
+func parseEntry(line, source string, lineNo int) (Entry, bool) {
+	marker, hosts, pub, comment, _, err := ssh.ParseKnownHosts([]byte(line))
+	if err != nil {
+		return Entry{}, false
+	}
+	sum := sha256.Sum256([]byte(source + "\n" + line))
+	return Entry{
+		ID:          hex.EncodeToString(sum[:8]),
+		Hosts:       hosts,
+		KeyType:     pub.Type(),
+		Fingerprint: ssh.FingerprintSHA256(pub),
+		Marker:      marker,
+		Comment:     comment,
+		Source:      source,
+		Line:        lineNo,
+	}, true
+}
+
+// readLines reads a file's lines; a missing file has none
+func readLines(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil, nil
+	}
+	return lines, nil
+}
+
+func containsHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is returned when a request is made on a closed client
+var ErrClosed = errors.New("mcp client closed")
+
+// toolsRefreshTimeout bounds the tools/list call made after a list_changed notification
+const toolsRefreshTimeout = 30 * time.Second
+
+// Transport carries JSON-RPC messages between the client and an MCP server
+type Transport interface {
+	// Start begins receiving messages, passing each one to handle
+	Start(ctx context.Context, handle func(msg []byte)) error
+	// Send delivers a single JSON-RPC message to the server
+	Send(ctx context.Context, msg []byte) error
+	// Close shuts the transport down
+	Close() error
+	// Done is closed once the transport stops receiving messages
+	Done() <-chan struct{}
+	// Err reports why the transport stopped (nil while running)
+	Err() error
+}
+
+// Client is an MCP client bound to a single server
+type Client struct {
+	transport  Transport
+	clientInfo Implementation
+
+	nextID  atomic.Int64
+	mu      sync.Mutex
+	pending map[string]chan *message
+
+	initResult *InitializeResult
+	tools      []Tool
+
+	onNotification func(method string, params json.RawMessage)
+}
+
+// NewClient creates a client that talks over the given transport
+func NewClient(transport Transport, clientInfo Implementation) *Client {
+	return &Client{
+		transport:  transport,
+		clientInfo: clientInfo,
+		pending:    make(map[string]chan *message),
+	}
+}
+
+// Connect starts the transport, performs the initialize handshake and loads the tool list
+func Connect(ctx context.Context, transport Transport, clientInfo Implementation) (*Client, error) {
+	c := NewClient(transport, clientInfo)
+	if err := c.Start(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := c.Initialize(ctx); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	if _, err := c.ListTools(ctx); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetNotificationHandler registers a callback for server notifications
+func (c *Client) SetNotificationHandler(fn func(method string, params json.RawMessage)) {
+	c.mu.Lock()
+	c.onNotification = fn
+	c.mu.Unlock()
+}
+
+// Start begins processing messages from the transport
+func (c *Client) Start(ctx context.Context) error {
+	if err := c.transport.Start(ctx, c.handleMessage); err != nil {
+		return fmt.Errorf("start transport: %w", err)
+	}
+	go func() {
+		<-c.transport.Done()
+		c.failPending()
+	}()
+	return nil
+}
+
+// Initialize performs the MCP initialize handshake
+func (c *Client) Initialize(ctx context.Context) (*InitializeResult, error) {
+	params := initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		ClientInfo:      c.clientInfo,
+	}
+	var result InitializeResult
+	if err := c.call(ctx, MethodInitialize, params, &result); err != nil {
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	if err := c.notify(ctx, NotifyInitialized, nil); err != nil {
+		return nil, fmt.Errorf("initialized notification: %w", err)
+	}
+
+	c.mu.Lock()
+	c.initResult = &result
+	c.mu.Unlock()
+	return &result, nil
+}
+
+// ServerInfo returns the result of the initialize handshake
+func (c *Client) ServerInfo() *InitializeResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.initResult
+}
+
+// ListTools fetches all tools from the server, following pagination cursors
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var all []Tool
+	cursor := ""
+	for {
+		var result listToolsResult
+		if err := c.call(ctx, MethodToolsList, listToolsParams{Cursor: cursor}, &result); err != nil {
+			return nil, fmt.Errorf("list tools: %w", err)
+		}
+		all = append(all, result.Tools...)
+		if result.NextCursor == "" || result.NextCursor == cursor {
+			break
+		}
+		cursor = result.NextCursor
+	}
+
+	c.mu.Lock()
+	c.tools = all
+	c.mu.Unlock()
+	return all, nil
+}
+
+// Tools returns the tool list from the last ListTools call
+func (c *Client) Tools() []Tool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Tool, len(c.tools))
+	copy(out, c.tools)
+	return out
+}
+
+// CallTool invokes a tool with JSON-encoded arguments
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error) {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	var result CallToolResult
+	if err := c.call(ctx, MethodToolsCall, callToolParams{Name: name, Arguments: arguments}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Ping checks that the server is responsive
+func (c *Client) Ping(ctx context.Context) error {
+	return c.call(ctx, MethodPing, nil, nil)
+}
+
+// Done is closed when the underlying transport stops
+func (c *Client) Done() <-chan struct{} {
+	return c.transport.Done()
+}
+
+// Err reports why the underlying transport stopped
+func (c *Client) Err() error {
+	return c.transport.Err()
+}
+
+// Close shuts down the client and its transport
+func (c *Client) Close() error {
+	err := c.transport.Close()
+	c.failPending()
+	return err
+}
+
+// call sends a request and waits for the matching response
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	id := strconv.FormatInt(c.nextID.Add(1), 10)
+	msg := message{JSONRPC: "2.0", ID: json.RawMessage(id), Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("encode params: %w", err)
+		}
+		msg.Params = raw
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan *message, 1)
+	c.mu.Lock()
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	select {
+	case <-c.transport.Done():
+		return c.closedErr()
+	default:
+	}
+
+	if err := c.transport.Send(ctx, data); err != nil {
+		return fmt.Errorf("send %s: %w", method, err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok || resp == nil {
+			return c.closedErr()
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("decode %s result: %w", method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notify sends a notification (no response expected)
+func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
+	msg := message{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = raw
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.transport.Send(ctx, data)
+}
+
+// handleMessage dispatches one incoming message (or batch)
+func (c *Client) handleMessage(raw []byte) {
+	// Batches are arrays of messages
+	if len(raw) > 0 && raw[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(raw, &batch); err == nil {
+			for _, item := range batch {
+				c.handleMessage(item)
+			}
+		}
+		return
+	}
+
+	var msg message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return
+	}
+
+	switch {
+	case msg.isResponse():
+		c.mu.Lock()
+		if ch, ok := c.pending[string(msg.ID)]; ok {
+			select {
+			case ch <- &msg:
+			default:
+			}
+		}
+		c.mu.Unlock()
+
+	case msg.isRequest():
+		go c.handleServerRequest(&msg)
+
+	case msg.Method != "":
+		if msg.Method == NotifyToolsListChanged {
+			go c.refreshTools()
+		}
+		c.mu.Lock()
+		fn := c.onNotification
+		c.mu.Unlock()
+		if fn != nil {
+			fn(msg.Method, msg.Params)
+		}
+	}
+}
+
+// handleServerRequest answers requests initiated by the server
+func (c *Client) handleServerRequest(req *message) {
+	resp := message{JSONRPC: "2.0", ID: req.ID}
+	switch req.Method {
+	case MethodPing:
+		resp.Result = json.RawMessage("{}")
+	default:
+		resp.Error = &RPCError{Code: ErrCodeMethodNotFound, Message: "method not supported: " + req.Method}
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	_ = c.transport.Send(context.Background(), data)
+}
+
+// refreshTools reloads the cached tool list after the server reports a change
+func (c *Client) refreshTools() {
+	ctx, cancel := context.WithTimeout(context.Background(), toolsRefreshTimeout)
+	defer cancel()
+	_, _ = c.ListTools(ctx)
+}
+
+// failPending unblocks all in-flight calls
+func (c *Client) failPending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
+
+func (c *Client) closedErr() error {
+	if err := c.transport.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrClosed, err)
+	}
+	return ErrClosed
+}
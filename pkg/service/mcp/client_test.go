@@ -0,0 +1,101 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+// fakeStdioServer answers MCP requests read from r and writes responses to w
+func fakeStdioServer(t *testing.T, r io.Reader, w io.Writer) {
+	t.Helper()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var req message
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			t.Errorf("server got invalid json: %v", err)
+			return
+		}
+		if len(req.ID) == 0 {
+			continue // notification
+		}
+		resp := message{JSONRPC: "2.0", ID: req.ID}
+		switch req.Method {
+		case MethodInitialize:
+			resp.Result = json.RawMessage(`{"protocolVersion":"2025-03-26","capabilities":{"tools":{}},"serverInfo":{"name":"fake","version":"1"}}`)
+		case MethodToolsList:
+			var p listToolsParams
+			_ = json.Unmarshal(req.Params, &p)
+			if p.Cursor == "" {
+				resp.Result = json.RawMessage(`{"tools":[{"name":"echo","inputSchema":{"type":"object"}}],"nextCursor":"2"}`)
+			} else {
+				resp.Result = json.RawMessage(`{"tools":[{"name":"fail"}]}`)
+			}
+		case MethodToolsCall:
+			var p callToolParams
+			_ = json.Unmarshal(req.Params, &p)
+			if p.Name == "echo" {
+				resp.Result, _ = json.Marshal(CallToolResult{Content: []Content{{Type: "text", Text: string(p.Arguments)}}})
+			} else {
+				resp.Error = &RPCError{Code: ErrCodeInvalidParams, Message: "boom"}
+			}
+		default:
+			resp.Error = &RPCError{Code: ErrCodeMethodNotFound, Message: "unknown"}
+		}
+		data, _ := json.Marshal(resp)
+		_, _ = w.Write(append(data, '\n'))
+	}
+}
+
+func TestClientOverStdio(t *testing.T) {
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	go fakeStdioServer(t, serverIn, serverOut)
+
+	transport := NewStdioTransport(clientIn, clientOut, func() error {
+		return serverOut.Close()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := Connect(ctx, transport, Implementation{Name: "test", Version: "0"})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer client.Close()
+
+	if got := client.ServerInfo().ServerInfo.Name; got != "fake" {
+		t.Errorf("server name = %q, want fake", got)
+	}
+
+	tools := client.Tools()
+	if len(tools) != 2 || tools[0].Name != "echo" || tools[1].Name != "fail" {
+		t.Fatalf("unexpected tools: %+v", tools)
+	}
+
+	result, err := client.CallTool(ctx, "echo", json.RawMessage(`{"x":1}`))
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if got := result.Text(); got != `{"x":1}` {
+		t.Errorf("result text = %q", got)
+	}
+
+	if _, err := client.CallTool(ctx, "fail", nil); err == nil {
+		t.Error("expected error from failing tool")
+	}
+
+	_ = client.Close()
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("client not done after Close")
+	}
+	if _, err := client.CallTool(ctx, "echo", nil); err == nil {
+		t.Error("expected error after Close")
+	}
+}
@@ -0,0 +1,394 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HeaderSessionID carries the session assigned by a streamable HTTP server
+const HeaderSessionID = "Mcp-Session-Id"
+
+// ErrSessionExpired is reported when the server no longer recognises our session
+var ErrSessionExpired = errors.New("mcp session expired")
+
+// retryBaseDelay is the first backoff step between HTTP retries
+const retryBaseDelay = 500 * time.Millisecond
+
+// HTTPOptions configures the HTTP-based transports
+type HTTPOptions struct {
+	// Headers are added to every request (auth, custom headers)
+	Headers http.Header
+	// Timeout bounds each POST request (0 = no limit)
+	Timeout time.Duration
+	// Retries is how many times a failed POST is retried. Messages the
+	// server may act on, such as tool calls, are only retried when the
+	// connection could not be made.
+	Retries int
+	// Client overrides the HTTP client (optional)
+	Client *http.Client
+}
+
+func (o HTTPOptions) httpClient() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	// No client-level timeout: event streams stay open indefinitely
+	return &http.Client{}
+}
+
+func (o HTTPOptions) applyHeaders(req *http.Request) {
+	for k, values := range o.Headers {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+}
+
+// post sends a JSON body, retrying on network errors and 5xx/429 responses,
+// see retrySafe. On success the caller must close the body and then call cancel.
+func (o HTTPOptions) post(ctx context.Context, client *http.Client, url string, body []byte, extra http.Header) (*http.Response, context.CancelFunc, error) {
+	safe := retrySafe(body)
+	var lastErr error
+	for attempt := 0; attempt <= o.Retries; attempt++ {
+		if attempt > 0 {
+			delay := retryBaseDelay << (attempt - 1)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+		}
+
+		reqCtx, cancel := ctx, context.CancelFunc(func() {})
+		if o.Timeout > 0 {
+			reqCtx, cancel = context.WithTimeout(ctx, o.Timeout)
+		}
+
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		o.applyHeaders(req)
+		for k, values := range extra {
+			for _, v := range values {
+				req.Header.Set(k, v)
+			}
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			cancel()
+			lastErr = err
+			if ctx.Err() != nil || (!safe && !isConnectError(err)) {
+				return nil, nil, err
+			}
+			continue
+		}
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			lastErr = httpStatusError(resp)
+			resp.Body.Close()
+			cancel()
+			if !safe {
+				return nil, nil, lastErr
+			}
+			continue
+		}
+		if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+			err := httpStatusError(resp)
+			resp.Body.Close()
+			cancel()
+			return nil, nil, err
+		}
+		return resp, cancel, nil
+	}
+	return nil, nil, lastErr
+}
+
+// retrySafe reports whether a message can be sent again after a failure the
+// server may have acted on: only the handshake, listing tools and ping,
+// which change nothing on the server
+func retrySafe(body []byte) bool {
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return false
+	}
+	switch msg.Method {
+	case MethodInitialize, MethodToolsList, MethodPing:
+		return true
+	}
+	return false
+}
+
+// isConnectError reports whether a request failed before reaching the server
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// httpStatusError builds an error from a non-success response, including a snippet of the body
+func httpStatusError(resp *http.Response) error {
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	msg := bytes.TrimSpace(snippet)
+	if len(msg) == 0 {
+		return fmt.Errorf("http %s", resp.Status)
+	}
+	return fmt.Errorf("http %s: %s", resp.Status, msg)
+}
+
+// StreamableHTTPTransport implements the streamable HTTP transport: every
+// message is POSTed to a single endpoint and responses come back either as a
+// JSON body or as an event stream. Server-initiated messages arrive on an
+// optional GET stream that is reopened with backoff when it drops.
+type StreamableHTTPTransport struct {
+	url    string
+	opts   HTTPOptions
+	client *http.Client
+	handle func(msg []byte)
+
+	sessionMu   sync.RWMutex
+	sessionID   string
+	lastEventID string
+	listenOnce  sync.Once
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	errMu     sync.Mutex
+	err       error
+	closeOnce sync.Once
+}
+
+// NewStreamableHTTPTransport creates a transport for the given MCP endpoint
+func NewStreamableHTTPTransport(endpoint string, opts HTTPOptions) *StreamableHTTPTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StreamableHTTPTransport{
+		url:    endpoint,
+		opts:   opts,
+		client: opts.httpClient(),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+// Start registers the message handler; the connection is made lazily on first Send
+func (t *StreamableHTTPTransport) Start(ctx context.Context, handle func(msg []byte)) error {
+	t.handle = handle
+	return nil
+}
+
+// SessionID returns the session assigned by the server (empty if stateless)
+func (t *StreamableHTTPTransport) SessionID() string {
+	t.sessionMu.RLock()
+	defer t.sessionMu.RUnlock()
+	return t.sessionID
+}
+
+// Send POSTs one message and dispatches whatever the server answers with
+func (t *StreamableHTTPTransport) Send(ctx context.Context, msg []byte) error {
+	select {
+	case <-t.done:
+		return ErrClosed
+	default:
+	}
+
+	// Tie the request to the transport lifetime so Close aborts in-flight streams
+	reqCtx, stopReq := context.WithCancel(t.ctx)
+	stop := context.AfterFunc(ctx, stopReq)
+
+	extra := http.Header{}
+	extra.Set("Accept", "application/json, text/event-stream")
+	sessionID := t.SessionID()
+	if sessionID != "" {
+		extra.Set(HeaderSessionID, sessionID)
+	}
+
+	resp, cancel, err := t.opts.post(reqCtx, t.client, t.url, msg, extra)
+	if err != nil {
+		stop()
+		stopReq()
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		cancel()
+		stop()
+		stopReq()
+		if sessionID != "" {
+			t.finish(ErrSessionExpired)
+			return ErrSessionExpired
+		}
+		return fmt.Errorf("http %s", resp.Status)
+	}
+
+	if id := resp.Header.Get(HeaderSessionID); id != "" {
+		t.sessionMu.Lock()
+		t.sessionID = id
+		t.sessionMu.Unlock()
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		// The response stream outlives the caller's context only as long as the
+		// transport does; the caller waits for its response via the client
+		stop()
+		go func() {
+			defer stopReq()
+			defer cancel()
+			defer resp.Body.Close()
+			_ = readSSE(resp.Body, func(ev sseEvent) bool {
+				if ev.Event == "" || ev.Event == "message" {
+					t.handle([]byte(ev.Data))
+				}
+				return true
+			})
+		}()
+	} else {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		cancel()
+		stop()
+		stopReq()
+		if err != nil {
+			return fmt.Errorf("read response: %w", err)
+		}
+		if len(bytes.TrimSpace(body)) > 0 && t.handle != nil {
+			t.handle(body)
+		}
+	}
+
+	// Once a session exists, open the server-to-client stream for notifications
+	if t.SessionID() != "" {
+		t.listenOnce.Do(func() { go t.listen() })
+	}
+	return nil
+}
+
+// listen keeps a GET event stream open for server-initiated messages
+func (t *StreamableHTTPTransport) listen() {
+	backoff := retryBaseDelay
+	for {
+		ok, retry := t.listenOnceStream()
+		if !retry {
+			return
+		}
+		if ok {
+			backoff = retryBaseDelay
+		}
+		select {
+		case <-time.After(backoff):
+		case <-t.ctx.Done():
+			return
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// listenOnceStream opens one GET stream. It reports whether the stream was
+// established and whether it is worth reopening afterwards.
+func (t *StreamableHTTPTransport) listenOnceStream() (bool, bool) {
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return false, false
+	}
+	t.opts.applyHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+	t.sessionMu.RLock()
+	if t.sessionID != "" {
+		req.Header.Set(HeaderSessionID, t.sessionID)
+	}
+	if t.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", t.lastEventID)
+	}
+	t.sessionMu.RUnlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return false, t.ctx.Err() == nil
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusMethodNotAllowed:
+		// Server doesn't offer a standalone stream
+		return false, false
+	case resp.StatusCode == http.StatusNotFound:
+		t.finish(ErrSessionExpired)
+		return false, false
+	case resp.StatusCode != http.StatusOK:
+		return false, true
+	}
+
+	_ = readSSE(resp.Body, func(ev sseEvent) bool {
+		if ev.ID != "" {
+			t.sessionMu.Lock()
+			t.lastEventID = ev.ID
+			t.sessionMu.Unlock()
+		}
+		if ev.Event == "" || ev.Event == "message" {
+			t.handle([]byte(ev.Data))
+		}
+		return true
+	})
+	return true, t.ctx.Err() == nil
+}
+
+// Close terminates the session (best effort) and stops all streams
+func (t *StreamableHTTPTransport) Close() error {
+	t.closeOnce.Do(func() {
+		if sessionID := t.SessionID(); sessionID != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+			if err == nil {
+				t.opts.applyHeaders(req)
+				req.Header.Set(HeaderSessionID, sessionID)
+				if resp, err := t.client.Do(req); err == nil {
+					resp.Body.Close()
+				}
+			}
+			cancel()
+		}
+		t.cancel()
+		t.finish(nil)
+	})
+	return nil
+}
+
+// Done is closed when the transport is closed or the session expires
+func (t *StreamableHTTPTransport) Done() <-chan struct{} {
+	return t.done
+}
+
+// Err reports why the transport stopped
+func (t *StreamableHTTPTransport) Err() error {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+	return t.err
+}
+
+func (t *StreamableHTTPTransport) finish(err error) {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+	select {
+	case <-t.done:
+		return
+	default:
+	}
+	t.err = err
+	close(t.done)
+	t.cancel()
+}
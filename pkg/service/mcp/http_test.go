@@ -0,0 +1,366 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubServer is a tiny MCP server shared by the HTTP transport tests.
+// Calling the "grow" tool adds a tool and emits tools/list_changed.
+type stubServer struct {
+	mu    sync.Mutex
+	tools []string
+	// notify receives server-initiated messages for the event stream
+	notify chan []byte
+}
+
+func newStubServer() *stubServer {
+	return &stubServer{
+		tools:  []string{"echo", "grow"},
+		notify: make(chan []byte, 16),
+	}
+}
+
+// respond answers one request; it returns nil for notifications
+func (s *stubServer) respond(req message) *message {
+	if len(req.ID) == 0 {
+		return nil
+	}
+	resp := &message{JSONRPC: "2.0", ID: req.ID}
+	switch req.Method {
+	case MethodInitialize:
+		resp.Result = json.RawMessage(`{"protocolVersion":"2025-03-26","capabilities":{"tools":{"listChanged":true}},"serverInfo":{"name":"stub","version":"1"}}`)
+	case MethodToolsList:
+		s.mu.Lock()
+		var tools []Tool
+		for _, name := range s.tools {
+			tools = append(tools, Tool{Name: name, InputSchema: json.RawMessage(`{"type":"object"}`)})
+		}
+		s.mu.Unlock()
+		resp.Result, _ = json.Marshal(listToolsResult{Tools: tools})
+	case MethodToolsCall:
+		var p callToolParams
+		_ = json.Unmarshal(req.Params, &p)
+		switch p.Name {
+		case "echo":
+			resp.Result, _ = json.Marshal(CallToolResult{Content: []Content{{Type: "text", Text: string(p.Arguments)}}})
+		case "grow":
+			s.mu.Lock()
+			s.tools = append(s.tools, fmt.Sprintf("tool%d", len(s.tools)))
+			s.mu.Unlock()
+			s.notify <- []byte(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`)
+			resp.Result = json.RawMessage(`{"content":[{"type":"text","text":"grown"}]}`)
+		default:
+			resp.Error = &RPCError{Code: ErrCodeInvalidParams, Message: "unknown tool"}
+		}
+	case MethodPing:
+		resp.Result = json.RawMessage(`{}`)
+	default:
+		resp.Error = &RPCError{Code: ErrCodeMethodNotFound, Message: "unknown"}
+	}
+	return resp
+}
+
+func writeSSE(w http.ResponseWriter, event string, data []byte) {
+	if event != "" {
+		fmt.Fprintf(w, "event: %s\n", event)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	w.(http.Flusher).Flush()
+}
+
+// waitForTools polls the client's cached tool list until it has n entries
+func waitForTools(t *testing.T, c *Client, n int) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(c.Tools()) == n {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("expected %d tools after list_changed, got %d", n, len(c.Tools()))
+}
+
+func TestClientOverSSE(t *testing.T) {
+	stub := newStubServer()
+	dropStream := make(chan struct{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "k" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeSSE(w, "endpoint", []byte("/messages?session=1"))
+		for {
+			select {
+			case msg := <-stub.notify:
+				writeSSE(w, "message", msg)
+			case <-dropStream:
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session") != "1" {
+			http.Error(w, "no session", http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req message
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		if resp := stub.respond(req); resp != nil {
+			data, _ := json.Marshal(resp)
+			stub.notify <- data
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Missing auth is rejected during connect
+	if _, err := Connect(ctx, NewSSETransport(server.URL+"/sse", HTTPOptions{}), Implementation{Name: "test"}); err == nil {
+		t.Fatal("expected unauthorized error")
+	}
+
+	opts := HTTPOptions{Headers: http.Header{"X-Api-Key": []string{"k"}}}
+	client, err := Connect(ctx, NewSSETransport(server.URL+"/sse", opts), Implementation{Name: "test"})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer client.Close()
+
+	if got := len(client.Tools()); got != 2 {
+		t.Fatalf("tools = %d, want 2", got)
+	}
+
+	result, err := client.CallTool(ctx, "echo", json.RawMessage(`{"a":"b"}`))
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if got := result.Text(); got != `{"a":"b"}` {
+		t.Errorf("echo = %q", got)
+	}
+
+	if _, err := client.CallTool(ctx, "grow", nil); err != nil {
+		t.Fatalf("CallTool grow: %v", err)
+	}
+	waitForTools(t, client, 3)
+
+	// Dropping the stream ends the transport with an error
+	close(dropStream)
+	select {
+	case <-client.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("client not done after stream dropped")
+	}
+	if client.Err() == nil {
+		t.Error("expected transport error after stream dropped")
+	}
+}
+
+func TestClientOverStreamableHTTP(t *testing.T) {
+	stub := newStubServer()
+
+	var mu sync.Mutex
+	session := "session-1"
+	failures := 1 // first POST fails with 503 to exercise retries
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		mu.Lock()
+		current := session
+		fail := failures > 0 && r.Method == http.MethodPost
+		if fail {
+			failures--
+		}
+		mu.Unlock()
+		if fail {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			if r.Header.Get(HeaderSessionID) != current {
+				http.Error(w, "unknown session", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case msg := <-stub.notify:
+					writeSSE(w, "", msg)
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusOK)
+
+		case http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			var req message
+			if err := json.Unmarshal(body, &req); err != nil {
+				http.Error(w, "bad json", http.StatusBadRequest)
+				return
+			}
+			if req.Method == MethodInitialize {
+				w.Header().Set(HeaderSessionID, current)
+			} else if r.Header.Get(HeaderSessionID) != current {
+				http.Error(w, "unknown session", http.StatusNotFound)
+				return
+			}
+
+			resp := stub.respond(req)
+			if resp == nil {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			data, _ := json.Marshal(resp)
+			if req.Method == MethodToolsCall {
+				// Answer tool calls as an event stream
+				w.Header().Set("Content-Type", "text/event-stream")
+				writeSSE(w, "message", data)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(data)
+		}
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := HTTPOptions{
+		Headers: http.Header{"Authorization": []string{"Bearer secret"}},
+		Timeout: 5 * time.Second,
+		Retries: 2,
+	}
+	transport := NewStreamableHTTPTransport(server.URL, opts)
+	client, err := Connect(ctx, transport, Implementation{Name: "test"})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer client.Close()
+
+	if got := transport.SessionID(); got != "session-1" {
+		t.Errorf("session = %q, want session-1", got)
+	}
+
+	result, err := client.CallTool(ctx, "echo", json.RawMessage(`{"n":1}`))
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if got := result.Text(); got != `{"n":1}` {
+		t.Errorf("echo = %q", got)
+	}
+
+	if _, err := client.CallTool(ctx, "grow", nil); err != nil {
+		t.Fatalf("CallTool grow: %v", err)
+	}
+	waitForTools(t, client, 3)
+
+	// Expiring the session on the server ends the client
+	mu.Lock()
+	session = "session-2"
+	mu.Unlock()
+	if err := client.Ping(ctx); err == nil {
+		t.Fatal("expected error after session expired")
+	}
+	select {
+	case <-client.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("client not done after session expired")
+	}
+	if !errors.Is(client.Err(), ErrSessionExpired) {
+		t.Errorf("Err = %v, want ErrSessionExpired", client.Err())
+	}
+}
+
+func TestPostRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		status   int // answered to the first request, 200 afterwards
+		dialFail bool
+		wantHits int
+		wantErr  bool
+	}{
+		{name: "list retried on 503", method: MethodToolsList, status: http.StatusServiceUnavailable, wantHits: 2},
+		{name: "call not retried on 503", method: MethodToolsCall, status: http.StatusServiceUnavailable, wantHits: 1, wantErr: true},
+		{name: "call retried on connect error", method: MethodToolsCall, status: http.StatusOK, dialFail: true, wantHits: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			hits := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				hits++
+				first := hits == 1
+				mu.Unlock()
+				if first && tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer server.Close()
+
+			dials := 0
+			dialer := &net.Dialer{}
+			client := &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					dials++
+					if tt.dialFail && dials == 1 {
+						return nil, &net.OpError{Op: "dial", Net: network, Err: errors.New("connection refused")}
+					}
+					return dialer.DialContext(ctx, network, addr)
+				},
+			}}
+
+			body, _ := json.Marshal(message{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: tt.method})
+			opts := HTTPOptions{Retries: 1}
+			resp, cancel, err := opts.post(context.Background(), client, server.URL, body, nil)
+			if err == nil {
+				resp.Body.Close()
+				cancel()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("post error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if hits != tt.wantHits {
+				t.Errorf("server hits = %d, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
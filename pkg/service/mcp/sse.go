@@ -0,0 +1,258 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// sseEvent is a single server-sent event
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// readSSE parses a text/event-stream body and calls fn for every event.
+// Reading stops when fn returns false or the stream ends.
+func readSSE(r io.Reader, fn func(ev sseEvent) bool) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var ev sseEvent
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "" && err == nil:
+			if len(data) > 0 {
+				ev.Data = strings.Join(data, "\n")
+				if !fn(ev) {
+					return nil
+				}
+			}
+			ev = sseEvent{}
+			data = data[:0]
+		case strings.HasPrefix(line, ":"):
+			// Comment / keepalive
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				ev.Event = value
+			case "data":
+				data = append(data, value)
+			case "id":
+				ev.ID = value
+			}
+		}
+
+		if err != nil {
+			// Flush a trailing event that wasn't terminated by a blank line
+			if len(data) > 0 {
+				ev.Data = strings.Join(data, "\n")
+				fn(ev)
+			}
+			return err
+		}
+	}
+}
+
+// SSETransport implements the HTTP+SSE transport: messages arrive on a
+// long-lived event stream and are sent by POSTing to the endpoint the server
+// announces in its first "endpoint" event
+type SSETransport struct {
+	url    string
+	opts   HTTPOptions
+	client *http.Client
+
+	endpointMu sync.RWMutex
+	endpoint   string
+
+	cancel    context.CancelFunc
+	done      chan struct{}
+	errMu     sync.Mutex
+	err       error
+	closeOnce sync.Once
+	startOnce sync.Once
+}
+
+// NewSSETransport creates an SSE transport for the given stream URL
+func NewSSETransport(streamURL string, opts HTTPOptions) *SSETransport {
+	return &SSETransport{
+		url:    streamURL,
+		opts:   opts,
+		client: opts.httpClient(),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start opens the event stream and waits for the server to announce its message endpoint
+func (t *SSETransport) Start(ctx context.Context, handle func(msg []byte)) error {
+	var err error
+	started := false
+	t.startOnce.Do(func() {
+		started = true
+		err = t.start(ctx, handle)
+	})
+	if !started {
+		return nil
+	}
+	return err
+}
+
+func (t *SSETransport) start(ctx context.Context, handle func(msg []byte)) error {
+	streamCtx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+
+	// Abort the connect if the caller gives up before the stream is established
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, t.url, nil)
+	if err != nil {
+		cancel()
+		return err
+	}
+	t.opts.applyHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("connect sse stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := httpStatusError(resp)
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("connect sse stream: %w", err)
+	}
+
+	endpointCh := make(chan struct{})
+	go t.readLoop(resp.Body, handle, endpointCh)
+
+	select {
+	case <-endpointCh:
+		return nil
+	case <-t.done:
+		if err := t.Err(); err != nil {
+			return err
+		}
+		return ErrClosed
+	case <-ctx.Done():
+		cancel()
+		return fmt.Errorf("waiting for sse endpoint: %w", ctx.Err())
+	}
+}
+
+func (t *SSETransport) readLoop(body io.ReadCloser, handle func(msg []byte), endpointCh chan struct{}) {
+	defer body.Close()
+	var endpointOnce sync.Once
+
+	err := readSSE(body, func(ev sseEvent) bool {
+		switch ev.Event {
+		case "endpoint":
+			endpoint, err := resolveEndpoint(t.url, ev.Data)
+			if err != nil {
+				return true
+			}
+			t.endpointMu.Lock()
+			t.endpoint = endpoint
+			t.endpointMu.Unlock()
+			endpointOnce.Do(func() { close(endpointCh) })
+		case "", "message":
+			handle([]byte(ev.Data))
+		}
+		return true
+	})
+
+	if err == nil || errors.Is(err, io.EOF) {
+		err = errors.New("sse stream closed by server")
+	}
+	t.finish(err)
+}
+
+// Send POSTs one message to the announced endpoint
+func (t *SSETransport) Send(ctx context.Context, msg []byte) error {
+	select {
+	case <-t.done:
+		return ErrClosed
+	default:
+	}
+
+	t.endpointMu.RLock()
+	endpoint := t.endpoint
+	t.endpointMu.RUnlock()
+	if endpoint == "" {
+		return errors.New("sse endpoint not yet announced")
+	}
+
+	resp, cancel, err := t.opts.post(ctx, t.client, endpoint, msg, nil)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return httpStatusError(resp)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Close tears down the event stream
+func (t *SSETransport) Close() error {
+	t.closeOnce.Do(func() {
+		if t.cancel != nil {
+			t.cancel()
+		}
+		t.finish(nil)
+	})
+	return nil
+}
+
+// Done is closed when the event stream ends
+func (t *SSETransport) Done() <-chan struct{} {
+	return t.done
+}
+
+// Err reports why the transport stopped
+func (t *SSETransport) Err() error {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+	return t.err
+}
+
+func (t *SSETransport) finish(err error) {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+	select {
+	case <-t.done:
+		return
+	default:
+	}
+	t.err = err
+	close(t.done)
+}
+
+// resolveEndpoint resolves the (usually relative) endpoint against the stream URL
+func resolveEndpoint(base, endpoint string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", err
+	}
+	return baseURL.ResolveReference(ref).String(), nil
+}
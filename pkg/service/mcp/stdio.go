@@ -0,0 +1,120 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// StdioTransport exchanges newline-delimited JSON-RPC messages over a
+// process's stdin/stdout pipes
+type StdioTransport struct {
+	stdout io.Reader
+	stdin  io.WriteCloser
+	// closer terminates the underlying process (optional)
+	closer func() error
+
+	writeMu   sync.Mutex
+	done      chan struct{}
+	errMu     sync.Mutex
+	err       error
+	closeOnce sync.Once
+	startOnce sync.Once
+}
+
+// NewStdioTransport creates a transport over the given pipes.
+// closer is invoked on Close to terminate the server process.
+func NewStdioTransport(stdout io.Reader, stdin io.WriteCloser, closer func() error) *StdioTransport {
+	return &StdioTransport{
+		stdout: stdout,
+		stdin:  stdin,
+		closer: closer,
+		done:   make(chan struct{}),
+	}
+}
+
+// Start begins reading messages from stdout
+func (t *StdioTransport) Start(ctx context.Context, handle func(msg []byte)) error {
+	t.startOnce.Do(func() {
+		go t.readLoop(handle)
+	})
+	return nil
+}
+
+func (t *StdioTransport) readLoop(handle func(msg []byte)) {
+	reader := bufio.NewReaderSize(t.stdout, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			handle(line)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("server process closed stdout")
+			}
+			t.finish(err)
+			return
+		}
+	}
+}
+
+// Send writes one message followed by a newline
+func (t *StdioTransport) Send(ctx context.Context, msg []byte) error {
+	select {
+	case <-t.done:
+		return ErrClosed
+	default:
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	buf := make([]byte, 0, len(msg)+1)
+	buf = append(buf, msg...)
+	buf = append(buf, '\n')
+	_, err := t.stdin.Write(buf)
+	return err
+}
+
+// Close closes stdin and terminates the process
+func (t *StdioTransport) Close() error {
+	var err error
+	t.closeOnce.Do(func() {
+		err = t.stdin.Close()
+		if t.closer != nil {
+			if cerr := t.closer(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+		t.finish(nil)
+	})
+	return err
+}
+
+// Done is closed when the read loop ends
+func (t *StdioTransport) Done() <-chan struct{} {
+	return t.done
+}
+
+// Err reports why the transport stopped
+func (t *StdioTransport) Err() error {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+	return t.err
+}
+
+func (t *StdioTransport) finish(err error) {
+	t.errMu.Lock()
+	select {
+	case <-t.done:
+		t.errMu.Unlock()
+		return
+	default:
+	}
+	t.err = err
+	close(t.done)
+	t.errMu.Unlock()
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/secrets"
 	"github.com/choraleia/choraleia/pkg/utils"
 	arkEmbed "github.com/cloudwego/eino-ext/components/embedding/ark"
 	dashscopeEmbed "github.com/cloudwego/eino-ext/components/embedding/dashscope"
@@ -38,9 +39,16 @@ type ModelService struct {
 }
 
 func NewModelService() *ModelService {
-	return &ModelService{
+	m := &ModelService{
 		logger: utils.GetLogger(),
 	}
+	// Move plaintext API keys of earlier versions into the vault
+	secrets.Default().WhenUnlocked(func() {
+		if err := models.MigrateModelSecrets(); err != nil {
+			m.logger.Error("Failed to move model API keys into the vault", "error", err)
+		}
+	})
+	return m
 }
 
 // GetModelList fetch model list
@@ -62,6 +70,7 @@ func (m *ModelService) GetModelList(c *gin.Context) {
 	for _, mm := range modelsList {
 		mm.Normalize()
 		mm.ApiKey = utils.MaskSensitiveString(mm.ApiKey)
+		mm.Extra = utils.RedactMap(mm.Extra)
 
 		// Apply domain filter
 		if domainFilter != "" {
@@ -162,6 +171,8 @@ func (m *ModelService) EditModel(c *gin.Context) {
 					return
 				}
 			}
+			// Secrets the list returned redacted keep their values
+			utils.RestoreRedacted(req.Extra, mm.Extra)
 			currentModels[i] = &req
 			currentModels[i].ID = id // keep ID unchanged
 			found = true
@@ -218,6 +229,13 @@ func (m *ModelService) TestModelConnection(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Provider required"})
 		return
 	}
+	// Saved API keys are offered to the form as references
+	apiKey, err := secrets.Resolve(req.ApiKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Failed to read API key: " + err.Error()})
+		return
+	}
+	req.ApiKey = apiKey
 
 	// Check task types to determine test method
 	hasChat := false
@@ -421,6 +439,9 @@ func (m *ModelService) CreateChatModel(ctx context.Context, config *models.Model
 	if config == nil {
 		return nil, fmt.Errorf("model config is nil")
 	}
+	if err := config.CheckSecrets(); err != nil {
+		return nil, err
+	}
 
 	switch config.Provider {
 	case "openai", "custom":
@@ -568,7 +589,9 @@ func (m *ModelService) GetProviderApiKeys(c *gin.Context) {
 		return
 	}
 
-	currentModels, err := models.LoadModels()
+	// The stored list holds the keys' vault references, which AddModel
+	// accepts in place of the key
+	currentModels, err := models.LoadSealedModels()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to read model list"})
 		return
@@ -596,9 +619,18 @@ func (m *ModelService) GetProviderApiKeys(c *gin.Context) {
 	}
 
 	var apiKeys []KeyInfo
-	for key := range apiKeySet {
+	for ref := range apiKeySet {
+		// Keys not moved into the vault yet are left out
+		if !secrets.IsRef(ref) {
+			continue
+		}
+		key, err := secrets.Resolve(ref)
+		if err != nil {
+			m.logger.Warn("Failed to read API key", "provider", provider, "error", err)
+			continue
+		}
 		apiKeys = append(apiKeys, KeyInfo{
-			Value:   key,
+			Value:   ref,
 			Display: utils.MaskSensitiveString(key),
 		})
 	}
@@ -636,6 +668,9 @@ func (m *ModelService) CreateEmbedder(ctx context.Context, config *models.ModelC
 	if !hasEmbedding {
 		return nil, fmt.Errorf("model %s does not support text_embedding task type", config.Name)
 	}
+	if err := config.CheckSecrets(); err != nil {
+		return nil, err
+	}
 
 	// Get dimension if provided
 	var dim *int
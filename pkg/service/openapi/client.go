@@ -0,0 +1,233 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultMaxResponseBytes caps how much of a response body is returned to the model
+const DefaultMaxResponseBytes = 64 * 1024
+
+// Client invokes operations against a base URL
+type Client struct {
+	BaseURL string
+	// Headers are sent with every request (auth, custom headers)
+	Headers http.Header
+	// HTTPClient overrides the default client (optional)
+	HTTPClient *http.Client
+	// MaxResponseBytes limits the returned body (0 = DefaultMaxResponseBytes)
+	MaxResponseBytes int64
+}
+
+// Response is the outcome of an operation call
+type Response struct {
+	StatusCode  int
+	Status      string
+	ContentType string
+	Body        []byte
+	Truncated   bool
+}
+
+// Call builds and sends the request for op using tool arguments
+func (c *Client) Call(ctx context.Context, op *Operation, args map[string]interface{}) (*Response, error) {
+	req, err := c.BuildRequest(ctx, op, args)
+	if err != nil {
+		return nil, err
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	limit := c.MaxResponseBytes
+	if limit <= 0 {
+		limit = DefaultMaxResponseBytes
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	out := &Response{
+		StatusCode:  resp.StatusCode,
+		Status:      resp.Status,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}
+	if int64(len(body)) > limit {
+		out.Body = body[:limit]
+		out.Truncated = true
+	}
+	return out, nil
+}
+
+// BuildRequest maps arguments onto path, query, header, cookie and body
+func (c *Client) BuildRequest(ctx context.Context, op *Operation, args map[string]interface{}) (*http.Request, error) {
+	if c.BaseURL == "" {
+		return nil, fmt.Errorf("no base URL configured")
+	}
+
+	path := op.Path
+	query := url.Values{}
+	headers := http.Header{}
+	var cookies []*http.Cookie
+
+	for _, p := range op.Parameters {
+		value, ok := args[p.Arg]
+		if !ok || value == nil {
+			if p.Required {
+				return nil, fmt.Errorf("missing required parameter %q", p.Arg)
+			}
+			continue
+		}
+
+		switch p.In {
+		case InPath:
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(formatValue(value)))
+		case InQuery:
+			if list, ok := value.([]interface{}); ok {
+				for _, item := range list {
+					query.Add(p.Name, formatValue(item))
+				}
+			} else {
+				query.Set(p.Name, formatValue(value))
+			}
+		case InHeader:
+			headers.Set(p.Name, formatValue(value))
+		case InCookie:
+			cookies = append(cookies, &http.Cookie{Name: p.Name, Value: formatValue(value)})
+		}
+	}
+
+	target := strings.TrimRight(c.BaseURL, "/") + "/" + strings.TrimLeft(path, "/")
+	if len(query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + query.Encode()
+	}
+
+	var body io.Reader
+	contentType := ""
+	if op.Body != nil {
+		value, ok := args[BodyArgument]
+		if ok && value != nil {
+			data, ct, err := encodeBody(op.Body.ContentType, value)
+			if err != nil {
+				return nil, err
+			}
+			body = bytes.NewReader(data)
+			contentType = ct
+		} else if op.Body.Required {
+			return nil, fmt.Errorf("missing required request body %q", BodyArgument)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, op.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, values := range c.Headers {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	for k, values := range headers {
+		req.Header[k] = values
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json, */*;q=0.8")
+	}
+	return req, nil
+}
+
+// encodeBody serializes the body argument for the declared media type
+func encodeBody(contentType string, value interface{}) ([]byte, string, error) {
+	switch {
+	case contentType == "application/x-www-form-urlencoded":
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("form body must be an object")
+		}
+		form := url.Values{}
+		for k, v := range fields {
+			form.Set(k, formatValue(v))
+		}
+		return []byte(form.Encode()), contentType, nil
+
+	case contentType == "multipart/form-data":
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("multipart body must be an object")
+		}
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		for k, v := range fields {
+			if err := w.WriteField(k, formatValue(v)); err != nil {
+				return nil, "", err
+			}
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), w.FormDataContentType(), nil
+
+	case strings.HasPrefix(contentType, "text/"):
+		return []byte(formatValue(value)), contentType, nil
+
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, "", fmt.Errorf("encode body: %w", err)
+		}
+		return data, contentType, nil
+	}
+}
+
+// formatValue renders a scalar argument for use in a URL or header
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		// JSON numbers decode as float64; keep integers free of exponents
+		if val == float64(int64(val)) {
+			return fmt.Sprintf("%d", int64(val))
+		}
+		return fmt.Sprintf("%v", val)
+	case bool, int, int64:
+		return fmt.Sprintf("%v", val)
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = formatValue(item)
+		}
+		return strings.Join(parts, ",")
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,469 @@
+// Package openapi parses OpenAPI 3.x and Swagger 2.0 documents into a flat
+// list of operations that can be exposed as agent tools and invoked over HTTP.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// httpMethods lists the path item keys that describe operations, in a stable order
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Parameter locations
+const (
+	InPath     = "path"
+	InQuery    = "query"
+	InHeader   = "header"
+	InCookie   = "cookie"
+	inBody     = "body"     // Swagger 2 only
+	inFormData = "formData" // Swagger 2 only
+)
+
+// BodyArgument is the input property that carries the request body
+const BodyArgument = "body"
+
+// Document is a parsed API description
+type Document struct {
+	// Version is the "openapi" or "swagger" field of the document
+	Version     string
+	Title       string
+	Description string
+	// ServerURL is the first declared server (OpenAPI 3) or scheme+host+basePath (Swagger 2)
+	ServerURL  string
+	Operations []*Operation
+
+	root map[string]interface{}
+}
+
+// Operation is a single HTTP operation
+type Operation struct {
+	ID          string
+	Method      string // upper case
+	Path        string
+	Summary     string
+	Description string
+	Deprecated  bool
+	Parameters  []*Parameter
+	Body        *RequestBody
+}
+
+// Parameter is a non-body operation parameter
+type Parameter struct {
+	Name        string
+	In          string
+	Required    bool
+	Description string
+	Schema      map[string]interface{}
+	// Arg is the input property name (differs from Name when names collide across locations)
+	Arg string
+}
+
+// RequestBody describes the operation payload
+type RequestBody struct {
+	ContentType string
+	Required    bool
+	Description string
+	Schema      map[string]interface{}
+}
+
+// Parse decodes a JSON or YAML document
+func Parse(data []byte) (*Document, error) {
+	root, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{root: root}
+	info, _ := root["info"].(map[string]interface{})
+	doc.Title = stringField(info, "title")
+	doc.Description = stringField(info, "description")
+
+	switch {
+	case stringField(root, "openapi") != "":
+		doc.Version = stringField(root, "openapi")
+		if !strings.HasPrefix(doc.Version, "3.") {
+			return nil, fmt.Errorf("unsupported openapi version %q", doc.Version)
+		}
+		doc.ServerURL = openAPI3Server(root)
+	case stringField(root, "swagger") != "":
+		doc.Version = stringField(root, "swagger")
+		if doc.Version != "2.0" {
+			return nil, fmt.Errorf("unsupported swagger version %q", doc.Version)
+		}
+		doc.ServerURL = swagger2Server(root)
+	default:
+		return nil, errors.New("document is neither OpenAPI 3 nor Swagger 2")
+	}
+
+	if err := doc.collectOperations(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// IsSwagger2 reports whether the document uses the Swagger 2.0 format
+func (d *Document) IsSwagger2() bool {
+	return d.Version == "2.0"
+}
+
+// decode accepts JSON or YAML and returns a JSON-compatible tree
+func decode(data []byte) (map[string]interface{}, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("empty document")
+	}
+
+	var root map[string]interface{}
+	if trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &root); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+		return root, nil
+	}
+
+	var raw interface{}
+	if err := yaml.Unmarshal(trimmed, &raw); err != nil {
+		return nil, fmt.Errorf("invalid yaml: %w", err)
+	}
+	root, ok := normalizeYAML(raw).(map[string]interface{})
+	if !ok {
+		return nil, errors.New("document root must be an object")
+	}
+	return root, nil
+}
+
+// normalizeYAML converts YAML maps with non-string keys (e.g. response codes) into JSON-style maps
+func normalizeYAML(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalizeYAML(item)
+		}
+		return val
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return out
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeYAML(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+var serverVarPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+func openAPI3Server(root map[string]interface{}) string {
+	servers, _ := root["servers"].([]interface{})
+	if len(servers) == 0 {
+		return ""
+	}
+	server, _ := servers[0].(map[string]interface{})
+	serverURL := stringField(server, "url")
+	vars, _ := server["variables"].(map[string]interface{})
+	return serverVarPattern.ReplaceAllStringFunc(serverURL, func(m string) string {
+		name := m[1 : len(m)-1]
+		if v, ok := vars[name].(map[string]interface{}); ok {
+			if def := stringField(v, "default"); def != "" {
+				return def
+			}
+		}
+		return m
+	})
+}
+
+func swagger2Server(root map[string]interface{}) string {
+	host := stringField(root, "host")
+	basePath := stringField(root, "basePath")
+	if host == "" {
+		return basePath
+	}
+	scheme := "https"
+	if schemes, ok := root["schemes"].([]interface{}); ok && len(schemes) > 0 {
+		if s, ok := schemes[0].(string); ok {
+			scheme = s
+		}
+	}
+	return scheme + "://" + host + basePath
+}
+
+func (d *Document) collectOperations() error {
+	paths, _ := d.root["paths"].(map[string]interface{})
+	if len(paths) == 0 {
+		return errors.New("document has no paths")
+	}
+
+	pathKeys := make([]string, 0, len(paths))
+	for p := range paths {
+		pathKeys = append(pathKeys, p)
+	}
+	sort.Strings(pathKeys)
+
+	usedIDs := make(map[string]int)
+	for _, path := range pathKeys {
+		item, ok := d.resolve(paths[path]).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		shared := d.rawParameters(item["parameters"])
+
+		for _, method := range httpMethods {
+			raw, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			op, err := d.buildOperation(method, path, raw, shared)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			// Operation IDs must be unique for tool names
+			if n := usedIDs[op.ID]; n > 0 {
+				usedIDs[op.ID] = n + 1
+				op.ID = fmt.Sprintf("%s_%d", op.ID, n+1)
+			} else {
+				usedIDs[op.ID] = 1
+			}
+			d.Operations = append(d.Operations, op)
+		}
+	}
+	return nil
+}
+
+func (d *Document) buildOperation(method, path string, raw map[string]interface{}, shared []map[string]interface{}) (*Operation, error) {
+	op := &Operation{
+		ID:          stringField(raw, "operationId"),
+		Method:      strings.ToUpper(method),
+		Path:        path,
+		Summary:     stringField(raw, "summary"),
+		Description: stringField(raw, "description"),
+	}
+	op.Deprecated, _ = raw["deprecated"].(bool)
+	if op.ID == "" {
+		op.ID = defaultOperationID(method, path)
+	}
+
+	// Operation-level parameters override path-level ones with the same name+location
+	params := make([]map[string]interface{}, 0, len(shared))
+	index := make(map[string]int)
+	for _, p := range append(shared, d.rawParameters(raw["parameters"])...) {
+		key := stringField(p, "in") + ":" + stringField(p, "name")
+		if i, ok := index[key]; ok {
+			params[i] = p
+			continue
+		}
+		index[key] = len(params)
+		params = append(params, p)
+	}
+
+	var formFields []map[string]interface{}
+	for _, p := range params {
+		switch stringField(p, "in") {
+		case inBody:
+			op.Body = &RequestBody{
+				ContentType: d.swaggerConsumes(raw),
+				Description: stringField(p, "description"),
+				Schema:      d.expandSchema(p["schema"]),
+			}
+			op.Body.Required, _ = p["required"].(bool)
+		case inFormData:
+			formFields = append(formFields, p)
+		case InPath, InQuery, InHeader, InCookie:
+			param := &Parameter{
+				Name:        stringField(p, "name"),
+				In:          stringField(p, "in"),
+				Description: stringField(p, "description"),
+			}
+			param.Required, _ = p["required"].(bool)
+			if param.In == InPath {
+				param.Required = true
+			}
+			if s, ok := p["schema"]; ok {
+				param.Schema = d.expandSchema(s)
+			} else {
+				param.Schema = swaggerParamSchema(p)
+			}
+			op.Parameters = append(op.Parameters, param)
+		}
+	}
+
+	if len(formFields) > 0 && op.Body == nil {
+		op.Body = d.formBody(raw, formFields)
+	}
+	if rb, ok := d.resolve(raw["requestBody"]).(map[string]interface{}); ok {
+		op.Body = d.openAPI3Body(rb)
+	}
+
+	assignArgNames(op)
+	return op, nil
+}
+
+// rawParameters resolves a parameters array into parameter objects
+func (d *Document) rawParameters(v interface{}) []map[string]interface{} {
+	list, _ := v.([]interface{})
+	out := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if p, ok := d.resolve(item).(map[string]interface{}); ok {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// openAPI3Body picks the most useful media type from an OpenAPI 3 request body
+func (d *Document) openAPI3Body(rb map[string]interface{}) *RequestBody {
+	content, _ := rb["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return nil
+	}
+
+	contentType := ""
+	for _, preferred := range []string{"application/json", "application/x-www-form-urlencoded", "text/plain"} {
+		if _, ok := content[preferred]; ok {
+			contentType = preferred
+			break
+		}
+	}
+	if contentType == "" {
+		// Fall back to any JSON-like media type, then to the first one alphabetically
+		keys := make([]string, 0, len(content))
+		for k := range content {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		contentType = keys[0]
+		for _, k := range keys {
+			if strings.Contains(k, "json") {
+				contentType = k
+				break
+			}
+		}
+	}
+
+	media, _ := content[contentType].(map[string]interface{})
+	body := &RequestBody{
+		ContentType: contentType,
+		Description: stringField(rb, "description"),
+		Schema:      d.expandSchema(media["schema"]),
+	}
+	body.Required, _ = rb["required"].(bool)
+	return body
+}
+
+// formBody turns Swagger 2 formData parameters into an object body
+func (d *Document) formBody(raw map[string]interface{}, fields []map[string]interface{}) *RequestBody {
+	props := make(map[string]interface{}, len(fields))
+	var required []interface{}
+	for _, f := range fields {
+		name := stringField(f, "name")
+		props[name] = swaggerParamSchema(f)
+		if r, _ := f["required"].(bool); r {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	contentType := "application/x-www-form-urlencoded"
+	if ct := d.swaggerConsumes(raw); ct == "multipart/form-data" {
+		contentType = ct
+	}
+	return &RequestBody{ContentType: contentType, Required: len(required) > 0, Schema: schema}
+}
+
+// swaggerConsumes returns the request content type of a Swagger 2 operation
+func (d *Document) swaggerConsumes(raw map[string]interface{}) string {
+	for _, src := range []interface{}{raw["consumes"], d.root["consumes"]} {
+		list, _ := src.([]interface{})
+		for _, item := range list {
+			if s, ok := item.(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+	return "application/json"
+}
+
+// swaggerParamSchema builds a JSON schema from a Swagger 2 non-body parameter
+func swaggerParamSchema(p map[string]interface{}) map[string]interface{} {
+	schema := make(map[string]interface{})
+	for _, key := range []string{"type", "format", "items", "enum", "default", "minimum", "maximum", "pattern"} {
+		if v, ok := p[key]; ok {
+			schema[key] = v
+		}
+	}
+	if schema["type"] == "file" {
+		schema["type"] = "string"
+	}
+	if desc := stringField(p, "description"); desc != "" {
+		schema["description"] = desc
+	}
+	return schema
+}
+
+// assignArgNames picks input property names, prefixing locations when names collide
+func assignArgNames(op *Operation) {
+	counts := make(map[string]int)
+	for _, p := range op.Parameters {
+		counts[p.Name]++
+	}
+	if op.Body != nil {
+		counts[BodyArgument]++
+	}
+	for _, p := range op.Parameters {
+		p.Arg = p.Name
+		if counts[p.Name] > 1 {
+			p.Arg = p.In + "_" + p.Name
+		}
+	}
+}
+
+var nonIdentChars = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// defaultOperationID derives an ID like get_pets_petId from method and path
+func defaultOperationID(method, path string) string {
+	id := strings.Trim(nonIdentChars.ReplaceAllString(path, "_"), "_")
+	if id == "" {
+		return method
+	}
+	return method + "_" + id
+}
+
+// ResolveServerURL joins a possibly relative server URL onto the spec location
+func ResolveServerURL(specURL, serverURL string) string {
+	if serverURL == "" || specURL == "" {
+		return serverURL
+	}
+	base, err := url.Parse(specURL)
+	if err != nil {
+		return serverURL
+	}
+	ref, err := url.Parse(serverURL)
+	if err != nil || ref.IsAbs() {
+		return serverURL
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	if m == nil {
+		return ""
+	}
+	s, _ := m[key].(string)
+	return s
+}
@@ -0,0 +1,198 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const petstoreV3 = `
+openapi: 3.0.3
+info:
+  title: Petstore
+servers:
+  - url: https://{env}.example.com/v1
+    variables:
+      env:
+        default: api
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        schema: {type: integer}
+    get:
+      operationId: getPet
+      summary: Get a pet
+      parameters:
+        - name: verbose
+          in: query
+          schema: {type: boolean}
+      responses:
+        200:
+          description: ok
+    put:
+      summary: Update a pet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        200:
+          description: ok
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        parent:
+          $ref: '#/components/schemas/Pet'
+`
+
+const petstoreV2 = `{
+  "swagger": "2.0",
+  "info": {"title": "Legacy"},
+  "host": "legacy.example.com",
+  "basePath": "/api",
+  "schemes": ["http"],
+  "paths": {
+    "/orders": {
+      "post": {
+        "operationId": "createOrder",
+        "parameters": [
+          {"name": "X-Trace", "in": "header", "type": "string"},
+          {"name": "body", "in": "body", "required": true, "schema": {"$ref": "#/definitions/Order"}}
+        ]
+      }
+    },
+    "/login": {
+      "post": {
+        "consumes": ["application/x-www-form-urlencoded"],
+        "parameters": [
+          {"name": "user", "in": "formData", "type": "string", "required": true},
+          {"name": "tags", "in": "query", "type": "array", "items": {"type": "string"}}
+        ]
+      }
+    }
+  },
+  "definitions": {
+    "Order": {"type": "object", "properties": {"qty": {"type": "integer"}}}
+  }
+}`
+
+func findOp(t *testing.T, doc *Document, id string) *Operation {
+	t.Helper()
+	for _, op := range doc.Operations {
+		if op.ID == id {
+			return op
+		}
+	}
+	t.Fatalf("operation %q not found", id)
+	return nil
+}
+
+func TestParseOpenAPI3(t *testing.T) {
+	doc, err := Parse([]byte(petstoreV3))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if doc.ServerURL != "https://api.example.com/v1" {
+		t.Errorf("ServerURL = %q", doc.ServerURL)
+	}
+	if len(doc.Operations) != 2 {
+		t.Fatalf("operations = %d, want 2", len(doc.Operations))
+	}
+
+	get := findOp(t, doc, "getPet")
+	if len(get.Parameters) != 2 || !get.Parameters[0].Required {
+		t.Errorf("getPet parameters = %+v", get.Parameters)
+	}
+
+	put := findOp(t, doc, "put_pets_petId")
+	if put.Body == nil || put.Body.ContentType != "application/json" || !put.Body.Required {
+		t.Fatalf("put body = %+v", put.Body)
+	}
+	// The recursive parent reference must be cut off rather than looping
+	props := put.Body.Schema["properties"].(map[string]interface{})
+	if parent := props["parent"].(map[string]interface{}); parent["type"] != "object" {
+		t.Errorf("parent schema = %v", parent)
+	}
+
+	schema := put.InputSchema()
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("marshal schema: %v", err)
+	}
+	if !strings.Contains(string(data), `"required":["petId","body"]`) {
+		t.Errorf("input schema = %s", data)
+	}
+}
+
+func TestParseSwagger2AndCall(t *testing.T) {
+	doc, err := Parse([]byte(petstoreV2))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if doc.ServerURL != "http://legacy.example.com/api" {
+		t.Errorf("ServerURL = %q", doc.ServerURL)
+	}
+
+	var got struct {
+		method, path, query, trace, auth, contentType, body string
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got.method, got.path, got.query = r.Method, r.URL.Path, r.URL.RawQuery
+		got.trace, got.auth = r.Header.Get("X-Trace"), r.Header.Get("Authorization")
+		got.contentType, got.body = r.Header.Get("Content-Type"), string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	client := &Client{
+		BaseURL: server.URL + "/api",
+		Headers: http.Header{"Authorization": []string{"Bearer t"}},
+	}
+
+	create := findOp(t, doc, "createOrder")
+	resp, err := client.Call(context.Background(), create, map[string]interface{}{
+		"X-Trace": "abc",
+		"body":    map[string]interface{}{"qty": float64(3)},
+	})
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if resp.StatusCode != 200 || string(resp.Body) != `{"ok":true}` {
+		t.Errorf("response = %d %s", resp.StatusCode, resp.Body)
+	}
+	if got.method != "POST" || got.path != "/api/orders" || got.trace != "abc" || got.auth != "Bearer t" {
+		t.Errorf("request = %+v", got)
+	}
+	if got.body != `{"qty":3}` || got.contentType != "application/json" {
+		t.Errorf("body = %s (%s)", got.body, got.contentType)
+	}
+
+	login := findOp(t, doc, "post_login")
+	if _, err := client.Call(context.Background(), login, map[string]interface{}{
+		"tags": []interface{}{"a", "b"},
+		"body": map[string]interface{}{"user": "bob"},
+	}); err != nil {
+		t.Fatalf("Call login: %v", err)
+	}
+	if got.query != "tags=a&tags=b" || got.body != "user=bob" || got.contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("login request = %+v", got)
+	}
+
+	if _, err := client.Call(context.Background(), create, map[string]interface{}{}); err == nil {
+		t.Error("expected error for missing required body")
+	}
+}
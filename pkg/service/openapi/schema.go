@@ -0,0 +1,158 @@
+package openapi
+
+import (
+	"strings"
+)
+
+// maxSchemaDepth bounds schema expansion so huge or recursive models stay small
+const maxSchemaDepth = 12
+
+// resolve follows a local $ref ("#/components/..." or "#/definitions/...") once
+func (d *Document) resolve(v interface{}) interface{} {
+	for i := 0; i < 16; i++ {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return v
+		}
+		target := d.lookup(ref)
+		if target == nil {
+			return v
+		}
+		v = target
+	}
+	return v
+}
+
+// lookup finds the node addressed by a local JSON pointer
+func (d *Document) lookup(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		// External references are not supported
+		return nil
+	}
+	var node interface{} = d.root
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node, ok = m[part]
+		if !ok {
+			return nil
+		}
+	}
+	return node
+}
+
+// expandSchema returns a copy of a schema with all local references inlined.
+// Recursive references collapse into a plain object schema.
+func (d *Document) expandSchema(v interface{}) map[string]interface{} {
+	out, _ := d.expand(v, map[string]bool{}, 0).(map[string]interface{})
+	if out == nil {
+		return map[string]interface{}{}
+	}
+	return out
+}
+
+func (d *Document) expand(v interface{}, visiting map[string]bool, depth int) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := val["$ref"].(string); ok {
+			if visiting[ref] || depth > maxSchemaDepth {
+				return map[string]interface{}{"type": "object"}
+			}
+			target := d.lookup(ref)
+			if target == nil {
+				return map[string]interface{}{}
+			}
+			visiting[ref] = true
+			out := d.expand(target, visiting, depth+1)
+			delete(visiting, ref)
+			return out
+		}
+		if depth > maxSchemaDepth {
+			return map[string]interface{}{"type": "object"}
+		}
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			switch k {
+			case "example", "examples", "xml", "externalDocs":
+				// Not useful to the model and can be large
+				continue
+			case "nullable":
+				// OpenAPI 3.0 extension, not part of JSON schema
+				continue
+			}
+			out[k] = d.expand(item, visiting, depth+1)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = d.expand(item, visiting, depth+1)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// InputSchema builds the JSON schema for the tool arguments of an operation:
+// one property per parameter plus "body" for the request payload
+func (op *Operation) InputSchema() map[string]interface{} {
+	props := make(map[string]interface{})
+	required := make([]interface{}, 0)
+
+	for _, p := range op.Parameters {
+		schema := make(map[string]interface{}, len(p.Schema)+1)
+		for k, v := range p.Schema {
+			schema[k] = v
+		}
+		desc := p.Description
+		if desc == "" {
+			desc, _ = schema["description"].(string)
+		}
+		if p.In != InQuery {
+			desc = strings.TrimSpace(desc + " (" + p.In + " parameter)")
+		}
+		if desc != "" {
+			schema["description"] = desc
+		}
+		if _, ok := schema["type"]; !ok && len(schema) <= 1 {
+			schema["type"] = "string"
+		}
+		props[p.Arg] = schema
+		if p.Required {
+			required = append(required, p.Arg)
+		}
+	}
+
+	if op.Body != nil {
+		body := make(map[string]interface{}, len(op.Body.Schema)+1)
+		for k, v := range op.Body.Schema {
+			body[k] = v
+		}
+		desc := "Request body (" + op.Body.ContentType + ")"
+		if op.Body.Description != "" {
+			desc = op.Body.Description + " - " + desc
+		}
+		body["description"] = desc
+		props[BodyArgument] = body
+		if op.Body.Required {
+			required = append(required, BodyArgument)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
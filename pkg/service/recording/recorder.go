@@ -0,0 +1,161 @@
+// Package recording records terminal sessions as asciicast v2 files, the
+// format played by asciinema, and manages the recordings on disk.
+package recording
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Event codes of asciicast v2
+const (
+	EventOutput = "o"
+	EventInput  = "i"
+	EventResize = "r"
+)
+
+// Header is the first line of an asciicast v2 file. AssetID is not part of
+// the format; players ignore it.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	AssetID   string            `json:"asset_id,omitempty"`
+}
+
+// Recorder appends the events of one session to its file. Its methods are
+// safe for concurrent use and do nothing once it is closed.
+type Recorder struct {
+	mu      sync.Mutex
+	id      string
+	file    *os.File
+	start   time.Time
+	partial map[string][]byte // incomplete UTF-8 sequence per event code
+	err     error
+	closed  bool
+	onClose func()
+}
+
+func newRecorder(id string, file *os.File, start time.Time, h Header, onClose func()) (*Recorder, error) {
+	h.Version = 2
+	h.Timestamp = start.Unix()
+	line, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("write recording header: %w", err)
+	}
+	return &Recorder{
+		id:      id,
+		file:    file,
+		start:   start,
+		partial: make(map[string][]byte),
+		onClose: onClose,
+	}, nil
+}
+
+// ID identifies the recording in its store
+func (r *Recorder) ID() string {
+	return r.id
+}
+
+// Output records data the terminal printed
+func (r *Recorder) Output(data []byte) {
+	r.text(EventOutput, data)
+}
+
+// Input records data sent to the terminal
+func (r *Recorder) Input(data []byte) {
+	r.text(EventInput, data)
+}
+
+// Resize records a change of the terminal size
+func (r *Recorder) Resize(cols, rows int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.write(EventResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// text records a stream event. A UTF-8 sequence split across writes is held
+// back until it is complete, as event data must be valid UTF-8.
+func (r *Recorder) text(code string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if held := r.partial[code]; len(held) > 0 {
+		data = append(held, data...)
+		delete(r.partial, code)
+	}
+	if cut := incompleteTail(data); cut < len(data) {
+		r.partial[code] = append([]byte(nil), data[cut:]...)
+		data = data[:cut]
+	}
+	if len(data) > 0 {
+		r.write(code, string(data))
+	}
+}
+
+// write appends one event line; the caller holds mu
+func (r *Recorder) write(code, data string) {
+	if r.closed || r.err != nil {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		r.err = err
+		return
+	}
+	elapsed := strconv.FormatFloat(time.Since(r.start).Seconds(), 'f', 6, 64)
+	line := make([]byte, 0, len(elapsed)+len(payload)+8)
+	line = append(line, '[')
+	line = append(line, elapsed...)
+	line = append(line, `, "`...)
+	line = append(line, code...)
+	line = append(line, `", `...)
+	line = append(line, payload...)
+	line = append(line, "]\n"...)
+	if _, err := r.file.Write(line); err != nil {
+		r.err = fmt.Errorf("write recording: %w", err)
+	}
+}
+
+// Close ends the recording. It reports the first error writing it met.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return r.err
+	}
+	r.closed = true
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if r.onClose != nil {
+		r.onClose()
+	}
+	return r.err
+}
+
+// incompleteTail returns where a UTF-8 sequence cut off at the end of data
+// starts, or len(data) when data ends on a character boundary
+func incompleteTail(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax+1; i-- {
+		if !utf8.RuneStart(data[i]) {
+			continue
+		}
+		if !utf8.FullRune(data[i:]) {
+			return i
+		}
+		break
+	}
+	return len(data)
+}
@@ -0,0 +1,90 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	store := NewStore(t.TempDir())
+	r, err := store.Create(Header{Width: 80, Height: 24, Title: "web", AssetID: "a1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Output([]byte("$ "))
+	r.Input([]byte("ls\r"))
+	// A character split across writes is recorded whole
+	r.Output([]byte("\xe4\xb8"))
+	r.Output([]byte("\xad\r\n"))
+	r.Resize(120, 40)
+
+	if err := store.Delete(r.ID()); !errors.Is(err, ErrActive) {
+		t.Fatalf("Delete() of an active recording = %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r.Output([]byte("after close"))
+
+	f, info, err := store.Open(r.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if info.AssetID != "a1" || info.Title != "web" || info.Width != 80 || info.Active {
+		t.Fatalf("Open() info = %+v", info)
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan()
+	var h Header
+	if err := json.Unmarshal(scanner.Bytes(), &h); err != nil || h.Version != 2 || h.Height != 24 {
+		t.Fatalf("header = %s, %v", scanner.Bytes(), err)
+	}
+	want := [][2]string{{"o", "$ "}, {"i", "ls\r"}, {"o", "中\r\n"}, {"r", "120x40"}}
+	for i := 0; scanner.Scan(); i++ {
+		var event [3]any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("event %d = %s: %v", i, scanner.Bytes(), err)
+		}
+		if i >= len(want) || event[1] != want[i][0] || event[2] != want[i][1] {
+			t.Fatalf("event %d = %s", i, scanner.Bytes())
+		}
+	}
+}
+
+func TestStoreRetention(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	write := func(id string, started time.Time, size int) {
+		h, _ := json.Marshal(Header{Version: 2, Width: 80, Height: 24, Timestamp: started.Unix()})
+		data := append(h, '\n')
+		data = append(data, make([]byte, size)...)
+		if err := os.WriteFile(filepath.Join(dir, id+Ext), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	write("old", now.Add(-48*time.Hour), 0)
+	write("mid", now.Add(-2*time.Hour), 1000)
+	write("new", now.Add(-time.Hour), 1000)
+
+	if _, err := store.Get("../old"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() of a path = %v", err)
+	}
+
+	store.SetRetention(24*time.Hour, 1500)
+	if n, err := store.Prune(); err != nil || n != 2 {
+		t.Fatalf("Prune() = %d, %v", n, err)
+	}
+	infos, err := store.List()
+	if err != nil || len(infos) != 1 || infos[0].ID != "new" {
+		t.Fatalf("List() = %+v, %v", infos, err)
+	}
+}
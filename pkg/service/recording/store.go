@@ -0,0 +1,310 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// Ext is the file extension of recordings
+const Ext = ".cast"
+
+var (
+	ErrNotFound = errors.New("recording not found")
+	ErrActive   = errors.New("recording is in progress")
+)
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// tailSize bounds how much of a file is read to find its last event
+const tailSize = 64 << 10
+
+// Info describes a recording on disk
+type Info struct {
+	ID        string    `json:"id"`
+	AssetID   string    `json:"asset_id,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	StartedAt time.Time `json:"started_at"`
+	Duration  float64   `json:"duration"` // seconds up to the last event
+	Size      int64     `json:"size"`
+	Active    bool      `json:"active"` // the session is still being recorded
+}
+
+// Store keeps recordings in a directory and applies the retention policy:
+// recordings older than maxAge, or the oldest ones past maxBytes in total,
+// are removed. Recordings in progress are never removed.
+type Store struct {
+	mu       sync.Mutex
+	dir      string
+	active   map[string]bool
+	maxAge   time.Duration
+	maxBytes int64
+	logger   *slog.Logger
+}
+
+var (
+	defaultStore *Store
+	defaultOnce  sync.Once
+)
+
+// NewStore creates a store over dir, which is created on the first recording
+func NewStore(dir string) *Store {
+	return &Store{
+		dir:    dir,
+		active: make(map[string]bool),
+		logger: utils.GetLogger(),
+	}
+}
+
+// Default returns the store over ~/.choraleia/recordings
+func Default() *Store {
+	defaultOnce.Do(func() {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		defaultStore = NewStore(filepath.Join(home, ".choraleia", "recordings"))
+	})
+	return defaultStore
+}
+
+// SetRetention sets how long recordings are kept and how much space they
+// may take in total. Zero disables either limit.
+func (s *Store) SetRetention(maxAge time.Duration, maxBytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxAge, s.maxBytes = maxAge, maxBytes
+}
+
+// Create starts a recording with the given header; its version and
+// timestamp are filled in
+func (s *Store) Create(h Header) (*Recorder, error) {
+	if _, err := s.Prune(); err != nil {
+		s.logger.Warn("Failed to prune recordings", "error", err)
+	}
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create recordings dir: %w", err)
+	}
+	start := time.Now()
+	id := start.UTC().Format("20060102T150405Z") + "-" + uuid.New().String()[:8]
+	file, err := os.OpenFile(s.path(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create recording: %w", err)
+	}
+
+	s.mu.Lock()
+	s.active[id] = true
+	s.mu.Unlock()
+
+	r, err := newRecorder(id, file, start, h, func() {
+		s.mu.Lock()
+		delete(s.active, id)
+		s.mu.Unlock()
+	})
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(s.path(id))
+		s.mu.Lock()
+		delete(s.active, id)
+		s.mu.Unlock()
+		return nil, err
+	}
+	return r, nil
+}
+
+// List returns the recordings, newest first
+func (s *Store) List() ([]Info, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read recordings dir: %w", err)
+	}
+	var infos []Info
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), Ext)
+		if !ok || e.IsDir() || !validID.MatchString(id) {
+			continue
+		}
+		info, err := s.Get(id)
+		if err != nil {
+			s.logger.Warn("Skipping unreadable recording", "id", id, "error", err)
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].StartedAt.After(infos[j].StartedAt) })
+	return infos, nil
+}
+
+// Get describes one recording
+func (s *Store) Get(id string) (Info, error) {
+	f, info, err := s.Open(id)
+	if err != nil {
+		return Info{}, err
+	}
+	_ = f.Close()
+	return info, nil
+}
+
+// Open opens a recording for reading
+func (s *Store) Open(id string) (*os.File, Info, error) {
+	if !validID.MatchString(id) {
+		return nil, Info{}, ErrNotFound
+	}
+	f, err := os.Open(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Info{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Info{}, err
+	}
+	info, err := s.describe(id, f)
+	if err != nil {
+		_ = f.Close()
+		return nil, Info{}, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, Info{}, err
+	}
+	return f, info, nil
+}
+
+// describe reads the header and the time of the last event of a recording
+func (s *Store) describe(id string, f *os.File) (Info, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return Info{}, err
+	}
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return Info{}, fmt.Errorf("read recording header: %w", err)
+	}
+	var h Header
+	if err := json.Unmarshal(line, &h); err != nil {
+		return Info{}, fmt.Errorf("parse recording header: %w", err)
+	}
+
+	s.mu.Lock()
+	active := s.active[id]
+	s.mu.Unlock()
+
+	return Info{
+		ID:        id,
+		AssetID:   h.AssetID,
+		Title:     h.Title,
+		Width:     h.Width,
+		Height:    h.Height,
+		StartedAt: time.Unix(h.Timestamp, 0),
+		Duration:  lastEventTime(f, stat.Size()),
+		Size:      stat.Size(),
+		Active:    active,
+	}, nil
+}
+
+// lastEventTime returns the time of the last complete event in a file, or 0
+func lastEventTime(f *os.File, size int64) float64 {
+	offset := max(size-tailSize, 0)
+	buf := make([]byte, size-offset)
+	if _, err := f.ReadAt(buf, offset); err != nil && !errors.Is(err, io.EOF) {
+		return 0
+	}
+	lines := bytes.Split(bytes.TrimRight(buf, "\n"), []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		var event []json.RawMessage
+		if json.Unmarshal(lines[i], &event) != nil || len(event) != 3 {
+			continue
+		}
+		var t float64
+		if json.Unmarshal(event[0], &t) == nil {
+			return t
+		}
+	}
+	return 0
+}
+
+// Delete removes a recording that is not in progress
+func (s *Store) Delete(id string) error {
+	if !validID.MatchString(id) {
+		return ErrNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active[id] {
+		return ErrActive
+	}
+	if err := os.Remove(s.path(id)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Prune applies the retention policy and returns how many recordings it
+// removed
+func (s *Store) Prune() (int, error) {
+	s.mu.Lock()
+	maxAge, maxBytes := s.maxAge, s.maxBytes
+	s.mu.Unlock()
+	if maxAge <= 0 && maxBytes <= 0 {
+		return 0, nil
+	}
+
+	infos, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, info := range infos {
+		total += info.Size
+	}
+
+	removed := 0
+	// Oldest first
+	for i := len(infos) - 1; i >= 0; i-- {
+		info := infos[i]
+		expired := maxAge > 0 && time.Since(info.StartedAt) > maxAge
+		oversize := maxBytes > 0 && total > maxBytes
+		if !expired && !oversize {
+			continue
+		}
+		if err := s.Delete(info.ID); err != nil {
+			if !errors.Is(err, ErrActive) && !errors.Is(err, ErrNotFound) {
+				s.logger.Warn("Failed to remove recording", "id", info.ID, "error", err)
+			}
+			continue
+		}
+		total -= info.Size
+		removed++
+	}
+	if removed > 0 {
+		s.logger.Info("Pruned terminal recordings", "removed", removed)
+	}
+	return removed, nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+Ext)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/secrets"
+	"github.com/choraleia/choraleia/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// PruneSecrets deletes the vault entries that no asset, asset revision,
+// model, workspace tool or port forward refers to any more, such as the
+// old password of an edited asset once its revisions are gone
+func PruneSecrets(db *gorm.DB) {
+	removed, err := secrets.Default().Prune(func() (map[string]bool, error) {
+		return secretRefs(db)
+	})
+	if err != nil {
+		utils.GetLogger().Error("Failed to prune the secret vault", "error", err)
+		return
+	}
+	if removed > 0 {
+		utils.GetLogger().Info("Pruned unused secrets", "count", removed)
+	}
+}
+
+// secretRefs gathers the vault references the stores hold. Failing to
+// read any of them fails the whole, so nothing in use is deleted.
+func secretRefs(db *gorm.DB) (map[string]bool, error) {
+	refs := make(map[string]bool)
+	var (
+		assets    []models.Asset
+		revisions []models.AssetRevision
+		tools     []models.WorkspaceTool
+		forwards  []models.PortForward
+	)
+	for _, list := range []interface{}{&assets, &revisions, &tools, &forwards} {
+		if err := db.Find(list).Error; err != nil {
+			return nil, err
+		}
+		if err := secrets.CollectRefs(list, refs); err != nil {
+			return nil, err
+		}
+	}
+	modelList, err := models.LoadSealedModels()
+	if err != nil {
+		return nil, err
+	}
+	if err := secrets.CollectRefs(modelList, refs); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
@@ -0,0 +1,50 @@
+# Choraleia shell integration for bash: OSC 133 prompt and command markers
+# and OSC 7 working directory reports. Needs bash 4.4 for PS0.
+if [ -z "$__choraleia_si" ]; then
+	__choraleia_si=1
+	__choraleia_hist=
+
+	__choraleia_urlencode() {
+		local LC_ALL=C s="$1" out="" c i
+		for ((i = 0; i < ${#s}; i++)); do
+			c="${s:i:1}"
+			case "$c" in
+			[a-zA-Z0-9./_~-]) out+="$c" ;;
+			*)
+				printf -v c '%%%02X' "'$c"
+				out+="$c"
+				;;
+			esac
+		done
+		printf '%s' "$out"
+	}
+
+	# Runs in a subshell from PS0: the command is the newest history entry,
+	# unless the history didn't grow, as for an empty line
+	__choraleia_preexec() {
+		local entry cmd=""
+		entry="$(HISTTIMEFORMAT='' builtin history 1)"
+		if [[ $entry =~ ^[[:space:]]*([0-9]+)[[:space:]]+(.*)$ ]] && [ "${BASH_REMATCH[1]}" != "$__choraleia_hist" ]; then
+			cmd="${BASH_REMATCH[2]}"
+		fi
+		printf '\033]133;C;cmdline_url=%s\007' "$(__choraleia_urlencode "$cmd")"
+	}
+
+	__choraleia_precmd() {
+		local ret=$? entry
+		printf '\033]133;D;%s\007' "$ret"
+		entry="$(HISTTIMEFORMAT='' builtin history 1)"
+		[[ $entry =~ ^[[:space:]]*([0-9]+) ]] && __choraleia_hist="${BASH_REMATCH[1]}"
+		printf '\033]7;file://%s%s\007\033]133;A\007' "$HOSTNAME" "$(__choraleia_urlencode "$PWD")"
+		return $ret
+	}
+
+	if [[ "$(declare -p PROMPT_COMMAND 2>/dev/null)" == "declare -a"* ]]; then
+		PROMPT_COMMAND=(__choraleia_precmd "${PROMPT_COMMAND[@]}")
+	else
+		PROMPT_COMMAND="__choraleia_precmd${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+	fi
+	PS1="$PS1\[\033]133;B\007\]"
+	PS0='$(__choraleia_preexec)'"$PS0"
+fi
+printf '\033]7777;choraleia-ready\007\r\033[2K'
@@ -0,0 +1,18 @@
+# Choraleia shell integration for fish: OSC 133 prompt and command markers
+# and OSC 7 working directory reports
+if not set -q __choraleia_si
+    set -g __choraleia_si 1
+
+    function __choraleia_preexec --on-event fish_preexec
+        printf '\e]133;C;cmdline_url=%s\a' (string escape --style=url -- "$argv")
+    end
+
+    function __choraleia_postexec --on-event fish_postexec
+        printf '\e]133;D;%s\a' $status
+    end
+
+    function __choraleia_prompt --on-event fish_prompt
+        printf '\e]7;file://%s%s\a\e]133;A\a' $hostname (string escape --style=url -- $PWD)
+    end
+end
+printf '\e]7777;choraleia-ready\a\r\e[2K'
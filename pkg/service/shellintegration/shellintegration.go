@@ -0,0 +1,107 @@
+// Package shellintegration installs prompt hooks in bash, zsh and fish and
+// follows the OSC 133 command markers and OSC 7 working directory reports
+// they print, for a structured history of the commands run in a terminal.
+//
+// Hooks are typed into the shell when a session starts. Any shell that
+// prints the markers itself, such as one set up for kitty or WezTerm, is
+// tracked too.
+package shellintegration
+
+import (
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"path"
+	"strings"
+	"time"
+)
+
+// ReadyMarker is printed by the hooks once they are installed
+const ReadyMarker = "\x1b]7777;choraleia-ready\x07"
+
+//go:embed scripts
+var scripts embed.FS
+
+// Shell families with hooks
+const (
+	ShellBash = "bash"
+	ShellZsh  = "zsh"
+	ShellFish = "fish"
+)
+
+// ShellOf returns the family of a shell path such as /usr/bin/zsh, or ""
+// when it has no hooks
+func ShellOf(shellPath string) string {
+	switch name := path.Base(strings.TrimSpace(shellPath)); name {
+	case ShellBash, ShellZsh, ShellFish:
+		return name
+	}
+	return ""
+}
+
+func script(shell string) string {
+	name := map[string]string{ShellBash: "bash.sh", ShellZsh: "zsh.zsh", ShellFish: "fish.fish"}[shell]
+	b, err := scripts.ReadFile("scripts/" + name)
+	if err != nil {
+		panic("shellintegration: missing script for " + shell)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// InstallCommand returns the line that installs the hooks, to be typed at the
+// shell's prompt. With shell "" the line works in bash, zsh and fish alike
+// and picks the hooks for the running shell. The line starts with a space,
+// which keeps it out of bash and fish history where configured.
+func InstallCommand(shell string) string {
+	switch shell {
+	case ShellBash, ShellZsh:
+		return ` eval "$(echo ` + script(shell) + ` | base64 -d)"`
+	case ShellFish:
+		return ` echo ` + script(shell) + ` | base64 -d | source`
+	}
+
+	// Both single-quoted strings are read the same way by every shell, so
+	// each shell parses the line and evaluates only its own half
+	posix := `if [ -n "$ZSH_VERSION" ]; then eval "$(echo ` + script(ShellZsh) + ` | base64 -d)"; ` +
+		`elif [ -n "$BASH_VERSION" ]; then eval "$(echo ` + script(ShellBash) + ` | base64 -d)"; ` +
+		`else printf '\033]7777;choraleia-ready\007'; fi`
+	return ` test -n "$FISH_VERSION" && eval 'echo ` + script(ShellFish) + ` | base64 -d | source'; ` +
+		`test -z "$FISH_VERSION" && eval 'eval "$(echo ` + base64.StdEncoding.EncodeToString([]byte(posix)) + ` | base64 -d)"'`
+}
+
+// maxHeld bounds the output a Gate holds back
+const maxHeld = 64 << 10
+
+// Gate hides the echo of the hook installation. Output is held from the
+// moment the hooks are typed until they print ReadyMarker, and what follows
+// the marker is let through. Should the marker not come in time, the held
+// output is let through as is.
+type Gate struct {
+	held     []byte
+	deadline time.Time
+	open     bool
+}
+
+// NewGate creates a closed gate that opens by itself after timeout
+func NewGate(timeout time.Duration) *Gate {
+	return &Gate{deadline: time.Now().Add(timeout)}
+}
+
+// Filter returns the part of p to show. It is not safe for concurrent use.
+func (g *Gate) Filter(p []byte) []byte {
+	if g.open {
+		return p
+	}
+	g.held = append(g.held, p...)
+	if i := bytes.Index(g.held, []byte(ReadyMarker)); i >= 0 {
+		out := g.held[i+len(ReadyMarker):]
+		g.open, g.held = true, nil
+		return out
+	}
+	if time.Now().After(g.deadline) || len(g.held) > maxHeld {
+		out := g.held
+		g.open, g.held = true, nil
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package shellintegration
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker(10)
+	// A marker split across writes is put together
+	_, _ = tr.Write([]byte("\x1b]7;file://host/home/me%20too\x07\x1b]133;A\x07$ \x1b]13"))
+	_, _ = tr.Write([]byte("3;B\x07ls\r\n\x1b]133;C;cmdline_url=ls%20-la\x07file\r\n\x1b]133;D;0\x1b\\"))
+	_, _ = tr.Write([]byte("\x1b]133;C;cmdline=false\x07\x1b]133;D;1\x07"))
+	// An empty line, and D without C, are not commands
+	_, _ = tr.Write([]byte("\x1b]133;C;cmdline_url=\x07\x1b]133;D;0\x07\x1b]133;D;0\x07"))
+	_, _ = tr.Write([]byte("\x1b]133;C;cmdline_url=sleep%2010\x07"))
+
+	if !tr.Active() {
+		t.Fatal("Active() = false")
+	}
+	if got := tr.Cwd(); got != "/home/me too" {
+		t.Fatalf("Cwd() = %q", got)
+	}
+	history := tr.History(0)
+	if len(history) != 2 {
+		t.Fatalf("History() = %+v", history)
+	}
+	if h := history[0]; h.Command != "ls -la" || h.Cwd != "/home/me too" || h.ExitCode == nil || *h.ExitCode != 0 || h.FinishedAt == nil {
+		t.Fatalf("History()[0] = %+v", h)
+	}
+	if h := history[1]; h.Command != "false" || h.ExitCode == nil || *h.ExitCode != 1 {
+		t.Fatalf("History()[1] = %+v", h)
+	}
+	if r := tr.Running(); r == nil || r.Command != "sleep 10" {
+		t.Fatalf("Running() = %+v", r)
+	}
+	if got := tr.History(1); len(got) != 1 || got[0].Command != "false" {
+		t.Fatalf("History(1) = %+v", got)
+	}
+}
+
+func TestGate(t *testing.T) {
+	g := NewGate(time.Minute)
+	if out := g.Filter([]byte(" eval \"$(echo aGk= | base64 -d)\"\r\n\x1b]7777;choraleia")); out != nil {
+		t.Fatalf("Filter() before the marker = %q", out)
+	}
+	if out := g.Filter([]byte("-ready\x07\r\x1b[2K$ ")); string(out) != "\r\x1b[2K$ " {
+		t.Fatalf("Filter() at the marker = %q", out)
+	}
+	if out := g.Filter([]byte("ls\r\n")); string(out) != "ls\r\n" {
+		t.Fatalf("Filter() after the marker = %q", out)
+	}
+
+	g = NewGate(0)
+	if out := g.Filter([]byte("sh: base64: not found\r\n")); string(out) != "sh: base64: not found\r\n" {
+		t.Fatalf("Filter() past the deadline = %q", out)
+	}
+}
+
+func TestInstallCommand(t *testing.T) {
+	if got := InstallCommand(ShellOf("/usr/bin/fish")); !strings.HasSuffix(got, "| base64 -d | source") {
+		t.Fatalf("InstallCommand(fish) = %q", got)
+	}
+	if ShellOf("/bin/sh") != "" {
+		t.Fatal("ShellOf(/bin/sh) has hooks")
+	}
+	if got := InstallCommand(""); !strings.HasPrefix(got, ` test -n "$FISH_VERSION"`) {
+		t.Fatalf("InstallCommand(\"\") = %q", got)
+	}
+}
@@ -0,0 +1,213 @@
+package shellintegration
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command is a command line run in a terminal
+type Command struct {
+	Command    string     `json:"command"`
+	Cwd        string     `json:"cwd,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	DurationMs int64      `json:"duration_ms,omitempty"`
+}
+
+// maxOSC bounds the payload of an OSC sequence; longer ones are skipped
+const maxOSC = 16 << 10
+
+const (
+	stateGround = iota
+	stateEscape
+	stateOSC
+	stateOSCEscape
+)
+
+// Tracker follows the markers in a terminal's output. Its methods are safe
+// for concurrent use.
+type Tracker struct {
+	mu         sync.Mutex
+	maxHistory int
+	history    []Command
+	running    *Command
+	cwd        string
+	active     bool
+
+	state    int
+	osc      []byte
+	overflow bool
+}
+
+// NewTracker creates a tracker that keeps the last maxHistory commands
+func NewTracker(maxHistory int) *Tracker {
+	return &Tracker{maxHistory: maxHistory}
+}
+
+// Write feeds terminal output to the tracker
+func (t *Tracker) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, b := range p {
+		switch t.state {
+		case stateGround:
+			if b == 0x1b {
+				t.state = stateEscape
+			}
+		case stateEscape:
+			if b == ']' {
+				t.state = stateOSC
+				t.osc, t.overflow = t.osc[:0], false
+			} else {
+				t.state = stateGround
+			}
+		case stateOSC:
+			switch b {
+			case 0x07:
+				t.state = stateGround
+				t.dispatch()
+			case 0x1b:
+				t.state = stateOSCEscape
+			case 0x18, 0x1a:
+				t.state = stateGround
+			default:
+				if len(t.osc) < maxOSC {
+					t.osc = append(t.osc, b)
+				} else {
+					t.overflow = true
+				}
+			}
+		case stateOSCEscape:
+			t.state = stateGround
+			if b == '\\' {
+				t.dispatch()
+			} else if b == ']' {
+				// An OSC cut short by the next one
+				t.state = stateOSC
+				t.osc, t.overflow = t.osc[:0], false
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// dispatch handles a complete OSC payload; the caller holds mu
+func (t *Tracker) dispatch() {
+	if t.overflow {
+		return
+	}
+	payload := string(t.osc)
+	switch {
+	case strings.HasPrefix(payload, "7;"):
+		if u, err := url.Parse(payload[2:]); err == nil && u.Path != "" {
+			t.cwd = u.Path
+		}
+	case strings.HasPrefix(payload, "133;"):
+		t.active = true
+		fields := strings.Split(payload[4:], ";")
+		switch fields[0] {
+		case "C":
+			t.start(commandLine(fields[1:]))
+		case "D":
+			code := -1
+			if len(fields) > 1 {
+				if n, err := strconv.Atoi(fields[1]); err == nil {
+					code = n
+				}
+			}
+			t.finish(code)
+		}
+	}
+}
+
+// commandLine reads the command from the options of a C marker, given
+// percent-encoded as cmdline_url or verbatim as cmdline
+func commandLine(options []string) string {
+	for _, option := range options {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "cmdline_url":
+			if s, err := url.PathUnescape(value); err == nil {
+				return s
+			}
+		case "cmdline":
+			return value
+		}
+	}
+	return ""
+}
+
+func (t *Tracker) start(command string) {
+	if t.running != nil {
+		// The shell didn't report how the previous command ended
+		t.finish(-1)
+	}
+	t.running = &Command{Command: command, Cwd: t.cwd, StartedAt: time.Now()}
+}
+
+func (t *Tracker) finish(code int) {
+	c := t.running
+	if c == nil {
+		return
+	}
+	t.running = nil
+	if strings.TrimSpace(c.Command) == "" {
+		return
+	}
+	now := time.Now()
+	c.FinishedAt = &now
+	c.DurationMs = now.Sub(c.StartedAt).Milliseconds()
+	if code >= 0 {
+		c.ExitCode = &code
+	}
+	if t.maxHistory <= 0 {
+		return
+	}
+	t.history = append(t.history, *c)
+	// Trim in batches so appending is not quadratic
+	if len(t.history) >= 2*t.maxHistory {
+		t.history = append(t.history[:0], t.history[len(t.history)-t.maxHistory:]...)
+	}
+}
+
+// Active reports whether the shell has printed command markers
+func (t *Tracker) Active() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.active
+}
+
+// Cwd returns the last working directory the shell reported
+func (t *Tracker) Cwd() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cwd
+}
+
+// Running returns the command running now, or nil at the prompt
+func (t *Tracker) Running() *Command {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running == nil {
+		return nil
+	}
+	c := *t.running
+	return &c
+}
+
+// History returns the last n finished commands, oldest first; n <= 0 for
+// all of them
+func (t *Tracker) History(n int) []Command {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	history := t.history[max(len(t.history)-t.maxHistory, 0):]
+	if n > 0 && len(history) > n {
+		history = history[len(history)-n:]
+	}
+	return append([]Command(nil), history...)
+}
@@ -0,0 +1,88 @@
+package sshconn
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ForwardAgent forwards an ssh-agent to a session when the config enables
+// agent forwarding. The local agent at SSH_AUTH_SOCK is forwarded when
+// there is one, otherwise an agent holding the asset's own keys. It must be
+// called before the session starts its shell or command, and at most once
+// per client.
+func (d *Dialer) ForwardAgent(client *ssh.Client, session *ssh.Session, cfg *models.SSHConfig) error {
+	if !cfg.AgentForwarding {
+		return nil
+	}
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if err := agent.ForwardToRemote(client, sock); err != nil {
+			return fmt.Errorf("forward ssh-agent: %w", err)
+		}
+	} else {
+		keyring, err := d.assetKeyring(cfg)
+		if err != nil {
+			return err
+		}
+		if err := agent.ForwardToAgent(client, keyring); err != nil {
+			return fmt.Errorf("forward ssh-agent: %w", err)
+		}
+	}
+	if err := agent.RequestAgentForwarding(session); err != nil {
+		return fmt.Errorf("request agent forwarding: %w", err)
+	}
+	return nil
+}
+
+// assetKeyring builds an in-memory agent holding the config's private keys
+// and their certificates
+func (d *Dialer) assetKeyring(cfg *models.SSHConfig) (agent.Agent, error) {
+	var raws [][]byte
+	if cfg.PrivateKeyPath != "" {
+		data, err := os.ReadFile(expandHome(cfg.PrivateKeyPath))
+		if err != nil {
+			return nil, err
+		}
+		raws = append(raws, data)
+	}
+	if cfg.PrivateKey != "" {
+		raws = append(raws, []byte(cfg.PrivateKey))
+	}
+	if len(raws) == 0 {
+		return nil, fmt.Errorf("agent forwarding needs SSH_AUTH_SOCK or a private key")
+	}
+
+	certs, err := loadCertificates(cfg)
+	if err != nil {
+		d.logger.Warn("Failed to load SSH certificate", "host", cfg.Host, "error", err)
+	}
+
+	keyring := agent.NewKeyring()
+	for _, raw := range raws {
+		key, err := ssh.ParseRawPrivateKey(raw)
+		if err != nil && cfg.PrivateKeyPassphrase != "" {
+			key, err = ssh.ParseRawPrivateKeyWithPassphrase(raw, []byte(cfg.PrivateKeyPassphrase))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %w", err)
+		}
+		if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+			return nil, err
+		}
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			continue
+		}
+		for _, cert := range certs {
+			if bytes.Equal(cert.Key.Marshal(), signer.PublicKey().Marshal()) {
+				_ = keyring.Add(agent.AddedKey{PrivateKey: key, Certificate: cert})
+			}
+		}
+	}
+	return keyring, nil
+}
@@ -0,0 +1,255 @@
+package sshconn
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Challenge is a keyboard-interactive challenge, such as an OTP prompt,
+// relayed to the user
+type Challenge struct {
+	User        string     `json:"user"`
+	Host        string     `json:"host"`
+	Name        string     `json:"name"`
+	Instruction string     `json:"instruction"`
+	Questions   []Question `json:"questions"`
+}
+
+// Question is one prompt of a challenge. Echo is false for secrets.
+type Question struct {
+	Prompt string `json:"prompt"`
+	Echo   bool   `json:"echo"`
+}
+
+// Prompt asks the user to answer a challenge, one answer per question
+type Prompt func(c *Challenge) ([]string, error)
+
+// authMethods builds the auth methods of a config in the order OpenSSH
+// tries them: public keys (certificates first, then agent keys), password,
+// then keyboard-interactive. Unusable keys are skipped with a warning so the
+// other methods still get their chance. The returned func releases the agent
+// connection once the handshake is over.
+func (d *Dialer) authMethods(cfg *models.SSHConfig, o *dialOptions) ([]ssh.AuthMethod, func()) {
+	signers := d.keySigners(cfg)
+
+	var agentConn net.Conn
+	if cfg.UseAgent {
+		conn, err := dialAgent()
+		if err != nil {
+			d.logger.Warn("ssh-agent unavailable", "host", cfg.Host, "error", err)
+		} else {
+			agentConn = conn
+		}
+	}
+
+	var methods []ssh.AuthMethod
+	if len(signers) > 0 || agentConn != nil {
+		methods = append(methods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			if agentConn == nil {
+				return signers, nil
+			}
+			agentSigners, err := agent.NewClient(agentConn).Signers()
+			if err != nil {
+				d.logger.Warn("Failed to list ssh-agent keys", "host", cfg.Host, "error", err)
+				return signers, nil
+			}
+			return append(append([]ssh.Signer(nil), signers...), agentSigners...), nil
+		}))
+	}
+	if cfg.Password != "" {
+		methods = append(methods, ssh.Password(cfg.Password))
+	}
+	// Servers that allow empty passwords still need a password attempt
+	if len(methods) == 0 {
+		methods = append(methods, ssh.Password(""))
+	}
+	methods = append(methods, ssh.KeyboardInteractive(challenge(cfg, o.prompt)))
+
+	release := func() {
+		if agentConn != nil {
+			_ = agentConn.Close()
+		}
+	}
+	return methods, release
+}
+
+// keySigners loads the configured private keys, each preceded by its
+// certificate when there is one
+func (d *Dialer) keySigners(cfg *models.SSHConfig) []ssh.Signer {
+	var keys []ssh.Signer
+	if cfg.PrivateKeyPath != "" {
+		if signer, err := LoadPrivateKey(cfg.PrivateKeyPath, cfg.PrivateKeyPassphrase); err == nil {
+			keys = append(keys, signer)
+		} else {
+			d.logger.Warn("Failed to load private key from file", "path", cfg.PrivateKeyPath, "host", cfg.Host, "error", err)
+		}
+	}
+	if cfg.PrivateKey != "" {
+		if signer, err := ParsePrivateKey([]byte(cfg.PrivateKey), cfg.PrivateKeyPassphrase); err == nil {
+			keys = append(keys, signer)
+		} else {
+			d.logger.Warn("Failed to parse provided private key", "host", cfg.Host, "error", err)
+		}
+	}
+
+	certs, err := loadCertificates(cfg)
+	if err != nil {
+		d.logger.Warn("Failed to load SSH certificate", "host", cfg.Host, "error", err)
+	}
+
+	var signers []ssh.Signer
+	for _, key := range keys {
+		for _, cert := range certs {
+			if !bytes.Equal(cert.Key.Marshal(), key.PublicKey().Marshal()) {
+				continue
+			}
+			if certSigner, err := ssh.NewCertSigner(cert, key); err == nil {
+				signers = append(signers, certSigner)
+			}
+		}
+		signers = append(signers, key)
+	}
+	return signers
+}
+
+// loadCertificates returns the inline certificate and the certificate file,
+// which like OpenSSH defaults to <key path>-cert.pub when it exists
+func loadCertificates(cfg *models.SSHConfig) ([]*ssh.Certificate, error) {
+	var certs []*ssh.Certificate
+	if cfg.Certificate != "" {
+		cert, err := ParseCertificate([]byte(cfg.Certificate))
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	path := cfg.CertificatePath
+	if path == "" && cfg.PrivateKeyPath != "" {
+		path = expandHome(cfg.PrivateKeyPath) + "-cert.pub"
+		if _, err := os.Stat(path); err != nil {
+			return certs, nil
+		}
+	}
+	if path == "" {
+		return certs, nil
+	}
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return certs, err
+	}
+	cert, err := ParseCertificate(data)
+	if err != nil {
+		return certs, fmt.Errorf("%s: %w", path, err)
+	}
+	return append(certs, cert), nil
+}
+
+// ParseCertificate parses an OpenSSH user certificate in authorized_keys
+// format
+func ParseCertificate(data []byte) (*ssh.Certificate, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, err
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("not an SSH certificate")
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("not a user certificate")
+	}
+	return cert, nil
+}
+
+// challenge answers keyboard-interactive challenges. A lone hidden password
+// prompt is answered once with the configured password, which covers servers
+// that ask for the password this way; anything else, such as an OTP code,
+// goes to the user. Without a prompt the challenge fails.
+func challenge(cfg *models.SSHConfig, prompt Prompt) ssh.KeyboardInteractiveChallenge {
+	passwordUsed := false
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		if len(questions) == 0 {
+			return nil, nil
+		}
+		if len(questions) == 1 && !echos[0] && !passwordUsed && cfg.Password != "" &&
+			strings.Contains(strings.ToLower(questions[0]), "password") {
+			passwordUsed = true
+			return []string{cfg.Password}, nil
+		}
+		if prompt == nil {
+			return nil, fmt.Errorf("keyboard-interactive authentication needs user input")
+		}
+
+		c := &Challenge{
+			User:        cfg.Username,
+			Host:        cfg.Host,
+			Name:        name,
+			Instruction: instruction,
+			Questions:   make([]Question, len(questions)),
+		}
+		for i, q := range questions {
+			c.Questions[i] = Question{Prompt: q, Echo: echos[i]}
+		}
+		answers, err := prompt(c)
+		if err != nil {
+			return nil, err
+		}
+		if len(answers) != len(questions) {
+			return nil, fmt.Errorf("expected %d answers, got %d", len(questions), len(answers))
+		}
+		return answers, nil
+	}
+}
+
+// dialAgent connects to the ssh-agent at SSH_AUTH_SOCK
+func dialAgent() (net.Conn, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, fmt.Errorf("SSH_AUTH_SOCK is not set")
+	}
+	return net.Dial("unix", sock)
+}
+
+// LoadPrivateKey reads a private key file, expanding a leading ~
+func LoadPrivateKey(path, passphrase string) (ssh.Signer, error) {
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(data, passphrase)
+}
+
+// ParsePrivateKey parses a private key, decrypting it with the passphrase
+// when it is encrypted
+func ParsePrivateKey(data []byte, passphrase string) (ssh.Signer, error) {
+	signer, err := ssh.ParsePrivateKey(data)
+	if err == nil {
+		return signer, nil
+	}
+	if passphrase == "" {
+		return nil, err
+	}
+	signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt private key: %w", err)
+	}
+	return signer, nil
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
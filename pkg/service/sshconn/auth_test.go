@@ -0,0 +1,89 @@
+package sshconn
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/utils"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestChallenge(t *testing.T) {
+	cfg := &models.SSHConfig{Username: "ops", Host: "bastion", Password: "secret"}
+	var asked *Challenge
+	answer := challenge(cfg, func(c *Challenge) ([]string, error) {
+		asked = c
+		return []string{"123456"}, nil
+	})
+
+	// The password prompt is answered once from the config
+	got, err := answer("", "", []string{"Password: "}, []bool{false})
+	if err != nil || len(got) != 1 || got[0] != "secret" || asked != nil {
+		t.Fatalf("password = %v, %v, asked %+v", got, err, asked)
+	}
+	got, err = answer("", "", []string{"Verification code: "}, []bool{false})
+	if err != nil || got[0] != "123456" || asked == nil || asked.Host != "bastion" || asked.Questions[0].Echo {
+		t.Fatalf("otp = %v, %v, asked %+v", got, err, asked)
+	}
+	// A second password prompt means the password was wrong; ask the user
+	asked = nil
+	if _, err = answer("", "", []string{"Password: "}, []bool{false}); err != nil || asked == nil {
+		t.Fatalf("retry = %v, asked %+v", err, asked)
+	}
+
+	if _, err := challenge(cfg, nil)("", "", []string{"Verification code: "}, []bool{false}); err == nil {
+		t.Fatal("expected an error without a prompt")
+	}
+}
+
+func TestKeySignersCertificate(t *testing.T) {
+	dir := t.TempDir()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, caKey, _ := ed25519.GenerateKey(rand.Reader)
+	ca, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &ssh.Certificate{
+		Key:             signer.PublicKey(),
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"ops"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath+"-cert.pub", ssh.MarshalAuthorizedKey(cert), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Dialer{logger: utils.GetLogger()}
+	signers := d.keySigners(&models.SSHConfig{PrivateKeyPath: keyPath})
+	if len(signers) != 2 {
+		t.Fatalf("signers = %d", len(signers))
+	}
+	if _, ok := signers[0].PublicKey().(*ssh.Certificate); !ok {
+		t.Fatalf("first signer = %s, want the certificate", signers[0].PublicKey().Type())
+	}
+}
@@ -0,0 +1,291 @@
+// Package sshconn opens SSH connections to assets. It owns authentication,
+// host key checking, proxies, jump host chains and keepalive so every SSH
+// feature connects to an asset the same way.
+package sshconn
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service/hostkey"
+	"github.com/choraleia/choraleia/pkg/utils"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+	// maxJumpHops bounds jump host chains
+	maxJumpHops = 8
+	// keepaliveMissed unanswered keepalive intervals close a connection
+	keepaliveMissed = 3
+)
+
+// Connection modes of an SSH asset
+const (
+	ModeDirect = "direct"
+	ModeProxy  = "proxy"
+	ModeJump   = "jump"
+)
+
+// AssetResolver resolves an asset by ID. Implemented by service.AssetService.
+type AssetResolver interface {
+	GetAsset(id string) (*models.Asset, error)
+}
+
+// Dialer opens SSH connections to assets
+type Dialer struct {
+	assets   AssetResolver
+	hostKeys *hostkey.Store
+	logger   *slog.Logger
+}
+
+// NewDialer creates a dialer checking host keys against the default store
+func NewDialer(assets AssetResolver) *Dialer {
+	return &Dialer{
+		assets:   assets,
+		hostKeys: hostkey.Default(),
+		logger:   utils.GetLogger(),
+	}
+}
+
+// Option adjusts a single dial
+type Option func(*dialOptions)
+
+type dialOptions struct {
+	confirm hostkey.Confirm
+	prompt  Prompt
+}
+
+// WithHostKeyConfirm asks confirm before trusting an unknown host key of
+// any hop, instead of trusting it on first use
+func WithHostKeyConfirm(confirm hostkey.Confirm) Option {
+	return func(o *dialOptions) { o.confirm = confirm }
+}
+
+// WithKeyboardInteractive relays keyboard-interactive challenges of any hop,
+// such as OTP codes, to prompt
+func WithKeyboardInteractive(prompt Prompt) Option {
+	return func(o *dialOptions) { o.prompt = prompt }
+}
+
+// Dial connects to an SSH asset by ID
+func (d *Dialer) Dial(ctx context.Context, assetID string, opts ...Option) (*ssh.Client, error) {
+	asset, err := d.assets.GetAsset(assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset: %w", err)
+	}
+	return d.DialAsset(ctx, asset, opts...)
+}
+
+// DialAsset connects to an SSH asset, through its proxy or jump host chain.
+// Closing the client also closes the jump hosts it was reached through.
+func (d *Dialer) DialAsset(ctx context.Context, asset *models.Asset, opts ...Option) (*ssh.Client, error) {
+	if asset.Type != models.AssetTypeSSH {
+		return nil, fmt.Errorf("asset %s is not an SSH asset", asset.Name)
+	}
+	var cfg models.SSHConfig
+	if err := asset.GetTypedConfig(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse SSH config: %w", err)
+	}
+	return d.dial(ctx, &cfg, newOptions(opts), []string{asset.ID})
+}
+
+// DialConfig connects with an SSH config that isn't saved as an asset
+func (d *Dialer) DialConfig(ctx context.Context, cfg *models.SSHConfig, opts ...Option) (*ssh.Client, error) {
+	return d.dial(ctx, cfg, newOptions(opts), nil)
+}
+
+func newOptions(opts []Option) *dialOptions {
+	o := &dialOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// dial connects one hop. chain holds the asset IDs already on the path, to
+// catch jump hosts that loop back.
+func (d *Dialer) dial(ctx context.Context, cfg *models.SSHConfig, o *dialOptions, chain []string) (*ssh.Client, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("SSH host not specified")
+	}
+	if cfg.Username == "" {
+		return nil, fmt.Errorf("SSH username not specified")
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 22
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	auth, releaseAuth := d.authMethods(cfg, o)
+	defer releaseAuth()
+	clientConfig := &ssh.ClientConfig{
+		User:            cfg.Username,
+		Auth:            auth,
+		HostKeyCallback: d.hostKeys.Callback(cfg.StrictHostKey, o.confirm),
+		Timeout:         timeout,
+	}
+	// Ask for the key types on record so a host with several keys isn't
+	// mistaken for a changed one
+	if algos := d.hostKeys.Algorithms(addr); len(algos) > 0 {
+		clientConfig.HostKeyAlgorithms = algos
+	}
+
+	var (
+		conn net.Conn
+		jump *ssh.Client
+		err  error
+	)
+	switch mode := connectionMode(cfg); mode {
+	case ModeJump:
+		jump, err = d.dialJump(ctx, cfg.JumpAssetID, o, chain)
+		if err != nil {
+			return nil, err
+		}
+		conn, err = jump.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			_ = jump.Close()
+			return nil, fmt.Errorf("failed to dial %s through jump host: %w", addr, err)
+		}
+	case ModeProxy:
+		conn, err = dialProxy(ctx, cfg, addr, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect via proxy: %w", err)
+		}
+	case ModeDirect:
+		dialer := &net.Dialer{Timeout: timeout}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported connection mode: %s", mode)
+	}
+
+	// The handshake may wait on the user to confirm a host key, so it is
+	// bounded by the context rather than the dial timeout
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, clientConfig)
+	close(done)
+	if err != nil {
+		_ = conn.Close()
+		if jump != nil {
+			_ = jump.Close()
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("SSH handshake with %s failed: %w", addr, err)
+	}
+	client := ssh.NewClient(c, chans, reqs)
+
+	if jump != nil {
+		go func() {
+			_ = client.Wait()
+			_ = jump.Close()
+		}()
+	}
+	if cfg.KeepaliveInterval > 0 {
+		go keepalive(client, time.Duration(cfg.KeepaliveInterval)*time.Second)
+	}
+	d.logger.Debug("SSH connection established", "addr", addr, "user", cfg.Username, "chain", chain)
+	return client, nil
+}
+
+// dialJump connects to a jump host asset, itself possibly behind a proxy or
+// another jump host
+func (d *Dialer) dialJump(ctx context.Context, jumpAssetID string, o *dialOptions, chain []string) (*ssh.Client, error) {
+	if jumpAssetID == "" {
+		return nil, fmt.Errorf("jump host asset ID not specified")
+	}
+	for _, id := range chain {
+		if id == jumpAssetID {
+			return nil, fmt.Errorf("jump host chain loops back to asset %s", id)
+		}
+	}
+	if len(chain) > maxJumpHops {
+		return nil, fmt.Errorf("jump host chain is longer than %d hops", maxJumpHops)
+	}
+
+	asset, err := d.assets.GetAsset(jumpAssetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jump host asset: %w", err)
+	}
+	if asset.Type != models.AssetTypeSSH {
+		return nil, fmt.Errorf("jump host %s is not an SSH asset", asset.Name)
+	}
+	var cfg models.SSHConfig
+	if err := asset.GetTypedConfig(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse jump host config: %w", err)
+	}
+	next := append(append([]string(nil), chain...), jumpAssetID)
+	client, err := d.dial(ctx, &cfg, o, next)
+	if err != nil {
+		return nil, fmt.Errorf("jump host %s: %w", asset.Name, err)
+	}
+	return client, nil
+}
+
+// keepalive pings the server until the connection closes. Like OpenSSH's
+// ServerAliveCountMax, a server that doesn't answer for keepaliveMissed
+// intervals is considered gone and the connection is closed.
+func keepalive(client *ssh.Client, interval time.Duration) {
+	closed := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+
+		answered := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			answered <- err
+		}()
+		select {
+		case <-closed:
+			return
+		case err := <-answered:
+			if err == nil {
+				continue
+			}
+		case <-time.After(interval * keepaliveMissed):
+		}
+		_ = client.Close()
+		return
+	}
+}
+
+func connectionMode(cfg *models.SSHConfig) string {
+	mode := strings.TrimSpace(cfg.ConnectionMode)
+	if mode == "" {
+		return ModeDirect
+	}
+	return mode
+}
@@ -0,0 +1,250 @@
+package sshconn
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+)
+
+// dialProxy connects to addr through the config's SOCKS or HTTP proxy
+func dialProxy(ctx context.Context, cfg *models.SSHConfig, addr string, timeout time.Duration) (net.Conn, error) {
+	if cfg.ProxyHost == "" {
+		return nil, fmt.Errorf("proxy host not specified")
+	}
+	port := cfg.ProxyPort
+	if port == 0 {
+		port = 1080
+	}
+	proxyType := cfg.ProxyType
+	if proxyType == "" {
+		proxyType = "socks5"
+	}
+	proxyAddr := net.JoinHostPort(cfg.ProxyHost, strconv.Itoa(port))
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	switch proxyType {
+	case "socks5":
+		err = socks5Connect(conn, addr, cfg.ProxyUsername, cfg.ProxyPassword)
+	case "socks4":
+		err = socks4Connect(ctx, conn, addr, cfg.ProxyUsername)
+	case "http":
+		conn, err = httpConnect(conn, addr, cfg.ProxyUsername, cfg.ProxyPassword)
+	default:
+		err = fmt.Errorf("unsupported proxy type: %s", proxyType)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// socks5Replies describes SOCKS5 reply codes
+var socks5Replies = map[byte]string{
+	0x01: "general failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// socks5Connect runs the SOCKS5 handshake (RFC 1928), authenticating with
+// username and password (RFC 1929) when the proxy asks for it
+func socks5Connect(conn net.Conn, addr, user, pass string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+
+	methods := []byte{0x00}
+	if user != "" {
+		methods = append(methods, 0x02)
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return fmt.Errorf("SOCKS5 handshake failed: %w", err)
+	}
+	resp := make([]byte, 2)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return fmt.Errorf("SOCKS5 handshake failed: %w", err)
+	}
+	if resp[0] != 0x05 {
+		return fmt.Errorf("SOCKS5 version mismatch")
+	}
+	switch resp[1] {
+	case 0x00:
+	case 0x02:
+		if user == "" {
+			return fmt.Errorf("SOCKS5 proxy requires authentication")
+		}
+		if len(user) > 255 || len(pass) > 255 {
+			return fmt.Errorf("SOCKS5 username or password too long")
+		}
+		auth := []byte{0x01, byte(len(user))}
+		auth = append(auth, user...)
+		auth = append(auth, byte(len(pass)))
+		auth = append(auth, pass...)
+		if _, err := conn.Write(auth); err != nil {
+			return fmt.Errorf("SOCKS5 auth failed: %w", err)
+		}
+		if _, err := io.ReadFull(conn, resp); err != nil {
+			return fmt.Errorf("SOCKS5 auth failed: %w", err)
+		}
+		if resp[1] != 0x00 {
+			return fmt.Errorf("SOCKS5 auth rejected")
+		}
+	default:
+		return fmt.Errorf("SOCKS5 proxy accepts none of the offered auth methods")
+	}
+
+	req := []byte{0x05, 0x01, 0x00}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("SOCKS5 host name too long")
+		}
+		req = append(req, 0x03, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, 0x01)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, 0x04)
+		req = append(req, ip.To16()...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("SOCKS5 connect failed: %w", err)
+	}
+
+	// Reply: VER REP RSV ATYP BND.ADDR BND.PORT
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return fmt.Errorf("SOCKS5 connect failed: %w", err)
+	}
+	if head[1] != 0x00 {
+		reason, ok := socks5Replies[head[1]]
+		if !ok {
+			reason = fmt.Sprintf("reply code %d", head[1])
+		}
+		return fmt.Errorf("SOCKS5 connect to %s rejected: %s", addr, reason)
+	}
+	var bound int
+	switch head[3] {
+	case 0x01:
+		bound = net.IPv4len
+	case 0x04:
+		bound = net.IPv6len
+	case 0x03:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return fmt.Errorf("SOCKS5 connect failed: %w", err)
+		}
+		bound = int(n[0])
+	default:
+		return fmt.Errorf("SOCKS5 reply has unknown address type %d", head[3])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, bound+2)); err != nil {
+		return fmt.Errorf("SOCKS5 connect failed: %w", err)
+	}
+	return nil
+}
+
+// socks4Connect runs the SOCKS4 handshake. SOCKS4 only carries IPv4
+// addresses, so the host is resolved locally.
+func socks4Connect(ctx context.Context, conn net.Conn, addr, user string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", host)
+	if err != nil || len(ips) == 0 {
+		return fmt.Errorf("failed to resolve %s to an IPv4 address: %w", host, err)
+	}
+
+	req := []byte{0x04, 0x01}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	req = append(req, ips[0].To4()...)
+	req = append(req, user...)
+	req = append(req, 0x00)
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("SOCKS4 handshake failed: %w", err)
+	}
+	resp := make([]byte, 8)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return fmt.Errorf("SOCKS4 handshake failed: %w", err)
+	}
+	if resp[1] != 0x5a {
+		return fmt.Errorf("SOCKS4 connect rejected: %d", resp[1])
+	}
+	return nil
+}
+
+// httpConnect opens a tunnel with HTTP CONNECT. Bytes the proxy sent past
+// the response, such as the SSH server banner, stay readable from the
+// returned conn.
+func httpConnect(conn net.Conn, addr, user, pass string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		return conn, fmt.Errorf("failed to send CONNECT: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return conn, fmt.Errorf("failed to read CONNECT response: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return conn, fmt.Errorf("HTTP CONNECT failed: %s", resp.Status)
+	}
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn reads through a reader that may hold bytes already received
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
@@ -0,0 +1,96 @@
+package sshconn
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+// serve accepts one connection on a local listener and hands it to handle
+func serve(t *testing.T, handle func(net.Conn)) net.Conn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		handle(conn)
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestSOCKS5Connect(t *testing.T) {
+	got := make(chan []byte, 1)
+	conn := serve(t, func(c net.Conn) {
+		greeting := make([]byte, 4)
+		_, _ = io.ReadFull(c, greeting)
+		_, _ = c.Write([]byte{0x05, 0x02})
+		auth := make([]byte, 1+1+4+1+6)
+		_, _ = io.ReadFull(c, auth)
+		_, _ = c.Write([]byte{0x01, 0x00})
+		req := make([]byte, 4+1+len("example.com")+2)
+		_, _ = io.ReadFull(c, req)
+		got <- req
+		// Reply with a domain-typed bound address
+		_, _ = c.Write([]byte{0x05, 0x00, 0x00, 0x03, 0x02, 'h', 'i', 0x00, 0x16})
+		_, _ = c.Write([]byte("SSH-2.0-test\r\n"))
+	})
+
+	if err := socks5Connect(conn, "example.com:22", "user", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	req := <-got
+	if req[3] != 0x03 || string(req[5:16]) != "example.com" || req[16] != 0 || req[17] != 22 {
+		t.Fatalf("request = %v", req)
+	}
+	banner, _ := bufio.NewReader(conn).ReadString('\n')
+	if banner != "SSH-2.0-test\r\n" {
+		t.Fatalf("banner = %q", banner)
+	}
+}
+
+func TestSOCKS5ConnectRejected(t *testing.T) {
+	conn := serve(t, func(c net.Conn) {
+		_, _ = io.ReadFull(c, make([]byte, 3))
+		_, _ = c.Write([]byte{0x05, 0x00})
+		_, _ = io.ReadFull(c, make([]byte, 10))
+		_, _ = c.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+	})
+	if err := socks5Connect(conn, "10.0.0.1:22", "", ""); err == nil {
+		t.Fatal("expected refusal")
+	}
+}
+
+func TestHTTPConnectKeepsBufferedBytes(t *testing.T) {
+	conn := serve(t, func(c net.Conn) {
+		req, err := http.ReadRequest(bufio.NewReader(c))
+		if err != nil || req.Method != http.MethodConnect || req.Host != "db:22" {
+			_, _ = c.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+			return
+		}
+		// The server banner arrives in the same packet as the response
+		_, _ = c.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\nSSH-2.0-test\r\n"))
+	})
+
+	tunneled, err := httpConnect(conn, "db:22", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	banner, _ := bufio.NewReader(tunneled).ReadString('\n')
+	if banner != "SSH-2.0-test\r\n" {
+		t.Fatalf("banner = %q", banner)
+	}
+}
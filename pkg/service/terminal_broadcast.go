@@ -0,0 +1,452 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/event"
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Broadcast group errors
+var (
+	ErrBroadcastGroupNotFound  = errors.New("broadcast group not found")
+	ErrBroadcastMemberNotFound = errors.New("session is not a member of the broadcast group")
+)
+
+// BroadcastMember is a session in a broadcast group. A disabled member stays
+// in the group but gets none of its input.
+type BroadcastMember struct {
+	SessionID string `json:"session_id"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// BroadcastGroup types the same input into several sessions; each keeps its
+// own output
+type BroadcastGroup struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Members   []BroadcastMember `json:"members"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+func (g *BroadcastGroup) clone() BroadcastGroup {
+	c := *g
+	c.Members = append([]BroadcastMember(nil), g.Members...)
+	return c
+}
+
+func (g *BroadcastGroup) member(sessionID string) *BroadcastMember {
+	for i := range g.Members {
+		if g.Members[i].SessionID == sessionID {
+			return &g.Members[i]
+		}
+	}
+	return nil
+}
+
+// BroadcastStore keeps the broadcast groups in memory. Members are session
+// IDs, which clients pick, so a group may name sessions that are still
+// connecting; sessions leave their groups when they end.
+type BroadcastStore struct {
+	mu     sync.Mutex
+	groups map[string]*BroadcastGroup
+}
+
+// NewBroadcastStore creates an empty broadcast store
+func NewBroadcastStore() *BroadcastStore {
+	return &BroadcastStore{groups: make(map[string]*BroadcastGroup)}
+}
+
+// Create makes a group of sessionIDs, all enabled
+func (s *BroadcastStore) Create(name string, sessionIDs []string) BroadcastGroup {
+	g := &BroadcastGroup{ID: uuid.New().String(), Name: name, Members: []BroadcastMember{}, CreatedAt: time.Now()}
+	addMembers(g, sessionIDs)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups[g.ID] = g
+	return g.clone()
+}
+
+// Get returns a group
+func (s *BroadcastStore) Get(id string) (BroadcastGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[id]
+	if !ok {
+		return BroadcastGroup{}, ErrBroadcastGroupNotFound
+	}
+	return g.clone(), nil
+}
+
+// List returns the groups, oldest first
+func (s *BroadcastStore) List() []BroadcastGroup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]BroadcastGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		result = append(result, g.clone())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
+
+// Delete removes a group and returns it
+func (s *BroadcastStore) Delete(id string) (BroadcastGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[id]
+	if !ok {
+		return BroadcastGroup{}, ErrBroadcastGroupNotFound
+	}
+	delete(s.groups, id)
+	return g.clone(), nil
+}
+
+// AddMembers adds sessions to a group, enabled; sessions already in it are
+// left as they are
+func (s *BroadcastStore) AddMembers(id string, sessionIDs []string) (BroadcastGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[id]
+	if !ok {
+		return BroadcastGroup{}, ErrBroadcastGroupNotFound
+	}
+	addMembers(g, sessionIDs)
+	return g.clone(), nil
+}
+
+func addMembers(g *BroadcastGroup, sessionIDs []string) {
+	for _, sessionID := range sessionIDs {
+		if sessionID != "" && g.member(sessionID) == nil {
+			g.Members = append(g.Members, BroadcastMember{SessionID: sessionID, Enabled: true})
+		}
+	}
+}
+
+// RemoveMember takes a session out of a group
+func (s *BroadcastStore) RemoveMember(id, sessionID string) (BroadcastGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[id]
+	if !ok {
+		return BroadcastGroup{}, ErrBroadcastGroupNotFound
+	}
+	if g.member(sessionID) == nil {
+		return BroadcastGroup{}, ErrBroadcastMemberNotFound
+	}
+	g.Members = removeMember(g.Members, sessionID)
+	return g.clone(), nil
+}
+
+func removeMember(members []BroadcastMember, sessionID string) []BroadcastMember {
+	result := members[:0]
+	for _, m := range members {
+		if m.SessionID != sessionID {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// SetEnabled turns a member's share of the group's input on or off
+func (s *BroadcastStore) SetEnabled(id, sessionID string, enabled bool) (BroadcastGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[id]
+	if !ok {
+		return BroadcastGroup{}, ErrBroadcastGroupNotFound
+	}
+	m := g.member(sessionID)
+	if m == nil {
+		return BroadcastGroup{}, ErrBroadcastMemberNotFound
+	}
+	m.Enabled = enabled
+	return g.clone(), nil
+}
+
+// Peers returns the sessions that input typed into sessionID is mirrored
+// to: the other enabled members of the groups in which it is enabled
+func (s *BroadcastStore) Peers(sessionID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var peers []string
+	seen := map[string]bool{sessionID: true}
+	for _, g := range s.groups {
+		if m := g.member(sessionID); m == nil || !m.Enabled {
+			continue
+		}
+		for _, m := range g.Members {
+			if m.Enabled && !seen[m.SessionID] {
+				seen[m.SessionID] = true
+				peers = append(peers, m.SessionID)
+			}
+		}
+	}
+	return peers
+}
+
+// Markers describes the groups sessionID is in, for its client
+func (s *BroadcastStore) Markers(sessionID string) []BroadcastMarker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var groups []*BroadcastGroup
+	for _, g := range s.groups {
+		if g.member(sessionID) != nil {
+			groups = append(groups, g)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].CreatedAt.Before(groups[j].CreatedAt) })
+	markers := make([]BroadcastMarker, len(groups))
+	for i, g := range groups {
+		markers[i] = BroadcastMarker{GroupID: g.ID, Name: g.Name, Enabled: g.member(sessionID).Enabled, Members: len(g.Members)}
+	}
+	return markers
+}
+
+// RemoveSession takes a session that ended out of its groups
+func (s *BroadcastStore) RemoveSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range s.groups {
+		g.Members = removeMember(g.Members, sessionID)
+	}
+}
+
+// RenameSession moves a session's memberships to its new ID
+func (s *BroadcastStore) RenameSession(oldSessionID, newSessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range s.groups {
+		if m := g.member(oldSessionID); m != nil {
+			if g.member(newSessionID) != nil {
+				g.Members = removeMember(g.Members, oldSessionID)
+				continue
+			}
+			m.SessionID = newSessionID
+		}
+	}
+}
+
+// BroadcastMarker tells a member's client which groups it is in, so it can
+// show that what is typed there goes to other sessions too
+type BroadcastMarker struct {
+	GroupID string `json:"group_id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Members int    `json:"members"`
+}
+
+// typeInput writes what the owner typed, and mirrors it to the sessions
+// broadcasting along with this one
+func (t *Terminal) typeInput(data []byte) {
+	t.writeToTerminal(data)
+	for _, sessionID := range GlobalTerminalManager.Broadcasts().Peers(t.sessionID) {
+		if peer := GlobalTerminalManager.GetTerminal(sessionID); peer != nil {
+			peer.writeToTerminal(data)
+		}
+	}
+}
+
+// writeBroadcastMarker sends the session's broadcast groups to conn; the
+// caller holds writeMutex
+func (t *Terminal) writeBroadcastMarker(conn *websocket.Conn) {
+	msg, err := json.Marshal(WebSocketMessage{
+		Type: "broadcast",
+		Data: map[string]interface{}{"groups": GlobalTerminalManager.Broadcasts().Markers(t.sessionID)},
+	})
+	if err != nil {
+		t.logger.Error("Failed to marshal broadcast marker", "error", err)
+		return
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+		t.logger.Debug("Failed to send broadcast marker", "error", err)
+	}
+}
+
+// notifyBroadcast updates the markers of sessions whose groups changed
+func notifyBroadcast(groupID string, sessionIDs []string) {
+	for _, sessionID := range sessionIDs {
+		term := GlobalTerminalManager.GetTerminal(sessionID)
+		if term == nil {
+			continue
+		}
+		term.writeMutex.Lock()
+		if term.conn != nil {
+			term.writeBroadcastMarker(term.conn)
+		}
+		term.writeMutex.Unlock()
+	}
+	event.Emit(event.TerminalBroadcastChangedEvent{GroupID: groupID, SessionIDs: sessionIDs})
+}
+
+func memberIDs(g BroadcastGroup) []string {
+	ids := make([]string, len(g.Members))
+	for i, m := range g.Members {
+		ids[i] = m.SessionID
+	}
+	return ids
+}
+
+func broadcastError(c *gin.Context, err error) {
+	c.JSON(http.StatusNotFound, models.Response{Code: 404, Message: err.Error()})
+}
+
+// CreateBroadcastGroupRequest creates a broadcast group
+type CreateBroadcastGroupRequest struct {
+	Name       string   `json:"name"`
+	SessionIDs []string `json:"session_ids"`
+}
+
+// BroadcastMembersRequest adds sessions to a broadcast group
+type BroadcastMembersRequest struct {
+	SessionIDs []string `json:"session_ids" binding:"required"`
+}
+
+// SetBroadcastMemberRequest enables or disables a broadcast group member
+type SetBroadcastMemberRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// BroadcastInputRequest is input for every enabled member of a group
+type BroadcastInputRequest struct {
+	Data string `json:"data" binding:"required"`
+}
+
+// BroadcastResult reports how input reached one member
+type BroadcastResult struct {
+	SessionID string `json:"session_id"`
+	Sent      bool   `json:"sent"`
+	Error     string `json:"error,omitempty"`
+}
+
+// CreateBroadcastGroup creates a broadcast group of sessions. They need not
+// be running yet, so a client may group the tabs it is about to open.
+// POST /terminal/broadcast
+func (s *TerminalService) CreateBroadcastGroup(c *gin.Context) {
+	var req CreateBroadcastGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: err.Error()})
+		return
+	}
+	if req.Name == "" {
+		req.Name = "Broadcast"
+	}
+	g := GlobalTerminalManager.Broadcasts().Create(req.Name, req.SessionIDs)
+	s.logger.Info("Created broadcast group", "groupID", g.ID, "members", len(g.Members))
+	notifyBroadcast(g.ID, memberIDs(g))
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: g})
+}
+
+// ListBroadcastGroups lists the broadcast groups
+// GET /terminal/broadcast
+func (s *TerminalService) ListBroadcastGroups(c *gin.Context) {
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: GlobalTerminalManager.Broadcasts().List()})
+}
+
+// GetBroadcastGroup returns a broadcast group
+// GET /terminal/broadcast/:groupId
+func (s *TerminalService) GetBroadcastGroup(c *gin.Context) {
+	g, err := GlobalTerminalManager.Broadcasts().Get(c.Param("groupId"))
+	if err != nil {
+		broadcastError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: g})
+}
+
+// DeleteBroadcastGroup removes a broadcast group; its sessions keep running
+// DELETE /terminal/broadcast/:groupId
+func (s *TerminalService) DeleteBroadcastGroup(c *gin.Context) {
+	g, err := GlobalTerminalManager.Broadcasts().Delete(c.Param("groupId"))
+	if err != nil {
+		broadcastError(c, err)
+		return
+	}
+	s.logger.Info("Deleted broadcast group", "groupID", g.ID)
+	notifyBroadcast(g.ID, memberIDs(g))
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK"})
+}
+
+// AddBroadcastMembers adds sessions to a broadcast group
+// POST /terminal/broadcast/:groupId/members
+func (s *TerminalService) AddBroadcastMembers(c *gin.Context) {
+	var req BroadcastMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: err.Error()})
+		return
+	}
+	g, err := GlobalTerminalManager.Broadcasts().AddMembers(c.Param("groupId"), req.SessionIDs)
+	if err != nil {
+		broadcastError(c, err)
+		return
+	}
+	notifyBroadcast(g.ID, memberIDs(g))
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: g})
+}
+
+// RemoveBroadcastMember takes a session out of a broadcast group
+// DELETE /terminal/broadcast/:groupId/members/:sessionId
+func (s *TerminalService) RemoveBroadcastMember(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	g, err := GlobalTerminalManager.Broadcasts().RemoveMember(c.Param("groupId"), sessionID)
+	if err != nil {
+		broadcastError(c, err)
+		return
+	}
+	notifyBroadcast(g.ID, append(memberIDs(g), sessionID))
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: g})
+}
+
+// SetBroadcastMember enables or disables a member of a broadcast group
+// PUT /terminal/broadcast/:groupId/members/:sessionId
+func (s *TerminalService) SetBroadcastMember(c *gin.Context) {
+	var req SetBroadcastMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: err.Error()})
+		return
+	}
+	g, err := GlobalTerminalManager.Broadcasts().SetEnabled(c.Param("groupId"), c.Param("sessionId"), *req.Enabled)
+	if err != nil {
+		broadcastError(c, err)
+		return
+	}
+	notifyBroadcast(g.ID, memberIDs(g))
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: g})
+}
+
+// BroadcastInput types data into every enabled member of a group
+// POST /terminal/broadcast/:groupId/input
+func (s *TerminalService) BroadcastInput(c *gin.Context) {
+	var req BroadcastInputRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: err.Error()})
+		return
+	}
+	g, err := GlobalTerminalManager.Broadcasts().Get(c.Param("groupId"))
+	if err != nil {
+		broadcastError(c, err)
+		return
+	}
+	results := make([]BroadcastResult, 0, len(g.Members))
+	for _, m := range g.Members {
+		if !m.Enabled {
+			continue
+		}
+		result := BroadcastResult{SessionID: m.SessionID, Sent: true}
+		if err := GlobalTerminalManager.SendInput(m.SessionID, []byte(req.Data)); err != nil {
+			result.Sent = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: results})
+}
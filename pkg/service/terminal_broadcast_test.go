@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+func TestBroadcastStore(t *testing.T) {
+	s := NewBroadcastStore()
+	web := s.Create("web", []string{"a", "b", "c", "a", ""})
+	if len(web.Members) != 3 {
+		t.Fatalf("Create() members = %+v", web.Members)
+	}
+	db := s.Create("db", []string{"c", "d"})
+
+	peers := func(sessionID string) []string {
+		p := s.Peers(sessionID)
+		sort.Strings(p)
+		return p
+	}
+	if got := peers("c"); len(got) != 3 || got[0] != "a" || got[2] != "d" {
+		t.Fatalf("Peers(c) = %v", got)
+	}
+
+	// A disabled member neither sends nor receives
+	if _, err := s.SetEnabled(web.ID, "b", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := peers("a"); len(got) != 1 || got[0] != "c" {
+		t.Fatalf("Peers(a) = %v", got)
+	}
+	if got := peers("b"); len(got) != 0 {
+		t.Fatalf("Peers(b) = %v", got)
+	}
+	if _, err := s.SetEnabled(web.ID, "d", true); !errors.Is(err, ErrBroadcastMemberNotFound) {
+		t.Fatalf("SetEnabled() of a non-member = %v", err)
+	}
+
+	if got := s.Markers("c"); len(got) != 2 || got[0].GroupID != web.ID || got[1].Name != "db" || !got[1].Enabled {
+		t.Fatalf("Markers(c) = %+v", got)
+	}
+	if got := s.Markers("b"); len(got) != 1 || got[0].Enabled || got[0].Members != 3 {
+		t.Fatalf("Markers(b) = %+v", got)
+	}
+
+	s.RenameSession("d", "e")
+	s.RemoveSession("c")
+	if g, _ := s.Get(db.ID); len(g.Members) != 1 || g.Members[0].SessionID != "e" {
+		t.Fatalf("Get() = %+v", g)
+	}
+
+	if _, err := s.Delete(web.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddMembers(web.ID, []string{"x"}); !errors.Is(err, ErrBroadcastGroupNotFound) {
+		t.Fatalf("AddMembers() to a deleted group = %v", err)
+	}
+	if got := s.List(); len(got) != 1 || got[0].ID != db.ID {
+		t.Fatalf("List() = %+v", got)
+	}
+}
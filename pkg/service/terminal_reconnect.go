@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// Reconnect backoff defaults, in seconds
+const (
+	defaultReconnectBackoff    = 1
+	defaultReconnectMaxBackoff = 30
+)
+
+// defaultMultiplexerSession names the tmux or screen session the shell is
+// kept in when the asset doesn't
+const defaultMultiplexerSession = "choraleia"
+
+// sshExitTimeout bounds how long a dropped connection is waited on for the
+// exit status that tells it from a shell that exited
+const sshExitTimeout = 5 * time.Second
+
+var unsafeSessionName = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// multiplexerCommand is the command that runs the shell inside the tmux or
+// screen session of the reconnect policy, creating it or attaching to it.
+// Without the multiplexer installed it falls back to a login shell.
+func multiplexerCommand(policy *models.SSHReconnect) string {
+	if policy == nil || policy.Multiplexer == "" {
+		return ""
+	}
+	name := unsafeSessionName.ReplaceAllString(policy.SessionName, "")
+	if name == "" {
+		name = defaultMultiplexerSession
+	}
+	tmux := fmt.Sprintf("command -v tmux >/dev/null 2>&1 && exec tmux new-session -A -s %s; ", name)
+	screen := fmt.Sprintf("command -v screen >/dev/null 2>&1 && exec screen -D -R -S %s; ", name)
+	var cmd string
+	switch policy.Multiplexer {
+	case "tmux":
+		cmd = tmux
+	case "screen":
+		cmd = screen
+	default:
+		cmd = tmux + screen
+	}
+	return cmd + `exec "$SHELL" -l`
+}
+
+// sshDropped reports whether the SSH session ended because the connection
+// was lost rather than because its shell exited
+func (t *Terminal) sshDropped() bool {
+	t.sshMu.RLock()
+	session := t.sshSession
+	t.sshMu.RUnlock()
+	if session == nil {
+		return false
+	}
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+	select {
+	case err := <-done:
+		var exit *ssh.ExitError
+		return err != nil && !errors.As(err, &exit)
+	case <-time.After(sshExitTimeout):
+		return true
+	case <-t.sessionCtx.Done():
+		return false
+	}
+}
+
+// reconnect redials a dropped SSH connection as the asset's reconnect
+// policy allows, telling the client about each attempt. It reports whether
+// the session is connected again.
+func (t *Terminal) reconnect() bool {
+	if t.connType != ConnectionTypeSSH || t.assetID == "local" {
+		return false
+	}
+	asset, err := t.assetService.GetAsset(t.assetID)
+	if err != nil {
+		return false
+	}
+	var cfg models.SSHConfig
+	if err := asset.GetTypedConfig(&cfg); err != nil {
+		return false
+	}
+	policy := cfg.Reconnect
+	if policy == nil || policy.MaxAttempts <= 0 || !t.sshDropped() {
+		return false
+	}
+	t.closeSSH()
+
+	backoff := time.Duration(policy.InitialBackoff) * time.Second
+	if backoff <= 0 {
+		backoff = defaultReconnectBackoff * time.Second
+	}
+	maxBackoff := time.Duration(policy.MaxBackoff) * time.Second
+	if maxBackoff <= 0 {
+		maxBackoff = defaultReconnectMaxBackoff * time.Second
+	}
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		t.sendConnectionStatus("reconnecting", fmt.Sprintf("Connection lost, reconnecting (%d/%d)", attempt, policy.MaxAttempts))
+		select {
+		case <-time.After(backoff):
+		case <-t.sessionCtx.Done():
+			return false
+		}
+
+		err := t.openSSH(t.sessionCtx, asset, &cfg, true)
+		if err == nil {
+			t.logger.Info("SSH connection restored", "assetId", t.assetID, "attempt", attempt)
+			t.sendConnectionStatus("reconnected", "Reconnected")
+			return true
+		}
+		t.logger.Warn("Failed to reconnect SSH session", "error", err, "assetId", t.assetID, "attempt", attempt)
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+	t.sendConnectionStatus("error", fmt.Sprintf("Could not reconnect after %d attempts", policy.MaxAttempts))
+	return false
+}
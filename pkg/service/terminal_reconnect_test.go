@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/choraleia/choraleia/pkg/models"
+)
+
+func TestMultiplexerCommand(t *testing.T) {
+	tests := []struct {
+		policy *models.SSHReconnect
+		want   string
+	}{
+		{nil, ""},
+		{&models.SSHReconnect{MaxAttempts: 3}, ""},
+		{
+			&models.SSHReconnect{Multiplexer: "tmux", SessionName: "work"},
+			`command -v tmux >/dev/null 2>&1 && exec tmux new-session -A -s work; exec "$SHELL" -l`,
+		},
+		{
+			&models.SSHReconnect{Multiplexer: "screen", SessionName: "a b;rm -rf"},
+			`command -v screen >/dev/null 2>&1 && exec screen -D -R -S abrm-rf; exec "$SHELL" -l`,
+		},
+		{
+			&models.SSHReconnect{Multiplexer: "auto"},
+			`command -v tmux >/dev/null 2>&1 && exec tmux new-session -A -s choraleia; ` +
+				`command -v screen >/dev/null 2>&1 && exec screen -D -R -S choraleia; exec "$SHELL" -l`,
+		},
+	}
+	for _, tt := range tests {
+		if got := multiplexerCommand(tt.policy); got != tt.want {
+			t.Errorf("multiplexerCommand(%+v) = %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
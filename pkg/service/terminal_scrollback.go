@@ -0,0 +1,95 @@
+package service
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+// Scrollback keeps the most recent output of a terminal session in a
+// fixed-size ring, so a client that reattaches can replay it and agent
+// tools can read what a command printed
+type Scrollback struct {
+	mu    sync.RWMutex
+	buf   []byte
+	start int   // index of the oldest byte in buf
+	size  int   // bytes held
+	total int64 // bytes ever written
+}
+
+// NewScrollback creates a scrollback holding up to capacity bytes
+func NewScrollback(capacity int) *Scrollback {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Scrollback{buf: make([]byte, capacity)}
+}
+
+// Write appends output, dropping the oldest bytes once the ring is full
+func (s *Scrollback) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(p)
+	s.total += int64(n)
+	capacity := len(s.buf)
+	if n >= capacity {
+		copy(s.buf, p[n-capacity:])
+		s.start, s.size = 0, capacity
+		return n, nil
+	}
+	end := (s.start + s.size) % capacity
+	copied := copy(s.buf[end:], p)
+	copy(s.buf, p[copied:])
+	if s.size += n; s.size > capacity {
+		s.start = (s.start + s.size - capacity) % capacity
+		s.size = capacity
+	}
+	return n, nil
+}
+
+// Offset is the number of bytes written so far. Pass it to Since to read
+// the output that follows.
+func (s *Scrollback) Offset() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.total
+}
+
+// Bytes returns a copy of the held output
+func (s *Scrollback) Bytes() []byte {
+	return s.Since(0)
+}
+
+// Since returns the output written after offset. Output that has already
+// left the ring is skipped, and so are the continuation bytes of a UTF-8
+// character cut at the start.
+func (s *Scrollback) Since(offset int64) []byte {
+	out, _ := s.Read(offset)
+	return out
+}
+
+// Read is Since that also returns the offset that follows the output
+func (s *Scrollback) Read(offset int64) ([]byte, int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	oldest := s.total - int64(s.size)
+	trimmed := offset < oldest
+	if trimmed {
+		offset = oldest
+	}
+	n := int(s.total - offset)
+	if n <= 0 {
+		return nil, s.total
+	}
+	out := make([]byte, n)
+	from := (s.start + s.size - n) % len(s.buf)
+	copied := copy(out, s.buf[from:])
+	copy(out[copied:], s.buf)
+	if trimmed && oldest > 0 {
+		for i := 0; i < utf8.UTFMax-1 && len(out) > 0 && !utf8.RuneStart(out[0]); i++ {
+			out = out[1:]
+		}
+	}
+	return out, s.total
+}
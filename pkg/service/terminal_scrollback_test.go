@@ -0,0 +1,41 @@
+package service
+
+import "testing"
+
+func TestScrollback(t *testing.T) {
+	s := NewScrollback(8)
+	_, _ = s.Write([]byte("abc"))
+	offset := s.Offset()
+	_, _ = s.Write([]byte("defgh"))
+	if got := string(s.Since(offset)); got != "defgh" {
+		t.Fatalf("Since(%d) = %q, want %q", offset, got, "defgh")
+	}
+
+	// Wrapping drops the oldest bytes
+	_, _ = s.Write([]byte("ijk"))
+	if got := string(s.Bytes()); got != "defghijk" {
+		t.Fatalf("Bytes() = %q, want %q", got, "defghijk")
+	}
+	if got := string(s.Since(offset)); got != "defghijk" {
+		t.Fatalf("Since(%d) = %q, want %q", offset, got, "defghijk")
+	}
+	if got := s.Since(s.Offset()); len(got) != 0 {
+		t.Fatalf("Since(end) = %q, want empty", got)
+	}
+	if got, end := s.Read(offset); string(got) != "defghijk" || end != s.Offset() {
+		t.Fatalf("Read(%d) = %q, %d", offset, got, end)
+	}
+
+	// A write larger than the ring keeps its tail
+	_, _ = s.Write([]byte("0123456789"))
+	if got := string(s.Bytes()); got != "23456789" {
+		t.Fatalf("Bytes() = %q, want %q", got, "23456789")
+	}
+
+	// A character cut by the ring is not replayed half
+	s = NewScrollback(3)
+	_, _ = s.Write([]byte("a€b"))
+	if got := string(s.Bytes()); got != "b" {
+		t.Fatalf("Bytes() = %q, want %q", got, "b")
+	}
+}
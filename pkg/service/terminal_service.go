@@ -1,24 +1,28 @@
 package service
 
 import (
-	"bufio"
 	"context"
 	"embed"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
-	"net"
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/choraleia/choraleia/pkg/config"
 	"github.com/choraleia/choraleia/pkg/message"
 	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service/hostkey"
+	"github.com/choraleia/choraleia/pkg/service/recording"
+	"github.com/choraleia/choraleia/pkg/service/shellintegration"
+	"github.com/choraleia/choraleia/pkg/service/sshconn"
+	"github.com/choraleia/choraleia/pkg/service/termtransfer"
 	"github.com/choraleia/choraleia/pkg/utils"
 	"github.com/gin-gonic/gin"
 
@@ -39,6 +43,11 @@ const (
 type TerminalService struct {
 	assetService *AssetService
 	logger       *slog.Logger
+	idleTimeout  time.Duration
+	recordings   *recording.Store
+	recordAll    bool
+	tasks        *TaskService
+	triggers     *TriggerService
 }
 
 // Terminal struct
@@ -46,6 +55,7 @@ type Terminal struct {
 	ctx          context.Context
 	conn         *websocket.Conn
 	assetService *AssetService
+	dialer       *sshconn.Dialer
 	assetID      string
 	sessionID    string // session ID field
 	logger       *slog.Logger
@@ -55,7 +65,9 @@ type Terminal struct {
 	localCmd *pty.Cmd
 	localTty pty.Pty
 
-	// SSH connection related
+	// SSH connection related; replaced when a dropped connection is
+	// redialed, under sshMu
+	sshMu      sync.RWMutex
 	sshClient  *ssh.Client
 	sshSession *ssh.Session
 	sshStdin   io.WriteCloser
@@ -73,8 +85,58 @@ type Terminal struct {
 	readyChan  chan struct{}
 	readyOnce  sync.Once
 	writeMutex sync.Mutex
+
+	// The session outlives its WebSocket: output goes to the scrollback
+	// while no client is attached, until one reattaches or idleTimeout passes.
+	// conn, named, idleTimer and detachedAt are guarded by writeMutex.
+	sessionCtx    context.Context
+	cancelSession context.CancelFunc
+	idleTimeout   time.Duration
+	named         bool
+	idleTimer     *time.Timer
+	detachedAt    time.Time
+	pumpOnce      sync.Once
+	closeOnce     sync.Once
+
+	// Sessions are recorded when recordAll is set or their asset asks for it
+	recordings *recording.Store
+	recordAll  bool
+	recorder   *recording.Recorder
+
+	// gate hides the echo of the shell integration hooks; guarded by writeMutex
+	gate *shellintegration.Gate
+
+	// Clients that joined through a share token, besides the owner in conn;
+	// guarded by writeMutex
+	viewers map[*websocket.Conn]Share
+
+	// An rz/sz or trz/tsz transfer found in the output takes the terminal
+	// over until it ends; transfer and detector are guarded by transferMu,
+	// which is taken before writeMutex. Its progress shows in tasks.
+	transferMu sync.Mutex
+	transfer   *transfer
+	detector   termtransfer.Detector
+	tasks      *TaskService
+
+	// Trigger rules run over the output; triggerScanner is used by the
+	// output loop only
+	triggers       *TriggerService
+	triggerScanner triggerScanner
 }
 
+// shellIntegrationTimeout bounds how long output is held back waiting for
+// the shell integration hooks to report ready
+const shellIntegrationTimeout = 3 * time.Second
+
+// promptTimeout bounds how long a connection waits for the user to confirm
+// an unknown host key or answer an authentication prompt
+const promptTimeout = 2 * time.Minute
+
+// terminalReadLimit bounds the messages read from terminal clients. The
+// largest is the answer to a file transfer read of termtransfer.MaxRead
+// bytes, base64-encoded.
+const terminalReadLimit = 32 * 1024
+
 // WebSocketMessage format
 type WebSocketMessage struct {
 	Type string      `json:"type"`
@@ -125,9 +187,35 @@ func NewTerminalService(assetService *AssetService) *TerminalService {
 	return &TerminalService{
 		assetService: assetService,
 		logger:       utils.GetLogger(),
+		idleTimeout:  config.DefaultTerminalIdleTimeout,
 	}
 }
 
+// SetSessionLimits sets how long a session without a client is kept and how
+// much output each session buffers for reattaching
+func (s *TerminalService) SetSessionLimits(idleTimeout time.Duration, scrollbackBytes int) {
+	s.idleTimeout = idleTimeout
+	GlobalTerminalManager.SetScrollbackSize(scrollbackBytes)
+}
+
+// SetRecording sets where session recordings go and whether every session
+// is recorded, rather than only those of assets with recording turned on
+func (s *TerminalService) SetRecording(store *recording.Store, recordAll bool) {
+	s.recordings = store
+	s.recordAll = recordAll
+}
+
+// SetTaskService sets where the progress of file transfers in terminals
+// shows
+func (s *TerminalService) SetTaskService(tasks *TaskService) {
+	s.tasks = tasks
+}
+
+// SetTriggers sets the trigger rules run over terminal output
+func (s *TerminalService) SetTriggers(triggers *TriggerService) {
+	s.triggers = triggers
+}
+
 func (s *TerminalService) RunTerminal(c *gin.Context) {
 	assetID := c.Param("assetId")
 	if assetID == "" {
@@ -180,7 +268,7 @@ func (s *TerminalService) RunTerminal(c *gin.Context) {
 	s.logger.Debug("WebSocket connection established", "assetId", assetID)
 
 	// Configure connection parameters
-	conn.SetReadLimit(32768)
+	conn.SetReadLimit(terminalReadLimit)
 	conn.SetPongHandler(func(string) error {
 		_ = conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 		return nil
@@ -206,6 +294,10 @@ func (s *TerminalService) RunTerminal(c *gin.Context) {
 
 	// Create new terminal instance with asset ID
 	term := NewTerminal(c.Request.Context(), conn, s.assetService, assetID)
+	term.idleTimeout = s.idleTimeout
+	term.recordings, term.recordAll = s.recordings, s.recordAll
+	term.tasks = s.tasks
+	term.triggers = s.triggers
 
 	// Start connection based on asset type
 	if err := term.Start(); err != nil {
@@ -295,7 +387,7 @@ func (s *TerminalService) RunDockerTerminal(c *gin.Context) {
 	defer conn.Close()
 
 	// Configure connection
-	conn.SetReadLimit(32768)
+	conn.SetReadLimit(terminalReadLimit)
 	conn.SetPongHandler(func(string) error {
 		_ = conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 		return nil
@@ -309,6 +401,10 @@ func (s *TerminalService) RunDockerTerminal(c *gin.Context) {
 
 	// Create terminal instance
 	term := NewTerminal(c.Request.Context(), conn, s.assetService, assetID)
+	term.idleTimeout = s.idleTimeout
+	term.recordings, term.recordAll = s.recordings, s.recordAll
+	term.tasks = s.tasks
+	term.triggers = s.triggers
 	term.SetContainerID(containerID)
 
 	// Start Docker exec
@@ -341,15 +437,107 @@ func (s *TerminalService) RunDockerTerminal(c *gin.Context) {
 	term.Run()
 }
 
+// ReattachTerminal handles a WebSocket that resumes a running session. The
+// output the client missed is replayed, starting at the byte offset it
+// passes, or the whole scrollback without one.
+func (s *TerminalService) ReattachTerminal(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	offset, _ := strconv.ParseInt(c.Query("offset"), 10, 64)
+
+	upgrader := &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     func(r *http.Request) bool { return true },
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		s.logger.Error("WebSocket upgrade failed", "error", err, "sessionID", sessionID)
+		return
+	}
+	defer conn.Close()
+
+	conn.SetReadLimit(terminalReadLimit)
+	conn.SetPongHandler(func(string) error {
+		_ = conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		return nil
+	})
+
+	term := GlobalTerminalManager.GetTerminal(sessionID)
+	if term == nil {
+		_ = conn.WriteJSON(map[string]interface{}{
+			"type": "status",
+			"data": map[string]string{"status": "expired", "message": "The terminal session has ended"},
+		})
+		return
+	}
+
+	_ = conn.WriteJSON(map[string]interface{}{
+		"type": "status",
+		"data": map[string]string{"status": "connected", "message": "Reattached to terminal session"},
+	})
+
+	if theme, err := loadTheme("tomorrow-night"); err == nil {
+		_ = conn.WriteJSON(map[string]interface{}{"type": "change-theme", "themeOptions": theme})
+	}
+
+	term.Attach(conn, offset)
+}
+
+// ListSessions lists the running terminal sessions and whether a client is
+// attached to each
+func (s *TerminalService) ListSessions(c *gin.Context) {
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: GlobalTerminalManager.ListSessions()})
+}
+
+// CommandHistory is a session's commands as reported by shell integration.
+// Active is false when the shell has not printed any command markers.
+type CommandHistory struct {
+	Active   bool                       `json:"active"`
+	Cwd      string                     `json:"cwd,omitempty"`
+	Running  *shellintegration.Command  `json:"running,omitempty"`
+	Commands []shellintegration.Command `json:"commands"`
+}
+
+// GetCommandHistory returns the commands run in a session, oldest first, at
+// most limit of them when given
+func (s *TerminalService) GetCommandHistory(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	tracker := GlobalTerminalManager.GetShellTracker(sessionID)
+	if tracker == nil {
+		c.JSON(http.StatusNotFound, models.Response{Code: 404, Message: "terminal session not found"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: CommandHistory{
+		Active:   tracker.Active(),
+		Cwd:      tracker.Cwd(),
+		Running:  tracker.Running(),
+		Commands: tracker.History(limit),
+	}})
+}
+
+// GetVariables returns the variables captured in a session by triggers
+func (s *TerminalService) GetVariables(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	if _, ok := GlobalTerminalManager.GetSessionAsset(sessionID); !ok {
+		c.JSON(http.StatusNotFound, models.Response{Code: 404, Message: "terminal session not found"})
+		return
+	}
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: GlobalTerminalManager.Variables(sessionID)})
+}
+
 // NewTerminal creates a new terminal instance
 func NewTerminal(ctx context.Context, conn *websocket.Conn, assetService *AssetService, assetID string) *Terminal {
 	// Generate temporary session ID; replaced later by frontend tab
 	tempSessionID := fmt.Sprintf("temp_%s_%d", assetID, time.Now().UnixNano())
+	sessionCtx, cancelSession := context.WithCancel(context.Background())
 
 	terminal := &Terminal{
 		ctx:          ctx,
 		conn:         conn,
 		assetService: assetService,
+		dialer:       sshconn.NewDialer(assetService),
 		assetID:      assetID,
 		logger:       utils.GetLogger(),
 		exitChan:     make(chan struct{}),
@@ -358,6 +546,10 @@ func NewTerminal(ctx context.Context, conn *websocket.Conn, assetService *AssetS
 		cols:         80,
 		paused:       false,
 		sessionID:    tempSessionID, // set temporary session ID
+
+		sessionCtx:    sessionCtx,
+		cancelSession: cancelSession,
+		idleTimeout:   config.DefaultTerminalIdleTimeout,
 	}
 
 	// Store session ID in terminal instance
@@ -378,39 +570,82 @@ func (t *Terminal) SetDockerHost(asset *models.Asset) {
 
 // Start starts appropriate connection by asset type
 func (t *Terminal) Start() error {
-	// Special case: "local" asset ID means use local terminal directly
+	var asset *models.Asset
 	if t.assetID == "local" {
-		t.connType = ConnectionTypeLocal
-		// Create a virtual local asset with default config
-		virtualAsset := &models.Asset{
+		// Special case: "local" asset ID means use local terminal directly
+		// with a virtual local asset of default config
+		asset = &models.Asset{
+			Name: "Local terminal",
 			Type: models.AssetTypeLocal,
 			Config: map[string]interface{}{
 				"shell": "/bin/bash",
 			},
 		}
-		return t.startLocalShell(virtualAsset)
-	}
-
-	// Retrieve asset info
-	asset, err := t.assetService.GetAsset(t.assetID)
-	if err != nil {
-		return fmt.Errorf("failed to get asset: %w", err)
+	} else {
+		// Retrieve asset info
+		var err error
+		asset, err = t.assetService.GetAsset(t.assetID)
+		if err != nil {
+			return fmt.Errorf("failed to get asset: %w", err)
+		}
 	}
 
 	// Start different connection according to asset type
+	var err error
 	switch asset.Type {
 	case models.AssetTypeLocal:
 		t.connType = ConnectionTypeLocal
-		return t.startLocalShell(asset)
+		err = t.startLocalShell(asset)
 	case models.AssetTypeSSH:
 		t.connType = ConnectionTypeSSH
-		return t.startSSHConnection(asset)
+		err = t.startSSHConnection(asset)
 	case models.AssetTypeDockerHost:
 		t.connType = ConnectionTypeDocker
-		return t.startDockerExec(asset)
+		err = t.startDockerExec(asset)
 	default:
-		return fmt.Errorf("unsupported asset type: %s", asset.Type)
+		err = fmt.Errorf("unsupported asset type: %s", asset.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	t.startRecording(asset)
+	return nil
+}
+
+// startRecording records the session as asciicast when every session or
+// this asset's sessions are to be recorded. A recording that can't be
+// created doesn't stop the session.
+func (t *Terminal) startRecording(asset *models.Asset) {
+	var cfg struct {
+		Record   bool   `json:"record"`
+		TermType string `json:"term_type"`
+	}
+	_ = asset.GetTypedConfig(&cfg)
+	if t.recordings == nil || !(t.recordAll || cfg.Record) {
+		return
+	}
+	if cfg.TermType == "" {
+		cfg.TermType = "xterm-256color"
+	}
+
+	title := asset.Name
+	if t.containerID != "" {
+		title += " / " + t.containerID
+	}
+	r, err := t.recordings.Create(recording.Header{
+		Width:   t.cols,
+		Height:  t.rows,
+		Title:   title,
+		Env:     map[string]string{"TERM": cfg.TermType},
+		AssetID: t.assetID,
+	})
+	if err != nil {
+		t.logger.Error("Failed to start session recording", "error", err, "assetId", t.assetID)
+		return
 	}
+	t.recorder = r
+	t.logger.Info("Recording terminal session", "recordingId", r.ID())
 }
 
 // startLocalShell starts local shell
@@ -472,21 +707,42 @@ func (t *Terminal) startLocalShell(asset *models.Asset) error {
 	t.localCmd = cmd
 
 	// Execute startup command if configured
-	if cfg.StartupCommand != "" {
-		go func() {
-			// Wait a bit for shell to initialize
-			time.Sleep(100 * time.Millisecond)
-			if _, err := t.localTty.Write([]byte(cfg.StartupCommand + "\n")); err != nil {
-				t.logger.Warn("Failed to execute startup command", "error", err, "assetId", t.assetID)
-			}
-		}()
+	var hooks string
+	if family := shellintegration.ShellOf(shell); cfg.ShellIntegration && family != "" {
+		hooks = shellintegration.InstallCommand(family)
 	}
+	// Wait a bit for shell to initialize
+	t.typeStartupInput(t.localTty, 100*time.Millisecond, hooks, cfg.StartupCommand)
 
 	// Mark terminal ready
 	t.readyOnce.Do(func() { close(t.readyChan) })
 	return nil
 }
 
+// typeStartupInput types the shell integration hooks, if any, and the
+// startup command, if any, into a new shell after delay
+func (t *Terminal) typeStartupInput(w io.Writer, delay time.Duration, hooks, startupCommand string) {
+	if hooks == "" && startupCommand == "" {
+		return
+	}
+	go func() {
+		time.Sleep(delay)
+		if hooks != "" {
+			t.writeMutex.Lock()
+			t.gate = shellintegration.NewGate(shellIntegrationTimeout)
+			t.writeMutex.Unlock()
+			if _, err := w.Write([]byte(hooks + "\n")); err != nil {
+				t.logger.Warn("Failed to install shell integration", "error", err, "assetId", t.assetID)
+			}
+		}
+		if startupCommand != "" {
+			if _, err := w.Write([]byte(startupCommand + "\n")); err != nil {
+				t.logger.Warn("Failed to execute startup command", "error", err, "assetId", t.assetID)
+			}
+		}
+	}()
+}
+
 // startSSHConnection starts SSH connection
 func (t *Terminal) startSSHConnection(asset *models.Asset) error {
 	// Parse typed config
@@ -495,111 +751,35 @@ func (t *Terminal) startSSHConnection(asset *models.Asset) error {
 		return fmt.Errorf("failed to parse SSH config: %w", err)
 	}
 
-	// Validate required fields
-	if cfg.Host == "" {
-		return fmt.Errorf("SSH host not specified")
-	}
-	if cfg.Username == "" {
-		return fmt.Errorf("SSH username not specified")
+	if err := t.openSSH(t.ctx, asset, &cfg, false); err != nil {
+		return err
 	}
 
-	// Apply defaults
-	port := cfg.Port
-	if port == 0 {
-		port = 22
-	}
+	// Mark terminal ready
+	t.readyOnce.Do(func() { close(t.readyChan) })
+	return nil
+}
+
+// openSSH dials the asset and starts a shell in a pty of the terminal's
+// size. When restoring a dropped connection into a tmux or screen session,
+// the shell hooks and startup command are not typed again: the session
+// still has them.
+func (t *Terminal) openSSH(ctx context.Context, asset *models.Asset, cfg *models.SSHConfig, restore bool) error {
 	termType := cfg.TermType
 	if termType == "" {
 		termType = "xterm-256color"
 	}
-	timeout := time.Duration(cfg.Timeout) * time.Second
-	if timeout == 0 {
-		timeout = 30 * time.Second
-	}
-
-	// Build SSH client config
-	sshConfig := &ssh.ClientConfig{
-		User:            cfg.Username,
-		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // NOTE: production should verify host key
-		Timeout:         timeout,
-	}
-
-	// Add authentication methods
-	if cfg.Password != "" {
-		sshConfig.Auth = append(sshConfig.Auth, ssh.Password(cfg.Password))
-	}
-
-	if cfg.PrivateKeyPath != "" {
-		if key, err := t.loadPrivateKey(cfg.PrivateKeyPath, cfg.PrivateKeyPassphrase); err == nil {
-			sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(key))
-		} else {
-			t.logger.Warn("Failed to load private key from file", "path", cfg.PrivateKeyPath, "error", err, "assetId", t.assetID)
-		}
-	}
-	if cfg.PrivateKey != "" {
-		if key, err := t.parsePrivateKey(cfg.PrivateKey, cfg.PrivateKeyPassphrase); err == nil {
-			sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(key))
-		} else {
-			t.logger.Warn("Failed to parse provided private key", "error", err, "assetId", t.assetID)
-		}
-	}
-
-	// If no auth methods, try empty password
-	if len(sshConfig.Auth) == 0 {
-		sshConfig.Auth = append(sshConfig.Auth, ssh.Password(""))
-	}
-
-	// Handle connection mode: direct, proxy, or jump
-	connectionMode := cfg.ConnectionMode
-	if connectionMode == "" {
-		connectionMode = "direct"
-	}
-
-	var client *ssh.Client
-	var err error
 
-	switch connectionMode {
-	case "jump":
-		if cfg.JumpAssetID == "" {
-			return fmt.Errorf("jump host asset ID not specified")
-		}
-		client, err = t.connectViaJumpHost(cfg.JumpAssetID, cfg.Host, port, sshConfig)
-		if err != nil {
-			return fmt.Errorf("failed to connect via jump host: %w", err)
-		}
-
-	case "proxy":
-		if cfg.ProxyHost == "" {
-			return fmt.Errorf("proxy host not specified")
-		}
-		proxyPort := cfg.ProxyPort
-		if proxyPort == 0 {
-			proxyPort = 1080
-		}
-		proxyType := cfg.ProxyType
-		if proxyType == "" {
-			proxyType = "socks5"
-		}
-		client, err = t.connectViaProxy(cfg.Host, port, sshConfig, proxyType, cfg.ProxyHost, proxyPort, cfg.ProxyUsername, cfg.ProxyPassword)
-		if err != nil {
-			return fmt.Errorf("failed to connect via proxy: %w", err)
-		}
-
-	default:
-		// Direct connection
-		addr := fmt.Sprintf("%s:%d", cfg.Host, port)
-		client, err = ssh.Dial("tcp", addr, sshConfig)
-		if err != nil {
-			return fmt.Errorf("failed to connect to SSH server: %w", err)
-		}
+	// A reconnect runs while the WebSocket read loop owns the socket, maybe
+	// with no client attached, so nobody can be asked: an unknown host key
+	// is refused and keyboard-interactive challenges fail
+	opts := t.dialOptions()
+	if restore {
+		opts = []sshconn.Option{sshconn.WithHostKeyConfirm(func(*hostkey.Key) bool { return false })}
 	}
-
-	t.sshClient = client
-
-	// Start keepalive if configured
-	if cfg.KeepaliveInterval > 0 {
-		go t.startSSHKeepalive(client, time.Duration(cfg.KeepaliveInterval)*time.Second)
+	client, err := t.dialer.DialAsset(ctx, asset, opts...)
+	if err != nil {
+		return err
 	}
 
 	// Create session
@@ -608,7 +788,6 @@ func (t *Terminal) startSSHConnection(asset *models.Asset) error {
 		client.Close()
 		return fmt.Errorf("failed to create SSH session: %w", err)
 	}
-	t.sshSession = session
 
 	// Set environment variables
 	for k, v := range cfg.Environment {
@@ -617,6 +796,10 @@ func (t *Terminal) startSSHConnection(asset *models.Asset) error {
 		}
 	}
 
+	if err := t.dialer.ForwardAgent(client, session, cfg); err != nil {
+		t.logger.Warn("Agent forwarding unavailable", "error", err, "assetId", t.assetID)
+	}
+
 	// Set terminal modes
 	modes := ssh.TerminalModes{
 		ssh.ECHO: 1,
@@ -635,7 +818,6 @@ func (t *Terminal) startSSHConnection(asset *models.Asset) error {
 		client.Close()
 		return fmt.Errorf("failed to get stdin pipe: %w", err)
 	}
-	t.sshStdin = stdin
 
 	stdout, err := session.StdoutPipe()
 	if err != nil {
@@ -643,7 +825,6 @@ func (t *Terminal) startSSHConnection(asset *models.Asset) error {
 		client.Close()
 		return fmt.Errorf("failed to get stdout pipe: %w", err)
 	}
-	t.sshStdout = stdout
 
 	stderr, err := session.StderrPipe()
 	if err != nil {
@@ -651,27 +832,35 @@ func (t *Terminal) startSSHConnection(asset *models.Asset) error {
 		client.Close()
 		return fmt.Errorf("failed to get stderr pipe: %w", err)
 	}
-	t.sshStderr = stderr
 
-	// Start shell
-	if err := session.Shell(); err != nil {
+	// Start shell, inside the multiplexer session if there is one
+	multiplexer := multiplexerCommand(cfg.Reconnect)
+	if multiplexer != "" {
+		err = session.Start(multiplexer)
+	} else {
+		err = session.Shell()
+	}
+	if err != nil {
 		session.Close()
 		client.Close()
 		return fmt.Errorf("failed to start shell: %w", err)
 	}
 
-	// Execute startup command if configured
-	if cfg.StartupCommand != "" {
-		go func() {
-			time.Sleep(200 * time.Millisecond)
-			if _, err := t.sshStdin.Write([]byte(cfg.StartupCommand + "\n")); err != nil {
-				t.logger.Warn("Failed to execute startup command", "error", err, "assetId", t.assetID)
-			}
-		}()
-	}
+	t.sshMu.Lock()
+	t.sshClient, t.sshSession = client, session
+	t.sshStdin, t.sshStdout, t.sshStderr = stdin, stdout, stderr
+	t.sshMu.Unlock()
 
-	// Mark terminal ready
-	t.readyOnce.Do(func() { close(t.readyChan) })
+	if restore && multiplexer != "" {
+		return nil
+	}
+	// Execute startup command if configured. The remote shell is unknown
+	// unless configured, so the hooks pick it themselves.
+	var hooks string
+	if cfg.ShellIntegration {
+		hooks = shellintegration.InstallCommand(shellintegration.ShellOf(cfg.Shell))
+	}
+	t.typeStartupInput(stdin, 200*time.Millisecond, hooks, cfg.StartupCommand)
 	return nil
 }
 
@@ -735,63 +924,9 @@ func (t *Terminal) startDockerExecLocal(dockerArgs []string) error {
 
 // startDockerExecViaSSH starts docker exec on remote host via SSH
 func (t *Terminal) startDockerExecViaSSH(sshAssetID string, dockerArgs []string) error {
-	// Get SSH asset
-	sshAsset, err := t.assetService.GetAsset(sshAssetID)
+	client, err := t.dialer.Dial(t.ctx, sshAssetID, t.dialOptions()...)
 	if err != nil {
-		return fmt.Errorf("SSH asset not found: %w", err)
-	}
-
-	if sshAsset.Type != models.AssetTypeSSH {
-		return fmt.Errorf("referenced asset is not an SSH connection")
-	}
-
-	// Parse typed config
-	var cfg models.SSHConfig
-	if err := sshAsset.GetTypedConfig(&cfg); err != nil {
-		return fmt.Errorf("failed to parse SSH config: %w", err)
-	}
-
-	// Validate required fields
-	if cfg.Host == "" {
-		return fmt.Errorf("SSH host not specified")
-	}
-	if cfg.Username == "" {
-		return fmt.Errorf("SSH username not specified")
-	}
-
-	// Apply defaults
-	port := cfg.Port
-	if port == 0 {
-		port = 22
-	}
-
-	sshConfig := &ssh.ClientConfig{
-		User:            cfg.Username,
-		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
-	}
-
-	// Add authentication methods
-	if cfg.Password != "" {
-		sshConfig.Auth = append(sshConfig.Auth, ssh.Password(cfg.Password))
-	}
-	if cfg.PrivateKeyPath != "" {
-		if key, err := t.loadPrivateKey(cfg.PrivateKeyPath, cfg.PrivateKeyPassphrase); err == nil {
-			sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(key))
-		}
-	}
-	if cfg.PrivateKey != "" {
-		if key, err := t.parsePrivateKey(cfg.PrivateKey, cfg.PrivateKeyPassphrase); err == nil {
-			sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(key))
-		}
-	}
-
-	// Connect to SSH server
-	addr := fmt.Sprintf("%s:%d", cfg.Host, port)
-	client, err := ssh.Dial("tcp", addr, sshConfig)
-	if err != nil {
-		return fmt.Errorf("failed to connect to SSH server: %w", err)
+		return err
 	}
 	t.sshClient = client
 
@@ -858,313 +993,94 @@ func (t *Terminal) startDockerExecViaSSH(sshAssetID string, dockerArgs []string)
 	return nil
 }
 
-// connectViaJumpHost connects to target via jump host
-func (t *Terminal) connectViaJumpHost(jumpAssetID string, targetHost string, targetPort int, targetConfig *ssh.ClientConfig) (*ssh.Client, error) {
-	// Get jump host asset
-	jumpAsset, err := t.assetService.GetAsset(jumpAssetID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get jump host asset: %w", err)
-	}
-
-	// Parse typed config
-	var cfg models.SSHConfig
-	if err := jumpAsset.GetTypedConfig(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse jump host config: %w", err)
-	}
-
-	// Validate required fields
-	if cfg.Host == "" {
-		return nil, fmt.Errorf("jump host not specified")
-	}
-	if cfg.Username == "" {
-		return nil, fmt.Errorf("jump host username not specified")
-	}
-
-	// Apply defaults
-	jumpPort := cfg.Port
-	if jumpPort == 0 {
-		jumpPort = 22
-	}
-
-	// Build jump host SSH config
-	jumpSSHConfig := &ssh.ClientConfig{
-		User:            cfg.Username,
-		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
+// dialOptions lets SSH dials ask the user about host keys and
+// keyboard-interactive challenges
+func (t *Terminal) dialOptions() []sshconn.Option {
+	return []sshconn.Option{
+		sshconn.WithHostKeyConfirm(t.confirmHostKey),
+		sshconn.WithKeyboardInteractive(t.promptChallenge),
 	}
+}
 
-	// Add jump host authentication
-	if cfg.Password != "" {
-		jumpSSHConfig.Auth = append(jumpSSHConfig.Auth, ssh.Password(cfg.Password))
-	}
-	if cfg.PrivateKeyPath != "" {
-		if key, err := t.loadPrivateKey(cfg.PrivateKeyPath, cfg.PrivateKeyPassphrase); err == nil {
-			jumpSSHConfig.Auth = append(jumpSSHConfig.Auth, ssh.PublicKeys(key))
+// confirmHostKey shows an unknown host key to the user and waits for their
+// answer
+func (t *Terminal) confirmHostKey(k *hostkey.Key) bool {
+	accept := false
+	err := t.ask("host_key", k, func(m interface{}) bool {
+		decision, ok := m.(*message.TermHostKeyDecision)
+		if ok {
+			accept = decision.Accept
 		}
-	}
-	if cfg.PrivateKey != "" {
-		if key, err := t.parsePrivateKey(cfg.PrivateKey, cfg.PrivateKeyPassphrase); err == nil {
-			jumpSSHConfig.Auth = append(jumpSSHConfig.Auth, ssh.PublicKeys(key))
-		}
-	}
-
-	// Connect to jump host
-	jumpAddr := fmt.Sprintf("%s:%d", cfg.Host, jumpPort)
-	jumpClient, err := ssh.Dial("tcp", jumpAddr, jumpSSHConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to jump host: %w", err)
-	}
-
-	// Connect to target through jump host
-	targetAddr := fmt.Sprintf("%s:%d", targetHost, targetPort)
-	conn, err := jumpClient.Dial("tcp", targetAddr)
-	if err != nil {
-		jumpClient.Close()
-		return nil, fmt.Errorf("failed to dial target through jump host: %w", err)
-	}
-
-	// Create SSH connection over the tunnel
-	ncc, chans, reqs, err := ssh.NewClientConn(conn, targetAddr, targetConfig)
+		return ok
+	})
 	if err != nil {
-		conn.Close()
-		jumpClient.Close()
-		return nil, fmt.Errorf("failed to create SSH client connection: %w", err)
+		t.logger.Warn("No answer to host key prompt", "host", k.Host, "error", err)
+		return false
 	}
-
-	client := ssh.NewClient(ncc, chans, reqs)
-	return client, nil
+	return accept
 }
 
-// connectViaProxy connects to SSH server via SOCKS/HTTP proxy
-func (t *Terminal) connectViaProxy(host string, port int, sshConfig *ssh.ClientConfig, proxyType, proxyHost string, proxyPort int, proxyUser, proxyPass string) (*ssh.Client, error) {
-	proxyAddr := fmt.Sprintf("%s:%d", proxyHost, proxyPort)
-	targetAddr := fmt.Sprintf("%s:%d", host, port)
-
-	var conn net.Conn
-	var err error
-
-	switch proxyType {
-	case "socks5", "socks4":
-		conn, err = t.dialSOCKS(proxyAddr, targetAddr, proxyUser, proxyPass, proxyType == "socks5")
-	case "http":
-		conn, err = t.dialHTTPProxy(proxyAddr, targetAddr, proxyUser, proxyPass)
-	default:
-		return nil, fmt.Errorf("unsupported proxy type: %s", proxyType)
-	}
-
+// promptChallenge relays a keyboard-interactive challenge, such as an OTP
+// prompt, to the user and waits for their answers
+func (t *Terminal) promptChallenge(c *sshconn.Challenge) ([]string, error) {
+	var resp *message.TermAuthResponse
+	err := t.ask("auth_prompt", c, func(m interface{}) bool {
+		r, ok := m.(*message.TermAuthResponse)
+		if ok {
+			resp = r
+		}
+		return ok
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect via proxy: %w", err)
+		return nil, fmt.Errorf("no answer to authentication prompt: %w", err)
 	}
-
-	// Create SSH connection over proxy
-	ncc, chans, reqs, err := ssh.NewClientConn(conn, targetAddr, sshConfig)
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to create SSH client connection: %w", err)
+	if resp.Cancel {
+		return nil, fmt.Errorf("authentication cancelled")
 	}
-
-	client := ssh.NewClient(ncc, chans, reqs)
-	return client, nil
+	return resp.Answers, nil
 }
 
-// dialSOCKS connects through SOCKS proxy
-func (t *Terminal) dialSOCKS(proxyAddr, targetAddr, user, pass string, isSocks5 bool) (net.Conn, error) {
-	conn, err := net.DialTimeout("tcp", proxyAddr, 30*time.Second)
+// ask sends a prompt to the user and reads the WebSocket until answered
+// reports the answer. It runs during Start, before the WebSocket read loop,
+// so it reads the socket itself and applies session and size messages sent
+// meanwhile.
+func (t *Terminal) ask(msgType string, data interface{}, answered func(m interface{}) bool) error {
+	t.writeMutex.Lock()
+	conn := t.conn
+	if conn == nil {
+		t.writeMutex.Unlock()
+		return fmt.Errorf("no client attached")
+	}
+	err := conn.WriteJSON(WebSocketMessage{Type: msgType, Data: data})
+	t.writeMutex.Unlock()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if isSocks5 {
-		// SOCKS5 handshake
-		var authMethod byte = 0x00 // No auth
-		if user != "" {
-			authMethod = 0x02 // Username/password
-		}
-
-		// Send greeting
-		conn.Write([]byte{0x05, 0x01, authMethod})
-
-		// Read response
-		resp := make([]byte, 2)
-		if _, err := io.ReadFull(conn, resp); err != nil {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS5 handshake failed: %w", err)
-		}
-
-		if resp[0] != 0x05 {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS5 version mismatch")
-		}
-
-		// Username/password auth if required
-		if resp[1] == 0x02 {
-			authReq := []byte{0x01, byte(len(user))}
-			authReq = append(authReq, []byte(user)...)
-			authReq = append(authReq, byte(len(pass)))
-			authReq = append(authReq, []byte(pass)...)
-			conn.Write(authReq)
-
-			authResp := make([]byte, 2)
-			if _, err := io.ReadFull(conn, authResp); err != nil {
-				conn.Close()
-				return nil, fmt.Errorf("SOCKS5 auth failed: %w", err)
-			}
-			if authResp[1] != 0x00 {
-				conn.Close()
-				return nil, fmt.Errorf("SOCKS5 auth rejected")
-			}
-		}
-
-		// Parse target address
-		host, portStr, _ := net.SplitHostPort(targetAddr)
-		port := 22
-		fmt.Sscanf(portStr, "%d", &port)
-
-		// Send connect request
-		connectReq := []byte{0x05, 0x01, 0x00, 0x03, byte(len(host))}
-		connectReq = append(connectReq, []byte(host)...)
-		connectReq = append(connectReq, byte(port>>8), byte(port&0xff))
-		conn.Write(connectReq)
-
-		// Read connect response
-		connectResp := make([]byte, 10)
-		if _, err := io.ReadFull(conn, connectResp); err != nil {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS5 connect failed: %w", err)
-		}
-
-		if connectResp[1] != 0x00 {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS5 connect rejected: %d", connectResp[1])
-		}
-	} else {
-		// SOCKS4 handshake
-		host, portStr, _ := net.SplitHostPort(targetAddr)
-		port := 22
-		fmt.Sscanf(portStr, "%d", &port)
-
-		// Resolve hostname
-		ips, err := net.LookupIP(host)
-		if err != nil || len(ips) == 0 {
-			conn.Close()
-			return nil, fmt.Errorf("failed to resolve hostname: %w", err)
-		}
-		ip := ips[0].To4()
-		if ip == nil {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS4 does not support IPv6")
+	_ = conn.SetReadDeadline(time.Now().Add(promptTimeout))
+	defer func() { _ = conn.SetReadDeadline(time.Time{}) }()
+	for {
+		wsType, raw, err := conn.ReadMessage()
+		if err != nil {
+			return err
 		}
-
-		// Send SOCKS4 request
-		req := []byte{0x04, 0x01, byte(port >> 8), byte(port & 0xff)}
-		req = append(req, ip...)
-		req = append(req, []byte(user)...)
-		req = append(req, 0x00)
-		conn.Write(req)
-
-		// Read response
-		resp := make([]byte, 8)
-		if _, err := io.ReadFull(conn, resp); err != nil {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS4 handshake failed: %w", err)
+		if wsType != websocket.TextMessage {
+			continue
 		}
-
-		if resp[1] != 0x5a {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS4 connect rejected: %d", resp[1])
+		m, err := message.ParseMessage(raw)
+		if err != nil {
+			continue
 		}
-	}
-
-	return conn, nil
-}
-
-// dialHTTPProxy connects through HTTP CONNECT proxy
-func (t *Terminal) dialHTTPProxy(proxyAddr, targetAddr, user, pass string) (net.Conn, error) {
-	conn, err := net.DialTimeout("tcp", proxyAddr, 30*time.Second)
-	if err != nil {
-		return nil, err
-	}
-
-	// Send CONNECT request
-	connectReq := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", targetAddr, targetAddr)
-	if user != "" {
-		// Basic auth
-		auth := fmt.Sprintf("%s:%s", user, pass)
-		encoded := base64.StdEncoding.EncodeToString([]byte(auth))
-		connectReq += fmt.Sprintf("Proxy-Authorization: Basic %s\r\n", encoded)
-	}
-	connectReq += "\r\n"
-
-	if _, err := conn.Write([]byte(connectReq)); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to send CONNECT: %w", err)
-	}
-
-	// Read response
-	reader := bufio.NewReader(conn)
-	resp, err := http.ReadResponse(reader, nil)
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to read CONNECT response: %w", err)
-	}
-	resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		conn.Close()
-		return nil, fmt.Errorf("HTTP CONNECT failed: %s", resp.Status)
-	}
-
-	return conn, nil
-}
-
-// startSSHKeepalive sends periodic keepalive requests to SSH server
-func (t *Terminal) startSSHKeepalive(client *ssh.Client, interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-t.ctx.Done():
-			return
-		case <-ticker.C:
-			if client == nil {
-				return
-			}
-			// Send keepalive request
-			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
-			if err != nil {
-				t.logger.Debug("SSH keepalive failed", "error", err)
-				return
-			}
+		if answered(m) {
+			return nil
 		}
-	}
-}
-
-// loadPrivateKey loads private key from file (supports optional passphrase)
-func (t *Terminal) loadPrivateKey(path string, passphrase string) (ssh.Signer, error) {
-	key, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	return t.parsePrivateKey(string(key), passphrase)
-}
-
-// parsePrivateKey parses private key; if encrypted and passphrase provided, attempts decryption
-func (t *Terminal) parsePrivateKey(keyData string, passphrase string) (ssh.Signer, error) {
-	signer, err := ssh.ParsePrivateKey([]byte(keyData))
-	if err == nil {
-		return signer, nil
-	}
-	// Attempt encrypted key parsing if passphrase given
-	if passphrase != "" {
-		signerWithPw, perr := ssh.ParsePrivateKeyWithPassphrase([]byte(keyData), []byte(passphrase))
-		if perr == nil {
-			return signerWithPw, nil
+		switch typedMsg := m.(type) {
+		case *message.TermSetSessionId:
+			t.handleSetSessionId(typedMsg.SessionId)
+		case *message.TermResize:
+			t.resizeTerminal(typedMsg.Rows, typedMsg.Cols)
 		}
-		return nil, perr
 	}
-	return nil, err
 }
 
 // WaitForReady waits terminal ready
@@ -1177,13 +1093,51 @@ func (t *Terminal) WaitForReady() error {
 	}
 }
 
-// Run runs terminal
+// Run serves the client that opened the terminal. The session keeps
+// running after that client disconnects.
 func (t *Terminal) Run() {
-	ctx, cancel := context.WithCancel(t.ctx)
-	defer cancel()
+	t.Attach(t.conn, 0)
+}
 
-	var wg sync.WaitGroup
-	wg.Add(2)
+// Attach makes conn the session's client, replays the output written after
+// offset and serves conn until it closes or the session ends. A client
+// attached before is disconnected, as with tmux attach -d.
+func (t *Terminal) Attach(conn *websocket.Conn, offset int64) {
+	t.pumpOnce.Do(func() { go t.pump() })
+	// A client attaching knows nothing of a transfer in progress
+	t.cancelTransfer()
+
+	t.writeMutex.Lock()
+	if t.sessionCtx.Err() != nil {
+		t.writeMutex.Unlock()
+		_ = conn.WriteJSON(WebSocketMessage{Type: "status", Data: map[string]string{"status": "expired", "message": "The terminal session has ended"}})
+		return
+	}
+	if prev := t.conn; prev != nil && prev != conn {
+		t.writeStatus(prev, "detached", "Session attached from another client")
+		_ = prev.Close()
+	}
+	t.conn = conn
+	if t.idleTimer != nil {
+		t.idleTimer.Stop()
+		t.idleTimer = nil
+	}
+	// Output is recorded and forwarded under writeMutex, so nothing is lost
+	// or sent twice between the replay and the live stream
+	if err := writeChunks(conn, GlobalTerminalManager.GetOutputSince(t.sessionID, offset)); err != nil {
+		t.logger.Warn("Failed to replay scrollback", "error", err)
+	}
+	if len(GlobalTerminalManager.Broadcasts().Markers(t.sessionID)) > 0 {
+		t.writeBroadcastMarker(conn)
+	}
+	if vars := GlobalTerminalManager.Variables(t.sessionID); len(vars) > 0 {
+		_ = conn.WriteJSON(WebSocketMessage{Type: "variables", Data: vars})
+	}
+	GlobalTerminalManager.SetTerminalConnection(t.sessionID, conn)
+	t.writeMutex.Unlock()
+
+	ctx, cancel := context.WithCancel(t.sessionCtx)
+	defer cancel()
 
 	// Periodically send ping to prevent WebSocket disconnect
 	go func() {
@@ -1195,33 +1149,100 @@ func (t *Terminal) Run() {
 				return
 			case <-ticker.C:
 				t.writeMutex.Lock()
-				if t.conn != nil {
-					_ = t.conn.WriteMessage(websocket.PingMessage, nil)
+				if t.conn == conn {
+					_ = conn.WriteMessage(websocket.PingMessage, nil)
 				}
 				t.writeMutex.Unlock()
 			}
 		}
 	}()
 
-	// Read terminal output and send to WebSocket
-	go func() {
-		defer wg.Done()
-		t.readFromTerminal(ctx)
-	}()
+	t.readFromWebSocket(ctx, conn)
+	t.detach(conn)
+}
 
-	// Read WebSocket messages and relay to terminal
-	go func() {
-		defer wg.Done()
-		t.readFromWebSocket(ctx)
-		// When WebSocket reading ends, cancel context to signal other goroutines
-		cancel()
-	}()
+// detach forgets conn once it has closed. The session then waits idleTimeout
+// for a client to reattach; a session the client never named cannot be
+// reattached and ends right away.
+func (t *Terminal) detach(conn *websocket.Conn) {
+	t.writeMutex.Lock()
+	if t.conn != conn || t.sessionCtx.Err() != nil {
+		t.writeMutex.Unlock()
+		return
+	}
+	t.conn = nil
+	t.paused = false
+	t.detachedAt = time.Now()
+	keep := t.named && t.idleTimeout > 0
+	if keep {
+		GlobalTerminalManager.SetTerminalConnection(t.sessionID, nil)
+		t.idleTimer = time.AfterFunc(t.idleTimeout, func() {
+			if _, attached := t.attachState(); !attached {
+				t.Close("Session closed after being idle for " + t.idleTimeout.String())
+			}
+		})
+	}
+	t.writeMutex.Unlock()
+	t.cancelTransfer()
 
-	// Wait for goroutines to finish
-	wg.Wait()
+	if !keep {
+		t.Close("Client disconnected")
+		return
+	}
+	t.logger.Info("Terminal session detached", "idleTimeout", t.idleTimeout)
+}
+
+// attachState reports whether a client is attached, and if not since when
+func (t *Terminal) attachState() (time.Time, bool) {
+	t.writeMutex.Lock()
+	defer t.writeMutex.Unlock()
+	return t.detachedAt, t.conn != nil
+}
 
-	// Clean up resources
-	t.cleanup()
+// pump records terminal output and forwards it to the attached client for
+// the life of the session, which ends when the process exits
+func (t *Terminal) pump() {
+	for {
+		t.readFromTerminal(t.sessionCtx)
+		if t.sessionCtx.Err() != nil || !t.reconnect() {
+			break
+		}
+	}
+	t.Close("Session ended")
+}
+
+// Close ends the session: the attached client and the viewers are told why
+// and disconnected, the process is stopped and the session leaves the registry
+func (t *Terminal) Close(reason string) {
+	t.closeOnce.Do(func() {
+		t.logger.Info("Closing terminal session", "reason", reason)
+
+		t.writeMutex.Lock()
+		t.cancelSession()
+		if t.idleTimer != nil {
+			t.idleTimer.Stop()
+			t.idleTimer = nil
+		}
+		if conn := t.conn; conn != nil {
+			t.writeStatus(conn, "disconnected", reason)
+			_ = conn.Close()
+			t.conn = nil
+		}
+		for conn := range t.viewers {
+			t.writeStatus(conn, "disconnected", reason)
+			_ = conn.Close()
+		}
+		t.viewers = nil
+		t.writeMutex.Unlock()
+
+		t.cleanup()
+		if t.recorder != nil {
+			if err := t.recorder.Close(); err != nil {
+				t.logger.Error("Failed to save session recording", "error", err, "recordingId", t.recorder.ID())
+			}
+		}
+		GlobalTerminalManager.RemoveTerminal(t.sessionID, t)
+	})
 }
 
 // readFromTerminal reads data from terminal and sends to WebSocket
@@ -1236,7 +1257,9 @@ func (t *Terminal) readFromTerminal(ctx context.Context) {
 	case ConnectionTypeLocal, ConnectionTypeDocker:
 		readers = []io.Reader{t.localTty}
 	case ConnectionTypeSSH:
+		t.sshMu.RLock()
 		readers = []io.Reader{t.sshStdout, t.sshStderr}
+		t.sshMu.RUnlock()
 	default:
 		t.logger.Error("Unknown connection type", "type", t.connType)
 		return
@@ -1285,36 +1308,48 @@ func (t *Terminal) readFromTerminal(ctx context.Context) {
 				activeReaders--
 				continue
 			}
-			if len(res.data) > 0 {
-				if err := t.sendDataToWebSocket(res.data); err != nil {
-					t.logger.Error("Error sending data to websocket", "error", err)
-					return
+			if data := t.feedTransfer(res.data); len(data) > 0 {
+				if err := t.sendDataToWebSocket(data); err != nil {
+					// The output is in the scrollback; the client is gone
+					// and its read loop detaches it
+					t.logger.Debug("Error sending data to websocket", "error", err)
 				}
+				t.runTriggers(data)
 			}
 		}
 	}
 	wg.Wait()
 }
 
-// sendDataToWebSocket safely sends data to WebSocket and captures output
+// sendDataToWebSocket captures output in the scrollback and sends it to the
+// attached client, if any
 func (t *Terminal) sendDataToWebSocket(data []byte) error {
 	t.writeMutex.Lock()
 	defer t.writeMutex.Unlock()
 
-	// Ensure WebSocket connection still valid
-	if t.conn == nil {
-		return fmt.Errorf("websocket connection is nil")
+	if t.gate != nil {
+		data = t.gate.Filter(data)
 	}
 
 	// Capture terminal output to global manager
 	if len(data) > 0 {
-		// Convert bytes to string and add to history
-		output := string(data)
-		//fmt.Println("Terminal Output:", output) // Debug output
-		GlobalTerminalManager.AppendOutput(t.sessionID, output)
+		GlobalTerminalManager.AppendOutput(t.sessionID, data)
+		if t.recorder != nil {
+			t.recorder.Output(data)
+		}
+	}
+
+	t.writeViewers(data)
+
+	// Detached: the scrollback keeps the output for the next client
+	if t.conn == nil {
+		return nil
 	}
+	return writeChunks(t.conn, data)
+}
 
-	// Ensure data is valid UTF-8 text
+// writeChunks sends terminal output as binary frames of bounded size
+func writeChunks(conn *websocket.Conn, data []byte) error {
 	const maxChunkSize = 8192
 	for i := 0; i < len(data); i += maxChunkSize {
 		end := i + maxChunkSize
@@ -1326,7 +1361,7 @@ func (t *Terminal) sendDataToWebSocket(data []byte) error {
 
 		// Send raw text data without JSON wrapping
 		// Allows frontend to display directly
-		err := t.conn.WriteMessage(websocket.BinaryMessage, chunk)
+		err := conn.WriteMessage(websocket.BinaryMessage, chunk)
 		if err != nil {
 			return fmt.Errorf("failed to write websocket message: %w", err)
 		}
@@ -1337,6 +1372,16 @@ func (t *Terminal) sendDataToWebSocket(data []byte) error {
 
 // sendConnectionStatus sends connection status
 func (t *Terminal) sendConnectionStatus(status, message string) {
+	t.writeMutex.Lock()
+	defer t.writeMutex.Unlock()
+
+	if t.conn != nil {
+		t.writeStatus(t.conn, status, message)
+	}
+}
+
+// writeStatus sends a status message to conn; the caller holds writeMutex
+func (t *Terminal) writeStatus(conn *websocket.Conn, status, message string) {
 	statusMsg := WebSocketMessage{
 		Type: "status",
 		Data: map[string]interface{}{
@@ -1351,27 +1396,33 @@ func (t *Terminal) sendConnectionStatus(status, message string) {
 		return
 	}
 
+	if err := conn.WriteMessage(websocket.TextMessage, msgBytes); err != nil {
+		t.logger.Error("Failed to send status message", "error", err)
+	}
+}
+
+// sendMessage sends a JSON message to the attached client
+func (t *Terminal) sendMessage(typ string, data interface{}) error {
 	t.writeMutex.Lock()
 	defer t.writeMutex.Unlock()
-
-	if t.conn != nil {
-		err = t.conn.WriteMessage(websocket.TextMessage, msgBytes)
-		if err != nil {
-			t.logger.Error("Failed to send status message", "error", err)
-		}
+	if t.conn == nil {
+		return errNoClient
 	}
+	return t.conn.WriteJSON(WebSocketMessage{Type: typ, Data: data})
 }
 
 // readFromWebSocket reads data from WebSocket and sends to terminal
-func (t *Terminal) readFromWebSocket(ctx context.Context) {
+func (t *Terminal) readFromWebSocket(ctx context.Context, conn *websocket.Conn) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			msgType, msg, err := t.conn.ReadMessage()
+			msgType, msg, err := conn.ReadMessage()
 			if err != nil {
-				t.logger.Error("Error reading from websocket", "error", err)
+				if ctx.Err() == nil {
+					t.logger.Info("Terminal client disconnected", "error", err)
+				}
 				return
 			}
 
@@ -1390,21 +1441,23 @@ func (t *Terminal) readFromWebSocket(ctx context.Context) {
 					// Handle session ID set message
 					t.handleSetSessionId(typedMsg.SessionId)
 				case *message.TermInput:
-					t.writeToTerminal([]byte(m.(*message.TermInput).Data))
+					t.typeInput([]byte(m.(*message.TermInput).Data))
 				case *message.TermResize:
 					t.resizeTerminal(typedMsg.Rows, typedMsg.Cols)
 				case *message.TermPause:
 					// Handle pause message
 					t.paused = m.(*message.TermPause).Pause
-				case *message.TermOutputResponse:
-					// Handle output response from frontend
-					GlobalOutputManager.HandleOutputResponse(typedMsg)
+				case *message.TermClose:
+					t.Close("Session closed")
+					return
+				case *message.TermTransferFiles, *message.TermTransferData, *message.TermTransferCancel:
+					t.handleTransferMessage(m)
 				default:
 					t.logger.Warn("Unknown message type received", "msgType", fmt.Sprintf("%T", m))
 				}
 			} else if msgType == websocket.BinaryMessage {
 				// Binary messages write directly to terminal
-				t.writeToTerminal(msg)
+				t.typeInput(msg)
 			}
 		}
 	}
@@ -1438,36 +1491,56 @@ func (t *Terminal) handleWebSocketMessage(msgType string, msg []byte) {
 
 // writeToTerminal writes data to terminal and captures command
 func (t *Terminal) writeToTerminal(data []byte) {
-	// Capture user-entered command (simple detection)
+	if t.holdInput(data) {
+		return
+	}
+	// Capture user-entered command (simple detection), unless the shell
+	// reports its commands itself
 	input := string(data)
-	if strings.Contains(input, "\r") || strings.Contains(input, "\n") {
+	tracker := GlobalTerminalManager.GetShellTracker(t.sessionID)
+	if (tracker == nil || !tracker.Active()) && (strings.Contains(input, "\r") || strings.Contains(input, "\n")) {
 		// If contains newline, maybe a complete command
 		// Place for more advanced parsing
 		if len(strings.TrimSpace(input)) > 0 {
 			GlobalTerminalManager.SetLastCommand(t.sessionID, strings.TrimSpace(input))
 		}
 	}
+	if t.recorder != nil {
+		t.recorder.Input(data)
+	}
+	if err := t.writeRaw(data); err != nil {
+		t.logger.Error("Failed to write to terminal", "error", err, "type", t.connType)
+	}
+}
 
+// writeRaw writes data to the local pty or the SSH session as is
+func (t *Terminal) writeRaw(data []byte) error {
 	switch t.connType {
 	case ConnectionTypeLocal, ConnectionTypeDocker:
 		if t.localTty != nil {
-			if _, err := t.localTty.Write(data); err != nil {
-				t.logger.Error("Failed to write to local terminal", "error", err)
-			}
+			_, err := t.localTty.Write(data)
+			return err
 		}
 	case ConnectionTypeSSH:
-		if t.sshStdin != nil {
-			if _, err := t.sshStdin.Write(data); err != nil {
-				t.logger.Error("Failed to write to SSH stdin", "error", err)
-			}
+		t.sshMu.RLock()
+		stdin := t.sshStdin
+		t.sshMu.RUnlock()
+		if stdin != nil {
+			_, err := stdin.Write(data)
+			return err
 		}
 	}
+	return nil
 }
 
 // resizeTerminal adjusts terminal size
 func (t *Terminal) resizeTerminal(rows, cols int) {
 	t.rows = rows
 	t.cols = cols
+	GlobalTerminalManager.ResizeScreen(t.sessionID, rows, cols)
+	if t.recorder != nil {
+		t.recorder.Resize(cols, rows)
+	}
 
 	switch t.connType {
 	case ConnectionTypeLocal, ConnectionTypeDocker:
@@ -1478,9 +1551,12 @@ func (t *Terminal) resizeTerminal(rows, cols int) {
 			}
 		}
 	case ConnectionTypeSSH:
-		if t.sshSession != nil {
+		t.sshMu.RLock()
+		session := t.sshSession
+		t.sshMu.RUnlock()
+		if session != nil {
 			t.logger.Debug("resizing ssh terminal", "rows", rows, "cols", cols)
-			if err := t.sshSession.WindowChange(rows, cols); err != nil {
+			if err := session.WindowChange(rows, cols); err != nil {
 				t.logger.Error("Failed to resize SSH terminal", "error", err, "rows", rows, "cols", cols)
 			} else {
 				t.logger.Debug("SSH terminal resized successfully", "rows", rows, "cols", cols)
@@ -1527,31 +1603,51 @@ func (t *Terminal) cleanup() {
 			}
 		}
 	case ConnectionTypeSSH:
-		if t.sshSession != nil {
-			// Send exit signal to remote process
-			if t.sshStdin != nil {
-				// Try to close stdin to signal EOF
-				_ = t.sshStdin.Close()
-			}
-			if err := t.sshSession.Signal(ssh.SIGTERM); err != nil {
-				t.logger.Debug("Failed to send SIGTERM to SSH session", "error", err)
-			}
-			if err := t.sshSession.Close(); err != nil {
-				t.logger.Error("Failed to close SSH session", "error", err)
-			}
-		}
-		if t.sshClient != nil {
-			if err := t.sshClient.Close(); err != nil {
-				t.logger.Error("Failed to close SSH client", "error", err)
-			}
-		}
+		t.closeSSH()
 	}
 
 	t.logger.Info("Terminal cleanup completed", "assetId", t.assetID)
 }
 
+// closeSSH ends the SSH session and connection
+func (t *Terminal) closeSSH() {
+	t.sshMu.RLock()
+	defer t.sshMu.RUnlock()
+	if t.sshSession != nil {
+		// Send exit signal to remote process
+		if t.sshStdin != nil {
+			// Try to close stdin to signal EOF
+			_ = t.sshStdin.Close()
+		}
+		if err := t.sshSession.Signal(ssh.SIGTERM); err != nil {
+			t.logger.Debug("Failed to send SIGTERM to SSH session", "error", err)
+		}
+		if err := t.sshSession.Close(); err != nil {
+			t.logger.Error("Failed to close SSH session", "error", err)
+		}
+	}
+	if t.sshClient != nil {
+		if err := t.sshClient.Close(); err != nil {
+			t.logger.Error("Failed to close SSH client", "error", err)
+		}
+	}
+}
+
 // handleSetSessionId handles session ID set message
 func (t *Terminal) handleSetSessionId(newSessionID string) {
+	t.writeMutex.Lock()
+	t.named = true
+	t.writeMutex.Unlock()
+	if newSessionID == t.sessionID {
+		// A reattached client names the session it is already attached to
+		return
+	}
+	// A tab that opened a new connection instead of reattaching replaces
+	// the session it had
+	if prev := GlobalTerminalManager.GetTerminal(newSessionID); prev != nil && prev != t {
+		prev.Close("Replaced by a new connection")
+	}
+
 	oldSessionID := t.sessionID
 	t.logger.Info("Updating session ID", "oldSessionID", oldSessionID, "newSessionID", newSessionID)
 
@@ -1566,5 +1662,15 @@ func (t *Terminal) handleSetSessionId(newSessionID string) {
 	// Re-attach terminal instance after migration
 	GlobalTerminalManager.AttachTerminal(newSessionID, t)
 
+	// The client may have put the session in a broadcast group before it
+	// connected
+	if len(GlobalTerminalManager.Broadcasts().Markers(newSessionID)) > 0 {
+		t.writeMutex.Lock()
+		if t.conn != nil {
+			t.writeBroadcastMarker(t.conn)
+		}
+		t.writeMutex.Unlock()
+	}
+
 	t.logger.Info("Session ID updated successfully", "sessionID", newSessionID)
 }
@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/message"
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Share roles. A read viewer only watches; a write viewer may also type.
+const (
+	ShareRoleRead  = "read"
+	ShareRoleWrite = "write"
+)
+
+// Share token lifetimes
+const (
+	DefaultShareTTL = time.Hour
+	MaxShareTTL     = 24 * time.Hour
+)
+
+// viewerWriteTimeout bounds a write to a viewer, so one slow viewer cannot
+// hold up the session
+const viewerWriteTimeout = 10 * time.Second
+
+// ErrShareNotFound is returned for unknown, revoked and expired share tokens
+var ErrShareNotFound = errors.New("share token not found or expired")
+
+// Share lets clients other than the owner join a session through its token
+type Share struct {
+	Token     string    `json:"token"`
+	SessionID string    `json:"session_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ShareStore keeps the share tokens of the running sessions in memory; they
+// go with the sessions
+type ShareStore struct {
+	mu     sync.Mutex
+	shares map[string]*Share
+}
+
+// NewShareStore creates an empty share store
+func NewShareStore() *ShareStore {
+	return &ShareStore{shares: make(map[string]*Share)}
+}
+
+// Create mints a token for sessionID with role, valid for ttl
+func (s *ShareStore) Create(sessionID, role string, ttl time.Duration) (Share, error) {
+	if role != ShareRoleRead && role != ShareRoleWrite {
+		return Share{}, fmt.Errorf("invalid share role %q", role)
+	}
+	if ttl <= 0 || ttl > MaxShareTTL {
+		return Share{}, fmt.Errorf("share lifetime must be between 0 and %s", MaxShareTTL)
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return Share{}, fmt.Errorf("failed to generate share token: %w", err)
+	}
+	now := time.Now()
+	share := &Share{
+		Token:     base64.RawURLEncoding.EncodeToString(b),
+		SessionID: sessionID,
+		Role:      role,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(now)
+	s.shares[share.Token] = share
+	return *share, nil
+}
+
+// Lookup returns the share of an unexpired token
+func (s *ShareStore) Lookup(token string) (Share, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	share, ok := s.shares[token]
+	if !ok || !time.Now().Before(share.ExpiresAt) {
+		return Share{}, ErrShareNotFound
+	}
+	return *share, nil
+}
+
+// List returns the unexpired shares of a session, oldest first
+func (s *ShareStore) List(sessionID string) []Share {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(time.Now())
+	result := []Share{}
+	for _, share := range s.shares {
+		if share.SessionID == sessionID {
+			result = append(result, *share)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
+
+// Revoke invalidates a token and returns its share
+func (s *ShareStore) Revoke(token string) (Share, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	share, ok := s.shares[token]
+	if !ok {
+		return Share{}, ErrShareNotFound
+	}
+	delete(s.shares, token)
+	return *share, nil
+}
+
+// RemoveSession invalidates the tokens of a session that ended
+func (s *ShareStore) RemoveSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, share := range s.shares {
+		if share.SessionID == sessionID {
+			delete(s.shares, token)
+		}
+	}
+}
+
+// RenameSession moves the tokens of a session to its new ID
+func (s *ShareStore) RenameSession(oldSessionID, newSessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, share := range s.shares {
+		if share.SessionID == oldSessionID {
+			share.SessionID = newSessionID
+		}
+	}
+}
+
+func (s *ShareStore) pruneLocked(now time.Time) {
+	for token, share := range s.shares {
+		if !now.Before(share.ExpiresAt) {
+			delete(s.shares, token)
+		}
+	}
+}
+
+// Join serves conn as a viewer of the session through share: it gets the
+// output written after offset, then the live output alongside the owner,
+// and may type into the terminal if the share allows it. The viewer is
+// disconnected when the share expires or is revoked.
+func (t *Terminal) Join(conn *websocket.Conn, share Share, offset int64) {
+	t.pumpOnce.Do(func() { go t.pump() })
+
+	t.writeMutex.Lock()
+	if t.sessionCtx.Err() != nil {
+		t.writeMutex.Unlock()
+		_ = conn.WriteJSON(WebSocketMessage{Type: "status", Data: map[string]string{"status": "expired", "message": "The terminal session has ended"}})
+		return
+	}
+	if t.viewers == nil {
+		t.viewers = make(map[*websocket.Conn]Share)
+	}
+	t.viewers[conn] = share
+	if err := writeChunks(conn, GlobalTerminalManager.GetOutputSince(t.sessionID, offset)); err != nil {
+		t.logger.Warn("Failed to replay scrollback to viewer", "error", err)
+	}
+	t.writeMutex.Unlock()
+	t.logger.Info("Viewer joined terminal session", "role", share.Role)
+
+	ctx, cancel := context.WithDeadline(t.sessionCtx, share.ExpiresAt)
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					t.dropViewers(share.Token, "expired", "The share has expired")
+				}
+				return
+			case <-ticker.C:
+				t.writeMutex.Lock()
+				if _, ok := t.viewers[conn]; ok {
+					_ = conn.WriteMessage(websocket.PingMessage, nil)
+				}
+				t.writeMutex.Unlock()
+			}
+		}
+	}()
+
+	t.readFromViewer(conn, share)
+
+	t.writeMutex.Lock()
+	delete(t.viewers, conn)
+	t.writeMutex.Unlock()
+}
+
+// readFromViewer forwards a viewer's input if its role allows it. The
+// owner alone controls the terminal size, flow and session ID.
+func (t *Terminal) readFromViewer(conn *websocket.Conn, share Share) {
+	warned := false
+	for {
+		msgType, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var input []byte
+		switch msgType {
+		case websocket.BinaryMessage:
+			input = msg
+		case websocket.TextMessage:
+			m, err := message.ParseMessage(msg)
+			if err != nil {
+				t.logger.Error("Error parsing websocket message", "error", err)
+				continue
+			}
+			switch typedMsg := m.(type) {
+			case *message.TermInput:
+				input = []byte(typedMsg.Data)
+			case *message.TermClose:
+				return
+			default:
+				continue
+			}
+		}
+		if len(input) == 0 {
+			continue
+		}
+
+		if share.Role != ShareRoleWrite {
+			if !warned {
+				t.writeMutex.Lock()
+				t.writeStatus(conn, "read_only", "This shared session is read-only")
+				t.writeMutex.Unlock()
+				warned = true
+			}
+			continue
+		}
+		t.writeToTerminal(input)
+	}
+}
+
+// writeViewers sends output to the viewers, dropping any that fail; the
+// caller holds writeMutex
+func (t *Terminal) writeViewers(data []byte) {
+	for conn := range t.viewers {
+		_ = conn.SetWriteDeadline(time.Now().Add(viewerWriteTimeout))
+		if err := writeChunks(conn, data); err != nil {
+			t.logger.Debug("Dropping terminal viewer", "error", err)
+			delete(t.viewers, conn)
+			_ = conn.Close()
+			continue
+		}
+		_ = conn.SetWriteDeadline(time.Time{})
+	}
+}
+
+// dropViewers disconnects the viewers that joined through token
+func (t *Terminal) dropViewers(token, status, reason string) {
+	t.writeMutex.Lock()
+	defer t.writeMutex.Unlock()
+	for conn, share := range t.viewers {
+		if share.Token == token {
+			t.writeStatus(conn, status, reason)
+			delete(t.viewers, conn)
+			_ = conn.Close()
+		}
+	}
+}
+
+// viewerCount returns how many viewers are joined
+func (t *Terminal) viewerCount() int {
+	t.writeMutex.Lock()
+	defer t.writeMutex.Unlock()
+	return len(t.viewers)
+}
+
+// CreateShareRequest asks for a share token of a session
+type CreateShareRequest struct {
+	Role      string `json:"role"`       // "read" (default) or "write"
+	ExpiresIn int    `json:"expires_in"` // seconds; 0 for an hour, at most a day
+}
+
+// CreateShare mints a share token for a running session
+// POST /terminal/sessions/:sessionId/shares
+func (s *TerminalService) CreateShare(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	var req CreateShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: err.Error()})
+		return
+	}
+	if GlobalTerminalManager.GetTerminal(sessionID) == nil {
+		c.JSON(http.StatusNotFound, models.Response{Code: 404, Message: "terminal session not found"})
+		return
+	}
+
+	role := req.Role
+	if role == "" {
+		role = ShareRoleRead
+	}
+	ttl := DefaultShareTTL
+	if req.ExpiresIn != 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	share, err := GlobalTerminalManager.Shares().Create(sessionID, role, ttl)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{Code: 400, Message: err.Error()})
+		return
+	}
+	s.logger.Info("Shared terminal session", "sessionID", sessionID, "role", share.Role, "expiresAt", share.ExpiresAt)
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: share})
+}
+
+// ListShares lists the unexpired share tokens of a session
+// GET /terminal/sessions/:sessionId/shares
+func (s *TerminalService) ListShares(c *gin.Context) {
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK", Data: GlobalTerminalManager.Shares().List(c.Param("sessionId"))})
+}
+
+// RevokeShare invalidates a share token and disconnects its viewers
+// DELETE /terminal/shares/:token
+func (s *TerminalService) RevokeShare(c *gin.Context) {
+	share, err := GlobalTerminalManager.Shares().Revoke(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Response{Code: 404, Message: err.Error()})
+		return
+	}
+	if term := GlobalTerminalManager.GetTerminal(share.SessionID); term != nil {
+		term.dropViewers(share.Token, "revoked", "The share has been revoked")
+	}
+	s.logger.Info("Revoked terminal share", "sessionID", share.SessionID)
+	c.JSON(http.StatusOK, models.Response{Code: 200, Message: "OK"})
+}
+
+// JoinSharedTerminal handles a WebSocket that joins a session through a
+// share token. As with reattaching, offset skips output already received.
+// GET /terminal/shared/:token?offset=N
+func (s *TerminalService) JoinSharedTerminal(c *gin.Context) {
+	offset, _ := strconv.ParseInt(c.Query("offset"), 10, 64)
+
+	upgrader := &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     func(r *http.Request) bool { return true },
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		s.logger.Error("WebSocket upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	conn.SetReadLimit(terminalReadLimit)
+	conn.SetPongHandler(func(string) error {
+		_ = conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		return nil
+	})
+
+	share, err := GlobalTerminalManager.Shares().Lookup(c.Param("token"))
+	if err != nil {
+		_ = conn.WriteJSON(WebSocketMessage{Type: "status", Data: map[string]string{"status": "denied", "message": "Invalid or expired share token"}})
+		return
+	}
+	term := GlobalTerminalManager.GetTerminal(share.SessionID)
+	if term == nil {
+		_ = conn.WriteJSON(WebSocketMessage{Type: "status", Data: map[string]string{"status": "expired", "message": "The terminal session has ended"}})
+		return
+	}
+
+	_ = conn.WriteJSON(WebSocketMessage{Type: "status", Data: map[string]string{
+		"status":  "connected",
+		"role":    share.Role,
+		"message": "Joined shared terminal session",
+	}})
+
+	if theme, err := loadTheme("tomorrow-night"); err == nil {
+		_ = conn.WriteJSON(map[string]interface{}{"type": "change-theme", "themeOptions": theme})
+	}
+
+	term.Join(conn, share, offset)
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShareStore(t *testing.T) {
+	s := NewShareStore()
+	if _, err := s.Create("tab", "admin", time.Hour); err == nil {
+		t.Fatal("Create() with an unknown role succeeded")
+	}
+	if _, err := s.Create("tab", ShareRoleRead, MaxShareTTL+time.Second); err == nil {
+		t.Fatal("Create() past the longest lifetime succeeded")
+	}
+
+	read, err := s.Create("tab", ShareRoleRead, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write, err := s.Create("tab", ShareRoleWrite, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Token == write.Token || len(read.Token) < 32 {
+		t.Fatalf("tokens %q and %q", read.Token, write.Token)
+	}
+	if got, err := s.Lookup(write.Token); err != nil || got.Role != ShareRoleWrite {
+		t.Fatalf("Lookup() = %+v, %v", got, err)
+	}
+
+	s.RenameSession("tab", "tab2")
+	if got := s.List("tab2"); len(got) != 2 || got[0].Token != read.Token {
+		t.Fatalf("List() = %+v", got)
+	}
+
+	if _, err := s.Revoke(read.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Lookup(read.Token); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("Lookup() of a revoked token = %v", err)
+	}
+
+	// Expired tokens no longer open the session
+	s.shares[write.Token].ExpiresAt = time.Now()
+	if _, err := s.Lookup(write.Token); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("Lookup() of an expired token = %v", err)
+	}
+
+	s.RemoveSession("tab2")
+	if got := s.List("tab2"); len(got) != 0 {
+		t.Fatalf("List() after RemoveSession() = %+v", got)
+	}
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/message"
+	"github.com/choraleia/choraleia/pkg/service/termtransfer"
+	"github.com/google/uuid"
+)
+
+// TaskTypeTerminalTransfer is the task type of rz/sz and trz/tsz transfers
+const TaskTypeTerminalTransfer TaskType = "terminal_transfer"
+
+// transferReadTimeout bounds how long an upload waits for the client to
+// send a piece of a file
+const transferReadTimeout = 30 * time.Second
+
+var errNoClient = errors.New("no client attached to the terminal")
+
+// transfer is a ZMODEM or trzsz transfer running in a terminal. While it
+// runs the remote program's output goes to in rather than the client, and
+// keyboard input is held back.
+type transfer struct {
+	id     string
+	start  termtransfer.Start
+	in     *termtransfer.Stream
+	cancel context.CancelFunc
+
+	// Answers from the client, routed by readFromWebSocket
+	files chan *message.TermTransferFiles
+	data  chan *message.TermTransferData
+
+	mu       sync.Mutex
+	progress TaskProgress
+	changed  chan struct{} // progress changed
+	done     chan struct{} // closed when the transfer ends, err set
+	err      error
+}
+
+// feedTransfer passes output to the transfer in progress, or starts one if
+// the output holds the marker of one. It returns the output that is for
+// the terminal.
+func (t *Terminal) feedTransfer(data []byte) []byte {
+	t.transferMu.Lock()
+	defer t.transferMu.Unlock()
+	if t.transfer != nil {
+		t.transfer.in.Push(data)
+		return nil
+	}
+	start, before, rest := t.detector.Scan(data)
+	if start == nil {
+		return data
+	}
+
+	ctx, cancel := context.WithCancel(t.sessionCtx)
+	tr := &transfer{
+		id:      uuid.NewString(),
+		start:   *start,
+		in:      termtransfer.NewStream(rest),
+		cancel:  cancel,
+		files:   make(chan *message.TermTransferFiles, 1),
+		data:    make(chan *message.TermTransferData, 4),
+		changed: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	t.transfer = tr
+	t.logger.Info("Terminal file transfer started", "protocol", start.Protocol, "direction", start.Direction)
+	go t.runTransfer(ctx, tr)
+	return before
+}
+
+// runTransfer carries out tr, then hands the terminal back
+func (t *Terminal) runTransfer(ctx context.Context, tr *transfer) {
+	defer tr.cancel()
+
+	err := t.sendMessage("transfer_start", map[string]interface{}{
+		"id":        tr.id,
+		"protocol":  tr.start.Protocol,
+		"direction": tr.start.Direction,
+	})
+	// Without a client the peer fails at once, and the remote program is
+	// told the transfer is off
+	if err == nil {
+		t.trackTransfer(tr)
+	}
+	err = termtransfer.Run(ctx, tr.start, tr.in, transferWriter{t}, &transferPeer{t: t, tr: tr})
+	if err == nil && ctx.Err() != nil {
+		err = termtransfer.ErrCanceled
+	}
+
+	tr.mu.Lock()
+	tr.err = err
+	tr.mu.Unlock()
+	close(tr.done)
+
+	end := map[string]interface{}{"id": tr.id}
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, termtransfer.ErrCanceled):
+		end["canceled"] = true
+		t.logger.Info("Terminal file transfer canceled")
+	case err != nil:
+		end["error"] = err.Error()
+		t.logger.Warn("Terminal file transfer failed", "error", err)
+	default:
+		t.logger.Info("Terminal file transfer finished")
+	}
+	_ = t.sendMessage("transfer_end", end)
+
+	// Whatever the transfer left unread is terminal output again. It is sent
+	// before newer output, which waits on transferMu in feedTransfer.
+	t.transferMu.Lock()
+	defer t.transferMu.Unlock()
+	t.transfer = nil
+	if rest := tr.in.Drain(); len(rest) > 0 {
+		if err := t.sendDataToWebSocket(rest); err != nil {
+			t.logger.Debug("Error sending data to websocket", "error", err)
+		}
+	}
+}
+
+// trackTransfer shows the transfer's progress as a task. Canceling the task
+// cancels the transfer.
+func (t *Terminal) trackTransfer(tr *transfer) {
+	if t.tasks == nil {
+		return
+	}
+	verb := "Download"
+	if tr.start.Direction == termtransfer.Upload {
+		verb = "Upload"
+	}
+	title := fmt.Sprintf("%s via %s in %s", verb, tr.start.Protocol, t.sessionID)
+	meta := map[string]interface{}{
+		"session_id": t.sessionID,
+		"transfer":   tr.id,
+		"protocol":   tr.start.Protocol,
+		"direction":  tr.start.Direction,
+	}
+	t.tasks.Enqueue(TaskTypeTerminalTransfer, title, meta, func(ctx context.Context, update func(TaskProgress), setNote func(string)) error {
+		for {
+			select {
+			case <-ctx.Done():
+				tr.cancel()
+				<-tr.done
+				return ctx.Err()
+			case <-tr.changed:
+				tr.mu.Lock()
+				p := tr.progress
+				tr.mu.Unlock()
+				update(p)
+			case <-tr.done:
+				tr.mu.Lock()
+				p, err := tr.progress, tr.err
+				tr.mu.Unlock()
+				update(p)
+				return err
+			}
+		}
+	})
+}
+
+// cancelTransfer stops the transfer in progress, if any
+func (t *Terminal) cancelTransfer() {
+	t.transferMu.Lock()
+	defer t.transferMu.Unlock()
+	if t.transfer != nil {
+		t.transfer.cancel()
+	}
+}
+
+// holdInput reports whether a transfer has the terminal, in which case
+// input is not typed into it. Ctrl+C cancels the transfer.
+func (t *Terminal) holdInput(data []byte) bool {
+	t.transferMu.Lock()
+	defer t.transferMu.Unlock()
+	if t.transfer == nil {
+		return false
+	}
+	if bytes.IndexByte(data, 0x03) >= 0 {
+		t.transfer.cancel()
+	}
+	return true
+}
+
+// currentTransfer returns the transfer in progress if its ID is id
+func (t *Terminal) currentTransfer(id string) *transfer {
+	t.transferMu.Lock()
+	defer t.transferMu.Unlock()
+	if t.transfer == nil || t.transfer.id != id {
+		return nil
+	}
+	return t.transfer
+}
+
+// handleTransferMessage routes a client's answer to the transfer it is for
+func (t *Terminal) handleTransferMessage(m interface{}) {
+	switch msg := m.(type) {
+	case *message.TermTransferFiles:
+		if tr := t.currentTransfer(msg.ID); tr != nil {
+			select {
+			case tr.files <- msg:
+			default:
+			}
+		}
+	case *message.TermTransferData:
+		if tr := t.currentTransfer(msg.ID); tr != nil {
+			select {
+			case tr.data <- msg:
+			default:
+			}
+		}
+	case *message.TermTransferCancel:
+		if tr := t.currentTransfer(msg.ID); tr != nil {
+			tr.cancel()
+		}
+	}
+}
+
+// transferWriter sends the transfer's replies to the remote program
+type transferWriter struct{ t *Terminal }
+
+func (w transferWriter) Write(p []byte) (int, error) {
+	if err := w.t.writeRaw(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// transferPeer is the client's side of a transfer
+type transferPeer struct {
+	t  *Terminal
+	tr *transfer
+}
+
+func (p *transferPeer) Choose(ctx context.Context) ([]termtransfer.File, error) {
+	timer := time.NewTimer(promptTimeout)
+	defer timer.Stop()
+	select {
+	case msg := <-p.tr.files:
+		if msg.Cancel {
+			return nil, nil
+		}
+		files := make([]termtransfer.File, 0, len(msg.Files))
+		for _, f := range msg.Files {
+			files = append(files, termtransfer.File{Name: f.Name, Size: f.Size})
+		}
+		return files, nil
+	case <-timer.C:
+		return nil, errors.New("no files were chosen in time")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *transferPeer) ReadAt(ctx context.Context, file int, offset int64, n int) ([]byte, error) {
+	err := p.t.sendMessage("transfer_read", map[string]interface{}{
+		"id":     p.tr.id,
+		"file":   file,
+		"offset": offset,
+		"size":   n,
+	})
+	if err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(transferReadTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case msg := <-p.tr.data:
+			// Answers to reads that timed out are dropped
+			if msg.File != file || msg.Offset != offset {
+				continue
+			}
+			if msg.Error != "" {
+				return nil, errors.New(msg.Error)
+			}
+			if len(msg.Data) > n {
+				msg.Data = msg.Data[:n]
+			}
+			return msg.Data, nil
+		case <-timer.C:
+			return nil, errors.New("timed out reading the file from the client")
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (p *transferPeer) Create(_ context.Context, name string, size int64) error {
+	return p.t.sendMessage("transfer_file", map[string]interface{}{
+		"id":   p.tr.id,
+		"name": name,
+		"size": size,
+	})
+}
+
+func (p *transferPeer) Write(_ context.Context, data []byte) error {
+	return p.t.sendMessage("transfer_data", map[string]interface{}{
+		"id":   p.tr.id,
+		"data": data,
+	})
+}
+
+func (p *transferPeer) Close(_ context.Context, complete bool) error {
+	return p.t.sendMessage("transfer_file_end", map[string]interface{}{
+		"id":       p.tr.id,
+		"complete": complete,
+	})
+}
+
+func (p *transferPeer) Progress(done, total int64, file string) {
+	p.tr.mu.Lock()
+	p.tr.progress = TaskProgress{Total: total, Done: done, Unit: "bytes", Note: file}
+	p.tr.mu.Unlock()
+	select {
+	case p.tr.changed <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/choraleia/choraleia/pkg/message"
+	"github.com/choraleia/choraleia/pkg/service/termtransfer"
+)
+
+// The client answers each upload read in one message, which the terminal's
+// read limit has to let through
+func TestTransferDataFitsReadLimit(t *testing.T) {
+	msg := message.TermTransferData{
+		Base:   message.Base{Type: "TermTransferData"},
+		ID:     "5f0e8a52-58d7-4c9b-9e3a-0d6d2b1c8f47",
+		File:   99,
+		Offset: 1 << 40,
+		Data:   make([]byte, termtransfer.MaxRead),
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > terminalReadLimit {
+		t.Fatalf("a %d byte read is answered in %d bytes, over the %d byte limit",
+			termtransfer.MaxRead, len(data), terminalReadLimit)
+	}
+}
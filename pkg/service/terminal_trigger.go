@@ -0,0 +1,220 @@
+package service
+
+import (
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/event"
+	"github.com/choraleia/choraleia/pkg/models"
+)
+
+// Trigger limits. Triggers that write to the terminal or raise events fire
+// at most once per cooldown and triggerBurst times a minute in a session,
+// so a response that brings back its own prompt can't loop.
+const (
+	defaultTriggerCooldown = time.Second
+	triggerBurst           = 10
+	// maxTriggerLine bounds the line triggers see; the rest is ignored
+	maxTriggerLine = 4096
+)
+
+// triggerFiring is a trigger that matched, with the line and where
+type triggerFiring struct {
+	trigger *compiledTrigger
+	line    []byte
+	match   []int // submatch indexes into line
+}
+
+// expand fills $0, $1 and so on in template from the match
+func (f triggerFiring) expand(template string) string {
+	return string(f.trigger.re.Expand(nil, []byte(template), f.line, f.match))
+}
+
+// triggerFires is when a trigger fired in a session, for its limits
+type triggerFires struct {
+	last   time.Time
+	minute time.Time // start of the minute being counted
+	count  int
+}
+
+// triggerScanner runs triggers over a session's output. Escape sequences
+// are dropped and each trigger fires at most once per line. Respond and
+// notify triggers also see a line as it grows, so a prompt waiting for input
+// is answered; highlights and captures wait for the line to end, so they
+// take all of the match.
+type triggerScanner struct {
+	line  []byte
+	esc   escState
+	fired map[string]bool // triggers that fired on the current line
+	fires map[string]*triggerFires
+}
+
+type escState int
+
+const (
+	escNone escState = iota
+	escStart
+	escCSI
+	escString // OSC, DCS and the like, up to BEL or ST
+	escStringEnd
+)
+
+// scan feeds output to the scanner and returns the triggers that fire
+func (s *triggerScanner) scan(data []byte, triggers []*compiledTrigger, now time.Time) []triggerFiring {
+	if s.fired == nil {
+		s.fired = make(map[string]bool)
+		s.fires = make(map[string]*triggerFires)
+	}
+	var firings []triggerFiring
+	for _, b := range data {
+		switch s.esc {
+		case escStart:
+			switch b {
+			case '[':
+				s.esc = escCSI
+			case ']', 'P', '_', '^', 'X':
+				s.esc = escString
+			default:
+				s.esc = escNone
+			}
+			continue
+		case escCSI:
+			if b >= 0x40 && b <= 0x7e {
+				s.esc = escNone
+			}
+			continue
+		case escString:
+			if b == 0x07 {
+				s.esc = escNone
+			} else if b == 0x1b {
+				s.esc = escStringEnd
+			}
+			continue
+		case escStringEnd:
+			s.esc = escNone
+			continue
+		}
+
+		switch {
+		case b == 0x1b:
+			s.esc = escStart
+		case b == '\n':
+			firings = s.match(firings, triggers, true, now)
+			s.line = s.line[:0]
+			clear(s.fired)
+		case b < 0x20 && b != '\t':
+			// Carriage returns, bells and the like don't show
+		case len(s.line) < maxTriggerLine:
+			s.line = append(s.line, b)
+		}
+	}
+	return s.match(firings, triggers, false, now)
+}
+
+// match runs the triggers that haven't fired on the current line, which is
+// complete once it has ended
+func (s *triggerScanner) match(firings []triggerFiring, triggers []*compiledTrigger, complete bool, now time.Time) []triggerFiring {
+	if len(s.line) == 0 {
+		return firings
+	}
+	for _, tr := range triggers {
+		if s.fired[tr.ID] || !complete && !tr.partial() {
+			continue
+		}
+		m := tr.re.FindSubmatchIndex(s.line)
+		if m == nil || m[0] == m[1] {
+			continue
+		}
+		s.fired[tr.ID] = true
+		if !s.allow(tr, now) {
+			continue
+		}
+		firings = append(firings, triggerFiring{trigger: tr, line: append([]byte(nil), s.line...), match: m})
+	}
+	return firings
+}
+
+// partial reports whether the trigger runs on lines that haven't ended
+func (tr *compiledTrigger) partial() bool {
+	return tr.Action == models.TriggerRespond || tr.Action == models.TriggerNotify
+}
+
+// allow applies the trigger's limits to a firing at now
+func (s *triggerScanner) allow(tr *compiledTrigger, now time.Time) bool {
+	cooldown := time.Duration(tr.Cooldown) * time.Second
+	limited := tr.partial()
+	if cooldown == 0 && limited {
+		cooldown = defaultTriggerCooldown
+	}
+	f := s.fires[tr.ID]
+	if f == nil {
+		f = &triggerFires{}
+		s.fires[tr.ID] = f
+	}
+	if !f.last.IsZero() && now.Sub(f.last) < cooldown {
+		return false
+	}
+	if limited {
+		if now.Sub(f.minute) >= time.Minute {
+			f.minute, f.count = now, 0
+		}
+		if f.count >= triggerBurst {
+			return false
+		}
+		f.count++
+	}
+	f.last = now
+	return true
+}
+
+// runTriggers runs the asset's triggers over output sent to the terminal.
+// It is called from the output loop only.
+func (t *Terminal) runTriggers(data []byte) {
+	if t.triggers == nil {
+		return
+	}
+	triggers := t.triggers.forAsset(t.assetID)
+	if len(triggers) == 0 {
+		return
+	}
+	for _, f := range t.triggerScanner.scan(data, triggers, time.Now()) {
+		t.fireTrigger(f)
+	}
+}
+
+func (t *Terminal) fireTrigger(f triggerFiring) {
+	tr := f.trigger
+	switch tr.Action {
+	case models.TriggerHighlight:
+		color := tr.Color
+		if color == "" {
+			color = "#ffd54f"
+		}
+		_ = t.sendMessage("trigger_highlight", map[string]interface{}{
+			"trigger_id": tr.ID,
+			"text":       string(f.line[f.match[0]:f.match[1]]),
+			"color":      color,
+		})
+	case models.TriggerNotify:
+		message := tr.Message
+		if message == "" {
+			message = "$0"
+		}
+		event.Emit(event.TerminalTriggerEvent{
+			SessionID: t.sessionID,
+			AssetID:   t.assetID,
+			TriggerID: tr.ID,
+			Name:      tr.Name,
+			Message:   f.expand(message),
+		})
+	case models.TriggerRespond:
+		t.logger.Info("Trigger responding", "trigger", tr.Name, "sessionId", t.sessionID)
+		t.writeToTerminal([]byte(f.expand(tr.Response)))
+	case models.TriggerCapture:
+		value := f.expand("$0")
+		if len(f.match) >= 4 && f.match[2] >= 0 {
+			value = f.expand("$1")
+		}
+		GlobalTerminalManager.SetVariable(t.sessionID, tr.Variable, value)
+		_ = t.sendMessage("variables", GlobalTerminalManager.Variables(t.sessionID))
+	}
+}
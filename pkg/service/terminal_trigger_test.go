@@ -0,0 +1,97 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+)
+
+func testTrigger(t *testing.T, id string, action models.TriggerAction, pattern string) *compiledTrigger {
+	t.Helper()
+	tr, err := compileTrigger(id, &models.TriggerRequest{
+		Name: id, Pattern: pattern, Action: action, Response: "yes\r", Variable: "v",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+func TestTriggerScanner(t *testing.T) {
+	host := testTrigger(t, "host", models.TriggerCapture, `host: (\S+)`)
+	prompt := testTrigger(t, "prompt", models.TriggerRespond, `\(yes/no\)\? $`)
+	triggers := []*compiledTrigger{host, prompt}
+	now := time.Now()
+
+	var s triggerScanner
+	// Colors and a title are dropped, and a capture waits for its line,
+	// though it comes in several reads
+	if got := s.scan([]byte("\x1b[1mhost: \x1b[32mweb"), triggers, now); len(got) != 0 {
+		t.Fatalf("capture fired on a partial line")
+	}
+	got := s.scan([]byte("01\x1b[0m\x1b]0;title\x07\r\nnext"), triggers, now)
+	if len(got) != 1 || got[0].trigger != host || got[0].expand("$1") != "web01" {
+		t.Fatalf("capture: got %d firings", len(got))
+	}
+
+	// A prompt fires before its line ends, and only once on the line
+	got = s.scan([]byte("\r\nContinue (yes/no)? "), triggers, now)
+	if len(got) != 1 || got[0].trigger != prompt {
+		t.Fatalf("prompt: got %d firings", len(got))
+	}
+	if got = s.scan([]byte("y"), triggers, now); len(got) != 0 {
+		t.Fatalf("prompt fired again on its line")
+	}
+
+	// The prompt coming back within the cooldown doesn't fire
+	if got = s.scan([]byte("\r\nContinue (yes/no)? "), triggers, now.Add(time.Second/2)); len(got) != 0 {
+		t.Fatalf("prompt fired within its cooldown")
+	}
+
+	// Nor more than triggerBurst times a minute
+	fired := 0
+	for i := 1; i <= 2*triggerBurst; i++ {
+		fired += len(s.scan([]byte("\r\nContinue (yes/no)? "), triggers, now.Add(time.Duration(i)*time.Second)))
+	}
+	if fired != triggerBurst-1 {
+		t.Fatalf("fired %d times in a minute, want %d", fired, triggerBurst-1)
+	}
+}
+
+func TestTriggerServicePersists(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "triggers.json")
+	s := newTriggerService(file)
+	if _, err := s.Create(&models.TriggerRequest{Name: "bad", Pattern: "(", Action: models.TriggerHighlight}); err == nil {
+		t.Fatal("created a trigger with an invalid pattern")
+	}
+	if _, err := s.Create(&models.TriggerRequest{Name: "capture", Pattern: "x", Action: models.TriggerCapture, Variable: "1x"}); err == nil {
+		t.Fatal("created a capture into an invalid variable name")
+	}
+	disabled := false
+	a, err := s.Create(&models.TriggerRequest{AssetID: "a", Name: "err", Pattern: "error", Action: models.TriggerHighlight})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(&models.TriggerRequest{Name: "off", Pattern: "x", Action: models.TriggerNotify, Enabled: &disabled}); err != nil {
+		t.Fatal(err)
+	}
+
+	s = newTriggerService(file)
+	if list := s.List(""); len(list) != 2 || list[0].ID != a.ID {
+		t.Fatalf("reloaded %d triggers", len(list))
+	}
+	if got := s.forAsset("a"); len(got) != 1 || got[0].ID != a.ID {
+		t.Fatalf("asset a runs %d triggers", len(got))
+	}
+	if got := s.forAsset("b"); len(got) != 0 {
+		t.Fatalf("asset b runs %d triggers", len(got))
+	}
+	if err := s.Delete(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(a.ID); err != ErrTriggerNotFound {
+		t.Fatalf("Get after Delete: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+// Package termtransfer moves files through a terminal session, the way rz/sz
+// and trz/tsz do: it spots a transfer starting in the output of the remote
+// program and then speaks ZMODEM or trzsz to it in place of the terminal.
+// The user's side of the transfer, where files come from and go to, is a
+// Peer.
+package termtransfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Protocols
+const (
+	ProtocolZmodem = "zmodem"
+	ProtocolTrzsz  = "trzsz"
+)
+
+// Directions, from the user's side
+const (
+	Upload   = "upload"   // rz, trz: files go to the remote host
+	Download = "download" // sz, tsz: files come from the remote host
+)
+
+// ErrCanceled is returned when either side gives up on the transfer
+var ErrCanceled = errors.New("transfer canceled")
+
+// MaxRead is the most a protocol asks Peer.ReadAt for. The client answers
+// a read in one WebSocket message, base64-encoded, which has to stay under
+// the terminal's read limit.
+const MaxRead = 16 * 1024
+
+// File describes a file to upload
+type File struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// Peer is the user's side of a transfer. Methods get the transfer's
+// context and may block; an error ends the transfer.
+type Peer interface {
+	// Choose asks for the files to upload; none means the user declined
+	Choose(ctx context.Context) ([]File, error)
+	// ReadAt returns up to n bytes, at most MaxRead, of the chosen file at
+	// index file from offset; fewer only at the end of the file
+	ReadAt(ctx context.Context, file int, offset int64, n int) ([]byte, error)
+	// Create starts a downloaded file, Write appends to it and Close ends
+	// it, complete or not
+	Create(ctx context.Context, name string, size int64) error
+	Write(ctx context.Context, data []byte) error
+	Close(ctx context.Context, complete bool) error
+	// Progress reports the bytes moved so far, out of total if known
+	Progress(done, total int64, file string)
+}
+
+// Start is a transfer found in terminal output
+type Start struct {
+	Protocol  string
+	Direction string
+}
+
+// Run carries out a transfer started with s. in gets the remote program's
+// output from the start marker on; the replies go to out.
+func Run(ctx context.Context, s Start, in *Stream, out io.Writer, peer Peer) error {
+	switch {
+	case s.Protocol == ProtocolZmodem && s.Direction == Download:
+		return zmodemReceive(ctx, in, out, peer)
+	case s.Protocol == ProtocolZmodem:
+		return zmodemSend(ctx, in, out, peer)
+	default:
+		return trzszRun(ctx, in, out, peer)
+	}
+}
+
+// Markers that start a transfer. sz opens with a ZRQINIT header and rz with
+// a ZRINIT one; trz and tsz print a line with their mode after the magic.
+var (
+	zrqinitMarker = []byte("**\x18B00")
+	zrinitMarker  = []byte("**\x18B01")
+	trzszMarker   = []byte("::TRZSZ:TRANSFER:")
+	// trzszPrefix comes before the magic: save cursor and a bell
+	trzszPrefix = []byte("\x1b7\x07")
+)
+
+// maxMarker bounds how much output Detector keeps to find markers split
+// across reads
+const maxMarker = 32
+
+// Detector finds the start of transfers in terminal output
+type Detector struct {
+	tail []byte // end of the output scanned before
+}
+
+// Scan looks for a transfer starting in data. If one does, it returns the
+// output before the start, which is for the terminal, and the output from
+// the start marker on, which is for the transfer. A marker split across
+// calls is found too; its first part has gone to the terminal already.
+func (d *Detector) Scan(data []byte) (*Start, []byte, []byte) {
+	buf := append(d.tail, data...)
+	at, start := -1, (*Start)(nil)
+	for _, m := range []struct {
+		marker []byte
+		start  Start
+	}{
+		{zrqinitMarker, Start{ProtocolZmodem, Download}},
+		{zrinitMarker, Start{ProtocolZmodem, Upload}},
+		{trzszMarker, Start{ProtocolTrzsz, ""}},
+	} {
+		if i := bytes.Index(buf, m.marker); i >= 0 && (at < 0 || i < at) {
+			at, start = i, &m.start
+		}
+	}
+	if start == nil {
+		if len(buf) > maxMarker {
+			buf = buf[len(buf)-maxMarker:]
+		}
+		d.tail = append(d.tail[:0], buf...)
+		return nil, data, nil
+	}
+	d.tail = d.tail[:0]
+
+	if start.Protocol == ProtocolTrzsz {
+		start.Direction = trzszDirection(buf[at+len(trzszMarker):])
+	}
+	seen := len(buf) - len(data) // bytes of buf from earlier calls
+	if at < seen {
+		return start, nil, append([]byte(nil), buf[at:]...)
+	}
+	before := data[:at-seen]
+	if start.Protocol == ProtocolTrzsz {
+		before = bytes.TrimSuffix(before, trzszPrefix)
+	}
+	return start, before, data[at-seen:]
+}
+
+// trzszDirection reads the mode after the trzsz magic: R and D for trz, S
+// for tsz
+func trzszDirection(mode []byte) string {
+	if len(mode) > 0 && mode[0] == 'S' {
+		return Download
+	}
+	return Upload
+}
+
+// Stream carries the remote program's output to a transfer. Push adds to it
+// as the output arrives; the transfer reads it with timeouts.
+type Stream struct {
+	mu     sync.Mutex
+	buf    []byte
+	notify chan struct{}
+	local  []byte // taken from buf, being read
+}
+
+// NewStream creates a stream holding data
+func NewStream(data []byte) *Stream {
+	return &Stream{buf: append([]byte(nil), data...), notify: make(chan struct{}, 1)}
+}
+
+// Push adds output to the stream
+func (s *Stream) Push(data []byte) {
+	s.mu.Lock()
+	s.buf = append(s.buf, data...)
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Drain returns what the transfer left unread
+func (s *Stream) Drain() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rest := append(s.local, s.buf...)
+	s.local, s.buf = nil, nil
+	return rest
+}
+
+// errTimeout is returned by reads that wait too long
+var errTimeout = errors.New("timed out waiting for the remote side")
+
+// readByte returns the next byte, waiting up to timeout for it
+func (s *Stream) readByte(ctx context.Context, timeout time.Duration) (byte, error) {
+	if len(s.local) == 0 && !s.fill(ctx, timeout) {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, errTimeout
+	}
+	b := s.local[0]
+	s.local = s.local[1:]
+	return b, nil
+}
+
+// fill moves pushed output into local, waiting up to timeout for some
+func (s *Stream) fill(ctx context.Context, timeout time.Duration) bool {
+	var timer *time.Timer
+	for {
+		s.mu.Lock()
+		if len(s.buf) > 0 {
+			s.local, s.buf = s.buf, s.local[:0]
+			s.mu.Unlock()
+			return true
+		}
+		s.mu.Unlock()
+		if timer == nil {
+			timer = time.NewTimer(timeout)
+			defer timer.Stop()
+		}
+		select {
+		case <-s.notify:
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// peekByte returns the next byte without waiting, if there is one
+func (s *Stream) peekByte() (byte, bool) {
+	if len(s.local) == 0 {
+		s.mu.Lock()
+		s.local, s.buf = s.buf, s.local[:0]
+		s.mu.Unlock()
+	}
+	if len(s.local) == 0 {
+		return 0, false
+	}
+	return s.local[0], true
+}
+
+// baseName keeps the last element of a path the remote side sent, so a
+// download can't name a file outside where the user saves it
+func baseName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return "download"
+	}
+	return name
+}
@@ -0,0 +1,248 @@
+package termtransfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePeer uploads files from memory and keeps what it downloads
+type fakePeer struct {
+	mu      sync.Mutex
+	upload  map[string][]byte
+	order   []string
+	got     map[string][]byte
+	current string
+	maxRead int // Largest read asked for
+}
+
+func (p *fakePeer) Choose(context.Context) ([]File, error) {
+	var files []File
+	for _, name := range p.order {
+		files = append(files, File{Name: name, Size: int64(len(p.upload[name]))})
+	}
+	return files, nil
+}
+
+func (p *fakePeer) ReadAt(_ context.Context, file int, offset int64, n int) ([]byte, error) {
+	p.maxRead = max(p.maxRead, n)
+	data := p.upload[p.order[file]][offset:]
+	return data[:min(n, len(data))], nil
+}
+
+func (p *fakePeer) Create(_ context.Context, name string, _ int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = name
+	p.got[name] = []byte{}
+	return nil
+}
+
+func (p *fakePeer) Write(_ context.Context, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.got[p.current] = append(p.got[p.current], data...)
+	return nil
+}
+
+func (p *fakePeer) Close(context.Context, bool) error { return nil }
+
+func (p *fakePeer) Progress(int64, int64, string) {}
+
+// link writes into a stream, corrupting the write numbered corrupt
+type link struct {
+	s       *Stream
+	writes  int
+	corrupt int
+}
+
+func (l *link) Write(p []byte) (int, error) {
+	l.writes++
+	data := append([]byte(nil), p...)
+	if l.writes == l.corrupt && len(data) > 100 {
+		data[50] ^= 0x01
+	}
+	l.s.Push(data)
+	return len(p), nil
+}
+
+func TestZmodem(t *testing.T) {
+	big := make([]byte, 5000)
+	for i := range big {
+		big[i] = byte(i * 7)
+	}
+	sender := &fakePeer{
+		upload: map[string][]byte{"big.bin": big, "empty": {}, "escapes": []byte("\x18\x11\x13\x10@\r*\x18B00\xff\x7f")},
+		order:  []string{"big.bin", "empty", "escapes"},
+	}
+	receiver := &fakePeer{got: map[string][]byte{}}
+
+	// The sender starts on the receiver's ZRINIT
+	toSender := NewStream(nil)
+	toReceiver := NewStream(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- zmodemSend(ctx, toSender, &link{s: toReceiver, corrupt: 5}, sender)
+	}()
+	go func() {
+		errs <- zmodemReceive(ctx, toReceiver, &link{s: toSender}, receiver)
+	}()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, want := range sender.upload {
+		if got := receiver.got[name]; !bytes.Equal(got, want) {
+			t.Fatalf("%s: got %d bytes, want %d", name, len(got), len(want))
+		}
+	}
+}
+
+func TestHexHeader(t *testing.T) {
+	var out bytes.Buffer
+	z := &zmodem{out: &out}
+	h := header{typ: zrinit}
+	h.data[3] = canFDX | canOVIO | canFC32
+	if err := z.sendHexHeader(h); err != nil {
+		t.Fatal(err)
+	}
+	// As sent by lrzsz rz
+	if got := out.String(); got != "**\x18B0100000023be50\r\x8a\x11" {
+		t.Fatalf("sendHexHeader() = %q", got)
+	}
+}
+
+func TestDetector(t *testing.T) {
+	var d Detector
+	if s, before, rest := d.Scan([]byte("$ sz file\r\nrz\r*")); s != nil || string(before) != "$ sz file\r\nrz\r*" || rest != nil {
+		t.Fatalf("Scan() = %v, %q, %q", s, before, rest)
+	}
+	// The marker is split across reads
+	s, before, rest := d.Scan([]byte("*\x18B00000000000000\r\x8a\x11"))
+	if s == nil || *s != (Start{ProtocolZmodem, Download}) || before != nil || string(rest) != "**\x18B00000000000000\r\x8a\x11" {
+		t.Fatalf("Scan() = %v, %q, %q", s, before, rest)
+	}
+
+	s, before, rest = d.Scan([]byte("x\x1b7\x07::TRZSZ:TRANSFER:S:1.1.6:1234567890123\r\n"))
+	if s == nil || *s != (Start{ProtocolTrzsz, Download}) || string(before) != "x" || !bytes.HasPrefix(rest, trzszMarker) {
+		t.Fatalf("Scan() = %v, %q, %q", s, before, rest)
+	}
+}
+
+// fakeTsz plays the remote tsz sending one file
+func fakeTsz(t *testing.T, ctx context.Context, in *Stream, out *link, name string, data []byte) error {
+	r := &trzsz{ctx: ctx, in: in, out: out, newline: "\n"}
+	act, err := r.recvString("ACT")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(act, `"confirm":true`) {
+		return fmt.Errorf("action %s", act)
+	}
+	steps := []func() error{
+		func() error {
+			return r.sendString("CFG", `{"timeout":5,"newline":"\n","protocol":2,"bufsize":10485760}`)
+		},
+		func() error { return r.sendInteger("NUM", 1) },
+		func() error { return r.checkInteger(1) },
+		func() error { return r.sendString("NAME", "../"+name) },
+		func() error {
+			got, err := r.recvString("SUCC")
+			if err == nil && got != name {
+				err = fmt.Errorf("saved as %q", got)
+			}
+			return err
+		},
+		func() error { return r.sendInteger("SIZE", int64(len(data))) },
+		func() error { return r.checkInteger(int64(len(data))) },
+		func() error { return r.sendLine("DATA", encodeTrzsz(data[:3], true)) },
+		func() error { return r.checkInteger(3) },
+		func() error { return r.sendLine("DATA", encodeTrzsz(data[3:], false)) },
+		func() error { return r.checkInteger(int64(len(data) - 3)) },
+		func() error {
+			sum := md5.Sum(data)
+			return r.sendLine("MD5", encodeTrzsz(sum[:], true))
+		},
+		func() error { _, err := r.recv("SUCC"); return err },
+		func() error { _, err := r.recvString("EXIT"); return err },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestTrzszDownload(t *testing.T) {
+	peer := &fakePeer{got: map[string][]byte{}}
+	toClient := NewStream([]byte("::TRZSZ:TRANSFER:S:1.1.6:1234567890123\r\n"))
+	toServer := NewStream(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	errs := make(chan error, 1)
+	go func() { errs <- fakeTsz(t, ctx, toServer, &link{s: toClient}, "notes.txt", []byte("hello, trzsz\n")) }()
+	if err := trzszRun(ctx, toClient, &link{s: toServer}, peer); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if got := string(peer.got["notes.txt"]); got != "hello, trzsz\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+// fakeTrz plays the remote trz receiving files
+func fakeTrz(ctx context.Context, in *Stream, out *link, peer Peer) error {
+	r := &trzsz{ctx: ctx, in: in, out: out, newline: "\n"}
+	if _, err := r.recvString("ACT"); err != nil {
+		return err
+	}
+	if err := r.sendString("CFG", `{"timeout":5,"newline":"\n","protocol":2,"bufsize":10485760}`); err != nil {
+		return err
+	}
+	if _, err := r.recvFiles(peer); err != nil {
+		return err
+	}
+	_, err := r.recvString("EXIT")
+	return err
+}
+
+func TestTrzszUpload(t *testing.T) {
+	big := make([]byte, 2*MaxRead+100)
+	for i := range big {
+		big[i] = byte(i * 7)
+	}
+	sender := &fakePeer{upload: map[string][]byte{"big.bin": big}, order: []string{"big.bin"}}
+	receiver := &fakePeer{got: map[string][]byte{}}
+	toClient := NewStream([]byte("::TRZSZ:TRANSFER:R:1.1.6:1234567890123\r\n"))
+	toServer := NewStream(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	errs := make(chan error, 1)
+	go func() { errs <- fakeTrz(ctx, toServer, &link{s: toClient}, receiver) }()
+	if err := trzszRun(ctx, toClient, &link{s: toServer}, sender); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if got := receiver.got["big.bin"]; !bytes.Equal(got, big) {
+		t.Fatalf("got %d bytes, want %d", len(got), len(big))
+	}
+	if sender.maxRead > MaxRead {
+		t.Fatalf("read %d bytes at once, more than %d", sender.maxRead, MaxRead)
+	}
+}
@@ -0,0 +1,416 @@
+package termtransfer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trzsz is spoken in lines of "#TYPE:value". Strings and binary values are
+// zlib-compressed and base64-encoded. Data goes the same way, since the
+// action we send doesn't offer binary mode.
+const (
+	trzszVersion  = "1.1.6"
+	trzszProtocol = 2
+	trzszTimeout  = 20 * time.Second
+	// trzszChunk is the size of the data lines sent
+	trzszChunk = MaxRead
+	// maxTrzszLine bounds the lines received
+	maxTrzszLine = 16 << 20
+)
+
+// trzszAction answers the remote trz or tsz
+type trzszAction struct {
+	Lang             string `json:"lang"`
+	Version          string `json:"version"`
+	Confirm          bool   `json:"confirm"`
+	Newline          string `json:"newline"`
+	Protocol         int    `json:"protocol"`
+	SupportBinary    bool   `json:"binary"`
+	SupportDirectory bool   `json:"support_dir"`
+}
+
+// trzszConfig is what the remote side settles on
+type trzszConfig struct {
+	Binary     bool   `json:"binary"`
+	Directory  bool   `json:"directory"`
+	Timeout    int    `json:"timeout"`
+	Newline    string `json:"newline"`
+	Protocol   int    `json:"protocol"`
+	MaxBufSize int64  `json:"bufsize"`
+	Compress   int    `json:"compress"` // 2 turns compression off
+}
+
+type trzsz struct {
+	ctx     context.Context
+	in      *Stream
+	out     io.Writer
+	newline string
+	cfg     trzszConfig
+}
+
+// trzszError is an error the remote side reported
+type trzszError struct{ msg string }
+
+func (e *trzszError) Error() string { return "trzsz: " + e.msg }
+
+func encodeTrzsz(data []byte, compress bool) string {
+	if !compress {
+		return base64.StdEncoding.EncodeToString(data)
+	}
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func decodeTrzsz(s string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		// Sent without compression
+		return data, nil
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (t *trzsz) sendLine(typ, value string) error {
+	_, err := io.WriteString(t.out, "#"+typ+":"+value+t.newline)
+	return err
+}
+
+func (t *trzsz) sendInteger(typ string, v int64) error {
+	return t.sendLine(typ, strconv.FormatInt(v, 10))
+}
+
+func (t *trzsz) sendString(typ, s string) error {
+	return t.sendLine(typ, encodeTrzsz([]byte(s), true))
+}
+
+// readLine reads up to the next newline, dropping the carriage return or
+// the "!" some remotes put before it
+func (t *trzsz) readLine(timeout time.Duration) (string, error) {
+	var line []byte
+	for {
+		b, err := t.in.readByte(t.ctx, timeout)
+		if err != nil {
+			return "", err
+		}
+		if b == '\n' {
+			return strings.TrimRight(string(line), "\r!"), nil
+		}
+		if len(line) >= maxTrzszLine {
+			return "", errors.New("trzsz: line too long")
+		}
+		line = append(line, b)
+	}
+}
+
+// recv reads the value of a line of type typ. Anything before the "#" is
+// junk the terminal added. A failure the remote side reports is returned
+// as an error.
+func (t *trzsz) recv(typ string) (string, error) {
+	line, err := t.readLine(t.timeout())
+	if err != nil {
+		return "", err
+	}
+	if i := strings.LastIndex(line, "#"+typ+":"); i >= 0 {
+		return line[i+len(typ)+2:], nil
+	}
+	for _, fail := range []string{"#fail:", "#FAIL:"} {
+		if i := strings.LastIndex(line, fail); i >= 0 {
+			msg, err := decodeTrzsz(line[i+len(fail):])
+			if err != nil {
+				return "", &trzszError{msg: line[i+len(fail):]}
+			}
+			return "", &trzszError{msg: string(msg)}
+		}
+	}
+	return "", fmt.Errorf("trzsz: expected %s, got %q", typ, line)
+}
+
+func (t *trzsz) recvInteger(typ string) (int64, error) {
+	v, err := t.recv(typ)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+func (t *trzsz) recvString(typ string) (string, error) {
+	v, err := t.recv(typ)
+	if err != nil {
+		return "", err
+	}
+	data, err := decodeTrzsz(v)
+	return string(data), err
+}
+
+func (t *trzsz) timeout() time.Duration {
+	if t.cfg.Timeout > 0 {
+		return time.Duration(t.cfg.Timeout) * time.Second
+	}
+	return trzszTimeout
+}
+
+// fail tells the remote side why the transfer stopped; it prints the reason
+func (t *trzsz) fail(err error) {
+	_ = t.sendString("fail", err.Error())
+}
+
+// trzszRun carries out a trz or tsz transfer
+func trzszRun(ctx context.Context, in *Stream, out io.Writer, peer Peer) (err error) {
+	t := &trzsz{ctx: ctx, in: in, out: out, newline: "\n"}
+
+	// The stream opens with the magic line: ::TRZSZ:TRANSFER:mode:version:id
+	magic, err := t.readLine(trzszTimeout)
+	if err != nil {
+		return err
+	}
+	fields := strings.Split(magic[strings.Index(magic, string(trzszMarker))+len(trzszMarker):], ":")
+	mode := fields[0]
+
+	var files []File
+	if mode == "R" {
+		if files, err = peer.Choose(ctx); err != nil {
+			return err
+		}
+	}
+	// Directories are neither sent nor taken
+	confirm := mode == "S" || mode == "R" && len(files) > 0
+	action, _ := json.Marshal(trzszAction{
+		Lang:     "go",
+		Version:  trzszVersion,
+		Confirm:  confirm,
+		Newline:  "\n",
+		Protocol: trzszProtocol,
+	})
+	if err := t.sendString("ACT", string(action)); err != nil {
+		return err
+	}
+	if !confirm {
+		if mode == "D" {
+			return errors.New("trzsz: directory transfers are not supported")
+		}
+		return nil
+	}
+
+	defer func() {
+		var remote *trzszError
+		if err != nil && !errors.As(err, &remote) {
+			t.fail(err)
+		}
+	}()
+	cfg, err := t.recvString("CFG")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(cfg), &t.cfg); err != nil {
+		return fmt.Errorf("trzsz: bad config: %w", err)
+	}
+	if t.cfg.Newline != "" {
+		t.newline = t.cfg.Newline
+	}
+	if t.cfg.Binary || t.cfg.Directory {
+		return errors.New("trzsz: binary and directory transfers are not supported")
+	}
+
+	var names []string
+	if mode == "S" {
+		names, err = t.recvFiles(peer)
+	} else {
+		names, err = t.sendFiles(peer, files)
+	}
+	if err != nil {
+		return err
+	}
+	return t.sendString("EXIT", fmt.Sprintf("Transferred %d file(s): %s\r\n", len(names), strings.Join(names, ", ")))
+}
+
+// sendFiles uploads the chosen files, returning their names on the remote
+// side
+func (t *trzsz) sendFiles(peer Peer, files []File) ([]string, error) {
+	var total, done int64
+	for _, f := range files {
+		total += f.Size
+	}
+	if err := t.sendInteger("NUM", int64(len(files))); err != nil {
+		return nil, err
+	}
+	if err := t.checkInteger(int64(len(files))); err != nil {
+		return nil, err
+	}
+
+	chunk := int64(trzszChunk)
+	if t.cfg.MaxBufSize > 0 && t.cfg.MaxBufSize < chunk {
+		chunk = t.cfg.MaxBufSize
+	}
+	var names []string
+	for i, f := range files {
+		if err := t.sendString("NAME", f.Name); err != nil {
+			return nil, err
+		}
+		name, err := t.recvString("SUCC")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+
+		if err := t.sendInteger("SIZE", f.Size); err != nil {
+			return nil, err
+		}
+		if err := t.checkInteger(f.Size); err != nil {
+			return nil, err
+		}
+		hash := md5.New()
+		for pos := int64(0); pos < f.Size; {
+			data, err := peer.ReadAt(t.ctx, i, pos, int(min(chunk, f.Size-pos)))
+			if err != nil {
+				return nil, err
+			}
+			if len(data) == 0 {
+				return nil, fmt.Errorf("%s ended early", f.Name)
+			}
+			if err := t.sendLine("DATA", encodeTrzsz(data, t.cfg.Compress != 2)); err != nil {
+				return nil, err
+			}
+			// Acknowledged with the chunk's length, or the bytes so far
+			ack, err := t.recvInteger("SUCC")
+			if err != nil {
+				return nil, err
+			}
+			hash.Write(data)
+			pos += int64(len(data))
+			if ack != int64(len(data)) && ack != pos {
+				return nil, fmt.Errorf("trzsz: %s: %d bytes acknowledged, %d sent", f.Name, ack, len(data))
+			}
+			peer.Progress(done+pos, total, f.Name)
+		}
+		done += f.Size
+
+		digest := hash.Sum(nil)
+		if err := t.sendLine("MD5", encodeTrzsz(digest, true)); err != nil {
+			return nil, err
+		}
+		v, err := t.recv("SUCC")
+		if err != nil {
+			return nil, err
+		}
+		if got, err := decodeTrzsz(v); err != nil || !bytes.Equal(got, digest) {
+			return nil, fmt.Errorf("trzsz: %s: checksum mismatch", f.Name)
+		}
+	}
+	return names, nil
+}
+
+func (t *trzsz) checkInteger(want int64) error {
+	got, err := t.recvInteger("SUCC")
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("trzsz: remote acknowledged %d, expected %d", got, want)
+	}
+	return nil
+}
+
+// recvFiles downloads the files the remote tsz sends
+func (t *trzsz) recvFiles(peer Peer) ([]string, error) {
+	num, err := t.recvInteger("NUM")
+	if err != nil {
+		return nil, err
+	}
+	if err := t.sendInteger("SUCC", num); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	var done, total int64
+	for i := int64(0); i < num; i++ {
+		remoteName, err := t.recvString("NAME")
+		if err != nil {
+			return nil, err
+		}
+		name := baseName(remoteName)
+		if err := t.sendString("SUCC", name); err != nil {
+			return nil, err
+		}
+		size, err := t.recvInteger("SIZE")
+		if err != nil {
+			return nil, err
+		}
+		if err := t.sendInteger("SUCC", size); err != nil {
+			return nil, err
+		}
+		total += size
+
+		if err := peer.Create(t.ctx, name, size); err != nil {
+			return nil, err
+		}
+		digest, err := t.recvFile(peer, name, size, done, total)
+		if closeErr := peer.Close(t.ctx, err == nil); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		done += size
+		if err := t.sendLine("SUCC", encodeTrzsz(digest, true)); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// recvFile takes a file's data lines and checks its MD5, which it returns
+func (t *trzsz) recvFile(peer Peer, name string, size, done, total int64) ([]byte, error) {
+	hash := md5.New()
+	for pos := int64(0); pos < size; {
+		v, err := t.recv("DATA")
+		if err != nil {
+			return nil, err
+		}
+		data, err := decodeTrzsz(v)
+		if err != nil {
+			return nil, fmt.Errorf("trzsz: bad data: %w", err)
+		}
+		if err := peer.Write(t.ctx, data); err != nil {
+			return nil, err
+		}
+		hash.Write(data)
+		pos += int64(len(data))
+		if err := t.sendInteger("SUCC", int64(len(data))); err != nil {
+			return nil, err
+		}
+		peer.Progress(done+pos, total, name)
+	}
+
+	v, err := t.recv("MD5")
+	if err != nil {
+		return nil, err
+	}
+	want, err := decodeTrzsz(v)
+	if err != nil {
+		return nil, fmt.Errorf("trzsz: bad checksum: %w", err)
+	}
+	digest := hash.Sum(nil)
+	if !bytes.Equal(want, digest) {
+		return nil, fmt.Errorf("trzsz: %s: checksum mismatch", name)
+	}
+	return digest, nil
+}
@@ -0,0 +1,802 @@
+package termtransfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ZMODEM framing characters
+const (
+	zpad   = '*'
+	zdle   = 0x18
+	zbin   = 'A'
+	zhex   = 'B'
+	zbin32 = 'C'
+	xon    = 0x11
+	xoff   = 0x13
+)
+
+// Frame types
+const (
+	zrqinit = 0
+	zrinit  = 1
+	zsinit  = 2
+	zack    = 3
+	zfile   = 4
+	zskip   = 5
+	znak    = 6
+	zabort  = 7
+	zfin    = 8
+	zrpos   = 9
+	zdata   = 10
+	zeof    = 11
+	zferr   = 12
+	zcrc    = 13
+	zcan    = 16
+)
+
+// Data subpacket ends, after a ZDLE
+const (
+	zcrce = 'h' // end of frame, a header follows
+	zcrcg = 'i' // frame goes on, no reply
+	zcrcq = 'j' // frame goes on, ZACK expected
+	zcrcw = 'k' // end of frame, ZACK expected
+	zrub0 = 'l' // escaped 0x7f
+	zrub1 = 'm' // escaped 0xff
+)
+
+// ZRINIT capabilities in ZF0
+const (
+	canFDX  = 0x01
+	canOVIO = 0x02
+	canFC32 = 0x20
+	escCtl  = 0x40
+)
+
+const (
+	// zmodemBlock is the size of the data subpackets sent; every rz
+	// accepts it
+	zmodemBlock = 1024
+	// maxSubpacket bounds the data subpackets received
+	maxSubpacket  = 8192
+	zmodemTimeout = 10 * time.Second
+	zmodemRetries = 10
+)
+
+// zmodemAbort is what lrzsz sends to cancel: CANs, then backspaces to erase
+// them from a terminal
+var zmodemAbort = []byte("\x18\x18\x18\x18\x18\x18\x18\x18\x18\x18\b\b\b\b\b\b\b\b\b\b")
+
+var errZmodemCRC = errors.New("zmodem: bad CRC")
+
+// header is a ZMODEM header: a frame type and four bytes, ZP0 first. The
+// bytes hold a position, least significant first, or flags, ZF0 last.
+type header struct {
+	typ  byte
+	data [4]byte
+	// crc32 is set on ZBIN32 headers, whose data subpackets use CRC-32 too
+	crc32 bool
+}
+
+func posHeader(typ byte, pos int64) header {
+	h := header{typ: typ}
+	binary.LittleEndian.PutUint32(h.data[:], uint32(pos))
+	return h
+}
+
+func (h header) pos() int64 { return int64(binary.LittleEndian.Uint32(h.data[:])) }
+
+// zf0 is the first flags byte
+func (h header) zf0() byte { return h.data[3] }
+
+func crc16(data []byte, crc uint16) uint16 {
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// zmodem is one side of a ZMODEM session
+type zmodem struct {
+	ctx context.Context
+	in  *Stream
+	out io.Writer
+	// useCRC32 and escapeCtl are what the receiver asked for in ZRINIT
+	useCRC32  bool
+	escapeCtl bool
+	lastSent  byte
+}
+
+func (z *zmodem) write(p []byte) error {
+	_, err := z.out.Write(p)
+	return err
+}
+
+// sendHexHeader sends a header in hex, as used when no data follows
+func (z *zmodem) sendHexHeader(h header) error {
+	raw := append([]byte{h.typ}, h.data[:]...)
+	crc := crc16(raw, 0)
+	raw = append(raw, byte(crc>>8), byte(crc))
+	buf := []byte{zpad, zpad, zdle, zhex}
+	buf = append(buf, fmt.Sprintf("%x", raw)...)
+	buf = append(buf, '\r', '\n'|0x80)
+	if h.typ != zack && h.typ != zfin {
+		buf = append(buf, xon)
+	}
+	return z.write(buf)
+}
+
+// sendBinHeader sends a header in binary, as used before data subpackets
+func (z *zmodem) sendBinHeader(h header) error {
+	raw := append([]byte{h.typ}, h.data[:]...)
+	var buf []byte
+	if z.useCRC32 {
+		buf = []byte{zpad, zdle, zbin32}
+		crc := crc32.ChecksumIEEE(raw)
+		raw = binary.LittleEndian.AppendUint32(raw, crc)
+	} else {
+		buf = []byte{zpad, zdle, zbin}
+		crc := crc16(raw, 0)
+		raw = append(raw, byte(crc>>8), byte(crc))
+	}
+	return z.write(z.escape(buf, raw))
+}
+
+// sendData sends a data subpacket ending with end
+func (z *zmodem) sendData(data []byte, end byte) error {
+	buf := z.escape(make([]byte, 0, len(data)+len(data)/8+16), data)
+	buf = append(buf, zdle, end)
+	var crc []byte
+	if z.useCRC32 {
+		crc = binary.LittleEndian.AppendUint32(nil, crc32.Update(crc32.ChecksumIEEE(data), crc32.IEEETable, []byte{end}))
+	} else {
+		c := crc16([]byte{end}, crc16(data, 0))
+		crc = []byte{byte(c >> 8), byte(c)}
+	}
+	buf = z.escape(buf, crc)
+	if end == zcrcw {
+		buf = append(buf, xon)
+	}
+	return z.write(buf)
+}
+
+// escape appends data to buf, ZDLE-escaping what could upset the link
+func (z *zmodem) escape(buf, data []byte) []byte {
+	for _, b := range data {
+		switch {
+		case b == zdle || b&0x7f == 0x10 || b&0x7f == xon || b&0x7f == xoff,
+			b&0x7f == '\r' && z.lastSent&0x7f == '@',
+			z.escapeCtl && b&0x60 == 0:
+			buf = append(buf, zdle, b^0x40)
+		default:
+			buf = append(buf, b)
+		}
+		z.lastSent = b
+	}
+	return buf
+}
+
+func (z *zmodem) readByte(timeout time.Duration) (byte, error) {
+	return z.in.readByte(z.ctx, timeout)
+}
+
+// Results of readEscaped besides data bytes
+const (
+	gotFrameEnd = 0x100 // | the subpacket end
+	gotCancel   = 0x200
+)
+
+// readEscaped reads a byte, undoing ZDLE escapes; XON and XOFF are dropped.
+// It returns a subpacket end or a cancel as gotFrameEnd or gotCancel.
+func (z *zmodem) readEscaped() (int, error) {
+	for {
+		b, err := z.readByte(zmodemTimeout)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case b&0x7f == xon || b&0x7f == xoff:
+			continue
+		case b != zdle:
+			return int(b), nil
+		}
+		cans := 1
+		for {
+			b, err = z.readByte(zmodemTimeout)
+			if err != nil {
+				return 0, err
+			}
+			if b&0x7f == xon || b&0x7f == xoff {
+				continue
+			}
+			if b != zdle {
+				break
+			}
+			if cans++; cans >= 5 {
+				return gotCancel, nil
+			}
+		}
+		switch {
+		case b == zcrce || b == zcrcg || b == zcrcq || b == zcrcw:
+			return gotFrameEnd | int(b), nil
+		case b == zrub0:
+			return 0x7f, nil
+		case b == zrub1:
+			return 0xff, nil
+		case b&0x60 == 0x40:
+			return int(b ^ 0x40), nil
+		}
+		return 0, fmt.Errorf("zmodem: bad escape %#x", b)
+	}
+}
+
+// readHeader skips to the next header and reads it
+// backChannel reports whether the receiver has started sending a header
+// while data streams, skipping the XONs and line ends left from earlier ones
+func (z *zmodem) backChannel() bool {
+	for {
+		b, ok := z.in.peekByte()
+		if !ok {
+			return false
+		}
+		if b == zpad || b == zdle {
+			return true
+		}
+		z.in.local = z.in.local[1:]
+	}
+}
+
+func (z *zmodem) readHeader(timeout time.Duration) (header, error) {
+	cans := 0
+	for {
+		b, err := z.readByte(timeout)
+		if err != nil {
+			return header{}, err
+		}
+		if b == zdle {
+			if cans++; cans >= 5 {
+				return header{}, ErrCanceled
+			}
+		} else {
+			cans = 0
+		}
+		if b != zpad {
+			continue
+		}
+		for b == zpad {
+			if b, err = z.readByte(timeout); err != nil {
+				return header{}, err
+			}
+		}
+		if b != zdle {
+			continue
+		}
+		if b, err = z.readByte(timeout); err != nil {
+			return header{}, err
+		}
+		var h header
+		switch b {
+		case zhex:
+			h, err = z.readHexHeader()
+		case zbin:
+			h, err = z.readBinHeader(false)
+		case zbin32:
+			h, err = z.readBinHeader(true)
+		default:
+			continue
+		}
+		if errors.Is(err, errZmodemCRC) {
+			continue
+		}
+		if err == nil && h.typ == zcan {
+			return h, ErrCanceled
+		}
+		return h, err
+	}
+}
+
+func (z *zmodem) readHexHeader() (header, error) {
+	raw := make([]byte, 7)
+	for i := range raw {
+		var digits [2]byte
+		for j := range digits {
+			b, err := z.readByte(zmodemTimeout)
+			if err != nil {
+				return header{}, err
+			}
+			digits[j] = b & 0x7f
+		}
+		v, err := strconv.ParseUint(string(digits[:]), 16, 8)
+		if err != nil {
+			return header{}, errZmodemCRC
+		}
+		raw[i] = byte(v)
+	}
+	if crc16(raw[:5], 0) != uint16(raw[5])<<8|uint16(raw[6]) {
+		return header{}, errZmodemCRC
+	}
+	// CR LF follow; the XON after them is skipped with the next header
+	if b, err := z.readByte(time.Second); err == nil && b&0x7f == '\r' {
+		_, _ = z.readByte(time.Second)
+	}
+	h := header{typ: raw[0]}
+	copy(h.data[:], raw[1:5])
+	return h, nil
+}
+
+func (z *zmodem) readBinHeader(use32 bool) (header, error) {
+	n := 7
+	if use32 {
+		n = 9
+	}
+	raw := make([]byte, n)
+	for i := range raw {
+		c, err := z.readEscaped()
+		if err != nil {
+			return header{}, err
+		}
+		if c == gotCancel {
+			return header{}, ErrCanceled
+		}
+		if c > 0xff {
+			return header{}, errZmodemCRC
+		}
+		raw[i] = byte(c)
+	}
+	if use32 {
+		if crc32.ChecksumIEEE(raw[:5]) != binary.LittleEndian.Uint32(raw[5:]) {
+			return header{}, errZmodemCRC
+		}
+	} else if crc16(raw[:5], 0) != uint16(raw[5])<<8|uint16(raw[6]) {
+		return header{}, errZmodemCRC
+	}
+	h := header{typ: raw[0], crc32: use32}
+	copy(h.data[:], raw[1:5])
+	return h, nil
+}
+
+// readData reads a data subpacket of a frame whose header was h, returning
+// its data and how it ends
+func (z *zmodem) readData(h header) ([]byte, byte, error) {
+	var data []byte
+	for {
+		c, err := z.readEscaped()
+		if err != nil {
+			return nil, 0, err
+		}
+		if c == gotCancel {
+			return nil, 0, ErrCanceled
+		}
+		if c&gotFrameEnd == 0 {
+			if len(data) >= maxSubpacket {
+				return nil, 0, errZmodemCRC
+			}
+			data = append(data, byte(c))
+			continue
+		}
+		end := byte(c)
+		n := 2
+		if h.crc32 {
+			n = 4
+		}
+		crc := make([]byte, n)
+		for i := range crc {
+			c, err := z.readEscaped()
+			if err != nil {
+				return nil, 0, err
+			}
+			if c > 0xff {
+				return nil, 0, errZmodemCRC
+			}
+			crc[i] = byte(c)
+		}
+		if h.crc32 {
+			if crc32.Update(crc32.ChecksumIEEE(data), crc32.IEEETable, []byte{end}) != binary.LittleEndian.Uint32(crc) {
+				return nil, 0, errZmodemCRC
+			}
+		} else if crc16([]byte{end}, crc16(data, 0)) != uint16(crc[0])<<8|uint16(crc[1]) {
+			return nil, 0, errZmodemCRC
+		}
+		return data, end, nil
+	}
+}
+
+// abort tells the remote side to stop
+func (z *zmodem) abort() {
+	_ = z.write(zmodemAbort)
+}
+
+// fileInfo is what a ZFILE subpacket says of a file
+type fileInfo struct {
+	name string
+	size int64 // -1 if not given
+}
+
+func parseFileInfo(data []byte) fileInfo {
+	name, rest, _ := bytes.Cut(data, []byte{0})
+	info := fileInfo{name: string(name), size: -1}
+	fields := bytes.Fields(bytes.TrimRight(rest, "\x00"))
+	if len(fields) > 0 {
+		if size, err := strconv.ParseInt(string(fields[0]), 10, 64); err == nil {
+			info.size = size
+		}
+	}
+	return info
+}
+
+// zmodemReceive downloads the files the remote sz sends
+func zmodemReceive(ctx context.Context, in *Stream, out io.Writer, peer Peer) (err error) {
+	z := &zmodem{ctx: ctx, in: in, out: out}
+	defer func() {
+		if err != nil && !errors.Is(err, ErrCanceled) {
+			z.abort()
+		}
+	}()
+	var done, total int64
+	zrinitHeader := header{typ: zrinit}
+	zrinitHeader.data[3] = canFDX | canOVIO | canFC32
+
+	if err := z.sendHexHeader(zrinitHeader); err != nil {
+		return err
+	}
+	retries := 0
+	for {
+		h, err := z.readHeader(zmodemTimeout)
+		if errors.Is(err, errTimeout) && retries < zmodemRetries {
+			retries++
+			if err := z.sendHexHeader(zrinitHeader); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		retries = 0
+
+		switch h.typ {
+		case zrqinit:
+			if err := z.sendHexHeader(zrinitHeader); err != nil {
+				return err
+			}
+		case zsinit:
+			if _, _, err := z.readData(h); err != nil && !errors.Is(err, errZmodemCRC) {
+				return err
+			}
+			if err := z.sendHexHeader(header{typ: zack}); err != nil {
+				return err
+			}
+		case zfile:
+			data, _, err := z.readData(h)
+			if errors.Is(err, errZmodemCRC) {
+				if err := z.sendHexHeader(header{typ: znak}); err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			info := parseFileInfo(data)
+			name := baseName(info.name)
+			if info.size > 0 {
+				total += info.size
+			}
+			if err := peer.Create(ctx, name, info.size); err != nil {
+				return err
+			}
+			n, err := z.receiveFile(peer, name, done, total)
+			done += n
+			if closeErr := peer.Close(ctx, err == nil); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			if err := z.sendHexHeader(zrinitHeader); err != nil {
+				return err
+			}
+		case zfin:
+			if err := z.sendHexHeader(header{typ: zfin}); err != nil {
+				return err
+			}
+			// The sender signs off with "OO"
+			for i := 0; i < 2; i++ {
+				if b, err := z.readByte(time.Second); err != nil || b != 'O' {
+					break
+				}
+			}
+			return nil
+		case zabort, zferr:
+			return ErrCanceled
+		default:
+			// ZCOMMAND and the like are not taken
+			if h.typ > zcan {
+				return fmt.Errorf("zmodem: unsupported frame type %d", h.typ)
+			}
+		}
+	}
+}
+
+// receiveFile takes a file's data after its ZFILE, until its ZEOF. done and
+// total are the bytes of the whole transfer, for progress.
+func (z *zmodem) receiveFile(peer Peer, name string, done, total int64) (int64, error) {
+	var pos int64
+	if err := z.sendHexHeader(posHeader(zrpos, 0)); err != nil {
+		return 0, err
+	}
+	retries := 0
+	for {
+		h, err := z.readHeader(zmodemTimeout)
+		if err != nil {
+			if errors.Is(err, errTimeout) && retries < zmodemRetries {
+				retries++
+				if err := z.sendHexHeader(posHeader(zrpos, pos)); err != nil {
+					return pos, err
+				}
+				continue
+			}
+			return pos, err
+		}
+
+		switch h.typ {
+		case zdata:
+			if h.pos() != pos {
+				if err := z.sendHexHeader(posHeader(zrpos, pos)); err != nil {
+					return pos, err
+				}
+				continue
+			}
+			for {
+				data, end, err := z.readData(h)
+				if errors.Is(err, errZmodemCRC) || errors.Is(err, errTimeout) {
+					// Ask for the data again from where it went wrong
+					retries++
+					if retries > zmodemRetries {
+						return pos, err
+					}
+					if err := z.sendHexHeader(posHeader(zrpos, pos)); err != nil {
+						return pos, err
+					}
+					break
+				}
+				if err != nil {
+					return pos, err
+				}
+				retries = 0
+				if len(data) > 0 {
+					if err := peer.Write(z.ctx, data); err != nil {
+						return pos, err
+					}
+					pos += int64(len(data))
+					peer.Progress(done+pos, total, name)
+				}
+				if end == zcrcw || end == zcrcq {
+					if err := z.sendHexHeader(posHeader(zack, pos)); err != nil {
+						return pos, err
+					}
+				}
+				if end == zcrce || end == zcrcw {
+					break
+				}
+			}
+		case zeof:
+			// A ZEOF that doesn't match what arrived is stale
+			if h.pos() == pos {
+				return pos, nil
+			}
+		case zfile:
+			// Sent again before our ZRPOS arrived
+			if _, _, err := z.readData(h); err != nil && !errors.Is(err, errZmodemCRC) {
+				return pos, err
+			}
+			if err := z.sendHexHeader(posHeader(zrpos, pos)); err != nil {
+				return pos, err
+			}
+		case znak:
+			if err := z.sendHexHeader(posHeader(zrpos, pos)); err != nil {
+				return pos, err
+			}
+		case zfin, zabort, zferr:
+			return pos, ErrCanceled
+		}
+	}
+}
+
+// zmodemSend uploads the files the user picks to the remote rz
+func zmodemSend(ctx context.Context, in *Stream, out io.Writer, peer Peer) (err error) {
+	z := &zmodem{ctx: ctx, in: in, out: out}
+	defer func() {
+		if err != nil && !errors.Is(err, ErrCanceled) {
+			z.abort()
+		}
+	}()
+
+	// The stream opens with the ZRINIT that started the transfer
+	h, err := z.readHeader(zmodemTimeout)
+	if err != nil {
+		return err
+	}
+	if h.typ == zrinit {
+		z.useCRC32 = h.zf0()&canFC32 != 0
+		z.escapeCtl = h.zf0()&escCtl != 0
+	}
+
+	files, err := peer.Choose(ctx)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+
+	var done int64
+	for i, f := range files {
+		if err := z.sendFile(peer, i, f, len(files)-i, total-done, done, total); err != nil {
+			return err
+		}
+		done += f.Size
+	}
+	return z.finish()
+}
+
+// sendFile offers a file with ZFILE and sends what the receiver asks for.
+// left and bytesLeft are for the ZFILE subpacket; done and total are for
+// progress.
+func (z *zmodem) sendFile(peer Peer, index int, f File, left int, bytesLeft, done, total int64) error {
+	fileHeader := header{typ: zfile}
+	fileHeader.data[3] = 1 // ZCBIN: binary transfer
+	info := fmt.Sprintf("%s\x00%d %o %o 0 %d %d\x00", f.Name, f.Size, time.Now().Unix(), 0o100644, left, bytesLeft)
+
+	offer := func() error {
+		if err := z.sendBinHeader(fileHeader); err != nil {
+			return err
+		}
+		return z.sendData([]byte(info), zcrcw)
+	}
+	if err := offer(); err != nil {
+		return err
+	}
+
+	retries := 0
+	for {
+		h, err := z.readHeader(zmodemTimeout)
+		if errors.Is(err, errTimeout) && retries < zmodemRetries {
+			retries++
+			if err := offer(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		switch h.typ {
+		case zrinit, zack:
+			// Left over from before the offer
+		case zrpos:
+			// The receiver may ask for data again while it is sent
+			for next := &h; next.typ == zrpos; {
+				if next, err = z.sendFrom(peer, index, f, next.pos(), done, total); err != nil || next == nil {
+					return err
+				}
+			}
+			return nil
+		case zskip:
+			return nil
+		case znak:
+			if err := offer(); err != nil {
+				return err
+			}
+		case zcrc:
+			// Only asked by receivers resuming a file; start over instead
+			if err := z.sendHexHeader(posHeader(zcrc, 0)); err != nil {
+				return err
+			}
+		case zabort, zfin, zferr:
+			return ErrCanceled
+		}
+	}
+}
+
+// sendFrom sends a file's data from pos and its ZEOF, then waits for the
+// receiver to take it. It returns nil once the receiver is ready for the
+// next file, or the header of a ZRPOS or ZSKIP that interrupted it.
+func (z *zmodem) sendFrom(peer Peer, index int, f File, pos, done, total int64) (*header, error) {
+	if err := z.sendBinHeader(posHeader(zdata, pos)); err != nil {
+		return nil, err
+	}
+	for {
+		data, err := peer.ReadAt(z.ctx, index, pos, zmodemBlock)
+		if err != nil {
+			return nil, err
+		}
+		pos += int64(len(data))
+		end := byte(zcrcg)
+		if pos >= f.Size || len(data) < zmodemBlock {
+			end = zcrce
+		}
+		if err := z.sendData(data, end); err != nil {
+			return nil, err
+		}
+		peer.Progress(done+pos, total, f.Name)
+		if end == zcrce {
+			break
+		}
+		// Listen for the receiver asking to go back or to stop
+		if z.backChannel() {
+			h, err := z.readHeader(time.Second)
+			if err != nil && !errors.Is(err, errTimeout) {
+				return nil, err
+			}
+			switch {
+			case err != nil, h.typ == zack:
+			case h.typ == zrpos, h.typ == zskip:
+				return &h, nil
+			case h.typ == zabort, h.typ == zfin, h.typ == zferr:
+				return nil, ErrCanceled
+			}
+		}
+	}
+
+	if err := z.sendBinHeader(posHeader(zeof, pos)); err != nil {
+		return nil, err
+	}
+	retries := 0
+	for {
+		h, err := z.readHeader(zmodemTimeout)
+		if errors.Is(err, errTimeout) && retries < zmodemRetries {
+			retries++
+			if err := z.sendBinHeader(posHeader(zeof, pos)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch h.typ {
+		case zrinit:
+			return nil, nil
+		case zrpos, zskip:
+			return &h, nil
+		case zabort, zfin, zferr:
+			return nil, ErrCanceled
+		}
+	}
+}
+
+// finish ends the session with ZFIN, and "OO" once the receiver agrees
+func (z *zmodem) finish() error {
+	for retries := 0; ; retries++ {
+		if err := z.sendHexHeader(header{typ: zfin}); err != nil {
+			return err
+		}
+		h, err := z.readHeader(zmodemTimeout)
+		if errors.Is(err, errTimeout) && retries < zmodemRetries {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if h.typ == zfin {
+			return z.write([]byte("OO"))
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/google/uuid"
+)
+
+// ErrTriggerNotFound is returned for an unknown trigger ID
+var ErrTriggerNotFound = errors.New("trigger not found")
+
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// compiledTrigger is a trigger with its pattern compiled
+type compiledTrigger struct {
+	models.Trigger
+	re *regexp.Regexp
+}
+
+// TriggerService keeps the terminal trigger rules in memory with JSON file
+// persistence
+type TriggerService struct {
+	mu       sync.RWMutex
+	triggers []*compiledTrigger // in creation order
+	dataFile string
+}
+
+// NewTriggerService loads the persisted triggers
+func NewTriggerService() *TriggerService {
+	homeDir, _ := os.UserHomeDir()
+	dataDir := filepath.Join(homeDir, ".choraleia")
+	_ = os.MkdirAll(dataDir, 0755)
+	return newTriggerService(filepath.Join(dataDir, "triggers.json"))
+}
+
+func newTriggerService(dataFile string) *TriggerService {
+	s := &TriggerService{dataFile: dataFile}
+	_ = s.load() // best-effort load
+	return s
+}
+
+func (s *TriggerService) load() error {
+	data, err := os.ReadFile(s.dataFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var list []models.Trigger
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tr := range list {
+		// A pattern that no longer compiles is kept but never matches
+		re, _ := regexp.Compile(tr.Pattern)
+		s.triggers = append(s.triggers, &compiledTrigger{Trigger: tr, re: re})
+	}
+	return nil
+}
+
+// save persists the triggers; the caller holds mu
+func (s *TriggerService) save() error {
+	list := make([]models.Trigger, 0, len(s.triggers))
+	for _, tr := range s.triggers {
+		list = append(list, tr.Trigger)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.dataFile, data, 0644)
+}
+
+// List returns the triggers, or with assetID set only that asset's
+func (s *TriggerService) List(assetID string) []models.Trigger {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]models.Trigger, 0, len(s.triggers))
+	for _, tr := range s.triggers {
+		if assetID == "" || tr.AssetID == assetID {
+			list = append(list, tr.Trigger)
+		}
+	}
+	return list
+}
+
+func (s *TriggerService) Get(id string) (models.Trigger, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if i := s.indexLocked(id); i >= 0 {
+		return s.triggers[i].Trigger, nil
+	}
+	return models.Trigger{}, ErrTriggerNotFound
+}
+
+func (s *TriggerService) Create(req *models.TriggerRequest) (models.Trigger, error) {
+	tr, err := compileTrigger(uuid.NewString(), req)
+	if err != nil {
+		return models.Trigger{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.triggers = append(s.triggers, tr)
+	if err := s.save(); err != nil {
+		s.triggers = s.triggers[:len(s.triggers)-1]
+		return models.Trigger{}, err
+	}
+	return tr.Trigger, nil
+}
+
+// Update replaces the trigger id with req
+func (s *TriggerService) Update(id string, req *models.TriggerRequest) (models.Trigger, error) {
+	tr, err := compileTrigger(id, req)
+	if err != nil {
+		return models.Trigger{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexLocked(id)
+	if i < 0 {
+		return models.Trigger{}, ErrTriggerNotFound
+	}
+	old := s.triggers[i]
+	s.triggers[i] = tr
+	if err := s.save(); err != nil {
+		s.triggers[i] = old
+		return models.Trigger{}, err
+	}
+	return tr.Trigger, nil
+}
+
+func (s *TriggerService) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexLocked(id)
+	if i < 0 {
+		return ErrTriggerNotFound
+	}
+	old := append([]*compiledTrigger(nil), s.triggers...)
+	s.triggers = append(s.triggers[:i], s.triggers[i+1:]...)
+	if err := s.save(); err != nil {
+		s.triggers = old
+		return err
+	}
+	return nil
+}
+
+// forAsset returns the enabled triggers that apply to the asset's sessions
+func (s *TriggerService) forAsset(assetID string) []*compiledTrigger {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var list []*compiledTrigger
+	for _, tr := range s.triggers {
+		if tr.Enabled && tr.re != nil && (tr.AssetID == "" || tr.AssetID == assetID) {
+			list = append(list, tr)
+		}
+	}
+	return list
+}
+
+func (s *TriggerService) indexLocked(id string) int {
+	for i, tr := range s.triggers {
+		if tr.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// compileTrigger checks a trigger request and compiles its pattern
+func compileTrigger(id string, req *models.TriggerRequest) (*compiledTrigger, error) {
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	re, err := regexp.Compile(req.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	switch req.Action {
+	case models.TriggerHighlight, models.TriggerNotify:
+	case models.TriggerRespond:
+		if req.Response == "" {
+			return nil, errors.New("response is required to respond")
+		}
+	case models.TriggerCapture:
+		if !variableName.MatchString(req.Variable) {
+			return nil, errors.New("capture needs a variable name of letters, digits and underscores")
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", req.Action)
+	}
+	if req.Cooldown < 0 {
+		return nil, errors.New("cooldown can't be negative")
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+	return &compiledTrigger{
+		Trigger: models.Trigger{
+			ID:        id,
+			AssetID:   req.AssetID,
+			Name:      req.Name,
+			Pattern:   req.Pattern,
+			Action:    req.Action,
+			Enabled:   enabled,
+			Color:     req.Color,
+			Message:   req.Message,
+			Response:  req.Response,
+			Variable:  req.Variable,
+			Cooldown:  req.Cooldown,
+			UpdatedAt: time.Now().UTC(),
+		},
+		re: re,
+	}, nil
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/choraleia/choraleia/pkg/event"
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/secrets"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPortForwardTarget is returned for a port forward whose target does
+	// not exist or is not of its type
+	ErrPortForwardTarget = errors.New("invalid port forward target")
+
+	errNoPortForwardDB = errors.New("port forward store not available")
+)
+
+// tunnelRoute is how a tunnel reaches the side it forwards to
+type tunnelRoute struct {
+	sshAssetID string // SSH asset to connect through, unless direct
+	host       string // remote host of a local forward
+	direct     bool   // dialed from this machine, without SSH
+}
+
+// SetDB sets the database standalone port forwards are kept in
+func (s *TunnelService) SetDB(db *gorm.DB) {
+	s.db = db
+}
+
+// SetDockerService sets the service container IPs are looked up with
+func (s *TunnelService) SetDockerService(dockerService *DockerService) {
+	s.dockerService = dockerService
+}
+
+// AutoMigrate creates the port forward table
+func (s *TunnelService) AutoMigrate() error {
+	if s.db == nil {
+		return errNoPortForwardDB
+	}
+	return s.db.AutoMigrate(&models.PortForward{})
+}
+
+// ListPortForwards returns the standalone port forwards, oldest first
+func (s *TunnelService) ListPortForwards() ([]models.PortForward, error) {
+	if s.db == nil {
+		return nil, errNoPortForwardDB
+	}
+	var forwards []models.PortForward
+	if err := s.db.Order("created_at").Find(&forwards).Error; err != nil {
+		return nil, err
+	}
+	return forwards, nil
+}
+
+// GetPortForward returns a standalone port forward
+func (s *TunnelService) GetPortForward(id string) (*models.PortForward, error) {
+	if s.db == nil {
+		return nil, errNoPortForwardDB
+	}
+	var pf models.PortForward
+	if err := s.db.First(&pf, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrTunnelNotFound, id)
+		}
+		return nil, err
+	}
+	return &pf, nil
+}
+
+// CreatePortForward stores a validated port forward and registers its
+// tunnel, stopped
+func (s *TunnelService) CreatePortForward(req *models.PortForwardRequest) (*models.PortForward, error) {
+	if s.db == nil {
+		return nil, errNoPortForwardDB
+	}
+	if err := s.checkTarget(req.Target); err != nil {
+		return nil, err
+	}
+	pf := &models.PortForward{
+		ID:     uuid.New().String(),
+		Name:   req.Name,
+		Target: req.Target,
+		Config: req.Config,
+	}
+	if err := sealPortForward(pf); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(pf).Error; err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.putPortForward(pf)
+	s.mu.Unlock()
+	event.Emit(event.TunnelCreatedEvent{TunnelID: pf.ID, AssetID: pf.Target.AssetID})
+	return pf, nil
+}
+
+// UpdatePortForward replaces a port forward's settings. A running forward
+// takes them on its next start.
+func (s *TunnelService) UpdatePortForward(id string, req *models.PortForwardRequest) (*models.PortForward, error) {
+	pf, err := s.GetPortForward(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTarget(req.Target); err != nil {
+		return nil, err
+	}
+	pf.Name, pf.Target, pf.Config = req.Name, req.Target, req.Config
+	if err := sealPortForward(pf); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(pf).Error; err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.putPortForward(pf)
+	s.mu.Unlock()
+	event.Emit(event.TunnelUpdatedEvent{TunnelID: pf.ID, AssetID: pf.Target.AssetID})
+	return pf, nil
+}
+
+// DeletePortForward stops a port forward and removes it
+func (s *TunnelService) DeletePortForward(id string) error {
+	pf, err := s.GetPortForward(id)
+	if err != nil {
+		return err
+	}
+	if err := s.StopTunnel(id); err != nil && !errors.Is(err, ErrTunnelNotFound) {
+		return err
+	}
+	if err := s.db.Delete(pf).Error; err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.tunnels, id)
+	s.mu.Unlock()
+	event.Emit(event.TunnelDeletedEvent{TunnelID: id})
+	return nil
+}
+
+// sealPortForward moves the forward's proxy password into the vault and
+// keeps its ID on the tunnel config, like the tunnels of SSH assets
+func sealPortForward(pf *models.PortForward) error {
+	pf.Config.ID = pf.ID
+	if p := pf.Config.Proxy; p != nil && p.Password != "" {
+		sealed, err := secrets.Seal(p.Password)
+		if err != nil {
+			return fmt.Errorf("failed to store proxy password: %w", err)
+		}
+		proxy := *p
+		proxy.Password = sealed
+		pf.Config.Proxy = &proxy
+	}
+	return nil
+}
+
+// checkTarget checks that the assets or workspace a target names exist and
+// are of its type
+func (s *TunnelService) checkTarget(target models.PortForwardTarget) error {
+	switch target.Type {
+	case models.PortForwardTargetSSH, models.PortForwardTargetDocker:
+		asset, err := s.assetService.GetAsset(target.AssetID)
+		if err != nil {
+			return fmt.Errorf("%w: asset %s not found", ErrPortForwardTarget, target.AssetID)
+		}
+		want := models.AssetTypeSSH
+		if target.Type == models.PortForwardTargetDocker {
+			want = models.AssetTypeDockerHost
+		}
+		if asset.Type != want {
+			return fmt.Errorf("%w: asset %s is not a %s asset", ErrPortForwardTarget, asset.Name, want)
+		}
+	case models.PortForwardTargetWorkspace:
+		var runtime models.WorkspaceRuntime
+		if err := s.db.First(&runtime, "workspace_id = ?", target.WorkspaceID).Error; err != nil {
+			return fmt.Errorf("%w: workspace %s has no runtime", ErrPortForwardTarget, target.WorkspaceID)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrPortForwardTarget, target.Type)
+	}
+	return nil
+}
+
+// loadPortForwards registers the tunnels of the stored port forwards and
+// marks them valid; s.mu must be held
+func (s *TunnelService) loadPortForwards(valid map[string]bool) error {
+	if s.db == nil {
+		return nil
+	}
+	var forwards []models.PortForward
+	if err := s.db.Find(&forwards).Error; err != nil {
+		return fmt.Errorf("failed to list port forwards: %w", err)
+	}
+	for i := range forwards {
+		valid[forwards[i].ID] = true
+		s.putPortForward(&forwards[i])
+	}
+	return nil
+}
+
+// putPortForward registers the tunnel of a port forward, or updates it;
+// a tunnel not running takes the new settings on its next start. s.mu must
+// be held.
+func (s *TunnelService) putPortForward(pf *models.PortForward) {
+	cfg := pf.Config
+	cfg.ID = pf.ID
+	if cfg.LocalHost == "" {
+		cfg.LocalHost = "127.0.0.1"
+	}
+	target := pf.Target
+
+	if existing, ok := s.tunnels[pf.ID]; ok {
+		existing.mu.Lock()
+		existing.AssetName = pf.Name
+		if existing.Status != TunnelStatusRunning && existing.supervisor == nil {
+			existing.AssetID = target.AssetID
+			existing.Target = &target
+			existing.Config = cfg
+		}
+		existing.mu.Unlock()
+		return
+	}
+
+	s.tunnels[pf.ID] = &Tunnel{
+		ID:        pf.ID,
+		AssetID:   target.AssetID,
+		AssetName: pf.Name,
+		Target:    &target,
+		Config:    cfg,
+		Status:    TunnelStatusStopped,
+	}
+}
+
+// route resolves how the tunnel reaches its remote side: through its SSH
+// asset, through the SSH asset of a remote Docker host, or directly to a
+// container or workspace on this machine
+func (s *TunnelService) route(ctx context.Context, tunnel *Tunnel) (tunnelRoute, error) {
+	tunnel.mu.RLock()
+	assetID, cfg, target := tunnel.AssetID, tunnel.Config, tunnel.Target
+	tunnel.mu.RUnlock()
+
+	if target == nil || target.Type == models.PortForwardTargetSSH {
+		return tunnelRoute{sshAssetID: assetID, host: cfg.RemoteHost}, nil
+	}
+	switch target.Type {
+	case models.PortForwardTargetDocker:
+		asset, err := s.assetService.GetAsset(target.AssetID)
+		if err != nil {
+			return tunnelRoute{}, fmt.Errorf("docker host: %w", err)
+		}
+		return s.containerRoute(ctx, asset, target.Container)
+	case models.PortForwardTargetWorkspace:
+		return s.workspaceRoute(ctx, target.WorkspaceID)
+	}
+	return tunnelRoute{}, fmt.Errorf("unknown port forward target: %s", target.Type)
+}
+
+// containerRoute reaches a container on the Docker host asset, or on this
+// machine's Docker daemon for a nil asset
+func (s *TunnelService) containerRoute(ctx context.Context, asset *models.Asset, container string) (tunnelRoute, error) {
+	if s.dockerService == nil {
+		return tunnelRoute{}, fmt.Errorf("docker service not available")
+	}
+	var cfg models.DockerHostConfig
+	if asset != nil {
+		if err := asset.GetTypedConfig(&cfg); err != nil {
+			return tunnelRoute{}, fmt.Errorf("invalid docker host config: %w", err)
+		}
+	}
+	ip, err := s.dockerService.ContainerIP(ctx, asset, container)
+	if err != nil {
+		return tunnelRoute{}, fmt.Errorf("container %s: %w", container, err)
+	}
+
+	if cfg.ConnectionType == "ssh" && cfg.SSHAssetID != "" {
+		return tunnelRoute{sshAssetID: cfg.SSHAssetID, host: ip}, nil
+	}
+	return tunnelRoute{host: ip, direct: true}, nil
+}
+
+// workspaceRoute reaches a workspace's runtime: this machine for a local
+// runtime, else its container
+func (s *TunnelService) workspaceRoute(ctx context.Context, workspaceID string) (tunnelRoute, error) {
+	if s.db == nil {
+		return tunnelRoute{}, errNoPortForwardDB
+	}
+	var runtime models.WorkspaceRuntime
+	if err := s.db.First(&runtime, "workspace_id = ?", workspaceID).Error; err != nil {
+		return tunnelRoute{}, fmt.Errorf("workspace runtime: %w", err)
+	}
+	if runtime.Type == models.RuntimeTypeLocal {
+		return tunnelRoute{host: "127.0.0.1", direct: true}, nil
+	}
+
+	var container string
+	if runtime.ContainerID != nil && *runtime.ContainerID != "" {
+		container = *runtime.ContainerID
+	} else if runtime.ContainerName != nil {
+		container = *runtime.ContainerName
+	}
+	if container == "" {
+		return tunnelRoute{}, fmt.Errorf("workspace %s has no container; start it first", workspaceID)
+	}
+
+	var asset *models.Asset
+	if runtime.Type == models.RuntimeTypeDockerRemote {
+		if runtime.DockerAssetID == nil {
+			return tunnelRoute{}, fmt.Errorf("workspace %s has no docker host", workspaceID)
+		}
+		var err error
+		if asset, err = s.assetService.GetAsset(*runtime.DockerAssetID); err != nil {
+			return tunnelRoute{}, fmt.Errorf("docker host: %w", err)
+		}
+	}
+	return s.containerRoute(ctx, asset, container)
+}
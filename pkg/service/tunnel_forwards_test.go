@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/choraleia/choraleia/pkg/models"
+)
+
+func TestPortForwardRequestValidate(t *testing.T) {
+	local := models.SSHTunnel{Type: "local", LocalPort: 8080, RemotePort: 80}
+	tests := []struct {
+		name  string
+		req   models.PortForwardRequest
+		valid bool
+	}{
+		{"container without remote host", models.PortForwardRequest{Name: "web",
+			Target: models.PortForwardTarget{Type: "docker", AssetID: "d", Container: "nginx"}, Config: local}, true},
+		{"workspace", models.PortForwardRequest{Name: "ws",
+			Target: models.PortForwardTarget{Type: "workspace", WorkspaceID: "w"}, Config: local}, true},
+		{"ssh needs remote host", models.PortForwardRequest{Name: "db",
+			Target: models.PortForwardTarget{Type: "ssh", AssetID: "a"}, Config: local}, false},
+		{"container needs a name", models.PortForwardRequest{Name: "web",
+			Target: models.PortForwardTarget{Type: "docker", AssetID: "d"}, Config: local}, false},
+		{"container dynamic forward", models.PortForwardRequest{Name: "web",
+			Target: models.PortForwardTarget{Type: "docker", AssetID: "d", Container: "nginx"},
+			Config: models.SSHTunnel{Type: "dynamic", LocalPort: 1080}}, false},
+		{"unknown target", models.PortForwardRequest{Name: "x",
+			Target: models.PortForwardTarget{Type: "k8s"}, Config: local}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestDirectLocalForward(t *testing.T) {
+	// The "container": echoes what it reads
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	go func() {
+		for {
+			c, err := target.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(c, c)
+				_ = c.Close()
+			}()
+		}
+	}()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	_ = l.Close()
+
+	tunnel := &Tunnel{
+		ID:         "t",
+		Config:     models.SSHTunnel{Type: "local", LocalHost: "127.0.0.1", LocalPort: port},
+		remoteAddr: target.Addr().String(),
+		direct:     true,
+	}
+	s := &TunnelService{tunnels: map[string]*Tunnel{"t": tunnel}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.startLocalForward(ctx, tunnel); err != nil {
+		t.Fatal(err)
+	}
+	defer s.teardown(tunnel)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("read %q, %v", buf, err)
+	}
+
+	// Without SSH the health check dials the container directly
+	if err := s.probeTunnel(tunnel, tunnelHealthTimeout); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/event"
+)
+
+// Tunnel metrics limits. Throughput is sampled every tunnelMetricsInterval
+// into per-minute history, and pushed as a tunnel.metrics event when it
+// changed.
+const (
+	tunnelMetricsInterval = 5 * time.Second
+	tunnelHistoryMinutes  = 24 * 60
+	tunnelRecentConns     = 100 // closed connections kept per tunnel
+)
+
+// errClosedByUser is recorded for a connection closed through the API
+var errClosedByUser = errors.New("closed by user")
+
+// TunnelConnection describes a connection through a tunnel
+type TunnelConnection struct {
+	ID            string     `json:"id"`
+	Client        string     `json:"client"`         // address the connection came from
+	Target        string     `json:"target"`         // address it was forwarded to
+	BytesSent     int64      `json:"bytes_sent"`     // local side to remote side, as the tunnel's
+	BytesReceived int64      `json:"bytes_received"` // remote side to local side
+	OpenedAt      time.Time  `json:"opened_at"`
+	ClosedAt      *time.Time `json:"closed_at,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// TunnelTrafficPoint is a tunnel's traffic in one minute
+type TunnelTrafficPoint struct {
+	Time          time.Time `json:"time"` // start of the minute
+	BytesSent     int64     `json:"bytes_sent"`
+	BytesReceived int64     `json:"bytes_received"`
+	Connections   int64     `json:"connections"` // opened in the minute
+}
+
+// tunnelConn is a connection in progress. Its byte counts are atomic; the
+// rest is guarded by the tunnel's metricsMu.
+type tunnelConn struct {
+	info     TunnelConnection
+	sent     int64
+	received int64
+	closers  []io.Closer
+	closed   bool // by CloseConnection
+}
+
+// tunnelMetrics is what the sampler last saw of a tunnel; guarded by
+// metricsMu
+type tunnelMetrics struct {
+	sent, received, opened int64
+	connections            int32
+	history                []TunnelTrafficPoint // oldest first, minutes with traffic only
+}
+
+// openConn records a connection from client through the tunnel, closed with
+// closers when it is force-closed
+func (t *Tunnel) openConn(client, target string, closers ...io.Closer) *tunnelConn {
+	c := &tunnelConn{
+		info: TunnelConnection{
+			ID:       strconv.FormatInt(atomic.AddInt64(&t.opened, 1), 10),
+			Client:   client,
+			Target:   target,
+			OpenedAt: time.Now(),
+		},
+		closers: closers,
+	}
+	t.metricsMu.Lock()
+	defer t.metricsMu.Unlock()
+	if t.conns == nil {
+		t.conns = make(map[string]*tunnelConn)
+	}
+	t.conns[c.info.ID] = c
+	return c
+}
+
+// track adds a connection, if any, to close when c is force-closed, and
+// sets the target once it is known
+func (t *Tunnel) track(c *tunnelConn, target string, closer io.Closer) {
+	t.metricsMu.Lock()
+	defer t.metricsMu.Unlock()
+	if target != "" {
+		c.info.Target = target
+	}
+	if closer == nil {
+		return
+	}
+	c.closers = append(c.closers, closer)
+	if c.closed {
+		_ = closer.Close()
+	}
+}
+
+// closeConn moves c to the tunnel's recent connections with err, if any
+func (t *Tunnel) closeConn(c *tunnelConn, err error) {
+	now := time.Now()
+	t.metricsMu.Lock()
+	defer t.metricsMu.Unlock()
+	delete(t.conns, c.info.ID)
+	info := c.snapshot()
+	info.ClosedAt = &now
+	if c.closed {
+		err = errClosedByUser
+	}
+	if err != nil {
+		info.Error = err.Error()
+	}
+	t.recent = append(t.recent, info)
+	if len(t.recent) > tunnelRecentConns {
+		t.recent = t.recent[len(t.recent)-tunnelRecentConns:]
+	}
+}
+
+// snapshot returns the connection's details with its current byte counts
+func (c *tunnelConn) snapshot() TunnelConnection {
+	info := c.info
+	info.BytesSent = atomic.LoadInt64(&c.sent)
+	info.BytesReceived = atomic.LoadInt64(&c.received)
+	return info
+}
+
+// countingWriter adds what it writes to a tunnel's and a connection's counts
+type countingWriter struct {
+	w            io.Writer
+	tunnel, conn *int64
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	atomic.AddInt64(w.tunnel, int64(n))
+	atomic.AddInt64(w.conn, int64(n))
+	return n, err
+}
+
+// quietCopyError drops the errors of a connection closing normally
+func quietCopyError(err error) error {
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// ListConnections returns the tunnel's open connections and those that
+// closed recently, oldest first
+func (s *TunnelService) ListConnections(tunnelID string) (active, recent []TunnelConnection, err error) {
+	tunnel, err := s.tunnel(tunnelID)
+	if err != nil {
+		return nil, nil, err
+	}
+	tunnel.metricsMu.Lock()
+	defer tunnel.metricsMu.Unlock()
+	active = make([]TunnelConnection, 0, len(tunnel.conns))
+	for _, c := range tunnel.conns {
+		active = append(active, c.snapshot())
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].OpenedAt.Before(active[j].OpenedAt) })
+	recent = append([]TunnelConnection{}, tunnel.recent...)
+	return active, recent, nil
+}
+
+// CloseConnection force-closes an open connection of the tunnel
+func (s *TunnelService) CloseConnection(tunnelID, connID string) error {
+	tunnel, err := s.tunnel(tunnelID)
+	if err != nil {
+		return err
+	}
+	tunnel.metricsMu.Lock()
+	defer tunnel.metricsMu.Unlock()
+	c, ok := tunnel.conns[connID]
+	if !ok {
+		return ErrTunnelConnectionNotFound
+	}
+	c.closed = true
+	for _, closer := range c.closers {
+		_ = closer.Close()
+	}
+	return nil
+}
+
+// TrafficHistory returns the tunnel's traffic in each of the last minutes,
+// oldest first, with zeros for minutes without any
+func (s *TunnelService) TrafficHistory(tunnelID string, minutes int) ([]TunnelTrafficPoint, error) {
+	tunnel, err := s.tunnel(tunnelID)
+	if err != nil {
+		return nil, err
+	}
+	if minutes <= 0 || minutes > tunnelHistoryMinutes {
+		minutes = tunnelHistoryMinutes
+	}
+	end := time.Now().Truncate(time.Minute)
+	start := end.Add(-time.Duration(minutes-1) * time.Minute)
+
+	tunnel.metricsMu.Lock()
+	defer tunnel.metricsMu.Unlock()
+	points := make([]TunnelTrafficPoint, minutes)
+	for i := range points {
+		points[i].Time = start.Add(time.Duration(i) * time.Minute)
+	}
+	for _, p := range tunnel.metrics.history {
+		if i := int(p.Time.Sub(start) / time.Minute); i >= 0 && i < minutes {
+			points[i] = p
+		}
+	}
+	return points, nil
+}
+
+// metricsLoop samples the tunnels' traffic until the service is gone
+func (s *TunnelService) metricsLoop() {
+	ticker := time.NewTicker(tunnelMetricsInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		s.sampleMetrics(now, tunnelMetricsInterval)
+	}
+}
+
+// sampleMetrics adds the traffic of each tunnel since the last sample to
+// its history, and emits tunnel.metrics for those whose traffic or
+// connections changed
+func (s *TunnelService) sampleMetrics(now time.Time, interval time.Duration) {
+	s.mu.RLock()
+	tunnels := make([]*Tunnel, 0, len(s.tunnels))
+	for _, t := range s.tunnels {
+		tunnels = append(tunnels, t)
+	}
+	s.mu.RUnlock()
+
+	minute := now.Truncate(time.Minute)
+	for _, t := range tunnels {
+		sent := atomic.LoadInt64(&t.BytesSent)
+		received := atomic.LoadInt64(&t.BytesReceived)
+		opened := atomic.LoadInt64(&t.opened)
+		connections := atomic.LoadInt32(&t.Connections)
+
+		t.metricsMu.Lock()
+		m := &t.metrics
+		point := TunnelTrafficPoint{
+			Time:          minute,
+			BytesSent:     sent - m.sent,
+			BytesReceived: received - m.received,
+			Connections:   opened - m.opened,
+		}
+		changed := point.BytesSent != 0 || point.BytesReceived != 0 || point.Connections != 0 || connections != m.connections
+		m.sent, m.received, m.opened, m.connections = sent, received, opened, connections
+		if point.BytesSent != 0 || point.BytesReceived != 0 || point.Connections != 0 {
+			if n := len(m.history); n > 0 && m.history[n-1].Time.Equal(minute) {
+				m.history[n-1].BytesSent += point.BytesSent
+				m.history[n-1].BytesReceived += point.BytesReceived
+				m.history[n-1].Connections += point.Connections
+			} else {
+				m.history = append(m.history, point)
+			}
+			cutoff := minute.Add(-tunnelHistoryMinutes * time.Minute)
+			for len(m.history) > 0 && !m.history[0].Time.After(cutoff) {
+				m.history = m.history[1:]
+			}
+		}
+		t.metricsMu.Unlock()
+
+		if changed {
+			seconds := interval.Seconds()
+			event.Emit(event.TunnelMetricsEvent{
+				TunnelID:      t.ID,
+				BytesSent:     sent,
+				BytesReceived: received,
+				Connections:   connections,
+				SendRate:      int64(float64(point.BytesSent) / seconds),
+				ReceiveRate:   int64(float64(point.BytesReceived) / seconds),
+			})
+		}
+	}
+}
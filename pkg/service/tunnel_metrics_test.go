@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTunnelConnections(t *testing.T) {
+	tunnel := &Tunnel{ID: "t"}
+	s := &TunnelService{tunnels: map[string]*Tunnel{"t": tunnel}}
+
+	client, local := net.Pipe()
+	remote, server := net.Pipe()
+	c := tunnel.openConn("127.0.0.1:5000", "db:5432", local)
+	tunnel.track(c, "", remote)
+	done := make(chan struct{})
+	go func() {
+		err := s.proxyConnections(tunnel, c, local, remote)
+		tunnel.closeConn(c, err)
+		close(done)
+	}()
+
+	buf := make([]byte, 5)
+	if _, err := client.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(server, buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Write([]byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(client, buf[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	// The counts are added once the peer has read the write
+	var active []TunnelConnection
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		var err error
+		if active, _, err = s.ListConnections("t"); err != nil {
+			t.Fatal(err)
+		}
+		if len(active) == 1 && active[0].BytesSent == 5 && active[0].BytesReceived == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("active connections: %+v", active)
+		}
+	}
+	if active[0].Client != "127.0.0.1:5000" || active[0].Target != "db:5432" {
+		t.Fatalf("active connection: %+v", active[0])
+	}
+
+	if err := s.CloseConnection("t", active[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("connection still proxied after close")
+	}
+	active, recent, _ := s.ListConnections("t")
+	if len(active) != 0 || len(recent) != 1 || recent[0].ClosedAt == nil || recent[0].Error != errClosedByUser.Error() {
+		t.Fatalf("after close: active %+v, recent %+v", active, recent)
+	}
+	if err := s.CloseConnection("t", recent[0].ID); !errors.Is(err, ErrTunnelConnectionNotFound) {
+		t.Fatalf("closing a closed connection: %v", err)
+	}
+	if _, _, err := s.ListConnections("missing"); !errors.Is(err, ErrTunnelNotFound) {
+		t.Fatalf("unknown tunnel: %v", err)
+	}
+}
+
+func TestTunnelTrafficHistory(t *testing.T) {
+	tunnel := &Tunnel{ID: "t"}
+	s := &TunnelService{tunnels: map[string]*Tunnel{"t": tunnel}}
+
+	now := time.Now()
+	tunnel.BytesSent, tunnel.BytesReceived, tunnel.opened = 100, 40, 1
+	s.sampleMetrics(now, tunnelMetricsInterval)
+	tunnel.BytesSent, tunnel.BytesReceived = 150, 40
+	s.sampleMetrics(now, tunnelMetricsInterval)
+
+	// The sample may fall in the previous minute of the window
+	points, err := s.TrafficHistory("t", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("got %d points, want 2", len(points))
+	}
+	var sent, received, opened int64
+	for _, p := range points {
+		sent += p.BytesSent
+		received += p.BytesReceived
+		opened += p.Connections
+	}
+	if sent != 150 || received != 40 || opened != 1 {
+		t.Fatalf("history %+v", points)
+	}
+
+	// A day later the first minute has aged out
+	tunnel.BytesSent = 160
+	s.sampleMetrics(now.Add(tunnelHistoryMinutes*time.Minute), tunnelMetricsInterval)
+	if h := tunnel.metrics.history; len(h) != 1 || h[0].BytesSent != 10 {
+		t.Fatalf("history after a day: %+v", h)
+	}
+}
@@ -0,0 +1,499 @@
+package service
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/secrets"
+)
+
+// ErrTunnelNoPAC is returned for the PAC file of a tunnel that serves none
+var ErrTunnelNoPAC = errors.New("tunnel does not serve a PAC file")
+
+var (
+	errForwardDenied = errors.New("destination not allowed")
+	errProxyAuth     = errors.New("proxy authentication failed")
+)
+
+// proxyPACPath is where the proxy of a dynamic forward serves its PAC file
+// to plain HTTP requests
+const proxyPACPath = "/proxy.pac"
+
+// bufferedConn reads through r, which may hold bytes the client sent past
+// the proxy handshake
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// proxySession is a client of a dynamic forward, recorded as c
+type proxySession struct {
+	s      *TunnelService
+	tunnel *Tunnel
+	c      *tunnelConn
+	conn   *bufferedConn
+	cfg    models.TunnelProxy
+}
+
+// handleProxy serves a client of a dynamic forward in the protocols its
+// proxy mode accepts, told apart by the first byte, and returns why it
+// failed
+func (s *TunnelService) handleProxy(tunnel *Tunnel, c *tunnelConn, conn net.Conn) error {
+	p := &proxySession{s: s, tunnel: tunnel, c: c, conn: &bufferedConn{Conn: conn, r: bufio.NewReader(conn)}}
+	if tunnel.Config.Proxy != nil {
+		p.cfg = *tunnel.Config.Proxy
+	}
+	if p.cfg.Mode == "" {
+		p.cfg.Mode = models.TunnelProxySOCKS
+	}
+	password, err := secrets.Resolve(p.cfg.Password)
+	if err != nil {
+		return fmt.Errorf("proxy password: %w", err)
+	}
+	p.cfg.Password = password
+
+	first, err := p.conn.r.Peek(1)
+	if err != nil {
+		return quietCopyError(err)
+	}
+	socks := p.cfg.Mode != models.TunnelProxyHTTP
+	switch {
+	case first[0] == 0x05 && socks:
+		return p.socks5()
+	case first[0] == 0x04 && socks:
+		return p.socks4()
+	case p.cfg.Mode != models.TunnelProxySOCKS:
+		return p.httpProxy()
+	}
+	return fmt.Errorf("proxy: unsupported protocol")
+}
+
+// dial connects to host:port through the tunnel's SSH connection if the
+// proxy's rules allow it
+func (p *proxySession) dial(host string, port int) (net.Conn, error) {
+	target := net.JoinHostPort(host, strconv.Itoa(port))
+	p.tunnel.track(p.c, target, nil)
+	if err := checkForwardRules(p.cfg.ForwardRules, host, port); err != nil {
+		return nil, err
+	}
+
+	p.tunnel.mu.RLock()
+	sshClient := p.tunnel.sshClient
+	p.tunnel.mu.RUnlock()
+	if sshClient == nil {
+		return nil, errors.New("SSH connection lost")
+	}
+	remote, err := sshClient.Dial("tcp", target)
+	if err != nil {
+		return nil, err
+	}
+	p.tunnel.track(p.c, "", remote)
+	return remote, nil
+}
+
+// authorized reports whether the credentials match the proxy's, or it
+// requires none
+func (p *proxySession) authorized(username, password string) bool {
+	if p.cfg.Username == "" {
+		return true
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(p.cfg.Username))
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(p.cfg.Password))
+	return userOK&passOK == 1
+}
+
+// socks5 serves a SOCKS5 CONNECT, with username/password authentication
+// when the proxy has a username
+func (p *proxySession) socks5() error {
+	r := p.conn.r
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return quietCopyError(err)
+	}
+	methods := make([]byte, head[1])
+	if _, err := io.ReadFull(r, methods); err != nil {
+		return quietCopyError(err)
+	}
+
+	if p.cfg.Username == "" {
+		p.conn.Write([]byte{0x05, 0x00}) // No auth required
+	} else {
+		if !slices.Contains(methods, 0x02) {
+			p.conn.Write([]byte{0x05, 0xFF}) // No acceptable methods
+			return fmt.Errorf("socks: %w: client does not offer username/password", errProxyAuth)
+		}
+		p.conn.Write([]byte{0x05, 0x02})
+		// RFC 1929: VER ULEN UNAME PLEN PASSWD
+		username, password, err := readSOCKS5Credentials(r)
+		if err != nil {
+			return err
+		}
+		if !p.authorized(username, password) {
+			p.conn.Write([]byte{0x01, 0x01})
+			return fmt.Errorf("socks: %w for %q", errProxyAuth, username)
+		}
+		p.conn.Write([]byte{0x01, 0x00})
+	}
+
+	// VER CMD RSV ATYP DST.ADDR DST.PORT
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(r, req); err != nil {
+		return quietCopyError(err)
+	}
+	if req[0] != 0x05 || req[1] != 0x01 {
+		p.conn.Write([]byte{0x05, 0x07, 0x00, 0x01, 0, 0, 0, 0, 0, 0}) // Command not supported
+		return fmt.Errorf("socks: unsupported command %d", req[1])
+	}
+
+	var host string
+	switch req[3] {
+	case 0x01: // IPv4
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return err
+		}
+		host = net.IP(ip).String()
+	case 0x03: // Domain
+		n, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		name := make([]byte, n)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return err
+		}
+		host = string(name)
+	case 0x04: // IPv6
+		ip := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return err
+		}
+		host = net.IP(ip).String()
+	default:
+		p.conn.Write([]byte{0x05, 0x08, 0x00, 0x01, 0, 0, 0, 0, 0, 0}) // Address type not supported
+		return fmt.Errorf("socks: unsupported address type %d", req[3])
+	}
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(r, portBytes); err != nil {
+		return err
+	}
+
+	remote, err := p.dial(host, int(binary.BigEndian.Uint16(portBytes)))
+	if err != nil {
+		reply := byte(0x05) // Connection refused
+		if errors.Is(err, errForwardDenied) {
+			reply = 0x02 // Connection not allowed by ruleset
+		}
+		p.conn.Write([]byte{0x05, reply, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return err
+	}
+	defer remote.Close()
+
+	p.conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+	return p.s.proxyConnections(p.tunnel, p.c, p.conn, remote)
+}
+
+// readSOCKS5Credentials reads a username/password request
+func readSOCKS5Credentials(r *bufio.Reader) (username, password string, err error) {
+	if ver, err := r.ReadByte(); err != nil || ver != 0x01 {
+		return "", "", fmt.Errorf("socks: bad authentication request")
+	}
+	read := func() (string, error) {
+		n, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return string(b), err
+	}
+	if username, err = read(); err != nil {
+		return "", "", err
+	}
+	if password, err = read(); err != nil {
+		return "", "", err
+	}
+	return username, password, nil
+}
+
+// socks4 serves a SOCKS4 or SOCKS4a CONNECT. SOCKS4 has no passwords, so
+// it is refused when the proxy requires authentication.
+func (p *proxySession) socks4() error {
+	r := p.conn.r
+	// VN CD DSTPORT DSTIP USERID NUL [DOMAIN NUL]
+	req := make([]byte, 8)
+	if _, err := io.ReadFull(r, req); err != nil {
+		return quietCopyError(err)
+	}
+	if _, err := r.ReadString(0); err != nil {
+		return err
+	}
+	reject := func(err error) error {
+		p.conn.Write([]byte{0x00, 0x5B, 0, 0, 0, 0, 0, 0})
+		return err
+	}
+	if req[1] != 0x01 {
+		return reject(fmt.Errorf("socks4: unsupported command %d", req[1]))
+	}
+	if p.cfg.Username != "" {
+		return reject(fmt.Errorf("socks4: %w: SOCKS4 cannot authenticate", errProxyAuth))
+	}
+
+	port := int(binary.BigEndian.Uint16(req[2:4]))
+	host := net.IP(req[4:8]).String()
+	// SOCKS4a: an IP of 0.0.0.x with x non-zero is followed by a domain
+	if req[4] == 0 && req[5] == 0 && req[6] == 0 && req[7] != 0 {
+		domain, err := r.ReadString(0)
+		if err != nil {
+			return err
+		}
+		host = strings.TrimSuffix(domain, "\x00")
+	}
+
+	remote, err := p.dial(host, port)
+	if err != nil {
+		return reject(err)
+	}
+	defer remote.Close()
+
+	p.conn.Write([]byte{0x00, 0x5A, 0, 0, 0, 0, 0, 0})
+	return p.s.proxyConnections(p.tunnel, p.c, p.conn, remote)
+}
+
+// httpProxy serves an HTTP proxy request: CONNECT tunnels any protocol, and an
+// absolute http:// URL is forwarded as a single request. A GET of
+// proxyPACPath serves the PAC file when the proxy has one.
+func (p *proxySession) httpProxy() error {
+	req, err := http.ReadRequest(p.conn.r)
+	if err != nil {
+		return quietCopyError(err)
+	}
+
+	if req.Method == http.MethodGet && !req.URL.IsAbs() && req.URL.Path == proxyPACPath && p.cfg.PAC {
+		pac := proxyPAC(&p.cfg, req.Host)
+		fmt.Fprintf(p.conn, "HTTP/1.1 200 OK\r\nContent-Type: application/x-ns-proxy-autoconfig\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s", len(pac), pac)
+		return nil
+	}
+
+	if p.cfg.Username != "" {
+		username, password, _ := parseProxyAuthorization(req.Header.Get("Proxy-Authorization"))
+		if !p.authorized(username, password) {
+			io.WriteString(p.conn, "HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: Basic realm=\"choraleia\"\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+			return fmt.Errorf("http proxy: %w for %q", errProxyAuth, username)
+		}
+	}
+	req.Header.Del("Proxy-Authorization")
+	req.Header.Del("Proxy-Connection")
+
+	var hostport string
+	switch {
+	case req.Method == http.MethodConnect:
+		hostport = req.Host
+	case req.URL.IsAbs() && req.URL.Scheme == "http":
+		hostport = req.URL.Host
+		if req.URL.Port() == "" {
+			hostport = net.JoinHostPort(req.URL.Hostname(), "80")
+		}
+	default:
+		writeHTTPProxyError(p.conn, http.StatusBadRequest, "not a proxy request")
+		return fmt.Errorf("http proxy: unsupported request %s %s", req.Method, req.RequestURI)
+	}
+	host, portStr, err := net.SplitHostPort(hostport)
+	port, _ := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		writeHTTPProxyError(p.conn, http.StatusBadRequest, "bad destination "+hostport)
+		return fmt.Errorf("http proxy: bad destination %q", hostport)
+	}
+
+	remote, err := p.dial(host, port)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, errForwardDenied) {
+			status = http.StatusForbidden
+		}
+		writeHTTPProxyError(p.conn, status, err.Error())
+		return err
+	}
+	defer remote.Close()
+
+	if req.Method == http.MethodConnect {
+		io.WriteString(p.conn, "HTTP/1.1 200 Connection Established\r\n\r\n")
+	} else {
+		// Later requests on the connection could be for other hosts, so
+		// the server closes it after this one
+		req.Close = true
+		if err := req.Write(countingWriter{remote, &p.tunnel.BytesSent, &p.c.sent}); err != nil {
+			return err
+		}
+	}
+	return p.s.proxyConnections(p.tunnel, p.c, p.conn, remote)
+}
+
+// parseProxyAuthorization decodes Basic credentials
+func parseProxyAuthorization(header string) (username, password string, ok bool) {
+	encoded, ok := strings.CutPrefix(header, "Basic ")
+	if !ok {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+	username, password, ok = strings.Cut(string(decoded), ":")
+	return username, password, ok
+}
+
+func writeHTTPProxyError(w io.Writer, status int, msg string) {
+	fmt.Fprintf(w, "HTTP/1.1 %d %s\r\nContent-Type: text/plain\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s",
+		status, http.StatusText(status), len(msg), msg)
+}
+
+// checkForwardRules returns errForwardDenied, wrapped, when the rules do not
+// let a forward connect to host:port. Hosts are matched as the client asked
+// for them, without resolving names: the SSH server resolves them, maybe
+// differently from here. So a name is refused whenever an IP or CIDR is
+// blocked, as it could resolve into the blocked range.
+func checkForwardRules(rules models.ForwardRules, host string, port int) error {
+	if slices.Contains(rules.BlockedForwardPorts, port) ||
+		len(rules.AllowedForwardPorts) > 0 && !slices.Contains(rules.AllowedForwardPorts, port) {
+		return fmt.Errorf("%w: port %d", errForwardDenied, port)
+	}
+	if net.ParseIP(strings.Trim(host, "[]")) == nil && slices.ContainsFunc(rules.BlockedHosts, isAddressPattern) {
+		return fmt.Errorf("%w: host %s is a name and addresses are blocked", errForwardDenied, host)
+	}
+	if matchesHost(rules.BlockedHosts, host) ||
+		len(rules.AllowedHosts) > 0 && !matchesHost(rules.AllowedHosts, host) {
+		return fmt.Errorf("%w: host %s", errForwardDenied, host)
+	}
+	return nil
+}
+
+// matchesHost reports whether host matches any of the patterns: a name
+// with * wildcards, case-insensitively, an IP, or a CIDR containing it
+func matchesHost(patterns []string, host string) bool {
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	ip := net.ParseIP(host)
+	for _, pattern := range patterns {
+		if strings.Contains(pattern, "/") {
+			if _, network, err := net.ParseCIDR(pattern); err == nil && ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if pip := net.ParseIP(pattern); pip != nil {
+			if pip.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isAddressPattern reports whether a host pattern is an IP or a CIDR
+func isAddressPattern(pattern string) bool {
+	if strings.Contains(pattern, "/") {
+		_, _, err := net.ParseCIDR(pattern)
+		return err == nil
+	}
+	return net.ParseIP(pattern) != nil
+}
+
+// ProxyPAC returns the PAC file of a dynamic forward serving one. host is
+// how clients reach the proxy when it listens on every address.
+func (s *TunnelService) ProxyPAC(tunnelID, host string) (string, error) {
+	tunnel, err := s.tunnel(tunnelID)
+	if err != nil {
+		return "", err
+	}
+	tunnel.mu.RLock()
+	cfg := tunnel.Config
+	tunnel.mu.RUnlock()
+	if cfg.Type != "dynamic" || cfg.Proxy == nil || !cfg.Proxy.PAC {
+		return "", ErrTunnelNoPAC
+	}
+	if ip := net.ParseIP(cfg.LocalHost); cfg.LocalHost != "" && (ip == nil || !ip.IsUnspecified()) {
+		host = cfg.LocalHost
+	}
+	return proxyPAC(cfg.Proxy, net.JoinHostPort(host, strconv.Itoa(cfg.LocalPort))), nil
+}
+
+// proxyPAC returns a PAC file sending browsers through the proxy at addr,
+// or the address they reached it at, and straight to the hosts its rules
+// would refuse
+func proxyPAC(cfg *models.TunnelProxy, addr string) string {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return proxyPAC(cfg, net.JoinHostPort(addr, "80"))
+	}
+	var route string
+	switch cfg.Mode {
+	case models.TunnelProxyHTTP:
+		route = "PROXY " + addr
+	case models.TunnelProxyMixed:
+		route = "PROXY " + addr + "; SOCKS5 " + addr
+	default:
+		route = "SOCKS5 " + addr + "; SOCKS " + addr
+	}
+
+	// isInNet only takes IPv4 networks; the proxy still enforces the rest
+	type rules struct {
+		Patterns []string    `json:"patterns"`
+		Nets     [][2]string `json:"nets"`
+	}
+	toRules := func(hosts []string) rules {
+		r := rules{Patterns: []string{}, Nets: [][2]string{}}
+		for _, h := range hosts {
+			if !strings.Contains(h, "/") {
+				r.Patterns = append(r.Patterns, strings.ToLower(h))
+				continue
+			}
+			if _, network, err := net.ParseCIDR(h); err == nil && network.IP.To4() != nil {
+				r.Nets = append(r.Nets, [2]string{network.IP.String(), net.IP(network.Mask).String()})
+			}
+		}
+		return r
+	}
+	allowed, _ := json.Marshal(toRules(cfg.AllowedHosts))
+	blocked, _ := json.Marshal(toRules(cfg.BlockedHosts))
+	routeJSON, _ := json.Marshal(route)
+
+	return fmt.Sprintf(`var allowed = %s;
+var blocked = %s;
+var restricted = %t;
+
+function matches(rules, host) {
+  for (var i = 0; i < rules.patterns.length; i++) {
+    if (shExpMatch(host.toLowerCase(), rules.patterns[i])) return true;
+  }
+  for (var j = 0; j < rules.nets.length; j++) {
+    if (isInNet(host, rules.nets[j][0], rules.nets[j][1])) return true;
+  }
+  return false;
+}
+
+function FindProxyForURL(url, host) {
+  if (matches(blocked, host) || (restricted && !matches(allowed, host))) return "DIRECT";
+  return %s;
+}
+`, allowed, blocked, len(cfg.AllowedHosts) > 0, routeJSON)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/choraleia/choraleia/pkg/models"
+)
+
+func TestCheckForwardRules(t *testing.T) {
+	rules := models.ForwardRules{
+		AllowedHosts:        []string{"*.internal", "10.0.0.0/8", "192.168.1.5"},
+		BlockedHosts:        []string{"secret.internal"},
+		AllowedForwardPorts: []int{22, 443},
+	}
+	tests := []struct {
+		host    string
+		port    int
+		allowed bool
+	}{
+		{"db.internal", 443, true},
+		{"DB.Internal", 22, true},
+		{"10.1.2.3", 22, true},
+		{"192.168.1.5", 443, true},
+		{"secret.internal", 443, false},
+		{"db.internal", 80, false},
+		{"example.com", 443, false},
+		{"192.168.1.6", 443, false},
+	}
+	for _, tt := range tests {
+		err := checkForwardRules(rules, tt.host, tt.port)
+		if (err == nil) != tt.allowed || err != nil && !errors.Is(err, errForwardDenied) {
+			t.Errorf("%s:%d: %v, want allowed %v", tt.host, tt.port, err, tt.allowed)
+		}
+	}
+	if err := checkForwardRules(models.ForwardRules{}, "anything", 1); err != nil {
+		t.Errorf("empty rules: %v", err)
+	}
+
+	// Names could resolve into a blocked range, so only IPs get through
+	rules = models.ForwardRules{BlockedHosts: []string{"169.254.0.0/16", "10.0.0.1"}}
+	tests = []struct {
+		host    string
+		port    int
+		allowed bool
+	}{
+		{"169.254.169.254", 80, false},
+		{"10.0.0.1", 80, false},
+		{"10.0.0.2", 80, true},
+		{"[::1]", 80, true},
+		{"metadata.internal", 80, false},
+		{"localhost", 80, false},
+	}
+	for _, tt := range tests {
+		err := checkForwardRules(rules, tt.host, tt.port)
+		if (err == nil) != tt.allowed || err != nil && !errors.Is(err, errForwardDenied) {
+			t.Errorf("%s:%d: %v, want allowed %v", tt.host, tt.port, err, tt.allowed)
+		}
+	}
+}
+
+func TestProxyHandshakes(t *testing.T) {
+	tunnel := &Tunnel{ID: "t", Config: models.SSHTunnel{
+		Type:      "dynamic",
+		LocalHost: "127.0.0.1",
+		LocalPort: 1080,
+		Proxy: &models.TunnelProxy{
+			Mode:         models.TunnelProxyMixed,
+			Username:     "alice",
+			Password:     "s3cret",
+			PAC:          true,
+			ForwardRules: models.ForwardRules{AllowedForwardPorts: []int{443}},
+		},
+	}}
+	s := &TunnelService{tunnels: map[string]*Tunnel{"t": tunnel}}
+
+	// serve sends input to the proxy and returns all it answered
+	serve := func(input string) string {
+		client, server := net.Pipe()
+		go func() {
+			c := tunnel.openConn("client", "", server)
+			tunnel.closeConn(c, s.handleProxy(tunnel, c, server))
+			server.Close()
+		}()
+		go client.Write([]byte(input))
+		out, _ := io.ReadAll(client)
+		return string(out)
+	}
+	socks5Auth := func(user, pass string) string {
+		return "\x01" + string(byte(len(user))) + user + string(byte(len(pass))) + pass
+	}
+	socks5Connect := "\x05\x01\x00\x03\x0bdb.internal\x00\x16" // db.internal:22
+	basic := base64.StdEncoding.EncodeToString([]byte("alice:s3cret"))
+
+	tests := []struct {
+		name, input, want string
+	}{
+		{"socks5 without auth method", "\x05\x01\x00", "\x05\xff"},
+		{"socks5 wrong password", "\x05\x01\x02" + socks5Auth("alice", "nope"), "\x05\x02\x01\x01"},
+		{"socks5 denied port", "\x05\x01\x02" + socks5Auth("alice", "s3cret") + socks5Connect,
+			"\x05\x02\x01\x00\x05\x02\x00\x01\x00\x00\x00\x00\x00\x00"},
+		{"socks4 needs auth", "\x04\x01\x01\xbb\x0a\x00\x00\x01alice\x00", "\x00\x5b\x00\x00\x00\x00\x00\x00"},
+		{"http without auth", "CONNECT db.internal:443 HTTP/1.1\r\nHost: db.internal:443\r\n\r\n", "HTTP/1.1 407 "},
+		{"http denied port", "CONNECT db.internal:22 HTTP/1.1\r\nHost: db.internal:22\r\nProxy-Authorization: Basic " + basic + "\r\n\r\n", "HTTP/1.1 403 "},
+		{"http without SSH", "CONNECT db.internal:443 HTTP/1.1\r\nHost: db.internal:443\r\nProxy-Authorization: Basic " + basic + "\r\n\r\n", "HTTP/1.1 502 "},
+		{"pac", "GET /proxy.pac HTTP/1.1\r\nHost: 10.0.0.2:1080\r\n\r\n", "HTTP/1.1 200 "},
+	}
+	for _, tt := range tests {
+		if got := serve(tt.input); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	pac := serve("GET /proxy.pac HTTP/1.1\r\nHost: 10.0.0.2:1080\r\n\r\n")
+	if !strings.Contains(pac, `"PROXY 10.0.0.2:1080; SOCKS5 10.0.0.2:1080"`) {
+		t.Errorf("pac served by the proxy: %s", pac)
+	}
+	if pac, err := s.ProxyPAC("t", "example.com"); err != nil || !strings.Contains(pac, "127.0.0.1:1080") {
+		t.Errorf("ProxyPAC = %q, %v", pac, err)
+	}
+	tunnel.Config.Proxy.PAC = false
+	if _, err := s.ProxyPAC("t", "example.com"); !errors.Is(err, ErrTunnelNoPAC) {
+		t.Errorf("ProxyPAC without pac: %v", err)
+	}
+}
@@ -2,22 +2,22 @@
 package service
 
 import (
-	"bufio"
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
-	"net/http"
-	"os"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/choraleia/choraleia/pkg/event"
 	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service/fs"
 	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
 )
 
 // TunnelStatus represents the current status of a tunnel
@@ -27,6 +27,13 @@ const (
 	TunnelStatusRunning TunnelStatus = "running"
 	TunnelStatusStopped TunnelStatus = "stopped"
 	TunnelStatusError   TunnelStatus = "error"
+	// The tunnel failed and waits to be restarted by its restart policy
+	TunnelStatusReconnecting TunnelStatus = "reconnecting"
+)
+
+var (
+	ErrTunnelNotFound           = errors.New("tunnel not found")
+	ErrTunnelConnectionNotFound = errors.New("tunnel connection not found")
 )
 
 // TunnelInfo represents runtime information about a tunnel
@@ -45,6 +52,11 @@ type TunnelInfo struct {
 	BytesReceived int64        `json:"bytes_received"`
 	Connections   int32        `json:"connections"`
 	StartedAt     *time.Time   `json:"started_at,omitempty"`
+	AutoStart     bool         `json:"auto_start"`
+	RestartPolicy string       `json:"restart_policy,omitempty"`
+	Restarts      int          `json:"restarts,omitempty"` // attempts since it last ran
+
+	Target *models.PortForwardTarget `json:"target,omitempty"` // standalone port forwards only
 }
 
 // TunnelStats represents aggregate statistics for all tunnels
@@ -53,6 +65,7 @@ type TunnelStats struct {
 	Running            int   `json:"running"`
 	Stopped            int   `json:"stopped"`
 	Error              int   `json:"error"`
+	Reconnecting       int   `json:"reconnecting"`
 	TotalBytesSent     int64 `json:"total_bytes_sent"`
 	TotalBytesReceived int64 `json:"total_bytes_received"`
 }
@@ -69,27 +82,68 @@ type Tunnel struct {
 	BytesReceived int64
 	Connections   int32
 	StartedAt     *time.Time
+	Target        *models.PortForwardTarget // set for a standalone port forward
 
 	listener  net.Listener
 	sshClient *ssh.Client
+	release   func() // returns sshClient to the pool
 	ctx       context.Context
 	cancel    context.CancelFunc
 	mu        sync.RWMutex
+
+	// Where a local forward connects, resolved on each start; direct when
+	// it is reached without SSH, like a container on this machine
+	remoteAddr string
+	direct     bool
+
+	// The supervisor keeps a started tunnel up until it is stopped
+	supervisor     context.Context
+	stopSupervisor context.CancelFunc
+	Restarts       int
+
+	// Connections and traffic history, see tunnel_metrics.go
+	metricsMu sync.Mutex
+	conns     map[string]*tunnelConn
+	recent    []TunnelConnection
+	metrics   tunnelMetrics
+	opened    int64 // connections ever opened, also their last ID
 }
 
 // TunnelService manages SSH tunnels
 type TunnelService struct {
-	assetService *AssetService
-	tunnels      map[string]*Tunnel
-	mu           sync.RWMutex
+	assetService  *AssetService
+	dockerService *DockerService
+	sshPool       *fs.SSHPool
+	db            *gorm.DB // standalone port forwards
+	tunnels       map[string]*Tunnel
+	mu            sync.RWMutex
 }
 
 // NewTunnelService creates a new tunnel service
 func NewTunnelService(assetService *AssetService) *TunnelService {
-	return &TunnelService{
+	s := &TunnelService{
 		assetService: assetService,
 		tunnels:      make(map[string]*Tunnel),
 	}
+	go s.metricsLoop()
+	return s
+}
+
+// tunnel returns the registered tunnel with the ID
+func (s *TunnelService) tunnel(tunnelID string) (*Tunnel, error) {
+	s.mu.RLock()
+	tunnel, exists := s.tunnels[tunnelID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrTunnelNotFound, tunnelID)
+	}
+	return tunnel, nil
+}
+
+// SetSSHPool sets the SSH pool tunnels connect through
+func (s *TunnelService) SetSSHPool(pool *fs.SSHPool) {
+	s.sshPool = pool
 }
 
 // GetTunnels returns all registered tunnels with their current status
@@ -117,6 +171,10 @@ func (s *TunnelService) GetTunnels() ([]TunnelInfo, TunnelStats) {
 			BytesReceived: atomic.LoadInt64(&t.BytesReceived),
 			Connections:   atomic.LoadInt32(&t.Connections),
 			StartedAt:     t.StartedAt,
+			AutoStart:     t.Config.AutoStart,
+			RestartPolicy: t.Config.RestartPolicy,
+			Restarts:      t.Restarts,
+			Target:        t.Target,
 		}
 		t.mu.RUnlock()
 
@@ -130,6 +188,8 @@ func (s *TunnelService) GetTunnels() ([]TunnelInfo, TunnelStats) {
 			stats.Stopped++
 		case TunnelStatusError:
 			stats.Error++
+		case TunnelStatusReconnecting:
+			stats.Reconnecting++
 		}
 		stats.TotalBytesSent += info.BytesSent
 		stats.TotalBytesReceived += info.BytesReceived
@@ -144,9 +204,10 @@ func (s *TunnelService) GetStats() TunnelStats {
 	return stats
 }
 
-// LoadTunnelsFromAssets loads all tunnel configurations from SSH assets
+// LoadTunnelsFromAssets loads all tunnel configurations from SSH assets and
+// the standalone port forwards
 func (s *TunnelService) LoadTunnelsFromAssets() error {
-	assets, err := s.assetService.ListAssets("", nil, "")
+	assets, err := s.assetService.ListAssets("", nil, "", "")
 	if err != nil {
 		return fmt.Errorf("failed to list assets: %w", err)
 	}
@@ -177,37 +238,40 @@ func (s *TunnelService) LoadTunnelsFromAssets() error {
 			tunnelID := tunnelCfg.ID
 			validTunnelIDs[tunnelID] = true
 
+			if tunnelCfg.LocalHost == "" {
+				tunnelCfg.LocalHost = "127.0.0.1"
+			}
+
 			// Check if tunnel already exists in memory
 			if existing, ok := s.tunnels[tunnelID]; ok {
-				// Update asset name in case it changed
+				existing.mu.Lock()
+				// Update asset name in case it changed; a tunnel not
+				// running takes the new settings on its next start
 				existing.AssetName = asset.Name
+				if existing.Status != TunnelStatusRunning && existing.supervisor == nil {
+					existing.Config = tunnelCfg
+				}
+				existing.mu.Unlock()
 				continue
 			}
 
 			// Create new tunnel entry
-			localHost := tunnelCfg.LocalHost
-			if localHost == "" {
-				localHost = "127.0.0.1"
-			}
-
 			tunnel := &Tunnel{
 				ID:        tunnelID,
 				AssetID:   asset.ID,
 				AssetName: asset.Name,
-				Config: models.SSHTunnel{
-					ID:         tunnelCfg.ID,
-					Type:       tunnelCfg.Type,
-					LocalHost:  localHost,
-					LocalPort:  tunnelCfg.LocalPort,
-					RemoteHost: tunnelCfg.RemoteHost,
-					RemotePort: tunnelCfg.RemotePort,
-				},
-				Status: TunnelStatusStopped,
+				Config:    tunnelCfg,
+				Status:    TunnelStatusStopped,
 			}
 			s.tunnels[tunnel.ID] = tunnel
 		}
 	}
 
+	// Load standalone port forwards
+	if err := s.loadPortForwards(validTunnelIDs); err != nil {
+		return err
+	}
+
 	// Remove tunnels that no longer exist in asset configs (only if stopped)
 	for id, t := range s.tunnels {
 		if !validTunnelIDs[id] && t.Status == TunnelStatusStopped {
@@ -218,48 +282,64 @@ func (s *TunnelService) LoadTunnelsFromAssets() error {
 	return nil
 }
 
-// StartTunnel starts a specific tunnel by ID
+// StartTunnel starts a specific tunnel by ID. A tunnel with a restart
+// policy is restarted when it fails, even if this first start does.
 func (s *TunnelService) StartTunnel(tunnelID string) error {
-	s.mu.RLock()
-	tunnel, exists := s.tunnels[tunnelID]
-	s.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("tunnel not found: %s", tunnelID)
+	tunnel, err := s.tunnel(tunnelID)
+	if err != nil {
+		return err
 	}
 
 	tunnel.mu.Lock()
-	if tunnel.Status == TunnelStatusRunning {
+	if tunnel.Status == TunnelStatusRunning || tunnel.supervisor != nil {
 		tunnel.mu.Unlock()
-		return nil // Already running
+		return nil // Already running, or restarting
 	}
+	ctx, stop := context.WithCancel(context.Background())
+	tunnel.supervisor, tunnel.stopSupervisor = ctx, stop
+	tunnel.Restarts = 0
+	policy := tunnel.Config.RestartPolicy
 	tunnel.mu.Unlock()
 
-	// Get SSH asset configuration
-	asset, err := s.assetService.GetAsset(tunnel.AssetID)
-	if err != nil {
-		s.setTunnelError(tunnel, fmt.Sprintf("failed to get asset: %v", err))
+	err = s.run(ctx, tunnel)
+	if err != nil && !restarts(policy) {
+		s.endSupervisor(tunnel, ctx)
+		s.setTunnelError(tunnel, err.Error())
 		return err
 	}
+	go s.supervise(ctx, tunnel, err)
+	return err
+}
 
-	sshConfig, err := parseSSHConfig(asset.Config)
+// run connects the tunnel and opens its listener
+func (s *TunnelService) run(parent context.Context, tunnel *Tunnel) error {
+	route, err := s.route(parent, tunnel)
 	if err != nil {
-		s.setTunnelError(tunnel, fmt.Sprintf("failed to parse SSH config: %v", err))
 		return err
 	}
 
-	// Create SSH client
-	sshClient, err := s.createSSHClient(sshConfig)
-	if err != nil {
-		s.setTunnelError(tunnel, fmt.Sprintf("SSH connection failed: %v", err))
-		return err
+	// Tunnels share the asset's pooled connection; a target reached
+	// directly needs none
+	var sshClient *ssh.Client
+	var release func()
+	if !route.direct {
+		if s.sshPool == nil {
+			return fmt.Errorf("ssh pool not available")
+		}
+		sshClient, release, err = s.sshPool.Acquire(route.sshAssetID)
+		if err != nil {
+			return fmt.Errorf("SSH connection failed: %w", err)
+		}
 	}
 
 	// Create context for cancellation
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parent)
 
 	tunnel.mu.Lock()
 	tunnel.sshClient = sshClient
+	tunnel.release = release
+	tunnel.remoteAddr = net.JoinHostPort(route.host, strconv.Itoa(tunnel.Config.RemotePort))
+	tunnel.direct = route.direct
 	tunnel.ctx = ctx
 	tunnel.cancel = cancel
 	tunnel.mu.Unlock()
@@ -267,59 +347,67 @@ func (s *TunnelService) StartTunnel(tunnelID string) error {
 	// Start the appropriate tunnel type
 	switch tunnel.Config.Type {
 	case "local":
-		return s.startLocalForward(tunnel)
+		err = s.startLocalForward(ctx, tunnel)
 	case "remote":
-		return s.startRemoteForward(tunnel)
+		err = s.startRemoteForward(ctx, tunnel)
 	case "dynamic":
-		return s.startDynamicForward(tunnel)
+		err = s.startDynamicForward(ctx, tunnel)
 	default:
-		s.setTunnelError(tunnel, fmt.Sprintf("unknown tunnel type: %s", tunnel.Config.Type))
-		return fmt.Errorf("unknown tunnel type: %s", tunnel.Config.Type)
+		err = fmt.Errorf("unknown tunnel type: %s", tunnel.Config.Type)
 	}
-}
-
-// StopTunnel stops a specific tunnel by ID
-func (s *TunnelService) StopTunnel(tunnelID string) error {
-	s.mu.RLock()
-	tunnel, exists := s.tunnels[tunnelID]
-	s.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("tunnel not found: %s", tunnelID)
+	if err != nil {
+		s.teardown(tunnel)
 	}
+	return err
+}
 
+// teardown closes the tunnel's listener and hands its SSH client back,
+// leaving its status to the caller
+func (s *TunnelService) teardown(tunnel *Tunnel) {
 	tunnel.mu.Lock()
 	defer tunnel.mu.Unlock()
 
-	if tunnel.Status != TunnelStatusRunning {
-		return nil // Not running
-	}
-
 	// Cancel context to stop goroutines
 	if tunnel.cancel != nil {
 		tunnel.cancel()
+		tunnel.cancel = nil
 	}
 
 	// Close listener
 	if tunnel.listener != nil {
 		tunnel.listener.Close()
+		tunnel.listener = nil
 	}
 
-	// Close SSH client
-	if tunnel.sshClient != nil {
-		tunnel.sshClient.Close()
+	// Hand the SSH client back to the pool, which owns it
+	tunnel.sshClient = nil
+	if tunnel.release != nil {
+		tunnel.release()
+		tunnel.release = nil
 	}
+}
 
-	tunnel.Status = TunnelStatusStopped
-	tunnel.ErrorMessage = ""
-	tunnel.StartedAt = nil
+// StopTunnel stops a specific tunnel by ID
+func (s *TunnelService) StopTunnel(tunnelID string) error {
+	tunnel, err := s.tunnel(tunnelID)
+	if err != nil {
+		return err
+	}
 
-	// Emit status changed event
-	event.Emit(event.TunnelStatusChangedEvent{
-		TunnelID: tunnel.ID,
-		Status:   string(TunnelStatusStopped),
-	})
+	tunnel.mu.Lock()
+	stop := tunnel.stopSupervisor
+	tunnel.supervisor, tunnel.stopSupervisor = nil, nil
+	if tunnel.Status != TunnelStatusRunning && stop == nil {
+		tunnel.mu.Unlock()
+		return nil // Not running
+	}
+	tunnel.mu.Unlock()
 
+	if stop != nil {
+		stop()
+	}
+	s.teardown(tunnel)
+	s.setTunnelStatus(tunnel, TunnelStatusStopped, "")
 	return nil
 }
 
@@ -336,396 +424,26 @@ func parseSSHConfig(config map[string]interface{}) (*models.SSHConfig, error) {
 	return &sshConfig, nil
 }
 
-// createSSHClient creates an SSH client connection
-func (s *TunnelService) createSSHClient(cfg *models.SSHConfig) (*ssh.Client, error) {
-	// Build SSH config
-	sshCfg := &ssh.ClientConfig{
-		User:            cfg.Username,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // TODO: implement proper host key verification
-		Timeout:         time.Duration(cfg.Timeout) * time.Second,
-	}
-
-	if sshCfg.Timeout == 0 {
-		sshCfg.Timeout = 30 * time.Second
-	}
-
-	// Add authentication methods
-	if cfg.Password != "" {
-		sshCfg.Auth = append(sshCfg.Auth, ssh.Password(cfg.Password))
-	}
-
-	// Load private key from path if specified
-	if cfg.PrivateKeyPath != "" {
-		keyData, err := os.ReadFile(cfg.PrivateKeyPath)
-		if err == nil {
-			var signer ssh.Signer
-			if cfg.PrivateKeyPassphrase != "" {
-				signer, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(cfg.PrivateKeyPassphrase))
-			} else {
-				signer, err = ssh.ParsePrivateKey(keyData)
-			}
-			if err == nil {
-				sshCfg.Auth = append(sshCfg.Auth, ssh.PublicKeys(signer))
-			}
-		}
-	}
-
-	// Parse inline private key if specified
-	if cfg.PrivateKey != "" {
-		var signer ssh.Signer
-		var err error
-		keyData := []byte(cfg.PrivateKey)
-		if cfg.PrivateKeyPassphrase != "" {
-			signer, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(cfg.PrivateKeyPassphrase))
-		} else {
-			signer, err = ssh.ParsePrivateKey(keyData)
-		}
-		if err == nil {
-			sshCfg.Auth = append(sshCfg.Auth, ssh.PublicKeys(signer))
-		}
-	}
-
-	// Get target address
-	port := cfg.Port
-	if port == 0 {
-		port = 22
-	}
-	targetAddr := fmt.Sprintf("%s:%d", cfg.Host, port)
-
-	// Handle connection mode: direct, proxy, or jump
-	connectionMode := cfg.ConnectionMode
-	if connectionMode == "" {
-		connectionMode = "direct"
-	}
-
-	switch connectionMode {
-	case "jump":
-		if cfg.JumpAssetID == "" {
-			return nil, fmt.Errorf("jump host asset ID not specified")
-		}
-		return s.connectViaJumpHost(cfg.JumpAssetID, cfg.Host, port, sshCfg)
-
-	case "proxy":
-		if cfg.ProxyHost == "" {
-			return nil, fmt.Errorf("proxy host not specified")
-		}
-		proxyPort := cfg.ProxyPort
-		if proxyPort == 0 {
-			proxyPort = 1080
-		}
-		proxyType := cfg.ProxyType
-		if proxyType == "" {
-			proxyType = "socks5"
-		}
-		return s.connectViaProxy(cfg.Host, port, sshCfg, proxyType, cfg.ProxyHost, proxyPort, cfg.ProxyUsername, cfg.ProxyPassword)
-
-	default:
-		// Direct connection
-		return ssh.Dial("tcp", targetAddr, sshCfg)
-	}
-}
-
-// connectViaJumpHost connects to target via jump host
-func (s *TunnelService) connectViaJumpHost(jumpAssetID string, targetHost string, targetPort int, targetConfig *ssh.ClientConfig) (*ssh.Client, error) {
-	// Get jump host asset
-	jumpAsset, err := s.assetService.GetAsset(jumpAssetID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get jump host asset: %w", err)
-	}
-
-	// Parse jump host config
-	var jumpCfg models.SSHConfig
-	if err := jumpAsset.GetTypedConfig(&jumpCfg); err != nil {
-		return nil, fmt.Errorf("failed to parse jump host config: %w", err)
-	}
-
-	// Validate required fields
-	if jumpCfg.Host == "" {
-		return nil, fmt.Errorf("jump host not specified")
-	}
-	if jumpCfg.Username == "" {
-		return nil, fmt.Errorf("jump host username not specified")
-	}
-
-	// Apply defaults
-	jumpPort := jumpCfg.Port
-	if jumpPort == 0 {
-		jumpPort = 22
-	}
-
-	// Build jump host SSH config
-	jumpSSHConfig := &ssh.ClientConfig{
-		User:            jumpCfg.Username,
-		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
-	}
-
-	// Add jump host authentication
-	if jumpCfg.Password != "" {
-		jumpSSHConfig.Auth = append(jumpSSHConfig.Auth, ssh.Password(jumpCfg.Password))
-	}
-	if jumpCfg.PrivateKeyPath != "" {
-		if keyData, err := os.ReadFile(jumpCfg.PrivateKeyPath); err == nil {
-			var signer ssh.Signer
-			if jumpCfg.PrivateKeyPassphrase != "" {
-				signer, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(jumpCfg.PrivateKeyPassphrase))
-			} else {
-				signer, err = ssh.ParsePrivateKey(keyData)
-			}
-			if err == nil {
-				jumpSSHConfig.Auth = append(jumpSSHConfig.Auth, ssh.PublicKeys(signer))
-			}
-		}
-	}
-	if jumpCfg.PrivateKey != "" {
-		var signer ssh.Signer
-		var err error
-		if jumpCfg.PrivateKeyPassphrase != "" {
-			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(jumpCfg.PrivateKey), []byte(jumpCfg.PrivateKeyPassphrase))
-		} else {
-			signer, err = ssh.ParsePrivateKey([]byte(jumpCfg.PrivateKey))
-		}
-		if err == nil {
-			jumpSSHConfig.Auth = append(jumpSSHConfig.Auth, ssh.PublicKeys(signer))
-		}
-	}
-
-	// Connect to jump host
-	jumpAddr := fmt.Sprintf("%s:%d", jumpCfg.Host, jumpPort)
-	jumpClient, err := ssh.Dial("tcp", jumpAddr, jumpSSHConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to jump host: %w", err)
-	}
-
-	// Connect to target through jump host
-	targetAddr := fmt.Sprintf("%s:%d", targetHost, targetPort)
-	conn, err := jumpClient.Dial("tcp", targetAddr)
-	if err != nil {
-		jumpClient.Close()
-		return nil, fmt.Errorf("failed to dial target through jump host: %w", err)
-	}
-
-	// Create SSH connection over the tunnel
-	ncc, chans, reqs, err := ssh.NewClientConn(conn, targetAddr, targetConfig)
-	if err != nil {
-		conn.Close()
-		jumpClient.Close()
-		return nil, fmt.Errorf("failed to create SSH client connection: %w", err)
-	}
-
-	return ssh.NewClient(ncc, chans, reqs), nil
-}
-
-// connectViaProxy connects to SSH server via SOCKS/HTTP proxy
-func (s *TunnelService) connectViaProxy(host string, port int, sshConfig *ssh.ClientConfig, proxyType, proxyHost string, proxyPort int, proxyUser, proxyPass string) (*ssh.Client, error) {
-	proxyAddr := fmt.Sprintf("%s:%d", proxyHost, proxyPort)
-	targetAddr := fmt.Sprintf("%s:%d", host, port)
-
-	var conn net.Conn
-	var err error
-
-	switch proxyType {
-	case "socks5", "socks4":
-		conn, err = dialSOCKS(proxyAddr, targetAddr, proxyUser, proxyPass, proxyType == "socks5")
-	case "http":
-		conn, err = dialHTTPProxy(proxyAddr, targetAddr, proxyUser, proxyPass)
-	default:
-		return nil, fmt.Errorf("unsupported proxy type: %s", proxyType)
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect via proxy: %w", err)
-	}
-
-	// Create SSH connection over proxy
-	ncc, chans, reqs, err := ssh.NewClientConn(conn, targetAddr, sshConfig)
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to create SSH client connection: %w", err)
-	}
-
-	return ssh.NewClient(ncc, chans, reqs), nil
-}
-
-// dialSOCKS connects through SOCKS proxy
-func dialSOCKS(proxyAddr, targetAddr, user, pass string, isSocks5 bool) (net.Conn, error) {
-	conn, err := net.DialTimeout("tcp", proxyAddr, 30*time.Second)
-	if err != nil {
-		return nil, err
-	}
-
-	if isSocks5 {
-		// SOCKS5 handshake
-		var authMethod byte = 0x00 // No auth
-		if user != "" {
-			authMethod = 0x02 // Username/password
-		}
-
-		// Send greeting
-		conn.Write([]byte{0x05, 0x01, authMethod})
-
-		// Read response
-		resp := make([]byte, 2)
-		if _, err := io.ReadFull(conn, resp); err != nil {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS5 handshake failed: %w", err)
-		}
-
-		if resp[0] != 0x05 {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS5 version mismatch")
-		}
-
-		// Username/password auth if required
-		if resp[1] == 0x02 {
-			authReq := []byte{0x01, byte(len(user))}
-			authReq = append(authReq, []byte(user)...)
-			authReq = append(authReq, byte(len(pass)))
-			authReq = append(authReq, []byte(pass)...)
-			conn.Write(authReq)
-
-			authResp := make([]byte, 2)
-			if _, err := io.ReadFull(conn, authResp); err != nil {
-				conn.Close()
-				return nil, fmt.Errorf("SOCKS5 auth failed: %w", err)
-			}
-			if authResp[1] != 0x00 {
-				conn.Close()
-				return nil, fmt.Errorf("SOCKS5 auth rejected")
-			}
-		}
-
-		// Parse target address
-		host, portStr, _ := net.SplitHostPort(targetAddr)
-		port := 22
-		fmt.Sscanf(portStr, "%d", &port)
-
-		// Send connect request
-		connectReq := []byte{0x05, 0x01, 0x00, 0x03, byte(len(host))}
-		connectReq = append(connectReq, []byte(host)...)
-		connectReq = append(connectReq, byte(port>>8), byte(port&0xff))
-		conn.Write(connectReq)
-
-		// Read connect response
-		connectResp := make([]byte, 10)
-		if _, err := io.ReadFull(conn, connectResp); err != nil {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS5 connect failed: %w", err)
-		}
-
-		if connectResp[1] != 0x00 {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS5 connect rejected: %d", connectResp[1])
-		}
-	} else {
-		// SOCKS4 handshake
-		host, portStr, _ := net.SplitHostPort(targetAddr)
-		port := 22
-		fmt.Sscanf(portStr, "%d", &port)
-
-		// Resolve hostname
-		ips, err := net.LookupIP(host)
-		if err != nil || len(ips) == 0 {
-			conn.Close()
-			return nil, fmt.Errorf("failed to resolve hostname: %w", err)
-		}
-		ip := ips[0].To4()
-		if ip == nil {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS4 does not support IPv6")
-		}
-
-		// Send SOCKS4 request
-		req := []byte{0x04, 0x01, byte(port >> 8), byte(port & 0xff)}
-		req = append(req, ip...)
-		req = append(req, []byte(user)...)
-		req = append(req, 0x00)
-		conn.Write(req)
-
-		// Read response
-		resp := make([]byte, 8)
-		if _, err := io.ReadFull(conn, resp); err != nil {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS4 handshake failed: %w", err)
-		}
-
-		if resp[1] != 0x5a {
-			conn.Close()
-			return nil, fmt.Errorf("SOCKS4 connect rejected: %d", resp[1])
-		}
-	}
-
-	return conn, nil
-}
-
-// dialHTTPProxy connects through HTTP CONNECT proxy
-func dialHTTPProxy(proxyAddr, targetAddr, user, pass string) (net.Conn, error) {
-	conn, err := net.DialTimeout("tcp", proxyAddr, 30*time.Second)
-	if err != nil {
-		return nil, err
-	}
-
-	// Send CONNECT request
-	connectReq := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", targetAddr, targetAddr)
-	if user != "" {
-		// Basic auth
-		auth := fmt.Sprintf("%s:%s", user, pass)
-		encoded := base64.StdEncoding.EncodeToString([]byte(auth))
-		connectReq += fmt.Sprintf("Proxy-Authorization: Basic %s\r\n", encoded)
-	}
-	connectReq += "\r\n"
-
-	if _, err := conn.Write([]byte(connectReq)); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to send CONNECT: %w", err)
-	}
-
-	// Read response
-	reader := bufio.NewReader(conn)
-	resp, err := http.ReadResponse(reader, nil)
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to read CONNECT response: %w", err)
-	}
-	resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		conn.Close()
-		return nil, fmt.Errorf("HTTP CONNECT failed: %s", resp.Status)
-	}
-
-	return conn, nil
-}
-
 // startLocalForward starts a local port forward (-L)
-func (s *TunnelService) startLocalForward(tunnel *Tunnel) error {
-	addr := fmt.Sprintf("%s:%d", tunnel.Config.LocalHost, tunnel.Config.LocalPort)
+func (s *TunnelService) startLocalForward(ctx context.Context, tunnel *Tunnel) error {
+	addr := net.JoinHostPort(tunnel.Config.LocalHost, strconv.Itoa(tunnel.Config.LocalPort))
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		s.setTunnelError(tunnel, fmt.Sprintf("failed to listen on %s: %v", addr, err))
-		return err
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
 
 	tunnel.mu.Lock()
 	tunnel.listener = listener
-	tunnel.Status = TunnelStatusRunning
 	now := time.Now()
 	tunnel.StartedAt = &now
-	tunnel.ErrorMessage = ""
 	tunnel.mu.Unlock()
-
-	// Emit status changed event
-	event.Emit(event.TunnelStatusChangedEvent{
-		TunnelID: tunnel.ID,
-		Status:   string(TunnelStatusRunning),
-	})
+	s.setTunnelStatus(tunnel, TunnelStatusRunning, "")
 
 	// Accept connections in goroutine
 	go func() {
 		for {
 			select {
-			case <-tunnel.ctx.Done():
+			case <-ctx.Done():
 				return
 			default:
 			}
@@ -733,38 +451,38 @@ func (s *TunnelService) startLocalForward(tunnel *Tunnel) error {
 			conn, err := listener.Accept()
 			if err != nil {
 				select {
-				case <-tunnel.ctx.Done():
+				case <-ctx.Done():
 					return
 				default:
-					continue
 				}
+				if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+					return
+				}
+				continue
 			}
 
 			atomic.AddInt32(&tunnel.Connections, 1)
 
 			go func(conn net.Conn) {
+				tunnel.mu.RLock()
+				remoteAddr := tunnel.remoteAddr
+				tunnel.mu.RUnlock()
+				c := tunnel.openConn(conn.RemoteAddr().String(), remoteAddr, conn)
+				var err error
 				defer func() {
 					conn.Close()
 					atomic.AddInt32(&tunnel.Connections, -1)
+					tunnel.closeConn(c, err)
 				}()
 
-				remoteAddr := fmt.Sprintf("%s:%d", tunnel.Config.RemoteHost, tunnel.Config.RemotePort)
-
-				tunnel.mu.RLock()
-				sshClient := tunnel.sshClient
-				tunnel.mu.RUnlock()
-
-				if sshClient == nil {
-					return
-				}
-
-				remoteConn, err := sshClient.Dial("tcp", remoteAddr)
+				remoteConn, err := tunnel.dialRemote(0)
 				if err != nil {
 					return
 				}
 				defer remoteConn.Close()
+				tunnel.track(c, "", remoteConn)
 
-				s.proxyConnections(tunnel, conn, remoteConn)
+				err = s.proxyConnections(tunnel, c, conn, remoteConn)
 			}(conn)
 		}
 	}()
@@ -772,8 +490,28 @@ func (s *TunnelService) startLocalForward(tunnel *Tunnel) error {
 	return nil
 }
 
+// dialRemote connects to the remote side of a local forward, through the
+// SSH connection unless the tunnel reaches it directly. A zero timeout
+// waits as long as the dial takes.
+func (t *Tunnel) dialRemote(timeout time.Duration) (net.Conn, error) {
+	t.mu.RLock()
+	addr, direct, sshClient := t.remoteAddr, t.direct, t.sshClient
+	t.mu.RUnlock()
+
+	if direct {
+		return net.DialTimeout("tcp", addr, timeout)
+	}
+	if sshClient == nil {
+		return nil, errors.New("SSH connection lost")
+	}
+	if timeout > 0 {
+		return dialSSHTimeout(sshClient, addr, timeout)
+	}
+	return sshClient.Dial("tcp", addr)
+}
+
 // startRemoteForward starts a remote port forward (-R)
-func (s *TunnelService) startRemoteForward(tunnel *Tunnel) error {
+func (s *TunnelService) startRemoteForward(ctx context.Context, tunnel *Tunnel) error {
 	tunnel.mu.RLock()
 	sshClient := tunnel.sshClient
 	tunnel.mu.RUnlock()
@@ -782,32 +520,24 @@ func (s *TunnelService) startRemoteForward(tunnel *Tunnel) error {
 		return fmt.Errorf("SSH client not connected")
 	}
 
-	remoteAddr := fmt.Sprintf("%s:%d", tunnel.Config.RemoteHost, tunnel.Config.RemotePort)
+	remoteAddr := net.JoinHostPort(tunnel.Config.RemoteHost, strconv.Itoa(tunnel.Config.RemotePort))
 	listener, err := sshClient.Listen("tcp", remoteAddr)
 	if err != nil {
-		s.setTunnelError(tunnel, fmt.Sprintf("failed to listen on remote %s: %v", remoteAddr, err))
-		return err
+		return fmt.Errorf("failed to listen on remote %s: %w", remoteAddr, err)
 	}
 
 	tunnel.mu.Lock()
 	tunnel.listener = listener
-	tunnel.Status = TunnelStatusRunning
 	now := time.Now()
 	tunnel.StartedAt = &now
-	tunnel.ErrorMessage = ""
 	tunnel.mu.Unlock()
-
-	// Emit status changed event
-	event.Emit(event.TunnelStatusChangedEvent{
-		TunnelID: tunnel.ID,
-		Status:   string(TunnelStatusRunning),
-	})
+	s.setTunnelStatus(tunnel, TunnelStatusRunning, "")
 
 	// Accept connections in goroutine
 	go func() {
 		for {
 			select {
-			case <-tunnel.ctx.Done():
+			case <-ctx.Done():
 				return
 			default:
 			}
@@ -815,29 +545,36 @@ func (s *TunnelService) startRemoteForward(tunnel *Tunnel) error {
 			remoteConn, err := listener.Accept()
 			if err != nil {
 				select {
-				case <-tunnel.ctx.Done():
+				case <-ctx.Done():
 					return
 				default:
-					continue
 				}
+				if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+					return
+				}
+				continue
 			}
 
 			atomic.AddInt32(&tunnel.Connections, 1)
 
 			go func(remoteConn net.Conn) {
+				localAddr := net.JoinHostPort(tunnel.Config.LocalHost, strconv.Itoa(tunnel.Config.LocalPort))
+				c := tunnel.openConn(remoteConn.RemoteAddr().String(), localAddr, remoteConn)
+				var err error
 				defer func() {
 					remoteConn.Close()
 					atomic.AddInt32(&tunnel.Connections, -1)
+					tunnel.closeConn(c, err)
 				}()
 
-				localAddr := fmt.Sprintf("%s:%d", tunnel.Config.LocalHost, tunnel.Config.LocalPort)
 				localConn, err := net.Dial("tcp", localAddr)
 				if err != nil {
 					return
 				}
 				defer localConn.Close()
+				tunnel.track(c, "", localConn)
 
-				s.proxyConnections(tunnel, localConn, remoteConn)
+				err = s.proxyConnections(tunnel, c, localConn, remoteConn)
 			}(remoteConn)
 		}
 	}()
@@ -845,34 +582,26 @@ func (s *TunnelService) startRemoteForward(tunnel *Tunnel) error {
 	return nil
 }
 
-// startDynamicForward starts a dynamic port forward / SOCKS proxy (-D)
-func (s *TunnelService) startDynamicForward(tunnel *Tunnel) error {
-	addr := fmt.Sprintf("%s:%d", tunnel.Config.LocalHost, tunnel.Config.LocalPort)
+// startDynamicForward starts a dynamic port forward / SOCKS or HTTP proxy (-D)
+func (s *TunnelService) startDynamicForward(ctx context.Context, tunnel *Tunnel) error {
+	addr := net.JoinHostPort(tunnel.Config.LocalHost, strconv.Itoa(tunnel.Config.LocalPort))
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		s.setTunnelError(tunnel, fmt.Sprintf("failed to listen on %s: %v", addr, err))
-		return err
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
 
 	tunnel.mu.Lock()
 	tunnel.listener = listener
-	tunnel.Status = TunnelStatusRunning
 	now := time.Now()
 	tunnel.StartedAt = &now
-	tunnel.ErrorMessage = ""
 	tunnel.mu.Unlock()
-
-	// Emit status changed event
-	event.Emit(event.TunnelStatusChangedEvent{
-		TunnelID: tunnel.ID,
-		Status:   string(TunnelStatusRunning),
-	})
+	s.setTunnelStatus(tunnel, TunnelStatusRunning, "")
 
 	// Accept connections in goroutine - simplified SOCKS5 implementation
 	go func() {
 		for {
 			select {
-			case <-tunnel.ctx.Done():
+			case <-ctx.Done():
 				return
 			default:
 			}
@@ -880,22 +609,28 @@ func (s *TunnelService) startDynamicForward(tunnel *Tunnel) error {
 			conn, err := listener.Accept()
 			if err != nil {
 				select {
-				case <-tunnel.ctx.Done():
+				case <-ctx.Done():
 					return
 				default:
-					continue
 				}
+				if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+					return
+				}
+				continue
 			}
 
 			atomic.AddInt32(&tunnel.Connections, 1)
 
 			go func(conn net.Conn) {
+				c := tunnel.openConn(conn.RemoteAddr().String(), "", conn)
+				var err error
 				defer func() {
 					conn.Close()
 					atomic.AddInt32(&tunnel.Connections, -1)
+					tunnel.closeConn(c, err)
 				}()
 
-				s.handleSOCKS5(tunnel, conn)
+				err = s.handleProxy(tunnel, c, conn)
 			}(conn)
 		}
 	}()
@@ -903,127 +638,52 @@ func (s *TunnelService) startDynamicForward(tunnel *Tunnel) error {
 	return nil
 }
 
-// handleSOCKS5 handles a SOCKS5 connection
-func (s *TunnelService) handleSOCKS5(tunnel *Tunnel, conn net.Conn) {
-	// Simplified SOCKS5 implementation
-	// Read version and methods
-	buf := make([]byte, 256)
-	n, err := conn.Read(buf)
-	if err != nil || n < 2 {
-		return
-	}
-
-	// Check SOCKS5 version
-	if buf[0] != 0x05 {
-		return
-	}
-
-	// Send no auth required
-	conn.Write([]byte{0x05, 0x00})
-
-	// Read connect request
-	n, err = conn.Read(buf)
-	if err != nil || n < 7 {
-		return
-	}
-
-	// Parse request
-	if buf[0] != 0x05 || buf[1] != 0x01 {
-		conn.Write([]byte{0x05, 0x07, 0x00, 0x01, 0, 0, 0, 0, 0, 0}) // Command not supported
-		return
-	}
-
-	var targetAddr string
-	var targetPort int
-
-	switch buf[3] {
-	case 0x01: // IPv4
-		if n < 10 {
-			return
-		}
-		targetAddr = fmt.Sprintf("%d.%d.%d.%d", buf[4], buf[5], buf[6], buf[7])
-		targetPort = int(buf[8])<<8 | int(buf[9])
-	case 0x03: // Domain
-		addrLen := int(buf[4])
-		if n < 5+addrLen+2 {
-			return
-		}
-		targetAddr = string(buf[5 : 5+addrLen])
-		targetPort = int(buf[5+addrLen])<<8 | int(buf[6+addrLen])
-	case 0x04: // IPv6
-		if n < 22 {
-			return
-		}
-		targetAddr = fmt.Sprintf("[%x:%x:%x:%x:%x:%x:%x:%x]",
-			uint16(buf[4])<<8|uint16(buf[5]),
-			uint16(buf[6])<<8|uint16(buf[7]),
-			uint16(buf[8])<<8|uint16(buf[9]),
-			uint16(buf[10])<<8|uint16(buf[11]),
-			uint16(buf[12])<<8|uint16(buf[13]),
-			uint16(buf[14])<<8|uint16(buf[15]),
-			uint16(buf[16])<<8|uint16(buf[17]),
-			uint16(buf[18])<<8|uint16(buf[19]))
-		targetPort = int(buf[20])<<8 | int(buf[21])
-	default:
-		conn.Write([]byte{0x05, 0x08, 0x00, 0x01, 0, 0, 0, 0, 0, 0}) // Address type not supported
-		return
-	}
-
-	// Connect through SSH
-	tunnel.mu.RLock()
-	sshClient := tunnel.sshClient
-	tunnel.mu.RUnlock()
-
-	if sshClient == nil {
-		conn.Write([]byte{0x05, 0x01, 0x00, 0x01, 0, 0, 0, 0, 0, 0}) // General failure
-		return
-	}
-
-	remoteConn, err := sshClient.Dial("tcp", fmt.Sprintf("%s:%d", targetAddr, targetPort))
-	if err != nil {
-		conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0}) // Connection refused
-		return
-	}
-	defer remoteConn.Close()
-
-	// Send success response
-	conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
-
-	s.proxyConnections(tunnel, conn, remoteConn)
-}
-
-// proxyConnections proxies data between two connections
-func (s *TunnelService) proxyConnections(tunnel *Tunnel, conn1, conn2 net.Conn) {
+// proxyConnections proxies data between two connections, counting it for
+// the tunnel and c, and returns the first error other than a close
+func (s *TunnelService) proxyConnections(tunnel *Tunnel, c *tunnelConn, conn1, conn2 net.Conn) error {
 	var wg sync.WaitGroup
 	wg.Add(2)
+	var sendErr, receiveErr error
 
 	// conn1 -> conn2
 	go func() {
 		defer wg.Done()
-		n, _ := io.Copy(conn2, conn1)
-		atomic.AddInt64(&tunnel.BytesSent, n)
+		_, sendErr = io.Copy(countingWriter{conn2, &tunnel.BytesSent, &c.sent}, conn1)
 	}()
 
 	// conn2 -> conn1
 	go func() {
 		defer wg.Done()
-		n, _ := io.Copy(conn1, conn2)
-		atomic.AddInt64(&tunnel.BytesReceived, n)
+		_, receiveErr = io.Copy(countingWriter{conn1, &tunnel.BytesReceived, &c.received}, conn2)
 	}()
 
 	wg.Wait()
+	if err := quietCopyError(sendErr); err != nil {
+		return err
+	}
+	return quietCopyError(receiveErr)
 }
 
 // setTunnelError sets the tunnel status to error with a message
 func (s *TunnelService) setTunnelError(tunnel *Tunnel, msg string) {
+	s.setTunnelStatus(tunnel, TunnelStatusError, msg)
+}
+
+// setTunnelStatus moves the tunnel to status, with why for an error or a
+// restart
+func (s *TunnelService) setTunnelStatus(tunnel *Tunnel, status TunnelStatus, msg string) {
 	tunnel.mu.Lock()
-	tunnel.Status = TunnelStatusError
+	tunnel.Status = status
 	tunnel.ErrorMessage = msg
+	if status != TunnelStatusRunning {
+		tunnel.StartedAt = nil
+	}
 	tunnel.mu.Unlock()
 
 	// Emit status changed event
 	event.Emit(event.TunnelStatusChangedEvent{
 		TunnelID: tunnel.ID,
-		Status:   string(TunnelStatusError),
+		Status:   string(status),
+		Message:  msg,
 	})
 }
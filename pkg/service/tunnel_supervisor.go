@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/utils"
+	"golang.org/x/crypto/ssh"
+)
+
+// Tunnel supervision defaults. A tunnel that ran for tunnelStableAfter
+// starts over with a fresh backoff and, under on-failure, fresh attempts.
+const (
+	tunnelKeepaliveInterval = 15 * time.Second
+	tunnelRestartBackoff    = time.Second
+	tunnelMaxRestartBackoff = time.Minute
+	tunnelMaxRestarts       = 5
+	tunnelStableAfter       = time.Minute
+	tunnelHealthInterval    = 30 * time.Second
+	tunnelHealthTimeout     = 5 * time.Second
+	tunnelHealthFailures    = 3
+)
+
+// restarts reports whether a tunnel with the policy is restarted on failure
+func restarts(policy string) bool {
+	return policy == models.TunnelRestartOnFailure || policy == models.TunnelRestartAlways
+}
+
+// AutoStartTunnels loads the tunnels and starts those flagged auto_start.
+// It is run once when the app starts.
+func (s *TunnelService) AutoStartTunnels() {
+	if err := s.LoadTunnelsFromAssets(); err != nil {
+		utils.GetLogger().Warn("Failed to load tunnels for auto start", "error", err)
+		return
+	}
+	s.mu.RLock()
+	var ids []string
+	for id, t := range s.tunnels {
+		t.mu.RLock()
+		if t.Config.AutoStart {
+			ids = append(ids, id)
+		}
+		t.mu.RUnlock()
+	}
+	s.mu.RUnlock()
+
+	for _, id := range ids {
+		if err := s.StartTunnel(id); err != nil {
+			utils.GetLogger().Warn("Failed to auto start tunnel", "id", id, "error", err)
+		}
+	}
+}
+
+// supervise keeps a started tunnel up as its restart policy asks until ctx,
+// the tunnel's supervisor context, is canceled by StopTunnel. err is the
+// outcome of the first start.
+func (s *TunnelService) supervise(ctx context.Context, tunnel *Tunnel, err error) {
+	tunnel.mu.RLock()
+	cfg := tunnel.Config
+	tunnel.mu.RUnlock()
+
+	backoff := tunnelRestartBackoff
+	attempts := 0
+	for {
+		if ctx.Err() != nil {
+			// Stopped while starting: StopTunnel ran before the listener
+			// opened
+			tunnel.mu.RLock()
+			restarted := tunnel.supervisor != nil
+			tunnel.mu.RUnlock()
+			if !restarted {
+				s.teardown(tunnel)
+				s.setTunnelStatus(tunnel, TunnelStatusStopped, "")
+			}
+			return
+		}
+		if err == nil {
+			started := time.Now()
+			err = s.watch(ctx, tunnel, s.tunnelKeepalive(tunnel.AssetID), cfg.HealthCheck)
+			if ctx.Err() != nil {
+				return // StopTunnel tore it down
+			}
+			s.teardown(tunnel)
+			if time.Since(started) >= tunnelStableAfter {
+				attempts, backoff = 0, tunnelRestartBackoff
+			}
+		}
+
+		if !restarts(cfg.RestartPolicy) || cfg.RestartPolicy == models.TunnelRestartOnFailure && attempts >= tunnelMaxRestarts {
+			if s.endSupervisor(tunnel, ctx) {
+				s.setTunnelError(tunnel, err.Error())
+			}
+			return
+		}
+		attempts++
+		tunnel.mu.Lock()
+		tunnel.Restarts = attempts
+		tunnel.mu.Unlock()
+		s.setTunnelStatus(tunnel, TunnelStatusReconnecting,
+			fmt.Sprintf("%v; restarting in %s (attempt %d)", err, backoff, attempts))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > tunnelMaxRestartBackoff {
+			backoff = tunnelMaxRestartBackoff
+		}
+		err = s.run(ctx, tunnel)
+		if err == nil {
+			tunnel.mu.Lock()
+			tunnel.Restarts = 0
+			tunnel.mu.Unlock()
+		}
+	}
+}
+
+// endSupervisor forgets the supervisor ctx belongs to, unless the tunnel
+// has since been stopped or started again. It reports whether ctx was the
+// tunnel's supervisor.
+func (s *TunnelService) endSupervisor(tunnel *Tunnel, ctx context.Context) bool {
+	tunnel.mu.Lock()
+	defer tunnel.mu.Unlock()
+	if tunnel.supervisor != ctx {
+		return false
+	}
+	tunnel.stopSupervisor()
+	tunnel.supervisor, tunnel.stopSupervisor = nil, nil
+	return true
+}
+
+// watch waits while the running tunnel is healthy and returns why it no
+// longer is: its SSH connection closed, stopped answering keepalives, or
+// the health check failed. A tunnel reached directly only has its health
+// check. It returns nil once ctx is canceled.
+func (s *TunnelService) watch(ctx context.Context, tunnel *Tunnel, keepalive time.Duration, health *models.TunnelHealthCheck) error {
+	tunnel.mu.RLock()
+	client, direct := tunnel.sshClient, tunnel.direct
+	tunnel.mu.RUnlock()
+	if client == nil && !direct {
+		return errors.New("SSH connection lost")
+	}
+
+	var closed chan struct{}
+	var keepaliveTick <-chan time.Time
+	if client != nil {
+		closed = make(chan struct{})
+		go func() {
+			_ = client.Wait()
+			close(closed)
+		}()
+
+		ticker := time.NewTicker(keepalive)
+		defer ticker.Stop()
+		keepaliveTick = ticker.C
+	}
+	var probe <-chan time.Time
+	timeout := tunnelHealthTimeout
+	if health != nil {
+		interval := tunnelHealthInterval
+		if health.Interval > 0 {
+			interval = time.Duration(health.Interval) * time.Second
+		}
+		if health.Timeout > 0 {
+			timeout = time.Duration(health.Timeout) * time.Second
+		}
+		probeTicker := time.NewTicker(interval)
+		defer probeTicker.Stop()
+		probe = probeTicker.C
+	}
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-closed:
+			return errors.New("SSH connection lost")
+		case <-keepaliveTick:
+			if !keepaliveAnswered(client, keepalive) {
+				// The pool hands out a client until it is closed, so close
+				// the dead one for the restart to dial a new connection
+				_ = client.Close()
+				return errors.New("SSH server stopped answering keepalives")
+			}
+		case <-probe:
+			err := s.probeTunnel(tunnel, timeout)
+			if err == nil {
+				failures = 0
+				continue
+			}
+			if failures++; failures >= tunnelHealthFailures {
+				return fmt.Errorf("health check failed: %w", err)
+			}
+		}
+	}
+}
+
+// keepaliveAnswered sends a keepalive and waits up to timeout for the reply
+func keepaliveAnswered(client *ssh.Client, timeout time.Duration) bool {
+	answered := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		answered <- err
+	}()
+	select {
+	case err := <-answered:
+		return err == nil
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// probeTunnel dials the port the tunnel forwards to: the remote target of a
+// local forward the way its connections go, the local target of a remote
+// forward, and the proxy's own listener for a dynamic forward
+func (s *TunnelService) probeTunnel(tunnel *Tunnel, timeout time.Duration) error {
+	tunnel.mu.RLock()
+	cfg := tunnel.Config
+	tunnel.mu.RUnlock()
+	var conn net.Conn
+	var err error
+	switch cfg.Type {
+	case "local":
+		conn, err = tunnel.dialRemote(timeout)
+	default:
+		conn, err = net.DialTimeout("tcp", net.JoinHostPort(cfg.LocalHost, strconv.Itoa(cfg.LocalPort)), timeout)
+	}
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// dialSSHTimeout dials addr through the SSH connection, giving up after
+// timeout
+func dialSSHTimeout(client *ssh.Client, addr string, timeout time.Duration) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := client.Dial("tcp", addr)
+		done <- result{conn, err}
+	}()
+	select {
+	case r := <-done:
+		return r.conn, r.err
+	case <-time.After(timeout):
+		go func() {
+			if r := <-done; r.conn != nil {
+				_ = r.conn.Close()
+			}
+		}()
+		return nil, fmt.Errorf("dial %s: timed out", addr)
+	}
+}
+
+// tunnelKeepalive is how often a tunnel's SSH connection is checked: the
+// asset's keepalive interval, or tunnelKeepaliveInterval
+func (s *TunnelService) tunnelKeepalive(assetID string) time.Duration {
+	asset, err := s.assetService.GetAsset(assetID)
+	if err != nil {
+		return tunnelKeepaliveInterval
+	}
+	cfg, err := parseSSHConfig(asset.Config)
+	if err != nil || cfg.KeepaliveInterval <= 0 {
+		return tunnelKeepaliveInterval
+	}
+	return time.Duration(cfg.KeepaliveInterval) * time.Second
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+)
+
+func TestTunnelRestartPolicy(t *testing.T) {
+	// Without an SSH pool every start fails, so the policy decides what
+	// happens next
+	s := &TunnelService{tunnels: map[string]*Tunnel{
+		"once":  {ID: "once", Config: models.SSHTunnel{Type: "local"}, Status: TunnelStatusStopped},
+		"retry": {ID: "retry", Config: models.SSHTunnel{Type: "local", RestartPolicy: models.TunnelRestartAlways}, Status: TunnelStatusStopped},
+	}}
+
+	if err := s.StartTunnel("once"); err == nil {
+		t.Fatal("started without an SSH pool")
+	}
+	if tunnels, _ := s.GetTunnels(); tunnelStatus(tunnels, "once") != TunnelStatusError {
+		t.Fatalf("once: status %s, want error", tunnelStatus(tunnels, "once"))
+	}
+
+	if err := s.StartTunnel("retry"); err == nil {
+		t.Fatal("started without an SSH pool")
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		tunnels, stats := s.GetTunnels()
+		if tunnelStatus(tunnels, "retry") == TunnelStatusReconnecting && stats.Reconnecting == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("retry: status %s, want reconnecting", tunnelStatus(tunnels, "retry"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Starting a tunnel waiting to restart is a no-op
+	if err := s.StartTunnel("retry"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.StopTunnel("retry"); err != nil {
+		t.Fatal(err)
+	}
+	if tunnels, _ := s.GetTunnels(); tunnelStatus(tunnels, "retry") != TunnelStatusStopped {
+		t.Fatalf("retry: status %s after stop", tunnelStatus(tunnels, "retry"))
+	}
+}
+
+func TestProbeTunnel(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_ = c.Close()
+		}
+	}()
+
+	s := &TunnelService{}
+	tunnel := &Tunnel{Config: models.SSHTunnel{Type: "remote", LocalHost: "127.0.0.1", LocalPort: port}}
+	if err := s.probeTunnel(tunnel, time.Second); err != nil {
+		t.Fatalf("probe of a listening port: %v", err)
+	}
+	_ = l.Close()
+	if err := s.probeTunnel(tunnel, time.Second); err == nil {
+		t.Fatal("probe of a closed port succeeded")
+	}
+}
+
+func tunnelStatus(tunnels []TunnelInfo, id string) TunnelStatus {
+	for _, info := range tunnels {
+		if info.ID == id {
+			return info.Status
+		}
+	}
+	return ""
+}
@@ -0,0 +1,717 @@
+// Package vt models a VT100/xterm screen from a terminal's output stream, so
+// the backend can read what a terminal shows without asking the browser.
+//
+// It tracks text and layout only: cursor movement, erasing, scroll regions,
+// line wrapping, wide characters and the alternate screen used by full-screen
+// applications. Colors and other attributes are parsed and dropped.
+package vt
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	DefaultRows = 24
+	DefaultCols = 80
+)
+
+// wideTail fills the cell after a double-width character
+const wideTail rune = 0
+
+const (
+	stateGround = iota
+	stateEscape
+	stateEscapeSkip // escape sequence with one more byte to ignore, e.g. ESC ( B
+	stateCSI
+	stateString // OSC, DCS, SOS, PM or APC, up to BEL or ST
+	stateStringEscape
+)
+
+type cursor struct {
+	x, y int
+}
+
+// Screen is the emulated terminal. Its methods are safe for concurrent use.
+type Screen struct {
+	mu sync.Mutex
+
+	rows, cols int
+	main, alt  [][]rune
+	altActive  bool
+
+	cur       cursor
+	mainSaved cursor
+	altSaved  cursor
+	wrapNext  bool // the last column was written; the next character wraps
+	autowrap  bool
+	origin    bool
+	top       int // scroll region, inclusive
+	bottom    int
+	last      rune // for REP
+
+	history    []string
+	maxHistory int
+
+	state   int
+	params  []byte
+	inter   []byte
+	partial []byte // incomplete UTF-8 sequence from the previous write
+}
+
+// New creates a blank screen that keeps up to history lines scrolled off its
+// top
+func New(rows, cols, history int) *Screen {
+	s := &Screen{maxHistory: history}
+	s.reset(max(rows, 1), max(cols, 1))
+	return s
+}
+
+func (s *Screen) reset(rows, cols int) {
+	s.rows, s.cols = rows, cols
+	s.main = newGrid(rows, cols)
+	s.alt = newGrid(rows, cols)
+	s.altActive = false
+	s.cur, s.mainSaved, s.altSaved = cursor{}, cursor{}, cursor{}
+	s.wrapNext = false
+	s.autowrap = true
+	s.origin = false
+	s.top, s.bottom = 0, rows-1
+	s.state = stateGround
+}
+
+func newGrid(rows, cols int) [][]rune {
+	g := make([][]rune, rows)
+	for i := range g {
+		g[i] = blankRow(cols)
+	}
+	return g
+}
+
+func blankRow(cols int) []rune {
+	row := make([]rune, cols)
+	clearCells(row)
+	return row
+}
+
+func clearCells(cells []rune) {
+	for i := range cells {
+		cells[i] = ' '
+	}
+}
+
+func (s *Screen) grid() [][]rune {
+	if s.altActive {
+		return s.alt
+	}
+	return s.main
+}
+
+// Write feeds terminal output to the screen
+func (s *Screen) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := p
+	if len(s.partial) > 0 {
+		data = append(s.partial, p...)
+		s.partial = nil
+	}
+	for i := 0; i < len(data); {
+		b := data[i]
+		if s.state != stateGround {
+			s.advance(b)
+			i++
+			continue
+		}
+		if b < 0x20 || b == 0x7f {
+			s.control(b)
+			i++
+			continue
+		}
+		if b < utf8.RuneSelf {
+			s.put(rune(b))
+			i++
+			continue
+		}
+		if !utf8.FullRune(data[i:]) {
+			s.partial = append([]byte(nil), data[i:]...)
+			break
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		s.put(r)
+		i += size
+	}
+	return len(p), nil
+}
+
+// advance runs the escape sequence parser for one byte
+func (s *Screen) advance(b byte) {
+	switch s.state {
+	case stateEscape:
+		s.escape(b)
+	case stateEscapeSkip:
+		s.state = stateGround
+	case stateCSI:
+		switch {
+		case b == 0x1b:
+			s.state = stateEscape
+		case b == 0x18 || b == 0x1a:
+			s.state = stateGround
+		case b < 0x20:
+			s.control(b)
+		case b < 0x30:
+			s.inter = append(s.inter, b)
+		case b < 0x40:
+			s.params = append(s.params, b)
+		case b < 0x7f:
+			s.state = stateGround
+			s.csi(b)
+		}
+	case stateString:
+		switch b {
+		case 0x07, 0x18, 0x1a:
+			s.state = stateGround
+		case 0x1b:
+			s.state = stateStringEscape
+		}
+	case stateStringEscape:
+		if b == '\\' {
+			s.state = stateGround
+		} else {
+			s.escape(b)
+		}
+	}
+}
+
+func (s *Screen) control(b byte) {
+	switch b {
+	case '\b':
+		if s.cur.x > 0 {
+			s.cur.x--
+		}
+		s.wrapNext = false
+	case '\t':
+		s.cur.x = min((s.cur.x/8+1)*8, s.cols-1)
+		s.wrapNext = false
+	case '\n', '\v', '\f':
+		s.index()
+		s.wrapNext = false
+	case '\r':
+		s.cur.x = 0
+		s.wrapNext = false
+	case 0x1b:
+		s.state = stateEscape
+	case 0x18, 0x1a:
+		s.state = stateGround
+	}
+}
+
+func (s *Screen) escape(b byte) {
+	s.state = stateGround
+	switch b {
+	case '[':
+		s.state = stateCSI
+		s.params = s.params[:0]
+		s.inter = s.inter[:0]
+	case ']', 'P', 'X', '^', '_':
+		s.state = stateString
+	case '(', ')', '*', '+', '-', '.', '/', '#', '%', ' ':
+		s.state = stateEscapeSkip
+	case '7':
+		s.saveCursor()
+	case '8':
+		s.restoreCursor()
+	case 'D':
+		s.index()
+		s.wrapNext = false
+	case 'E':
+		s.cur.x = 0
+		s.index()
+		s.wrapNext = false
+	case 'M':
+		s.reverseIndex()
+		s.wrapNext = false
+	case 'c':
+		history := s.history
+		s.reset(s.rows, s.cols)
+		s.history = history
+	}
+}
+
+// csi dispatches a control sequence by its final byte
+func (s *Screen) csi(final byte) {
+	if len(s.inter) > 0 {
+		return
+	}
+	private := byte(0)
+	params := s.params
+	if len(params) > 0 && params[0] >= '<' {
+		private, params = params[0], params[1:]
+	}
+	args := parseParams(params)
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+
+	if private == '?' {
+		if final == 'h' || final == 'l' {
+			for _, mode := range args {
+				s.setMode(mode, final == 'h')
+			}
+		}
+		return
+	}
+	if private != 0 {
+		return
+	}
+
+	switch final {
+	case 'A':
+		s.moveTo(s.cur.x, max(s.cur.y-arg(0, 1), s.upperBound()))
+	case 'B', 'e':
+		s.moveTo(s.cur.x, min(s.cur.y+arg(0, 1), s.lowerBound()))
+	case 'C', 'a':
+		s.moveTo(s.cur.x+arg(0, 1), s.cur.y)
+	case 'D':
+		s.moveTo(s.cur.x-arg(0, 1), s.cur.y)
+	case 'E':
+		s.moveTo(0, min(s.cur.y+arg(0, 1), s.lowerBound()))
+	case 'F':
+		s.moveTo(0, max(s.cur.y-arg(0, 1), s.upperBound()))
+	case 'G', '`':
+		s.moveTo(arg(0, 1)-1, s.cur.y)
+	case 'H', 'f':
+		s.moveTo(arg(1, 1)-1, s.originRow(arg(0, 1)-1))
+	case 'd':
+		s.moveTo(s.cur.x, s.originRow(arg(0, 1)-1))
+	case 'J':
+		s.eraseDisplay(arg(0, 0))
+	case 'K':
+		s.eraseLine(arg(0, 0))
+	case 'L':
+		if s.cur.y >= s.top && s.cur.y <= s.bottom {
+			s.scrollDown(s.cur.y, s.bottom, arg(0, 1))
+			s.cur.x = 0
+		}
+	case 'M':
+		if s.cur.y >= s.top && s.cur.y <= s.bottom {
+			s.scrollUp(s.cur.y, s.bottom, arg(0, 1))
+			s.cur.x = 0
+		}
+	case '@':
+		row := s.grid()[s.cur.y]
+		n := min(arg(0, 1), s.cols-s.cur.x)
+		copy(row[s.cur.x+n:], row[s.cur.x:])
+		clearCells(row[s.cur.x : s.cur.x+n])
+	case 'P':
+		row := s.grid()[s.cur.y]
+		n := min(arg(0, 1), s.cols-s.cur.x)
+		copy(row[s.cur.x:], row[s.cur.x+n:])
+		clearCells(row[s.cols-n:])
+	case 'X':
+		row := s.grid()[s.cur.y]
+		clearCells(row[s.cur.x:min(s.cur.x+arg(0, 1), s.cols)])
+	case 'S':
+		s.scrollUp(s.top, s.bottom, arg(0, 1))
+	case 'T':
+		s.scrollDown(s.top, s.bottom, arg(0, 1))
+	case 'r':
+		top, bottom := arg(0, 1)-1, arg(1, s.rows)-1
+		if top < bottom && bottom < s.rows {
+			s.top, s.bottom = top, bottom
+			s.moveTo(0, s.originRow(0))
+		}
+	case 's':
+		s.saveCursor()
+	case 'u':
+		s.restoreCursor()
+	case 'b':
+		if s.last != 0 {
+			for n := min(arg(0, 1), s.rows*s.cols); n > 0; n-- {
+				s.put(s.last)
+			}
+		}
+	}
+}
+
+func parseParams(b []byte) []int {
+	if len(b) == 0 {
+		return nil
+	}
+	parts := strings.Split(string(b), ";")
+	args := make([]int, len(parts))
+	for i, part := range parts {
+		// Sub-parameters, as in SGR 38:2:r:g:b, only matter for colors
+		part, _, _ = strings.Cut(part, ":")
+		args[i], _ = strconv.Atoi(part)
+	}
+	return args
+}
+
+func (s *Screen) setMode(mode int, on bool) {
+	switch mode {
+	case 6:
+		s.origin = on
+		s.moveTo(0, s.originRow(0))
+	case 7:
+		s.autowrap = on
+	case 47, 1047:
+		s.switchScreen(on, false)
+	case 1049:
+		s.switchScreen(on, true)
+	}
+}
+
+// switchScreen enters or leaves the alternate screen. xterm's mode 1049 also
+// saves the cursor and starts from a blank alternate screen.
+func (s *Screen) switchScreen(alt, saveCursor bool) {
+	if alt == s.altActive {
+		return
+	}
+	if alt && saveCursor {
+		s.saveCursor()
+	}
+	s.altActive = alt
+	if alt && saveCursor {
+		s.alt = newGrid(s.rows, s.cols)
+	}
+	if !alt && saveCursor {
+		s.restoreCursor()
+	}
+	s.wrapNext = false
+}
+
+func (s *Screen) saveCursor() {
+	if s.altActive {
+		s.altSaved = s.cur
+	} else {
+		s.mainSaved = s.cur
+	}
+}
+
+func (s *Screen) restoreCursor() {
+	if s.altActive {
+		s.cur = s.altSaved
+	} else {
+		s.cur = s.mainSaved
+	}
+	s.moveTo(s.cur.x, s.cur.y)
+}
+
+func (s *Screen) moveTo(x, y int) {
+	s.cur.x = min(max(x, 0), s.cols-1)
+	s.cur.y = min(max(y, 0), s.rows-1)
+	s.wrapNext = false
+}
+
+// originRow maps a row to the screen, relative to the scroll region in
+// origin mode
+func (s *Screen) originRow(y int) int {
+	if s.origin {
+		return min(y+s.top, s.bottom)
+	}
+	return y
+}
+
+// upperBound is the highest row cursor-up reaches: the top margin from
+// inside the scroll region
+func (s *Screen) upperBound() int {
+	if s.cur.y >= s.top {
+		return s.top
+	}
+	return 0
+}
+
+func (s *Screen) lowerBound() int {
+	if s.cur.y <= s.bottom {
+		return s.bottom
+	}
+	return s.rows - 1
+}
+
+// put prints a character at the cursor
+func (s *Screen) put(r rune) {
+	width := runeWidth(r)
+	if width == 0 {
+		return
+	}
+	if width == 2 && s.cols < 2 {
+		width = 1
+	}
+	if s.wrapNext {
+		if s.autowrap {
+			s.cur.x = 0
+			s.index()
+		}
+		s.wrapNext = false
+	}
+	if width == 2 && s.cur.x == s.cols-1 {
+		if !s.autowrap {
+			return
+		}
+		s.cur.x = 0
+		s.index()
+	}
+	row := s.grid()[s.cur.y]
+	row[s.cur.x] = r
+	if width == 2 {
+		row[s.cur.x+1] = wideTail
+	}
+	s.last = r
+	if s.cur.x += width; s.cur.x >= s.cols {
+		s.cur.x = s.cols - 1
+		s.wrapNext = true
+	}
+}
+
+// index moves the cursor down, scrolling at the bottom margin
+func (s *Screen) index() {
+	switch {
+	case s.cur.y == s.bottom:
+		s.scrollUp(s.top, s.bottom, 1)
+	case s.cur.y < s.rows-1:
+		s.cur.y++
+	}
+}
+
+// reverseIndex moves the cursor up, scrolling at the top margin
+func (s *Screen) reverseIndex() {
+	switch {
+	case s.cur.y == s.top:
+		s.scrollDown(s.top, s.bottom, 1)
+	case s.cur.y > 0:
+		s.cur.y--
+	}
+}
+
+// scrollUp moves rows top..bottom up by n. Rows leaving the top of the main
+// screen go to the history.
+func (s *Screen) scrollUp(top, bottom, n int) {
+	n = min(n, bottom-top+1)
+	g := s.grid()
+	if top == 0 && !s.altActive {
+		for _, row := range g[:n] {
+			s.pushHistory(rowText(row))
+		}
+	}
+	out := append([][]rune(nil), g[top:top+n]...)
+	copy(g[top:], g[top+n:bottom+1])
+	for i, row := range out {
+		clearCells(row)
+		g[bottom-n+1+i] = row
+	}
+}
+
+// scrollDown moves rows top..bottom down by n, blanking the rows opened at
+// the top
+func (s *Screen) scrollDown(top, bottom, n int) {
+	n = min(n, bottom-top+1)
+	g := s.grid()
+	out := append([][]rune(nil), g[bottom-n+1:bottom+1]...)
+	copy(g[top+n:bottom+1], g[top:bottom+1-n])
+	for i, row := range out {
+		clearCells(row)
+		g[top+i] = row
+	}
+}
+
+func (s *Screen) pushHistory(line string) {
+	if s.maxHistory <= 0 {
+		return
+	}
+	s.history = append(s.history, line)
+	// Trim in batches so scrolling is not quadratic
+	if len(s.history) >= 2*s.maxHistory {
+		s.history = append(s.history[:0], s.history[len(s.history)-s.maxHistory:]...)
+	}
+}
+
+func (s *Screen) historyTail() []string {
+	return s.history[max(len(s.history)-s.maxHistory, 0):]
+}
+
+func (s *Screen) eraseDisplay(mode int) {
+	g := s.grid()
+	switch mode {
+	case 0:
+		clearCells(g[s.cur.y][s.cur.x:])
+		for _, row := range g[s.cur.y+1:] {
+			clearCells(row)
+		}
+	case 1:
+		for _, row := range g[:s.cur.y] {
+			clearCells(row)
+		}
+		clearCells(g[s.cur.y][:s.cur.x+1])
+	case 2:
+		for _, row := range g {
+			clearCells(row)
+		}
+	case 3:
+		s.history = nil
+	}
+}
+
+func (s *Screen) eraseLine(mode int) {
+	row := s.grid()[s.cur.y]
+	switch mode {
+	case 0:
+		clearCells(row[s.cur.x:])
+	case 1:
+		clearCells(row[:s.cur.x+1])
+	case 2:
+		clearCells(row)
+	}
+}
+
+// Resize changes the screen size. Rows that no longer fit above the cursor
+// scroll into the history, as in xterm.
+func (s *Screen) Resize(rows, cols int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, cols = max(rows, 1), max(cols, 1)
+	if rows == s.rows && cols == s.cols {
+		return
+	}
+	shift := max(s.cur.y-rows+1, 0)
+	if !s.altActive {
+		for _, row := range s.main[:shift] {
+			s.pushHistory(rowText(row))
+		}
+	}
+	s.main = resizeGrid(s.main, rows, cols, shiftIf(!s.altActive, shift))
+	s.alt = resizeGrid(s.alt, rows, cols, shiftIf(s.altActive, shift))
+	s.rows, s.cols = rows, cols
+	s.top, s.bottom = 0, rows-1
+	s.cur.y -= shift
+	s.moveTo(s.cur.x, s.cur.y)
+	s.mainSaved.x, s.mainSaved.y = min(s.mainSaved.x, cols-1), min(s.mainSaved.y, rows-1)
+	s.altSaved.x, s.altSaved.y = min(s.altSaved.x, cols-1), min(s.altSaved.y, rows-1)
+}
+
+func shiftIf(cond bool, n int) int {
+	if cond {
+		return n
+	}
+	return 0
+}
+
+func resizeGrid(g [][]rune, rows, cols, shift int) [][]rune {
+	out := newGrid(rows, cols)
+	for i := range out {
+		if i+shift < len(g) {
+			copy(out[i], g[i+shift])
+		}
+	}
+	return out
+}
+
+// Lines returns the last n lines of rendered text, n <= 0 for all of them.
+// On the main screen that is the history followed by the screen down to its
+// last non-blank row; a full-screen application on the alternate screen
+// shows only the screen.
+func (s *Screen) Lines(n int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lines []string
+	if !s.altActive {
+		lines = append(lines, s.historyTail()...)
+	}
+	lines = append(lines, s.screenLines(true)...)
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// Screen returns the visible rows as text
+func (s *Screen) Screen() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.screenLines(false)
+}
+
+// screenLines renders the visible rows, optionally without the blank rows
+// at the bottom
+func (s *Screen) screenLines(trim bool) []string {
+	g := s.grid()
+	lines := make([]string, len(g))
+	end := 0
+	for i, row := range g {
+		if lines[i] = rowText(row); lines[i] != "" {
+			end = i + 1
+		}
+	}
+	if !trim {
+		end = len(g)
+	}
+	return lines[:end]
+}
+
+// Cursor returns the zero-based cursor position
+func (s *Screen) Cursor() (row, col int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cur.y, s.cur.x
+}
+
+// AltScreen reports whether a full-screen application has switched to the
+// alternate screen
+func (s *Screen) AltScreen() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.altActive
+}
+
+// Size returns the screen size
+func (s *Screen) Size() (rows, cols int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rows, s.cols
+}
+
+func rowText(row []rune) string {
+	var b strings.Builder
+	for _, r := range row {
+		if r != wideTail {
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// runeWidth is the number of cells a character takes: 2 for East Asian wide
+// characters and emoji, 0 for combining marks and format characters
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x300:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0x303e,
+		r >= 0x3041 && r <= 0x33ff,
+		r >= 0x3400 && r <= 0x4dbf,
+		r >= 0x4e00 && r <= 0x9fff,
+		r >= 0xa000 && r <= 0xa4cf,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
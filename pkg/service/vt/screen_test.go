@@ -0,0 +1,83 @@
+package vt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShellOutput(t *testing.T) {
+	s := New(3, 10, 100)
+	_, _ = s.Write([]byte("\x1b]0;title\x07\x1b[1;32m$\x1b[0m ls\r\none\r\ntwo\r\nthree\r\n$ "))
+
+	// Lines that scrolled off the top are kept
+	if got, want := s.Lines(0), []string{"$ ls", "one", "two", "three", "$"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Lines(0) = %q, want %q", got, want)
+	}
+	if got, want := s.Lines(2), []string{"three", "$"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Lines(2) = %q, want %q", got, want)
+	}
+	if row, col := s.Cursor(); row != 2 || col != 2 {
+		t.Fatalf("Cursor() = %d, %d", row, col)
+	}
+
+	// Carriage return, erase and cursor movement redraw a line in place
+	_, _ = s.Write([]byte("\r\x1b[Kload 10%\r\x1b[5C99%"))
+	if got := s.Screen()[2]; got != "load 99%" {
+		t.Fatalf("progress line = %q", got)
+	}
+}
+
+func TestWrapAndWideCharacters(t *testing.T) {
+	s := New(4, 4, 0)
+	// A split UTF-8 sequence is joined across writes
+	_, _ = s.Write([]byte("abcde\r\n\xe4\xb8"))
+	_, _ = s.Write([]byte("\xad\xe6\x96\x87x"))
+	if got, want := s.Screen(), []string{"abcd", "e", "中文", "x"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Screen() = %q, want %q", got, want)
+	}
+}
+
+func TestAlternateScreen(t *testing.T) {
+	s := New(4, 20, 100)
+	_, _ = s.Write([]byte("$ top\r\n"))
+	_, _ = s.Write([]byte("\x1b[?1049h\x1b[H\x1b[2Jtop - 10:00\x1b[3;1HPID USER\x1b[4;1H  1 root"))
+	if !s.AltScreen() {
+		t.Fatal("AltScreen() = false after 1049h")
+	}
+	if got, want := s.Lines(0), []string{"top - 10:00", "", "PID USER", "  1 root"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Lines(0) on the alternate screen = %q, want %q", got, want)
+	}
+
+	_, _ = s.Write([]byte("\x1b[?1049l"))
+	if got, want := s.Lines(0), []string{"$ top"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Lines(0) after leaving = %q, want %q", got, want)
+	}
+	if row, col := s.Cursor(); row != 1 || col != 0 {
+		t.Fatalf("Cursor() after leaving = %d, %d", row, col)
+	}
+}
+
+func TestScrollRegion(t *testing.T) {
+	s := New(4, 10, 100)
+	_, _ = s.Write([]byte("head\r\n1\r\n2\r\nfoot"))
+	// Scrolling inside rows 2-3 keeps the header and footer in place
+	_, _ = s.Write([]byte("\x1b[2;3r\x1b[3;1H\n3"))
+	if got, want := s.Screen(), []string{"head", "2", "3", "foot"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Screen() = %q, want %q", got, want)
+	}
+	if got := s.Lines(0); len(got) != 4 {
+		t.Fatalf("a scroll region below the top must not fill the history: %q", got)
+	}
+}
+
+func TestResize(t *testing.T) {
+	s := New(4, 10, 100)
+	_, _ = s.Write([]byte("a\r\nb\r\nc\r\nd"))
+	s.Resize(2, 5)
+	if got, want := s.Screen(), []string{"c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Screen() = %q, want %q", got, want)
+	}
+	if got, want := s.Lines(0), []string{"a", "b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Lines(0) = %q, want %q", got, want)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,6 +17,7 @@ import (
 	"github.com/choraleia/choraleia/pkg/models"
 	"github.com/choraleia/choraleia/pkg/service/fs"
 	"github.com/choraleia/choraleia/pkg/utils"
+	"golang.org/x/crypto/ssh"
 )
 
 // RuntimeManager manages workspace runtime environments
@@ -608,6 +610,251 @@ func (m *RuntimeManager) Exec(ctx context.Context, workspace *models.Workspace,
 	}
 }
 
+// RuntimeProcess is a long-running process with piped stdio, started either on the
+// host or inside a workspace runtime
+type RuntimeProcess struct {
+	Stdin  io.WriteCloser
+	Stdout io.Reader
+	stderr *tailBuffer
+	wait   func() error
+	kill   func() error
+	done   chan struct{}
+	err    error
+}
+
+// Wait blocks until the process exits and returns its exit error
+func (p *RuntimeProcess) Wait() error {
+	<-p.done
+	return p.err
+}
+
+// Done is closed when the process exits
+func (p *RuntimeProcess) Done() <-chan struct{} {
+	return p.done
+}
+
+// Kill terminates the process
+func (p *RuntimeProcess) Kill() error {
+	return p.kill()
+}
+
+// Stderr returns the last few KB written to stderr (useful for error messages)
+func (p *RuntimeProcess) Stderr() string {
+	return p.stderr.String()
+}
+
+func (p *RuntimeProcess) start() {
+	p.done = make(chan struct{})
+	go func() {
+		p.err = p.wait()
+		close(p.done)
+	}()
+}
+
+// StartLocalProcess starts a process on the host machine with piped stdin/stdout
+func StartLocalProcess(cmd []string, env map[string]string, cwd string) (*RuntimeProcess, error) {
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	c := exec.Command(cmd[0], cmd[1:]...)
+	c.Dir = expandPath(cwd)
+	c.Env = os.Environ()
+	for k, v := range env {
+		c.Env = append(c.Env, k+"="+v)
+	}
+	return startExecProcess(c)
+}
+
+// processWaitDelay bounds how long Wait waits for output after the process exits
+const processWaitDelay = 2 * time.Second
+
+// startExecProcess wires pipes for an exec.Cmd and starts it.
+// Stdout goes through an io.Pipe so Wait only returns once everything
+// the process wrote has been consumed.
+func startExecProcess(c *exec.Cmd) (*RuntimeProcess, error) {
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdoutR, stdoutW := io.Pipe()
+	c.Stdout = stdoutW
+	stderr := newTailBuffer(4096)
+	c.Stderr = stderr
+	// Don't hang in Wait when a killed process leaves children holding the pipes
+	c.WaitDelay = processWaitDelay
+
+	if err := c.Start(); err != nil {
+		stdoutW.Close()
+		return nil, fmt.Errorf("start %s: %w", c.Path, err)
+	}
+
+	p := &RuntimeProcess{
+		Stdin:  stdin,
+		Stdout: stdoutR,
+		stderr: stderr,
+		wait: func() error {
+			err := c.Wait()
+			stdoutW.Close()
+			return err
+		},
+		kill: func() error {
+			// Unblock the stdout copy in case nobody is reading
+			stdoutR.CloseWithError(io.ErrClosedPipe)
+			if c.Process == nil {
+				return nil
+			}
+			return c.Process.Kill()
+		},
+	}
+	p.start()
+	return p, nil
+}
+
+// StartProcess starts a long-running process inside the workspace runtime.
+// Local runtimes run it on the host (in the workspace work dir by default);
+// docker runtimes use `docker exec -i`, over SSH for remote docker hosts.
+func (m *RuntimeManager) StartProcess(ctx context.Context, workspace *models.Workspace, cmd []string, env map[string]string, cwd string) (*RuntimeProcess, error) {
+	if workspace.Runtime == nil {
+		return nil, fmt.Errorf("workspace has no runtime configuration")
+	}
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	if workspace.Runtime.Type == models.RuntimeTypeLocal {
+		if cwd == "" {
+			cwd = workspace.Runtime.WorkDirPath
+		}
+		return StartLocalProcess(cmd, env, cwd)
+	}
+
+	if workspace.Runtime.Type != models.RuntimeTypeDockerLocal && workspace.Runtime.Type != models.RuntimeTypeDockerRemote {
+		return nil, fmt.Errorf("unsupported runtime type: %s", workspace.Runtime.Type)
+	}
+
+	m.mu.RLock()
+	info, exists := m.containers[workspace.ID]
+	m.mu.RUnlock()
+
+	containerID := ""
+	if exists {
+		containerID = info.ContainerID
+	} else {
+		containerID = m.getContainerIDFromRuntime(workspace.Runtime)
+	}
+	if containerID == "" {
+		return nil, fmt.Errorf("container not configured")
+	}
+
+	if cwd == "" && workspace.Runtime.WorkDirContainerPath != nil {
+		cwd = *workspace.Runtime.WorkDirContainerPath
+	}
+
+	args := []string{"exec", "-i"}
+	if cwd != "" {
+		args = append(args, "-w", cwd)
+	}
+	for k, v := range env {
+		args = append(args, "-e", k+"="+v)
+	}
+	args = append(args, containerID)
+	args = append(args, cmd...)
+
+	if workspace.Runtime.Type == models.RuntimeTypeDockerRemote && workspace.Runtime.DockerAssetID != nil {
+		dockerAsset, err := m.assetService.GetAsset(*workspace.Runtime.DockerAssetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get docker host asset: %w", err)
+		}
+		var cfg models.DockerHostConfig
+		if err := dockerAsset.GetTypedConfig(&cfg); err != nil {
+			return nil, fmt.Errorf("invalid docker host config: %w", err)
+		}
+		if cfg.ConnectionType == "ssh" && cfg.SSHAssetID != "" {
+			return m.startSSHProcess(cfg.SSHAssetID, "docker "+shellQuoteArgs(args))
+		}
+	}
+
+	return startExecProcess(exec.Command("docker", args...))
+}
+
+// startSSHProcess runs a command on a remote host over a pooled SSH connection
+func (m *RuntimeManager) startSSHProcess(sshAssetID string, cmdStr string) (*RuntimeProcess, error) {
+	if m.sshPool == nil {
+		return nil, fmt.Errorf("ssh pool not available")
+	}
+	client, err := m.sshPool.GetSSHClient(sshAssetID)
+	if err != nil {
+		return nil, fmt.Errorf("SSH connection failed: %w", err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("SSH session failed: %w", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stdoutR, stdoutW := io.Pipe()
+	session.Stdout = stdoutW
+	stderr := newTailBuffer(4096)
+	session.Stderr = stderr
+
+	if err := session.Start(cmdStr); err != nil {
+		session.Close()
+		stdoutW.Close()
+		return nil, fmt.Errorf("start remote command: %w", err)
+	}
+
+	p := &RuntimeProcess{
+		Stdin:  stdin,
+		Stdout: stdoutR,
+		stderr: stderr,
+		wait: func() error {
+			defer session.Close()
+			err := session.Wait()
+			stdoutW.Close()
+			return err
+		},
+		kill: func() error {
+			stdoutR.CloseWithError(io.ErrClosedPipe)
+			_ = session.Signal(ssh.SIGKILL)
+			return session.Close()
+		},
+	}
+	p.start()
+	return p, nil
+}
+
+// tailBuffer keeps only the last max bytes written to it
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+	max int
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = b.buf[len(b.buf)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimSpace(string(b.buf))
+}
+
 // getContainerIDFromRuntime extracts container ID or name from runtime config
 func (m *RuntimeManager) getContainerIDFromRuntime(runtime *models.WorkspaceRuntime) string {
 	if runtime.ContainerName != nil && *runtime.ContainerName != "" {
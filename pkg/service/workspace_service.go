@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/secrets"
 	"github.com/choraleia/choraleia/pkg/service/fs"
 	"github.com/choraleia/choraleia/pkg/service/repomap"
 	"github.com/google/uuid"
@@ -26,6 +27,7 @@ var (
 	ErrWorkspaceNotRunning  = errors.New("workspace is not running")
 	ErrRoomNotFound         = errors.New("room not found")
 	ErrCannotDeleteLastRoom = errors.New("cannot delete the last room")
+	ErrToolApprovalInvalid  = errors.New("tool approval mode must be always, dangerous, never or allowlist")
 )
 
 // workspaceNameRegex validates DNS-compatible names
@@ -42,10 +44,13 @@ type WorkspaceService struct {
 
 // NewWorkspaceService creates a new WorkspaceService
 func NewWorkspaceService(db *gorm.DB) *WorkspaceService {
+	runtimeManager := NewRuntimeManager()
+	toolManager := NewToolManager()
+	toolManager.SetRuntimeManager(runtimeManager)
 	return &WorkspaceService{
 		db:             db,
-		runtimeManager: NewRuntimeManager(),
-		toolManager:    NewToolManager(),
+		runtimeManager: runtimeManager,
+		toolManager:    toolManager,
 	}
 }
 
@@ -57,6 +62,9 @@ func (s *WorkspaceService) DB() *gorm.DB {
 // SetRuntimeManager sets the runtime manager (for dependency injection)
 func (s *WorkspaceService) SetRuntimeManager(rm *RuntimeManager) {
 	s.runtimeManager = rm
+	if s.toolManager != nil {
+		s.toolManager.SetRuntimeManager(rm)
+	}
 }
 
 // GetRuntimeManager returns the runtime manager
@@ -67,6 +75,14 @@ func (s *WorkspaceService) GetRuntimeManager() *RuntimeManager {
 // SetToolManager sets the tool manager (for dependency injection)
 func (s *WorkspaceService) SetToolManager(tm *ToolManager) {
 	s.toolManager = tm
+	if tm != nil {
+		tm.SetRuntimeManager(s.runtimeManager)
+	}
+}
+
+// GetToolManager returns the tool manager
+func (s *WorkspaceService) GetToolManager() *ToolManager {
+	return s.toolManager
 }
 
 // SetDockerService sets the docker service on the runtime manager
@@ -239,6 +255,38 @@ func (s *WorkspaceService) updateRuntimeContainerInfo(workspaceID, containerID,
 
 // AutoMigrate creates database tables
 func (s *WorkspaceService) AutoMigrate() error {
+	if err := s.autoMigrateTables(); err != nil {
+		return err
+	}
+	// Move plaintext tool secrets of earlier versions into the vault
+	secrets.Default().WhenUnlocked(s.migrateToolSecrets)
+	return nil
+}
+
+// migrateToolSecrets seals plaintext auth tokens and keys left in tool
+// configs
+func (s *WorkspaceService) migrateToolSecrets() {
+	var tools []models.WorkspaceTool
+	if err := s.db.Find(&tools).Error; err != nil {
+		log.Printf("[Secrets] Failed to load workspace tools: %v", err)
+		return
+	}
+	for i := range tools {
+		changed, err := secrets.SealMap(tools[i].Config)
+		if err != nil {
+			log.Printf("[Secrets] Failed to seal config of tool %s: %v", tools[i].ID, err)
+			return
+		}
+		if !changed {
+			continue
+		}
+		if err := s.db.Model(&tools[i]).Update("config", tools[i].Config).Error; err != nil {
+			log.Printf("[Secrets] Failed to save config of tool %s: %v", tools[i].ID, err)
+		}
+	}
+}
+
+func (s *WorkspaceService) autoMigrateTables() error {
 	return s.db.AutoMigrate(
 		&models.Workspace{},
 		&models.WorkspaceRuntime{},
@@ -260,6 +308,15 @@ func validateWorkspaceName(name string) error {
 	return nil
 }
 
+// validateToolApprovalMode checks that a tool approval mode is known
+func validateToolApprovalMode(mode models.ToolApprovalMode) error {
+	switch mode {
+	case models.ToolApprovalAlways, models.ToolApprovalDangerous, models.ToolApprovalNever, models.ToolApprovalAllowlist:
+		return nil
+	}
+	return ErrToolApprovalInvalid
+}
+
 // CreateWorkspaceRequest represents a request to create a workspace
 type CreateWorkspaceRequest struct {
 	Name        string                  `json:"name"`
@@ -276,6 +333,9 @@ type CreateWorkspaceRequest struct {
 	EmbeddingModel     *string `json:"embedding_model,omitempty"`
 	EmbeddingDimension *int    `json:"embedding_dimension,omitempty"`
 	ExtractionModel    *string `json:"extraction_model,omitempty"`
+	// Tool approval configuration
+	ToolApprovalMode      models.ToolApprovalMode `json:"tool_approval_mode,omitempty"`
+	ToolApprovalAllowlist []string                `json:"tool_approval_allowlist,omitempty"`
 }
 
 // CreateRuntimeRequest represents runtime configuration for creation
@@ -314,6 +374,11 @@ func (s *WorkspaceService) Create(ctx context.Context, req *CreateWorkspaceReque
 	if err := validateWorkspaceName(req.Name); err != nil {
 		return nil, err
 	}
+	if req.ToolApprovalMode != "" {
+		if err := validateToolApprovalMode(req.ToolApprovalMode); err != nil {
+			return nil, err
+		}
+	}
 
 	// Check if name exists
 	var count int64
@@ -338,8 +403,14 @@ func (s *WorkspaceService) Create(ctx context.Context, req *CreateWorkspaceReque
 		EmbeddingModel:     req.EmbeddingModel,
 		EmbeddingDimension: req.EmbeddingDimension,
 		ExtractionModel:    req.ExtractionModel,
-		CreatedAt:          time.Now(),
-		UpdatedAt:          time.Now(),
+		// Tool approval configuration
+		ToolApprovalMode:      req.ToolApprovalMode,
+		ToolApprovalAllowlist: req.ToolApprovalAllowlist,
+		CreatedAt:             time.Now(),
+		UpdatedAt:             time.Now(),
+	}
+	if workspace.ToolApprovalMode == "" {
+		workspace.ToolApprovalMode = models.ToolApprovalDangerous
 	}
 
 	// Create in transaction
@@ -411,6 +482,9 @@ func (s *WorkspaceService) Create(ctx context.Context, req *CreateWorkspaceReque
 			if toolReq.Enabled != nil {
 				enabled = *toolReq.Enabled
 			}
+			if _, err := secrets.SealMap(toolReq.Config); err != nil {
+				return fmt.Errorf("tool %s: %w", toolReq.Name, err)
+			}
 			tool := &models.WorkspaceTool{
 				ID:          uuid.New().String(),
 				WorkspaceID: workspace.ID,
@@ -525,6 +599,9 @@ type UpdateWorkspaceRequest struct {
 	EmbeddingModel     *string `json:"embedding_model,omitempty"`
 	EmbeddingDimension *int    `json:"embedding_dimension,omitempty"`
 	ExtractionModel    *string `json:"extraction_model,omitempty"`
+	// Tool approval configuration
+	ToolApprovalMode      *models.ToolApprovalMode `json:"tool_approval_mode,omitempty"`
+	ToolApprovalAllowlist *[]string                `json:"tool_approval_allowlist,omitempty"`
 }
 
 // Update updates a workspace
@@ -548,6 +625,11 @@ func (s *WorkspaceService) Update(ctx context.Context, id string, req *UpdateWor
 			return nil, ErrWorkspaceNameExists
 		}
 	}
+	if req.ToolApprovalMode != nil {
+		if err := validateToolApprovalMode(*req.ToolApprovalMode); err != nil {
+			return nil, err
+		}
+	}
 
 	err = s.db.Transaction(func(tx *gorm.DB) error {
 		updates := map[string]interface{}{
@@ -582,6 +664,13 @@ func (s *WorkspaceService) Update(ctx context.Context, id string, req *UpdateWor
 		if req.ExtractionModel != nil {
 			updates["extraction_model"] = req.ExtractionModel
 		}
+		// Tool approval configuration
+		if req.ToolApprovalMode != nil {
+			updates["tool_approval_mode"] = *req.ToolApprovalMode
+		}
+		if req.ToolApprovalAllowlist != nil {
+			updates["tool_approval_allowlist"] = models.StringList(*req.ToolApprovalAllowlist)
+		}
 
 		if err := tx.Model(workspace).Updates(updates).Error; err != nil {
 			return err
@@ -661,6 +750,9 @@ func (s *WorkspaceService) Update(ctx context.Context, id string, req *UpdateWor
 				if toolReq.Enabled != nil {
 					enabled = *toolReq.Enabled
 				}
+				if _, err := secrets.SealMap(toolReq.Config); err != nil {
+					return fmt.Errorf("tool %s: %w", toolReq.Name, err)
+				}
 				tool := &models.WorkspaceTool{
 					ID:          uuid.New().String(),
 					WorkspaceID: id,
@@ -726,6 +818,9 @@ func (s *WorkspaceService) Clone(ctx context.Context, id string, newName string)
 		Name:        newName,
 		Description: workspace.Description,
 		Color:       workspace.Color,
+		// Tool approval configuration
+		ToolApprovalMode:      workspace.ToolApprovalMode,
+		ToolApprovalAllowlist: workspace.ToolApprovalAllowlist,
 	}
 
 	if workspace.Runtime != nil {
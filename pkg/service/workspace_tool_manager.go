@@ -2,15 +2,23 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service/mcp"
+	"github.com/choraleia/choraleia/pkg/utils"
 )
 
 // ToolManager manages workspace tools
 type ToolManager struct {
-	tools map[string]*ToolInstance
-	mu    sync.RWMutex
+	tools          map[string]*ToolInstance
+	mu             sync.RWMutex
+	startLocks     map[string]*sync.Mutex // per tool, serializes starts so one instance runs
+	runtimeManager *RuntimeManager
+	logger         *slog.Logger
 }
 
 // ToolInstance represents a running tool instance
@@ -19,15 +27,45 @@ type ToolInstance struct {
 	WorkspaceID string
 	Status      models.ToolStatus
 	Error       string
+
+	// Runtime handles (set for tools backed by a live connection or process)
+	mcpClient *mcp.Client
+	process   *RuntimeProcess
+	openapi   *OpenAPIService
+	stopping  bool
+	// ctx is cancelled when the instance is stopped; it ends reconnect waits
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Restart state for tools that reconnect after the connection drops
+	workspace *models.Workspace
+	tool      models.WorkspaceTool
+	reconnect *reconnectPolicy
+}
+
+// reconnectPolicy controls automatic restarts of a dropped tool connection
+type reconnectPolicy struct {
+	interval    time.Duration // first backoff delay, doubled after each failure
+	maxAttempts int           // 0 = retry until stopped
 }
 
+// maxReconnectDelay caps the reconnect backoff
+const maxReconnectDelay = time.Minute
+
 // NewToolManager creates a new ToolManager
 func NewToolManager() *ToolManager {
 	return &ToolManager{
-		tools: make(map[string]*ToolInstance),
+		tools:      make(map[string]*ToolInstance),
+		startLocks: make(map[string]*sync.Mutex),
+		logger:     utils.GetLogger(),
 	}
 }
 
+// SetRuntimeManager sets the runtime manager used to run tools inside workspace runtimes
+func (m *ToolManager) SetRuntimeManager(rm *RuntimeManager) {
+	m.runtimeManager = rm
+}
+
 // InitializeTools initializes all enabled tools for a workspace
 func (m *ToolManager) InitializeTools(ctx context.Context, workspace *models.Workspace) error {
 	for _, tool := range workspace.Tools {
@@ -35,16 +73,12 @@ func (m *ToolManager) InitializeTools(ctx context.Context, workspace *models.Wor
 			continue
 		}
 
-		if err := m.StartTool(ctx, workspace.ID, &tool); err != nil {
-			// Store error but continue with other tools
-			m.mu.Lock()
-			m.tools[tool.ID] = &ToolInstance{
-				ToolID:      tool.ID,
-				WorkspaceID: workspace.ID,
-				Status:      models.ToolStatusError,
-				Error:       err.Error(),
-			}
-			m.mu.Unlock()
+		if err := m.StartTool(ctx, workspace, &tool); err != nil {
+			// Error is recorded on the instance, continue with other tools
+			m.logger.Warn("Failed to start workspace tool",
+				"workspaceID", workspace.ID,
+				"toolName", tool.Name,
+				"error", err)
 		}
 	}
 
@@ -67,42 +101,235 @@ func (m *ToolManager) ShutdownTools(ctx context.Context, workspaceID string) err
 	return nil
 }
 
-// StartTool starts a single tool
-func (m *ToolManager) StartTool(ctx context.Context, workspaceID string, tool *models.WorkspaceTool) error {
+// StartTool starts a single tool, replacing any running instance of it.
+// Starting may spawn processes or open connections, so it runs outside the
+// lock, serialized with other starts of the same tool.
+func (m *ToolManager) StartTool(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool) error {
+	lock := m.startLock(tool.ID)
+	lock.Lock()
+	defer lock.Unlock()
+	return m.startTool(ctx, workspace, tool)
+}
+
+// startLock returns the lock serializing starts of a tool
+func (m *ToolManager) startLock(toolID string) *sync.Mutex {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	lock, ok := m.startLocks[toolID]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.startLocks[toolID] = lock
+	}
+	return lock
+}
+
+// startTool starts a tool; the caller holds its start lock
+func (m *ToolManager) startTool(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool) error {
+	_ = m.StopTool(ctx, tool.ID)
 
+	instance := m.newInstance(workspace, tool)
+	err := m.startInstance(ctx, workspace, tool, instance)
+	if err != nil {
+		instance.Status = models.ToolStatusError
+		instance.Error = err.Error()
+	}
+
+	m.mu.Lock()
+	m.tools[tool.ID] = instance
+	m.mu.Unlock()
+
+	if err == nil && instance.mcpClient != nil {
+		go m.watchInstance(instance)
+	}
+	return err
+}
+
+// newInstance creates an instance record for a tool about to be started
+func (m *ToolManager) newInstance(workspace *models.Workspace, tool *models.WorkspaceTool) *ToolInstance {
 	instance := &ToolInstance{
 		ToolID:      tool.ID,
-		WorkspaceID: workspaceID,
+		WorkspaceID: tool.WorkspaceID,
 		Status:      models.ToolStatusRunning,
+		workspace:   workspace,
+		tool:        *tool,
 	}
+	instance.ctx, instance.cancel = context.WithCancel(context.Background())
+	if workspace != nil {
+		instance.WorkspaceID = workspace.ID
+	}
+	return instance
+}
 
-	var err error
+// startInstance runs the type-specific start logic for a tool
+func (m *ToolManager) startInstance(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool, instance *ToolInstance) error {
 	switch tool.Type {
 	case models.ToolTypeMCPStdio:
-		err = m.startMCPStdioTool(ctx, tool)
+		return m.startMCPStdioTool(ctx, workspace, tool, instance)
 	case models.ToolTypeMCPSSE:
-		err = m.startMCPSSETool(ctx, tool)
+		return m.startMCPSSETool(ctx, workspace, tool, instance)
 	case models.ToolTypeMCPHTTP:
-		err = m.startMCPHTTPTool(ctx, tool)
+		return m.startMCPHTTPTool(ctx, workspace, tool, instance)
 	case models.ToolTypeOpenAPI:
-		err = m.startOpenAPITool(ctx, tool)
+		return m.startOpenAPITool(ctx, workspace, tool, instance)
 	case models.ToolTypeScript:
-		err = m.startScriptTool(ctx, tool)
+		return m.startScriptTool(ctx, workspace, tool, instance)
 	case models.ToolTypeBrowserService:
-		err = m.startBrowserServiceTool(ctx, tool)
+		return m.startBrowserServiceTool(ctx, workspace, tool, instance)
 	case models.ToolTypeBuiltin:
-		err = m.startBuiltinTool(ctx, tool)
+		return m.startBuiltinTool(ctx, workspace, tool, instance)
 	}
+	return nil
+}
 
-	if err != nil {
-		instance.Status = models.ToolStatusError
+// watchInstance marks a tool as errored when its connection or process goes away,
+// and schedules a reconnect for tools that allow it
+func (m *ToolManager) watchInstance(instance *ToolInstance) {
+	<-instance.mcpClient.Done()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if instance.stopping {
+		return
+	}
+	instance.Status = models.ToolStatusError
+	instance.Error = "connection closed"
+	if err := instance.mcpClient.Err(); err != nil {
 		instance.Error = err.Error()
 	}
+	if instance.process != nil {
+		if stderr := instance.process.Stderr(); stderr != "" {
+			instance.Error += ": " + stderr
+		}
+	}
+	m.logger.Warn("Workspace tool stopped unexpectedly",
+		"toolID", instance.ToolID,
+		"workspaceID", instance.WorkspaceID,
+		"error", instance.Error)
 
-	m.tools[tool.ID] = instance
-	return err
+	if instance.reconnect != nil {
+		go m.reconnectInstance(instance)
+	}
+}
+
+// reconnectInstance restarts a dropped tool with exponential backoff until it
+// comes back or the tool is stopped/replaced
+func (m *ToolManager) reconnectInstance(old *ToolInstance) {
+	policy := old.reconnect
+	delay := policy.interval
+	var lastErr error
+	for attempt := 1; policy.maxAttempts <= 0 || attempt <= policy.maxAttempts; attempt++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-old.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if delay < maxReconnectDelay {
+			delay *= 2
+			if delay > maxReconnectDelay {
+				delay = maxReconnectDelay
+			}
+		}
+
+		if !m.isCurrent(old) {
+			return
+		}
+
+		instance := m.newInstance(old.workspace, &old.tool)
+		ctx, cancel := context.WithTimeout(old.ctx, mcpHandshakeTimeout)
+		err := m.startInstance(ctx, old.workspace, &old.tool, instance)
+		cancel()
+		if err != nil {
+			lastErr = err
+			m.logger.Warn("Workspace tool reconnect failed",
+				"toolID", old.ToolID,
+				"attempt", attempt,
+				"error", err)
+			m.mu.Lock()
+			if m.tools[old.ToolID] == old && !old.stopping {
+				old.Error = fmt.Sprintf("reconnecting (attempt %d): %v", attempt, err)
+			}
+			m.mu.Unlock()
+			continue
+		}
+
+		m.mu.Lock()
+		if m.tools[old.ToolID] != old || old.stopping {
+			// Tool was stopped or restarted while we were connecting
+			m.mu.Unlock()
+			m.stopToolInstance(context.Background(), instance)
+			return
+		}
+		m.tools[old.ToolID] = instance
+		m.mu.Unlock()
+
+		m.logger.Info("Workspace tool reconnected",
+			"toolID", old.ToolID,
+			"attempt", attempt)
+		if instance.mcpClient != nil {
+			go m.watchInstance(instance)
+		}
+		return
+	}
+
+	m.logger.Warn("Workspace tool reconnect gave up",
+		"toolID", old.ToolID,
+		"attempts", policy.maxAttempts)
+	m.mu.Lock()
+	if m.tools[old.ToolID] == old && !old.stopping {
+		old.Error = fmt.Sprintf("gave up reconnecting after %d attempts: %v", policy.maxAttempts, lastErr)
+	}
+	m.mu.Unlock()
+}
+
+// isCurrent reports whether instance is still the registered, non-stopped instance of its tool
+func (m *ToolManager) isCurrent(instance *ToolInstance) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tools[instance.ToolID] == instance && !instance.stopping
+}
+
+// GetMCPClient returns the live MCP client for a tool, starting the tool if it is not running
+func (m *ToolManager) GetMCPClient(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool) (*mcp.Client, error) {
+	instance, err := m.ensureInstance(ctx, workspace, tool, func(i *ToolInstance) bool {
+		return i.mcpClient != nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if instance.mcpClient == nil {
+		return nil, fmt.Errorf("tool %s is not an MCP tool", tool.Name)
+	}
+	return instance.mcpClient, nil
+}
+
+// ensureInstance returns the running instance of a tool, starting it when it is
+// missing, errored or not ready. Starts are serialized so concurrent chats share one instance.
+func (m *ToolManager) ensureInstance(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool, ready func(*ToolInstance) bool) (*ToolInstance, error) {
+	lock := m.startLock(tool.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	m.mu.RLock()
+	instance, exists := m.tools[tool.ID]
+	if exists && instance.Status == models.ToolStatusRunning && ready(instance) {
+		m.mu.RUnlock()
+		return instance, nil
+	}
+	m.mu.RUnlock()
+
+	if err := m.startTool(ctx, workspace, tool); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	instance, exists = m.tools[tool.ID]
+	if !exists {
+		return nil, fmt.Errorf("tool %s failed to start", tool.Name)
+	}
+	return instance, nil
 }
 
 // StopTool stops a single tool
@@ -120,9 +347,18 @@ func (m *ToolManager) StopTool(ctx context.Context, toolID string) error {
 	return nil
 }
 
-// stopToolInstance stops a tool instance
+// stopToolInstance stops a tool instance (caller holds m.mu)
 func (m *ToolManager) stopToolInstance(ctx context.Context, instance *ToolInstance) {
-	// TODO: Implement actual tool shutdown based on type
+	instance.stopping = true
+	if instance.cancel != nil {
+		instance.cancel()
+	}
+	if instance.mcpClient != nil {
+		_ = instance.mcpClient.Close()
+	}
+	if instance.process != nil {
+		_ = instance.process.Kill()
+	}
 	instance.Status = models.ToolStatusStopped
 }
 
@@ -139,40 +375,72 @@ func (m *ToolManager) GetToolStatus(ctx context.Context, toolID string) *ToolIns
 		}
 	}
 
-	return instance
+	// Return a snapshot so callers don't race with the watcher goroutine
+	return &ToolInstance{
+		ToolID:      instance.ToolID,
+		WorkspaceID: instance.WorkspaceID,
+		Status:      instance.Status,
+		Error:       instance.Error,
+	}
 }
 
-// TestConnection tests the connection to a tool
-func (m *ToolManager) TestConnection(ctx context.Context, tool *models.WorkspaceTool) (*ToolTestResult, error) {
+// TestConnection tests the connection to a tool of a workspace
+func (m *ToolManager) TestConnection(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool) (*ToolTestResult, error) {
 	result := &ToolTestResult{
 		Success: false,
 	}
 
 	switch tool.Type {
 	case models.ToolTypeMCPStdio:
-		// TODO: Test MCP stdio connection
-		result.Success = true
-		result.Message = "MCP stdio tool ready"
+		instance := &ToolInstance{ToolID: tool.ID}
+		if err := m.startMCPStdioTool(ctx, workspace, tool, instance); err != nil {
+			result.Message = err.Error()
+			return result, nil
+		}
+		fillMCPTestResult(result, instance.mcpClient)
+		m.stopToolInstance(ctx, instance)
 
 	case models.ToolTypeMCPSSE:
-		// TODO: Test SSE connection
-		result.Success = true
-		result.Message = "MCP SSE endpoint reachable"
+		instance := &ToolInstance{ToolID: tool.ID}
+		if err := m.startMCPSSETool(ctx, workspace, tool, instance); err != nil {
+			result.Message = err.Error()
+			return result, nil
+		}
+		fillMCPTestResult(result, instance.mcpClient)
+		m.stopToolInstance(ctx, instance)
 
 	case models.ToolTypeMCPHTTP:
-		// TODO: Test HTTP connection
-		result.Success = true
-		result.Message = "MCP HTTP endpoint reachable"
+		instance := &ToolInstance{ToolID: tool.ID}
+		if err := m.startMCPHTTPTool(ctx, workspace, tool, instance); err != nil {
+			result.Message = err.Error()
+			return result, nil
+		}
+		fillMCPTestResult(result, instance.mcpClient)
+		m.stopToolInstance(ctx, instance)
 
 	case models.ToolTypeOpenAPI:
-		// TODO: Validate OpenAPI spec
+		instance := &ToolInstance{ToolID: tool.ID}
+		if err := m.startOpenAPITool(ctx, workspace, tool, instance); err != nil {
+			result.Message = err.Error()
+			return result, nil
+		}
+		doc := instance.openapi.Document
 		result.Success = true
-		result.Message = "OpenAPI spec valid"
+		result.ToolsCount = len(doc.Operations)
+		result.Message = fmt.Sprintf("OpenAPI %s spec valid", doc.Version)
+		if doc.Title != "" {
+			result.Message = fmt.Sprintf("%s (OpenAPI %s) spec valid", doc.Title, doc.Version)
+		}
 
 	case models.ToolTypeScript:
-		// TODO: Validate script
+		msg, err := checkScriptTool(tool)
+		if err != nil {
+			result.Message = err.Error()
+			return result, nil
+		}
 		result.Success = true
-		result.Message = "Script ready"
+		result.ToolsCount = 1
+		result.Message = msg
 
 	case models.ToolTypeBrowserService:
 		// TODO: Test browser service API
@@ -195,39 +463,14 @@ type ToolTestResult struct {
 	ToolsCount   int      `json:"tools_count,omitempty"`
 }
 
-// MCP tool implementations (stubs for now)
-
-func (m *ToolManager) startMCPStdioTool(ctx context.Context, tool *models.WorkspaceTool) error {
-	// TODO: Start MCP stdio process
-	return nil
-}
-
-func (m *ToolManager) startMCPSSETool(ctx context.Context, tool *models.WorkspaceTool) error {
-	// TODO: Connect to MCP SSE endpoint
-	return nil
-}
-
-func (m *ToolManager) startMCPHTTPTool(ctx context.Context, tool *models.WorkspaceTool) error {
-	// TODO: Initialize MCP HTTP client
-	return nil
-}
-
-func (m *ToolManager) startOpenAPITool(ctx context.Context, tool *models.WorkspaceTool) error {
-	// TODO: Load and validate OpenAPI spec
-	return nil
-}
-
-func (m *ToolManager) startScriptTool(ctx context.Context, tool *models.WorkspaceTool) error {
-	// TODO: Prepare script runner
-	return nil
-}
+// Remaining tool implementations (stubs for now)
 
-func (m *ToolManager) startBrowserServiceTool(ctx context.Context, tool *models.WorkspaceTool) error {
+func (m *ToolManager) startBrowserServiceTool(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool, instance *ToolInstance) error {
 	// TODO: Connect to browser service
 	return nil
 }
 
-func (m *ToolManager) startBuiltinTool(ctx context.Context, tool *models.WorkspaceTool) error {
+func (m *ToolManager) startBuiltinTool(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool, instance *ToolInstance) error {
 	// Built-in tools are always ready
 	return nil
 }
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service/mcp"
+)
+
+// mcpHandshakeTimeout bounds process start + initialize + tools/list
+const mcpHandshakeTimeout = 60 * time.Second
+
+// mcpClientInfo identifies Choraleia to MCP servers
+var mcpClientInfo = mcp.Implementation{Name: "choraleia", Version: "1.0.0"}
+
+// startMCPStdioTool spawns the configured MCP server and performs the handshake.
+// RuntimeEnvWorkspace runs the command inside the workspace runtime, otherwise on the host.
+func (m *ToolManager) startMCPStdioTool(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool, instance *ToolInstance) error {
+	var cfg models.MCPStdioConfig
+	if err := models.DecodeToolConfig(tool.Config, "mcp_stdio", &cfg); err != nil {
+		return fmt.Errorf("invalid mcp stdio config: %w", err)
+	}
+	if cfg.Command == "" {
+		return fmt.Errorf("mcp stdio command is required")
+	}
+
+	cmd := append([]string{cfg.Command}, cfg.Args...)
+
+	var proc *RuntimeProcess
+	var err error
+	switch cfg.RuntimeEnv {
+	case models.RuntimeEnvWorkspace:
+		if workspace == nil {
+			return fmt.Errorf("runtime_env workspace requires a workspace context")
+		}
+		if m.runtimeManager == nil {
+			return fmt.Errorf("runtime manager not available")
+		}
+		proc, err = m.runtimeManager.StartProcess(ctx, workspace, cmd, cfg.Env, cfg.Cwd)
+	default:
+		proc, err = StartLocalProcess(cmd, cfg.Env, cfg.Cwd)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start mcp server: %w", err)
+	}
+
+	transport := mcp.NewStdioTransport(proc.Stdout, proc.Stdin, proc.Kill)
+
+	hctx, cancel := context.WithTimeout(ctx, mcpHandshakeTimeout)
+	defer cancel()
+
+	client, err := mcp.Connect(hctx, transport, mcpClientInfo)
+	if err != nil {
+		_ = transport.Close()
+		if stderr := proc.Stderr(); stderr != "" {
+			return fmt.Errorf("mcp handshake failed: %w: %s", err, stderr)
+		}
+		return fmt.Errorf("mcp handshake failed: %w", err)
+	}
+
+	m.logger.Info("MCP stdio tool started",
+		"toolName", tool.Name,
+		"command", cfg.Command,
+		"runtimeEnv", cfg.RuntimeEnv,
+		"toolsCount", len(client.Tools()))
+
+	instance.mcpClient = client
+	instance.process = proc
+	return nil
+}
+
+// defaultMCPReconnectInterval is the first reconnect delay when none is configured
+const defaultMCPReconnectInterval = time.Second
+
+// startMCPSSETool connects to an MCP server over the HTTP+SSE transport
+func (m *ToolManager) startMCPSSETool(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool, instance *ToolInstance) error {
+	var cfg models.MCPSSEConfig
+	if err := models.DecodeToolConfig(tool.Config, "mcp_sse", &cfg); err != nil {
+		return fmt.Errorf("invalid mcp sse config: %w", err)
+	}
+	if cfg.URL == "" {
+		return fmt.Errorf("mcp sse url is required")
+	}
+
+	opts := mcpHTTPOptions(cfg.Headers, cfg.Auth, cfg.Timeout, 0)
+	client, err := m.connectMCP(ctx, mcp.NewSSETransport(cfg.URL, opts), cfg.Timeout)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("MCP SSE tool connected",
+		"toolName", tool.Name,
+		"url", cfg.URL,
+		"toolsCount", len(client.Tools()))
+
+	instance.mcpClient = client
+	if cfg.Reconnect == nil || *cfg.Reconnect {
+		interval := time.Duration(cfg.ReconnectInterval) * time.Millisecond
+		if interval <= 0 {
+			interval = defaultMCPReconnectInterval
+		}
+		instance.reconnect = &reconnectPolicy{interval: interval, maxAttempts: cfg.ReconnectAttempts}
+	}
+	return nil
+}
+
+// startMCPHTTPTool connects to an MCP server over the streamable HTTP transport
+func (m *ToolManager) startMCPHTTPTool(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool, instance *ToolInstance) error {
+	var cfg models.MCPHTTPConfig
+	if err := models.DecodeToolConfig(tool.Config, "mcp_http", &cfg); err != nil {
+		return fmt.Errorf("invalid mcp http config: %w", err)
+	}
+	if cfg.URL == "" {
+		return fmt.Errorf("mcp http url is required")
+	}
+
+	opts := mcpHTTPOptions(cfg.Headers, cfg.Auth, cfg.Timeout, cfg.Retries)
+	client, err := m.connectMCP(ctx, mcp.NewStreamableHTTPTransport(cfg.URL, opts), cfg.Timeout)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("MCP HTTP tool connected",
+		"toolName", tool.Name,
+		"url", cfg.URL,
+		"toolsCount", len(client.Tools()))
+
+	instance.mcpClient = client
+	// Sessions can expire server-side; start a fresh one when that happens
+	instance.reconnect = &reconnectPolicy{interval: defaultMCPReconnectInterval}
+	return nil
+}
+
+// connectMCP performs the handshake over a remote transport.
+// timeoutMs overrides the default handshake timeout when set.
+func (m *ToolManager) connectMCP(ctx context.Context, transport mcp.Transport, timeoutMs int) (*mcp.Client, error) {
+	timeout := mcpHandshakeTimeout
+	if timeoutMs > 0 {
+		timeout = time.Duration(timeoutMs) * time.Millisecond
+	}
+	hctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client, err := mcp.Connect(hctx, transport, mcpClientInfo)
+	if err != nil {
+		_ = transport.Close()
+		return nil, fmt.Errorf("mcp handshake failed: %w", err)
+	}
+	return client, nil
+}
+
+// mcpHTTPOptions builds transport options from tool headers, auth and limits
+func mcpHTTPOptions(headers map[string]string, auth *models.MCPAuthConfig, timeoutMs, retries int) mcp.HTTPOptions {
+	h := http.Header{}
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+
+	if auth != nil {
+		switch auth.Type {
+		case "bearer":
+			if auth.Token != "" {
+				h.Set("Authorization", "Bearer "+auth.Token)
+			}
+		case "basic":
+			creds := base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+			h.Set("Authorization", "Basic "+creds)
+		case "apiKey":
+			header := auth.APIKeyHeader
+			if header == "" {
+				header = "X-API-Key"
+			}
+			if auth.APIKey != "" {
+				h.Set(header, auth.APIKey)
+			}
+		case "custom":
+			for k, v := range auth.CustomHeaders {
+				h.Set(k, v)
+			}
+		}
+	}
+
+	return mcp.HTTPOptions{
+		Headers: h,
+		Timeout: time.Duration(timeoutMs) * time.Millisecond,
+		Retries: retries,
+	}
+}
+
+// fillMCPTestResult reports server capabilities and tool count for TestConnection
+func fillMCPTestResult(result *ToolTestResult, client *mcp.Client) {
+	result.Success = true
+	result.ToolsCount = len(client.Tools())
+
+	info := client.ServerInfo()
+	if info == nil {
+		result.Message = "MCP server connected"
+		return
+	}
+	result.Message = fmt.Sprintf("Connected to %s %s", info.ServerInfo.Name, info.ServerInfo.Version)
+	if info.Capabilities.Tools != nil {
+		result.Capabilities = append(result.Capabilities, "tools")
+	}
+	if len(info.Capabilities.Resources) > 0 {
+		result.Capabilities = append(result.Capabilities, "resources")
+	}
+	if len(info.Capabilities.Prompts) > 0 {
+		result.Capabilities = append(result.Capabilities, "prompts")
+	}
+	if len(info.Capabilities.Logging) > 0 {
+		result.Capabilities = append(result.Capabilities, "logging")
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service/openapi"
+)
+
+const (
+	// openAPIFetchTimeout bounds downloading a spec from SpecURL
+	openAPIFetchTimeout = 30 * time.Second
+	// openAPIRequestTimeout bounds each operation call
+	openAPIRequestTimeout = 60 * time.Second
+	// maxOpenAPISpecSize guards against pointing SpecURL at something huge
+	maxOpenAPISpecSize = 10 << 20
+)
+
+// OpenAPIService is a loaded spec together with the client used to call it
+type OpenAPIService struct {
+	Document *openapi.Document
+	Client   *openapi.Client
+}
+
+// startOpenAPITool loads and validates the spec, then prepares the HTTP client
+func (m *ToolManager) startOpenAPITool(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool, instance *ToolInstance) error {
+	var cfg models.OpenAPIConfig
+	if err := models.DecodeToolConfig(tool.Config, "openapi", &cfg); err != nil {
+		return fmt.Errorf("invalid openapi config: %w", err)
+	}
+
+	data, err := loadOpenAPISpec(ctx, &cfg)
+	if err != nil {
+		return err
+	}
+	doc, err := openapi.Parse(data)
+	if err != nil {
+		return fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	if len(doc.Operations) == 0 {
+		return fmt.Errorf("openapi spec defines no operations")
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = openapi.ResolveServerURL(cfg.SpecURL, doc.ServerURL)
+	}
+	if baseURL == "" {
+		return fmt.Errorf("base url is required when the spec declares no server")
+	}
+
+	m.logger.Info("OpenAPI tool loaded",
+		"toolName", tool.Name,
+		"title", doc.Title,
+		"baseURL", baseURL,
+		"operations", len(doc.Operations))
+
+	instance.openapi = &OpenAPIService{
+		Document: doc,
+		Client: &openapi.Client{
+			BaseURL:    baseURL,
+			Headers:    openAPIHeaders(&cfg),
+			HTTPClient: &http.Client{Timeout: openAPIRequestTimeout},
+		},
+	}
+	return nil
+}
+
+// GetOpenAPIService returns the loaded spec for a tool, loading it if needed
+func (m *ToolManager) GetOpenAPIService(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool) (*OpenAPIService, error) {
+	instance, err := m.ensureInstance(ctx, workspace, tool, func(i *ToolInstance) bool {
+		return i.openapi != nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if instance.openapi == nil {
+		return nil, fmt.Errorf("tool %s is not an OpenAPI tool", tool.Name)
+	}
+	return instance.openapi, nil
+}
+
+// loadOpenAPISpec returns inline spec content or downloads SpecURL
+func loadOpenAPISpec(ctx context.Context, cfg *models.OpenAPIConfig) ([]byte, error) {
+	if cfg.SpecContent != "" {
+		return []byte(cfg.SpecContent), nil
+	}
+	if cfg.SpecURL == "" {
+		return nil, fmt.Errorf("spec_url or spec_content is required")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, openAPIFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.SpecURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec url: %w", err)
+	}
+	// Specs are often served behind the same auth as the API itself
+	for k, values := range openAPIHeaders(cfg) {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch spec: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch spec: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxOpenAPISpecSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec: %w", err)
+	}
+	if len(data) > maxOpenAPISpecSize {
+		return nil, fmt.Errorf("spec exceeds %d bytes", maxOpenAPISpecSize)
+	}
+	return data, nil
+}
+
+// openAPIHeaders combines configured headers with auth
+func openAPIHeaders(cfg *models.OpenAPIConfig) http.Header {
+	h := http.Header{}
+	for k, v := range cfg.Headers {
+		h.Set(k, v)
+	}
+	if cfg.Auth == nil {
+		return h
+	}
+
+	switch cfg.Auth.Type {
+	case "bearer":
+		if cfg.Auth.Token != "" {
+			h.Set("Authorization", "Bearer "+cfg.Auth.Token)
+		}
+	case "basic":
+		creds := base64.StdEncoding.EncodeToString([]byte(cfg.Auth.Username + ":" + cfg.Auth.Password))
+		h.Set("Authorization", "Basic "+creds)
+	case "apiKey":
+		header := cfg.Auth.APIKeyHeader
+		if header == "" {
+			header = "X-API-Key"
+		}
+		if cfg.Auth.APIKey != "" {
+			h.Set(header, cfg.Auth.APIKey)
+		}
+	}
+	return h
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// defaultScriptTimeout applies when the tool doesn't configure one
+	defaultScriptTimeout = 60 * time.Second
+	// maxScriptOutput caps the stdout captured from a script
+	maxScriptOutput = 256 * 1024
+	// maxScriptArgsEnv is the largest argument payload also exported via environment
+	maxScriptArgsEnv = 32 * 1024
+)
+
+// ScriptResult is the outcome of a script tool run
+type ScriptResult struct {
+	Stdout    string
+	Stderr    string
+	ExitCode  int
+	TimedOut  bool
+	Truncated bool
+	Duration  time.Duration
+}
+
+// startScriptTool validates the script configuration; scripts run per call
+func (m *ToolManager) startScriptTool(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool, instance *ToolInstance) error {
+	var cfg models.ScriptConfig
+	if err := models.DecodeToolConfig(tool.Config, "script", &cfg); err != nil {
+		return fmt.Errorf("invalid script config: %w", err)
+	}
+	_, err := scriptCommand(&cfg)
+	return err
+}
+
+// RunScript executes a script tool with JSON arguments.
+// Arguments are written to stdin and exported as CHORALEIA_TOOL_ARGS.
+func (m *ToolManager) RunScript(ctx context.Context, workspace *models.Workspace, tool *models.WorkspaceTool, argsJSON string) (*ScriptResult, error) {
+	var cfg models.ScriptConfig
+	if err := models.DecodeToolConfig(tool.Config, "script", &cfg); err != nil {
+		return nil, fmt.Errorf("invalid script config: %w", err)
+	}
+	cmd, err := scriptCommand(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	if argsJSON == "" {
+		argsJSON = "{}"
+	}
+
+	env := make(map[string]string, len(cfg.Env)+2)
+	for k, v := range cfg.Env {
+		env[k] = v
+	}
+	env["CHORALEIA_TOOL_NAME"] = tool.Name
+	if len(argsJSON) <= maxScriptArgsEnv {
+		env["CHORALEIA_TOOL_ARGS"] = argsJSON
+	}
+
+	var proc *RuntimeProcess
+	switch cfg.RuntimeEnv {
+	case models.RuntimeEnvWorkspace:
+		if workspace == nil {
+			return nil, fmt.Errorf("runtime_env workspace requires a workspace context")
+		}
+		if m.runtimeManager == nil {
+			return nil, fmt.Errorf("runtime manager not available")
+		}
+		proc, err = m.runtimeManager.StartProcess(ctx, workspace, cmd, env, cfg.Cwd)
+	default:
+		proc, err = StartLocalProcess(cmd, env, cfg.Cwd)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start script: %w", err)
+	}
+
+	timeout := defaultScriptTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Millisecond
+	}
+
+	start := time.Now()
+	go func() {
+		_, _ = io.WriteString(proc.Stdin, argsJSON)
+		_ = proc.Stdin.Close()
+	}()
+
+	var stdout bytes.Buffer
+	readDone := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(&stdout, io.LimitReader(proc.Stdout, maxScriptOutput+1))
+		// Drain the rest so the script isn't blocked on a full pipe
+		_, _ = io.Copy(io.Discard, proc.Stdout)
+		readDone <- err
+	}()
+
+	result := &ScriptResult{}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-proc.Done():
+	case <-timer.C:
+		result.TimedOut = true
+		_ = proc.Kill()
+	case <-ctx.Done():
+		_ = proc.Kill()
+		<-proc.Done()
+		return nil, ctx.Err()
+	}
+
+	waitErr := proc.Wait()
+	<-readDone
+	result.Duration = time.Since(start)
+	result.Stderr = proc.Stderr()
+	result.ExitCode = exitCodeOf(waitErr)
+
+	out := stdout.Bytes()
+	if len(out) > maxScriptOutput {
+		out = out[:maxScriptOutput]
+		result.Truncated = true
+	}
+	result.Stdout = string(out)
+	return result, nil
+}
+
+// checkScriptTool validates a script tool without running it. Interpreter and
+// script file are only checked for local execution since workspace runtimes
+// may not be running yet.
+func checkScriptTool(tool *models.WorkspaceTool) (string, error) {
+	var cfg models.ScriptConfig
+	if err := models.DecodeToolConfig(tool.Config, "script", &cfg); err != nil {
+		return "", fmt.Errorf("invalid script config: %w", err)
+	}
+	cmd, err := scriptCommand(&cfg)
+	if err != nil {
+		return "", err
+	}
+	if cfg.RuntimeEnv == models.RuntimeEnvWorkspace {
+		return "Script config valid (runs in workspace runtime)", nil
+	}
+
+	interpreter, err := exec.LookPath(cmd[0])
+	if err != nil {
+		return "", fmt.Errorf("interpreter %s not found on host", cmd[0])
+	}
+	if cfg.Script == "" {
+		path := cfg.ScriptPath
+		if !filepath.IsAbs(path) && cfg.Cwd != "" {
+			path = filepath.Join(expandPath(cfg.Cwd), path)
+		}
+		if _, err := os.Stat(expandPath(path)); err != nil {
+			return "", fmt.Errorf("script file not accessible: %w", err)
+		}
+	}
+	return fmt.Sprintf("Script ready (%s)", interpreter), nil
+}
+
+// scriptCommand builds the interpreter command line for a script config.
+// Inline scripts are passed via the interpreter's eval flag so nothing needs
+// to be written to disk, which also works inside containers.
+func scriptCommand(cfg *models.ScriptConfig) ([]string, error) {
+	if cfg.Script == "" && cfg.ScriptPath == "" {
+		return nil, fmt.Errorf("script or script_path is required")
+	}
+
+	var cmd []string
+	inline := cfg.Script != ""
+	switch cfg.Runtime {
+	case "python", "":
+		if inline {
+			cmd = []string{"python3", "-c", cfg.Script}
+		} else {
+			cmd = []string{"python3", cfg.ScriptPath}
+		}
+	case "node":
+		if inline {
+			cmd = []string{"node", "-e", cfg.Script}
+		} else {
+			cmd = []string{"node", cfg.ScriptPath}
+		}
+	case "shell":
+		if inline {
+			// The extra "sh" becomes $0 so user args start at $1
+			cmd = []string{"sh", "-c", cfg.Script, "sh"}
+		} else {
+			cmd = []string{"sh", cfg.ScriptPath}
+		}
+	case "deno":
+		if inline {
+			cmd = []string{"deno", "eval", cfg.Script}
+		} else {
+			cmd = []string{"deno", "run", "--allow-all", cfg.ScriptPath}
+		}
+	case "bun":
+		if inline {
+			cmd = []string{"bun", "-e", cfg.Script}
+		} else {
+			cmd = []string{"bun", "run", cfg.ScriptPath}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported script runtime: %s", cfg.Runtime)
+	}
+	return append(cmd, cfg.Args...), nil
+}
+
+// exitCodeOf extracts the exit status from a local or SSH wait error
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	var sshExitErr *ssh.ExitError
+	if errors.As(err, &sshExitErr) {
+		return sshExitErr.ExitStatus()
+	}
+	return -1
+}
@@ -9,6 +9,7 @@ import (
 	_ "github.com/choraleia/choraleia/pkg/tools/browser"
 	_ "github.com/choraleia/choraleia/pkg/tools/database"
 	_ "github.com/choraleia/choraleia/pkg/tools/memory"
+	_ "github.com/choraleia/choraleia/pkg/tools/terminal_interact"
 	_ "github.com/choraleia/choraleia/pkg/tools/transfer"
 	_ "github.com/choraleia/choraleia/pkg/tools/workspace_exec"
 	_ "github.com/choraleia/choraleia/pkg/tools/workspace_fs"
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/choraleia/choraleia/pkg/models"
 	"github.com/choraleia/choraleia/pkg/tools"
+	"github.com/choraleia/choraleia/pkg/tools/policy"
 )
 
 func init() {
@@ -65,14 +67,16 @@ func executeOnAsset(ctx context.Context, tc *tools.ToolContext, assetID, command
 		return "", -1, fmt.Errorf("asset %s is not an SSH asset, cannot execute commands", asset.Name)
 	}
 
-	// Parse SSH config from asset.Config
-	sshConfig, err := parseSSHConfig(asset.Config)
+	if tc.FSService == nil {
+		return "", -1, fmt.Errorf("ssh pool not available")
+	}
+	sshClient, err := tc.FSService.SSHPool().GetSSHClient(assetID)
 	if err != nil {
-		return "", -1, fmt.Errorf("failed to parse SSH config: %w", err)
+		return "", -1, fmt.Errorf("failed to connect: %w", err)
 	}
 
 	// Execute command via SSH
-	output, exitCode, err := executeSSHCommand(ctx, sshConfig, command, timeout)
+	output, exitCode, err := executeSSHCommand(ctx, sshClient, command, timeout)
 	if err != nil {
 		return output, exitCode, err
 	}
@@ -80,43 +84,52 @@ func executeOnAsset(ctx context.Context, tc *tools.ToolContext, assetID, command
 	return output, exitCode, nil
 }
 
-// parseSSHConfig converts asset.Config map to SSHConfig struct
-func parseSSHConfig(config map[string]interface{}) (*models.SSHConfig, error) {
-	data, err := json.Marshal(config)
+// executeSSHCommand executes a command over a pooled SSH client. The client
+// is shared, so a timeout only closes the command's session.
+func executeSSHCommand(ctx context.Context, sshClient *ssh.Client, command string, timeout int) (string, int, error) {
+	session, err := sshClient.NewSession()
 	if err != nil {
-		return nil, err
-	}
-	var sshConfig models.SSHConfig
-	if err := json.Unmarshal(data, &sshConfig); err != nil {
-		return nil, err
+		return "", -1, fmt.Errorf("failed to create session: %w", err)
 	}
-	return &sshConfig, nil
-}
+	defer session.Close()
 
-// executeSSHCommand executes a command over SSH
-func executeSSHCommand(ctx context.Context, config *models.SSHConfig, command string, timeout int) (string, int, error) {
-	// Build SSH connection
-	sshClient, err := connectSSH(config)
-	if err != nil {
-		return "", -1, fmt.Errorf("failed to connect: %w", err)
+	// Execute command, bounded by the timeout
+	var (
+		output []byte
+		runErr error
+		done   = make(chan struct{})
+	)
+	go func() {
+		output, runErr = session.CombinedOutput(command)
+		close(done)
+	}()
+
+	// Many servers ignore signal requests, closing the channel right away
+	// hangs up the command and ends CombinedOutput
+	kill := func() {
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
 	}
-	defer sshClient.Close()
 
-	session, err := sshClient.NewSession()
-	if err != nil {
-		return "", -1, fmt.Errorf("failed to create session: %w", err)
+	timer := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		kill()
+		return "", -1, fmt.Errorf("command timed out after %ds", timeout)
+	case <-ctx.Done():
+		kill()
+		return "", -1, ctx.Err()
 	}
-	defer session.Close()
 
-	// Execute command
-	output, err := session.CombinedOutput(command)
 	exitCode := 0
-	if err != nil {
+	if runErr != nil {
 		// Try to get exit status from SSH exit error
-		if exitErr, ok := err.(*ssh.ExitError); ok {
+		if exitErr, ok := runErr.(*ssh.ExitError); ok {
 			exitCode = exitErr.ExitStatus()
 		} else {
-			return string(output), -1, err
+			return string(output), -1, runErr
 		}
 	}
 
@@ -146,6 +159,12 @@ func NewExecCommandTool(tc *tools.ToolContext) tool.InvokableTool {
 			timeout = 30
 		}
 
+		p := tc.AssetPolicy(input.AssetID)
+		if v := p.CheckCommand(input.Command); v != nil {
+			return tc.Refuse("asset_exec_command", v), nil
+		}
+		timeout = p.SessionTimeout(timeout)
+
 		output, exitCode, err := executeOnAsset(ctx, tc, input.AssetID, input.Command, timeout)
 
 		var sb strings.Builder
@@ -199,6 +218,13 @@ func NewExecScriptTool(tc *tools.ToolContext) tool.InvokableTool {
 			timeout = 60
 		}
 
+		// The script body is checked line by line, along with the shell running it
+		p := tc.AssetPolicy(input.AssetID)
+		if v := p.CheckCommand(shell + "\n" + input.Script); v != nil {
+			return tc.Refuse("asset_exec_script", v), nil
+		}
+		timeout = p.SessionTimeout(timeout)
+
 		// Wrap script in shell
 		command := fmt.Sprintf("%s << 'SCRIPT_EOF'\n%s\nSCRIPT_EOF", shell, input.Script)
 
@@ -235,11 +261,12 @@ type ExecBatchInput struct {
 }
 
 type BatchResult struct {
-	AssetID   string `json:"asset_id"`
-	AssetName string `json:"asset_name"`
-	ExitCode  int    `json:"exit_code"`
-	Output    string `json:"output"`
-	Error     string `json:"error,omitempty"`
+	AssetID   string            `json:"asset_id"`
+	AssetName string            `json:"asset_name"`
+	ExitCode  int               `json:"exit_code"`
+	Output    string            `json:"output"`
+	Error     string            `json:"error,omitempty"`
+	Violation *policy.Violation `json:"policy_violation,omitempty"`
 }
 
 func NewExecBatchTool(tc *tools.ToolContext) tool.InvokableTool {
@@ -266,7 +293,20 @@ func NewExecBatchTool(tc *tools.ToolContext) tool.InvokableTool {
 				AssetName: getAssetName(tc, assetID),
 			}
 
-			output, exitCode, err := executeOnAsset(ctx, tc, assetID, input.Command, timeout)
+			p := tc.AssetPolicy(assetID)
+			if v := p.CheckCommand(input.Command); v != nil {
+				tc.LogViolation("asset_exec_batch", v)
+				result.Error = "refused by workspace restrictions: " + v.Reason
+				result.Violation = v
+				result.ExitCode = -1
+				results = append(results, result)
+				if input.StopOnError {
+					break
+				}
+				continue
+			}
+
+			output, exitCode, err := executeOnAsset(ctx, tc, assetID, input.Command, p.SessionTimeout(timeout))
 			result.Output = output
 			result.ExitCode = exitCode
 
@@ -115,6 +115,10 @@ func NewListTool(tc *tools.ToolContext) tool.InvokableTool {
 			"all":      {Type: schema.Boolean, Required: false, Desc: "Include hidden files (default: false)"},
 		}),
 	}, func(ctx context.Context, input *ListInput) (string, error) {
+		if v := tc.AssetPolicy(input.AssetID).CheckFiles(input.Path); v != nil {
+			return tc.Refuse("asset_fs_list", v), nil
+		}
+
 		result, err := tc.ListDir(ctx, tc.AssetEndpoint(input.AssetID), input.Path, input.All)
 		if err != nil {
 			return fmt.Sprintf("Error: failed to list directory '%s' on %s: %v", input.Path, getAssetName(tc, input.AssetID), err), nil
@@ -156,6 +160,10 @@ func NewReadTool(tc *tools.ToolContext) tool.InvokableTool {
 			"max_bytes": {Type: schema.Integer, Required: false, Desc: "Maximum bytes to read (default: no limit)"},
 		}),
 	}, func(ctx context.Context, input *ReadInput) (string, error) {
+		if v := tc.AssetPolicy(input.AssetID).CheckFiles(input.Path); v != nil {
+			return tc.Refuse("asset_fs_read", v), nil
+		}
+
 		content, err := tc.ReadFile(ctx, tc.AssetEndpoint(input.AssetID), input.Path)
 		if err != nil {
 			return fmt.Sprintf("Error: failed to read file on %s: %v", getAssetName(tc, input.AssetID), err), nil
@@ -189,6 +197,10 @@ func NewWriteTool(tc *tools.ToolContext) tool.InvokableTool {
 			"overwrite": {Type: schema.Boolean, Required: false, Desc: "Overwrite existing file (default: true)"},
 		}),
 	}, func(ctx context.Context, input *WriteInput) (string, error) {
+		if v := tc.AssetPolicy(input.AssetID).CheckFiles(input.Path); v != nil {
+			return tc.Refuse("asset_fs_write", v), nil
+		}
+
 		err := tc.WriteFile(ctx, tc.AssetEndpoint(input.AssetID), input.Path, input.Content)
 		if err != nil {
 			return fmt.Sprintf("Error: failed to write file on %s: %v", getAssetName(tc, input.AssetID), err), nil
@@ -215,6 +227,10 @@ func NewStatTool(tc *tools.ToolContext) tool.InvokableTool {
 			"path":     {Type: schema.String, Required: true, Desc: "File or directory path"},
 		}),
 	}, func(ctx context.Context, input *StatInput) (string, error) {
+		if v := tc.AssetPolicy(input.AssetID).CheckFiles(input.Path); v != nil {
+			return tc.Refuse("asset_fs_stat", v), nil
+		}
+
 		info, err := tc.Stat(ctx, tc.AssetEndpoint(input.AssetID), input.Path)
 		if err != nil {
 			return fmt.Sprintf("Error: failed to get file info on %s: %v", getAssetName(tc, input.AssetID), err), nil
@@ -250,6 +266,10 @@ func NewMkdirTool(tc *tools.ToolContext) tool.InvokableTool {
 			"path":     {Type: schema.String, Required: true, Desc: "Directory path to create"},
 		}),
 	}, func(ctx context.Context, input *MkdirInput) (string, error) {
+		if v := tc.AssetPolicy(input.AssetID).CheckFiles(input.Path); v != nil {
+			return tc.Refuse("asset_fs_mkdir", v), nil
+		}
+
 		err := tc.Mkdir(ctx, tc.AssetEndpoint(input.AssetID), input.Path)
 		if err != nil {
 			return fmt.Sprintf("Error: failed to create directory on %s: %v", getAssetName(tc, input.AssetID), err), nil
@@ -274,6 +294,10 @@ func NewRemoveTool(tc *tools.ToolContext) tool.InvokableTool {
 			"path":     {Type: schema.String, Required: true, Desc: "File or directory path to remove"},
 		}),
 	}, func(ctx context.Context, input *RemoveInput) (string, error) {
+		if v := tc.AssetPolicy(input.AssetID).CheckFiles(input.Path); v != nil {
+			return tc.Refuse("asset_fs_remove", v), nil
+		}
+
 		err := tc.Remove(ctx, tc.AssetEndpoint(input.AssetID), input.Path)
 		if err != nil {
 			return fmt.Sprintf("Error: failed to remove on %s: %v", getAssetName(tc, input.AssetID), err), nil
@@ -300,6 +324,10 @@ func NewRenameTool(tc *tools.ToolContext) tool.InvokableTool {
 			"to":       {Type: schema.String, Required: true, Desc: "Destination path"},
 		}),
 	}, func(ctx context.Context, input *RenameInput) (string, error) {
+		if v := tc.AssetPolicy(input.AssetID).CheckFiles(input.From, input.To); v != nil {
+			return tc.Refuse("asset_fs_rename", v), nil
+		}
+
 		err := tc.Rename(ctx, tc.AssetEndpoint(input.AssetID), input.From, input.To)
 		if err != nil {
 			return fmt.Sprintf("Error: failed to rename on %s: %v", getAssetName(tc, input.AssetID), err), nil
@@ -326,6 +354,10 @@ func NewCopyTool(tc *tools.ToolContext) tool.InvokableTool {
 			"destination": {Type: schema.String, Required: true, Desc: "Destination file path"},
 		}),
 	}, func(ctx context.Context, input *CopyInput) (string, error) {
+		if v := tc.AssetPolicy(input.AssetID).CheckFiles(input.Source, input.Destination); v != nil {
+			return tc.Refuse("asset_fs_copy", v), nil
+		}
+
 		err := tc.Copy(ctx, tc.AssetEndpoint(input.AssetID), input.Source, input.Destination)
 		if err != nil {
 			return fmt.Sprintf("Error: failed to copy on %s: %v", getAssetName(tc, input.AssetID), err), nil
@@ -224,12 +224,25 @@ func (s *BuiltinToolsService) CreateToolsForWorkspace(
 		if err != nil {
 			continue
 		}
-		tools = append(tools, t)
+		tools = append(tools, &builtinTool{InvokableTool: t, dangerous: def.Dangerous})
 	}
 
 	return tools, nil
 }
 
+// builtinTool is a tool created from the registry; it reports the Dangerous
+// flag of its definition, which external tools can't take on by sharing its
+// name
+type builtinTool struct {
+	tool.InvokableTool
+	dangerous bool
+}
+
+// Dangerous reports the registered definition's flag
+func (t *builtinTool) Dangerous() bool {
+	return t.dangerous
+}
+
 // CreateGlobalTools creates invokable tools for global (non-workspace) use
 func (s *BuiltinToolsService) CreateGlobalTools(
 	enabledToolIDs []string,
@@ -13,6 +13,7 @@ import (
 	"github.com/redis/go-redis/v9"
 
 	"github.com/choraleia/choraleia/pkg/tools"
+	"github.com/choraleia/choraleia/pkg/tools/policy"
 )
 
 // ==================== Redis Tools ====================
@@ -44,6 +45,10 @@ func NewRedisCommandTool(tc *tools.ToolContext) tool.InvokableTool {
 			port = 6379
 		}
 
+		if v := tc.ServicePolicy(policy.KindRedis, input.Host, port).CheckRedis(input.Command, input.Args); v != nil {
+			return tc.Refuse("redis_command", v), nil
+		}
+
 		// Create Redis client
 		rdb := redis.NewClient(&redis.Options{
 			Addr:        fmt.Sprintf("%s:%d", input.Host, port),
@@ -103,10 +108,16 @@ func NewRedisKeysTool(tc *tools.ToolContext) tool.InvokableTool {
 			port = 6379
 		}
 
+		p := tc.ServicePolicy(policy.KindRedis, input.Host, port)
+		if v := p.CheckRedis("SCAN", nil); v != nil {
+			return tc.Refuse("redis_keys", v), nil
+		}
+
 		limit := input.Limit
 		if limit <= 0 {
 			limit = 100
 		}
+		limit = p.MaxKeys(limit)
 
 		// Create Redis client
 		rdb := redis.NewClient(&redis.Options{
@@ -128,7 +139,8 @@ func NewRedisKeysTool(tc *tools.ToolContext) tool.InvokableTool {
 				return fmt.Sprintf("Error: scan failed: %v", err), nil
 			}
 
-			keys = append(keys, batch...)
+			// Keys outside the allowed patterns are never shown
+			keys = append(keys, p.FilterKeys(batch)...)
 			if len(keys) >= limit || cursor == 0 {
 				break
 			}
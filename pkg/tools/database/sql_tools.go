@@ -18,6 +18,7 @@ import (
 	_ "github.com/lib/pq"
 
 	"github.com/choraleia/choraleia/pkg/tools"
+	"github.com/choraleia/choraleia/pkg/tools/policy"
 )
 
 func init() {
@@ -134,10 +135,16 @@ func NewMySQLQueryTool(tc *tools.ToolContext) tool.InvokableTool {
 			port = 3306
 		}
 
+		p := tc.ServicePolicy(policy.KindDatabase, input.Host, port)
+		if v := p.CheckSQL(input.Database, input.Query); v != nil {
+			return tc.Refuse("mysql_query", v), nil
+		}
+
 		limit := input.Limit
 		if limit <= 0 {
 			limit = 100
 		}
+		limit = p.MaxRows(limit)
 
 		// Connect to MySQL
 		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&timeout=10s",
@@ -170,9 +177,14 @@ func NewMySQLQueryTool(tc *tools.ToolContext) tool.InvokableTool {
 			return fmt.Sprintf("Error: failed to get columns: %v", err), nil
 		}
 
-		// Fetch results
+		// Fetch results, stopping at the limit even if the query set its own
 		results := make([]map[string]interface{}, 0)
+		truncated := false
 		for rows.Next() {
+			if len(results) >= limit {
+				truncated = true
+				break
+			}
 			values := make([]interface{}, len(columns))
 			valuePtrs := make([]interface{}, len(columns))
 			for i := range columns {
@@ -203,6 +215,9 @@ func NewMySQLQueryTool(tc *tools.ToolContext) tool.InvokableTool {
 			"rows":     len(results),
 			"data":     results,
 		}
+		if truncated {
+			output["truncated"] = true
+		}
 
 		data, _ := json.MarshalIndent(output, "", "  ")
 		return string(data), nil
@@ -236,6 +251,10 @@ func NewMySQLExecuteTool(tc *tools.ToolContext) tool.InvokableTool {
 			port = 3306
 		}
 
+		if v := tc.ServicePolicy(policy.KindDatabase, input.Host, port).CheckSQL(input.Database, input.Statement); v != nil {
+			return tc.Refuse("mysql_execute", v), nil
+		}
+
 		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&timeout=10s",
 			input.Username, input.Password, input.Host, port, input.Database)
 
@@ -292,6 +311,10 @@ func NewMySQLSchemaTool(tc *tools.ToolContext) tool.InvokableTool {
 			port = 3306
 		}
 
+		if v := tc.ServicePolicy(policy.KindDatabase, input.Host, port).CheckTable(input.Database, input.Table); v != nil {
+			return tc.Refuse("mysql_schema", v), nil
+		}
+
 		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&timeout=10s",
 			input.Username, input.Password, input.Host, port, input.Database)
 
@@ -390,10 +413,16 @@ func NewPostgresQueryTool(tc *tools.ToolContext) tool.InvokableTool {
 			port = 5432
 		}
 
+		p := tc.ServicePolicy(policy.KindDatabase, input.Host, port)
+		if v := p.CheckSQL(input.Database, input.Query); v != nil {
+			return tc.Refuse("postgres_query", v), nil
+		}
+
 		limit := input.Limit
 		if limit <= 0 {
 			limit = 100
 		}
+		limit = p.MaxRows(limit)
 
 		sslMode := input.SSLMode
 		if sslMode == "" {
@@ -424,8 +453,13 @@ func NewPostgresQueryTool(tc *tools.ToolContext) tool.InvokableTool {
 
 		columns, _ := rows.Columns()
 		results := make([]map[string]interface{}, 0)
+		truncated := false
 
 		for rows.Next() {
+			if len(results) >= limit {
+				truncated = true
+				break
+			}
 			values := make([]interface{}, len(columns))
 			valuePtrs := make([]interface{}, len(columns))
 			for i := range columns {
@@ -455,6 +489,9 @@ func NewPostgresQueryTool(tc *tools.ToolContext) tool.InvokableTool {
 			"rows":     len(results),
 			"data":     results,
 		}
+		if truncated {
+			output["truncated"] = true
+		}
 
 		data, _ := json.MarshalIndent(output, "", "  ")
 		return string(data), nil
@@ -490,6 +527,10 @@ func NewPostgresExecuteTool(tc *tools.ToolContext) tool.InvokableTool {
 			port = 5432
 		}
 
+		if v := tc.ServicePolicy(policy.KindDatabase, input.Host, port).CheckSQL(input.Database, input.Statement); v != nil {
+			return tc.Refuse("postgres_execute", v), nil
+		}
+
 		sslMode := input.SSLMode
 		if sslMode == "" {
 			sslMode = "disable"
@@ -553,6 +594,10 @@ func NewPostgresSchemaTool(tc *tools.ToolContext) tool.InvokableTool {
 			port = 5432
 		}
 
+		if v := tc.ServicePolicy(policy.KindDatabase, input.Host, port).CheckTable(input.Database, input.Table); v != nil {
+			return tc.Refuse("postgres_schema", v), nil
+		}
+
 		sslMode := input.SSLMode
 		if sslMode == "" {
 			sslMode = "disable"
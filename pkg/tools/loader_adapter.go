@@ -8,24 +8,34 @@ import (
 	"log/slog"
 
 	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service"
+	"github.com/choraleia/choraleia/pkg/service/mcp"
 	"github.com/choraleia/choraleia/pkg/utils"
 	"github.com/cloudwego/eino/components/tool"
 )
 
 // ToolLoaderAdapter implements the ToolLoader interface from ChatService
 type ToolLoaderAdapter struct {
+	toolCtx        *ToolContext
 	builtinService *BuiltinToolsService
+	toolManager    *service.ToolManager
 	logger         *slog.Logger
 }
 
 // NewToolLoaderAdapter creates a new tool loader adapter
 func NewToolLoaderAdapter(ctx *ToolContext) *ToolLoaderAdapter {
 	return &ToolLoaderAdapter{
+		toolCtx:        ctx,
 		builtinService: NewBuiltinToolsService(ctx),
 		logger:         utils.GetLogger(),
 	}
 }
 
+// SetToolManager sets the tool manager that owns MCP server connections
+func (a *ToolLoaderAdapter) SetToolManager(tm *service.ToolManager) {
+	a.toolManager = tm
+}
+
 // LoadWorkspaceTools loads tools configured for a workspace
 // This implements the ToolLoader interface from ChatService
 func (a *ToolLoaderAdapter) LoadWorkspaceTools(
@@ -45,6 +55,8 @@ func (a *ToolLoaderAdapter) LoadWorkspaceTools(
 	}
 
 	var tools []tool.InvokableTool
+	// Names of script tools, which are not prefixed like MCP/OpenAPI tools
+	scriptNames := make(map[string]bool)
 
 	for _, cfg := range toolConfigs {
 		a.logger.Debug("Processing tool config",
@@ -75,13 +87,42 @@ func (a *ToolLoaderAdapter) LoadWorkspaceTools(
 			tools = append(tools, builtinTools...)
 
 		case models.ToolTypeMCPStdio, models.ToolTypeMCPSSE, models.ToolTypeMCPHTTP:
-			a.logger.Warn("MCP tools not yet supported", "toolName", cfg.Name, "type", cfg.Type)
+			mcpTools, err := a.loadMCPTools(ctx, workspaceID, cfg)
+			if err != nil {
+				a.logger.Warn("Failed to load MCP tools",
+					"toolName", cfg.Name,
+					"type", cfg.Type,
+					"error", err)
+				continue
+			}
+			a.logger.Info("Loaded MCP tools",
+				"toolName", cfg.Name,
+				"count", len(mcpTools))
+			tools = append(tools, mcpTools...)
 
 		case models.ToolTypeOpenAPI:
-			a.logger.Warn("OpenAPI tools not yet supported", "toolName", cfg.Name)
+			openAPITools, err := a.loadOpenAPITools(ctx, workspaceID, cfg)
+			if err != nil {
+				a.logger.Warn("Failed to load OpenAPI tools",
+					"toolName", cfg.Name,
+					"error", err)
+				continue
+			}
+			a.logger.Info("Loaded OpenAPI tools",
+				"toolName", cfg.Name,
+				"count", len(openAPITools))
+			tools = append(tools, openAPITools...)
 
 		case models.ToolTypeScript:
-			a.logger.Warn("Script tools not yet supported", "toolName", cfg.Name)
+			scriptTool, err := a.loadScriptTool(workspaceID, cfg, scriptNames)
+			if err != nil {
+				a.logger.Warn("Failed to load script tool",
+					"toolName", cfg.Name,
+					"error", err)
+				continue
+			}
+			a.logger.Info("Loaded script tool", "toolName", cfg.Name)
+			tools = append(tools, scriptTool)
 
 		case models.ToolTypeBrowserService:
 			a.logger.Warn("Browser service tools not yet supported", "toolName", cfg.Name)
@@ -98,6 +139,19 @@ func (a *ToolLoaderAdapter) LoadWorkspaceTools(
 	return tools, nil
 }
 
+// IsDangerousTool reports whether a loaded tool can modify data. Tools report
+// it themselves: built-in tools from their registered definition, MCP,
+// OpenAPI and script tools from what they know of their calls. Tools that
+// don't are treated as dangerous; the name is not looked up, as an external
+// tool may share a built-in tool's name.
+// This implements the ToolLoader interface from ChatService
+func (a *ToolLoaderAdapter) IsDangerousTool(t tool.InvokableTool, name string) bool {
+	if d, ok := t.(interface{ Dangerous() bool }); ok {
+		return d.Dangerous()
+	}
+	return true
+}
+
 // loadBuiltinTools loads built-in tools based on configuration
 func (a *ToolLoaderAdapter) loadBuiltinTools(
 	ctx context.Context,
@@ -145,6 +199,93 @@ func (a *ToolLoaderAdapter) loadBuiltinTools(
 	return a.builtinService.CreateToolsForWorkspace(ctx, workspaceID, conversationID, toolIDs, options)
 }
 
+// loadMCPTools connects to (or reuses) the MCP server behind a workspace tool
+// and wraps every tool it exposes
+func (a *ToolLoaderAdapter) loadMCPTools(
+	ctx context.Context,
+	workspaceID string,
+	cfg models.WorkspaceTool,
+) ([]tool.InvokableTool, error) {
+	if a.toolManager == nil {
+		return nil, fmt.Errorf("tool manager not configured")
+	}
+
+	workspace, err := a.getWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the client on every call so tools survive reconnects
+	getClient := func(ctx context.Context) (*mcp.Client, error) {
+		return a.toolManager.GetMCPClient(ctx, workspace, &cfg)
+	}
+
+	client, err := getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := client.Tools()
+	result := make([]tool.InvokableTool, 0, len(defs))
+	for _, def := range defs {
+		result = append(result, newMCPTool(def, cfg.Name, getClient))
+	}
+	return result, nil
+}
+
+// loadOpenAPITools wraps every operation of a workspace tool's OpenAPI spec
+func (a *ToolLoaderAdapter) loadOpenAPITools(
+	ctx context.Context,
+	workspaceID string,
+	cfg models.WorkspaceTool,
+) ([]tool.InvokableTool, error) {
+	if a.toolManager == nil {
+		return nil, fmt.Errorf("tool manager not configured")
+	}
+
+	workspace, err := a.getWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	svc, err := a.toolManager.GetOpenAPIService(ctx, workspace, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]tool.InvokableTool, 0, len(svc.Document.Operations))
+	for _, op := range svc.Document.Operations {
+		result = append(result, newOpenAPITool(svc, op, cfg.Name))
+	}
+	return result, nil
+}
+
+// loadScriptTool wraps a script workspace tool; taken holds the script tool
+// names already in use
+func (a *ToolLoaderAdapter) loadScriptTool(workspaceID string, cfg models.WorkspaceTool, taken map[string]bool) (tool.InvokableTool, error) {
+	if a.toolManager == nil {
+		return nil, fmt.Errorf("tool manager not configured")
+	}
+
+	workspace, err := a.getWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return newScriptTool(a.toolManager, workspace, cfg, scriptToolName(cfg.Name, taken))
+}
+
+// getWorkspace looks up the workspace for tools that need its runtime (nil if unavailable)
+func (a *ToolLoaderAdapter) getWorkspace(workspaceID string) (*models.Workspace, error) {
+	if a.toolCtx == nil || a.toolCtx.WorkspaceGetter == nil {
+		return nil, nil
+	}
+	workspace, err := a.toolCtx.WorkspaceGetter.GetWorkspace(workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	return workspace, nil
+}
+
 // parseBuiltinConfig parses the config map into BuiltinConfig struct
 func (a *ToolLoaderAdapter) parseBuiltinConfig(config models.JSONMap) (*models.BuiltinConfig, error) {
 	// Check for nested "builtin" key first (frontend format)
@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/choraleia/choraleia/pkg/service/mcp"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// namedTool is a tool that only has a name
+type namedTool string
+
+func (t namedTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: string(t)}, nil
+}
+
+func (t namedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return "ok", nil
+}
+
+func TestIsDangerousTool(t *testing.T) {
+	for _, def := range []ToolDefinition{
+		{ID: "gate_test_write", Dangerous: true, Scope: ScopeWorkspace},
+		{ID: "gate_test_read", Scope: ScopeWorkspace},
+	} {
+		id := def.ID
+		Register(def, func(*ToolContext) tool.InvokableTool { return namedTool(id) })
+	}
+	builtins, err := NewBuiltinToolsService(&ToolContext{}).CreateToolsForWorkspace(context.Background(), "w", "c",
+		[]string{"gate_test_write", "gate_test_read"}, nil)
+	if err != nil || len(builtins) != 2 {
+		t.Fatalf("builtins = %v, %v", builtins, err)
+	}
+
+	readOnly := true
+	a := &ToolLoaderAdapter{}
+	tests := []struct {
+		name      string
+		tool      tool.InvokableTool
+		dangerous bool
+	}{
+		{"dangerous builtin", builtins[0], true},
+		{"safe builtin", builtins[1], false},
+		// External tools named like built-ins keep their own flag
+		{"external named like a safe builtin", newMCPTool(mcp.Tool{Name: "read"}, "gate_test", nil), true},
+		{"read-only external named like a dangerous builtin",
+			newMCPTool(mcp.Tool{Name: "write", Annotations: &mcp.ToolAnnotations{ReadOnlyHint: &readOnly}}, "gate_test", nil), false},
+		{"unknown tool named like a safe builtin", namedTool("gate_test_read"), true},
+	}
+	for _, tt := range tests {
+		info, err := tt.tool.Info(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.IsDangerousTool(tt.tool, info.Name); got != tt.dangerous {
+			t.Errorf("%s (%s): dangerous = %v, want %v", tt.name, info.Name, got, tt.dangerous)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/choraleia/choraleia/pkg/service/mcp"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+)
+
+// toolNameSanitizer strips characters that model APIs reject in tool names
+var toolNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// maxToolNameLength is the tool name limit enforced by most model providers
+const maxToolNameLength = 64
+
+// qualifiedToolName builds "<prefix>_<name>" so tools from different servers don't collide
+func qualifiedToolName(prefix, name string) string {
+	return capToolName(toolNameSanitizer.ReplaceAllString(prefix, "_") + "_" + toolNameSanitizer.ReplaceAllString(name, "_"))
+}
+
+// capToolName cuts a sanitized name to maxToolNameLength. A cut name ends in
+// a hash of the whole one, so long names sharing a prefix stay distinct.
+func capToolName(name string) string {
+	if len(name) <= maxToolNameLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(sum[:4])
+	return name[:maxToolNameLength-len(suffix)-1] + "_" + suffix
+}
+
+// paramsFromJSONSchema converts a raw JSON schema into eino tool parameters.
+// An empty or invalid schema yields a tool without declared parameters.
+func paramsFromJSONSchema(raw json.RawMessage) *schema.ParamsOneOf {
+	if len(raw) == 0 {
+		return nil
+	}
+	var js jsonschema.Schema
+	if err := json.Unmarshal(raw, &js); err != nil {
+		return nil
+	}
+	return schema.NewParamsOneOfByJSONSchema(&js)
+}
+
+// mcpTool exposes a single MCP server tool as an eino InvokableTool
+type mcpTool struct {
+	def       mcp.Tool
+	name      string
+	getClient func(ctx context.Context) (*mcp.Client, error)
+}
+
+// newMCPTool wraps an MCP tool definition; prefix is the workspace tool name.
+// getClient returns the current connection to the server.
+func newMCPTool(def mcp.Tool, prefix string, getClient func(ctx context.Context) (*mcp.Client, error)) tool.InvokableTool {
+	return &mcpTool{
+		def:       def,
+		name:      qualifiedToolName(prefix, def.Name),
+		getClient: getClient,
+	}
+}
+
+func (t *mcpTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	desc := t.def.Description
+	if desc == "" {
+		desc = fmt.Sprintf("MCP tool %s", t.def.Name)
+	}
+	return &schema.ToolInfo{
+		Name:        t.name,
+		Desc:        desc,
+		ParamsOneOf: paramsFromJSONSchema(t.def.InputSchema),
+	}, nil
+}
+
+// Dangerous trusts the server's read-only hint; other tools may modify data
+func (t *mcpTool) Dangerous() bool {
+	a := t.def.Annotations
+	return a == nil || a.ReadOnlyHint == nil || !*a.ReadOnlyHint
+}
+
+func (t *mcpTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args json.RawMessage
+	if argumentsInJSON != "" {
+		args = json.RawMessage(argumentsInJSON)
+	}
+
+	client, err := t.getClient(ctx)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	result, err := client.CallTool(ctx, t.def.Name, args)
+	if err != nil {
+		// Return error as result so AI can see it and handle accordingly
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	text := result.Text()
+	if result.IsError {
+		return "Error: " + text, nil
+	}
+	return text, nil
+}
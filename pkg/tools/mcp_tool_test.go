@@ -0,0 +1,25 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQualifiedToolName(t *testing.T) {
+	if got := qualifiedToolName("my server", "get.item"); got != "my_server_get_item" {
+		t.Errorf("sanitized name = %q", got)
+	}
+
+	long := strings.Repeat("x", 80)
+	a := qualifiedToolName("api", long+"_list_users")
+	b := qualifiedToolName("api", long+"_list_groups")
+	if len(a) != maxToolNameLength || len(b) != maxToolNameLength {
+		t.Errorf("lengths %d, %d, want %d", len(a), len(b), maxToolNameLength)
+	}
+	if a == b {
+		t.Errorf("long names sharing a prefix collide: %q", a)
+	}
+	if a != qualifiedToolName("api", long+"_list_users") {
+		t.Error("capped name is not stable")
+	}
+}
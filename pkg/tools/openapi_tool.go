@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/choraleia/choraleia/pkg/service"
+	"github.com/choraleia/choraleia/pkg/service/openapi"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// maxOpenAPIDescLength keeps operation descriptions from bloating the tool list
+const maxOpenAPIDescLength = 1024
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// openAPITool exposes a single OpenAPI operation as an eino InvokableTool
+type openAPITool struct {
+	svc  *service.OpenAPIService
+	op   *openapi.Operation
+	name string
+}
+
+// newOpenAPITool wraps an operation; prefix is the workspace tool name
+func newOpenAPITool(svc *service.OpenAPIService, op *openapi.Operation, prefix string) tool.InvokableTool {
+	return &openAPITool{
+		svc:  svc,
+		op:   op,
+		name: qualifiedToolName(prefix, op.ID),
+	}
+}
+
+func (t *openAPITool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	var parts []string
+	if t.op.Summary != "" {
+		parts = append(parts, t.op.Summary)
+	}
+	if t.op.Description != "" && t.op.Description != t.op.Summary {
+		parts = append(parts, t.op.Description)
+	}
+	desc := strings.Join(parts, "\n")
+	if len(desc) > maxOpenAPIDescLength {
+		desc = truncateUTF8(desc, maxOpenAPIDescLength) + "..."
+	}
+	desc = strings.TrimSpace(fmt.Sprintf("%s\n\nHTTP %s %s", desc, t.op.Method, t.op.Path))
+	if t.op.Deprecated {
+		desc += " (deprecated)"
+	}
+
+	raw, err := json.Marshal(t.op.InputSchema())
+	if err != nil {
+		return nil, err
+	}
+	return &schema.ToolInfo{
+		Name:        t.name,
+		Desc:        desc,
+		ParamsOneOf: paramsFromJSONSchema(raw),
+	}, nil
+}
+
+// Dangerous reports whether the operation uses a method that can modify data
+func (t *openAPITool) Dangerous() bool {
+	switch t.op.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return false
+	}
+	return true
+}
+
+func (t *openAPITool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	args := make(map[string]interface{})
+	if strings.TrimSpace(argumentsInJSON) != "" {
+		if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+			return fmt.Sprintf("Error: invalid arguments: %v", err), nil
+		}
+	}
+
+	resp, err := t.svc.Client.Call(ctx, t.op, args)
+	if err != nil {
+		// Return error as result so AI can see it and handle accordingly
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	var sb strings.Builder
+	if resp.StatusCode >= 400 {
+		sb.WriteString("Error: ")
+	}
+	sb.WriteString(fmt.Sprintf("HTTP %s\n", resp.Status))
+	sb.Write(resp.Body)
+	if resp.Truncated {
+		sb.WriteString("\n... (response truncated)")
+	}
+	return sb.String(), nil
+}
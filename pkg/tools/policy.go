@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"fmt"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/tools/policy"
+	"github.com/choraleia/choraleia/pkg/utils"
+)
+
+// AssetPolicy returns the workspace restrictions for an asset. It returns nil,
+// which allows everything, outside a workspace or for unreferenced assets. If
+// the workspace can't be loaded everything on the asset is refused.
+func (c *ToolContext) AssetPolicy(assetID string) *policy.Policy {
+	if assetID == "" || c.WorkspaceID == "" || c.WorkspaceGetter == nil {
+		return nil
+	}
+	workspace, err := c.loadWorkspace()
+	if err != nil {
+		return policy.Unavailable(assetID, policy.KindTerminal, err)
+	}
+	for i := range workspace.Assets {
+		if workspace.Assets[i].AssetID == assetID {
+			return policy.New(&workspace.Assets[i])
+		}
+	}
+	return nil
+}
+
+// ServicePolicy returns the restrictions for a database or Redis server the
+// model addresses by host and port, matched against the workspace's asset
+// references of that kind. Servers matching none are refused while any of
+// them is restricted, and every server when the workspace can't be loaded.
+func (c *ToolContext) ServicePolicy(kind, host string, port int) *policy.Policy {
+	if c.WorkspaceID == "" || c.WorkspaceGetter == nil || c.AssetService == nil {
+		return nil
+	}
+	workspace, err := c.loadWorkspace()
+	if err != nil {
+		return policy.Unavailable("", kind, err)
+	}
+	return policy.ForService(kind, host, port, workspace.Assets, c.GetAsset)
+}
+
+// loadWorkspace returns the current workspace, reporting a missing one as an error
+func (c *ToolContext) loadWorkspace() (*models.Workspace, error) {
+	workspace, err := c.GetWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	if workspace == nil {
+		return nil, fmt.Errorf("workspace %s not found", c.WorkspaceID)
+	}
+	return workspace, nil
+}
+
+// Refuse logs a policy violation and formats it as the tool result
+func (c *ToolContext) Refuse(toolName string, v *policy.Violation) string {
+	c.LogViolation(toolName, v)
+	return v.Refusal()
+}
+
+// LogViolation records a refused tool call
+func (c *ToolContext) LogViolation(toolName string, v *policy.Violation) {
+	utils.GetLogger().Warn("Tool call refused by asset restrictions",
+		"tool", toolName,
+		"workspaceID", c.WorkspaceID,
+		"conversationID", c.ConversationID,
+		"assetID", v.AssetID,
+		"rule", v.Rule,
+		"subject", v.Subject,
+		"reason", v.Reason)
+}
@@ -0,0 +1,406 @@
+package policy
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// wrapperCommands run the command that follows them, so the wrapped command
+// is what the allow and block lists are checked against
+var wrapperCommands = map[string]bool{
+	"sudo": true, "doas": true, "env": true, "nohup": true, "time": true,
+	"nice": true, "exec": true, "command": true, "builtin": true,
+	"xargs": true, "timeout": true, "stdbuf": true,
+}
+
+// sudoCommands escalate privileges
+var sudoCommands = map[string]bool{"sudo": true, "doas": true, "su": true, "pkexec": true}
+
+// shellCommands take a nested command line via -c
+var shellCommands = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "ash": true, "fish": true}
+
+// sudoOptionsWithArg are sudo flags that consume the next word
+var sudoOptionsWithArg = map[string]bool{"-u": true, "-g": true, "-U": true, "-C": true, "-p": true, "-r": true, "-t": true, "-h": true, "-D": true}
+
+// envVarRef matches $NAME and ${NAME} references
+var envVarRef = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// maxCommandDepth bounds recursion into sh -c and eval
+const maxCommandDepth = 4
+
+// CheckCommand checks a shell command line against the command, sudo,
+// environment and path restrictions. Pipelines, command lists and command
+// substitutions are split so each command is checked on its own. Paths are
+// checked on a best-effort basis: only absolute and home-relative arguments
+// are inspected.
+func (p *Policy) CheckCommand(command string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.invalid(); v != nil {
+		return v
+	}
+	if v := p.checkEnvRefs(command); v != nil {
+		return v
+	}
+	return p.checkCommandLine(command, 0)
+}
+
+func (p *Policy) checkCommandLine(command string, depth int) *Violation {
+	for _, segment := range splitCommands(command) {
+		words := shellWords(segment)
+		if len(words) == 0 {
+			continue
+		}
+		if v := p.checkSimpleCommand(words, depth); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// checkSimpleCommand checks a single command given as shell words
+func (p *Policy) checkSimpleCommand(words []string, depth int) *Violation {
+	// Leading VAR=value assignments don't change which program runs
+	for len(words) > 0 && isAssignment(words[0]) {
+		words = words[1:]
+	}
+
+	usesSudo := false
+	for len(words) > 0 {
+		name := path.Base(words[0])
+		if sudoCommands[name] {
+			usesSudo = true
+		}
+		if !wrapperCommands[name] {
+			break
+		}
+		// A wrapper on its own (plain "env" or "sudo") is the program itself
+		rest := skipWrapperArgs(name, words[1:])
+		if len(rest) == 0 {
+			break
+		}
+		words = rest
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	program := path.Base(words[0])
+	line := strings.Join(append([]string{program}, words[1:]...), " ")
+	if usesSudo || sudoCommands[program] {
+		if v := p.checkSudo(line); v != nil {
+			return v
+		}
+	}
+
+	for _, pattern := range p.terminal.BlockedCommands {
+		if matchCommand(pattern, program, words[0], line) {
+			return p.violation("blocked_command", line, "command matches blocked pattern %q", pattern)
+		}
+	}
+	if len(p.terminal.AllowedCommands) > 0 {
+		allowed := false
+		for _, pattern := range p.terminal.AllowedCommands {
+			if matchCommand(pattern, program, words[0], line) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return p.violation("command_not_allowed", line, "%s is not in the allowed commands: %s", program, strings.Join(p.terminal.AllowedCommands, ", "))
+		}
+	}
+
+	if v := p.checkEnvDump(program, words[1:]); v != nil {
+		return v
+	}
+	if dockerCommands[program] {
+		if v := p.checkDocker(program, words[1:], line); v != nil {
+			return v
+		}
+	}
+
+	for _, arg := range words[1:] {
+		for _, candidate := range pathCandidates(arg) {
+			if v := p.CheckPath(candidate); v != nil {
+				v.Subject = line
+				return v
+			}
+		}
+	}
+
+	// Commands handed to a nested shell are checked like top-level ones
+	if depth < maxCommandDepth {
+		if shellCommands[program] {
+			for i, arg := range words[1:] {
+				if arg == "-c" && i+2 < len(words) {
+					return p.checkCommandLine(words[i+2], depth+1)
+				}
+			}
+		}
+		if program == "eval" {
+			return p.checkCommandLine(strings.Join(words[1:], " "), depth+1)
+		}
+	}
+	return nil
+}
+
+// checkSudo refuses privilege escalation when sudo is disabled
+func (p *Policy) checkSudo(line string) *Violation {
+	if p.allowSudo != nil && !*p.allowSudo {
+		return p.violation("sudo_not_allowed", line, "privilege escalation (sudo, su, doas) is disabled for this asset")
+	}
+	return nil
+}
+
+// checkEnvRefs checks $VAR references against the environment restrictions
+func (p *Policy) checkEnvRefs(command string) *Violation {
+	if len(p.terminal.AllowedEnvVars) == 0 && len(p.terminal.BlockedEnvVars) == 0 {
+		return nil
+	}
+	for _, m := range envVarRef.FindAllStringSubmatch(command, -1) {
+		if v := p.checkEnvVar(m[1]); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// checkEnvVar checks a single variable name
+func (p *Policy) checkEnvVar(name string) *Violation {
+	for _, pattern := range p.terminal.BlockedEnvVars {
+		if matchGlob(pattern, name) {
+			return p.violation("blocked_env_var", name, "environment variable %s is blocked", name)
+		}
+	}
+	if len(p.terminal.AllowedEnvVars) == 0 {
+		return nil
+	}
+	for _, pattern := range p.terminal.AllowedEnvVars {
+		if matchGlob(pattern, name) {
+			return nil
+		}
+	}
+	return p.violation("env_var_not_allowed", name, "environment variable %s is not in the allowed list", name)
+}
+
+// checkEnvDump refuses commands that print the whole environment while
+// variables are restricted, and checks variables named to printenv
+func (p *Policy) checkEnvDump(program string, args []string) *Violation {
+	if len(p.terminal.AllowedEnvVars) == 0 && len(p.terminal.BlockedEnvVars) == 0 {
+		return nil
+	}
+	switch program {
+	case "printenv":
+		for _, name := range args {
+			if !strings.HasPrefix(name, "-") {
+				if v := p.checkEnvVar(name); v != nil {
+					return v
+				}
+			}
+		}
+		if len(args) > 0 {
+			return nil
+		}
+	case "env", "set":
+		if len(args) > 0 {
+			return nil
+		}
+	case "export", "declare":
+		if len(args) > 1 || len(args) == 1 && args[0] != "-p" {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return p.violation("blocked_env_var", program, "printing the whole environment is not allowed while environment variables are restricted")
+}
+
+// matchCommand matches a command pattern. Bare names match the program;
+// patterns with spaces or wildcards match the whole normalized command line.
+func matchCommand(pattern, program, invoked, line string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return false
+	}
+	if !strings.HasPrefix(pattern, "re:") && !strings.ContainsAny(pattern, " *?") {
+		return strings.EqualFold(pattern, program) || pattern == invoked
+	}
+	return matchGlob(pattern, line)
+}
+
+// skipWrapperArgs drops a wrapper's own options so the wrapped command is next
+func skipWrapperArgs(wrapper string, args []string) []string {
+	for len(args) > 0 {
+		arg := args[0]
+		switch {
+		case wrapper == "env" && isAssignment(arg):
+			args = args[1:]
+		case arg == "--":
+			return args[1:]
+		case strings.HasPrefix(arg, "-"):
+			args = args[1:]
+			if (wrapper == "sudo" && sudoOptionsWithArg[arg]) || (wrapper == "nice" && arg == "-n") ||
+				(wrapper == "timeout" && (arg == "-s" || arg == "-k")) || (wrapper == "xargs" && (arg == "-I" || arg == "-n" || arg == "-P")) {
+				if len(args) > 0 {
+					args = args[1:]
+				}
+			}
+		case wrapper == "timeout":
+			// The first positional argument is the duration
+			return args[1:]
+		default:
+			return args
+		}
+	}
+	return args
+}
+
+// isAssignment reports whether w is a NAME=value word
+func isAssignment(w string) bool {
+	i := strings.IndexByte(w, '=')
+	if i <= 0 {
+		return false
+	}
+	for j, r := range w[:i] {
+		if !(r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || j > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// pathCandidates extracts absolute or home-relative paths from an argument,
+// including redirection targets and --opt=value forms
+func pathCandidates(arg string) []string {
+	arg = strings.TrimLeft(arg, "0123456789&<>|")
+	var out []string
+	if i := strings.IndexByte(arg, '='); i >= 0 {
+		if v := arg[i+1:]; isAbsPath(v) {
+			out = append(out, v)
+		}
+	}
+	if isAbsPath(arg) {
+		out = append(out, arg)
+	}
+	return out
+}
+
+// splitCommands splits a command line into simple commands on ; & | and
+// newlines, treating $(...) and backtick substitutions as separate commands.
+// Single-quoted text is never split.
+func splitCommands(s string) []string {
+	var (
+		out      []string
+		cur      strings.Builder
+		single   bool
+		double   bool
+		backtick bool
+		// outer double-quote state for each open $( substitution
+		substs []bool
+	)
+	flush := func() {
+		if t := strings.TrimSpace(cur.String()); t != "" {
+			out = append(out, t)
+		}
+		cur.Reset()
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case single:
+			if c == '\'' {
+				single = false
+			}
+			cur.WriteByte(c)
+		case c == '\\' && i+1 < len(s):
+			cur.WriteByte(c)
+			cur.WriteByte(s[i+1])
+			i++
+		case c == '\'' && !double:
+			single = true
+			cur.WriteByte(c)
+		case c == '"':
+			double = !double
+			cur.WriteByte(c)
+		case strings.HasPrefix(s[i:], "$(("):
+			// Arithmetic expansion holds no commands
+			end := strings.Index(s[i:], "))")
+			if end < 0 {
+				end = len(s) - i - 2
+			}
+			cur.WriteString(s[i : i+end+2])
+			i += end + 1
+		case c == '$' && i+1 < len(s) && s[i+1] == '(':
+			flush()
+			substs = append(substs, double)
+			double = false
+			i++
+		case c == ')' && len(substs) > 0:
+			flush()
+			double = substs[len(substs)-1]
+			substs = substs[:len(substs)-1]
+		case c == '`':
+			flush()
+			backtick = !backtick
+		case double:
+			cur.WriteByte(c)
+		case c == '&' && (i > 0 && (s[i-1] == '>' || s[i-1] == '<') || i+1 < len(s) && s[i+1] == '>'):
+			// Redirections like 2>&1 and &>file
+			cur.WriteByte(c)
+		case c == ';' || c == '&' || c == '|' || c == '\n' || c == '(' || c == ')':
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return out
+}
+
+// shellWords splits a simple command into words, removing quotes
+func shellWords(s string) []string {
+	var (
+		words   []string
+		cur     strings.Builder
+		inWord  bool
+		quote   byte
+		escaped bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			cur.WriteByte(c)
+			escaped = false
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' && i+1 < len(s) {
+				i++
+				cur.WriteByte(s[i])
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '\\':
+			escaped, inWord = true, true
+		case c == '\'' || c == '"':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t' || c == '\r':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words
+}
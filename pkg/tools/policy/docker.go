@@ -0,0 +1,249 @@
+package policy
+
+import (
+	"strings"
+)
+
+// dockerCommands are the CLIs whose subcommands the Docker restrictions cover
+var dockerCommands = map[string]bool{"docker": true, "podman": true, "nerdctl": true, "docker-compose": true}
+
+// dockerContainerCommands take a container as their first argument
+var dockerContainerCommands = map[string]bool{
+	"attach": true, "commit": true, "cp": true, "diff": true, "exec": true, "export": true,
+	"inspect": true, "kill": true, "logs": true, "pause": true, "port": true, "rename": true,
+	"restart": true, "rm": true, "start": true, "stats": true, "stop": true, "top": true,
+	"unpause": true, "update": true, "wait": true,
+}
+
+// dockerSingleContainerCommands take one container, followed by other arguments
+var dockerSingleContainerCommands = map[string]bool{
+	"attach": true, "commit": true, "diff": true, "exec": true, "export": true, "logs": true,
+	"port": true, "rename": true, "top": true, "update": true,
+}
+
+// dockerOptionsWithArg are global and run/create flags that consume the next word
+var dockerOptionsWithArg = map[string]bool{
+	"-H": true, "--host": true, "--context": true, "-c": true, "--config": true, "-l": true, "--log-level": true,
+	"-e": true, "--env": true, "--env-file": true, "--name": true, "-p": true, "--publish": true,
+	"-u": true, "--user": true, "-w": true, "--workdir": true, "--entrypoint": true, "--label": true,
+	"--restart": true, "--platform": true, "--hostname": true, "-m": true, "--memory": true, "--cpus": true,
+	"-v": true, "--volume": true, "--mount": true, "--volumes-from": true, "--net": true, "--network": true,
+	"--cap-add": true, "--device": true, "--pid": true, "--ipc": true, "--userns": true,
+	"--tail": true, "--since": true, "--until": true, "--time": true, "--signal": true, "-s": true,
+	"--format": true, "--filter": true, "-f": true, "--file": true, "--project-name": true,
+}
+
+// CheckContainer checks opening a shell in a container of a Docker host
+func (p *Policy) CheckContainer(container string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.invalid(); v != nil {
+		return v
+	}
+	if container == "" {
+		return nil
+	}
+	if disabled(p.docker.AllowContainerExec) {
+		return p.violation("container_exec_not_allowed", container, "running commands in containers of this Docker host is disabled")
+	}
+	return p.checkContainerName(container)
+}
+
+// checkContainerName checks a container name or ID against the container lists
+func (p *Policy) checkContainerName(container string) *Violation {
+	d := &p.docker
+	for _, pattern := range d.BlockedContainers {
+		if matchGlob(pattern, container) {
+			return p.violation("blocked_container", container, "container matches blocked pattern %q", pattern)
+		}
+	}
+	if len(d.AllowedContainers) == 0 {
+		return nil
+	}
+	for _, pattern := range d.AllowedContainers {
+		if matchGlob(pattern, container) {
+			return nil
+		}
+	}
+	return p.violation("container_not_allowed", container, "%s is not in the allowed containers: %s", container, strings.Join(d.AllowedContainers, ", "))
+}
+
+// checkDocker checks a docker CLI invocation against the Docker restrictions
+func (p *Policy) checkDocker(program string, args []string, line string) *Violation {
+	args = skipDockerOptions(args)
+	if program == "docker-compose" {
+		args = append([]string{"compose"}, args...)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	sub, rest := dockerSubcommand(args)
+
+	d := &p.docker
+	switch sub {
+	case "run", "create", "up":
+		if disabled(d.AllowContainerCreate) {
+			return p.violation("container_create_not_allowed", line, "creating containers on this Docker host is disabled")
+		}
+		if sub != "up" {
+			return p.checkDockerRun(rest, line)
+		}
+	case "rm", "prune", "down":
+		if disabled(d.AllowContainerDelete) {
+			return p.violation("container_delete_not_allowed", line, "deleting containers on this Docker host is disabled")
+		}
+	case "exec", "attach":
+		if disabled(d.AllowContainerExec) {
+			return p.violation("container_exec_not_allowed", line, "running commands in containers of this Docker host is disabled")
+		}
+	case "pull", "build", "load", "import":
+		if disabled(d.AllowImagePull) {
+			return p.violation("image_pull_not_allowed", line, "pulling or building images on this Docker host is disabled")
+		}
+	case "rmi":
+		if disabled(d.AllowImageDelete) {
+			return p.violation("image_delete_not_allowed", line, "deleting images on this Docker host is disabled")
+		}
+	case "system prune":
+		if disabled(d.AllowContainerDelete) {
+			return p.violation("container_delete_not_allowed", line, "deleting containers on this Docker host is disabled")
+		}
+		if disabled(d.AllowImageDelete) {
+			return p.violation("image_delete_not_allowed", line, "deleting images on this Docker host is disabled")
+		}
+	case "volume":
+		if disabled(d.AllowVolumeAccess) {
+			return p.violation("volume_access_not_allowed", line, "volumes of this Docker host are not accessible")
+		}
+	case "network":
+		if disabled(d.AllowNetworkAccess) {
+			return p.violation("network_access_not_allowed", line, "networks of this Docker host are not accessible")
+		}
+	}
+
+	if !dockerContainerCommands[sub] {
+		return nil
+	}
+	for _, arg := range skipDockerOptions(rest) {
+		// docker cp names the container as container:path
+		if sub == "cp" {
+			name, _, ok := strings.Cut(arg, ":")
+			if !ok || strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, ".") {
+				continue
+			}
+			arg = name
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if v := p.checkContainerName(arg); v != nil {
+			v.Subject = line
+			return v
+		}
+		if dockerSingleContainerCommands[sub] {
+			// The rest is the command run in the container
+			break
+		}
+	}
+	return nil
+}
+
+// dockerSubcommand names what a docker CLI invocation does, folding the
+// management commands ("docker container rm", "docker image rm") into the
+// short forms ("docker rm", "docker rmi")
+func dockerSubcommand(args []string) (string, []string) {
+	sub, rest := args[0], args[1:]
+	if sub == "volume" || sub == "network" {
+		return sub, rest
+	}
+	if sub != "container" && sub != "image" && sub != "system" && sub != "compose" {
+		return sub, rest
+	}
+	group := sub
+	rest = skipDockerOptions(rest)
+	if len(rest) == 0 {
+		return group, rest
+	}
+	sub, rest = rest[0], rest[1:]
+	switch {
+	case group == "image" && (sub == "rm" || sub == "remove" || sub == "prune"):
+		return "rmi", rest
+	case group == "image" && (sub == "pull" || sub == "build" || sub == "load" || sub == "import"):
+		return sub, rest
+	case group == "system" && sub == "prune":
+		return "system prune", rest
+	case group == "system" || group == "image":
+		return group + " " + sub, rest
+	case group == "compose" && (sub == "rm" || sub == "down"):
+		return "down", rest
+	case group == "compose" && (sub == "run" || sub == "create" || sub == "up"):
+		// compose names services, not containers
+		return "up", rest
+	case group == "compose":
+		return "compose " + sub, rest
+	case sub == "remove":
+		return "rm", rest
+	}
+	return sub, rest
+}
+
+// checkDockerRun checks the flags of docker run and create. Without network
+// access a container must be started with --network none.
+func (p *Policy) checkDockerRun(args []string, line string) *Violation {
+	d := &p.docker
+	isolated := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !hasValue && dockerOptionsWithArg[name] && i+1 < len(args) {
+			value = args[i+1]
+			i++
+		}
+		switch name {
+		case "--privileged", "--cap-add", "--device":
+			if disabled(d.AllowPrivileged) {
+				return p.violation("privileged_not_allowed", line, "privileged containers are disabled on this Docker host")
+			}
+		case "--pid", "--ipc", "--userns":
+			if disabled(d.AllowPrivileged) && value == "host" {
+				return p.violation("privileged_not_allowed", line, "privileged containers are disabled on this Docker host")
+			}
+		case "-v", "--volume", "--mount", "--volumes-from":
+			if disabled(d.AllowVolumeAccess) {
+				return p.violation("volume_access_not_allowed", line, "mounting volumes is disabled on this Docker host")
+			}
+		case "--net", "--network":
+			isolated = value == "none"
+		case "--name":
+			if v := p.checkContainerName(value); v != nil {
+				v.Subject = line
+				return v
+			}
+		}
+	}
+	if disabled(d.AllowNetworkAccess) && !isolated {
+		return p.violation("network_access_not_allowed", line, "network access is disabled for containers on this Docker host; start them with --network none")
+	}
+	return nil
+}
+
+// disabled reports whether an optional permission is explicitly turned off
+func disabled(allow *bool) bool {
+	return allow != nil && !*allow
+}
+
+// skipDockerOptions drops the docker CLI's global options before the subcommand
+func skipDockerOptions(args []string) []string {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, _, hasValue := strings.Cut(args[0], "=")
+		args = args[1:]
+		if !hasValue && dockerOptionsWithArg[name] && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	return args
+}
@@ -0,0 +1,59 @@
+package policy
+
+// InputLine follows the line being typed into an interactive terminal, so
+// each line can be checked as a command when it is entered. The zero value
+// is an empty line.
+type InputLine struct {
+	buf []rune
+}
+
+// CheckKeys checks keystrokes typed into an interactive terminal. Every line
+// they enter, together with what was typed before, is checked as a command.
+// Keys that edit the line in ways that can't be followed, such as history
+// recall, Tab completion and cursor movement, are refused while commands
+// are restricted. The line only advances when the keys are allowed.
+func (p *Policy) CheckKeys(line *InputLine, keys string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.invalid(); v != nil {
+		return v
+	}
+
+	buf := append([]rune(nil), line.buf...)
+	for _, r := range keys {
+		switch {
+		case r == '\r' || r == '\n':
+			if v := p.CheckCommand(string(buf)); v != nil {
+				return v
+			}
+			buf = buf[:0]
+		case r == 0x7f || r == '\b':
+			if len(buf) > 0 {
+				buf = buf[:len(buf)-1]
+			}
+		case r == 0x03 || r == 0x15:
+			// Ctrl+C and Ctrl+U drop the line
+			buf = buf[:0]
+		case r == 0x04:
+			// Ctrl+D ends input; it types nothing
+		case r < 0x20 || r == 0x9b:
+			if p.restrictsCommands() {
+				return p.violation("keys_not_allowed", keys, "line editing keys such as arrows, Tab and Ctrl shortcuts can't be checked against this asset's command restrictions; type whole lines instead")
+			}
+		default:
+			buf = append(buf, r)
+		}
+	}
+	line.buf = buf
+	return nil
+}
+
+// restrictsCommands reports whether CheckCommand can refuse anything
+func (p *Policy) restrictsCommands() bool {
+	t := p.terminal
+	return len(t.AllowedCommands) > 0 || len(t.BlockedCommands) > 0 ||
+		len(t.AllowedPaths) > 0 || len(t.BlockedPaths) > 0 ||
+		len(t.AllowedEnvVars) > 0 || len(t.BlockedEnvVars) > 0 ||
+		p.allowSudo != nil && !*p.allowSudo
+}
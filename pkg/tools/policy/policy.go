@@ -0,0 +1,287 @@
+// Package policy enforces the per-workspace restrictions stored on
+// WorkspaceAssetRef before agent tools act on an asset.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/choraleia/choraleia/pkg/models"
+)
+
+// Restriction kinds, derived from the asset type of a reference
+const (
+	KindTerminal = "terminal"
+	KindDatabase = "database"
+	KindRedis    = "redis"
+)
+
+// Violation is a structured refusal returned to the model
+type Violation struct {
+	AssetID   string `json:"asset_id"`
+	AssetName string `json:"asset_name,omitempty"`
+	Rule      string `json:"rule"`
+	Subject   string `json:"subject,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("policy violation (%s): %s", v.Rule, v.Reason)
+}
+
+// Refusal formats the violation as a tool result for the model
+func (v *Violation) Refusal() string {
+	data, _ := json.Marshal(map[string]interface{}{"policy_violation": v})
+	return fmt.Sprintf("Error: refused by workspace restrictions. Do not retry this action or work around it.\n%s", data)
+}
+
+// Policy holds the decoded restrictions for one asset reference.
+// A nil *Policy allows everything.
+type Policy struct {
+	AssetID   string
+	AssetName string
+	Kind      string
+
+	terminal   models.TerminalRestrictions
+	allowSudo  *bool
+	allowScp   *bool
+	allowSftp  *bool
+	maxSession *int
+	database   models.DatabaseRestrictions
+	redis      models.RedisRestrictions
+	docker     models.DockerRestrictions
+
+	// err is set when the stored restrictions can't be decoded; every
+	// check then fails closed
+	err error
+	// unavailable is set when the restrictions couldn't be looked up;
+	// every check then fails closed
+	unavailable error
+	// unmatched is the server, matching no asset reference, that every
+	// check refuses
+	unmatched string
+}
+
+// KindOf maps an asset type to the restriction kind that applies to it
+func KindOf(assetType string) string {
+	switch strings.ToLower(assetType) {
+	case "database", "mysql", "postgres", "postgresql":
+		return KindDatabase
+	case "redis":
+		return KindRedis
+	default:
+		return KindTerminal
+	}
+}
+
+// New builds the policy for a workspace asset reference
+func New(ref *models.WorkspaceAssetRef) *Policy {
+	assetType := ref.AssetType
+	if t, ok := ref.Restrictions["type"].(string); ok && assetType == "" {
+		assetType = t
+	}
+	p := &Policy{
+		AssetID:   ref.AssetID,
+		AssetName: ref.AssetName,
+		Kind:      KindOf(assetType),
+	}
+	if len(ref.Restrictions) == 0 {
+		return p
+	}
+
+	switch {
+	case p.Kind == KindDatabase:
+		p.err = models.DecodeRestrictions(ref.Restrictions, &p.database)
+	case p.Kind == KindRedis:
+		p.err = models.DecodeRestrictions(ref.Restrictions, &p.redis)
+	case assetType == string(models.AssetTypeSSH):
+		var r models.SSHRestrictions
+		p.err = models.DecodeRestrictions(ref.Restrictions, &r)
+		p.terminal = r.TerminalRestrictions
+		p.allowSudo, p.allowScp, p.allowSftp, p.maxSession = r.AllowSudo, r.AllowScp, r.AllowSftp, r.MaxSessionDuration
+	case assetType == string(models.AssetTypeDockerHost):
+		p.err = models.DecodeRestrictions(ref.Restrictions, &p.docker)
+		p.terminal = p.docker.TerminalRestrictions
+	case assetType == string(models.AssetTypeLocal):
+		var r models.LocalRestrictions
+		p.err = models.DecodeRestrictions(ref.Restrictions, &r)
+		p.terminal, p.allowSudo = r.TerminalRestrictions, r.AllowSudo
+	default:
+		p.err = models.DecodeRestrictions(ref.Restrictions, &p.terminal)
+	}
+	return p
+}
+
+// Unavailable returns a policy refusing everything on an asset whose
+// restrictions couldn't be looked up, such as when the workspace fails to load
+func Unavailable(assetID, kind string, err error) *Policy {
+	return &Policy{AssetID: assetID, Kind: kind, unavailable: err}
+}
+
+// violation builds a Violation for this policy's asset
+func (p *Policy) violation(rule, subject, reason string, args ...interface{}) *Violation {
+	return &Violation{
+		AssetID:   p.AssetID,
+		AssetName: p.AssetName,
+		Rule:      rule,
+		Subject:   subject,
+		Reason:    fmt.Sprintf(reason, args...),
+	}
+}
+
+// invalid returns a violation when the restrictions couldn't be looked up or
+// decoded, or for a server that matches none of the workspace's assets
+func (p *Policy) invalid() *Violation {
+	if p.unmatched != "" {
+		return p.violation("unknown_server", p.unmatched, "%s is not a workspace asset; while the workspace restricts its %s assets only those can be used, by their configured host", p.unmatched, p.Kind)
+	}
+	if p.unavailable != nil {
+		return p.violation("restrictions_unavailable", "", "the workspace restrictions could not be loaded: %v", p.unavailable)
+	}
+	if p.err == nil {
+		return nil
+	}
+	return p.violation("invalid_restrictions", "", "restrictions for this asset are malformed: %v", p.err)
+}
+
+// SessionTimeout caps a requested timeout in seconds by the max session duration
+func (p *Policy) SessionTimeout(seconds int) int {
+	if p == nil || p.maxSession == nil || *p.maxSession <= 0 {
+		return seconds
+	}
+	if seconds <= 0 || seconds > *p.maxSession {
+		return *p.maxSession
+	}
+	return seconds
+}
+
+// CheckFiles checks file system access to an asset (SFTP) for the given paths
+func (p *Policy) CheckFiles(paths ...string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.invalid(); v != nil {
+		return v
+	}
+	if p.allowSftp != nil && !*p.allowSftp {
+		return p.violation("sftp_not_allowed", "", "file system access to this asset is disabled")
+	}
+	for _, pth := range paths {
+		if v := p.CheckPath(pth); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// CheckTransfer checks a file transfer to or from an asset for the given paths
+func (p *Policy) CheckTransfer(paths ...string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.invalid(); v != nil {
+		return v
+	}
+	if p.allowScp != nil && !*p.allowScp {
+		return p.violation("scp_not_allowed", "", "file transfers to and from this asset are disabled")
+	}
+	return p.CheckFiles(paths...)
+}
+
+// CheckPath checks a path against the allowed and blocked path lists.
+// Relative paths are refused when an allow list is set since they can't be
+// resolved without the remote working directory.
+func (p *Policy) CheckPath(pth string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.invalid(); v != nil {
+		return v
+	}
+	if len(p.terminal.AllowedPaths) == 0 && len(p.terminal.BlockedPaths) == 0 {
+		return nil
+	}
+
+	cleaned := cleanPath(pth)
+	for _, pattern := range p.terminal.BlockedPaths {
+		if matchPath(pattern, cleaned) {
+			return p.violation("blocked_path", pth, "path matches blocked pattern %q", pattern)
+		}
+	}
+	if len(p.terminal.AllowedPaths) == 0 {
+		return nil
+	}
+	if !isAbsPath(cleaned) {
+		return p.violation("path_not_allowed", pth, "use an absolute path; only %s are allowed", strings.Join(p.terminal.AllowedPaths, ", "))
+	}
+	for _, pattern := range p.terminal.AllowedPaths {
+		if matchPath(pattern, cleaned) {
+			return nil
+		}
+	}
+	return p.violation("path_not_allowed", pth, "path is outside the allowed paths: %s", strings.Join(p.terminal.AllowedPaths, ", "))
+}
+
+// cleanPath normalizes a path so ".." can't be used to escape a prefix
+func cleanPath(p string) string {
+	if p == "" {
+		return "."
+	}
+	return path.Clean(p)
+}
+
+// isAbsPath reports whether p is absolute or relative to the home directory
+func isAbsPath(p string) bool {
+	return strings.HasPrefix(p, "/") || p == "~" || strings.HasPrefix(p, "~/")
+}
+
+// matchPath reports whether p is covered by pattern. Plain patterns match the
+// path itself and everything below it; glob patterns match p or any parent.
+func matchPath(pattern, p string) bool {
+	if !strings.ContainsAny(pattern, "*?[") {
+		pattern = cleanPath(pattern)
+		if pattern == "/" {
+			return strings.HasPrefix(p, "/")
+		}
+		return p == pattern || strings.HasPrefix(p, pattern+"/")
+	}
+	for candidate := p; ; candidate = path.Dir(candidate) {
+		if ok, _ := path.Match(pattern, candidate); ok {
+			return true
+		}
+		if candidate == "/" || candidate == "." || candidate == "~" || !strings.Contains(candidate, "/") {
+			return false
+		}
+	}
+}
+
+// globCache avoids recompiling patterns for every command segment
+var globCache sync.Map
+
+// matchGlob matches s against a pattern where * spans any characters,
+// case-insensitively. Patterns prefixed with "re:" are regular expressions.
+func matchGlob(pattern, s string) bool {
+	if cached, ok := globCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp).MatchString(s)
+	}
+	expr := ""
+	if strings.HasPrefix(pattern, "re:") {
+		expr = "(?i)" + strings.TrimPrefix(pattern, "re:")
+	} else {
+		quoted := regexp.QuoteMeta(pattern)
+		quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+		quoted = strings.ReplaceAll(quoted, `\?`, ".")
+		expr = "(?i)^" + quoted + "$"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		// An invalid regex can only match literally
+		re = regexp.MustCompile("^" + regexp.QuoteMeta(pattern) + "$")
+	}
+	globCache.Store(pattern, re)
+	return re.MatchString(s)
+}
@@ -0,0 +1,306 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/choraleia/choraleia/pkg/models"
+)
+
+func newPolicy(assetType string, restrictions map[string]interface{}) *Policy {
+	return New(&models.WorkspaceAssetRef{
+		AssetID:      "a1",
+		AssetName:    "web-1",
+		AssetType:    assetType,
+		Restrictions: models.JSONMap(restrictions),
+	})
+}
+
+func TestCheckCommand(t *testing.T) {
+	p := newPolicy("ssh", map[string]interface{}{
+		"blockedCommands": []interface{}{"rm -rf *", "shutdown"},
+		"blockedPaths":    []interface{}{"/etc/shadow", "/root"},
+		"blockedEnvVars":  []interface{}{"AWS_*"},
+		"allowSudo":       false,
+	})
+
+	cases := []struct {
+		command string
+		rule    string
+	}{
+		{"ls -la /var/log | grep error", ""},
+		{"uptime && echo $HOME 2>&1", ""},
+		{"echo \"a (b)\"; echo $((1+2))", ""},
+		{"cd /tmp; rm -rf build", "blocked_command"},
+		{"echo $(/sbin/shutdown -h now)", "blocked_command"},
+		{"bash -c 'sleep 1; shutdown now'", "blocked_command"},
+		{"sudo -u root systemctl restart nginx", "sudo_not_allowed"},
+		{"FOO=1 nohup su - admin", "sudo_not_allowed"},
+		{"cat /etc/../etc/shadow", "blocked_path"},
+		{"ls >/root/out.txt", "blocked_path"},
+		{"echo ${AWS_SECRET_ACCESS_KEY}", "blocked_env_var"},
+		{"env", "blocked_env_var"},
+	}
+	for _, c := range cases {
+		v := p.CheckCommand(c.command)
+		switch {
+		case c.rule == "" && v != nil:
+			t.Errorf("%q refused: %v", c.command, v)
+		case c.rule != "" && (v == nil || v.Rule != c.rule):
+			t.Errorf("%q = %v, want rule %s", c.command, v, c.rule)
+		}
+	}
+
+	allow := newPolicy("ssh", map[string]interface{}{
+		"allowed_commands": []interface{}{"ls", "cat", "git status"},
+		"allowed_paths":    []interface{}{"/srv/app"},
+	})
+	if v := allow.CheckCommand("ls /srv/app | cat"); v != nil {
+		t.Errorf("allowed command refused: %v", v)
+	}
+	if v := allow.CheckCommand("git status"); v != nil {
+		t.Errorf("allowed git status refused: %v", v)
+	}
+	if v := allow.CheckCommand("git push"); v == nil || v.Rule != "command_not_allowed" {
+		t.Errorf("git push = %v", v)
+	}
+	if v := allow.CheckCommand("cat /srv/app/../../etc/passwd"); v == nil || v.Rule != "path_not_allowed" {
+		t.Errorf("path escape = %v", v)
+	}
+	if v := allow.CheckFiles("logs/app.log"); v == nil || v.Rule != "path_not_allowed" {
+		t.Errorf("relative path = %v", v)
+	}
+}
+
+func TestCheckFilesAndSession(t *testing.T) {
+	p := newPolicy("ssh", map[string]interface{}{
+		"allowScp":           false,
+		"blockedPaths":       []interface{}{"/home/*/.ssh"},
+		"maxSessionDuration": 20,
+	})
+	if v := p.CheckFiles("/home/bob/.ssh/id_rsa"); v == nil || v.Rule != "blocked_path" {
+		t.Errorf("glob path = %v", v)
+	}
+	if v := p.CheckFiles("/home/bob/notes"); v != nil {
+		t.Errorf("plain path refused: %v", v)
+	}
+	if v := p.CheckTransfer("/tmp/x"); v == nil || v.Rule != "scp_not_allowed" {
+		t.Errorf("transfer = %v", v)
+	}
+	if got := p.SessionTimeout(60); got != 20 {
+		t.Errorf("SessionTimeout(60) = %d", got)
+	}
+
+	var unrestricted *Policy
+	if unrestricted.CheckCommand("sudo rm -rf /") != nil || unrestricted.SessionTimeout(60) != 60 {
+		t.Error("nil policy must allow everything")
+	}
+
+	broken := newPolicy("ssh", map[string]interface{}{"blockedCommands": "rm"})
+	if v := broken.CheckCommand("ls"); v == nil || v.Rule != "invalid_restrictions" {
+		t.Errorf("malformed restrictions = %v", v)
+	}
+
+	unavailable := Unavailable("a1", KindTerminal, fmt.Errorf("database is locked"))
+	if v := unavailable.CheckFiles("/tmp/x"); v == nil || v.Rule != "restrictions_unavailable" {
+		t.Errorf("unavailable restrictions = %v", v)
+	}
+}
+
+func TestCheckDocker(t *testing.T) {
+	p := newPolicy("docker_host", map[string]interface{}{
+		"blockedContainers":    []interface{}{"prod-*"},
+		"allowContainerDelete": false,
+		"allowImagePull":       false,
+		"allowPrivileged":      false,
+		"allowVolumeAccess":    false,
+		"allowNetworkAccess":   false,
+		"blockedCommands":      []interface{}{"shutdown"},
+	})
+
+	cases := []struct {
+		command string
+		rule    string
+	}{
+		{"docker ps -a", ""},
+		{"docker logs --tail 100 web-1", ""},
+		{"docker run --rm --network none alpine echo hi", ""},
+		{"docker exec -it prod-db psql", "blocked_container"},
+		{"sudo docker -H unix:///var/run/docker.sock restart web-1 prod-api", "blocked_container"},
+		{"docker cp prod-db:/etc/passwd /tmp", "blocked_container"},
+		{"docker container rm web-1", "container_delete_not_allowed"},
+		{"docker image pull nginx", "image_pull_not_allowed"},
+		{"docker run --network none --privileged alpine", "privileged_not_allowed"},
+		{"docker run --network=none -v /:/host alpine", "volume_access_not_allowed"},
+		{"docker run alpine", "network_access_not_allowed"},
+		{"docker compose up -d", ""},
+		{"docker compose down", "container_delete_not_allowed"},
+		{"shutdown now", "blocked_command"},
+	}
+	for _, c := range cases {
+		v := p.CheckCommand(c.command)
+		switch {
+		case c.rule == "" && v != nil:
+			t.Errorf("%q refused: %v", c.command, v)
+		case c.rule != "" && (v == nil || v.Rule != c.rule):
+			t.Errorf("%q = %v, want rule %s", c.command, v, c.rule)
+		}
+	}
+
+	if v := p.CheckContainer("prod-api"); v == nil || v.Rule != "blocked_container" {
+		t.Errorf("CheckContainer(prod-api) = %v", v)
+	}
+	noExec := newPolicy("docker_host", map[string]interface{}{"allowContainerExec": false})
+	if v := noExec.CheckContainer("web-1"); v == nil || v.Rule != "container_exec_not_allowed" {
+		t.Errorf("CheckContainer without exec = %v", v)
+	}
+}
+
+func TestCheckKeys(t *testing.T) {
+	p := newPolicy("ssh", map[string]interface{}{"blockedCommands": []interface{}{"shutdown"}})
+	var line InputLine
+
+	// A line typed in pieces is checked when it is entered
+	if v := p.CheckKeys(&line, "shut"); v != nil {
+		t.Fatalf("partial line refused: %v", v)
+	}
+	if v := p.CheckKeys(&line, "down now\r"); v == nil || v.Rule != "blocked_command" {
+		t.Fatalf("entered line = %v", v)
+	}
+	// A refused line stays unsent, and Ctrl+U drops it
+	if v := p.CheckKeys(&line, "\x15uptime\r"); v != nil {
+		t.Fatalf("cleared line refused: %v", v)
+	}
+	if v := p.CheckKeys(&line, "shutdowX\x7fn\r"); v == nil {
+		t.Fatal("line fixed with backspace allowed")
+	}
+	if v := p.CheckKeys(&line, "\x03\x1b[A\r"); v == nil || v.Rule != "keys_not_allowed" {
+		t.Fatalf("history recall = %v", v)
+	}
+
+	free := newPolicy("ssh", nil)
+	if v := free.CheckKeys(&InputLine{}, "\x1b[A\t\r"); v != nil {
+		t.Fatalf("unrestricted keys refused: %v", v)
+	}
+}
+
+func TestCheckSQL(t *testing.T) {
+	p := newPolicy("database", map[string]interface{}{
+		"readOnly":         true,
+		"blockedDatabases": []interface{}{"mysql"},
+		"blockedTables":    []interface{}{"users", "audit_*"},
+		"maxRowsReturn":    50,
+	})
+
+	cases := []struct {
+		db, sql, rule string
+	}{
+		{"app", "SELECT id, EXTRACT(YEAR FROM created_at) FROM orders o JOIN items i ON i.order_id = o.id", ""},
+		{"app", "SELECT * FROM orders WHERE note = 'DELETE FROM users'", ""},
+		{"app", "-- report\nSELECT * FROM orders, `users`", "blocked_table"},
+		{"app", "select * from app.audit_log", "blocked_table"},
+		{"app", "SELECT 1; DELETE FROM orders", "read_only"},
+		{"app", "WITH x AS (SELECT 1) UPDATE orders SET a = 1", "read_only"},
+		{"mysql", "SELECT 1", "blocked_database"},
+	}
+	for _, c := range cases {
+		v := p.CheckSQL(c.db, c.sql)
+		switch {
+		case c.rule == "" && v != nil:
+			t.Errorf("%q refused: %v", c.sql, v)
+		case c.rule != "" && (v == nil || v.Rule != c.rule):
+			t.Errorf("%q = %v, want rule %s", c.sql, v, c.rule)
+		}
+	}
+	if got := p.MaxRows(0); got != 50 {
+		t.Errorf("MaxRows(0) = %d", got)
+	}
+	if got := p.MaxRows(10); got != 10 {
+		t.Errorf("MaxRows(10) = %d", got)
+	}
+
+	ddl := newPolicy("database", map[string]interface{}{"allow_ddl": false, "allowed_tables": []interface{}{"orders"}})
+	if v := ddl.CheckSQL("app", "DROP TABLE IF EXISTS orders"); v == nil || v.Rule != "ddl_not_allowed" {
+		t.Errorf("DDL = %v", v)
+	}
+	if v := ddl.CheckSQL("app", "INSERT INTO payments (a) VALUES (1)"); v == nil || v.Rule != "table_not_allowed" {
+		t.Errorf("insert into payments = %v", v)
+	}
+}
+
+func TestCheckRedis(t *testing.T) {
+	p := newPolicy("redis", map[string]interface{}{
+		"readOnly":           true,
+		"blockedCommands":    []interface{}{"KEYS"},
+		"allowedKeyPatterns": []interface{}{"cache:*", "session:*"},
+		"maxKeysReturn":      10,
+	})
+	if v := p.CheckRedis("get", []string{"cache:home"}); v != nil {
+		t.Errorf("GET refused: %v", v)
+	}
+	if v := p.CheckRedis("MGET", []string{"cache:a", "secret:b"}); v == nil || v.Rule != "key_not_allowed" {
+		t.Errorf("MGET = %v", v)
+	}
+	if v := p.CheckRedis("SET", []string{"cache:a", "1"}); v == nil || v.Rule != "read_only" {
+		t.Errorf("SET = %v", v)
+	}
+	if v := p.CheckRedis("keys", []string{"*"}); v == nil || v.Rule != "blocked_command" {
+		t.Errorf("KEYS = %v", v)
+	}
+	if got := strings.Join(p.FilterKeys([]string{"cache:a", "user:1", "session:x"}), ","); got != "cache:a,session:x" {
+		t.Errorf("FilterKeys = %s", got)
+	}
+	if got := p.MaxKeys(100); got != 10 {
+		t.Errorf("MaxKeys = %d", got)
+	}
+	if !strings.Contains(p.CheckRedis("DEL", []string{"x"}).Refusal(), `"rule":"read_only"`) {
+		t.Error("refusal should carry the rule")
+	}
+}
+
+func TestForService(t *testing.T) {
+	refs := []models.WorkspaceAssetRef{
+		{AssetID: "db", AssetName: "orders", AssetType: "postgres", Restrictions: models.JSONMap{"readOnly": true}},
+		{AssetID: "cache", AssetName: "cache", AssetType: "redis"},
+	}
+	assets := map[string]*models.Asset{
+		"db":    {ID: "db", Config: models.JSONMap{"host": "localhost", "port": float64(5432)}},
+		"cache": {ID: "cache", Config: models.JSONMap{"host": "cache.internal"}},
+	}
+	getAsset := func(id string) (*models.Asset, error) {
+		if a, ok := assets[id]; ok {
+			return a, nil
+		}
+		return nil, fmt.Errorf("asset not found")
+	}
+
+	if p := ForService(KindDatabase, "LOCALHOST", 5432, refs, getAsset); p == nil || p.AssetID != "db" {
+		t.Fatalf("host name: %+v", p)
+	}
+	if v := ForService(KindDatabase, "localhost", 5432, refs, getAsset).CheckSQL("", "DELETE FROM orders"); v == nil || v.Rule != "read_only" {
+		t.Errorf("write through host name = %v", v)
+	}
+
+	// The address of a restricted server can't be used to get around it
+	if addrs, err := net.LookupHost("localhost"); err == nil && slices.Contains(addrs, "127.0.0.1") {
+		if v := ForService(KindDatabase, "127.0.0.1", 5432, refs, getAsset).CheckSQL("", "DELETE FROM orders"); v == nil || v.Rule != "read_only" {
+			t.Errorf("write through IP alias = %v", v)
+		}
+	}
+	for _, target := range []struct {
+		host string
+		port int
+	}{{"10.255.0.9", 5432}, {"localhost", 3306}} {
+		if v := ForService(KindDatabase, target.host, target.port, refs, getAsset).CheckSQL("", "SELECT 1"); v == nil || v.Rule != "unknown_server" {
+			t.Errorf("%s:%d = %v, want unknown_server", target.host, target.port, v)
+		}
+	}
+
+	// No Redis asset is restricted, so other servers stay allowed
+	if p := ForService(KindRedis, "10.255.0.9", 6379, refs, getAsset); p != nil {
+		t.Errorf("unrestricted kind = %+v", p)
+	}
+}
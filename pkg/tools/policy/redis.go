@@ -0,0 +1,166 @@
+package policy
+
+import (
+	"strconv"
+	"strings"
+)
+
+// redisReadCommands don't modify data and are allowed in read-only mode
+var redisReadCommands = map[string]bool{
+	"GET": true, "MGET": true, "STRLEN": true, "GETRANGE": true, "GETBIT": true, "BITCOUNT": true,
+	"EXISTS": true, "TYPE": true, "TTL": true, "PTTL": true, "KEYS": true, "SCAN": true, "RANDOMKEY": true,
+	"HGET": true, "HMGET": true, "HGETALL": true, "HKEYS": true, "HVALS": true, "HLEN": true,
+	"HEXISTS": true, "HSTRLEN": true, "HSCAN": true,
+	"LRANGE": true, "LLEN": true, "LINDEX": true, "LPOS": true,
+	"SMEMBERS": true, "SISMEMBER": true, "SMISMEMBER": true, "SCARD": true, "SSCAN": true,
+	"SRANDMEMBER": true, "SUNION": true, "SINTER": true, "SDIFF": true,
+	"ZRANGE": true, "ZREVRANGE": true, "ZRANGEBYSCORE": true, "ZREVRANGEBYSCORE": true,
+	"ZSCORE": true, "ZMSCORE": true, "ZCARD": true, "ZCOUNT": true, "ZRANK": true, "ZREVRANK": true, "ZSCAN": true,
+	"XRANGE": true, "XREVRANGE": true, "XLEN": true, "PFCOUNT": true, "GEOPOS": true, "GEODIST": true,
+	"PING": true, "ECHO": true, "INFO": true, "DBSIZE": true, "TIME": true,
+}
+
+// redisKeylessCommands take no key arguments
+var redisKeylessCommands = map[string]bool{
+	"PING": true, "ECHO": true, "INFO": true, "DBSIZE": true, "TIME": true, "SCAN": true, "RANDOMKEY": true,
+	"FLUSHALL": true, "FLUSHDB": true, "CONFIG": true, "CLIENT": true, "SELECT": true, "AUTH": true,
+	"SAVE": true, "BGSAVE": true, "BGREWRITEAOF": true, "SHUTDOWN": true, "DEBUG": true, "SLOWLOG": true,
+	"MONITOR": true, "SCRIPT": true, "FUNCTION": true, "COMMAND": true, "MEMORY": true, "LATENCY": true,
+	"MULTI": true, "EXEC": true, "DISCARD": true, "UNWATCH": true, "PUBLISH": true, "SUBSCRIBE": true,
+}
+
+// redisMultiKeyCommands take only keys as arguments
+var redisMultiKeyCommands = map[string]bool{
+	"MGET": true, "DEL": true, "UNLINK": true, "EXISTS": true, "TOUCH": true, "WATCH": true,
+	"SUNION": true, "SINTER": true, "SDIFF": true, "PFCOUNT": true,
+}
+
+// redisTwoKeyCommands take a source and destination key
+var redisTwoKeyCommands = map[string]bool{
+	"RENAME": true, "RENAMENX": true, "COPY": true, "SMOVE": true, "LMOVE": true, "RPOPLPUSH": true,
+}
+
+// CheckRedis checks a Redis command and the keys it touches against the
+// command lists, read-only mode and key patterns
+func (p *Policy) CheckRedis(command string, args []string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.invalid(); v != nil {
+		return v
+	}
+	r := &p.redis
+	cmd := strings.ToUpper(strings.TrimSpace(command))
+	line := strings.TrimSpace(cmd + " " + strings.Join(args, " "))
+
+	for _, pattern := range r.BlockedCommands {
+		if matchGlob(pattern, cmd) {
+			return p.violation("blocked_command", line, "Redis command %s is blocked", cmd)
+		}
+	}
+	if len(r.AllowedCommands) > 0 {
+		allowed := false
+		for _, pattern := range r.AllowedCommands {
+			if matchGlob(pattern, cmd) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return p.violation("command_not_allowed", line, "Redis command %s is not in the allowed commands: %s", cmd, strings.Join(r.AllowedCommands, ", "))
+		}
+	}
+	if r.ReadOnly != nil && *r.ReadOnly && !redisReadCommands[cmd] {
+		return p.violation("read_only", line, "Redis is read-only; %s is not allowed", cmd)
+	}
+
+	for _, key := range redisKeys(cmd, args) {
+		if v := p.CheckKey(key); v != nil {
+			v.Subject = line
+			return v
+		}
+	}
+	return nil
+}
+
+// CheckKey checks a key against the allowed and blocked key patterns
+func (p *Policy) CheckKey(key string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.invalid(); v != nil {
+		return v
+	}
+	r := &p.redis
+	for _, pattern := range r.BlockedKeyPatterns {
+		if matchGlob(pattern, key) {
+			return p.violation("blocked_key", key, "key %s matches blocked pattern %q", key, pattern)
+		}
+	}
+	if len(r.AllowedKeyPatterns) == 0 {
+		return nil
+	}
+	for _, pattern := range r.AllowedKeyPatterns {
+		if matchGlob(pattern, key) {
+			return nil
+		}
+	}
+	return p.violation("key_not_allowed", key, "key %s doesn't match the allowed patterns: %s", key, strings.Join(r.AllowedKeyPatterns, ", "))
+}
+
+// FilterKeys drops keys the model isn't allowed to see
+func (p *Policy) FilterKeys(keys []string) []string {
+	if p == nil || len(p.redis.AllowedKeyPatterns) == 0 && len(p.redis.BlockedKeyPatterns) == 0 {
+		return keys
+	}
+	filtered := keys[:0]
+	for _, key := range keys {
+		if p.CheckKey(key) == nil {
+			filtered = append(filtered, key)
+		}
+	}
+	return filtered
+}
+
+// MaxKeys caps a requested key count by the configured maximum
+func (p *Policy) MaxKeys(limit int) int {
+	if p == nil || p.redis.MaxKeysReturn == nil || *p.redis.MaxKeysReturn <= 0 {
+		return limit
+	}
+	if limit <= 0 || limit > *p.redis.MaxKeysReturn {
+		return *p.redis.MaxKeysReturn
+	}
+	return limit
+}
+
+// redisKeys returns the key arguments of a command
+func redisKeys(cmd string, args []string) []string {
+	switch {
+	case len(args) == 0 || redisKeylessCommands[cmd]:
+		return nil
+	case redisMultiKeyCommands[cmd]:
+		return args
+	case redisTwoKeyCommands[cmd] && len(args) >= 2:
+		return args[:2]
+	case cmd == "MSET" || cmd == "MSETNX":
+		var keys []string
+		for i := 0; i < len(args); i += 2 {
+			keys = append(keys, args[i])
+		}
+		return keys
+	case cmd == "EVAL" || cmd == "EVALSHA" || cmd == "EVAL_RO" || cmd == "EVALSHA_RO":
+		if len(args) < 2 {
+			return nil
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return nil
+		}
+		if 2+n > len(args) {
+			n = len(args) - 2
+		}
+		return args[2 : 2+n]
+	default:
+		return args[:1]
+	}
+}
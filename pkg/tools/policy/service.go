@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+)
+
+// resolveTimeout bounds the lookups that match a server to an asset
+const resolveTimeout = 5 * time.Second
+
+// ForService returns the restrictions for a database or Redis server the
+// model addresses by host and port. The server matches an asset reference of
+// that kind whose asset has the same host, by name or by a resolved address,
+// and port. When none matches but some reference of that kind carries
+// restrictions, the server is refused: otherwise an IP address or another
+// alias of a restricted server would get around them. It returns nil, which
+// allows everything, when the workspace restricts no server of that kind.
+func ForService(kind, host string, port int, refs []models.WorkspaceAssetRef, getAsset func(id string) (*models.Asset, error)) *Policy {
+	restricted := false
+	var candidates []*models.WorkspaceAssetRef
+	var hosts []string
+	for i := range refs {
+		ref := &refs[i]
+		if KindOf(ref.AssetType) != kind {
+			continue
+		}
+		if len(ref.Restrictions) > 0 {
+			restricted = true
+		}
+		asset, err := getAsset(ref.AssetID)
+		if err != nil {
+			continue
+		}
+		assetHost, _ := asset.Config["host"].(string)
+		assetPort := 0
+		switch v := asset.Config["port"].(type) {
+		case float64:
+			assetPort = int(v)
+		case int:
+			assetPort = v
+		}
+		if assetHost == "" || assetPort != 0 && assetPort != port {
+			continue
+		}
+		if strings.EqualFold(assetHost, host) {
+			return New(ref)
+		}
+		candidates = append(candidates, ref)
+		hosts = append(hosts, assetHost)
+	}
+
+	if len(candidates) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+		defer cancel()
+		if addrs := resolve(ctx, host); len(addrs) > 0 {
+			for i, ref := range candidates {
+				for addr := range resolve(ctx, hosts[i]) {
+					if addrs[addr] {
+						return New(ref)
+					}
+				}
+			}
+		}
+	}
+
+	if restricted {
+		return &Policy{Kind: kind, unmatched: net.JoinHostPort(host, strconv.Itoa(port))}
+	}
+	return nil
+}
+
+// resolve returns the addresses of a host name or IP literal
+func resolve(ctx context.Context, host string) map[string]bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return map[string]bool{ip.String(): true}
+	}
+	names, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil
+	}
+	addrs := make(map[string]bool, len(names))
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			addrs[ip.String()] = true
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,361 @@
+package policy
+
+import (
+	"strings"
+)
+
+// SQL statement classes by leading keyword
+var (
+	sqlReadOps      = map[string]bool{"SELECT": true, "SHOW": true, "DESCRIBE": true, "DESC": true, "EXPLAIN": true, "USE": true, "VALUES": true, "TABLE": true}
+	sqlDDLOps       = map[string]bool{"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true, "COMMENT": true}
+	sqlProcedureOps = map[string]bool{"CALL": true, "EXEC": true, "EXECUTE": true, "DO": true}
+)
+
+// sqlTableKeywords are followed by a table name
+var sqlTableKeywords = map[string]bool{"FROM": true, "JOIN": true, "INTO": true, "UPDATE": true, "TABLE": true, "TRUNCATE": true}
+
+// sqlTableModifiers may sit between a table keyword and the table name
+var sqlTableModifiers = map[string]bool{"IF": true, "NOT": true, "EXISTS": true, "ONLY": true, "IGNORE": true, "LOW_PRIORITY": true, "TEMPORARY": true, "TABLE": true}
+
+// sqlClauseKeywords end a table reference, so they are never taken as an alias
+var sqlClauseKeywords = map[string]bool{
+	"WHERE": true, "GROUP": true, "ORDER": true, "LIMIT": true, "HAVING": true, "JOIN": true,
+	"LEFT": true, "RIGHT": true, "INNER": true, "OUTER": true, "CROSS": true, "FULL": true,
+	"NATURAL": true, "ON": true, "USING": true, "UNION": true, "SET": true, "VALUES": true,
+	"SELECT": true, "OFFSET": true, "FOR": true, "WINDOW": true, "RETURNING": true,
+	"EXCEPT": true, "INTERSECT": true, "PARTITION": true, "STRAIGHT_JOIN": true, "LATERAL": true,
+}
+
+// sqlFromFunctions use FROM inside their argument list
+var sqlFromFunctions = map[string]bool{"EXTRACT": true, "TRIM": true, "SUBSTRING": true, "POSITION": true, "OVERLAY": true}
+
+// CheckDatabase checks a database name against the allowed and blocked lists
+func (p *Policy) CheckDatabase(database string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.invalid(); v != nil || database == "" {
+		return v
+	}
+	r := &p.database
+	for _, pattern := range r.BlockedDatabases {
+		if matchGlob(pattern, database) {
+			return p.violation("blocked_database", database, "database %s is blocked", database)
+		}
+	}
+	if len(r.AllowedDatabases) == 0 {
+		return nil
+	}
+	for _, pattern := range r.AllowedDatabases {
+		if matchGlob(pattern, database) {
+			return nil
+		}
+	}
+	return p.violation("database_not_allowed", database, "database %s is not in the allowed databases: %s", database, strings.Join(r.AllowedDatabases, ", "))
+}
+
+// CheckTable checks a table name, optionally qualified, against the table lists
+func (p *Policy) CheckTable(database, table string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.CheckDatabase(database); v != nil {
+		return v
+	}
+	if table == "" {
+		return nil
+	}
+	r := &p.database
+	for _, pattern := range r.BlockedTables {
+		if matchTable(pattern, database, table) {
+			return p.violation("blocked_table", table, "table %s is blocked", table)
+		}
+	}
+	if len(r.AllowedTables) == 0 {
+		return nil
+	}
+	for _, pattern := range r.AllowedTables {
+		if matchTable(pattern, database, table) {
+			return nil
+		}
+	}
+	return p.violation("table_not_allowed", table, "table %s is not in the allowed tables: %s", table, strings.Join(r.AllowedTables, ", "))
+}
+
+// CheckSQL checks every statement in sql against the database restrictions:
+// read-only mode, DDL and stored procedure bans, operation lists and the
+// database and table lists
+func (p *Policy) CheckSQL(database, sql string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.CheckDatabase(database); v != nil {
+		return v
+	}
+	r := &p.database
+	for _, stmt := range splitSQL(sql) {
+		tokens := sqlTokens(stmt)
+		if len(tokens) == 0 {
+			continue
+		}
+		op := sqlOperation(tokens)
+		summary := stmt
+		if len(summary) > 200 {
+			summary = summary[:200] + "..."
+		}
+
+		switch {
+		case r.ReadOnly != nil && *r.ReadOnly && !sqlReadOps[op]:
+			return p.violation("read_only", summary, "the database is read-only; %s statements are not allowed", op)
+		case sqlDDLOps[op] && r.AllowDDL != nil && !*r.AllowDDL:
+			return p.violation("ddl_not_allowed", summary, "DDL statements (%s) are not allowed", op)
+		case sqlProcedureOps[op] && r.AllowStoredProcedures != nil && !*r.AllowStoredProcedures:
+			return p.violation("stored_procedure_not_allowed", summary, "stored procedure calls are not allowed")
+		}
+		for _, blocked := range r.BlockedOperations {
+			if strings.EqualFold(blocked, op) {
+				return p.violation("blocked_operation", summary, "%s statements are blocked", op)
+			}
+		}
+		if len(r.AllowedOperations) > 0 {
+			allowed := false
+			for _, a := range r.AllowedOperations {
+				if strings.EqualFold(a, op) {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				return p.violation("operation_not_allowed", summary, "%s is not in the allowed operations: %s", op, strings.Join(r.AllowedOperations, ", "))
+			}
+		}
+
+		if op == "USE" && len(tokens) > 1 {
+			if v := p.CheckDatabase(tokens[1]); v != nil {
+				return v
+			}
+		}
+		if len(r.AllowedTables) > 0 || len(r.BlockedTables) > 0 {
+			for _, table := range sqlTables(tokens) {
+				if v := p.CheckTable(database, table); v != nil {
+					v.Subject = summary
+					return v
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// MaxRows caps a requested row limit by the configured maximum
+func (p *Policy) MaxRows(limit int) int {
+	if p == nil || p.database.MaxRowsReturn == nil || *p.database.MaxRowsReturn <= 0 {
+		return limit
+	}
+	if limit <= 0 || limit > *p.database.MaxRowsReturn {
+		return *p.database.MaxRowsReturn
+	}
+	return limit
+}
+
+// matchTable matches a table pattern against bare and qualified forms of a name
+func matchTable(pattern, database, table string) bool {
+	bare := table
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		bare = table[i+1:]
+	}
+	if matchGlob(pattern, table) || matchGlob(pattern, bare) {
+		return true
+	}
+	return database != "" && matchGlob(pattern, database+"."+bare)
+}
+
+// sqlOperation returns the statement's operation keyword. Common table
+// expressions report the data-modifying verb they wrap, if any.
+func sqlOperation(tokens []string) string {
+	op := strings.ToUpper(tokens[0])
+	if op != "WITH" {
+		return op
+	}
+	for _, t := range tokens[1:] {
+		switch u := strings.ToUpper(t); u {
+		case "INSERT", "UPDATE", "DELETE", "MERGE":
+			return u
+		}
+	}
+	return "SELECT"
+}
+
+// sqlTables extracts table names referenced by a statement
+func sqlTables(tokens []string) []string {
+	var (
+		tables []string
+		parens []string
+	)
+	isIdent := func(i int) bool {
+		return i < len(tokens) && isSQLIdent(tokens[i]) && !sqlClauseKeywords[strings.ToUpper(tokens[i])]
+	}
+	for i := 0; i < len(tokens); i++ {
+		kw := strings.ToUpper(tokens[i])
+		switch {
+		case tokens[i] == "(":
+			fn := ""
+			if i > 0 {
+				fn = strings.ToUpper(tokens[i-1])
+			}
+			parens = append(parens, fn)
+			continue
+		case tokens[i] == ")":
+			if len(parens) > 0 {
+				parens = parens[:len(parens)-1]
+			}
+			continue
+		case i == 0 && (kw == "DESCRIBE" || kw == "DESC"):
+		case !sqlTableKeywords[kw]:
+			continue
+		}
+		if kw == "FROM" && len(parens) > 0 && sqlFromFunctions[parens[len(parens)-1]] {
+			continue
+		}
+
+		j := i + 1
+		for j < len(tokens) && sqlTableModifiers[strings.ToUpper(tokens[j])] {
+			j++
+		}
+		for isIdent(j) {
+			tables = append(tables, tokens[j])
+			j++
+			if kw != "FROM" {
+				break
+			}
+			// Skip an alias, then continue a comma-separated table list
+			if j < len(tokens) && strings.EqualFold(tokens[j], "AS") {
+				j += 2
+			} else if isIdent(j) {
+				j++
+			}
+			if j < len(tokens) && tokens[j] == "," {
+				j++
+				continue
+			}
+			break
+		}
+	}
+	return tables
+}
+
+// isSQLIdent reports whether a token is a (possibly qualified) identifier
+func isSQLIdent(t string) bool {
+	if t == "" {
+		return false
+	}
+	c := t[0]
+	return c == '_' || c == '$' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= 0x80
+}
+
+// splitSQL splits a script into statements on semicolons outside literals
+func splitSQL(sql string) []string {
+	var (
+		out   []string
+		start int
+		quote byte
+	)
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(sql)
+			}
+		case c == ';':
+			if s := strings.TrimSpace(sql[start:i]); s != "" {
+				out = append(out, s)
+			}
+			start = i + 1
+		}
+	}
+	if start < len(sql) {
+		if s := strings.TrimSpace(sql[start:]); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// sqlTokens splits a statement into identifiers, keywords and punctuation.
+// Comments and string literals are dropped, quoted identifiers are unquoted
+// and dotted names are joined into one token.
+func sqlTokens(stmt string) []string {
+	var tokens []string
+	appendIdent := func(name string) {
+		if n := len(tokens); n >= 2 && tokens[n-1] == "." && isSQLIdent(tokens[n-2]) {
+			tokens[n-2] += "." + name
+			tokens = tokens[:n-1]
+			return
+		}
+		tokens = append(tokens, name)
+	}
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '-' && i+1 < len(stmt) && stmt[i+1] == '-':
+			for i < len(stmt) && stmt[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(stmt) && stmt[i+1] == '*':
+			if end := strings.Index(stmt[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(stmt)
+			}
+		case c == '\'':
+			// String literal, skipped entirely
+			i++
+			for i < len(stmt) && stmt[i] != '\'' {
+				i++
+			}
+			i++
+		case c == '`' || c == '"' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(stmt[i+1:], closing)
+			if end < 0 {
+				end = len(stmt) - i - 1
+			}
+			appendIdent(stmt[i+1 : i+1+end])
+			i += end + 2
+		case isSQLIdent(string(c)) || c >= '0' && c <= '9':
+			j := i
+			for j < len(stmt) && (isSQLIdent(string(stmt[j])) || stmt[j] >= '0' && stmt[j] <= '9') {
+				j++
+			}
+			word := stmt[i:j]
+			if c >= '0' && c <= '9' {
+				tokens = append(tokens, word)
+			} else {
+				appendIdent(word)
+			}
+			i = j
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
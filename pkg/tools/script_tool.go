@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// scriptTool exposes a user-defined script as an eino InvokableTool
+type scriptTool struct {
+	manager   *service.ToolManager
+	workspace *models.Workspace
+	cfg       models.WorkspaceTool
+	script    models.ScriptConfig
+	name      string
+}
+
+// scriptToolName sanitizes and caps a script tool's name, numbering it when
+// a built-in tool or an earlier script tool has taken the name
+func scriptToolName(name string, taken map[string]bool) string {
+	base := capToolName(toolNameSanitizer.ReplaceAllString(name, "_"))
+	result := base
+	for n := 2; ; n++ {
+		if _, builtin := GetToolDefinition(ToolID(result)); !builtin && !taken[result] {
+			break
+		}
+		result = capToolName(fmt.Sprintf("%s_%d", base, n))
+	}
+	taken[result] = true
+	return result
+}
+
+// newScriptTool wraps a script workspace tool under the given tool name
+func newScriptTool(manager *service.ToolManager, workspace *models.Workspace, cfg models.WorkspaceTool, name string) (tool.InvokableTool, error) {
+	var script models.ScriptConfig
+	if err := models.DecodeToolConfig(cfg.Config, "script", &script); err != nil {
+		return nil, fmt.Errorf("invalid script config: %w", err)
+	}
+	return &scriptTool{
+		manager:   manager,
+		workspace: workspace,
+		cfg:       cfg,
+		script:    script,
+		name:      name,
+	}, nil
+}
+
+func (t *scriptTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	desc := fmt.Sprintf("Run the %s script tool %q", t.script.Runtime, t.cfg.Name)
+	if t.cfg.Description != nil && *t.cfg.Description != "" {
+		desc = *t.cfg.Description
+	}
+	if t.cfg.AIHint != nil && *t.cfg.AIHint != "" {
+		desc += "\n\n" + *t.cfg.AIHint
+	}
+
+	var params *schema.ParamsOneOf
+	if len(t.script.InputSchema) > 0 {
+		raw, err := json.Marshal(t.script.InputSchema)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input schema: %w", err)
+		}
+		params = paramsFromJSONSchema(raw)
+	}
+
+	return &schema.ToolInfo{
+		Name:        t.name,
+		Desc:        desc,
+		ParamsOneOf: params,
+	}, nil
+}
+
+// Dangerous is always true: a script can do anything its runtime allows
+func (t *scriptTool) Dangerous() bool {
+	return true
+}
+
+func (t *scriptTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if strings.TrimSpace(argumentsInJSON) != "" && !json.Valid([]byte(argumentsInJSON)) {
+		return "Error: arguments must be valid JSON", nil
+	}
+
+	result, err := t.manager.RunScript(ctx, t.workspace, &t.cfg, argumentsInJSON)
+	if err != nil {
+		// Return error as result so AI can see it and handle accordingly
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	output := strings.TrimSpace(result.Stdout)
+	if result.Truncated {
+		output += "\n... (output truncated)"
+	}
+
+	switch {
+	case result.TimedOut:
+		return fmt.Sprintf("Error: script timed out after %s\n%s", result.Duration.Round(time.Millisecond), output), nil
+	case result.ExitCode != 0:
+		detail := strings.TrimSpace(result.Stderr)
+		if detail == "" {
+			detail = output
+		}
+		return fmt.Sprintf("Error: script exited with code %d\n%s", result.ExitCode, detail), nil
+	}
+
+	// Scripts can report failures as {"error": "..."} on stdout
+	var parsed map[string]interface{}
+	if json.Unmarshal([]byte(output), &parsed) == nil {
+		if msg, ok := parsed["error"].(string); ok && msg != "" {
+			return "Error: " + msg, nil
+		}
+	}
+	if output == "" {
+		return "(no output)", nil
+	}
+	return output, nil
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/service"
+)
+
+func TestScriptToolName(t *testing.T) {
+	Register(ToolDefinition{ID: "script_test_builtin"}, nil)
+
+	taken := make(map[string]bool)
+	if got := scriptToolName("fetch report.v2", taken); got != "fetch_report_v2" {
+		t.Errorf("sanitized name = %q", got)
+	}
+	if got := scriptToolName("fetch report/v2", taken); got != "fetch_report_v2_2" {
+		t.Errorf("duplicate name = %q", got)
+	}
+	if got := scriptToolName("script_test_builtin", taken); got != "script_test_builtin_2" {
+		t.Errorf("name of a builtin = %q", got)
+	}
+
+	long := strings.Repeat("a", 100)
+	first, second := scriptToolName(long, taken), scriptToolName(long, taken)
+	if len(first) > maxToolNameLength || len(second) > maxToolNameLength {
+		t.Errorf("names %q, %q exceed %d characters", first, second, maxToolNameLength)
+	}
+	if first == second {
+		t.Errorf("long duplicate names collide: %q", first)
+	}
+}
+
+func TestScriptToolRun(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	run := func(script, args string) string {
+		cfg := models.WorkspaceTool{
+			Name:   "test",
+			Type:   models.ToolTypeScript,
+			Config: models.JSONMap{"runtime": "shell", "script": script},
+		}
+		st, err := newScriptTool(service.NewToolManager(), nil, cfg, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := st.InvokableRun(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name, script, args, want string
+	}{
+		{"arguments on stdin", "cat", `{"n":1}`, `{"n":1}`},
+		{"arguments in env", `echo "$CHORALEIA_TOOL_ARGS"`, `{"n":2}`, `{"n":2}`},
+		{"no arguments", "cat", "", "{}"},
+		{"invalid arguments", "cat", "{", "Error: arguments must be valid JSON"},
+		{"exit code", "echo broken >&2; exit 3", "{}", "Error: script exited with code 3\nbroken"},
+		{"reported error", `echo '{"error": "no such report"}'`, "{}", "Error: no such report"},
+		{"no output", "true", "{}", "(no output)"},
+	}
+	for _, tt := range tests {
+		if got := run(tt.script, tt.args); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
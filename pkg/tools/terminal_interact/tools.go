@@ -0,0 +1,340 @@
+// Package terminal_interact provides an expect-style tool that drives a live
+// terminal session: typing into it and waiting for what it prints. It
+// reaches programs that a one-shot command can't, such as REPLs, ssh and
+// sudo prompts and database shells.
+package terminal_interact
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/choraleia/choraleia/pkg/service"
+	"github.com/choraleia/choraleia/pkg/tools"
+	"github.com/choraleia/choraleia/pkg/tools/policy"
+)
+
+func init() {
+	tools.Register(tools.ToolDefinition{
+		ID:          "terminal_interact",
+		Name:        "Interact with Terminal",
+		Description: "Type into a live terminal session and wait for its output",
+		Category:    tools.CategoryAsset,
+		Scope:       tools.ScopeGlobal,
+		Dangerous:   true,
+	}, NewInteractTool)
+}
+
+// Actions
+const (
+	ActionSendKeys   = "send_keys"
+	ActionSendLine   = "send_line"
+	ActionWaitFor    = "wait_for"
+	ActionReadScreen = "read_screen"
+)
+
+const (
+	defaultWaitTimeout = 30  // seconds
+	maxWaitTimeout     = 300 // seconds
+	defaultScreenLines = 50
+	// maxResultBytes bounds the output returned by wait_for
+	maxResultBytes = 8000
+	pollInterval   = 100 * time.Millisecond
+)
+
+// sessionState is what the tool remembers of a session between calls
+type sessionState struct {
+	// cursor is the output offset wait_for matches from; sends move it to
+	// the offset at which they were typed, and matches past what they matched
+	cursor int64
+	line   policy.InputLine
+}
+
+var (
+	statesMu sync.Mutex
+	states   = map[string]*sessionState{}
+)
+
+// stateOf returns the tool's state of a session, forgetting sessions that
+// have ended
+func stateOf(sessionID string) (*sessionState, error) {
+	statesMu.Lock()
+	defer statesMu.Unlock()
+	for id := range states {
+		if service.GlobalTerminalManager.GetTerminal(id) == nil {
+			delete(states, id)
+		}
+	}
+	if service.GlobalTerminalManager.GetTerminal(sessionID) == nil {
+		return nil, fmt.Errorf("terminal session not found: %s", sessionID)
+	}
+	st, ok := states[sessionID]
+	if !ok {
+		_, end, err := service.GlobalTerminalManager.ReadOutput(sessionID, 0)
+		if err != nil {
+			return nil, err
+		}
+		st = &sessionState{cursor: end}
+		states[sessionID] = st
+	}
+	return st, nil
+}
+
+type InteractInput struct {
+	SessionID      string `json:"session_id"`
+	Action         string `json:"action"`
+	Keys           string `json:"keys,omitempty"`
+	Line           string `json:"line,omitempty"`
+	Pattern        string `json:"pattern,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+	Lines          int    `json:"lines,omitempty"`
+}
+
+func NewInteractTool(tc *tools.ToolContext) tool.InvokableTool {
+	return utils.NewTool(&schema.ToolInfo{
+		Name: "terminal_interact",
+		Desc: "Drive a live terminal session like expect: type into it and wait for a prompt. " +
+			"Use it for interactive programs such as REPLs, ssh and sudo password prompts or database shells. " +
+			"Actions: send_line types a line and presses Enter; send_keys types keys as given, where <Enter>, <Tab>, <Esc>, <Backspace>, <Up>, <Down>, <Left>, <Right> and <C-c> style names stand for special keys; " +
+			"wait_for waits until the output since the last send matches a regular expression; read_screen shows what the terminal displays now.",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"session_id": {Type: schema.String, Required: true, Desc: "Terminal session ID"},
+			"action": {
+				Type:     schema.String,
+				Required: true,
+				Desc:     "Operation to perform",
+				Enum:     []string{ActionSendKeys, ActionSendLine, ActionWaitFor, ActionReadScreen},
+			},
+			"keys":            {Type: schema.String, Required: false, Desc: "Keys for send_keys"},
+			"line":            {Type: schema.String, Required: false, Desc: "Line for send_line, without the trailing newline"},
+			"pattern":         {Type: schema.String, Required: false, Desc: "Regular expression for wait_for, e.g. '[$#] $' or '(?i)password:'"},
+			"timeout_seconds": {Type: schema.Integer, Required: false, Desc: "How long wait_for waits (default: 30, max: 300)"},
+			"lines":           {Type: schema.Integer, Required: false, Desc: "Lines of history and screen for read_screen (default: 50)"},
+		}),
+	}, func(ctx context.Context, input *InteractInput) (string, error) {
+		if input.SessionID == "" {
+			return "Error: session_id is required", nil
+		}
+		st, err := stateOf(input.SessionID)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		assetID, _ := service.GlobalTerminalManager.GetSessionAsset(input.SessionID)
+		p := tc.AssetPolicy(assetID)
+		if v := p.CheckContainer(service.GlobalTerminalManager.GetSessionContainer(input.SessionID)); v != nil {
+			return tc.Refuse("terminal_interact", v), nil
+		}
+
+		switch input.Action {
+		case ActionSendKeys:
+			return send(tc, p, input.SessionID, st, expandKeys(input.Keys))
+		case ActionSendLine:
+			return send(tc, p, input.SessionID, st, input.Line+"\r")
+		case ActionWaitFor:
+			return waitFor(ctx, p, input, st)
+		case ActionReadScreen:
+			lines := input.Lines
+			if lines <= 0 {
+				lines = defaultScreenLines
+			}
+			screen, err := service.GetTerminalOutput(ctx, &service.TerminalOutputInput{TerminalId: input.SessionID, Lines: lines})
+			if err != nil {
+				return fmt.Sprintf("Error: %v", err), nil
+			}
+			return screen, nil
+		default:
+			return fmt.Sprintf("Error: unknown action %q", input.Action), nil
+		}
+	})
+}
+
+// send types keys into a session once the asset's restrictions allow them
+func send(tc *tools.ToolContext, p *policy.Policy, sessionID string, st *sessionState, keys string) (string, error) {
+	if keys == "" {
+		return "Error: nothing to send", nil
+	}
+	statesMu.Lock()
+	defer statesMu.Unlock()
+
+	if v := p.CheckKeys(&st.line, keys); v != nil {
+		return tc.Refuse("terminal_interact", v), nil
+	}
+	_, end, err := service.GlobalTerminalManager.ReadOutput(sessionID, 0)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if err := service.GlobalTerminalManager.SendInput(sessionID, []byte(keys)); err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	st.cursor = end
+	return fmt.Sprintf("Sent %d bytes to session %s. Use wait_for to wait for the response.", len(keys), sessionID), nil
+}
+
+// waitFor polls the output since the cursor until it matches the pattern
+func waitFor(ctx context.Context, p *policy.Policy, input *InteractInput, st *sessionState) (string, error) {
+	if input.Pattern == "" {
+		return "Error: pattern is required for wait_for", nil
+	}
+	re, err := regexp.Compile(input.Pattern)
+	if err != nil {
+		return fmt.Sprintf("Error: invalid pattern: %v", err), nil
+	}
+	timeout := input.TimeoutSeconds
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+	timeout = p.SessionTimeout(min(timeout, maxWaitTimeout))
+
+	statesMu.Lock()
+	cursor := st.cursor
+	statesMu.Unlock()
+
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	var text string
+	for {
+		out, end, err := service.GlobalTerminalManager.ReadOutput(input.SessionID, cursor)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		var ends []int
+		text, ends = plainText(out)
+		if m := re.FindStringSubmatchIndex(text); m != nil {
+			statesMu.Lock()
+			if st.cursor == cursor {
+				// Later waits start after the match
+				consumed := 0
+				if m[1] > 0 {
+					consumed = ends[m[1]-1]
+				}
+				st.cursor = end - int64(len(out)-consumed)
+			}
+			statesMu.Unlock()
+			return formatMatch(input.Pattern, text, m), nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Sprintf("Error: %v", ctx.Err()), nil
+		case <-time.After(pollInterval):
+		}
+	}
+	return fmt.Sprintf("Timed out after %ds waiting for /%s/.\nOutput since the last send:\n%s", timeout, input.Pattern, tail(text)), nil
+}
+
+func formatMatch(pattern, text string, m []int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Matched /%s/: %q\n", pattern, text[m[0]:m[1]]))
+	for i := 2; i+1 < len(m); i += 2 {
+		if m[i] >= 0 {
+			sb.WriteString(fmt.Sprintf("Group %d: %q\n", i/2, text[m[i]:m[i+1]]))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("Output up to the match:\n%s", tail(text[:m[1]])))
+	return sb.String()
+}
+
+// tail keeps the end of long output
+func tail(s string) string {
+	if len(s) <= maxResultBytes {
+		return s
+	}
+	return "...(truncated)\n" + strings.ToValidUTF8(s[len(s)-maxResultBytes:], "")
+}
+
+// keyNames maps the names send_keys accepts to the bytes a terminal sends
+var keyNames = map[string]string{
+	"enter": "\r", "return": "\r", "tab": "\t", "esc": "\x1b", "escape": "\x1b",
+	"backspace": "\x7f", "bs": "\x7f", "space": " ", "del": "\x1b[3~", "delete": "\x1b[3~",
+	"up": "\x1b[A", "down": "\x1b[B", "right": "\x1b[C", "left": "\x1b[D",
+	"home": "\x1b[H", "end": "\x1b[F", "pageup": "\x1b[5~", "pagedown": "\x1b[6~",
+}
+
+var keyToken = regexp.MustCompile(`<([A-Za-z]+|[Cc]-[A-Za-z@\[\\\]^_])>`)
+
+// expandKeys replaces key names such as <Enter> and <C-c> with their bytes;
+// anything else is typed as is
+func expandKeys(keys string) string {
+	return keyToken.ReplaceAllStringFunc(keys, func(tok string) string {
+		name := tok[1 : len(tok)-1]
+		if len(name) == 3 && (name[0] == 'C' || name[0] == 'c') && name[1] == '-' {
+			c := name[2]
+			if c >= 'a' && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			return string(rune(c & 0x1f))
+		}
+		if seq, ok := keyNames[strings.ToLower(name)]; ok {
+			return seq
+		}
+		return tok
+	})
+}
+
+// plainText strips escape sequences, carriage returns and other control
+// characters from terminal output. ends[i] is the offset in out just past
+// the byte that produced text[i].
+func plainText(out []byte) (string, []int) {
+	const (
+		ground = iota
+		escape
+		csi
+		str    // OSC, DCS and the like, up to BEL or ST
+		strEsc // ESC inside a string
+		charset
+	)
+	text := make([]byte, 0, len(out))
+	ends := make([]int, 0, len(out))
+	state := ground
+	for i, b := range out {
+		switch state {
+		case ground:
+			switch {
+			case b == 0x1b:
+				state = escape
+			case b == '\n' || b == '\t' || b >= 0x20 && b != 0x7f:
+				text = append(text, b)
+				ends = append(ends, i+1)
+			}
+		case escape:
+			switch b {
+			case '[':
+				state = csi
+			case ']', 'P', 'X', '^', '_':
+				state = str
+			case '(', ')', '*', '+', '#', '%':
+				state = charset
+			default:
+				state = ground
+			}
+		case csi:
+			if b >= 0x40 && b <= 0x7e {
+				state = ground
+			}
+		case str:
+			switch b {
+			case 0x07:
+				state = ground
+			case 0x1b:
+				state = strEsc
+			}
+		case strEsc:
+			if b == '\\' {
+				state = ground
+			} else {
+				state = str
+			}
+		case charset:
+			state = ground
+		}
+	}
+	return string(text), ends
+}
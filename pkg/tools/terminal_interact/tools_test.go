@@ -0,0 +1,32 @@
+package terminal_interact
+
+import "testing"
+
+func TestExpandKeys(t *testing.T) {
+	cases := map[string]string{
+		"ls<Enter>":       "ls\r",
+		"<C-c><c-D>":      "\x03\x04",
+		"<Up><tab>":       "\x1b[A\t",
+		"a <b> <Unknown>": "a <b> <Unknown>",
+	}
+	for keys, want := range cases {
+		if got := expandKeys(keys); got != want {
+			t.Errorf("expandKeys(%q) = %q, want %q", keys, got, want)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	out := []byte("\x1b]133;C\x07\x1b[1;32mok\x1b[0m\r\nmysql> ")
+	text, ends := plainText(out)
+	if text != "ok\nmysql> " {
+		t.Fatalf("plainText() = %q", text)
+	}
+	// The offsets lead back to the raw output, past the escape sequences
+	if got := string(out[:ends[1]]); got != "\x1b]133;C\x07\x1b[1;32mok" {
+		t.Fatalf("raw output up to %q = %q", text[:2], got)
+	}
+	if ends[len(ends)-1] != len(out) {
+		t.Fatalf("last end = %d, want %d", ends[len(ends)-1], len(out))
+	}
+}
@@ -90,6 +90,10 @@ func NewUploadTool(tc *tools.ToolContext) tool.InvokableTool {
 			"create_parent": {Type: schema.Boolean, Required: false, Desc: "Create parent directories if needed (default: true)"},
 		}),
 	}, func(ctx context.Context, input *UploadInput) (string, error) {
+		if v := tc.AssetPolicy(input.AssetID).CheckTransfer(input.RemotePath); v != nil {
+			return tc.Refuse("transfer_upload", v), nil
+		}
+
 		createParent := true
 		if input.CreateParent {
 			createParent = input.CreateParent
@@ -154,6 +158,10 @@ func NewDownloadTool(tc *tools.ToolContext) tool.InvokableTool {
 			"create_parent": {Type: schema.Boolean, Required: false, Desc: "Create parent directories if needed (default: true)"},
 		}),
 	}, func(ctx context.Context, input *DownloadInput) (string, error) {
+		if v := tc.AssetPolicy(input.AssetID).CheckTransfer(input.RemotePath); v != nil {
+			return tc.Refuse("transfer_download", v), nil
+		}
+
 		createParent := true
 		if input.CreateParent {
 			createParent = input.CreateParent
@@ -218,6 +226,13 @@ func NewCopyBetweenAssetsTool(tc *tools.ToolContext) tool.InvokableTool {
 			"overwrite":       {Type: schema.Boolean, Required: false, Desc: "Overwrite if exists (default: false)"},
 		}),
 	}, func(ctx context.Context, input *CopyBetweenInput) (string, error) {
+		if v := tc.AssetPolicy(input.SourceAssetID).CheckTransfer(input.SourcePath); v != nil {
+			return tc.Refuse("transfer_copy", v), nil
+		}
+		if v := tc.AssetPolicy(input.TargetAssetID).CheckTransfer(input.TargetPath); v != nil {
+			return tc.Refuse("transfer_copy", v), nil
+		}
+
 		sourceSpec := tc.AssetEndpoint(input.SourceAssetID)
 		targetSpec := tc.AssetEndpoint(input.TargetAssetID)
 
@@ -287,6 +302,15 @@ func NewSyncTool(tc *tools.ToolContext) tool.InvokableTool {
 			"dry_run":         {Type: schema.Boolean, Required: false, Desc: "Show what would be done without actually doing it (default: false)"},
 		}),
 	}, func(ctx context.Context, input *SyncInput) (string, error) {
+		sourcePolicy := tc.AssetPolicy(input.SourceAssetID)
+		if v := sourcePolicy.CheckTransfer(input.SourcePath); v != nil {
+			return tc.Refuse("transfer_sync", v), nil
+		}
+		targetPolicy := tc.AssetPolicy(input.TargetAssetID)
+		if v := targetPolicy.CheckTransfer(input.TargetPath); v != nil {
+			return tc.Refuse("transfer_sync", v), nil
+		}
+
 		sourceSpec := service.EndpointSpec{}
 		if input.SourceAssetID != "" {
 			sourceSpec = tc.AssetEndpoint(input.SourceAssetID)
@@ -332,6 +356,17 @@ func NewSyncTool(tc *tools.ToolContext) tool.InvokableTool {
 			targetEntry, exists := targetFiles[sourceEntry.Name]
 			delete(targetFiles, sourceEntry.Name)
 
+			// Individual files may still fall under a blocked path
+			v := sourcePolicy.CheckPath(sourcePath)
+			if v == nil {
+				v = targetPolicy.CheckPath(targetPath)
+			}
+			if v != nil {
+				tc.LogViolation("transfer_sync", v)
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: refused by workspace restrictions: %s", sourceEntry.Name, v.Reason))
+				continue
+			}
+
 			// Check if need to copy
 			needCopy := !exists ||
 				sourceEntry.Size != targetEntry.Size ||
@@ -378,6 +413,11 @@ func NewSyncTool(tc *tools.ToolContext) tool.InvokableTool {
 					result.Deleted = append(result.Deleted, name+" (dry-run)")
 				} else {
 					targetPath := path.Join(input.TargetPath, name)
+					if v := targetPolicy.CheckPath(targetPath); v != nil {
+						tc.LogViolation("transfer_sync", v)
+						result.Errors = append(result.Errors, fmt.Sprintf("%s: refused by workspace restrictions: %s", name, v.Reason))
+						continue
+					}
 					err := tc.Remove(ctx, targetSpec, targetPath)
 					if err != nil {
 						result.Errors = append(result.Errors, fmt.Sprintf("%s: delete error: %v", name, err))
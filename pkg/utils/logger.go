@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
 )
 
 var logger *slog.Logger
@@ -20,12 +21,12 @@ func InitLogger() {
 	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		// Fallback to stdout when file cannot be created
-		logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: redactAttr}))
 		return
 	}
 
 	// Create JSON format handler
-	handler := slog.NewJSONHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: true})
+	handler := slog.NewJSONHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: true, ReplaceAttr: redactAttr})
 	logger = slog.New(handler)
 
 	// Set as default
@@ -39,3 +40,30 @@ func GetLogger() *slog.Logger {
 	}
 	return logger
 }
+
+// redactAttr keeps secrets out of log lines, whether logged under a secret
+// key or inside a logged config map
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if IsSecretKey(a.Key) {
+			return slog.String(a.Key, RedactString(a.Value.String()))
+		}
+	case slog.KindAny:
+		v := reflect.ValueOf(a.Value.Any())
+		// Named map types such as models.JSONMap included
+		switch {
+		case !v.IsValid():
+		case v.CanConvert(anyMapType):
+			return slog.Any(a.Key, RedactMap(v.Convert(anyMapType).Interface().(map[string]interface{})))
+		case v.CanConvert(stringMapType):
+			return slog.Any(a.Key, redactStringMap(v.Convert(stringMapType).Interface().(map[string]string)))
+		}
+	}
+	return a
+}
+
+var (
+	anyMapType    = reflect.TypeOf(map[string]interface{}{})
+	stringMapType = reflect.TypeOf(map[string]string{})
+)
@@ -1,5 +1,17 @@
 package utils
 
+import "strings"
+
+// RedactedValue replaces secret values in API responses and log lines
+const RedactedValue = "******"
+
+// SecretRefPrefix starts a reference to a value in the secret vault
+// (pkg/secrets). References reveal nothing and are never redacted.
+const SecretRefPrefix = "secret:"
+
+// Key name suffixes that mark a secret once lowercased with separators removed
+var secretKeySuffixes = []string{"password", "passwd", "passphrase", "privatekey", "token", "apikey", "secret", "secretkey", "accesskey"}
+
 // MaskSensitiveString masks sensitive string: show first & last 4 chars, middle replaced by asterisks; if shorter, return all asterisks
 func MaskSensitiveString(s string) string {
 	if len(s) > 8 {
@@ -9,3 +21,120 @@ func MaskSensitiveString(s string) string {
 	}
 	return ""
 }
+
+// IsSecretKey reports whether a config or log key names a secret, such as
+// password, private_key_passphrase, apiKey, token or an Authorization header
+func IsSecretKey(key string) bool {
+	k := strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.ToLower(key))
+	if k == "authorization" || k == "proxyauthorization" || k == "cookie" {
+		return true
+	}
+	for _, suffix := range secretKeySuffixes {
+		if strings.HasSuffix(k, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString redacts a secret value, leaving empty values and vault
+// references as they are
+func RedactString(s string) string {
+	if s == "" || strings.HasPrefix(s, SecretRefPrefix) {
+		return s
+	}
+	return RedactedValue
+}
+
+// RedactMap returns a copy of m with the values of secret keys redacted at
+// any depth
+func RedactMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		switch v := v.(type) {
+		case string:
+			if IsSecretKey(k) {
+				out[k] = RedactString(v)
+			} else {
+				out[k] = v
+			}
+		case map[string]interface{}:
+			out[k] = RedactMap(v)
+		case map[string]string:
+			out[k] = redactStringMap(v)
+		case []interface{}:
+			items := make([]interface{}, len(v))
+			for i, item := range v {
+				items[i] = item
+				if nested, ok := item.(map[string]interface{}); ok {
+					items[i] = RedactMap(nested)
+				}
+			}
+			out[k] = items
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// RestoreRedacted puts back the previous values of secrets that a client
+// returned redacted, so saving a form loaded from the API keeps them. Maps
+// in lists are matched by their "id", or else by position.
+func RestoreRedacted(next, prev map[string]interface{}) {
+	for k, v := range next {
+		switch v := v.(type) {
+		case string:
+			if v == RedactedValue && IsSecretKey(k) {
+				if old, ok := prev[k].(string); ok {
+					next[k] = old
+				}
+			}
+		case map[string]interface{}:
+			if old, ok := prev[k].(map[string]interface{}); ok {
+				RestoreRedacted(v, old)
+			}
+		case []interface{}:
+			if old, ok := prev[k].([]interface{}); ok {
+				restoreRedactedList(v, old)
+			}
+		}
+	}
+}
+
+func restoreRedactedList(next, prev []interface{}) {
+	for i, item := range next {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var old map[string]interface{}
+		if id, ok := m["id"].(string); ok && id != "" {
+			for _, p := range prev {
+				if pm, ok := p.(map[string]interface{}); ok && pm["id"] == id {
+					old = pm
+					break
+				}
+			}
+		} else if i < len(prev) {
+			old, _ = prev[i].(map[string]interface{})
+		}
+		if old != nil {
+			RestoreRedacted(m, old)
+		}
+	}
+}
+
+func redactStringMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if IsSecretKey(k) {
+			v = RedactString(v)
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -15,7 +15,11 @@ import (
 	"github.com/choraleia/choraleia/pkg/config"
 	"github.com/choraleia/choraleia/pkg/event"
 	"github.com/choraleia/choraleia/pkg/handler"
+	"github.com/choraleia/choraleia/pkg/models"
+	"github.com/choraleia/choraleia/pkg/secrets"
 	"github.com/choraleia/choraleia/pkg/service"
+	"github.com/choraleia/choraleia/pkg/service/hostkey"
+	"github.com/choraleia/choraleia/pkg/service/recording"
 	"github.com/choraleia/choraleia/pkg/service/repomap"
 	"github.com/choraleia/choraleia/pkg/tools"
 	"github.com/choraleia/choraleia/pkg/tools/workspace_repomap"
@@ -108,6 +112,17 @@ func NewServer() *Server {
 	return server
 }
 
+// localOnly refuses clients that are not on this machine. It checks the
+// connection's address, not forwarding headers a client could set.
+func localOnly(c *gin.Context) {
+	host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+		c.AbortWithStatusJSON(http.StatusForbidden, models.Response{Code: 403, Message: "only available from this machine"})
+		return
+	}
+	c.Next()
+}
+
 func (s *Server) Start(ctx context.Context) error {
 	// Load server port from YAML config file under the user's home directory.
 	// If the config file doesn't exist, a default one will be created.
@@ -164,9 +179,6 @@ func (s *Server) Start(ctx context.Context) error {
 }
 
 func (s *Server) SetupRoutes() {
-	// Create asset service instance
-	assetService := service.NewAssetService()
-
 	// Get chat store service instance
 	chatStoreService, err := service.NewChatStore()
 	if err != nil {
@@ -174,6 +186,12 @@ func (s *Server) SetupRoutes() {
 		os.Exit(1)
 	}
 
+	// Create asset service instance; assets live in the app database
+	assetService := service.NewAssetService(chatStoreService.DB())
+	if err := assetService.AutoMigrate(); err != nil {
+		s.logger.Error("Failed to migrate asset tables", "error", err)
+	}
+
 	// Create model service instance
 	modelService := service.NewModelService()
 
@@ -182,6 +200,22 @@ func (s *Server) SetupRoutes() {
 
 	// Create terminal service instance
 	terminalService := service.NewTerminalService(assetService)
+	// Detached sessions, their scrollback and session recordings follow the
+	// terminal config; a nil config gives the defaults
+	termCfg, _, err := config.Load()
+	if err != nil {
+		s.logger.Warn("Failed to load terminal config; using defaults", "error", err)
+	}
+	terminalService.SetSessionLimits(termCfg.TerminalIdleTimeout(), termCfg.ScrollbackBytes())
+	recordingStore := recording.Default()
+	recordingStore.SetRetention(termCfg.RecordingRetention(), termCfg.RecordingMaxBytes())
+	terminalService.SetRecording(recordingStore, termCfg.RecordAllSessions())
+	go func() {
+		if _, err := recordingStore.Prune(); err != nil {
+			s.logger.Warn("Failed to prune terminal recordings", "error", err)
+		}
+	}()
+	recordingHandler := handler.NewRecordingHandler(recordingStore, s.logger)
 
 	// Create Docker service instance
 	dockerService := service.NewDockerService(assetService)
@@ -189,6 +223,7 @@ func (s *Server) SetupRoutes() {
 
 	// Task system (background jobs)
 	taskService := service.NewTaskService(2)
+	terminalService.SetTaskService(taskService)
 	transferTaskService := service.NewTransferTaskService(taskService, assetService)
 	taskHandler := handler.NewTaskHandler(taskService, transferTaskService)
 
@@ -200,20 +235,70 @@ func (s *Server) SetupRoutes() {
 	fsService := service.NewFSService(fsRegistry)
 	fsHandler := handler.NewFSHandler(fsService)
 
+	// Remote Docker hosts share the pooled SSH connections
+	dockerService.SetSSHPool(fsRegistry.SSHPool())
+
 	// Create quick command service instance
 	quickCmdService := service.NewQuickCommandService()
 	quickCmdHandler := handler.NewQuickCmdHandler(quickCmdService, s.logger)
 
+	// Trigger rules run over terminal output
+	triggerService := service.NewTriggerService()
+	terminalService.SetTriggers(triggerService)
+	triggerHandler := handler.NewTriggerHandler(triggerService, s.logger)
+
 	// Create tunnel service and handler
 	tunnelService := service.NewTunnelService(assetService)
+	tunnelService.SetSSHPool(fsRegistry.SSHPool())
+	// Standalone port forwards live in the app database and may target
+	// containers
+	tunnelService.SetDB(chatStoreService.DB())
+	if err := tunnelService.AutoMigrate(); err != nil {
+		s.logger.Error("Failed to migrate tunnel tables", "error", err)
+	}
+	tunnelService.SetDockerService(dockerService)
+	// Supervise tunnels flagged to start with the app
+	go tunnelService.AutoStartTunnels()
 	tunnelHandler := handler.NewTunnelHandler(tunnelService, s.logger)
 
+	// Create SSH host key handler
+	hostKeyHandler := handler.NewHostKeyHandler(hostkey.Default(), s.logger)
+
+	// Create secret vault handler
+	secretsHandler := handler.NewSecretsHandler(secrets.Default(), s.logger)
+
 	// Terminal connection routes
 	// /terminal
 	termGroups := s.ginEngine.Group("/terminal")
-	termGroups.GET("connect/:assetId", terminalService.RunTerminal)
+	// Join a session through a share token: /terminal/shared/:token?offset=N
+	termGroups.GET("shared/:token", terminalService.JoinSharedTerminal)
+	// The rest reach sessions without a token, so only this machine may
+	// use them, even when the server listens on the network for viewers
+	ownerGroup := termGroups.Group("", localOnly)
+	ownerGroup.GET("connect/:assetId", terminalService.RunTerminal)
 	// Docker container terminal: /terminal/docker/:assetId/:containerId
-	termGroups.GET("docker/:assetId/:containerId", terminalService.RunDockerTerminal)
+	ownerGroup.GET("docker/:assetId/:containerId", terminalService.RunDockerTerminal)
+	// Reattach to a running session: /terminal/attach/:sessionId?offset=N
+	ownerGroup.GET("attach/:sessionId", terminalService.ReattachTerminal)
+	// Running sessions, attached or not: /terminal/sessions
+	ownerGroup.GET("sessions", terminalService.ListSessions)
+	// Commands run in a session, from shell integration: /terminal/sessions/:sessionId/history?limit=N
+	ownerGroup.GET("sessions/:sessionId/history", terminalService.GetCommandHistory)
+	// Values captured by triggers: /terminal/sessions/:sessionId/variables
+	ownerGroup.GET("sessions/:sessionId/variables", terminalService.GetVariables)
+	// Share tokens letting other clients watch or type into a session
+	ownerGroup.POST("sessions/:sessionId/shares", terminalService.CreateShare)
+	ownerGroup.GET("sessions/:sessionId/shares", terminalService.ListShares)
+	ownerGroup.DELETE("shares/:token", terminalService.RevokeShare)
+	// Broadcast groups typing the same input into several sessions
+	ownerGroup.POST("broadcast", terminalService.CreateBroadcastGroup)
+	ownerGroup.GET("broadcast", terminalService.ListBroadcastGroups)
+	ownerGroup.GET("broadcast/:groupId", terminalService.GetBroadcastGroup)
+	ownerGroup.DELETE("broadcast/:groupId", terminalService.DeleteBroadcastGroup)
+	ownerGroup.POST("broadcast/:groupId/members", terminalService.AddBroadcastMembers)
+	ownerGroup.PUT("broadcast/:groupId/members/:sessionId", terminalService.SetBroadcastMember)
+	ownerGroup.DELETE("broadcast/:groupId/members/:sessionId", terminalService.RemoveBroadcastMember)
+	ownerGroup.POST("broadcast/:groupId/input", terminalService.BroadcastInput)
 
 	// API group
 	// /api
@@ -232,6 +317,9 @@ func (s *Server) SetupRoutes() {
 	assetsGroup.PUT(":id", assetHandler.Update)
 	assetsGroup.PUT(":id/move", assetHandler.Move)
 	assetsGroup.DELETE(":id", assetHandler.Delete)
+	assetsGroup.GET("/deleted", assetHandler.Deleted)
+	assetsGroup.GET(":id/revisions", assetHandler.Revisions)
+	assetsGroup.POST(":id/revisions/:revId/restore", assetHandler.RestoreRevision)
 	assetsGroup.POST("/import/ssh", assetHandler.ImportSSH)
 	assetsGroup.GET("/ssh-config", assetHandler.ParseSSH)
 	assetsGroup.GET("/user-ssh-keys", assetHandler.ListSSHKeys)          // added endpoint
@@ -309,14 +397,62 @@ func (s *Server) SetupRoutes() {
 		quickCmdGroup.POST("/reorder", quickCmdHandler.Reorder)
 	}
 
+	// Terminal trigger API routes
+	// /api/triggers?asset_id=
+	triggersGroup := apiGroup.Group("/triggers")
+	{
+		triggersGroup.GET("", triggerHandler.List)
+		triggersGroup.POST("", triggerHandler.Create)
+		triggersGroup.GET("/:id", triggerHandler.Get)
+		triggersGroup.PUT("/:id", triggerHandler.Update)
+		triggersGroup.DELETE("/:id", triggerHandler.Delete)
+	}
+
 	// Tunnel API routes
 	// /api/tunnels
 	tunnelsGroup := apiGroup.Group("/tunnels")
 	{
 		tunnelsGroup.GET("", tunnelHandler.List)
 		tunnelsGroup.GET("/stats", tunnelHandler.GetStats)
+		tunnelsGroup.POST("", tunnelHandler.Create)
+		tunnelsGroup.GET("/:id", tunnelHandler.Get)
+		tunnelsGroup.PUT("/:id", tunnelHandler.Update)
+		tunnelsGroup.DELETE("/:id", tunnelHandler.Delete)
 		tunnelsGroup.POST("/:id/start", tunnelHandler.Start)
 		tunnelsGroup.POST("/:id/stop", tunnelHandler.Stop)
+		tunnelsGroup.GET("/:id/connections", tunnelHandler.Connections)
+		tunnelsGroup.DELETE("/:id/connections/:connId", tunnelHandler.CloseConnection)
+		tunnelsGroup.GET("/:id/history", tunnelHandler.History)
+		tunnelsGroup.GET("/:id/proxy.pac", tunnelHandler.ProxyPAC)
+	}
+
+	// SSH host key API routes
+	// /api/host-keys
+	hostKeysGroup := apiGroup.Group("/host-keys")
+	{
+		hostKeysGroup.GET("", hostKeyHandler.List)
+		hostKeysGroup.GET("/scan", hostKeyHandler.Scan)
+		hostKeysGroup.POST("", hostKeyHandler.Accept)
+		hostKeysGroup.DELETE("/:id", hostKeyHandler.Revoke)
+	}
+
+	// Secret vault API routes
+	// /api/secrets
+	secretsGroup := apiGroup.Group("/secrets")
+	{
+		secretsGroup.GET("/status", secretsHandler.Status)
+		secretsGroup.POST("/unlock", secretsHandler.Unlock)
+		secretsGroup.PUT("/passphrase", secretsHandler.SetPassphrase)
+	}
+
+	// Terminal recording API routes
+	// /api/recordings
+	recordingsGroup := apiGroup.Group("/recordings")
+	{
+		recordingsGroup.GET("", recordingHandler.List)
+		recordingsGroup.GET("/:id", recordingHandler.Stream)
+		recordingsGroup.GET("/:id/download", recordingHandler.Download)
+		recordingsGroup.DELETE("/:id", recordingHandler.Delete)
 	}
 
 	// Workspace API routes
@@ -325,6 +461,8 @@ func (s *Server) SetupRoutes() {
 	if err := workspaceService.AutoMigrate(); err != nil {
 		s.logger.Error("Failed to migrate workspace tables", "error", err)
 	}
+	// With every store migrated, drop the vault entries nothing refers to
+	secrets.Default().WhenUnlocked(func() { service.PruneSecrets(chatStoreService.DB()) })
 	// Inject DockerService, SSHPool, and RuntimeStatusService into WorkspaceService's RuntimeManager
 	workspaceService.SetDockerService(dockerService)
 	workspaceService.SetSSHPool(fsRegistry.SSHPool())
@@ -452,6 +590,8 @@ func (s *Server) SetupRoutes() {
 		toolCtx.WithMemoryService(memoryService)
 	}
 	toolLoader := tools.NewToolLoaderAdapter(toolCtx)
+	// Configure tool manager for MCP server connections
+	toolLoader.SetToolManager(workspaceService.GetToolManager())
 	chatService.SetToolLoader(toolLoader)
 
 	// Memory API routes